@@ -0,0 +1,97 @@
+// Command agent-ca manages the certificate authority the device agent uses
+// for mutual TLS: it issues certificates for the agent core and other
+// clients, reissues them before they expire, and prints the fingerprints
+// the agent pins
+//
+// Usage:
+//
+//	agent-ca -dir /var/lib/device-agent/tls init
+//	agent-ca -dir /var/lib/device-agent/tls issue rust-core
+//	agent-ca -dir /var/lib/device-agent/tls issue -hosts agent.lan,10.0.0.5 device-agent
+//	agent-ca fingerprint rust-core.pem
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/mtls"
+)
+
+func main() {
+	dir := flag.String("dir", "tls", "CA directory, as passed to the agent's -tls-dir")
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintln(out, "Usage: agent-ca [-dir dir] <command> [flags] [args]")
+		fmt.Fprintln(out, "Commands:")
+		fmt.Fprintln(out, "  init                      create the CA")
+		fmt.Fprintln(out, "  issue [-hosts h,...] name issue or reissue a certificate, for a client unless -hosts is given")
+		fmt.Fprintln(out, "  fingerprint cert.pem      print the key fingerprint -tls-pin takes")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	args := flag.Args()[1:]
+	switch flag.Arg(0) {
+	case "init":
+		ca, err := mtls.LoadOrCreateCA(*dir, "device-agent CA")
+		if err != nil {
+			fail(err)
+		}
+		fmt.Printf("CA %s in %s, valid until %s\n", ca.Certificate().Subject.CommonName, *dir, ca.Certificate().NotAfter.Format(time.DateOnly))
+
+	case "issue":
+		cmd := flag.NewFlagSet("issue", flag.ExitOnError)
+		hosts := cmd.String("hosts", "", "comma-separated DNS names and IPs, for a server certificate")
+		validity := cmd.Duration("validity", 90*24*time.Hour, "how long the certificate is valid")
+		cmd.Parse(args)
+		if cmd.NArg() != 1 {
+			fail(fmt.Errorf("issue needs one certificate name"))
+		}
+		ca, err := mtls.LoadCA(*dir)
+		if err != nil {
+			fail(fmt.Errorf("%w (run agent-ca init first)", err))
+		}
+		req := mtls.Request{Name: cmd.Arg(0), Validity: *validity}
+		for _, h := range strings.Split(*hosts, ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				req.Hosts = append(req.Hosts, h)
+			}
+		}
+		path, err := ca.IssueFiles(*dir, req)
+		if err != nil {
+			fail(err)
+		}
+		cert, err := mtls.LoadCertificate(path)
+		if err != nil {
+			fail(err)
+		}
+		fmt.Printf("Issued %s, valid until %s\nFingerprint: %s\n", path, cert.NotAfter.Format(time.DateOnly), mtls.Fingerprint(cert))
+
+	case "fingerprint":
+		if len(args) != 1 {
+			fail(fmt.Errorf("fingerprint needs one certificate file"))
+		}
+		cert, err := mtls.LoadCertificate(args[0])
+		if err != nil {
+			fail(err)
+		}
+		fmt.Println(mtls.Fingerprint(cert))
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "FAILED:", err)
+	os.Exit(1)
+}
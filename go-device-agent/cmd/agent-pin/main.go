@@ -0,0 +1,34 @@
+// Command agent-pin hashes a PIN or spoken code for the device agent's
+// -challenge config. The PIN is read from stdin so it stays out of the
+// shell history and the process list.
+//
+// Usage:
+//
+//	agent-pin < pin.txt
+//	read -s pin && echo "$pin" | agent-pin
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/challenge"
+)
+
+func main() {
+	pin, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fail(err)
+	}
+	hash, err := challenge.HashPIN(string(pin))
+	if err != nil {
+		fail(err)
+	}
+	fmt.Println(hash)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "FAILED:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,92 @@
+// Command agent-secrets edits the device agent's encrypted secrets file.
+// The passphrase is read from AGENT_SECRETS_PASSPHRASE or -passphrase-file,
+// and values from stdin so they stay out of the shell history and the
+// process list.
+//
+// Usage:
+//
+//	agent-secrets -file secrets.json set smtp/password < password.txt
+//	agent-secrets -file secrets.json list
+//	agent-secrets -file secrets.json delete smtp/password
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/secrets"
+)
+
+func main() {
+	path := flag.String("file", "secrets.json", "secrets file, as passed to the agent's -secrets")
+	passphraseFile := flag.String("passphrase-file", "", "file holding the passphrase (default AGENT_SECRETS_PASSPHRASE)")
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintln(out, "Usage: agent-secrets [flags] <command> [name]")
+		fmt.Fprintln(out, "Commands:")
+		fmt.Fprintln(out, "  set name     store the value read from stdin")
+		fmt.Fprintln(out, "  list         list secret names")
+		fmt.Fprintln(out, "  delete name  remove a secret")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	passphrase := os.Getenv("AGENT_SECRETS_PASSPHRASE")
+	if *passphraseFile != "" {
+		data, err := os.ReadFile(*passphraseFile)
+		if err != nil {
+			fail(err)
+		}
+		passphrase = strings.TrimRight(string(data), "\r\n")
+	}
+	file, err := secrets.OpenFile(*path, passphrase)
+	if err != nil {
+		fail(err)
+	}
+
+	switch flag.Arg(0) {
+	case "set":
+		if flag.NArg() != 2 {
+			fail(fmt.Errorf("set needs a secret name"))
+		}
+		value, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fail(err)
+		}
+		// A trailing newline is almost always from echo or an editor
+		if err := file.Set(flag.Arg(1), strings.TrimRight(string(value), "\r\n")); err != nil {
+			fail(err)
+		}
+		fmt.Printf("Stored %s; reference it as %s%s\n", flag.Arg(1), secrets.Scheme, flag.Arg(1))
+
+	case "list":
+		for _, name := range file.Names() {
+			fmt.Println(name)
+		}
+
+	case "delete":
+		if flag.NArg() != 2 {
+			fail(fmt.Errorf("delete needs a secret name"))
+		}
+		if err := file.Delete(flag.Arg(1)); err != nil {
+			fail(err)
+		}
+		fmt.Printf("Deleted %s\n", flag.Arg(1))
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "FAILED:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"slices"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/daemon"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// version is set at build time with -ldflags "-X main.version=v1.2.3";
+// otherwise it comes from the module's build info
+var version = ""
+
+// usage describes the commands before the flags they share
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "Usage: agent [command] [flags] [args]")
+	fmt.Fprintln(out, "Commands:")
+	fmt.Fprintln(out, "  serve                  run the agent (the default)")
+	fmt.Fprintln(out, "  exec intent.json       run one intent and print its result; exits 1 if it fails")
+	fmt.Fprintln(out, "  validate intent.json   check an intent against the schemas and policies without running it; exits 1 if invalid")
+	fmt.Fprintln(out, "  capabilities           print the capability manifest")
+	fmt.Fprintln(out, "  repl                   compose intents at a prompt and send them to the gateway")
+	fmt.Fprintln(out, "  install-service        install and start the agent as a systemd, launchd, or Windows service run")
+	fmt.Fprintln(out, "                         with the other flags given; see -service-name, -service-scope, and -dry-run")
+	fmt.Fprintln(out, "  uninstall-service      stop the service and remove it")
+	fmt.Fprintln(out, "  start-service          start the installed service")
+	fmt.Fprintln(out, "  stop-service           stop the service until it is started again or the machine restarts")
+	fmt.Fprintln(out, "  version                print the agent's version")
+	fmt.Fprintln(out, "Intent files may be - for stdin, and are read with -codec. Every command")
+	fmt.Fprintln(out, "sets the agent up from the same flags and -config.")
+	fmt.Fprintln(out, "Flags:")
+	flag.PrintDefaults()
+}
+
+// readIntent reads an intent file, or stdin for "-"
+func readIntent(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// runExec runs one intent, printing its result, and returns the exit code
+func runExec(ctx context.Context, gw *gateway.Gateway, path, codecName string, logger *slog.Logger) int {
+	codec, ok := intent.CodecForName(codecName)
+	if !ok {
+		logger.Error("Unknown codec", "codec", codecName)
+		return 1
+	}
+	data, err := readIntent(path)
+	if err != nil {
+		logger.Error("Failed to read intent", "error", err)
+		return 1
+	}
+	result, err := gw.ProcessEncodedIntent(ctx, data, codec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Rejected intent: %v\n", err)
+		return 1
+	}
+	if !printJSON(result, logger) || !result.Success {
+		return 1
+	}
+	return 0
+}
+
+// runValidate checks one intent without running it, printing each check,
+// and returns the exit code
+func runValidate(ctx context.Context, gw *gateway.Gateway, path, codecName string, logger *slog.Logger) int {
+	codec, ok := intent.CodecForName(codecName)
+	if !ok {
+		logger.Error("Unknown codec", "codec", codecName)
+		return 1
+	}
+	data, err := readIntent(path)
+	if err != nil {
+		logger.Error("Failed to read intent", "error", err)
+		return 1
+	}
+	validation := gw.ValidateIntent(ctx, data, codec)
+	if !printJSON(validation, logger) || !validation.Valid {
+		return 1
+	}
+	return 0
+}
+
+// printJSON prints v to stdout, reporting whether it could be encoded
+func printJSON(v interface{}, logger *slog.Logger) bool {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		logger.Error("Failed to encode output", "error", err)
+		return false
+	}
+	fmt.Println(string(out))
+	return true
+}
+
+func printVersion() {
+	v, revision := version, ""
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if v == "" {
+			v = info.Main.Version
+		}
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" && len(s.Value) >= 12 {
+				revision = " " + s.Value[:12]
+			}
+		}
+	}
+	if v == "" {
+		v = "(devel)"
+	}
+	fmt.Printf("agent %s%s (%s)\n", v, revision, runtime.Version())
+}
+
+// serviceFlags set up installing the service rather than the service itself
+var serviceFlags = []string{"service-name", "service-scope", "dry-run", "chdir"}
+
+// runService installs, uninstalls, starts, or stops the agent's service,
+// which runs this binary with the other flags given on the command line,
+// and returns the exit code
+func runService(command, name, scope string, dryRun bool) int {
+	c := daemon.Config{
+		Name:        name,
+		Scope:       scope,
+		Description: "local-agent-core device agent",
+		Args:        []string{"serve"},
+	}
+	var steps []daemon.Step
+	var err error
+	switch command {
+	case "uninstall-service":
+		steps, err = daemon.Uninstall(c)
+	case "start-service":
+		steps, err = daemon.Start(c)
+	case "stop-service":
+		steps, err = daemon.Stop(c)
+	default:
+		for _, terminal := range []string{"pipe", "tui"} {
+			if flag.Lookup(terminal).Value.String() == "true" {
+				fmt.Fprintf(os.Stderr, "A service has no terminal for -%s\n", terminal)
+				return 2
+			}
+		}
+		flag.Visit(func(f *flag.Flag) {
+			if !slices.Contains(serviceFlags, f.Name) {
+				c.Args = append(c.Args, "-"+f.Name+"="+f.Value.String())
+			}
+		})
+		if c.Executable, err = os.Executable(); err == nil {
+			c.Executable, err = filepath.EvalSymlinks(c.Executable)
+		}
+		if err == nil {
+			c.WorkingDir, err = os.Getwd()
+		}
+		if err == nil {
+			steps, err = daemon.Install(c)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to %s: %v\n", command, err)
+		return 1
+	}
+
+	for _, step := range steps {
+		if dryRun {
+			fmt.Println("#", step)
+			os.Stdout.Write(step.Data)
+			continue
+		}
+		fmt.Fprintln(os.Stderr, step)
+		if err := step.Do(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to %s: %v\n", step, err)
+			return 1
+		}
+	}
+	return 0
+}
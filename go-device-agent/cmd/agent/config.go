@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/approval"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/config"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/logging"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/quota"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/transport"
+)
+
+// configSchema places each flag that -config may set in a section of the
+// file. Flags missing here, such as -config itself, are command-line only.
+var configSchema = config.Schema{
+	"agent": {
+		"data-dir", "pid-file", "notes-dir", "id-strategy", "timezone", "air-gapped", "egress-allow",
+		"pool-max-per-host", "pool-idle-timeout",
+	},
+	"transports": {
+		"pipe", "tui", "codec", "announce-capabilities", "pipe-identity",
+		"http", "tls-dir", "tls-hosts", "tls-pin", "result-webhook", "admin-socket",
+	},
+	"security": {
+		"roles", "roles-reload", "secrets", "secrets-passphrase-file", "secrets-keychain",
+		"trusted-keys", "require-signatures", "sign-results", "encrypted-params", "payload-key",
+		"identity-key", "strict-intents", "clock-skew", "max-intent-age", "sandbox", "sandbox-enforce",
+	},
+	"policies": {
+		"risk", "approval-push", "approval-callback", "approval-button", "challenge", "quotas",
+		"anomaly", "sensitive-intents", "speaker-min-confidence", "known-speakers", "require-home", "param-transforms",
+		"redactions", "child-safety", "safety-policy", "modes", "mode", "module-groups",
+	},
+	"thresholds": {
+		"degraded-p95", "system-thresholds", "firmware-health-timeout", "low-battery",
+	},
+	"executors": {
+		"disable-executors", "hardware", "microphone", "presence", "bluetooth-adapter",
+		"gtfs", "gtfs-realtime", "translate-url", "documents", "memory-embed-url", "memory-model",
+		"frame-folders", "frame-command", "frame-chromecasts", "frame-media-addr",
+		"media-chromecasts", "cast-discovery", "share-url", "share-addr", "hue-bridge",
+		"file-roots", "file-max-size", "printers", "print-dirs", "audio-max-volume", "email-templates",
+		"email-attachments", "browser-schemes", "browser-fetch", "input", "input-max-text",
+		"clipboard-max-size", "services", "service-user", "updates-backend", "updates-sudo",
+		"system-commands", "http-request-hosts", "system-disks", "mqtt-devices", "mqtt-broker",
+		"zigbee2mqtt-broker", "zigbee2mqtt-topic", "zwave-js-server", "maintenance",
+		"notifications", "cameras", "camera-snapshots", "camera-keep", "calendars", "ics-feeds",
+		"weather-provider", "weather-location", "weather-units", "weather-alerts",
+		"weather-alert-areas", "severe-weather-intents", "news-feeds", "scenes",
+	},
+	"logging": {
+		"log-format", "log-level", "trace", "otlp-endpoint", "trace-service",
+	},
+	"audit": {
+		"audit-log", "audit-rotate-size", "audit-rotate-interval", "audit-max-age", "audit-max-files",
+		"audit-export", "audit-export-interval", "audit-export-endpoint", "audit-export-region",
+		"audit-export-retention", "audit-export-lock-mode", "history", "history-retention",
+	},
+}
+
+// addLiveSettings registers the settings a reload applies while the agent
+// runs: policy rules, quotas, executor settings, and log levels. Each
+// reads the flags, which the reloader has already set.
+func addLiveSettings(r *config.Reloader, gw *gateway.Gateway, logs *logging.Logs, whoIsHome gateway.PresenceSource) {
+	value := func(name string) string { return flag.Lookup(name).Value.String() }
+	get := func(name string) interface{} { return flag.Lookup(name).Value.(flag.Getter).Get() }
+
+	r.AddLive(config.Live{Flags: []string{"log-level"}, Apply: func() error {
+		levels, err := logging.ParseLevels(value("log-level"))
+		if err != nil {
+			return err
+		}
+		logs.SetLevels(levels)
+		return nil
+	}})
+
+	// The approvers and the quota executor are set up at start, so turning
+	// approvals or quotas on needs a restart
+	r.AddLive(config.Live{Flags: []string{"risk"}, Files: []string{"risk"}, Apply: func() error {
+		approvals := gw.Approvals()
+		if approvals == nil || value("risk") == "" {
+			return config.ErrRestart
+		}
+		table, err := approval.LoadTable(value("risk"))
+		if err != nil {
+			return err
+		}
+		return approvals.SetTable(table)
+	}})
+	r.AddLive(config.Live{Flags: []string{"quotas"}, Files: []string{"quotas"}, Apply: func() error {
+		tracker := gw.Quotas()
+		if tracker == nil {
+			return config.ErrRestart
+		}
+		if value("quotas") == "" {
+			return tracker.SetQuotas(nil)
+		}
+		cfg, err := quota.LoadConfig(value("quotas"))
+		if err != nil {
+			return err
+		}
+		return tracker.SetQuotas(cfg.Quotas)
+	}})
+
+	r.AddLive(config.Live{Flags: []string{"sensitive-intents", "speaker-min-confidence", "known-speakers"}, Apply: func() error {
+		gw.SetSpeakerPolicy(gateway.SpeakerPolicy{
+			Sensitive:     splitList(value("sensitive-intents")),
+			MinConfidence: float32(get("speaker-min-confidence").(float64)),
+			Known:         splitList(value("known-speakers")),
+		})
+		return nil
+	}})
+	r.AddLive(config.Live{Flags: []string{"require-home"}, Apply: func() error {
+		gw.SetPresence(whoIsHome, gateway.PresencePolicy{RequireHome: splitList(value("require-home"))})
+		return nil
+	}})
+	r.AddLive(config.Live{Flags: []string{"mode"}, Apply: func() error {
+		return gw.SetMode(value("mode"))
+	}})
+	r.AddLive(config.Live{Flags: []string{"max-intent-age"}, Apply: func() error {
+		gw.SetMaxIntentAge(get("max-intent-age").(time.Duration))
+		return nil
+	}})
+
+	r.AddLive(config.Live{Flags: []string{"param-transforms"}, Files: []string{"param-transforms"}, Apply: func() error {
+		var transformers []gateway.ParamTransformer
+		if path := value("param-transforms"); path != "" {
+			rules, err := gateway.LoadTransforms(path)
+			if err != nil {
+				return err
+			}
+			for _, rule := range rules {
+				transformers = append(transformers, rule)
+			}
+		}
+		gw.SetTransformers(transformers)
+		return nil
+	}})
+	r.AddLive(config.Live{Flags: []string{"redactions"}, Files: []string{"redactions"}, Apply: func() error {
+		var rules []gateway.Redaction
+		if path := value("redactions"); path != "" {
+			var err error
+			if rules, err = gateway.LoadRedactions(path); err != nil {
+				return err
+			}
+		}
+		return gw.SetRedactions(rules)
+	}})
+	r.AddLive(config.Live{Flags: []string{"safety-policy"}, Files: []string{"safety-policy"}, Apply: func() error {
+		policy, err := loadSafetyPolicy(value("safety-policy"))
+		if err != nil {
+			return err
+		}
+		gw.SetSafetyPolicy(policy)
+		return nil
+	}})
+
+	r.AddLive(config.Live{Flags: []string{"degraded-p95"}, Apply: func() error {
+		latency, err := parseLatencyPolicy(value("degraded-p95"))
+		if err != nil {
+			return err
+		}
+		gw.SetLatencyPolicy(latency)
+		return nil
+	}})
+	// As at start, executors this agent doesn't run are skipped, so a
+	// profile can list every home executor whatever the deployment has
+	disabled := splitList(value("disable-executors"))
+	r.AddLive(config.Live{Flags: []string{"disable-executors"}, Apply: func() error {
+		next := splitList(value("disable-executors"))
+		var errs []error
+		collect := func(err error) {
+			if err != nil && agenterrors.CodeOf(err) != agenterrors.NotFound {
+				errs = append(errs, err)
+			}
+		}
+		for _, name := range disabled {
+			if !slices.Contains(next, name) {
+				collect(gw.EnableExecutor(name))
+			}
+		}
+		for _, name := range next {
+			if !slices.Contains(disabled, name) {
+				collect(gw.DisableExecutor(name))
+			}
+		}
+		disabled = next
+		return errors.Join(errs...)
+	}})
+}
+
+// watchConfig applies config changes without a restart: policies, quotas,
+// executor settings, and log levels change when the config file or their
+// own files do, on SIGHUP, or from the admin API. Other changes are
+// reported as taking effect on restart.
+func watchConfig(ctx context.Context, o *options, a *agent, reloader *config.Reloader, server *transport.HTTPServer, whoIsHome gateway.PresenceSource) {
+	addLiveSettings(reloader, a.gw, a.logs, whoIsHome)
+	reloader.OnReload = func(r *config.Report) { logReload(a.logger, r) }
+	server.SetConfigReloader(reloader)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				reloader.Reload("SIGHUP")
+			}
+		}
+	}()
+	if o.configReload > 0 {
+		reloader.Watch(ctx, o.configReload)
+	}
+}
+
+// logReload logs what a config reload changed
+func logReload(logger *slog.Logger, r *config.Report) {
+	logger = logger.With("trigger", r.Trigger)
+	if r.Error != "" {
+		logger.Error("Config reload failed, keeping the running settings", "error", r.Error)
+		return
+	}
+	for _, c := range r.Applied {
+		logger.Info("Config reload applied a setting", "setting", c.String())
+	}
+	for _, c := range r.Failed {
+		logger.Error("Config reload failed to apply a setting", "setting", c.String(), "error", c.Error)
+	}
+	for _, c := range r.Restart {
+		logger.Warn("Config setting takes effect on restart", "setting", c.String())
+	}
+	for _, c := range r.Overridden {
+		logger.Warn("Config reload ignored a setting the command line sets", "setting", c.String())
+	}
+	if len(r.Applied)+len(r.Failed)+len(r.Restart)+len(r.Overridden) == 0 {
+		logger.Info("Config reload changed nothing")
+	}
+}
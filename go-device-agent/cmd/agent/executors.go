@@ -0,0 +1,834 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/config"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/audio"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/bluetooth"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/browser"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/calendar"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/camera"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/clipboard"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/clock"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/convert"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/deliveries"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/documents"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/email"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/files"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/firmware"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/frame"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/gpio"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/httpreq"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/hue"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/input"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/maintenance"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/media"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/memory"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/mqtt"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/news"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/notes"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/presence"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/printer"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/profile"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/security"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/serial"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/service"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/share"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/shopping"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/sound"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/sysinfo"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/system"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/todo"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/transit"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/translate"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/updates"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/weather"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/window"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/zigbee"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/zwave"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// executors are the executors other subsystems are set up with
+type executors struct {
+	// gpio waits on the approval button, when there are GPIO pins
+	gpio *gpio.Executor
+
+	// host's thresholds are inspected through the admin API
+	host *sysinfo.Executor
+
+	// whoIsHome is the presence executor, when there is a presence file
+	whoIsHome gateway.PresenceSource
+}
+
+// setupExecutors registers the executors the flags enable, and starts
+// those that watch for events until ctx is done. Executors whose backend
+// is missing are left out; invalid settings are errors.
+func setupExecutors(ctx context.Context, o *options, a *agent, reloader *config.Reloader) (*executors, error) {
+	ex := &executors{}
+	if err := registerCoreExecutors(o, a, reloader); err != nil {
+		return nil, err
+	}
+	if err := registerPersonalExecutors(ctx, o, a); err != nil {
+		return nil, err
+	}
+	if err := registerWeather(ctx, o, a); err != nil {
+		return nil, err
+	}
+	if err := registerDesktopExecutors(ctx, o, a); err != nil {
+		return nil, err
+	}
+	if err := registerSystemExecutors(ctx, o, a, ex); err != nil {
+		return nil, err
+	}
+	if err := registerHomeExecutors(ctx, o, a, ex); err != nil {
+		return nil, err
+	}
+	return ex, nil
+}
+
+// registerCoreExecutors registers the gateway's own executors, which are
+// always available
+func registerCoreExecutors(o *options, a *agent, reloader *config.Reloader) error {
+	gw := a.gw
+	gw.RegisterExecutor(executor.NewDeviceExecutor())
+	gw.RegisterExecutor(a.notifier)
+	gw.RegisterExecutor(security.NewExecutor(a.devices))
+	gw.RegisterExecutor(gateway.NewPlanExecutor(gw))
+	if o.scenes != "" {
+		loaded, err := gateway.LoadScenes(o.scenes)
+		if err != nil {
+			return fmt.Errorf("failed to load scenes: %w", err)
+		}
+		gw.RegisterExecutor(gateway.NewSceneExecutor(gw, loaded))
+	}
+	gw.RegisterExecutor(gateway.NewCapabilitiesExecutor(gw))
+	gw.RegisterExecutor(gateway.NewModeExecutor(gw))
+	if o.configPath != "" {
+		gw.RegisterExecutor(profile.NewExecutor(reloader, a.bus))
+	}
+	return nil
+}
+
+// registerPersonalExecutors registers the clock, calendars, lists, notes,
+// memory, and the executors that look things up or send mail
+func registerPersonalExecutors(ctx context.Context, o *options, a *agent) error {
+	gw, logger := a.gw, a.logger
+	var env secretEnv
+
+	var home *time.Location
+	if o.timezone != "" {
+		loc, err := time.LoadLocation(o.timezone)
+		if err != nil {
+			return fmt.Errorf("invalid -timezone: %w", err)
+		}
+		home = loc
+	}
+	gw.RegisterExecutor(clock.NewExecutor(clock.Config{Location: home}))
+
+	gw.RegisterExecutor(convert.NewExecutor(convert.Config{
+		RatesFile: filepath.Join(o.dataDir, "ecb-rates.json"),
+	}))
+
+	if o.newsFeeds != "" {
+		var feeds []news.Feed
+		for _, url := range splitList(o.newsFeeds) {
+			feeds = append(feeds, news.Feed{URL: url})
+		}
+		gw.RegisterExecutor(news.NewExecutor(news.Config{Feeds: feeds}))
+	}
+
+	if o.translateURL != "" {
+		gw.RegisterExecutor(translate.NewExecutor(translate.Config{
+			URL:    o.translateURL,
+			APIKey: env.get("TRANSLATE_API_KEY"),
+		}))
+	}
+
+	if lists, err := shopping.NewExecutor(filepath.Join(o.dataDir, "shopping.json")); err != nil {
+		logger.Warn("Shopping lists unavailable", "error", err)
+	} else {
+		if url := os.Getenv("SHOPPING_CALDAV_URL"); url != "" {
+			lists.SetSyncAdapter(&shopping.CalDAV{
+				BaseURL:  url,
+				Username: env.get("SHOPPING_CALDAV_USERNAME"),
+				Password: env.get("SHOPPING_CALDAV_PASSWORD"),
+			})
+		}
+		gw.RegisterExecutor(lists)
+	}
+
+	if tasks, err := todo.NewExecutor(filepath.Join(o.dataDir, "todo.json")); err != nil {
+		logger.Warn("Task lists unavailable", "error", err)
+	} else {
+		if url := os.Getenv("TODO_CALDAV_URL"); url != "" {
+			tasks.SetSyncAdapter(&todo.CalDAV{
+				BaseURL:  url,
+				Username: env.get("TODO_CALDAV_USERNAME"),
+				Password: env.get("TODO_CALDAV_PASSWORD"),
+			})
+		}
+		gw.RegisterExecutor(tasks)
+	}
+
+	var noteStore notes.Store
+	var err error
+	if o.notesDir != "" {
+		noteStore, err = notes.OpenMarkdownDir(o.notesDir)
+	} else {
+		noteStore, err = notes.OpenFile(filepath.Join(o.dataDir, "notes.json"))
+	}
+	if err != nil {
+		logger.Warn("Notes unavailable", "error", err)
+	} else {
+		gw.RegisterExecutor(notes.NewExecutor(noteStore))
+	}
+
+	if o.calendars != "" || o.icsFeeds != "" {
+		cfg := calendar.Config{Location: home}
+		for _, entry := range splitList(o.calendars) {
+			name, url, _ := strings.Cut(entry, "=")
+			cfg.Calendars = append(cfg.Calendars, calendar.Calendar{
+				Name:     name,
+				URL:      url,
+				Username: env.get("CALDAV_USERNAME"),
+				Password: env.get("CALDAV_PASSWORD"),
+			})
+		}
+		for _, entry := range splitList(o.icsFeeds) {
+			name, url, _ := strings.Cut(entry, "=")
+			cfg.Calendars = append(cfg.Calendars, calendar.Calendar{Name: name, URL: url, Feed: true})
+		}
+		if cal, err := calendar.NewExecutor(cfg); err != nil {
+			logger.Warn("Calendar unavailable", "error", err)
+		} else {
+			gw.RegisterExecutor(cal)
+		}
+	}
+
+	// Facts are always kept; activity is only recorded with an embedding model
+	var embedder memory.Embedder
+	if o.memoryEmbedURL != "" {
+		embedder = memory.NewOllama(o.memoryEmbedURL, o.memoryModel)
+	}
+	if mem, err := memory.NewExecutor(memory.Config{
+		StoreFile: filepath.Join(o.dataDir, "memory.json"),
+		FactsFile: filepath.Join(o.dataDir, "memory-facts.json"),
+	}, embedder, a.bus); err != nil {
+		logger.Warn("Memory unavailable", "error", err)
+	} else {
+		mem.SetLogger(a.logs.Logger("executor:memory"))
+		gw.RegisterExecutor(mem)
+		mem.Start(ctx)
+	}
+
+	if o.documentsDir != "" {
+		docs, err := documents.NewExecutor(documents.Config{
+			Dir:       o.documentsDir,
+			IndexFile: filepath.Join(o.dataDir, "documents-index.json"),
+		})
+		if err != nil {
+			logger.Warn("Documents unavailable", "error", err)
+		} else {
+			gw.RegisterExecutor(docs)
+			if err := docs.Start(ctx); err != nil {
+				logger.Warn("Document watcher failed to start", "error", err)
+			}
+		}
+	}
+
+	if o.gtfsPath != "" {
+		timetable, err := transit.LoadGTFS(o.gtfsPath)
+		if err != nil {
+			logger.Warn("Failed to load GTFS feed", "error", err)
+		} else {
+			backends := []transit.Backend{timetable}
+			if o.gtfsRealtime != "" {
+				backends = []transit.Backend{transit.NewRealtime(timetable, o.gtfsRealtime, 30*time.Second), timetable}
+			}
+			gw.RegisterExecutor(transit.NewExecutor(timetable, backends...))
+		}
+	}
+
+	// Credentials come from the environment so they stay off the command line
+	if server := os.Getenv("DELIVERIES_IMAP_SERVER"); server != "" {
+		tracker := deliveries.NewExecutor(deliveries.Config{
+			Server:   server,
+			Username: env.get("DELIVERIES_IMAP_USERNAME"),
+			Password: env.get("DELIVERIES_IMAP_PASSWORD"),
+		}, a.bus)
+		gw.RegisterExecutor(tracker)
+		tracker.Start(ctx)
+	}
+
+	if smtpServer, imapServer := os.Getenv("SMTP_SERVER"), os.Getenv("EMAIL_IMAP_SERVER"); smtpServer != "" || imapServer != "" {
+		var templates map[string]*email.Template
+		if o.emailTemplates != "" {
+			var err error
+			if templates, err = email.LoadTemplates(o.emailTemplates); err != nil {
+				return fmt.Errorf("failed to load email templates: %w", err)
+			}
+		}
+		mailer, err := email.NewExecutor(email.Config{
+			SMTP: email.SMTP{
+				Server:   smtpServer,
+				Username: env.get("SMTP_USERNAME"),
+				Password: env.get("SMTP_PASSWORD"),
+				From:     os.Getenv("EMAIL_FROM"),
+			},
+			IMAP: email.IMAP{
+				Server:   imapServer,
+				Username: env.get("EMAIL_IMAP_USERNAME"),
+				Password: env.get("EMAIL_IMAP_PASSWORD"),
+			},
+			Templates:     templates,
+			AttachmentDir: o.emailAttachments,
+		})
+		if err != nil {
+			logger.Warn("Email unavailable", "error", err)
+		} else {
+			gw.RegisterExecutor(mailer)
+		}
+	}
+	return env.err
+}
+
+// registerWeather registers forecasts and warnings. Warnings are shown,
+// and severe ones run the intents in -severe-weather-intents.
+func registerWeather(ctx context.Context, o *options, a *agent) error {
+	var env secretEnv
+	var forecasts weather.Provider
+	switch o.weatherProvider {
+	case "open-meteo":
+		forecasts = &weather.OpenMeteo{}
+	case "openweathermap":
+		forecasts = &weather.OpenWeatherMap{APIKey: env.get("OPENWEATHERMAP_API_KEY")}
+	default:
+		return fmt.Errorf("unknown weather provider: %s", o.weatherProvider)
+	}
+	if env.err != nil {
+		return env.err
+	}
+	var warnings []weather.AlertSource
+	for _, feed := range splitList(o.weatherAlerts) {
+		switch name, country, _ := strings.Cut(feed, ":"); name {
+		case "nws":
+			warnings = append(warnings, &weather.NWS{})
+		case "meteoalarm":
+			warnings = append(warnings, &weather.MeteoAlarm{Country: country, Areas: splitList(o.weatherAlertAreas)})
+		default:
+			return fmt.Errorf("unknown weather alert feed: %s", feed)
+		}
+	}
+	var severeIntents []struct {
+		IntentType string                 `json:"intent_type"`
+		Parameters map[string]interface{} `json:"parameters"`
+	}
+	if o.severeWeatherIntents != "" {
+		data, err := os.ReadFile(o.severeWeatherIntents)
+		if err == nil {
+			err = json.Unmarshal(data, &severeIntents)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load severe weather intents: %w", err)
+		}
+	}
+
+	w, err := weather.NewExecutor(weather.Config{
+		Provider: forecasts,
+		Location: o.weatherLocation,
+		Units:    weather.Units(o.weatherUnits),
+		Alerts:   warnings,
+	}, a.bus)
+	if err != nil {
+		a.logger.Warn("Weather unavailable", "error", err)
+		return nil
+	}
+	a.gw.RegisterExecutor(w)
+	w.Start(ctx)
+	a.bus.Subscribe("weather.alert", func(e events.Event) {
+		message, _ := e.Data["message"].(string)
+		severe, _ := e.Data["severe"].(bool)
+		go func() {
+			n := executor.Notification{Title: "Weather warning", Message: message}
+			if severe {
+				n.Urgency = "critical"
+			}
+			a.notify(ctx, e.Type, n)
+			if !severe {
+				return
+			}
+			for _, si := range severeIntents {
+				data, err := intent.New(si.IntentType).
+					Params(si.Parameters).
+					Reasoning("Severe weather warning: "+message).
+					Origin(intent.OriginAutomation, "severe-weather", "Severe weather").
+					JSON()
+				var result *gateway.ExecutionResult
+				if err == nil {
+					result, err = a.gw.ProcessIntent(ctx, data)
+				}
+				if err != nil {
+					a.logger.Warn("Severe weather intent failed", "intent_type", si.IntentType, "error", err)
+				} else if !result.Success {
+					a.logger.Warn("Severe weather intent failed", "intent_type", si.IntentType, "error", result.Error)
+				}
+			}
+		}()
+	})
+	return nil
+}
+
+// registerDesktopExecutors registers the executors for this computer's
+// desktop: sound, windows, input, the clipboard, browsing, printing,
+// sharing, the photo frame, and media playback
+func registerDesktopExecutors(ctx context.Context, o *options, a *agent) error {
+	gw, logger := a.gw, a.logger
+	var env secretEnv
+
+	if o.microphone != "" {
+		monitor := sound.NewExecutor(sound.Config{Device: o.microphone}, a.bus)
+		gw.RegisterExecutor(monitor)
+		if err := monitor.Start(ctx); err != nil {
+			logger.Warn("Sound monitor failed to start", "error", err)
+		}
+	}
+
+	if sound, err := audio.NewExecutor(audio.Config{MaxVolume: o.audioMaxVolume}); err != nil {
+		logger.Warn("Audio control unavailable", "error", err)
+	} else {
+		gw.RegisterExecutor(sound)
+	}
+
+	if bt, err := bluetooth.NewExecutor(bluetooth.Config{Adapter: o.bluetoothAdapter}); err != nil {
+		logger.Warn("Bluetooth unavailable", "error", err)
+	} else {
+		gw.RegisterExecutor(bt)
+	}
+
+	if browse, err := browser.NewExecutor(browser.Config{
+		Schemes: splitList(o.browserSchemes),
+		Fetch:   o.browserFetch,
+	}); err != nil {
+		logger.Warn("Browser unavailable", "error", err)
+	} else {
+		gw.RegisterExecutor(browse)
+	}
+
+	if windows, err := window.NewExecutor(); err != nil {
+		logger.Warn("Window management unavailable", "error", err)
+	} else {
+		gw.RegisterExecutor(windows)
+	}
+
+	if o.inputEnabled {
+		if in, err := input.NewExecutor(input.Config{MaxText: o.inputMaxText}); err != nil {
+			logger.Warn("Input automation unavailable", "error", err)
+		} else {
+			gw.RegisterExecutor(in)
+			logger.Info("Input automation enabled", "backend", in.Backend())
+		}
+	}
+
+	if clip, err := clipboard.NewExecutor(clipboard.Config{MaxBytes: o.clipboardMaxSize}); err != nil {
+		logger.Warn("Clipboard unavailable", "error", err)
+	} else {
+		gw.RegisterExecutor(clip)
+	}
+
+	if o.printers != "" {
+		var queues []printer.Printer
+		for _, spec := range splitList(o.printers) {
+			name, uri, ok := strings.Cut(spec, "=")
+			if !ok {
+				return fmt.Errorf("invalid printer %q: want name=uri", spec)
+			}
+			queues = append(queues, printer.Printer{Name: name, URI: uri})
+		}
+		printing, err := printer.NewExecutor(printer.Config{Printers: queues, Dirs: splitList(o.printDirs)})
+		if err != nil {
+			return fmt.Errorf("invalid printers: %w", err)
+		}
+		gw.RegisterExecutor(printing)
+	}
+
+	var links *share.LinkServer
+	if o.shareURL != "" {
+		links = share.NewLinkServer(o.shareAddr, o.shareURL)
+		if err := links.Start(ctx); err != nil {
+			logger.Warn("Share link server failed to start", "error", err)
+			links = nil
+		}
+	}
+	sharer := share.NewExecutor(share.Config{Dir: filepath.Join(o.dataDir, "share")}, links)
+	gw.RegisterExecutor(sharer)
+
+	if o.frameFolders != "" {
+		frames, err := frame.NewExecutor(frame.Config{Folders: splitList(o.frameFolders)})
+		if err != nil {
+			logger.Warn("Photo frame unavailable", "error", err)
+		} else {
+			if o.frameCommand != "" {
+				if local, err := frame.NewCommand("local", o.frameCommand); err != nil {
+					logger.Warn("Invalid -frame-command", "error", err)
+				} else {
+					frames.AddDisplay(local)
+				}
+			}
+			if o.frameChromecasts != "" {
+				media := frame.NewMediaServer(o.frameMediaAddr)
+				if err := media.Start(ctx); err != nil {
+					logger.Warn("Frame media server failed to start", "error", err)
+				} else {
+					for _, entry := range splitList(o.frameChromecasts) {
+						name, host, ok := strings.Cut(entry, "=")
+						if !ok {
+							name, host = entry, entry
+						}
+						frames.AddDisplay(&frame.Chromecast{DisplayName: name, Addr: host, Media: media})
+					}
+				}
+			}
+			gw.RegisterExecutor(frames)
+			sharer.SetScreen(frames)
+			frames.Start(ctx)
+		}
+	}
+
+	var players []media.Backend
+	if mpris, err := media.NewMPRIS(); err != nil {
+		logger.Warn("Local media players unavailable", "error", err)
+	} else {
+		players = append(players, mpris)
+	}
+	if id := os.Getenv("SPOTIFY_CLIENT_ID"); id != "" {
+		players = append(players, &media.Spotify{
+			ClientID:     id,
+			ClientSecret: env.get("SPOTIFY_CLIENT_SECRET"),
+			RefreshToken: env.get("SPOTIFY_REFRESH_TOKEN"),
+		})
+	}
+	if o.mediaChromecasts != "" || o.castDiscovery {
+		casts := &media.Chromecast{Devices: make(map[string]string), Discover: o.castDiscovery}
+		for _, entry := range splitList(o.mediaChromecasts) {
+			name, host, ok := strings.Cut(entry, "=")
+			if !ok {
+				name, host = entry, entry
+			}
+			casts.Devices[name] = host
+		}
+		players = append(players, casts)
+	}
+	if playback, err := media.NewExecutor(players...); err != nil {
+		logger.Warn("Media playback unavailable", "error", err)
+	} else {
+		gw.RegisterExecutor(playback)
+	}
+	return env.err
+}
+
+// registerSystemExecutors registers the executors that manage this
+// machine: files, commands, services, package updates, HTTP requests,
+// GPIO and serial hardware, and system metrics
+func registerSystemExecutors(ctx context.Context, o *options, a *agent, ex *executors) error {
+	gw, logger := a.gw, a.logger
+
+	if o.fileRoots != "" {
+		var roots []files.Root
+		for _, spec := range splitList(o.fileRoots) {
+			name, dir, ok := strings.Cut(spec, "=")
+			if !ok {
+				return fmt.Errorf("invalid file root %q: want name=dir", spec)
+			}
+			dir, writable := strings.CutSuffix(dir, ":rw")
+			roots = append(roots, files.Root{Name: name, Path: dir, Writable: writable})
+		}
+		fileExec, err := files.NewExecutor(files.Config{Roots: roots, MaxSize: o.fileMaxSize})
+		if err != nil {
+			return fmt.Errorf("invalid file roots: %w", err)
+		}
+		a.onExit(func() { fileExec.Close() })
+		gw.RegisterExecutor(fileExec)
+	}
+
+	if o.systemCommands != "" {
+		commands, err := system.LoadCommands(o.systemCommands)
+		if err != nil {
+			return fmt.Errorf("failed to load system commands: %w", err)
+		}
+		runner, err := system.NewExecutor(system.Config{Commands: commands, Root: filepath.Join(o.dataDir, "system")})
+		if err != nil {
+			return fmt.Errorf("invalid system commands: %w", err)
+		}
+		gw.RegisterExecutor(runner)
+	}
+
+	if packages, err := updates.NewExecutor(updates.Config{Backend: o.updatesBackend, Sudo: o.updatesSudo}); err != nil {
+		logger.Warn("Package updates unavailable", "error", err)
+	} else {
+		gw.RegisterExecutor(packages)
+	}
+
+	if o.services != "" {
+		if units, err := service.NewExecutor(service.Config{Units: splitList(o.services), User: o.serviceUser}); err != nil {
+			logger.Warn("Service control unavailable", "error", err)
+		} else {
+			gw.RegisterExecutor(units)
+		}
+	}
+
+	if o.httpHosts != "" {
+		hosts, err := httpreq.LoadHosts(o.httpHosts)
+		if err != nil {
+			return fmt.Errorf("failed to load http.request hosts: %w", err)
+		}
+		requests, err := httpreq.NewExecutor(httpreq.Config{Hosts: hosts})
+		if err != nil {
+			return fmt.Errorf("invalid http.request hosts: %w", err)
+		}
+		gw.RegisterExecutor(requests)
+	}
+
+	if o.hardware != "" {
+		pins, err := gpio.LoadPins(o.hardware)
+		if err != nil {
+			return fmt.Errorf("invalid hardware file: %w", err)
+		}
+		ports, err := serial.LoadPorts(o.hardware)
+		if err != nil {
+			return fmt.Errorf("invalid hardware file: %w", err)
+		}
+		if len(pins) > 0 {
+			if lines, err := gpio.NewExecutor(pins); err != nil {
+				logger.Warn("GPIO unavailable", "error", err)
+			} else {
+				a.onExit(func() { lines.Close() })
+				gw.RegisterExecutor(lines)
+				ex.gpio = lines
+			}
+		}
+		if len(ports) > 0 {
+			if uart, err := serial.NewExecutor(ports); err != nil {
+				logger.Warn("Serial ports unavailable", "error", err)
+			} else {
+				a.onExit(func() { uart.Close() })
+				gw.RegisterExecutor(uart)
+			}
+		}
+	}
+
+	var thresholds []sysinfo.Threshold
+	if o.systemThresholds != "" {
+		var err error
+		if thresholds, err = sysinfo.LoadThresholds(o.systemThresholds); err != nil {
+			return fmt.Errorf("failed to load system thresholds: %w", err)
+		}
+	}
+	host, err := sysinfo.NewExecutor(sysinfo.Config{Disks: splitList(o.systemDisks), Thresholds: thresholds}, a.bus)
+	if err != nil {
+		return fmt.Errorf("invalid system thresholds: %w", err)
+	}
+	host.SetLogger(a.logs.Logger("executor:sysinfo"))
+	gw.RegisterExecutor(host)
+	host.Start(ctx)
+	ex.host = host
+	// Crossed thresholds are shown and run the intents configured for them
+	a.bus.Subscribe("system.threshold", func(e events.Event) {
+		name, _ := e.Data["name"].(string)
+		message, _ := e.Data["message"].(string)
+		go func() {
+			a.notify(ctx, e.Type, executor.Notification{Title: "System", Message: message})
+			for _, t := range host.Thresholds() {
+				if t.Name != name {
+					continue
+				}
+				for _, action := range t.Intents {
+					data, err := intent.New(action.IntentType).
+						Params(action.Parameters).
+						Reasoning(message).
+						Origin(intent.OriginAutomation, "threshold:"+name, name).
+						JSON()
+					var result *gateway.ExecutionResult
+					if err == nil {
+						result, err = gw.ProcessIntent(ctx, data)
+					}
+					if err != nil {
+						logger.Warn("Threshold intent failed", "intent_type", action.IntentType, "error", err)
+					} else if !result.Success {
+						logger.Warn("Threshold intent failed", "intent_type", action.IntentType, "error", result.Error)
+					}
+				}
+			}
+		}()
+	})
+	return nil
+}
+
+// registerHomeExecutors registers the smart home: lights, MQTT, Zigbee,
+// and Z-Wave devices, cameras, presence, and the firmware updates and
+// upkeep of devices
+func registerHomeExecutors(ctx context.Context, o *options, a *agent, ex *executors) error {
+	gw, logger, devices := a.gw, a.logger, a.devices
+	var env secretEnv
+
+	if lights, err := hue.NewExecutor(hue.Config{
+		StatePath: filepath.Join(o.dataDir, "hue.json"),
+		Address:   o.hueBridge,
+	}, devices); err != nil {
+		logger.Warn("Hue unavailable", "error", err)
+	} else {
+		gw.RegisterExecutor(lights)
+	}
+
+	// Backends that can update their devices' firmware, by module
+	updaters := make(map[string]firmware.Updater)
+
+	if o.mqttDevices != "" {
+		deviceMap, err := mqtt.LoadDevices(o.mqttDevices)
+		if err != nil {
+			return fmt.Errorf("failed to load MQTT devices: %w", err)
+		}
+		things, err := mqtt.NewExecutor(mqtt.Config{
+			Broker:   o.mqttBroker,
+			Username: env.get("MQTT_USERNAME"),
+			Password: env.get("MQTT_PASSWORD"),
+			Devices:  deviceMap,
+		}, devices)
+		if env.err != nil {
+			return env.err
+		}
+		if err != nil {
+			logger.Warn("MQTT devices unavailable", "error", err)
+		} else {
+			things.SetLogger(a.logs.Logger("executor:mqtt"))
+			gw.RegisterExecutor(things)
+			things.Start(ctx)
+			updaters["mqtt"] = things
+		}
+	}
+
+	if o.zigbeeBroker != "" {
+		zb, err := zigbee.NewExecutor(zigbee.Config{
+			Broker:    o.zigbeeBroker,
+			Username:  env.get("MQTT_USERNAME"),
+			Password:  env.get("MQTT_PASSWORD"),
+			BaseTopic: o.zigbeeTopic,
+		}, devices)
+		if env.err != nil {
+			return env.err
+		}
+		if err != nil {
+			return fmt.Errorf("invalid Zigbee2MQTT settings: %w", err)
+		}
+		zb.SetLogger(a.logs.Logger("executor:zigbee"))
+		gw.RegisterExecutor(zb)
+		zb.Start(ctx)
+	}
+
+	if o.zwaveServer != "" {
+		zw, err := zwave.NewExecutor(zwave.Config{URL: o.zwaveServer}, devices)
+		if err != nil {
+			return fmt.Errorf("invalid zwave-js-server settings: %w", err)
+		}
+		zw.SetLogger(a.logs.Logger("executor:zwave"))
+		gw.RegisterExecutor(zw)
+		zw.Start(ctx)
+	}
+
+	if o.presenceFile != "" {
+		file, err := presence.LoadFile(o.presenceFile)
+		if err != nil {
+			return fmt.Errorf("failed to load presence file: %w", err)
+		}
+		people, err := presence.NewExecutor(presence.Config{
+			File:             file,
+			Username:         env.get("MQTT_USERNAME"),
+			Password:         env.get("MQTT_PASSWORD"),
+			BluetoothAdapter: o.bluetoothAdapter,
+		}, a.bus)
+		if env.err != nil {
+			return env.err
+		}
+		if err != nil {
+			return fmt.Errorf("invalid presence settings: %w", err)
+		}
+		people.SetLogger(a.logs.Logger("executor:presence"))
+		gw.RegisterExecutor(people)
+		people.Start(ctx)
+		ex.whoIsHome = people
+	}
+
+	if o.camerasFile != "" {
+		cameras, err := camera.LoadCameras(o.camerasFile)
+		if err != nil {
+			return fmt.Errorf("failed to load cameras: %w", err)
+		}
+		dir := o.cameraSnapshots
+		if dir == "" {
+			dir = filepath.Join(o.dataDir, "snapshots")
+		}
+		if cams, err := camera.NewExecutor(camera.Config{Cameras: cameras, Dir: dir, Keep: o.cameraKeep}, devices); err != nil {
+			logger.Warn("Cameras unavailable", "error", err)
+		} else {
+			gw.RegisterExecutor(cams)
+		}
+	}
+
+	if len(updaters) > 0 {
+		flasher, err := firmware.NewExecutor(firmware.Config{
+			Updaters:      updaters,
+			HealthTimeout: o.firmwareHealthTimeout,
+		}, devices, gw.Exclusions(), a.bus)
+		if err != nil {
+			logger.Warn("Firmware updates unavailable", "error", err)
+		} else {
+			flasher.SetLogger(a.logs.Logger("executor:firmware"))
+			flasher.Start(ctx)
+			gw.RegisterExecutor(flasher)
+			// Failed updates need a person, so they're shown as well
+			a.bus.Subscribe("firmware.update_failed", func(e events.Event) {
+				message, _ := e.Data["message"].(string)
+				go a.notify(ctx, e.Type, executor.Notification{Title: "Firmware update failed", Message: message, Urgency: "critical"})
+			})
+		}
+	}
+
+	var schedules []maintenance.Schedule
+	if o.maintenanceFile != "" {
+		var err error
+		if schedules, err = maintenance.LoadSchedules(o.maintenanceFile); err != nil {
+			return fmt.Errorf("failed to load maintenance schedules: %w", err)
+		}
+	}
+	upkeep, err := maintenance.NewExecutor(maintenance.Config{
+		Schedules:  schedules,
+		StateFile:  filepath.Join(o.dataDir, "maintenance.json"),
+		LowBattery: o.lowBattery,
+	}, devices, a.bus)
+	if err != nil {
+		logger.Warn("Maintenance tracking unavailable", "error", err)
+		return nil
+	}
+	upkeep.SetLogger(a.logs.Logger("executor:maintenance"))
+	gw.RegisterExecutor(upkeep)
+	upkeep.Start(ctx)
+	// Upkeep reminders go to the desktop as well as the event stream
+	a.bus.Subscribe("device.*", func(e events.Event) {
+		if e.Type != "device.battery_low" && e.Type != "device.maintenance_due" {
+			return
+		}
+		message, _ := e.Data["message"].(string)
+		go a.notify(ctx, e.Type, executor.Notification{Title: "Maintenance", Message: message})
+	})
+	return nil
+}
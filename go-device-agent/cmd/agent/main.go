@@ -3,64 +3,389 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
+	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
 	"syscall"
+	"time"
 
-	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/access"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/config"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/daemon"
 	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/logging"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/mtls"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/secrets"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/transport"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/tui"
 )
 
 func main() {
-	logger := log.New(os.Stdout, "[device-agent] ", log.LstdFlags)
-	logger.Println("Starting device agent...")
+	// Set by one-shot commands; deferred first so it runs after every
+	// other deferred cleanup
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
 
-	// Create intent gateway
-	gw := gateway.NewGateway(logger)
+	o := registerFlags()
+	flag.Usage = usage
+	flag.Parse()
+	// The command may come before or after the flags
+	command := "serve"
+	if flag.NArg() > 0 {
+		command = flag.Arg(0)
+		flag.CommandLine.Parse(flag.Args()[1:])
+	}
+	if o.chdir != "" {
+		if err := os.Chdir(o.chdir); err != nil {
+			log.Fatal(err)
+		}
+	}
+	switch command {
+	case "serve", "exec", "validate", "capabilities", "repl":
+	case "install-service", "uninstall-service", "start-service", "stop-service":
+		exitCode = runService(command, o.serviceName, o.serviceScope, o.dryRun)
+		return
+	case "version":
+		printVersion()
+		return
+	case "help":
+		flag.CommandLine.SetOutput(os.Stdout)
+		flag.Usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", command)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if (command == "exec" || command == "validate") && flag.NArg() != 1 {
+		log.Fatalf("%s needs one intent file, or - for stdin", command)
+	}
+
+	reloader, err := config.NewReloader(o.configPath, flag.CommandLine, configSchema, o.profileName)
+	if err != nil {
+		log.Fatalf("Invalid config file:\n%v", err)
+	}
+	// One-shot commands print to stdout and leave the terminal alone
+	oneShot := command != "serve"
+	if oneShot {
+		o.pipeMode, o.tuiMode = false, false
+	}
+
+	a, err := newAgent(o, oneShot)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer a.close()
+	logger := a.logger
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	a.onExit(stop)
+	if a.winService != nil {
+		a.onExit(a.winService.Exit)
+		go func() {
+			<-a.winService.Stopping()
+			stop()
+		}()
+	}
+
+	if err := setupSandbox(o, a); err != nil {
+		a.fatal("Startup failed", err)
+	}
+	if err := setupSignatures(o, a); err != nil {
+		a.fatal("Startup failed", err)
+	}
+	auth, pipeClient, err := setupAuth(ctx, o, a)
+	if err != nil {
+		a.fatal("Startup failed", err)
+	}
+	if err := setupTracing(ctx, o, a); err != nil {
+		a.fatal("Startup failed", err)
+	}
+	if err := setupAudit(ctx, o, a); err != nil {
+		a.fatal("Startup failed", err)
+	}
+	ex, err := setupExecutors(ctx, o, a, reloader)
+	if err != nil {
+		a.fatal("Startup failed", err)
+	}
+	keyboard, err := setupApprovals(ctx, o, a, ex)
+	if err != nil {
+		a.fatal("Startup failed", err)
+	}
+	var names []string
+	for _, e := range a.gw.GetExecutors() {
+		names = append(names, e.Name())
+	}
+	slices.Sort(names)
+	logger.Info("Device agent ready", "executors", names)
+
+	if err := setupPolicies(o, a, ex); err != nil {
+		a.fatal("Startup failed", err)
+	}
+	t, err := setupTransports(o, a, auth, ex)
+	if err != nil {
+		a.fatal("Startup failed", err)
+	}
+	if o.sandboxEnforce {
+		if err := enforceSandbox(o, a); err != nil {
+			a.fatal("Startup failed", err)
+		}
+	}
+	watchConfig(ctx, o, a, reloader, t.server, ex.whoIsHome)
+
+	switch command {
+	case "exec":
+		exitCode = runExec(ctx, a.gw, flag.Arg(0), o.codecName, logger)
+		return
+	case "validate":
+		exitCode = runValidate(ctx, a.gw, flag.Arg(0), o.codecName, logger)
+		return
+	case "capabilities":
+		if !printJSON(a.gw.Capabilities(), logger) {
+			exitCode = 1
+		}
+		return
+	case "repl":
+		if err := tui.NewREPL(a.gw).Run(ctx, os.Stdin, os.Stdout); err != nil {
+			a.fatal("REPL failed", err)
+		}
+		return
+	}
+
+	t.serveAdminSocket(ctx, o, a)
+	if o.pipeMode {
+		codec, ok := intent.CodecForName(o.codecName)
+		if !ok {
+			a.fatal("Unknown codec", fmt.Errorf("no codec named %q", o.codecName))
+		}
+		if err := runPipe(ctx, a.gw, codec, o.announce, pipeClient, a.logs); err != nil {
+			a.fatal("Pipe transport failed", err)
+		}
+		return
+	}
+	t.serveHTTP(ctx, o, a)
+
+	// Under systemd, say the agent is up and keep the watchdog fed while the
+	// gateway answers
+	daemon.Notify("READY=1")
+	daemon.Watchdog(ctx, func() bool { return a.gw.Mode() != "" })
+	go func() {
+		<-ctx.Done()
+		daemon.Notify("STOPPING=1")
+	}()
+
+	if o.tuiMode {
+		dashboard := tui.New(a.gw, a.bus, keyboard)
+		dashboard.SetNote("Logging to " + a.logPath)
+		if err := dashboard.Run(ctx, os.Stdin, os.Stdout); err != nil {
+			a.fatal("Dashboard failed", err)
+		}
+		logger.Info("Dashboard closed, shutting down device agent")
+		return
+	}
 
-	// Register executors
-	gw.RegisterExecutor(executor.NewDeviceExecutor())
-	gw.RegisterExecutor(executor.NewNotificationExecutor())
-	gw.RegisterExecutor(executor.NewMockExecutor("time", []string{"time.query"}))
-	gw.RegisterExecutor(executor.NewMockExecutor("weather", []string{"weather.query"}))
+	runDemo(ctx, a.gw, logger)
+}
+
+// defaultDataDir keeps local state in the user's config directory
+func defaultDataDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "data"
+	}
+	return filepath.Join(dir, "local-agent-core")
+}
 
-	logger.Println("Device agent ready. Registered executors:")
-	for _, e := range gw.GetExecutors() {
-		logger.Printf("  - %s: %v", e.Name(), e.SupportedActions())
+// openSecrets builds the secret store: the encrypted file if one is given,
+// then the OS keychain if enabled, then SECRET_* environment variables
+func openSecrets(path, passphraseFile string, keychain bool) (*secrets.Store, error) {
+	var backends []secrets.Backend
+	if path != "" {
+		passphrase := os.Getenv("AGENT_SECRETS_PASSPHRASE")
+		if passphraseFile != "" {
+			data, err := os.ReadFile(passphraseFile)
+			if err != nil {
+				return nil, err
+			}
+			passphrase = strings.TrimRight(string(data), "\r\n")
+		}
+		file, err := secrets.OpenFile(path, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, file)
 	}
+	if keychain {
+		backends = append(backends, secrets.Keychain{})
+	}
+	return secrets.New(append(backends, secrets.Env{})...), nil
+}
 
+// serverTLS loads or creates the CA in dir, issues the agent's server
+// certificate if it is missing or due for renewal, and keeps renewing it
+// daily; the transport picks up the new certificate without a restart
+func serverTLS(ctx context.Context, dir, hosts, pins string, logger *slog.Logger) (*tls.Config, error) {
+	ca, err := mtls.LoadOrCreateCA(dir, "device-agent CA")
+	if err != nil {
+		return nil, err
+	}
+	req := mtls.Request{Name: "device-agent", Hosts: splitList(hosts)}
+	if len(req.Hosts) == 0 {
+		req.Hosts = []string{"localhost", "127.0.0.1"}
+		if name, err := os.Hostname(); err == nil {
+			req.Hosts = append(req.Hosts, name)
+		}
+	}
+	certPath, keyPath, issued, err := ca.EnsureCert(dir, req)
+	if err != nil {
+		return nil, err
+	}
+	if issued {
+		logger.Info("Issued TLS certificate", "path", certPath, "hosts", req.Hosts)
+	}
+	go func() {
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, _, issued, err := ca.EnsureCert(dir, req); err != nil {
+					logger.Error("TLS certificate renewal failed", "error", err)
+				} else if issued {
+					logger.Info("Renewed TLS certificate", "path", certPath)
+				}
+			}
+		}
+	}()
+	return mtls.ServerConfig{
+		CAFile:   filepath.Join(dir, mtls.CACertFile),
+		CertFile: certPath,
+		KeyFile:  keyPath,
+		Pins:     splitList(pins),
+	}.TLSConfig()
+}
+
+// loopbackAddr reports whether a listen address only accepts connections
+// from this machine
+func loopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseLatencyPolicy parses -degraded-p95: a default limit and
+// executor=limit overrides
+func parseLatencyPolicy(value string) (gateway.LatencyPolicy, error) {
+	latency := gateway.LatencyPolicy{Executors: make(map[string]time.Duration)}
+	for _, entry := range splitList(value) {
+		name, value, found := strings.Cut(entry, "=")
+		if !found {
+			name, value = "", entry
+		}
+		limit, err := time.ParseDuration(value)
+		if err != nil {
+			return latency, fmt.Errorf("%q: %w", entry, err)
+		}
+		if name == "" {
+			latency.P95 = limit
+		} else {
+			latency.Executors[name] = limit
+		}
+	}
+	return latency, nil
+}
+
+// loadSafetyPolicy reads -safety-policy, or returns the built-in policy
+// without one
+func loadSafetyPolicy(path string) (gateway.SafetyPolicy, error) {
+	if path == "" {
+		return gateway.ChildSafetyPolicy(), nil
+	}
+	var policy gateway.SafetyPolicy
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, &policy)
+	}
+	return policy, err
+}
+
+// runPipe serves intents over stdin/stdout until stdin closes or a signal arrives
+func runPipe(ctx context.Context, gw *gateway.Gateway, codec intent.Codec, announce bool, identity *access.Identity, logs *logging.Logs) error {
+	logger := logs.Logger("agent")
+	logger.Info("Serving intents on stdin/stdout", "codec", codec.Name())
+	pipe := transport.NewPipe(gw, os.Stdin, os.Stdout, logs.Logger("transport"))
+	pipe.SetCodec(codec)
+	pipe.SetAnnounce(announce)
+	if identity != nil {
+		pipe.SetIdentity(identity)
+	}
+	if err := pipe.Serve(ctx); err != nil && ctx.Err() == nil {
+		return err
+	}
+	logger.Info("Input closed, shutting down device agent")
+	return nil
+}
+
+// runDemo processes a sample intent and waits for a shutdown signal
+func runDemo(ctx context.Context, gw *gateway.Gateway, logger *slog.Logger) {
 	// Example: Process a sample intent
-	sampleIntent := `{
-		"id": "550e8400-e29b-41d4-a716-446655440000",
-		"intent_type": "device.control",
-		"confidence": 0.9,
-		"parameters": {
-			"device": "living_room_light",
-			"action": "on"
-		},
-		"reasoning": "User wants to turn on the living room light",
-		"requires_permission": true,
-		"target_module": "device",
-		"created_at": "2026-01-03T15:00:00Z"
-	}`
-
-	logger.Println("\nProcessing sample intent...")
-	ctx := context.Background()
-	result, err := gw.ProcessIntent(ctx, []byte(sampleIntent))
+	sampleIntent, err := intent.New("device.control").
+		Param("device", "living_room_light").
+		Param("action", "on").
+		Confidence(0.9).
+		Reasoning("User wants to turn on the living room light").
+		RequirePermission().
+		JSON()
+	if err != nil {
+		logger.Error("Failed to build sample intent", "error", err)
+		return
+	}
+
+	logger.Info("Processing sample intent")
+	result, err := gw.ProcessIntent(ctx, sampleIntent)
 	if err != nil {
-		logger.Printf("Error processing intent: %v", err)
+		logger.Error("Error processing intent", "error", err)
 	} else {
-		resultJSON, _ := json.MarshalIndent(result, "", "  ")
-		logger.Printf("Result:\n%s", string(resultJSON))
+		resultJSON, _ := json.Marshal(result)
+		logger.Info("Sample intent processed", "result", string(resultJSON))
 	}
 
 	// Wait for interrupt signal
-	logger.Println("\nDevice agent running. Press Ctrl+C to exit.")
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	<-sigChan
+	logger.Info("Device agent running. Press Ctrl+C to exit.")
+	<-ctx.Done()
 
-	logger.Println("\nShutting down device agent...")
+	logger.Info("Shutting down device agent")
 }
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/daemon"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/logging"
+)
+
+// options are the agent's flags, which the config file may set too
+type options struct {
+	chdir                 string
+	configPath            string
+	profileName           string
+	configReload          time.Duration
+	pipeMode              bool
+	tuiMode               bool
+	codecName             string
+	announce              bool
+	httpAddr              string
+	rolesFile             string
+	secretsFile           string
+	secretsPassFile       string
+	secretsKeychain       bool
+	riskTable             string
+	approvalPush          string
+	approvalCallback      string
+	approvalButton        string
+	challengeConfig       string
+	quotaConfig           string
+	anomalyConfig         string
+	tlsDir                string
+	tlsHosts              string
+	tlsPins               string
+	adminSocket           string
+	rolesReload           time.Duration
+	pipeIdentity          string
+	resultWebhook         string
+	moduleGroups          string
+	degradedP95           string
+	disableExecutors      string
+	gtfsPath              string
+	gtfsRealtime          string
+	microphone            string
+	translateURL          string
+	documentsDir          string
+	memoryEmbedURL        string
+	memoryModel           string
+	frameFolders          string
+	frameCommand          string
+	frameChromecasts      string
+	frameMediaAddr        string
+	mediaChromecasts      string
+	castDiscovery         bool
+	shareURL              string
+	shareAddr             string
+	hueBridge             string
+	fileRoots             string
+	printers              string
+	printDirs             string
+	fileMaxSize           int64
+	audioMaxVolume        int
+	emailTemplates        string
+	emailAttachments      string
+	bluetoothAdapter      string
+	presenceFile          string
+	browserSchemes        string
+	browserFetch          bool
+	inputEnabled          bool
+	inputMaxText          int
+	clipboardMaxSize      int
+	services              string
+	serviceUser           bool
+	updatesBackend        string
+	updatesSudo           bool
+	hardware              string
+	systemCommands        string
+	httpHosts             string
+	systemDisks           string
+	systemThresholds      string
+	mqttDevices           string
+	firmwareHealthTimeout time.Duration
+	maintenanceFile       string
+	lowBattery            float64
+	notifications         string
+	mqttBroker            string
+	zigbeeBroker          string
+	zigbeeTopic           string
+	zwaveServer           string
+	camerasFile           string
+	cameraSnapshots       string
+	cameraKeep            int
+	calendars             string
+	icsFeeds              string
+	timezone              string
+	weatherProvider       string
+	weatherLocation       string
+	weatherUnits          string
+	weatherAlerts         string
+	weatherAlertAreas     string
+	severeWeatherIntents  string
+	newsFeeds             string
+	poolPerHost           int
+	airGapped             bool
+	egressAllow           string
+	poolIdle              time.Duration
+	dataDir               string
+	pidFile               string
+	serviceName           string
+	serviceScope          string
+	dryRun                bool
+	notesDir              string
+	tracingOn             bool
+	otlpEndpoint          string
+	traceService          string
+	historyOn             bool
+	historyRetention      time.Duration
+	auditLog              string
+	auditRotateSize       int64
+	auditRotateInterval   time.Duration
+	auditMaxAge           time.Duration
+	auditMaxFiles         int
+	auditExport           string
+	auditExportInterval   time.Duration
+	auditExportEndpoint   string
+	auditExportRegion     string
+	auditExportRetention  time.Duration
+	auditExportLockMode   string
+	trustedKeys           string
+	requireSignatures     bool
+	signResults           bool
+	encryptedParams       bool
+	payloadKey            string
+	identityKey           string
+	strictIntents         bool
+	idStrategy            string
+	clockSkew             time.Duration
+	sensitiveIntents      string
+	speakerConfidence     float64
+	knownSpeakers         string
+	requireHome           string
+	scenes                string
+	paramTransforms       string
+	redactions            string
+	childSafety           bool
+	safetyPolicy          string
+	modesFile             string
+	startMode             string
+	sandboxPolicy         string
+	sandboxEnforce        bool
+	maxIntentAge          time.Duration
+	logFormat             string
+	logLevel              string
+}
+
+// registerFlags defines the agent's flags on the command line flag set
+func registerFlags() *options {
+	o := &options{}
+	flag.StringVar(&o.chdir, "chdir", "", "change to this directory first, so relative paths in flags and the config file are taken from it")
+	flag.StringVar(&o.configPath, "config", "", "YAML config file of settings grouped into sections (transports, executors, policies, ...); flags given on the command line override it")
+	flag.StringVar(&o.profileName, "profile", "", "profile of -config to start with, such as home or travel, whose settings replace the file's own (switch with profile.switch)")
+	flag.DurationVar(&o.configReload, "config-reload", 10*time.Second, "how often -config and the policy files it names are checked for changes, which apply without a restart where they can (0 disables; SIGHUP reloads at any time)")
+	flag.BoolVar(&o.pipeMode, "pipe", false, "read intent JSON lines from stdin and write results to stdout")
+	flag.BoolVar(&o.tuiMode, "tui", false, "show a live terminal dashboard of intents in flight, executor health, results, and approvals, answering keyboard approvals (see -risk); logs go to agent.log under -data-dir")
+	flag.StringVar(&o.codecName, "codec", "json", "encoding of the pipe and of exec and validate intent files: json, cbor, or protobuf")
+	flag.BoolVar(&o.announce, "announce-capabilities", false, "in pipe mode, write the capability manifest before the first result")
+	flag.StringVar(&o.httpAddr, "http", "", "serve the HTTP transport on this address (e.g. 127.0.0.1:8080)")
+	flag.StringVar(&o.rolesFile, "roles", "", "JSON file of client identities, their API keys or JWT settings, and the intent types their roles allow")
+	flag.StringVar(&o.secretsFile, "secrets", "", "encrypted secrets file that secret:// references are looked up in first; its passphrase is AGENT_SECRETS_PASSPHRASE or -secrets-passphrase-file")
+	flag.StringVar(&o.secretsPassFile, "secrets-passphrase-file", "", "file holding the -secrets passphrase")
+	flag.BoolVar(&o.secretsKeychain, "secrets-keychain", false, "look secret:// references up in the OS keychain after the secrets file")
+	flag.StringVar(&o.riskTable, "risk", "", "JSON risk table of intents held for approval on a second channel, and the approvers each risk level needs")
+	flag.StringVar(&o.approvalPush, "approval-push", "", "ntfy topic URL push approvals are sent to (access token in APPROVAL_PUSH_TOKEN)")
+	flag.StringVar(&o.approvalCallback, "approval-callback", "", "URL the phone reaches the HTTP transport at, for push approvals' buttons")
+	flag.StringVar(&o.approvalButton, "approval-button", "", "GPIO input pin of a physical approval button (see -hardware)")
+	flag.StringVar(&o.challengeConfig, "challenge", "", "JSON config of hashed PINs (see agent-pin) and the intents that need one in their challenge_response parameter")
+	flag.StringVar(&o.quotaConfig, "quotas", "", "JSON config of hourly and daily quotas per intent type; intents beyond them fail with QUOTA_EXCEEDED")
+	flag.StringVar(&o.anomalyConfig, "anomaly", "", "JSON anomaly config; intent bursts far beyond what is usual for the hour are announced, and those of its hold types wait for approval (see -risk)")
+	flag.StringVar(&o.tlsDir, "tls-dir", "", "directory of the mutual TLS CA and the agent's certificate, created on first use; -http then serves HTTPS and requires client certificates")
+	flag.StringVar(&o.tlsHosts, "tls-hosts", "", "comma-separated DNS names and IPs the agent's certificate covers (default this host's name, localhost, and 127.0.0.1)")
+	flag.StringVar(&o.tlsPins, "tls-pin", "", "comma-separated fingerprints of the only client certificate keys accepted (see agent-ca fingerprint)")
+	flag.StringVar(&o.adminSocket, "admin-socket", "", "serve the /v1/admin routes on this Unix socket, without the admin token; only the agent's user can connect")
+	flag.DurationVar(&o.rolesReload, "roles-reload", 10*time.Second, "how often the -roles file is checked for changes, so keys rotate without a restart (0 disables)")
+	flag.StringVar(&o.pipeIdentity, "pipe-identity", "", "with -roles, the identity intents read from the pipe are sent as (default unrestricted)")
+	flag.StringVar(&o.resultWebhook, "result-webhook", "", "with -http, POST async results to this URL, signed with RESULT_WEBHOOK_SECRET")
+	flag.StringVar(&o.moduleGroups, "module-groups", "", "comma-separated name=module+module groups intents can target together (e.g. all-lights=hue+zigbee)")
+	flag.StringVar(&o.degradedP95, "degraded-p95", "30s", "p95 execution latency over which an executor is marked degraded and only takes intents from the user, with per-executor limits (e.g. 30s,firmware=20m; 0 for none)")
+	flag.StringVar(&o.disableExecutors, "disable-executors", "", "comma-separated executors to start disabled (re-enable through the admin API)")
+	flag.StringVar(&o.gtfsPath, "gtfs", "", "GTFS static feed (.zip or directory) for transit queries")
+	flag.StringVar(&o.gtfsRealtime, "gtfs-realtime", "", "GTFS-realtime TripUpdates feed URL")
+	flag.StringVar(&o.microphone, "microphone", "", "ALSA capture device to monitor for sound events (disabled when empty)")
+	flag.StringVar(&o.translateURL, "translate-url", "", "LibreTranslate server for text.translate (e.g. http://127.0.0.1:5000)")
+	flag.StringVar(&o.documentsDir, "documents", "", "folder of scans and PDFs to OCR and index for documents.search")
+	flag.StringVar(&o.memoryEmbedURL, "memory-embed-url", "", "Ollama server used to embed activity for memory.search (e.g. http://127.0.0.1:11434)")
+	flag.StringVar(&o.memoryModel, "memory-model", "nomic-embed-text", "embedding model for memory.search")
+	flag.StringVar(&o.frameFolders, "frame-folders", "", "comma-separated image folders the photo frame may show")
+	flag.StringVar(&o.frameCommand, "frame-command", "", "viewer for a locally connected screen, with {} for the image (e.g. \"feh -F {}\")")
+	flag.StringVar(&o.frameChromecasts, "frame-chromecasts", "", "comma-separated name=host Chromecasts to use as photo frames")
+	flag.StringVar(&o.frameMediaAddr, "frame-media-addr", ":8099", "address serving images to Chromecasts (must be reachable on the LAN)")
+	flag.StringVar(&o.mediaChromecasts, "media-chromecasts", "", "comma-separated name=host Chromecasts for media playback, in addition to those found with mDNS")
+	flag.BoolVar(&o.castDiscovery, "cast-discovery", true, "find Chromecasts for media playback with mDNS")
+	flag.StringVar(&o.shareURL, "share-url", "", "base URL others use to reach share links (e.g. http://192.168.1.10:8098); links are disabled when empty")
+	flag.StringVar(&o.shareAddr, "share-addr", ":8098", "address serving share links")
+	flag.StringVar(&o.hueBridge, "hue-bridge", "", "Hue bridge address, skipping mDNS/SSDP discovery when pairing")
+	flag.StringVar(&o.fileRoots, "file-roots", "", "comma-separated name=dir roots for file.* intents, read-only unless suffixed :rw (e.g. notes=/home/me/notes:rw)")
+	flag.StringVar(&o.printers, "printers", "", "comma-separated name=uri IPP print queues, the first being the default (e.g. office=ipp://localhost:631/printers/office)")
+	flag.StringVar(&o.printDirs, "print-dirs", "", "comma-separated directories print.document may print files from")
+	flag.Int64Var(&o.fileMaxSize, "file-max-size", 1<<20, "largest file file.read and file.write handle, in bytes")
+	flag.IntVar(&o.audioMaxVolume, "audio-max-volume", 100, "loudest volume audio.volume may set, in percent")
+	flag.StringVar(&o.emailTemplates, "email-templates", "", "JSON file of named email.send templates")
+	flag.StringVar(&o.emailAttachments, "email-attachments", "", "directory email.send may attach files from")
+	flag.StringVar(&o.bluetoothAdapter, "bluetooth-adapter", "", "Bluetooth adapter for bluetooth.* intents and presence beacons, e.g. hci1 (default the first)")
+	flag.StringVar(&o.presenceFile, "presence", "", "JSON file of people and the phones, MQTT topics, and Bluetooth beacons that show they're home")
+	flag.StringVar(&o.browserSchemes, "browser-schemes", "http,https", "comma-separated URL schemes browser.open may open (e.g. http,https,mailto)")
+	flag.BoolVar(&o.browserFetch, "browser-fetch", false, "let browser.fetch read public web pages as text")
+	flag.BoolVar(&o.inputEnabled, "input", false, "enable input.type and input.click, which type and click on the desktop; needs -audit-log")
+	flag.IntVar(&o.inputMaxText, "input-max-text", 500, "most characters input.type types at once")
+	flag.IntVar(&o.clipboardMaxSize, "clipboard-max-size", 64<<10, "largest text clipboard.set accepts and clipboard.get returns, in bytes")
+	flag.StringVar(&o.services, "services", "", "comma-separated systemd units or globs (e.g. nginx,backup-*.timer) service.* intents may act on")
+	flag.BoolVar(&o.serviceUser, "service-user", false, "manage the agent user's systemd units instead of the system's")
+	flag.StringVar(&o.updatesBackend, "updates-backend", "", "package manager system.check_updates uses: apt, dnf, pacman, or brew (default the first installed)")
+	flag.BoolVar(&o.updatesSudo, "updates-sudo", false, "run package list refreshes and system.apply_updates through sudo -n when the agent isn't root")
+	flag.StringVar(&o.hardware, "hardware", "", "JSON file of GPIO pins and serial ports gpio.* and serial.write may drive")
+	flag.StringVar(&o.systemCommands, "system-commands", "", "JSON allowlist of commands system.run may execute")
+	flag.StringVar(&o.httpHosts, "http-request-hosts", "", "JSON allowlist of hosts http.request may call, with per-host headers and CA bundles")
+	flag.StringVar(&o.systemDisks, "system-disks", "", "comma-separated mount points system.metrics always reports, besides mounted block devices")
+	flag.StringVar(&o.systemThresholds, "system-thresholds", "", "JSON file of metric thresholds (e.g. disk.used_percent above 90) that raise events and run intents")
+	flag.StringVar(&o.mqttDevices, "mqtt-devices", "", "YAML map of MQTT devices (Tasmota, Zigbee2MQTT) to control")
+	flag.DurationVar(&o.firmwareHealthTimeout, "firmware-health-timeout", 10*time.Minute, "how long a device has to come back on new firmware before its update counts as failed")
+	flag.StringVar(&o.maintenanceFile, "maintenance", "", "JSON file of device service intervals and consumables to track")
+	flag.Float64Var(&o.lowBattery, "low-battery", 20, "battery percentage at or below which device.battery_low is raised")
+	flag.StringVar(&o.notifications, "notifications", "auto", "where notification.send goes: desktop, log, or auto (the desktop when one is reachable)")
+	flag.StringVar(&o.mqttBroker, "mqtt-broker", "", "MQTT broker URL, overriding the device map's (e.g. tcp://localhost:1883)")
+	flag.StringVar(&o.zigbeeBroker, "zigbee2mqtt-broker", "", "MQTT broker Zigbee2MQTT publishes to (e.g. tcp://localhost:1883); its devices are found from the bridge's device list")
+	flag.StringVar(&o.zigbeeTopic, "zigbee2mqtt-topic", "zigbee2mqtt", "Zigbee2MQTT's base topic")
+	flag.StringVar(&o.zwaveServer, "zwave-js-server", "", "zwave-js-server WebSocket URL (e.g. ws://localhost:3000) for controlling Z-Wave devices")
+	flag.StringVar(&o.camerasFile, "cameras", "", "JSON file of IP cameras for camera.snapshot and camera.stream_url, by ONVIF address or snapshot/stream URL")
+	flag.StringVar(&o.cameraSnapshots, "camera-snapshots", "", "directory camera.snapshot saves JPEGs to (default snapshots in -data-dir)")
+	flag.IntVar(&o.cameraKeep, "camera-keep", 100, "snapshots kept per camera; older ones are deleted")
+	flag.StringVar(&o.calendars, "calendars", "", "comma-separated name=url CalDAV calendar collections, signed in with CALDAV_USERNAME and CALDAV_PASSWORD")
+	flag.StringVar(&o.icsFeeds, "ics-feeds", "", "comma-separated name=url read-only ICS calendar feeds")
+	flag.StringVar(&o.timezone, "timezone", "", "IANA time zone for time and calendar intents (default the system's)")
+	flag.StringVar(&o.weatherProvider, "weather-provider", "open-meteo", "weather source: open-meteo, or openweathermap with OPENWEATHERMAP_API_KEY")
+	flag.StringVar(&o.weatherLocation, "weather-location", "", "place name or latitude,longitude for weather.query when none is named")
+	flag.StringVar(&o.weatherUnits, "weather-units", "metric", "default weather units: metric or imperial")
+	flag.StringVar(&o.weatherAlerts, "weather-alerts", "", "comma-separated warning feeds polled for -weather-location: nws, or meteoalarm:<country> (e.g. meteoalarm:germany)")
+	flag.StringVar(&o.weatherAlertAreas, "weather-alert-areas", "", "comma-separated MeteoAlarm area names to keep warnings for (default the location's region and name)")
+	flag.StringVar(&o.severeWeatherIntents, "severe-weather-intents", "", "JSON file of intents (e.g. closing covers) run when a severe or extreme weather warning starts")
+	flag.StringVar(&o.newsFeeds, "news-feeds", "", "comma-separated RSS/Atom feed URLs for news briefings")
+	flag.IntVar(&o.poolPerHost, "pool-max-per-host", 0, "cap on HTTP connections per host shared by executors (0 for no limit)")
+	flag.BoolVar(&o.airGapped, "air-gapped", false, "refuse all network egress beyond the local network except -egress-allow; executors needing the cloud report themselves unavailable")
+	flag.StringVar(&o.egressAllow, "egress-allow", "", "in air-gapped mode, comma-separated hosts, *.domains, IPs, or CIDR blocks that may still be reached")
+	flag.DurationVar(&o.poolIdle, "pool-idle-timeout", 90*time.Second, "close shared keep-alive connections unused this long")
+	flag.StringVar(&o.dataDir, "data-dir", defaultDataDir(), "directory for local state such as shopping lists")
+	flag.StringVar(&o.pidFile, "pid-file", "", "write the agent's process ID to this file while it runs, refusing to start if another agent's is there")
+	flag.StringVar(&o.serviceName, "service-name", daemon.DefaultName, "name of the service install-service and uninstall-service act on")
+	flag.StringVar(&o.serviceScope, "service-scope", daemon.SystemScope, "install the service to start at boot (system) or at login for the current user (user)")
+	flag.BoolVar(&o.dryRun, "dry-run", false, "have install-service and uninstall-service print what they would do without doing it")
+	flag.StringVar(&o.notesDir, "notes-dir", "", "directory to keep note.* notes in as Markdown files, e.g. an Obsidian vault (default notes.json under -data-dir)")
+	flag.BoolVar(&o.tracingOn, "trace", false, "record a trace of spans for each intent and return its trace_id in results; see -otlp-endpoint")
+	flag.StringVar(&o.otlpEndpoint, "otlp-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "OpenTelemetry collector OTLP/HTTP base URL spans are exported to, e.g. http://localhost:4318 (implies -trace; headers in OTEL_EXPORTER_OTLP_HEADERS)")
+	flag.StringVar(&o.traceService, "trace-service", "device-agent", "service name spans are exported under")
+	flag.BoolVar(&o.historyOn, "history", true, "keep every result in history.jsonl under -data-dir, for history.query")
+	flag.DurationVar(&o.historyRetention, "history-retention", 90*24*time.Hour, "delete history records older than this (0 keeps them all)")
+	flag.StringVar(&o.auditLog, "audit-log", "", "append a JSON Lines record of every handled intent to this file")
+	flag.Int64Var(&o.auditRotateSize, "audit-rotate-size", 0, "rotate the audit log once it reaches this many bytes (0 never)")
+	flag.DurationVar(&o.auditRotateInterval, "audit-rotate-interval", 0, "rotate the audit log once its first entry is this old, e.g. 24h (0 never)")
+	flag.DurationVar(&o.auditMaxAge, "audit-max-age", 0, "delete rotated audit logs last written longer ago than this (0 keeps them); entries not yet exported are kept")
+	flag.IntVar(&o.auditMaxFiles, "audit-max-files", 0, "keep at most this many rotated audit logs (0 keeps them all); entries not yet exported are kept")
+	flag.StringVar(&o.auditExport, "audit-export", "", "write-once target the audit chain is exported to: a directory, or s3://bucket/prefix with Object Lock (credentials in AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY)")
+	flag.DurationVar(&o.auditExportInterval, "audit-export-interval", time.Hour, "how often new audit entries are exported")
+	flag.StringVar(&o.auditExportEndpoint, "audit-export-endpoint", "", "S3-compatible endpoint for -audit-export (default AWS)")
+	flag.StringVar(&o.auditExportRegion, "audit-export-region", "us-east-1", "S3 region for -audit-export")
+	flag.DurationVar(&o.auditExportRetention, "audit-export-retention", 0, "Object Lock retention for each exported segment (0 uses the bucket's default)")
+	flag.StringVar(&o.auditExportLockMode, "audit-export-lock-mode", "compliance", "Object Lock mode: compliance or governance")
+	flag.StringVar(&o.trustedKeys, "trusted-keys", "", "JSON file of agent core Ed25519 public keys used to verify intent signatures")
+	flag.BoolVar(&o.requireSignatures, "require-signatures", false, "reject unsigned intents (strict mode)")
+	flag.BoolVar(&o.signResults, "sign-results", false, "sign every result with the agent's Ed25519 identity key, producing verifiable receipts")
+	flag.BoolVar(&o.encryptedParams, "encrypted-params", false, "accept intents whose encrypted_parameters are sealed to the agent's X25519 payload key")
+	flag.StringVar(&o.payloadKey, "payload-key", "", "the agent's payload key file, generated on first use (default <data-dir>/payload-key.json)")
+	flag.StringVar(&o.identityKey, "identity-key", "", "the agent's identity key file, generated on first use (default <data-dir>/identity.json)")
+	flag.BoolVar(&o.strictIntents, "strict-intents", false, "reject intents with unknown fields, IDs that aren't UUIDs or ULIDs, no target_module, or a created_at in the future")
+	flag.StringVar(&o.idStrategy, "id-strategy", intent.IDUUIDv4, "how generated intent, event, and job IDs are made: uuidv4, or time-ordered uuidv7 or ulid")
+	flag.DurationVar(&o.clockSkew, "clock-skew", intent.DefaultClockSkew, "how far in the future created_at may be in strict mode")
+	flag.StringVar(&o.sensitiveIntents, "sensitive-intents", "", "comma-separated intent types (or module.*) that need a recognized voice or requires_permission")
+	flag.Float64Var(&o.speakerConfidence, "speaker-min-confidence", 0.8, "speaker_confidence a voice needs to count as recognized for sensitive intents")
+	flag.StringVar(&o.knownSpeakers, "known-speakers", "", "comma-separated voice profile IDs recognized for sensitive intents (any when empty)")
+	flag.StringVar(&o.requireHome, "require-home", "", "comma-separated intent types (or module.*) refused while nobody is home, per -presence")
+	flag.StringVar(&o.scenes, "scenes", "", "JSON file of named scenes (e.g. movie night) that scene.activate runs as plans")
+	flag.StringVar(&o.paramTransforms, "param-transforms", "", "JSON file of parameter aliases and value maps applied before dispatch")
+	flag.StringVar(&o.redactions, "redactions", "", "JSON file of rules that redact or truncate result fields before they leave the gateway")
+	flag.BoolVar(&o.childSafety, "child-safety", false, "start with the safety policy on (toggle through the admin API)")
+	flag.StringVar(&o.safetyPolicy, "safety-policy", "", "JSON file replacing the built-in child-safety policy")
+	flag.StringVar(&o.modesFile, "modes", "", "JSON file of modes (e.g. privacy, dnd) that suppress classes of intents, adding to or replacing the built-in ones")
+	flag.StringVar(&o.startMode, "mode", gateway.NormalMode, "mode to start in (switch with mode.set or the admin API)")
+	flag.StringVar(&o.sandboxPolicy, "sandbox", "", "JSON sandbox policy of the network, paths, and programs executors may declare they need; others are refused")
+	flag.BoolVar(&o.sandboxEnforce, "sandbox-enforce", false, "confine the agent process to what its executors declared (Landlock and seccomp on Linux), refusing executors that declare nothing")
+	flag.DurationVar(&o.maxIntentAge, "max-intent-age", 0, "refuse intents created longer ago than this, even without their own expiry (0 disables)")
+	flag.StringVar(&o.logFormat, "log-format", logging.FormatText, "log record format: text or json")
+	flag.StringVar(&o.logLevel, "log-level", "info", "comma-separated log levels: a default, then component=level for gateway, transport, audit, tracing, agent, executor, or executor:<name> (e.g. info,gateway=debug,executor:mqtt=warn)")
+	return o
+}
@@ -0,0 +1,697 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/access"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/anomaly"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/approval"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/audit"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/challenge"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/crypto"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/daemon"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/guest"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/history"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/logging"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/quota"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/registry"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/secrets"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/tracing"
+)
+
+// agent is what every subsystem is set up around: the gateway, the event
+// bus and device registry, logging, and notifications
+type agent struct {
+	gw       *gateway.Gateway
+	bus      *events.Bus
+	devices  *registry.Registry
+	notifier *executor.NotificationExecutor
+	logs     *logging.Logs
+	logger   *slog.Logger
+
+	// logOutput is where logs are written, and logPath the file with -tui
+	logOutput io.Writer
+	logPath   string
+
+	// winService is set when the Windows service manager started the agent
+	winService *daemon.Service
+
+	closers []func()
+}
+
+// onExit runs fn when the agent shuts down, after the functions added
+// later, as with defer
+func (a *agent) onExit(fn func()) {
+	a.closers = append(a.closers, fn)
+}
+
+// close runs the exit functions, last added first
+func (a *agent) close() {
+	for n := len(a.closers) - 1; n >= 0; n-- {
+		a.closers[n]()
+	}
+}
+
+// fatal logs why the agent can't go on and exits, as log.Fatal did
+func (a *agent) fatal(msg string, err error) {
+	a.logger.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// notify shows a notification, logging it if that fails
+func (a *agent) notify(ctx context.Context, eventType string, n executor.Notification) {
+	if _, err := a.notifier.Notify(ctx, n); err != nil {
+		a.logger.Warn("Failed to notify", "event", eventType, "error", err)
+	}
+}
+
+// secretEnv reads credentials from environment variables, which may hold
+// secret:// references, keeping the first that fails to resolve
+type secretEnv struct {
+	err error
+}
+
+func (s *secretEnv) get(variable string) string {
+	value, err := secrets.Resolve(os.Getenv(variable))
+	if err != nil && s.err == nil {
+		s.err = fmt.Errorf("%s: %w", variable, err)
+	}
+	return value
+}
+
+// newAgent sets up logging, the shared connection pool and secret store,
+// and the gateway with its event bus, device registry, and notifications
+func newAgent(o *options, oneShot bool) (*agent, error) {
+	a := &agent{logOutput: os.Stdout}
+
+	// In pipe mode and one-shot commands stdout carries results, so
+	// everything else goes to stderr; the dashboard has the terminal to
+	// itself, so logs go to a file
+	switch {
+	case o.pipeMode && o.tuiMode:
+		return nil, errors.New("-tui and -pipe both need the terminal")
+	case o.pipeMode, oneShot:
+		a.logOutput = os.Stderr
+	case o.tuiMode:
+		a.logPath = filepath.Join(o.dataDir, "agent.log")
+		if err := os.MkdirAll(o.dataDir, 0o700); err != nil {
+			return nil, err
+		}
+		file, err := os.OpenFile(a.logPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		a.onExit(func() { file.Close() })
+		a.logOutput = file
+	}
+	// Started by the Windows service manager, the agent answers it and logs
+	// to the event log
+	if !oneShot {
+		var err error
+		if a.winService, err = daemon.StartService(o.serviceName); err != nil {
+			return nil, fmt.Errorf("failed to start as a Windows service: %w", err)
+		}
+	}
+
+	levels, err := logging.ParseLevels(o.logLevel)
+	if err != nil {
+		return nil, err
+	}
+	if a.winService != nil {
+		handler, err := a.winService.LogHandler()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open the event log: %w", err)
+		}
+		a.logs = logging.NewHandler(handler, levels)
+	} else if a.logs, err = logging.New(a.logOutput, o.logFormat, levels); err != nil {
+		return nil, err
+	}
+	// Lines printed with the log package become agent records too
+	a.logger = a.logs.Logger("agent")
+	slog.SetDefault(a.logger)
+	a.logger.Info("Starting device agent")
+	if o.pidFile != "" && !oneShot {
+		remove, err := daemon.WritePIDFile(o.pidFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write PID file: %w", err)
+		}
+		a.onExit(remove)
+	}
+
+	if err := intent.SetIDStrategy(o.idStrategy); err != nil {
+		return nil, fmt.Errorf("invalid ID strategy: %w", err)
+	}
+
+	// Executors share keep-alive connections through the default pool
+	var egress *connpool.Egress
+	if o.airGapped {
+		egress = &connpool.Egress{
+			Allow: splitList(o.egressAllow),
+			OnBlocked: func(host string) {
+				a.logger.Warn("Blocked egress (air-gapped)", "host", host)
+			},
+		}
+		a.logger.Info("Air-gapped: egress limited to the local network", "allow", egress.Allow)
+	}
+	connpool.Default = connpool.New(connpool.Config{
+		MaxPerHost:  o.poolPerHost,
+		IdleTimeout: o.poolIdle,
+		Egress:      egress,
+	})
+
+	// Credentials below may be secret:// references instead of values
+	store, err := openSecrets(o.secretsFile, o.secretsPassFile, o.secretsKeychain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open secrets: %w", err)
+	}
+	secrets.Default = store
+
+	// Create intent gateway, device registry, and event bus
+	a.gw = gateway.NewGateway(a.logs.Logger("gateway"))
+	a.gw.SetMaxIntentAge(o.maxIntentAge)
+	a.gw.SetStrictParsing(o.strictIntents, o.clockSkew)
+	a.devices = registry.New()
+	a.bus = events.NewBus()
+	a.gw.SetEventBus(a.bus)
+	a.gw.SetDeviceRegistry(a.devices)
+	a.bus.Subscribe("*", func(e events.Event) {
+		if e.Type == "intent.completed" || e.Type == "capabilities.changed" {
+			return // the gateway logs these itself
+		}
+		a.logger.Info("Event", "type", e.Type, "source", e.Source, "data", e.Data)
+	})
+
+	a.notifier = executor.NewNotificationExecutor()
+	a.notifier.SetOutput(a.logOutput)
+	a.notifier.SetEventBus(a.bus)
+	switch o.notifications {
+	case "log":
+	case "desktop", "auto":
+		if err := a.notifier.UseDesktop(); err != nil {
+			if o.notifications == "desktop" {
+				return nil, fmt.Errorf("desktop notifications unavailable: %w", err)
+			}
+			a.logger.Warn("Desktop notifications unavailable, logging them instead", "error", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown notifications backend: %s", o.notifications)
+	}
+	return a, nil
+}
+
+// setupSandbox sets the policy executors' declared needs are checked
+// against when they register. It is enforced on the process once they
+// all have, by enforceSandbox.
+func setupSandbox(o *options, a *agent) error {
+	if o.sandboxPolicy != "" {
+		p, err := sandbox.LoadPolicy(o.sandboxPolicy)
+		if err != nil {
+			return fmt.Errorf("failed to load sandbox policy: %w", err)
+		}
+		p.RequireDeclaration = p.RequireDeclaration || o.sandboxEnforce
+		a.gw.SetSandboxPolicy(p)
+	} else if o.sandboxEnforce {
+		// Whatever executors declare is allowed, but only what they declare
+		// can be confined
+		a.gw.SetSandboxPolicy(&sandbox.Policy{
+			RequireDeclaration: true,
+			Default:            sandbox.Grant{Network: true, Subprocess: true, Write: []string{"/"}},
+		})
+	}
+	return nil
+}
+
+// enforceSandbox confines the process to what the registered executors
+// declared and what the agent itself needs: its state and configuration
+func enforceSandbox(o *options, a *agent) error {
+	own := sandbox.Needs{Write: []string{o.dataDir}}
+	if o.tlsDir != "" {
+		own.Write = append(own.Write, o.tlsDir)
+	}
+	if o.auditLog != "" {
+		own.Write = append(own.Write, filepath.Dir(o.auditLog))
+	}
+	if o.auditExport != "" && !strings.HasPrefix(o.auditExport, "s3://") {
+		own.Write = append(own.Write, o.auditExport)
+	}
+	if o.rolesFile != "" {
+		own.Read = append(own.Read, o.rolesFile)
+	}
+	if o.adminSocket != "" {
+		own.Write = append(own.Write, filepath.Dir(o.adminSocket))
+	}
+
+	needs := []sandbox.Needs{own}
+	for _, n := range a.gw.ExecutorNeeds() {
+		needs = append(needs, n)
+	}
+	report, err := sandbox.Enforce(sandbox.Merge(needs...))
+	if err != nil {
+		return fmt.Errorf("failed to enforce sandbox: %w", err)
+	}
+	if report.Landlock > 0 {
+		a.logger.Info("Sandbox: file access limited with Landlock", "abi", report.Landlock)
+	}
+	if report.NoExec {
+		a.logger.Info("Sandbox: starting programs blocked with seccomp")
+	}
+	for _, skipped := range report.Skipped {
+		a.logger.Warn("Sandbox not enforced", "reason", skipped)
+	}
+	return nil
+}
+
+// setupSignatures sets up intent signature checks, signed receipts, and
+// encrypted parameters
+func setupSignatures(o *options, a *agent) error {
+	if o.trustedKeys != "" {
+		verifier, err := crypto.LoadVerifier(o.trustedKeys)
+		if err != nil {
+			return fmt.Errorf("failed to load trusted keys: %w", err)
+		}
+		a.gw.SetVerifier(verifier, o.requireSignatures)
+	} else if o.requireSignatures {
+		a.gw.SetVerifier(nil, true)
+	}
+	if o.signResults {
+		path := o.identityKey
+		if path == "" {
+			path = filepath.Join(o.dataDir, "identity.json")
+		}
+		signer, err := crypto.LoadOrCreateSigner(path)
+		if err != nil {
+			return fmt.Errorf("failed to load identity key: %w", err)
+		}
+		a.gw.SetReceiptSigner(signer)
+		key := signer.PublicKey()
+		a.logger.Info("Signing receipts", "key", key.ID, "public_key", base64.StdEncoding.EncodeToString(key.PublicKey))
+	}
+	if o.encryptedParams {
+		path := o.payloadKey
+		if path == "" {
+			path = filepath.Join(o.dataDir, "payload-key.json")
+		}
+		key, err := crypto.LoadOrCreatePayloadKey(path)
+		if err != nil {
+			return fmt.Errorf("failed to load payload key: %w", err)
+		}
+		a.gw.SetPayloadKey(key)
+		a.logger.Info("Opening encrypted parameters", "key", key.KeyID(), "public_key", base64.StdEncoding.EncodeToString(key.PublicKey()))
+	}
+	return nil
+}
+
+// setupAuth loads the client identities and their roles, reloading them
+// as the file changes, and guest access. It returns the authenticator,
+// nil without -roles, and the identity of pipe clients.
+func setupAuth(ctx context.Context, o *options, a *agent) (*access.Authenticator, *access.Identity, error) {
+	var auth *access.Authenticator
+	if o.rolesFile != "" {
+		var err error
+		if auth, err = access.NewAuthenticator(o.rolesFile); err != nil {
+			return nil, nil, fmt.Errorf("failed to load roles: %w", err)
+		}
+		roles := auth.Policy()
+		a.gw.SetRolePolicy(roles)
+		a.logger.Info("Loaded roles", "identities", len(roles.Identities), "roles", len(roles.Roles))
+	} else if o.httpAddr != "" && o.tlsDir == "" && !loopbackAddr(o.httpAddr) {
+		a.logger.Warn("HTTP transport accepts intents from anyone who can reach it; use -roles to require API keys", "addr", o.httpAddr)
+	}
+
+	var pipeClient *access.Identity
+	if o.pipeIdentity != "" {
+		if auth == nil {
+			return nil, nil, errors.New("-pipe-identity needs -roles")
+		}
+		id, ok := auth.Policy().Identity(o.pipeIdentity)
+		if !ok {
+			return nil, nil, fmt.Errorf("-pipe-identity: no identity %s in %s", o.pipeIdentity, o.rolesFile)
+		}
+		pipeClient = id
+	}
+
+	if auth != nil && o.rolesReload > 0 {
+		auth.Watch(ctx, o.rolesReload, func(p *access.Policy, err error) {
+			if err != nil {
+				a.logger.Warn("Keeping previous roles", "error", err)
+				return
+			}
+			a.gw.SetRolePolicy(p)
+			a.logger.Info("Reloaded roles", "identities", len(p.Identities), "roles", len(p.Roles))
+		})
+	}
+
+	if grants, err := access.Open(filepath.Join(o.dataDir, "guest-grants.json")); err != nil {
+		a.logger.Warn("Guest access unavailable", "error", err)
+	} else if guestLog, err := audit.Open(filepath.Join(o.dataDir, "guest-audit.jsonl")); err != nil {
+		a.logger.Warn("Guest access unavailable", "error", err)
+	} else {
+		a.onExit(func() { guestLog.Close() })
+		a.gw.SetGuestAccess(grants, guestLog)
+		a.gw.RegisterExecutor(guest.NewExecutor(grants))
+		grants.Start(ctx, func(g access.Grant) {
+			a.bus.Publish(events.Event{
+				Type:   "guest.expired",
+				Source: "guest",
+				Data:   map[string]interface{}{"id": g.ID, "name": g.Name},
+			})
+		})
+	}
+	return auth, pipeClient, nil
+}
+
+// setupTracing records spans for each intent with -trace, exporting them
+// with -otlp-endpoint until the agent shuts down
+func setupTracing(ctx context.Context, o *options, a *agent) error {
+	if !o.tracingOn && o.otlpEndpoint == "" {
+		return nil
+	}
+	var env secretEnv
+	headers := map[string]string{}
+	for _, kv := range strings.Split(env.get("OTEL_EXPORTER_OTLP_HEADERS"), ",") {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	if env.err != nil {
+		return env.err
+	}
+	tracer := tracing.New(tracing.Config{
+		Service:  o.traceService,
+		Endpoint: o.otlpEndpoint,
+		Headers:  headers,
+		Client:   connpool.Default.HTTP(30 * time.Second),
+		Logger:   a.logs.Logger("tracing"),
+	})
+	a.gw.SetTracer(tracer)
+	if o.otlpEndpoint != "" {
+		a.logger.Info("Exporting traces", "endpoint", o.otlpEndpoint)
+	}
+
+	tracer.StartExport(ctx)
+	// Export the last spans before exiting
+	a.onExit(func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tracer.Flush(flushCtx); err != nil {
+			a.logger.Error("Final trace export failed", "error", err)
+		}
+	})
+	return nil
+}
+
+// setupAudit opens the audit log, exporting its chain with -audit-export,
+// and the result history
+func setupAudit(ctx context.Context, o *options, a *agent) error {
+	if o.auditLog != "" {
+		l, err := audit.Open(o.auditLog)
+		if err != nil {
+			return fmt.Errorf("failed to open audit log: %w", err)
+		}
+		a.onExit(func() { l.Close() })
+		a.gw.SetAuditLog(l)
+		if o.auditExport != "" {
+			var env secretEnv
+			sink, err := audit.ParseSink(o.auditExport, audit.S3Sink{
+				Endpoint:  o.auditExportEndpoint,
+				Region:    o.auditExportRegion,
+				AccessKey: env.get("AWS_ACCESS_KEY_ID"),
+				SecretKey: env.get("AWS_SECRET_ACCESS_KEY"),
+				Retention: o.auditExportRetention,
+				LockMode:  o.auditExportLockMode,
+			})
+			if env.err != nil {
+				return env.err
+			}
+			if err != nil {
+				return fmt.Errorf("invalid audit export target: %w", err)
+			}
+			exporter := audit.NewExporter(l, sink, o.auditExportInterval)
+			exporter.SetLogger(a.logs.Logger("audit"))
+			exporter.Start(ctx)
+			// Export what was recorded since the last export before the log closes
+			a.onExit(func() {
+				exportCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				defer cancel()
+				if _, err := exporter.Export(exportCtx); err != nil {
+					a.logger.Error("Final audit export failed", "error", err)
+				}
+			})
+		}
+		if err := l.SetRotation(audit.Rotation{
+			MaxSize:  o.auditRotateSize,
+			Interval: o.auditRotateInterval,
+			MaxAge:   o.auditMaxAge,
+			MaxFiles: o.auditMaxFiles,
+		}); err != nil {
+			a.logger.Error("Audit log rotation failed", "error", err)
+		}
+	} else if o.auditExport != "" {
+		return errors.New("-audit-export needs -audit-log")
+	}
+	if o.inputEnabled && o.auditLog == "" {
+		return errors.New("-input needs -audit-log, so everything typed and clicked is recorded")
+	}
+
+	if o.historyOn {
+		h, err := history.Open(filepath.Join(o.dataDir, "history.jsonl"), o.historyRetention)
+		if err != nil {
+			return fmt.Errorf("failed to open history: %w", err)
+		}
+		a.onExit(func() { h.Close() })
+		a.gw.SetHistory(h)
+		a.gw.RegisterExecutor(gateway.NewHistoryExecutor(a.gw))
+	}
+	return nil
+}
+
+// setupApprovals sets up the checks intents pass before they run: approval
+// on a second channel, PINs, unusual bursts, and quotas. It returns the
+// keyboard the dashboard answers approvals on, nil without -tui.
+func setupApprovals(ctx context.Context, o *options, a *agent, ex *executors) (*approval.Keyboard, error) {
+	var keyboard *approval.Keyboard
+	if o.tuiMode {
+		keyboard = &approval.Keyboard{}
+	}
+	if o.riskTable != "" {
+		table, err := approval.LoadTable(o.riskTable)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load risk table: %w", err)
+		}
+		var env secretEnv
+		var approvers []approval.Approver
+		// Answered on the web dashboard, and in the terminal one with -tui
+		approvers = append(approvers, &approval.Dashboard{})
+		if keyboard != nil {
+			approvers = append(approvers, keyboard)
+		}
+		if secret := env.get("APPROVAL_TOTP_SECRET"); secret != "" {
+			totp, err := approval.NewTOTP(secret)
+			if err != nil {
+				return nil, fmt.Errorf("APPROVAL_TOTP_SECRET: %w", err)
+			}
+			approvers = append(approvers, totp)
+		}
+		if o.approvalPush != "" {
+			if o.approvalCallback == "" || o.httpAddr == "" {
+				return nil, errors.New("-approval-push needs -approval-callback and -http")
+			}
+			approvers = append(approvers, &approval.Push{
+				TopicURL:    o.approvalPush,
+				AccessToken: env.get("APPROVAL_PUSH_TOKEN"),
+				CallbackURL: o.approvalCallback,
+			})
+		}
+		if env.err != nil {
+			return nil, env.err
+		}
+		if o.approvalButton != "" {
+			if ex.gpio == nil {
+				return nil, errors.New("-approval-button needs GPIO pins in -hardware")
+			}
+			pin, lines := o.approvalButton, ex.gpio
+			approvers = append(approvers, &approval.Button{
+				Press: func(ctx context.Context) error { return lines.WaitPress(ctx, pin) },
+				Announce: func(r *approval.Request) {
+					a.bus.Publish(events.Event{
+						Type:   "approval.requested",
+						Source: "approval",
+						Data:   map[string]interface{}{"id": r.ID, "intent_type": r.Intent.IntentType, "risk": r.Risk, "button": pin},
+					})
+				},
+			})
+		}
+		approvals, err := approval.New(table, approvers...)
+		if err != nil {
+			return nil, fmt.Errorf("invalid risk table: %w", err)
+		}
+		a.gw.SetApprovals(approvals)
+		a.logger.Info("Holding risk rules for approval", "rules", len(table.Rules), "approvers", approvals.Approvers())
+	}
+
+	if o.challengeConfig != "" {
+		cfg, err := challenge.LoadConfig(o.challengeConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load challenge config: %w", err)
+		}
+		verifier, err := challenge.New(*cfg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid challenge config: %w", err)
+		}
+		a.gw.SetChallenges(verifier)
+		// Someone may be guessing PINs
+		a.bus.Subscribe("challenge.locked", func(e events.Event) {
+			until, _ := e.Data["until"].(string)
+			go a.notify(ctx, e.Type, executor.Notification{
+				Title:   "Security",
+				Message: "Too many wrong PINs; PIN-protected actions are locked until " + until,
+				Urgency: "critical",
+			})
+		})
+		a.logger.Info("Requiring a PIN", "rules", len(cfg.Require))
+	}
+
+	if o.anomalyConfig != "" {
+		cfg, err := anomaly.LoadConfig(o.anomalyConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load anomaly config: %w", err)
+		}
+		if len(cfg.Hold) > 0 && a.gw.Approvals() == nil {
+			return nil, errors.New("-anomaly holds bursts for approval, which needs -risk")
+		}
+		cfg.StateFile = filepath.Join(o.dataDir, "anomaly.json")
+		detector, err := anomaly.New(*cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start anomaly detection: %w", err)
+		}
+		a.onExit(func() { detector.Save() })
+		a.gw.SetAnomalyDetector(detector)
+		// Bursts are announced, urgently if they are held
+		a.bus.Subscribe("intent.anomaly", func(e events.Event) {
+			message, _ := e.Data["message"].(string)
+			held, _ := e.Data["held"].(bool)
+			n := executor.Notification{Title: "Unusual activity", Message: message}
+			if held {
+				n.Message += "; held for approval"
+				n.Urgency = "critical"
+			}
+			go a.notify(ctx, e.Type, n)
+		})
+		a.logger.Info("Watching for unusual intent bursts", "hold", cfg.Hold)
+	}
+
+	if o.quotaConfig != "" {
+		cfg, err := quota.LoadConfig(o.quotaConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load quotas: %w", err)
+		}
+		cfg.StateFile = filepath.Join(o.dataDir, "quota.json")
+		tracker, err := quota.New(*cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start quotas: %w", err)
+		}
+		a.onExit(func() { tracker.Save() })
+		a.gw.SetQuotas(tracker)
+		a.gw.RegisterExecutor(gateway.NewQuotaExecutor(a.gw))
+		// A used-up quota may be a runaway loop, so it is announced
+		a.bus.Subscribe("quota.exceeded", func(e events.Event) {
+			name, _ := e.Data["quota"].(string)
+			intentType, _ := e.Data["intent_type"].(string)
+			go a.notify(ctx, e.Type, executor.Notification{
+				Title:   "Quota reached",
+				Message: "Quota " + name + " is used up; further " + intentType + " intents are refused until it resets",
+			})
+		})
+		a.logger.Info("Enforcing quotas", "quotas", len(cfg.Quotas))
+	}
+	return keyboard, nil
+}
+
+// setupPolicies applies the gateway's routing and safety policies, its
+// mode, and the executors to start disabled
+func setupPolicies(o *options, a *agent, ex *executors) error {
+	gw := a.gw
+	for _, entry := range splitList(o.moduleGroups) {
+		name, modules, ok := strings.Cut(entry, "=")
+		if !ok || name == "" {
+			a.logger.Warn("Ignoring module group, expected name=module+module", "group", entry)
+			continue
+		}
+		gw.SetModuleGroup(name, strings.Split(modules, "+"))
+	}
+
+	gw.SetSpeakerPolicy(gateway.SpeakerPolicy{
+		Sensitive:     splitList(o.sensitiveIntents),
+		MinConfidence: float32(o.speakerConfidence),
+		Known:         splitList(o.knownSpeakers),
+	})
+	gw.SetPresence(ex.whoIsHome, gateway.PresencePolicy{RequireHome: splitList(o.requireHome)})
+
+	latency, err := parseLatencyPolicy(o.degradedP95)
+	if err != nil {
+		return fmt.Errorf("invalid -degraded-p95: %w", err)
+	}
+	gw.SetLatencyPolicy(latency)
+
+	if o.paramTransforms != "" {
+		rules, err := gateway.LoadTransforms(o.paramTransforms)
+		if err != nil {
+			return fmt.Errorf("failed to load parameter transforms: %w", err)
+		}
+		for _, r := range rules {
+			gw.AddTransformer(r)
+		}
+	}
+
+	if o.redactions != "" {
+		rules, err := gateway.LoadRedactions(o.redactions)
+		if err != nil {
+			return fmt.Errorf("failed to load redactions: %w", err)
+		}
+		for _, r := range rules {
+			if err := gw.AddRedaction(r); err != nil {
+				return fmt.Errorf("failed to load redactions: %w", err)
+			}
+		}
+	}
+
+	policy, err := loadSafetyPolicy(o.safetyPolicy)
+	if err != nil {
+		return fmt.Errorf("failed to load safety policy: %w", err)
+	}
+	gw.SetSafetyPolicy(policy)
+	gw.SetSafetyMode(o.childSafety)
+
+	if o.modesFile != "" {
+		modes, err := gateway.LoadModes(o.modesFile)
+		if err != nil {
+			return fmt.Errorf("failed to load modes: %w", err)
+		}
+		gw.SetModes(modes)
+	}
+	if err := gw.SetMode(o.startMode); err != nil {
+		return fmt.Errorf("-mode: %w", err)
+	}
+
+	for _, name := range splitList(o.disableExecutors) {
+		if err := gw.DisableExecutor(name); err != nil {
+			a.logger.Warn("Cannot disable executor", "error", err)
+		}
+	}
+	return nil
+}
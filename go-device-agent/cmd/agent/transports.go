@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/access"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/transport"
+)
+
+// transports serve the gateway to clients other than the pipe
+type transports struct {
+	server        *transport.HTTPServer
+	webhookSecret string
+}
+
+// setupTransports sets up the HTTP server and its admin routes, which are
+// served over HTTP with the admin token, and on the admin socket without
+// one. Nothing is served until serveAdminSocket and serveHTTP.
+func setupTransports(o *options, a *agent, auth *access.Authenticator, ex *executors) (*transports, error) {
+	// Resolved before the sandbox, which may block the keychain's helpers
+	var env secretEnv
+	adminToken := env.get("AGENT_ADMIN_TOKEN")
+	t := &transports{webhookSecret: env.get("RESULT_WEBHOOK_SECRET")}
+	if env.err != nil {
+		return nil, env.err
+	}
+
+	gw := a.gw
+	t.server = transport.NewHTTPServer(gw, a.logs.Logger("transport"))
+	t.server.SetAdminToken(adminToken)
+	if auth != nil {
+		t.server.SetAuthenticator(auth)
+		t.server.AddReloader("roles", func() error {
+			p, err := auth.Reload()
+			if p != nil {
+				gw.SetRolePolicy(p)
+			}
+			return err
+		})
+	}
+	t.server.AddInspector("system_thresholds", func() interface{} { return ex.host.Thresholds() })
+	return t, nil
+}
+
+// serveAdminSocket serves the admin routes on -admin-socket until ctx is done
+func (t *transports) serveAdminSocket(ctx context.Context, o *options, a *agent) {
+	if o.adminSocket == "" {
+		return
+	}
+	go func() {
+		if err := t.server.ServeAdminSocket(ctx, o.adminSocket); err != nil {
+			a.fatal("Admin socket failed", err)
+		}
+	}()
+}
+
+// serveHTTP serves intents on -http until ctx is done, over TLS with
+// -tls-dir, delivering async results to -result-webhook
+func (t *transports) serveHTTP(ctx context.Context, o *options, a *agent) {
+	if o.httpAddr == "" {
+		return
+	}
+	go func() {
+		if o.tlsDir != "" {
+			config, err := serverTLS(ctx, o.tlsDir, o.tlsHosts, o.tlsPins, a.logger)
+			if err != nil {
+				a.fatal("Failed to set up TLS", err)
+			}
+			t.server.SetTLSConfig(config)
+		}
+		if o.resultWebhook != "" {
+			hook, err := t.server.Webhooks().Add(transport.Webhook{URL: o.resultWebhook, Secret: t.webhookSecret})
+			if err != nil {
+				a.fatal("Invalid result webhook", err)
+			}
+			a.logger.Info("Delivering async results", "url", hook.URL, "webhook", hook.ID)
+		}
+		if err := t.server.ListenAndServe(ctx, o.httpAddr); err != nil {
+			a.fatal("HTTP transport failed", err)
+		}
+	}()
+}
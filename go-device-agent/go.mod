@@ -1,3 +1,21 @@
 module github.com/vinod901/local-agent-core/go-device-agent
 
 go 1.24.11
+
+require (
+	github.com/apognu/gocal v0.9.1
+	github.com/eclipse/paho.mqtt.golang v1.5.1
+	github.com/fxamacker/cbor/v2 v2.9.0
+	github.com/godbus/dbus/v5 v5.2.2
+	github.com/gorilla/websocket v1.5.3
+	go.yaml.in/yaml/v3 v3.0.4
+	golang.org/x/net v0.44.0
+	golang.org/x/sys v0.36.0
+	google.golang.org/protobuf v1.36.9
+)
+
+require (
+	github.com/ChannelMeter/iso8601duration v0.0.0-20150204201828-8da3af7a2a61 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
+	golang.org/x/sync v0.17.0 // indirect
+)
@@ -0,0 +1,286 @@
+// Package cast speaks the Cast v2 protocol to Chromecasts and other Google
+// Cast receivers: CastMessage protobufs carrying JSON payloads over TLS
+package cast
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/internal/wire"
+)
+
+// Protocol constants
+const (
+	Port = "8009"
+
+	// DefaultMediaReceiver is the app that plays a URL it is given
+	DefaultMediaReceiver = "CC1AD845"
+
+	NamespaceConnection = "urn:x-cast:com.google.cast.tp.connection"
+	NamespaceHeartbeat  = "urn:x-cast:com.google.cast.tp.heartbeat"
+	NamespaceReceiver   = "urn:x-cast:com.google.cast.receiver"
+	NamespaceMedia      = "urn:x-cast:com.google.cast.media"
+
+	// Receiver is the platform's own destination
+	Receiver = "receiver-0"
+
+	maxMessage = 64 << 10
+)
+
+// Message is a received payload. Status is left raw since the receiver
+// and media namespaces shape it differently; see ReceiverStatus and
+// MediaStatus.
+type Message struct {
+	Namespace string          `json:"-"`
+	Type      string          `json:"type"`
+	RequestID int             `json:"requestId"`
+	Reason    string          `json:"reason"`
+	Status    json.RawMessage `json:"status"`
+}
+
+// ReceiverStatus is the device's volume and running apps
+type ReceiverStatus struct {
+	Applications []Application `json:"applications"`
+	Volume       struct {
+		Level float64 `json:"level"`
+		Muted bool    `json:"muted"`
+	} `json:"volume"`
+}
+
+// Application is an app running on the receiver
+type Application struct {
+	AppID        string `json:"appId"`
+	DisplayName  string `json:"displayName"`
+	SessionID    string `json:"sessionId"`
+	TransportID  string `json:"transportId"`
+	IsIdleScreen bool   `json:"isIdleScreen"`
+}
+
+// MediaStatus is one media session of an app
+type MediaStatus struct {
+	MediaSessionID int    `json:"mediaSessionId"`
+	PlayerState    string `json:"playerState"`
+}
+
+// Conn is a connection to a receiver
+type Conn struct {
+	conn      net.Conn
+	requestID int
+}
+
+// Dial connects to a receiver at host or host:port. The context's deadline,
+// if any, bounds the whole conversation.
+func Dial(ctx context.Context, addr string) (*Conn, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, Port)
+	}
+	// Cast devices present self-signed certificates
+	dialer := &tls.Dialer{Config: &tls.Config{InsecureSkipVerify: true}}
+	raw, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		raw.SetDeadline(deadline)
+	}
+	c := &Conn{conn: raw}
+	if err := c.Send(Receiver, NamespaceConnection, map[string]interface{}{"type": "CONNECT"}); err != nil {
+		raw.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close ends the connection
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// LocalIP is the address the receiver reaches us on
+func (c *Conn) LocalIP() net.IP {
+	return c.conn.LocalAddr().(*net.TCPAddr).IP
+}
+
+// Send sends a payload to a destination (Receiver or an app's transport)
+func (c *Conn) Send(destination, namespace string, payload map[string]interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	var b []byte
+	b = wire.AppendInt64(b, 1, 0) // protocol_version CASTV2_1_0
+	b = wire.AppendString(b, 2, "sender-0")
+	b = wire.AppendString(b, 3, destination)
+	b = wire.AppendString(b, 4, namespace)
+	b = wire.AppendInt64(b, 5, 0) // payload_type STRING
+	b = wire.AppendString(b, 6, string(data))
+
+	frame := binary.BigEndian.AppendUint32(nil, uint32(len(b)))
+	_, err = c.conn.Write(append(frame, b...))
+	return err
+}
+
+// Request sends a payload with a fresh requestId and returns the reply to
+// it, answering heartbeats and skipping unrelated broadcasts meanwhile
+func (c *Conn) Request(destination, namespace string, payload map[string]interface{}) (*Message, error) {
+	c.requestID++
+	id := c.requestID
+	payload["requestId"] = id
+	if err := c.Send(destination, namespace, payload); err != nil {
+		return nil, err
+	}
+	for {
+		msg, err := c.Next()
+		if err != nil {
+			return nil, err
+		}
+		if msg.RequestID == id {
+			return msg, nil
+		}
+	}
+}
+
+// Next reads messages until one worth acting on, answering heartbeats
+func (c *Conn) Next() (*Message, error) {
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(c.conn, size[:]); err != nil {
+			return nil, fmt.Errorf("cast connection lost: %w", err)
+		}
+		n := binary.BigEndian.Uint32(size[:])
+		if n > maxMessage {
+			return nil, errors.New("cast message too large")
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(c.conn, data); err != nil {
+			return nil, fmt.Errorf("cast connection lost: %w", err)
+		}
+
+		var namespace, payload string
+		err := wire.Walk(data, func(f wire.Field) error {
+			switch f.Num {
+			case 4:
+				namespace = f.String()
+			case 6:
+				payload = f.String()
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("invalid cast message: %w", err)
+		}
+
+		var msg Message
+		if json.Unmarshal([]byte(payload), &msg) != nil {
+			continue
+		}
+		msg.Namespace = namespace
+		if namespace == NamespaceHeartbeat && msg.Type == "PING" {
+			if err := c.Send(Receiver, NamespaceHeartbeat, map[string]interface{}{"type": "PONG"}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return &msg, nil
+	}
+}
+
+// ReceiverStatus asks for the volume and running apps
+func (c *Conn) ReceiverStatus() (*ReceiverStatus, error) {
+	msg, err := c.Request(Receiver, NamespaceReceiver, map[string]interface{}{"type": "GET_STATUS"})
+	if err != nil {
+		return nil, err
+	}
+	return msg.Receiver()
+}
+
+// Receiver decodes a RECEIVER_STATUS message's status
+func (m *Message) Receiver() (*ReceiverStatus, error) {
+	if m.Type != "RECEIVER_STATUS" {
+		return nil, fmt.Errorf("expected RECEIVER_STATUS, got %s", m.Type)
+	}
+	var s ReceiverStatus
+	if err := json.Unmarshal(m.Status, &s); err != nil {
+		return nil, fmt.Errorf("invalid receiver status: %w", err)
+	}
+	return &s, nil
+}
+
+// Media decodes a MEDIA_STATUS message's sessions
+func (m *Message) Media() ([]MediaStatus, error) {
+	if m.Type != "MEDIA_STATUS" {
+		return nil, fmt.Errorf("expected MEDIA_STATUS, got %s", m.Type)
+	}
+	var s []MediaStatus
+	if err := json.Unmarshal(m.Status, &s); err != nil {
+		return nil, fmt.Errorf("invalid media status: %w", err)
+	}
+	return s, nil
+}
+
+// Launch starts an app, or finds it already running, and connects to it
+func (c *Conn) Launch(appID string) (*Application, error) {
+	c.requestID++
+	id := c.requestID
+	if err := c.Send(Receiver, NamespaceReceiver, map[string]interface{}{
+		"type": "LAUNCH", "appId": appID, "requestId": id,
+	}); err != nil {
+		return nil, err
+	}
+	// The reply may come before the app is up; wait for a status that
+	// shows it with a transport
+	for {
+		msg, err := c.Next()
+		if err != nil {
+			return nil, err
+		}
+		switch msg.Type {
+		case "RECEIVER_STATUS":
+			status, err := msg.Receiver()
+			if err != nil {
+				return nil, err
+			}
+			for _, app := range status.Applications {
+				if app.AppID == appID && app.TransportID != "" {
+					return &app, c.Connect(app.TransportID)
+				}
+			}
+		case "LAUNCH_ERROR":
+			if msg.RequestID == id {
+				return nil, fmt.Errorf("launch refused: %s", msg.Reason)
+			}
+		}
+	}
+}
+
+// Connect opens a virtual connection to an app's transport
+func (c *Conn) Connect(transportID string) error {
+	return c.Send(transportID, NamespaceConnection, map[string]interface{}{"type": "CONNECT"})
+}
+
+// Load has a media app play a URL, returning once it reports the media
+// loaded
+func (c *Conn) Load(transportID, url, contentType, streamType string) error {
+	msg, err := c.Request(transportID, NamespaceMedia, map[string]interface{}{
+		"type":     "LOAD",
+		"autoplay": true,
+		"media": map[string]interface{}{
+			"contentId":   url,
+			"contentType": contentType,
+			"streamType":  streamType,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if msg.Type != "MEDIA_STATUS" {
+		return fmt.Errorf("media not loaded (%s)", msg.Type)
+	}
+	return nil
+}
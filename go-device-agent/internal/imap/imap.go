@@ -0,0 +1,228 @@
+// Package imap is a minimal read-only IMAP4rev1 client, enough to poll a
+// folder for new messages or summarize unread ones without a mail library
+package imap
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is a fetched message's headers and the start of its body
+type Message struct {
+	UID    uint32
+	Header []byte
+	Text   []byte
+}
+
+// literal is an IMAP literal and the response text that preceded it
+type literal struct {
+	prefix string
+	data   []byte
+}
+
+// responseLine is one untagged or tagged server response
+type responseLine struct {
+	text     string
+	literals []literal
+}
+
+var (
+	literalSuffix = regexp.MustCompile(`\{(\d+)\}$`)
+	uidField      = regexp.MustCompile(`UID (\d+)`)
+)
+
+// Client supports what reading a single folder needs: LOGIN, EXAMINE,
+// UID SEARCH, and UID FETCH of headers and text. It never changes flags.
+type Client struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+// Dial connects over TLS to server (host, or host:port with 993 the
+// default port), bounding the whole session by timeout
+func Dial(server string, timeout time.Duration) (*Client, error) {
+	host, _, err := net.SplitHostPort(server)
+	if err != nil {
+		host = server
+		server = net.JoinHostPort(server, "993")
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", server, &tls.Config{ServerName: host})
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	c := &Client{conn: conn, r: bufio.NewReader(conn)}
+	greeting, err := c.readLine()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(greeting.text, "* OK") {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected IMAP greeting: %s", greeting.text)
+	}
+	return c, nil
+}
+
+func (c *Client) Close() error {
+	c.command("LOGOUT")
+	return c.conn.Close()
+}
+
+func (c *Client) Login(username, password string) error {
+	_, err := c.command("LOGIN %s %s", quote(username), quote(password))
+	return err
+}
+
+// Examine selects a folder read-only so polling never changes flags
+func (c *Client) Examine(folder string) error {
+	_, err := c.command("EXAMINE %s", quote(folder))
+	return err
+}
+
+// SearchSince returns the UIDs of messages received on or after since
+func (c *Client) SearchSince(since time.Time) ([]uint32, error) {
+	return c.Search("SINCE " + since.Format("02-Jan-2006"))
+}
+
+// Search returns the UIDs of messages matching search criteria, such as
+// "UNSEEN", in ascending order
+func (c *Client) Search(criteria string) ([]uint32, error) {
+	lines, err := c.command("UID SEARCH %s", criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	var uids []uint32
+	for _, l := range lines {
+		if !strings.HasPrefix(l.text, "* SEARCH") {
+			continue
+		}
+		for _, f := range strings.Fields(strings.TrimPrefix(l.text, "* SEARCH")) {
+			if uid, err := strconv.ParseUint(f, 10, 32); err == nil {
+				uids = append(uids, uint32(uid))
+			}
+		}
+	}
+	return uids, nil
+}
+
+// Fetch returns the From, Subject, and Date headers and the first
+// textBytes of the body of each message (none when textBytes is 0)
+func (c *Client) Fetch(uids []uint32, textBytes int) ([]Message, error) {
+	if len(uids) == 0 {
+		return nil, nil
+	}
+	set := make([]string, len(uids))
+	for i, uid := range uids {
+		set[i] = strconv.FormatUint(uint64(uid), 10)
+	}
+
+	text := ""
+	if textBytes > 0 {
+		text = fmt.Sprintf(" BODY.PEEK[TEXT]<0.%d>", textBytes)
+	}
+	lines, err := c.command("UID FETCH %s (UID BODY.PEEK[HEADER.FIELDS (FROM SUBJECT DATE)]%s)",
+		strings.Join(set, ","), text)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []Message
+	for _, l := range lines {
+		if !strings.HasPrefix(l.text, "* ") || !strings.Contains(l.text, " FETCH ") {
+			continue
+		}
+		var m Message
+		if match := uidField.FindStringSubmatch(l.text); match != nil {
+			uid, _ := strconv.ParseUint(match[1], 10, 32)
+			m.UID = uint32(uid)
+		}
+		for _, lit := range l.literals {
+			switch {
+			case strings.Contains(lit.prefix, "HEADER.FIELDS"):
+				m.Header = lit.data
+			case strings.Contains(lit.prefix, "BODY[TEXT]"):
+				m.Text = lit.data
+			}
+		}
+		messages = append(messages, m)
+	}
+	return messages, nil
+}
+
+// command sends a tagged command and collects responses until its completion
+func (c *Client) command(format string, args ...interface{}) ([]responseLine, error) {
+	c.tag++
+	tag := fmt.Sprintf("a%d", c.tag)
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, fmt.Sprintf(format, args...)); err != nil {
+		return nil, err
+	}
+
+	var lines []responseLine
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(line.text, tag+" ") {
+			status := strings.TrimPrefix(line.text, tag+" ")
+			if !strings.HasPrefix(status, "OK") {
+				return nil, fmt.Errorf("IMAP command failed: %s", status)
+			}
+			return lines, nil
+		}
+		lines = append(lines, line)
+	}
+}
+
+// readLine reads one logical response line, consuming any literals it contains
+func (c *Client) readLine() (responseLine, error) {
+	var line responseLine
+	var segment strings.Builder
+
+	for {
+		raw, err := c.r.ReadString('\n')
+		if err != nil {
+			return line, err
+		}
+		raw = strings.TrimRight(raw, "\r\n")
+		segment.WriteString(raw)
+
+		match := literalSuffix.FindStringSubmatch(raw)
+		if match == nil {
+			line.text += segment.String()
+			return line, nil
+		}
+
+		size, err := strconv.Atoi(match[1])
+		if err != nil || size > 1<<20 {
+			return line, fmt.Errorf("invalid IMAP literal size: %s", match[1])
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(c.r, data); err != nil {
+			return line, err
+		}
+		line.literals = append(line.literals, literal{prefix: segment.String(), data: data})
+		line.text += segment.String()
+		segment.Reset()
+	}
+}
+
+// quote encodes s as an IMAP quoted string
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,228 @@
+// Package mdns finds DNS-SD services on the local network with one-shot
+// multicast DNS queries, enough to discover Hue bridges and Cast devices
+// without a resolver daemon
+package mdns
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"time"
+)
+
+var groupAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// Service is an instance of a service, as one response described it
+type Service struct {
+	// Instance is the full instance name, e.g.
+	// "Chromecast-1a2b._googlecast._tcp.local."
+	Instance string
+
+	// Addr is the instance's IPv4 address, or the responder's when the
+	// response carried no A record
+	Addr net.IP
+
+	// Port is from the SRV record; 0 when the response had none
+	Port int
+
+	// TXT holds the key=value pairs of the TXT record
+	TXT map[string]string
+}
+
+// Browse asks for instances of service (e.g. "_hue._tcp.local.") and calls
+// found for every response naming one, until ctx is done. It returns an
+// error only when the query can't be sent.
+func Browse(ctx context.Context, service string, found func(Service)) error {
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stopOnDone(ctx, conn)
+
+	if _, err := conn.WriteToUDP(query(service), groupAddr); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return nil
+		}
+		records, err := parse(buf[:n])
+		if err != nil {
+			continue
+		}
+		s := Service{TXT: make(map[string]string)}
+		matches := false
+		for _, r := range records {
+			switch r.typ {
+			case typePTR:
+				if strings.EqualFold(r.name, service) {
+					matches = true
+					s.Instance = r.target
+				}
+			case typeSRV:
+				s.Port = r.port
+			case typeTXT:
+				for _, kv := range r.txt {
+					k, v, _ := strings.Cut(kv, "=")
+					s.TXT[strings.ToLower(k)] = v
+				}
+			case typeA:
+				s.Addr = r.ip
+			}
+		}
+		if !matches {
+			continue
+		}
+		if s.Addr == nil {
+			s.Addr = from.IP
+		}
+		found(s)
+	}
+}
+
+// stopOnDone unblocks reads on conn once ctx is done
+func stopOnDone(ctx context.Context, conn *net.UDPConn) {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetReadDeadline(deadline)
+		return
+	}
+	go func() {
+		<-ctx.Done()
+		conn.SetReadDeadline(time.Now())
+	}()
+}
+
+// DNS record types used by discovery
+const (
+	typeA   = 1
+	typePTR = 12
+	typeTXT = 16
+	typeSRV = 33
+)
+
+type record struct {
+	name   string
+	typ    uint16
+	ip     net.IP
+	txt    []string
+	target string // PTR
+	port   int    // SRV
+}
+
+// query builds a PTR question with the unicast-response bit set, so
+// answers come straight back to our ephemeral port
+func query(name string) []byte {
+	msg := make([]byte, 12)
+	binary.BigEndian.PutUint16(msg[4:], 1) // one question
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, typePTR)
+	return binary.BigEndian.AppendUint16(msg, 0x8001) // QU, class IN
+}
+
+var errBadDNS = errors.New("malformed DNS message")
+
+// parse returns the answer, authority, and additional records of a
+// response
+func parse(msg []byte) ([]record, error) {
+	if len(msg) < 12 {
+		return nil, errBadDNS
+	}
+	questions := int(binary.BigEndian.Uint16(msg[4:]))
+	count := int(binary.BigEndian.Uint16(msg[6:])) + int(binary.BigEndian.Uint16(msg[8:])) + int(binary.BigEndian.Uint16(msg[10:]))
+	off := 12
+	for range questions {
+		_, n, err := readName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+		off = n + 4
+	}
+
+	var records []record
+	for range count {
+		name, n, err := readName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+		off = n
+		if off+10 > len(msg) {
+			return nil, errBadDNS
+		}
+		typ := binary.BigEndian.Uint16(msg[off:])
+		length := int(binary.BigEndian.Uint16(msg[off+8:]))
+		off += 10
+		if off+length > len(msg) {
+			return nil, errBadDNS
+		}
+		data := msg[off : off+length]
+		r := record{name: name, typ: typ}
+		switch typ {
+		case typeA:
+			if length == 4 {
+				r.ip = net.IP(append([]byte(nil), data...))
+			}
+		case typePTR:
+			// Names in record data may point back into the whole message
+			if target, _, err := readName(msg, off); err == nil {
+				r.target = target
+			}
+		case typeSRV:
+			if length >= 6 {
+				r.port = int(binary.BigEndian.Uint16(data[4:]))
+			}
+		case typeTXT:
+			for len(data) > 0 && int(data[0]) < len(data) {
+				r.txt = append(r.txt, string(data[1:1+data[0]]))
+				data = data[1+data[0]:]
+			}
+		}
+		records = append(records, r)
+		off += length
+	}
+	return records, nil
+}
+
+// readName decodes a possibly compressed name at off, returning it and the
+// offset just past it
+func readName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	end := -1
+	for jumps := 0; ; {
+		if off >= len(msg) {
+			return "", 0, errBadDNS
+		}
+		n := int(msg[off])
+		switch {
+		case n == 0:
+			if end < 0 {
+				end = off + 1
+			}
+			return strings.Join(labels, ".") + ".", end, nil
+		case n&0xC0 == 0xC0:
+			if off+1 >= len(msg) || jumps > 16 {
+				return "", 0, errBadDNS
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3FFF)
+			jumps++
+		default:
+			if off+1+n > len(msg) {
+				return "", 0, errBadDNS
+			}
+			labels = append(labels, string(msg[off+1:off+1+n]))
+			off += 1 + n
+		}
+	}
+}
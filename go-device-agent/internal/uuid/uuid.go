@@ -0,0 +1,207 @@
+// Package uuid generates identifiers for intents, events, and records:
+// random UUIDv4s, or time-ordered UUIDv7s or ULIDs when a strategy that
+// sorts by creation time is chosen
+package uuid
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ID strategies for NewID
+const (
+	V4   = "uuidv4"
+	V7   = "uuidv7"
+	ULID = "ulid"
+)
+
+var strategy atomic.Value // string
+
+// SetStrategy chooses what NewID generates: V4 (the default), V7, or ULID
+func SetStrategy(name string) error {
+	switch name = strings.ToLower(name); name {
+	case V4, V7, ULID:
+		strategy.Store(name)
+		return nil
+	case "", "uuid", "v4":
+		strategy.Store(V4)
+		return nil
+	}
+	return fmt.Errorf("unknown ID strategy %q (uuidv4, uuidv7, or ulid)", name)
+}
+
+// Strategy returns the strategy NewID uses
+func Strategy() string {
+	if s, ok := strategy.Load().(string); ok {
+		return s
+	}
+	return V4
+}
+
+// NewID returns an ID for an intent, event, or job in the chosen strategy.
+// UUIDv7s and ULIDs from one process are strictly increasing, so they sort
+// in the order they were made, even within a millisecond.
+func NewID() string {
+	switch Strategy() {
+	case V7:
+		return NewV7()
+	case ULID:
+		return NewULID()
+	}
+	return New()
+}
+
+// New returns a random UUIDv4 string, for tokens and IDs that shouldn't
+// reveal when they were made
+func New() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return format(b)
+}
+
+func format(b [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// monotonic hands out a millisecond timestamp and random bits that
+// strictly increase: within a millisecond the random bits are counted up
+// from the last ID's, and when they run out the timestamp moves on
+type monotonic struct {
+	hiBits, loBits uint
+
+	mu sync.Mutex
+	ms uint64
+	hi uint64
+	lo uint64
+}
+
+func (m *monotonic) next() (ms, hi, lo uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := uint64(time.Now().UnixMilli())
+	if now > m.ms {
+		m.ms = now
+		m.seed()
+		return m.ms, m.hi, m.lo
+	}
+	m.lo = (m.lo + 1) & (1<<m.loBits - 1)
+	if m.lo == 0 {
+		m.hi = (m.hi + 1) & (1<<m.hiBits - 1)
+		if m.hi == 0 {
+			m.ms++
+			m.seed()
+		}
+	}
+	return m.ms, m.hi, m.lo
+}
+
+// seed draws fresh random bits, leaving the top bit of lo clear so a burst
+// of IDs in one millisecond has room to count up
+func (m *monotonic) seed() {
+	var b [16]byte
+	rand.Read(b[:])
+	m.hi = binary.BigEndian.Uint64(b[:8]) & (1<<m.hiBits - 1)
+	m.lo = binary.BigEndian.Uint64(b[8:]) & (1<<(m.loBits-1) - 1)
+}
+
+var (
+	v7Clock   = &monotonic{hiBits: 12, loBits: 62}
+	ulidClock = &monotonic{hiBits: 16, loBits: 64}
+)
+
+// NewV7 returns a UUIDv7: a 48-bit Unix millisecond timestamp followed by
+// random bits, so IDs sort by creation time
+func NewV7() string {
+	ms, hi, lo := v7Clock.next()
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], ms<<16|0x7<<12|hi)
+	binary.BigEndian.PutUint64(b[8:], 0b10<<62|lo)
+	return format(b)
+}
+
+// crockford is the ULID alphabet, Crockford's base 32
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID returns a ULID: 26 characters encoding a 48-bit millisecond
+// timestamp and 80 random bits, sortable as text
+func NewULID() string {
+	ms, hi, lo := ulidClock.next()
+	// 128 bits as 26 five-bit digits, the first holding the top 3 bits
+	top := ms<<16 | hi
+	var out [26]byte
+	for n := 25; n >= 0; n-- {
+		out[n] = crockford[lo&31]
+		lo = lo>>5 | (top&31)<<59
+		top >>= 5
+	}
+	return string(out[:])
+}
+
+// Time returns when a UUIDv7 or ULID was generated; other IDs carry no
+// time
+func Time(id string) (time.Time, bool) {
+	switch {
+	case ValidULID(id):
+		var ms uint64
+		for _, c := range strings.ToUpper(id[:10]) {
+			ms = ms<<5 | uint64(strings.IndexRune(crockford, c))
+		}
+		return time.UnixMilli(int64(ms)), true
+	case Valid(id) && id[14] == '7':
+		b, err := hex.DecodeString(id[:8] + id[9:13])
+		if err != nil {
+			return time.Time{}, false
+		}
+		return time.UnixMilli(int64(binary.BigEndian.Uint64(append([]byte{0, 0}, b...)))), true
+	}
+	return time.Time{}, false
+}
+
+// Valid reports whether s is a UUID in canonical 8-4-4-4-12 hex form
+func Valid(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ValidULID reports whether s is a ULID: 26 Crockford base 32 characters,
+// in either case, no larger than 128 bits
+func ValidULID(s string) bool {
+	if len(s) != 26 || s[0] > '7' {
+		return false
+	}
+	for _, c := range strings.ToUpper(s) {
+		if !strings.ContainsRune(crockford, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidID reports whether s is an ID NewID could have generated under any
+// strategy: a UUID or a ULID
+func ValidID(s string) bool {
+	return Valid(s) || ValidULID(s)
+}
@@ -0,0 +1,214 @@
+// Package wire holds the protobuf field helpers shared by the intent and
+// result encoders. Messages are hand-encoded with protowire so the agent does
+// not need generated code; proto/agent.proto documents the field numbers.
+package wire
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// AppendString appends a string field, skipping the proto3 default
+func AppendString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+// AppendBytes appends a bytes field, skipping the proto3 default
+func AppendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+// AppendBool appends a bool field, skipping the proto3 default
+func AppendBool(b []byte, num protowire.Number, v bool) []byte {
+	if !v {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, protowire.EncodeBool(v))
+}
+
+// AppendFloat appends a float field, skipping the proto3 default
+func AppendFloat(b []byte, num protowire.Number, v float32) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.Fixed32Type)
+	return protowire.AppendFixed32(b, math.Float32bits(v))
+}
+
+// AppendMessage appends an embedded message encoded by the caller. Unlike
+// the other helpers it always writes the field, so repeated messages keep
+// their count.
+func AppendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+// AppendInt64 appends an int64 field. Unlike the other helpers it always
+// writes the value, so callers use it for proto3 optional fields.
+func AppendInt64(b []byte, num protowire.Number, v int64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(v))
+}
+
+// AppendStruct appends a map as a google.protobuf.Struct field.
+// Values that Struct can't hold directly (typed structs and slices) are
+// normalised through their JSON form first.
+func AppendStruct(b []byte, num protowire.Number, v map[string]interface{}) ([]byte, error) {
+	if v == nil {
+		return b, nil
+	}
+	s, err := structpb.NewStruct(v)
+	if err != nil {
+		s, err = normalizedStruct(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	data, err := proto.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, data), nil
+}
+
+func normalizedStruct(v map[string]interface{}) (*structpb.Struct, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(m)
+}
+
+// AppendTime appends a time as a google.protobuf.Timestamp field
+func AppendTime(b []byte, num protowire.Number, t time.Time) []byte {
+	if t.IsZero() {
+		return b
+	}
+	var ts []byte
+	ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+	ts = protowire.AppendVarint(ts, uint64(t.Unix()))
+	if nanos := t.Nanosecond(); nanos != 0 {
+		ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+		ts = protowire.AppendVarint(ts, uint64(nanos))
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, ts)
+}
+
+// Field is a single decoded field passed to a Walk callback
+type Field struct {
+	Num  protowire.Number
+	Type protowire.Type
+	raw  []byte
+	v    uint64
+}
+
+// String returns the field as a string
+func (f Field) String() string {
+	return string(f.raw)
+}
+
+// Bytes returns the raw contents of a length-delimited field,
+// which for embedded messages can be passed to Walk
+func (f Field) Bytes() []byte {
+	return f.raw
+}
+
+// Int64 returns a varint field as a signed integer
+func (f Field) Int64() int64 {
+	return int64(f.v)
+}
+
+// Bool returns the field as a bool
+func (f Field) Bool() bool {
+	return protowire.DecodeBool(f.v)
+}
+
+// Float returns the field as a float
+func (f Field) Float() float32 {
+	return math.Float32frombits(uint32(f.v))
+}
+
+// Struct decodes the field as a google.protobuf.Struct
+func (f Field) Struct() (map[string]interface{}, error) {
+	var s structpb.Struct
+	if err := proto.Unmarshal(f.raw, &s); err != nil {
+		return nil, err
+	}
+	return s.AsMap(), nil
+}
+
+// Time decodes the field as a google.protobuf.Timestamp
+func (f Field) Time() (time.Time, error) {
+	var secs, nanos int64
+	err := Walk(f.raw, func(tf Field) error {
+		switch tf.Num {
+		case 1:
+			secs = int64(tf.v)
+		case 2:
+			nanos = int64(tf.v)
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(secs, nanos).UTC(), nil
+}
+
+// Walk calls fn for every field in a message. Unknown fields are passed
+// through as well so callers can simply ignore numbers they don't know.
+func Walk(data []byte, fn func(Field) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		f := Field{Num: num, Type: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.v, n = protowire.ConsumeVarint(data)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(data)
+			f.v = uint64(v)
+		case protowire.Fixed64Type:
+			f.v, n = protowire.ConsumeFixed64(data)
+		case protowire.BytesType:
+			f.raw, n = protowire.ConsumeBytes(data)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if n < 0 {
+			return fmt.Errorf("field %d: %w", num, protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package access
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// token builds a compact JWT, signing it with sign if it isn't nil
+func token(t *testing.T, header, claims map[string]interface{}, sign func([]byte) []byte) string {
+	t.Helper()
+	segment := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := segment(header) + "." + segment(claims)
+	var sig []byte
+	if sign != nil {
+		sig = sign([]byte(signed))
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func hs256(secret []byte) func([]byte) []byte {
+	return func(msg []byte) []byte {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(msg)
+		return mac.Sum(nil)
+	}
+}
+
+// testPolicy accepts EdDSA tokens from "ed", HS256 tokens from "hs", and
+// the API key "core-key" for identity "core"
+func testPolicy(t *testing.T) (*Policy, ed25519.PublicKey, ed25519.PrivateKey, []byte) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	p := &Policy{
+		Roles: map[string]Role{"core": {Rules: []Rule{{IntentTypes: []string{"*"}}}}},
+		Identities: []Identity{
+			{Name: "core", Roles: []string{"core"}, APIKeySHA256: hashToken("core-key")},
+			{Name: "app", Roles: []string{"core"}},
+		},
+		JWT: &JWTConfig{
+			Issuer:   "https://auth.home.lan",
+			Audience: "device-agent",
+			Keys: []JWTKey{
+				{ID: "ed", Algorithm: "EdDSA", PublicKey: pub},
+				{ID: "hs", Algorithm: "HS256", Secret: secret},
+			},
+		},
+	}
+	if err := p.check(); err != nil {
+		t.Fatal(err)
+	}
+	return p, pub, priv, secret
+}
+
+func validClaims() map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"sub": "app",
+		"iss": "https://auth.home.lan",
+		"aud": "device-agent",
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+	}
+}
+
+func with(claims map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	claims[key] = value
+	return claims
+}
+
+func without(claims map[string]interface{}, key string) map[string]interface{} {
+	delete(claims, key)
+	return claims
+}
+
+func TestAuthenticateJWT(t *testing.T) {
+	p, pub, priv, secret := testPolicy(t)
+	eddsa := func(msg []byte) []byte { return ed25519.Sign(priv, msg) }
+	edHeader := map[string]interface{}{"alg": "EdDSA", "kid": "ed", "typ": "JWT"}
+	hour := time.Hour
+
+	tests := []struct {
+		name  string
+		token string
+		want  error // nil to accept
+	}{
+		{"EdDSA", token(t, edHeader, validClaims(), eddsa), nil},
+		{"HS256", token(t, map[string]interface{}{"alg": "HS256", "kid": "hs"}, validClaims(), hs256(secret)), nil},
+		{"audience list", token(t, edHeader, with(validClaims(), "aud", []string{"other", "device-agent"}), eddsa), nil},
+		{"within leeway", token(t, edHeader, with(validClaims(), "exp", time.Now().Add(-30*time.Second).Unix()), eddsa), nil},
+
+		// alg confusion: the EdDSA public key used as an HMAC secret, and
+		// unsigned tokens, with and without a kid
+		{"HS256 with the EdDSA key", token(t, map[string]interface{}{"alg": "HS256", "kid": "ed"}, validClaims(), hs256(pub)), ErrUnknownKey},
+		{"HS256 with the EdDSA key, no kid", token(t, map[string]interface{}{"alg": "HS256"}, validClaims(), hs256(pub)), ErrBadSignature},
+		{"alg none", token(t, map[string]interface{}{"alg": "none"}, validClaims(), nil), ErrUnknownKey},
+		{"alg none naming a key", token(t, map[string]interface{}{"alg": "none", "kid": "ed"}, validClaims(), nil), ErrUnknownKey},
+		{"alg None", token(t, map[string]interface{}{"alg": "None", "kid": "hs"}, validClaims(), nil), ErrUnknownKey},
+		{"EdDSA unsigned", token(t, edHeader, validClaims(), nil), ErrBadSignature},
+		{"EdDSA naming the HS256 key", token(t, map[string]interface{}{"alg": "EdDSA", "kid": "hs"}, validClaims(), eddsa), ErrUnknownKey},
+
+		{"expired", token(t, edHeader, with(validClaims(), "exp", time.Now().Add(-hour).Unix()), eddsa), ErrTokenExpired},
+		{"no expiry", token(t, edHeader, without(validClaims(), "exp"), eddsa), ErrTokenExpired},
+		{"not yet valid", token(t, edHeader, with(validClaims(), "nbf", time.Now().Add(hour).Unix()), eddsa), ErrTokenNotYet},
+		{"wrong audience", token(t, edHeader, with(validClaims(), "aud", "another-service"), eddsa), ErrWrongAudience},
+		{"wrong audience list", token(t, edHeader, with(validClaims(), "aud", []string{"a", "b"}), eddsa), ErrWrongAudience},
+		{"no audience", token(t, edHeader, without(validClaims(), "aud"), eddsa), ErrWrongAudience},
+		{"wrong issuer", token(t, edHeader, with(validClaims(), "iss", "https://evil.example"), eddsa), ErrWrongIssuer},
+		{"unknown subject", token(t, edHeader, with(validClaims(), "sub", "mallory"), eddsa), ErrUnknownSubject},
+		{"malformed", "a.b.c", ErrMalformedToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := p.Authenticate(tt.token)
+			switch {
+			case tt.want == nil && err != nil:
+				t.Fatalf("rejected: %v", err)
+			case tt.want == nil && id.Name != "app":
+				t.Fatalf("authenticated as %s, want app", id.Name)
+			case tt.want != nil && !errors.Is(err, tt.want):
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticateJWTTamperedClaims(t *testing.T) {
+	p, _, priv, _ := testPolicy(t)
+	good := token(t, map[string]interface{}{"alg": "EdDSA", "kid": "ed"}, validClaims(), func(msg []byte) []byte { return ed25519.Sign(priv, msg) })
+	forged := token(t, map[string]interface{}{"alg": "EdDSA", "kid": "ed"}, with(validClaims(), "sub", "core"), nil)
+
+	// The claims of one token with the signature of another
+	parts, sig := strings.Split(forged, "."), strings.Split(good, ".")[2]
+	if _, err := p.Authenticate(parts[0] + "." + parts[1] + "." + sig); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("got %v, want ErrBadSignature", err)
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	p, _, _, _ := testPolicy(t)
+	if id, err := p.Authenticate("core-key"); err != nil || id.Name != "core" {
+		t.Fatalf("got %v, %v; want core", id, err)
+	}
+	for name, key := range map[string]string{
+		"wrong key":         "core-key2",
+		"the hash itself":   hashToken("core-key"),
+		"differing in case": "Core-key",
+	} {
+		if _, err := p.Authenticate(key); !errors.Is(err, ErrUnknownAPIKey) {
+			t.Errorf("%s: got %v, want ErrUnknownAPIKey", name, err)
+		}
+	}
+	if _, err := p.Authenticate(""); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("no key: got %v, want ErrNoCredentials", err)
+	}
+}
+
+func TestPolicyRejectsBadKeyHashes(t *testing.T) {
+	for name, id := range map[string]Identity{
+		"not hex":   {Name: "core", APIKeySHA256: "zz" + hashToken("k")[2:]},
+		"too short": {Name: "core", APIKeySHA256: hashToken("k")[:32]},
+	} {
+		p := &Policy{Identities: []Identity{id}}
+		if err := p.check(); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+	shared := &Policy{Identities: []Identity{
+		{Name: "a", APIKeySHA256: hashToken("k")},
+		{Name: "b", APIKeySHA256: hashToken("k")},
+	}}
+	if err := shared.check(); err == nil {
+		t.Error("two identities sharing a key: accepted")
+	}
+}
+
+func TestAuthenticatorRevokesKeysOnReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "roles.json")
+	write := func(identities string, modTime time.Time) {
+		t.Helper()
+		data := `{"roles": {"core": {"rules": [{"intent_types": ["*"]}]}}, "identities": [` + identities + `]}`
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+	write(`{"name": "core", "roles": ["core"], "api_key_sha256": "`+hashToken("old-key")+`"}`, start)
+	a, err := NewAuthenticator(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Authenticate("old-key"); err != nil {
+		t.Fatalf("key before rotation: %v", err)
+	}
+
+	write(`{"name": "core", "roles": ["core"], "api_key_sha256": "`+hashToken("new-key")+`"}`, start.Add(time.Minute))
+	if p, err := a.Reload(); err != nil || p == nil {
+		t.Fatalf("reload: %v, %v", p, err)
+	}
+	if _, err := a.Authenticate("old-key"); !errors.Is(err, ErrUnknownAPIKey) {
+		t.Errorf("revoked key: got %v, want ErrUnknownAPIKey", err)
+	}
+	if _, err := a.Authenticate("new-key"); err != nil {
+		t.Errorf("new key: %v", err)
+	}
+
+	// A broken file keeps the keys in use
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, start.Add(2*time.Minute), start.Add(2*time.Minute))
+	if _, err := a.Reload(); err == nil {
+		t.Error("a broken roles file reloaded")
+	}
+	if _, err := a.Authenticate("new-key"); err != nil {
+		t.Errorf("key after a failed reload: %v", err)
+	}
+
+	stats := a.Stats()
+	if stats.Accepted != 3 || stats.Rejected["unknown_api_key"] != 1 || stats.Reloads != 1 || stats.ReloadErrors != 1 {
+		t.Errorf("stats: %+v", stats)
+	}
+}
@@ -0,0 +1,181 @@
+package access
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"sync"
+	"time"
+)
+
+// rejectReasons names authentication failures in AuthStats
+var rejectReasons = []struct {
+	err    error
+	reason string
+}{
+	{ErrNoCredentials, "no_credentials"},
+	{ErrUnknownAPIKey, "unknown_api_key"},
+	{ErrMalformedToken, "malformed_token"},
+	{ErrUnknownKey, "unknown_signing_key"},
+	{ErrBadSignature, "bad_signature"},
+	{ErrTokenExpired, "expired"},
+	{ErrTokenNotYet, "not_yet_valid"},
+	{ErrWrongIssuer, "wrong_issuer"},
+	{ErrWrongAudience, "wrong_audience"},
+	{ErrUnknownSubject, "unknown_subject"},
+	{ErrUnknownCert, "unknown_certificate"},
+}
+
+// AuthStats counts authentication outcomes since the agent started
+type AuthStats struct {
+	Accepted uint64            `json:"accepted"`
+	Rejected map[string]uint64 `json:"rejected"`
+
+	// LoadedAt is when the policy in use was read. Reloads counts the
+	// times it was replaced, and ReloadErrors the changed files that
+	// were refused, the last with LastReloadError.
+	LoadedAt        time.Time `json:"loaded_at"`
+	Reloads         uint64    `json:"reloads"`
+	ReloadErrors    uint64    `json:"reload_errors"`
+	LastReloadError string    `json:"last_reload_error,omitempty"`
+}
+
+// Authenticator checks transport credentials against a roles file,
+// rereading it when it changes so keys can be added, rotated, and revoked
+// without a restart
+type Authenticator struct {
+	path string
+
+	mu      sync.RWMutex
+	policy  *Policy
+	modTime time.Time
+	stats   AuthStats
+}
+
+// NewAuthenticator loads the roles file at path (see LoadPolicy)
+func NewAuthenticator(path string) (*Authenticator, error) {
+	a := &Authenticator{path: path, stats: AuthStats{Rejected: make(map[string]uint64)}}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := LoadPolicy(path)
+	if err != nil {
+		return nil, err
+	}
+	a.policy, a.modTime, a.stats.LoadedAt = p, info.ModTime(), time.Now().UTC()
+	return a, nil
+}
+
+// Policy returns the policy in use
+func (a *Authenticator) Policy() *Policy {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.policy
+}
+
+// Authenticate returns the identity presenting the credential, counting
+// the outcome
+func (a *Authenticator) Authenticate(credential string) (*Identity, error) {
+	id, err := a.Policy().Authenticate(credential)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err != nil {
+		a.stats.Rejected[reasonOf(err)]++
+		return nil, err
+	}
+	a.stats.Accepted++
+	return id, nil
+}
+
+// AuthenticateCertificate returns the identity a verified TLS client
+// certificate names in its common name, counting the outcome
+func (a *Authenticator) AuthenticateCertificate(cert *x509.Certificate) (*Identity, error) {
+	id, ok := a.Policy().Identity(cert.Subject.CommonName)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !ok {
+		a.stats.Rejected[reasonOf(ErrUnknownCert)]++
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCert, cert.Subject.CommonName)
+	}
+	a.stats.Accepted++
+	return id, nil
+}
+
+func reasonOf(err error) string {
+	for _, r := range rejectReasons {
+		if errors.Is(err, r.err) {
+			return r.reason
+		}
+	}
+	return "other"
+}
+
+// Stats returns the authentication counts
+func (a *Authenticator) Stats() AuthStats {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	stats := a.stats
+	stats.Rejected = maps.Clone(a.stats.Rejected)
+	return stats
+}
+
+// Reload rereads the roles file if it changed, returning the new policy,
+// or nil if it is unchanged. A file that fails to load leaves the policy
+// in use.
+func (a *Authenticator) Reload() (*Policy, error) {
+	info, err := os.Stat(a.path)
+	if err == nil {
+		a.mu.RLock()
+		unchanged := info.ModTime().Equal(a.modTime)
+		a.mu.RUnlock()
+		if unchanged {
+			return nil, nil
+		}
+	}
+	var p *Policy
+	if err == nil {
+		p, err = LoadPolicy(a.path)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err != nil {
+		if info != nil {
+			a.modTime = info.ModTime() // report a bad file once, not every poll
+		}
+		a.stats.ReloadErrors++
+		a.stats.LastReloadError = err.Error()
+		return nil, err
+	}
+	a.policy, a.modTime = p, info.ModTime()
+	a.stats.LoadedAt = time.Now().UTC()
+	a.stats.Reloads++
+	a.stats.LastReloadError = ""
+	return p, nil
+}
+
+// Watch checks the roles file for changes every interval until ctx is
+// cancelled, calling onReload with each new policy or failed reload
+func (a *Authenticator) Watch(ctx context.Context, interval time.Duration, onReload func(*Policy, error)) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p, err := a.Reload()
+				if (p != nil || err != nil) && onReload != nil {
+					onReload(p, err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,316 @@
+// Package access issues time-boxed guest grants: scoped permissions tied to
+// a token or a voice profile that lapse on their own
+package access
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/internal/uuid"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// MaxGrantDuration caps how long one grant lasts
+const MaxGrantDuration = 30 * 24 * time.Hour
+
+// historyRetention is how long lapsed grants stay listed
+const historyRetention = 30 * 24 * time.Hour
+
+// Rule allows intent types, optionally only with certain parameter values.
+// Intent types may end in ".*" to match a whole module. Params maps a
+// parameter name to its allowed values, compared case-insensitively; an
+// intent missing a listed parameter doesn't match.
+type Rule struct {
+	IntentTypes []string            `json:"intent_types"`
+	Params      map[string][]string `json:"params,omitempty"`
+}
+
+// Grant is a guest's scoped, time-limited permission
+type Grant struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	SpeakerID string    `json:"speaker_id,omitempty"`
+	Rules     []Rule    `json:"rules"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	RevokedAt time.Time `json:"revoked_at,omitzero"`
+
+	TokenHash string `json:"token_hash,omitempty"`
+}
+
+// Active reports whether the grant is in force at t
+func (g *Grant) Active(t time.Time) bool {
+	return g.RevokedAt.IsZero() && t.Before(g.ExpiresAt)
+}
+
+// Allows reports whether one of the grant's rules covers the intent
+func (g *Grant) Allows(i *intent.Intent) bool {
+	for _, r := range g.Rules {
+		if r.Matches(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether the rule covers the intent: its type matches one
+// of the patterns and every listed parameter has one of the listed values
+func (r Rule) Matches(i *intent.Intent) bool {
+	matched := false
+	for _, pattern := range r.IntentTypes {
+		if MatchIntentType(pattern, i.IntentType) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return false
+	}
+	for name, allowed := range r.Params {
+		v, ok := i.Parameters[name]
+		if !ok {
+			return false
+		}
+		value := fmt.Sprint(v)
+		found := false
+		for _, a := range allowed {
+			if strings.EqualFold(a, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchIntentType matches an intent type against "device.control",
+// "device.*", or "*"
+func MatchIntentType(pattern, intentType string) bool {
+	if pattern == "*" || pattern == intentType {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, ".*"); ok {
+		return strings.HasPrefix(intentType, prefix+".")
+	}
+	return false
+}
+
+// Grants is the persisted set of guest grants
+type Grants struct {
+	path string
+
+	mu     sync.Mutex
+	grants []*Grant
+}
+
+// Open loads the grants stored at path, which need not exist yet
+func Open(path string) (*Grants, error) {
+	g := &Grants{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return g, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &g.grants); err != nil {
+		return nil, fmt.Errorf("invalid grants file %s: %w", path, err)
+	}
+	return g, nil
+}
+
+// Create mints a grant lasting d. Unless speakerID is set it is tied to the
+// returned token, which is shown only once.
+func (g *Grants) Create(name, speakerID string, rules []Rule, d time.Duration) (Grant, string, error) {
+	if len(rules) == 0 {
+		return Grant{}, "", agenterrors.New(agenterrors.InvalidParams, "a grant needs at least one rule")
+	}
+	for _, r := range rules {
+		if len(r.IntentTypes) == 0 {
+			return Grant{}, "", agenterrors.New(agenterrors.InvalidParams, "every rule needs intent_types")
+		}
+		for _, t := range r.IntentTypes {
+			if MatchIntentType(t, "guest.grant") || MatchIntentType(t, "guest.revoke") {
+				return Grant{}, "", agenterrors.New(agenterrors.DeniedByPolicy, "guests cannot manage grants")
+			}
+		}
+	}
+	if d <= 0 || d > MaxGrantDuration {
+		return Grant{}, "", agenterrors.Newf(agenterrors.InvalidParams, "grant duration must be between 0 and %s", MaxGrantDuration)
+	}
+
+	now := time.Now().UTC()
+	grant := &Grant{
+		ID:        uuid.NewID(),
+		Name:      name,
+		SpeakerID: speakerID,
+		Rules:     rules,
+		CreatedAt: now,
+		ExpiresAt: now.Add(d),
+	}
+	var token string
+	if speakerID == "" {
+		var raw [24]byte
+		if _, err := rand.Read(raw[:]); err != nil {
+			return Grant{}, "", err
+		}
+		token = "guest_" + base64.RawURLEncoding.EncodeToString(raw[:])
+		grant.TokenHash = hashToken(token)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.grants = append(g.grants, grant)
+	if err := g.save(); err != nil {
+		g.grants = g.grants[:len(g.grants)-1]
+		return Grant{}, "", err
+	}
+	return *grant, token, nil
+}
+
+// Revoke ends a grant early
+func (g *Grants) Revoke(id string) (Grant, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, grant := range g.grants {
+		if grant.ID != id {
+			continue
+		}
+		if grant.RevokedAt.IsZero() {
+			grant.RevokedAt = time.Now().UTC()
+			if err := g.save(); err != nil {
+				return Grant{}, err
+			}
+		}
+		return *grant, nil
+	}
+	return Grant{}, agenterrors.Newf(agenterrors.NotFound, "no grant %s", id)
+}
+
+// List returns every grant, including recently lapsed ones, oldest first
+func (g *Grants) List() []Grant {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	list := make([]Grant, len(g.grants))
+	for n, grant := range g.grants {
+		list[n] = *grant
+	}
+	return list
+}
+
+// Lookup finds the grant an intent acts under. It returns nil for intents
+// that are not from a guest, and an error for unknown or lapsed tokens;
+// a lapsed grant is returned with its error.
+func (g *Grants) Lookup(i *intent.Intent) (*Grant, error) {
+	if i.GuestToken == "" && i.SpeakerID == "" {
+		return nil, nil
+	}
+	now := time.Now()
+	hash := hashToken(i.GuestToken)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, grant := range g.grants {
+		var match bool
+		if i.GuestToken != "" {
+			match = grant.TokenHash != "" && subtle.ConstantTimeCompare([]byte(grant.TokenHash), []byte(hash)) == 1
+		} else {
+			match = grant.SpeakerID == i.SpeakerID && grant.Active(now)
+		}
+		if !match {
+			continue
+		}
+		found := *grant
+		if !grant.Active(now) {
+			return &found, agenterrors.Newf(agenterrors.Unauthorized, "guest access for %s has ended", grant.Name)
+		}
+		return &found, nil
+	}
+	if i.GuestToken != "" {
+		return nil, agenterrors.New(agenterrors.Unauthorized, "unknown guest token")
+	}
+	return nil, nil // a recognized household voice, not a guest
+}
+
+// Start revokes grants as they expire, calling onExpire for each, and
+// forgets long-lapsed ones, until ctx is cancelled
+func (g *Grants) Start(ctx context.Context, onExpire func(Grant)) {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				for _, grant := range g.sweep(now) {
+					if onExpire != nil {
+						onExpire(grant)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// sweep marks expired grants revoked and returns them
+func (g *Grants) sweep(now time.Time) []Grant {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var expired []Grant
+	kept := g.grants[:0]
+	for _, grant := range g.grants {
+		if grant.RevokedAt.IsZero() && !now.Before(grant.ExpiresAt) {
+			grant.RevokedAt = grant.ExpiresAt
+			expired = append(expired, *grant)
+		}
+		if !grant.RevokedAt.IsZero() && now.Sub(grant.RevokedAt) > historyRetention {
+			continue
+		}
+		kept = append(kept, grant)
+	}
+	changed := len(expired) > 0 || len(kept) != len(g.grants)
+	g.grants = kept
+	if changed {
+		g.save() // retried on the next sweep if it fails
+	}
+	return expired
+}
+
+// save writes the grants atomically. Callers hold g.mu.
+func (g *Grants) save() error {
+	data, err := json.MarshalIndent(g.grants, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(g.path), 0o700); err != nil {
+		return err
+	}
+	tmp := g.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, g.path)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
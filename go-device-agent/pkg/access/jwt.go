@@ -0,0 +1,209 @@
+package access
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Authentication failures, which transports count by reason
+var (
+	ErrNoCredentials  = errors.New("no credentials")
+	ErrUnknownAPIKey  = errors.New("unknown API key")
+	ErrMalformedToken = errors.New("malformed token")
+	ErrUnknownKey     = errors.New("unknown signing key")
+	ErrBadSignature   = errors.New("bad token signature")
+	ErrTokenExpired   = errors.New("token expired")
+	ErrTokenNotYet    = errors.New("token not yet valid")
+	ErrWrongIssuer    = errors.New("wrong token issuer")
+	ErrWrongAudience  = errors.New("wrong token audience")
+	ErrUnknownSubject = errors.New("token subject is not a known identity")
+	ErrUnknownCert    = errors.New("client certificate names no known identity")
+)
+
+// minSecret is the shortest HS256 secret accepted, per RFC 7518
+const minSecret = 32
+
+// JWTConfig accepts signed JSON Web Tokens whose sub claim names an
+// identity. Tokens must expire; the identity's roles come from the policy,
+// never from the token.
+type JWTConfig struct {
+	// Issuer and Audience, when set, must match the iss and aud claims
+	Issuer   string `json:"issuer,omitempty"`
+	Audience string `json:"audience,omitempty"`
+
+	// Keys verify tokens. Several may be listed while rotating: add the new
+	// key, switch the issuer to it, then remove the old one.
+	Keys []JWTKey `json:"keys"`
+
+	// Leeway tolerates clock skew in exp and nbf, as a Go duration
+	// (default 1m)
+	Leeway string `json:"leeway,omitempty"`
+
+	leeway time.Duration
+}
+
+// JWTKey verifies tokens signed with HS256 (Secret) or EdDSA with Ed25519
+// (PublicKey), both base64. Tokens naming the key's ID in their kid header
+// are only checked against it.
+type JWTKey struct {
+	ID        string            `json:"id"`
+	Algorithm string            `json:"alg"`
+	Secret    []byte            `json:"secret,omitempty"`
+	PublicKey ed25519.PublicKey `json:"public_key,omitempty"`
+	NotAfter  time.Time         `json:"not_after,omitzero"`
+}
+
+func (c *JWTConfig) check() error {
+	c.leeway = time.Minute
+	if c.Leeway != "" {
+		d, err := time.ParseDuration(c.Leeway)
+		if err != nil || d < 0 {
+			return errors.New("jwt leeway must be a Go duration such as 30s")
+		}
+		c.leeway = d
+	}
+	ids := make(map[string]bool)
+	for _, k := range c.Keys {
+		if k.ID == "" {
+			return errors.New("jwt key has no id")
+		}
+		if ids[k.ID] {
+			return fmt.Errorf("jwt key %s is listed twice", k.ID)
+		}
+		ids[k.ID] = true
+		switch k.Algorithm {
+		case "HS256":
+			if len(k.Secret) < minSecret {
+				return fmt.Errorf("jwt key %s: HS256 secret must be at least %d bytes", k.ID, minSecret)
+			}
+		case "EdDSA":
+			if len(k.PublicKey) != ed25519.PublicKeySize {
+				return fmt.Errorf("jwt key %s: public key must be %d bytes", k.ID, ed25519.PublicKeySize)
+			}
+		default:
+			return fmt.Errorf("jwt key %s: unsupported alg %q: use HS256 or EdDSA", k.ID, k.Algorithm)
+		}
+	}
+	return nil
+}
+
+// claims are the registered claims checked
+type claims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *float64        `json:"exp"`
+	NotBefore *float64        `json:"nbf"`
+}
+
+// verify checks a compact JWT's signature and claims at now, returning
+// its subject
+func (c *JWTConfig) verify(token string, now time.Time) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", ErrMalformedToken
+	}
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return "", err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrMalformedToken
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	verified, known := false, false
+	for _, k := range c.Keys {
+		// The algorithm must be the key's own, so an EdDSA public key can
+		// never be used as an HMAC secret
+		if k.Algorithm != header.Algorithm || (header.KeyID != "" && k.ID != header.KeyID) {
+			continue
+		}
+		if !k.NotAfter.IsZero() && !now.Before(k.NotAfter) {
+			continue
+		}
+		known = true
+		switch k.Algorithm {
+		case "HS256":
+			mac := hmac.New(sha256.New, k.Secret)
+			mac.Write(signed)
+			verified = hmac.Equal(mac.Sum(nil), sig)
+		case "EdDSA":
+			verified = ed25519.Verify(k.PublicKey, signed, sig)
+		}
+		if verified {
+			break
+		}
+	}
+	if !known {
+		return "", ErrUnknownKey
+	}
+	if !verified {
+		return "", ErrBadSignature
+	}
+
+	var cl claims
+	if err := decodeSegment(parts[1], &cl); err != nil {
+		return "", err
+	}
+	leeway := c.leeway
+	if cl.ExpiresAt == nil || !now.Before(unix(*cl.ExpiresAt).Add(leeway)) {
+		return "", ErrTokenExpired
+	}
+	if cl.NotBefore != nil && now.Add(leeway).Before(unix(*cl.NotBefore)) {
+		return "", ErrTokenNotYet
+	}
+	if c.Issuer != "" && cl.Issuer != c.Issuer {
+		return "", ErrWrongIssuer
+	}
+	if c.Audience != "" && !hasAudience(cl.Audience, c.Audience) {
+		return "", ErrWrongAudience
+	}
+	if cl.Subject == "" {
+		return "", ErrUnknownSubject
+	}
+	return cl.Subject, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrMalformedToken
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return ErrMalformedToken
+	}
+	return nil
+}
+
+// hasAudience reports whether aud, a string or an array of them, holds want
+func hasAudience(aud json.RawMessage, want string) bool {
+	var one string
+	if json.Unmarshal(aud, &one) == nil {
+		return one == want
+	}
+	var many []string
+	if json.Unmarshal(aud, &many) == nil {
+		for _, a := range many {
+			if a == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func unix(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
@@ -0,0 +1,199 @@
+package access
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// Role is a set of powers given to clients, such as "core" for the agent
+// core or "viewer" for a dashboard. An intent is allowed when one of the
+// rules covers it and no deny pattern matches its type.
+type Role struct {
+	Rules []Rule   `json:"rules"`
+	Deny  []string `json:"deny,omitempty"`
+
+	// ViewAudit lets the role read every client's results and audit
+	// entries, rather than only its own
+	ViewAudit bool `json:"view_audit,omitempty"`
+}
+
+// Identity is a client of the transports, such as the agent core, a
+// mobile app, or a CLI, and the roles it holds
+type Identity struct {
+	Name  string   `json:"name"`
+	Roles []string `json:"roles"`
+
+	// APIKeySHA256 is the hex SHA-256 of the API key the client presents
+	// as "Authorization: Bearer <key>"
+	APIKeySHA256 string `json:"api_key_sha256,omitempty"`
+}
+
+// Policy maps the identities transports recognize to their roles
+type Policy struct {
+	Roles      map[string]Role `json:"roles"`
+	Identities []Identity      `json:"identities"`
+
+	// JWT, if set, also accepts signed tokens naming an identity
+	JWT *JWTConfig `json:"jwt,omitempty"`
+}
+
+// LoadPolicy reads a JSON role policy
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid roles file %s: %w", path, err)
+	}
+	if err := p.check(); err != nil {
+		return nil, fmt.Errorf("invalid roles file %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// check rejects unknown roles, duplicate names and keys, and malformed
+// key hashes
+func (p *Policy) check() error {
+	if p.JWT != nil {
+		if err := p.JWT.check(); err != nil {
+			return err
+		}
+	}
+	for name, role := range p.Roles {
+		for _, r := range role.Rules {
+			if len(r.IntentTypes) == 0 {
+				return fmt.Errorf("role %s: every rule needs intent_types", name)
+			}
+		}
+	}
+	names := make(map[string]bool)
+	keys := make(map[string]bool)
+	for n := range p.Identities {
+		id := &p.Identities[n]
+		if id.Name == "" {
+			return fmt.Errorf("identity %d needs a name", n)
+		}
+		if names[id.Name] {
+			return fmt.Errorf("identity %s is listed twice", id.Name)
+		}
+		names[id.Name] = true
+		for _, role := range id.Roles {
+			if _, ok := p.Roles[role]; !ok {
+				return fmt.Errorf("identity %s: unknown role %s", id.Name, role)
+			}
+		}
+		if id.APIKeySHA256 != "" {
+			id.APIKeySHA256 = strings.ToLower(id.APIKeySHA256)
+			if b, err := hex.DecodeString(id.APIKeySHA256); err != nil || len(b) != 32 {
+				return fmt.Errorf("identity %s: api_key_sha256 must be a hex SHA-256", id.Name)
+			}
+			if keys[id.APIKeySHA256] {
+				return fmt.Errorf("identity %s shares its API key with another identity", id.Name)
+			}
+			keys[id.APIKeySHA256] = true
+		}
+	}
+	return nil
+}
+
+// Identity returns the identity with the given name
+func (p *Policy) Identity(name string) (*Identity, bool) {
+	for n := range p.Identities {
+		if p.Identities[n].Name == name {
+			found := p.Identities[n]
+			return &found, true
+		}
+	}
+	return nil, false
+}
+
+// Authenticate returns the identity presenting a bearer credential: a JWT
+// when the policy accepts them and the credential looks like one, and
+// otherwise an API key. Failures wrap one of the Err variables.
+func (p *Policy) Authenticate(credential string) (*Identity, error) {
+	if credential == "" {
+		return nil, ErrNoCredentials
+	}
+	if p.JWT != nil && strings.Count(credential, ".") == 2 {
+		subject, err := p.JWT.verify(credential, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		id, ok := p.Identity(subject)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownSubject, subject)
+		}
+		return id, nil
+	}
+	id, ok := p.authenticateKey(credential)
+	if !ok {
+		return nil, ErrUnknownAPIKey
+	}
+	return id, nil
+}
+
+// authenticateKey returns the identity with the API key. Every identity's
+// hash is compared, in constant time, so timing doesn't reveal which keys
+// are close.
+func (p *Policy) authenticateKey(key string) (*Identity, bool) {
+	hash := []byte(hashToken(key))
+	var found *Identity
+	for n := range p.Identities {
+		id := &p.Identities[n]
+		if id.APIKeySHA256 != "" && subtle.ConstantTimeCompare([]byte(id.APIKeySHA256), hash) == 1 {
+			found = id
+		}
+	}
+	if found == nil {
+		return nil, false
+	}
+	copied := *found
+	return &copied, true
+}
+
+// Allows reports whether the identity's roles allow the intent: some role
+// must cover it, and none may deny its type
+func (p *Policy) Allows(id *Identity, i *intent.Intent) bool {
+	allowed := false
+	for _, name := range id.Roles {
+		role := p.Roles[name]
+		if slices.ContainsFunc(role.Deny, func(pattern string) bool { return MatchIntentType(pattern, i.IntentType) }) {
+			return false
+		}
+		if slices.ContainsFunc(role.Rules, func(r Rule) bool { return r.Matches(i) }) {
+			allowed = true
+		}
+	}
+	return allowed
+}
+
+// ViewsAudit reports whether one of the identity's roles has view_audit
+func (p *Policy) ViewsAudit(id *Identity) bool {
+	return slices.ContainsFunc(id.Roles, func(name string) bool { return p.Roles[name].ViewAudit })
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a context carrying the identity a transport
+// authenticated
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// IdentityFrom returns the identity a transport attached to ctx, or nil for
+// intents the agent raised itself
+func IdentityFrom(ctx context.Context) *Identity {
+	id, _ := ctx.Value(identityKey{}).(*Identity)
+	return id
+}
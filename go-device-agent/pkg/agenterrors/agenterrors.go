@@ -0,0 +1,148 @@
+// Package agenterrors classifies failures with stable codes so the agent
+// core can decide whether to retry, rephrase the request, or ask the user,
+// instead of parsing free-form error strings
+package agenterrors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Code is a machine-readable failure class carried in ExecutionResult
+type Code string
+
+// Failure classes
+const (
+	// InvalidIntent: the intent itself is malformed or failed validation
+	InvalidIntent Code = "INVALID_INTENT"
+	// InvalidParams: parameters are missing, mistyped, or out of range; rephrase
+	InvalidParams Code = "INVALID_PARAMS"
+	// NotFound: the named device, item, or resource doesn't exist
+	NotFound Code = "NOT_FOUND"
+	// Unsupported: the executor doesn't handle this action
+	Unsupported Code = "UNSUPPORTED"
+	// Unauthorized: the intent lacks the signature or permission required
+	Unauthorized Code = "UNAUTHORIZED"
+	// ConfirmationRequired: confirm with the user, then resend the intent
+	// with requires_permission set
+	ConfirmationRequired Code = "CONFIRMATION_REQUIRED"
+	// DeniedByPolicy: configuration forbids the action; don't retry
+	DeniedByPolicy Code = "DENIED_BY_POLICY"
+	// ExecutorDisabled: the executor was switched off at runtime; ask the user
+	ExecutorDisabled Code = "EXECUTOR_DISABLED"
+	// UnderMaintenance: the device or executor only takes intents from the
+	// user while it is being maintained; automations should skip it
+	UnderMaintenance Code = "UNDER_MAINTENANCE"
+	// Conflict: the current state prevents the action (e.g. a door is open)
+	Conflict Code = "CONFLICT"
+	// Expired: the intent's deadline passed before it ran
+	Expired Code = "EXPIRED"
+	// Timeout: the action didn't finish in time; may be retried
+	Timeout Code = "TIMEOUT"
+	// Cancelled: the action was cancelled before it finished
+	Cancelled Code = "CANCELLED"
+	// Unavailable: a device, service, or tool is unreachable; may be retried
+	Unavailable Code = "UNAVAILABLE"
+	// RateLimited: too many requests; retry later
+	RateLimited Code = "RATE_LIMITED"
+	// QuotaExceeded: the intent type's hourly or daily quota is used up;
+	// don't retry until it resets
+	QuotaExceeded Code = "QUOTA_EXCEEDED"
+	// PartialFailure: some parts of a compound action (e.g. a plan) failed
+	PartialFailure Code = "PARTIAL_FAILURE"
+	// Internal: an unexpected failure in the device agent
+	Internal Code = "INTERNAL"
+)
+
+// Retryable reports whether repeating the same intent later may succeed
+func (c Code) Retryable() bool {
+	switch c {
+	case Timeout, Unavailable, RateLimited:
+		return true
+	}
+	return false
+}
+
+// Error is an error with a failure code
+type Error struct {
+	Code Code
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New returns an error with the given code and message
+func New(code Code, message string) error {
+	return &Error{Code: code, Err: errors.New(message)}
+}
+
+// Newf formats an error with the given code; %w wraps as with fmt.Errorf
+func Newf(code Code, format string, args ...interface{}) error {
+	return &Error{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// Wrap attaches a code to err, returning nil for a nil err
+func Wrap(code Code, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// UnsupportedAction is the error executors return for intent types they
+// don't handle
+func UnsupportedAction(intentType string) error {
+	return Newf(Unsupported, "unsupported action: %s", intentType)
+}
+
+// CodeOf classifies err: an explicit code wins, then well-known error
+// types are mapped, and anything else is Internal. A nil err has no code.
+func CodeOf(err error) Code {
+	if err == nil {
+		return ""
+	}
+
+	var coded *Error
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+
+	var paramErr *intent.ParamError
+	var schemaErr *schema.ValidationError
+	var intentErr *intent.ValidationError
+	var netErr net.Error
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	switch {
+	case errors.As(err, &paramErr), errors.As(err, &schemaErr):
+		return InvalidParams
+	case errors.As(err, &intentErr):
+		return InvalidIntent
+	case errors.Is(err, context.DeadlineExceeded):
+		return Timeout
+	case errors.Is(err, context.Canceled):
+		return Cancelled
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return Timeout
+	case errors.As(err, &opErr), errors.As(err, &dnsErr), errors.Is(err, exec.ErrNotFound):
+		return Unavailable
+	case errors.Is(err, os.ErrNotExist):
+		return NotFound
+	case errors.Is(err, os.ErrPermission):
+		return Unauthorized
+	}
+	return Internal
+}
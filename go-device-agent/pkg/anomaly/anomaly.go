@@ -0,0 +1,317 @@
+// Package anomaly learns how often each intent type normally arrives at
+// each hour of the day and spots bursts far beyond it, such as fifty
+// lock.unlock intents at 3am. Bursts are flagged, and bursts of intent
+// types configured for it are held until someone approves them.
+package anomaly
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/access"
+)
+
+// maxTypes bounds the intent types learned, so clients inventing types
+// can't grow the state without limit
+const maxTypes = 1000
+
+// Config tunes what counts as a burst
+//
+//	{
+//	  "window": "10m",
+//	  "min_count": 10,
+//	  "sigma": 4,
+//	  "ignore": ["clock.*", "capabilities.*"],
+//	  "hold": ["lock.*", "security.*"],
+//	  "hold_level": "high"
+//	}
+type Config struct {
+	// Window is the span intents are counted over, as a Go duration
+	// (default 10m)
+	Window string `json:"window,omitempty"`
+
+	// MinCount is the fewest intents of a type in a window that can be a
+	// burst, however quiet the hour usually is (default 10)
+	MinCount int `json:"min_count,omitempty"`
+
+	// Sigma is how many standard deviations above the usual count a
+	// window must be to be a burst (default 4)
+	Sigma float64 `json:"sigma,omitempty"`
+
+	// LearnDays is roughly how many days of history the usual counts
+	// reflect (default 14)
+	LearnDays int `json:"learn_days,omitempty"`
+
+	// Ignore lists intent types (or module.*) never counted
+	Ignore []string `json:"ignore,omitempty"`
+
+	// Hold lists intent types (or module.*) whose bursts are held for
+	// approval rather than only flagged
+	Hold []string `json:"hold,omitempty"`
+
+	// HoldLevel is the risk level whose approvers confirm held intents
+	// (default "high")
+	HoldLevel string `json:"hold_level,omitempty"`
+
+	// StateFile keeps what was learned across restarts
+	StateFile string `json:"-"`
+
+	window time.Duration
+}
+
+// LoadConfig reads a JSON anomaly config
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid anomaly config %s: %w", path, err)
+	}
+	if err := c.check(); err != nil {
+		return nil, fmt.Errorf("invalid anomaly config %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+func (c *Config) check() error {
+	c.window = 10 * time.Minute
+	if c.Window != "" {
+		d, err := time.ParseDuration(c.Window)
+		if err != nil || d < time.Minute || d > time.Hour {
+			return errors.New("window must be a Go duration from 1m to 1h")
+		}
+		c.window = d
+	}
+	if c.MinCount <= 0 {
+		c.MinCount = 10
+	}
+	if c.Sigma <= 0 {
+		c.Sigma = 4
+	}
+	if c.LearnDays <= 0 {
+		c.LearnDays = 14
+	}
+	if c.HoldLevel == "" {
+		c.HoldLevel = "high"
+	}
+	return nil
+}
+
+// Anomaly is a burst of one intent type
+type Anomaly struct {
+	IntentType string    `json:"intent_type"`
+	Count      int       `json:"count"`
+	Expected   float64   `json:"expected"`
+	Limit      float64   `json:"limit"`
+	Window     string    `json:"window"`
+	Since      time.Time `json:"since"`
+
+	// Held is set for intent types whose bursts wait for approval
+	Held bool `json:"held"`
+
+	// First is set for the intent that made the window a burst; later
+	// intents of the window are anomalous too but needn't be announced
+	First bool `json:"-"`
+}
+
+// Message describes the burst for a person
+func (a *Anomaly) Message() string {
+	return fmt.Sprintf("%d %s intents since %s, where about %.1f are usual",
+		a.Count, a.IntentType, a.Since.Local().Format("15:04"), a.Expected)
+}
+
+// Usual is what was learned about one intent type in one hour of the day:
+// the mean and variance of its count per window
+type Usual struct {
+	Mean     float64 `json:"mean"`
+	Variance float64 `json:"variance"`
+}
+
+// state is what is saved between runs
+type state struct {
+	Start  time.Time             `json:"window_start"`
+	Counts map[string]int        `json:"counts"`
+	Usual  map[string]*[24]Usual `json:"usual"`
+}
+
+// Detector counts intents and compares each window with the usual count
+// for its hour
+type Detector struct {
+	cfg   Config
+	alpha float64
+
+	mu      sync.Mutex
+	st      state
+	flagged map[string]bool // types already announced this window
+}
+
+// New creates a detector, resuming from cfg.StateFile if it exists
+func New(cfg Config) (*Detector, error) {
+	if err := cfg.check(); err != nil {
+		return nil, err
+	}
+	perDay := float64(24*time.Hour) / float64(cfg.window)
+	d := &Detector{
+		cfg:     cfg,
+		alpha:   1 / (float64(cfg.LearnDays) * perDay / 24),
+		flagged: make(map[string]bool),
+		st:      state{Counts: make(map[string]int), Usual: make(map[string]*[24]Usual)},
+	}
+	if cfg.StateFile != "" {
+		data, err := os.ReadFile(cfg.StateFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, &d.st); err != nil {
+				return nil, fmt.Errorf("invalid anomaly state %s: %w", cfg.StateFile, err)
+			}
+		}
+	}
+	return d, nil
+}
+
+// HoldLevel is the risk level whose approvers confirm held intents
+func (d *Detector) HoldLevel() string {
+	return d.cfg.HoldLevel
+}
+
+// Observe counts an intent of the given type arriving at t, returning the
+// anomaly if its window is a burst, or nil
+func (d *Detector) Observe(intentType string, t time.Time) *Anomaly {
+	if matchAny(d.cfg.Ignore, intentType) {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.advance(t)
+
+	usual, ok := d.st.Usual[intentType]
+	if !ok {
+		if len(d.st.Usual) >= maxTypes {
+			return nil
+		}
+		usual = new([24]Usual)
+		d.st.Usual[intentType] = usual
+	}
+	d.st.Counts[intentType]++
+	count := d.st.Counts[intentType]
+
+	u := usual[d.st.Start.Local().Hour()]
+	limit := d.limit(u)
+	if float64(count) <= limit {
+		return nil
+	}
+	first := !d.flagged[intentType]
+	d.flagged[intentType] = true
+	return &Anomaly{
+		IntentType: intentType,
+		Count:      count,
+		Expected:   u.Mean,
+		Limit:      limit,
+		Window:     d.cfg.window.String(),
+		Since:      d.st.Start,
+		Held:       matchAny(d.cfg.Hold, intentType),
+		First:      first,
+	}
+}
+
+// limit is the most intents a window can have without being a burst
+func (d *Detector) limit(u Usual) float64 {
+	// A deviation of at least one keeps types that are always seen the
+	// same number of times from bursting at one more
+	sd := math.Max(math.Sqrt(u.Variance), 1)
+	return math.Max(float64(d.cfg.MinCount), u.Mean+d.cfg.Sigma*sd)
+}
+
+// advance closes the windows that ended before t, learning their counts
+func (d *Detector) advance(t time.Time) {
+	start := t.Truncate(d.cfg.window)
+	if d.st.Start.IsZero() {
+		d.st.Start = start
+		return
+	}
+	if !start.After(d.st.Start) {
+		return
+	}
+	// Idle gaps are learned as empty windows, up to a week of them
+	closed := int(start.Sub(d.st.Start) / d.cfg.window)
+	maxClosed := int(7 * 24 * time.Hour / d.cfg.window)
+	for n := 0; n < closed && n < maxClosed; n++ {
+		hour := d.st.Start.Add(time.Duration(n) * d.cfg.window).Local().Hour()
+		for intentType, usual := range d.st.Usual {
+			count := 0.0
+			if n == 0 {
+				count = float64(d.st.Counts[intentType])
+			}
+			u := &usual[hour]
+			// Bursts are learned only up to the limit, so repeating one
+			// doesn't quickly make it usual
+			count = math.Min(count, d.limit(*u))
+			diff := count - u.Mean
+			u.Mean += d.alpha * diff
+			u.Variance = (1 - d.alpha) * (u.Variance + d.alpha*diff*diff)
+		}
+	}
+	d.st.Start = start
+	d.st.Counts = make(map[string]int)
+	d.flagged = make(map[string]bool)
+	// If saving fails learning carries on in memory; the next window
+	// tries again
+	_ = d.save()
+}
+
+// Usual returns what was learned for each intent type, by hour of the day
+func (d *Detector) Usual() map[string][24]Usual {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	usual := make(map[string][24]Usual, len(d.st.Usual))
+	for intentType, u := range d.st.Usual {
+		usual[intentType] = *u
+	}
+	return usual
+}
+
+// Save writes what was learned to the state file. It is also saved as
+// each window closes.
+func (d *Detector) Save() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.save()
+}
+
+func (d *Detector) save() error {
+	if d.cfg.StateFile == "" {
+		return nil
+	}
+	data, err := json.Marshal(d.st)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(d.cfg.StateFile), 0o700); err != nil {
+		return err
+	}
+	// Written atomically so a crash never leaves a torn file
+	tmp := d.cfg.StateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, d.cfg.StateFile)
+}
+
+func matchAny(patterns []string, intentType string) bool {
+	for _, p := range patterns {
+		if access.MatchIntentType(p, intentType) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package approval
+
+import (
+	"context"
+	"sync"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// Answerer is an approver whose requests are answered by someone the agent
+// already trusts, at its terminal or through its admin API, rather than
+// with a token only the second channel carries
+type Answerer interface {
+	Answer(id string, approve bool) error
+}
+
+// Keyboard asks for approval on the agent's terminal dashboard, where the
+// person at the keyboard approves or denies each request. Answers come
+// only through Answer, never over the network, so Approvals.Decide refuses
+// its requests.
+type Keyboard struct {
+	answers
+}
+
+func (k *Keyboard) Name() string {
+	return "keyboard"
+}
+
+func (k *Keyboard) Approve(ctx context.Context, r *Request) error {
+	return k.wait(ctx, r, k.Name())
+}
+
+// Dashboard asks for approval on the web dashboard, where an admin approves
+// or denies each request through the admin API
+type Dashboard struct {
+	answers
+}
+
+func (d *Dashboard) Name() string {
+	return "dashboard"
+}
+
+func (d *Dashboard) Approve(ctx context.Context, r *Request) error {
+	return d.wait(ctx, r, d.Name())
+}
+
+// answers holds the requests waiting for an Answer
+type answers struct {
+	mu      sync.Mutex
+	waiting map[string]chan bool
+}
+
+func (a *answers) wait(ctx context.Context, r *Request, approver string) error {
+	answer := make(chan bool, 1)
+	a.mu.Lock()
+	if a.waiting == nil {
+		a.waiting = make(map[string]chan bool)
+	}
+	a.waiting[r.ID] = answer
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		delete(a.waiting, r.ID)
+		a.mu.Unlock()
+	}()
+
+	select {
+	case approved := <-answer:
+		if !approved {
+			return errDenied(r, approver)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Answer approves or denies a waiting request
+func (a *answers) Answer(id string, approve bool) error {
+	a.mu.Lock()
+	answer, ok := a.waiting[id]
+	a.mu.Unlock()
+	if !ok {
+		return agenterrors.Newf(agenterrors.NotFound, "no pending approval %s", id)
+	}
+	select {
+	case answer <- approve:
+	default:
+		// Already answered
+	}
+	return nil
+}
@@ -0,0 +1,364 @@
+// Package approval holds high-risk intents, such as unlocking doors,
+// payments, and shell commands, until someone confirms them on a second
+// channel: a code from an authenticator app, a push notification's Approve
+// button, or a physical button wired to GPIO. A risk table classifies
+// intents and says which channels each risk level needs.
+package approval
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/access"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// defaultTimeout is how long a level waits for approval unless it says
+const defaultTimeout = 2 * time.Minute
+
+// Table classifies intents by risk. The first rule covering an intent
+// gives its level; intents no rule covers need no approval.
+//
+//	{
+//	  "rules": [
+//	    {"intent_types": ["lock.unlock"], "risk": "high"},
+//	    {"intent_types": ["payment.*", "shell.*"], "risk": "critical"}
+//	  ],
+//	  "levels": {
+//	    "high": {"approvers": ["push"], "timeout": "2m"},
+//	    "critical": {"approvers": ["button", "totp"]}
+//	  }
+//	}
+type Table struct {
+	Rules  []RiskRule       `json:"rules"`
+	Levels map[string]Level `json:"levels"`
+}
+
+// RiskRule gives the intents an access rule covers a risk level
+type RiskRule struct {
+	access.Rule
+	Risk string `json:"risk"`
+}
+
+// Level is what intents of a risk level need before they run
+type Level struct {
+	// Approvers names the channels that must each confirm, in order
+	Approvers []string `json:"approvers"`
+
+	// Timeout is how long each channel is waited for, as a Go duration
+	// (default 2m)
+	Timeout string `json:"timeout,omitempty"`
+
+	timeout time.Duration
+}
+
+// LoadTable reads a JSON risk table
+func LoadTable(path string) (*Table, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var t Table
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("invalid risk table %s: %w", path, err)
+	}
+	if err := t.check(); err != nil {
+		return nil, fmt.Errorf("invalid risk table %s: %w", path, err)
+	}
+	return &t, nil
+}
+
+func (t *Table) check() error {
+	for n, r := range t.Rules {
+		if len(r.IntentTypes) == 0 {
+			return fmt.Errorf("rule %d needs intent_types", n)
+		}
+		if _, ok := t.Levels[r.Risk]; !ok {
+			return fmt.Errorf("rule %d: unknown risk level %q", n, r.Risk)
+		}
+	}
+	for name, level := range t.Levels {
+		if len(level.Approvers) == 0 {
+			return fmt.Errorf("level %s needs approvers", name)
+		}
+		level.timeout = defaultTimeout
+		if level.Timeout != "" {
+			d, err := time.ParseDuration(level.Timeout)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("level %s: timeout must be a Go duration such as 90s", name)
+			}
+			level.timeout = d
+		}
+		t.Levels[name] = level
+	}
+	return nil
+}
+
+// Classify returns the risk level of an intent, or "" if it needs no
+// approval
+func (t *Table) Classify(i *intent.Intent) string {
+	for _, r := range t.Rules {
+		if r.Matches(i) {
+			return r.Risk
+		}
+	}
+	return ""
+}
+
+// Request is an intent waiting for approval
+type Request struct {
+	ID         string         `json:"id"`
+	Intent     *intent.Intent `json:"-"`
+	IntentID   string         `json:"intent_id"`
+	IntentType string         `json:"intent_type"`
+	Risk       string         `json:"risk"`
+	Approver   string         `json:"approver"`
+
+	// Identity is the client that sent the intent, if known
+	Identity string `json:"identity,omitempty"`
+
+	Started time.Time `json:"started"`
+	Expires time.Time `json:"expires"`
+}
+
+// Summary describes the request for a person deciding on it
+func (r *Request) Summary() string {
+	s := r.Intent.IntentType
+	if len(r.Intent.Parameters) > 0 {
+		if params, err := json.Marshal(r.Intent.Parameters); err == nil {
+			s += " " + string(params)
+		}
+	}
+	if r.Identity != "" {
+		s += " from " + r.Identity
+	}
+	if r.Intent.Reasoning != "" {
+		s += ": " + r.Intent.Reasoning
+	}
+	return s
+}
+
+// Approver confirms requests on one channel
+type Approver interface {
+	Name() string
+
+	// Approve returns nil once the request is confirmed, or an error if it
+	// is refused, unconfirmed when ctx ends, or can't be asked
+	Approve(ctx context.Context, r *Request) error
+}
+
+// Decider is an approver whose requests are answered from outside, such as
+// by a push notification's buttons calling back to the HTTP transport
+type Decider interface {
+	Decide(id, token string, approve bool) error
+}
+
+// Record says how an intent was approved, for the audit log
+type Record struct {
+	Risk      string   `json:"risk"`
+	Approvers []string `json:"approvers"`
+	WaitedMS  int64    `json:"waited_ms"`
+}
+
+// Approvals checks intents against the risk table, asking each level's
+// approvers in turn
+type Approvals struct {
+	approvers map[string]Approver
+
+	mu      sync.Mutex
+	table   *Table
+	pending map[string]*Request
+}
+
+// New creates the approval check for a table, failing if a level names an
+// approver that isn't given
+func New(table *Table, approvers ...Approver) (*Approvals, error) {
+	a := &Approvals{table: table, approvers: make(map[string]Approver), pending: make(map[string]*Request)}
+	for _, ap := range approvers {
+		a.approvers[ap.Name()] = ap
+	}
+	if err := a.checkApprovers(table); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// SetTable replaces the risk table, failing if a level names an approver
+// that isn't configured. Requests already waiting keep their level.
+func (a *Approvals) SetTable(table *Table) error {
+	if err := a.checkApprovers(table); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.table = table
+	return nil
+}
+
+func (a *Approvals) checkApprovers(table *Table) error {
+	for name, level := range table.Levels {
+		for _, ap := range level.Approvers {
+			if _, ok := a.approvers[ap]; !ok {
+				return fmt.Errorf("level %s needs the %s approver, which is not configured", name, ap)
+			}
+		}
+	}
+	return nil
+}
+
+func (a *Approvals) currentTable() *Table {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.table
+}
+
+// Classify returns the risk level the table puts an intent at, or "" if it
+// needs no approval
+func (a *Approvals) Classify(i *intent.Intent) string {
+	return a.currentTable().Classify(i)
+}
+
+// Check returns nil, nil for intents needing no approval, and otherwise
+// blocks until every approver of the intent's risk level confirms it
+func (a *Approvals) Check(ctx context.Context, i *intent.Intent) (*Record, error) {
+	risk := a.Classify(i)
+	if risk == "" {
+		return nil, nil
+	}
+	return a.Require(ctx, i, risk)
+}
+
+// Require blocks until every approver of the named risk level confirms the
+// intent, whatever the table classifies it as. It is for intents held for
+// other reasons, such as arriving in an unusual burst.
+func (a *Approvals) Require(ctx context.Context, i *intent.Intent, risk string) (*Record, error) {
+	level, ok := a.currentTable().Levels[risk]
+	if !ok {
+		return nil, fmt.Errorf("unknown risk level %q", risk)
+	}
+	record := &Record{Risk: risk}
+	started := time.Now()
+
+	for _, name := range level.Approvers {
+		id, err := newID()
+		if err != nil {
+			return nil, err
+		}
+		req := &Request{
+			ID:         id,
+			Intent:     i,
+			IntentID:   i.ID,
+			IntentType: i.IntentType,
+			Risk:       risk,
+			Approver:   name,
+			Started:    time.Now().UTC(),
+			Expires:    time.Now().Add(level.timeout).UTC(),
+		}
+		if identity := access.IdentityFrom(ctx); identity != nil {
+			req.Identity = identity.Name
+		}
+		if err := a.ask(ctx, a.approvers[name], req, level.timeout); err != nil {
+			return nil, err
+		}
+		record.Approvers = append(record.Approvers, name)
+	}
+	record.WaitedMS = time.Since(started).Milliseconds()
+	return record, nil
+}
+
+func (a *Approvals) ask(ctx context.Context, approver Approver, req *Request, timeout time.Duration) error {
+	a.mu.Lock()
+	a.pending[req.ID] = req
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		delete(a.pending, req.ID)
+		a.mu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := approver.Approve(ctx, req)
+	if err != nil && ctx.Err() != nil && agenterrors.CodeOf(err) != agenterrors.DeniedByPolicy {
+		return agenterrors.Newf(agenterrors.Timeout, "%s was not approved by %s within %s", req.Intent.IntentType, approver.Name(), timeout)
+	}
+	return err
+}
+
+// Pending lists the requests waiting for an answer, oldest first
+func (a *Approvals) Pending() []Request {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	list := make([]Request, 0, len(a.pending))
+	for _, r := range a.pending {
+		list = append(list, *r)
+	}
+	sort.Slice(list, func(x, y int) bool { return list[x].Started.Before(list[y].Started) })
+	return list
+}
+
+// Decide answers a pending request through its approver, if the approver
+// takes answers from outside
+func (a *Approvals) Decide(id, token string, approve bool) error {
+	a.mu.Lock()
+	req, ok := a.pending[id]
+	a.mu.Unlock()
+	if !ok {
+		return agenterrors.Newf(agenterrors.NotFound, "no pending approval %s", id)
+	}
+	decider, ok := a.approvers[req.Approver].(Decider)
+	if !ok {
+		return agenterrors.Newf(agenterrors.InvalidParams, "approval %s is answered by %s, not here", id, req.Approver)
+	}
+	return decider.Decide(id, token, approve)
+}
+
+// Answer answers a pending request waiting on the approver named via,
+// which must be an Answerer. Naming the approver keeps an answer meant
+// for one channel, such as the web dashboard, from settling a request
+// another, such as the keyboard, was asked.
+func (a *Approvals) Answer(id, via string, approve bool) error {
+	a.mu.Lock()
+	req, ok := a.pending[id]
+	a.mu.Unlock()
+	if !ok {
+		return agenterrors.Newf(agenterrors.NotFound, "no pending approval %s", id)
+	}
+	answerer, ok := a.approvers[req.Approver].(Answerer)
+	if !ok || req.Approver != via {
+		return agenterrors.Newf(agenterrors.InvalidParams, "approval %s is answered by %s, not %s", id, req.Approver, via)
+	}
+	return answerer.Answer(id, approve)
+}
+
+// Approvers names the configured approvers
+func (a *Approvals) Approvers() []string {
+	names := make([]string, 0, len(a.approvers))
+	for name := range a.approvers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// errDenied is returned by approvers when a person refuses the request
+func errDenied(r *Request, approver string) error {
+	return agenterrors.Newf(agenterrors.DeniedByPolicy, "%s was denied by %s", r.Intent.IntentType, approver)
+}
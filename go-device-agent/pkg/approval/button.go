@@ -0,0 +1,32 @@
+package approval
+
+import (
+	"context"
+	"errors"
+)
+
+// Button approves intents when someone presses a physical button at the
+// device, such as one wired to a GPIO input beside the door it unlocks.
+// Nobody reaching the agent over the network can press it.
+type Button struct {
+	// Press waits until the button is pressed, or ctx ends
+	Press func(ctx context.Context) error
+
+	// Announce, if set, tells people nearby a press is wanted, e.g. by
+	// lighting an LED or speaking the request
+	Announce func(r *Request)
+}
+
+func (b *Button) Name() string {
+	return "button"
+}
+
+func (b *Button) Approve(ctx context.Context, r *Request) error {
+	if b.Press == nil {
+		return errors.New("button approver has no button")
+	}
+	if b.Announce != nil {
+		b.Announce(r)
+	}
+	return b.Press(ctx)
+}
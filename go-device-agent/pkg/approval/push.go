@@ -0,0 +1,129 @@
+package approval
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+)
+
+// Push asks for approval with a push notification to a phone, through an
+// ntfy topic (self-hosted or ntfy.sh). The notification's Approve and Deny
+// buttons call back to the agent's HTTP transport with a one-time token
+// that only the notification carries.
+type Push struct {
+	// TopicURL is the ntfy topic, e.g. https://ntfy.sh/agent-approvals-k3x9
+	TopicURL string
+
+	// AccessToken authenticates to the ntfy server, if it needs it
+	AccessToken string
+
+	// CallbackURL is where the phone reaches the agent's HTTP transport,
+	// e.g. https://agent.home.lan:8443
+	CallbackURL string
+
+	client *http.Client
+
+	mu      sync.Mutex
+	waiting map[string]*pushWait
+}
+
+type pushWait struct {
+	token  string
+	answer chan bool
+}
+
+func (p *Push) Name() string {
+	return "push"
+}
+
+func (p *Push) Approve(ctx context.Context, r *Request) error {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return err
+	}
+	wait := &pushWait{token: hex.EncodeToString(token), answer: make(chan bool, 1)}
+
+	p.mu.Lock()
+	if p.waiting == nil {
+		p.waiting = make(map[string]*pushWait)
+		p.client = connpool.Default.HTTP(15 * time.Second)
+	}
+	p.waiting[r.ID] = wait
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.waiting, r.ID)
+		p.mu.Unlock()
+	}()
+
+	if err := p.send(ctx, r, wait.token); err != nil {
+		return agenterrors.Wrap(agenterrors.Unavailable, fmt.Errorf("push approval: %w", err))
+	}
+	select {
+	case approved := <-wait.answer:
+		if !approved {
+			return errDenied(r, "push")
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// send publishes the notification with Approve and Deny buttons
+func (p *Push) send(ctx context.Context, r *Request, token string) error {
+	decide := func(answer string) string {
+		return fmt.Sprintf("%s/v1/approvals/%s/%s?token=%s", strings.TrimSuffix(p.CallbackURL, "/"), url.PathEscape(r.ID), answer, token)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TopicURL, bytes.NewBufferString(r.Summary()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Title", fmt.Sprintf("Approve %s? (%s risk)", r.Intent.IntentType, r.Risk))
+	req.Header.Set("Priority", "high")
+	req.Header.Set("Tags", "warning")
+	req.Header.Set("Actions", fmt.Sprintf("http, Approve, %s, method=POST, clear=true; http, Deny, %s, method=POST, clear=true",
+		decide("approve"), decide("deny")))
+	if p.AccessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+p.AccessToken)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s answered %s", p.TopicURL, resp.Status)
+	}
+	return nil
+}
+
+// Decide answers a request with the token its notification carried
+func (p *Push) Decide(id, token string, approve bool) error {
+	p.mu.Lock()
+	wait, ok := p.waiting[id]
+	p.mu.Unlock()
+	if !ok {
+		return agenterrors.Newf(agenterrors.NotFound, "no pending approval %s", id)
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(wait.token)) != 1 {
+		return agenterrors.New(agenterrors.Unauthorized, "wrong approval token")
+	}
+	select {
+	case wait.answer <- approve:
+	default:
+		// Already answered
+	}
+	return nil
+}
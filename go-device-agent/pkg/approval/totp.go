@@ -0,0 +1,90 @@
+package approval
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// TOTP approves intents that carry a current code from an authenticator
+// app (RFC 6238: HMAC-SHA1, six digits, 30-second steps) in approval_code.
+// It never waits: an intent without a valid code fails with
+// CONFIRMATION_REQUIRED, and the core asks the user for the code and
+// resends it.
+type TOTP struct {
+	secret []byte
+
+	mu sync.Mutex
+	// used is the last step a code was accepted for, and by which
+	// correlation, so a code can't be replayed by another request but
+	// does cover every step of the plan it was given for
+	used            int64
+	usedCorrelation string
+}
+
+const (
+	totpPeriod = 30
+	totpDigits = 6
+)
+
+// NewTOTP creates a TOTP approver from a base32 secret, as authenticator
+// apps are given in otpauth:// URIs
+func NewTOTP(secret string) (*TOTP, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(secret), " ", ""))
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(secret, "="))
+	if err != nil {
+		return nil, fmt.Errorf("TOTP secret must be base32: %w", err)
+	}
+	if len(key) < 10 {
+		return nil, fmt.Errorf("TOTP secret must be at least 80 bits")
+	}
+	return &TOTP{secret: key}, nil
+}
+
+func (t *TOTP) Name() string {
+	return "totp"
+}
+
+func (t *TOTP) Approve(ctx context.Context, r *Request) error {
+	code := strings.TrimSpace(r.Intent.ApprovalCode)
+	if code == "" {
+		return agenterrors.Newf(agenterrors.ConfirmationRequired,
+			"%s is %s risk: resend it with approval_code from the authenticator app", r.Intent.IntentType, r.Risk)
+	}
+	step := time.Now().Unix() / totpPeriod
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// One step either side allows for clock drift and slow typing
+	for _, s := range []int64{step - 1, step, step + 1} {
+		if !hmac.Equal([]byte(t.code(s)), []byte(code)) {
+			continue
+		}
+		if s < t.used || (s == t.used && (r.Intent.CorrelationID == "" || r.Intent.CorrelationID != t.usedCorrelation)) {
+			return agenterrors.New(agenterrors.Unauthorized, "approval code was already used")
+		}
+		t.used, t.usedCorrelation = s, r.Intent.CorrelationID
+		return nil
+	}
+	return agenterrors.New(agenterrors.Unauthorized, "approval code is wrong or expired")
+}
+
+// code is the TOTP code for a time step
+func (t *TOTP) code(step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, t.secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1_000_000)
+}
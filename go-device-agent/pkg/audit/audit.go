@@ -0,0 +1,244 @@
+// Package audit keeps an append-only record of the intents the gateway
+// handled, so every action can be traced back to the request behind it.
+// Entries are hash-chained, so an entry altered or removed is detected,
+// and the chain can be exported to write-once storage for archiving.
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/usage"
+)
+
+// Entry is one handled intent
+type Entry struct {
+	Time          time.Time `json:"time"`
+	IntentID      string    `json:"intent_id"`
+	IntentType    string    `json:"intent_type"`
+	Module        string    `json:"module,omitempty"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	SessionID     string    `json:"session_id,omitempty"`
+	Guest         string    `json:"guest,omitempty"`
+	SpeakerID     string    `json:"speaker_id,omitempty"`
+	Success       bool      `json:"success"`
+	Error         string    `json:"error,omitempty"`
+
+	// Identity is the transport client that sent the intent, and Roles
+	// the roles it held; both are empty for intents the agent raised
+	Identity string   `json:"identity,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+
+	// Provenance is the chain of automations, routines, or intents that
+	// generated the intent, root origin first
+	Provenance []intent.Origin `json:"provenance,omitempty"`
+
+	// Usage is what handling the intent cost
+	Usage *usage.Usage `json:"usage,omitempty"`
+
+	// Details is what the executor did, from executors that report it,
+	// such as the text input.type typed
+	Details map[string]interface{} `json:"details,omitempty"`
+
+	// Seq numbers the log's entries from 1. Prev is the previous entry's
+	// Hash, and Hash is "sha256:" and the hex SHA-256 of this entry's
+	// canonical JSON without Hash, chaining each entry to the one before.
+	// Set by Record.
+	Seq  uint64 `json:"seq,omitempty"`
+	Prev string `json:"prev,omitempty"`
+	Hash string `json:"hash,omitempty"`
+}
+
+// Filter selects entries in Query. Empty fields match everything.
+type Filter struct {
+	CorrelationID string
+	SessionID     string
+	IntentType    string
+	Guest         string
+	Identity      string
+	Origin        string // ID of any origin in the provenance chain
+	Since         time.Time
+	Limit         int // most recent entries to return (0 for all)
+}
+
+// Match reports whether e passes the filter
+func (f Filter) Match(e Entry) bool {
+	if f.CorrelationID != "" && e.CorrelationID != f.CorrelationID {
+		return false
+	}
+	if f.SessionID != "" && e.SessionID != f.SessionID {
+		return false
+	}
+	if f.IntentType != "" && e.IntentType != f.IntentType {
+		return false
+	}
+	if f.Guest != "" && e.Guest != f.Guest {
+		return false
+	}
+	if f.Identity != "" && e.Identity != f.Identity {
+		return false
+	}
+	if f.Origin != "" && !slices.ContainsFunc(e.Provenance, func(o intent.Origin) bool { return o.ID == f.Origin }) {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	return true
+}
+
+// Log is a JSON Lines audit file
+type Log struct {
+	path string
+	file *os.File
+	mu   sync.Mutex
+
+	// The chain's last link, which the next entry continues
+	tip Link
+
+	// The current file's size, its first chained entry, and when that was
+	// recorded, for rotation
+	size    int64
+	first   uint64
+	started time.Time
+
+	rotation Rotation
+
+	// An exporter guards the log: rotated files holding entries after
+	// exported are kept, whatever the retention policy
+	guarded  bool
+	exported uint64
+}
+
+// Open opens the audit log at path for appending, creating it if needed.
+// The chain continues from the last entry, in a rotated file if the log
+// was just rotated; a log written before entries were chained starts a
+// chain at 1.
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	l := &Log{path: path, file: file}
+	if err := l.load(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// load finds the chain's tip and the current file's first entry
+func (l *Log) load() error {
+	info, err := l.file.Stat()
+	if err != nil {
+		return err
+	}
+	l.size = info.Size()
+	if l.size == 0 {
+		l.tip, err = previousLink(l.path)
+		return err
+	}
+	if l.tip, err = lastLink(l.path); err != nil {
+		return err
+	}
+	first, err := firstEntry(l.path)
+	if err != nil {
+		return err
+	}
+	l.first, l.started = first.Seq, first.Time
+	return nil
+}
+
+// Record appends an entry, stamping the time if it is not set and linking
+// it to the chain. The log is rotated afterwards if its policy says so.
+func (l *Log) Record(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return errors.New("audit log is closed")
+	}
+	e.Seq, e.Prev, e.Hash = l.tip.Seq+1, l.tip.Hash, ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if e.Hash, err = entryHash(data); err != nil {
+		return err
+	}
+	if data, err = json.Marshal(e); err != nil {
+		return err
+	}
+	if _, err = l.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	l.tip = Link{Seq: e.Seq, Hash: e.Hash}
+	l.size += int64(len(data)) + 1
+	if l.first == 0 {
+		l.first, l.started = e.Seq, e.Time
+	}
+	if l.rotation.due(l.size, l.started) {
+		return l.rotate()
+	}
+	return nil
+}
+
+// Query returns matching entries, oldest first, from the rotated files
+// still kept and the current one
+func (l *Log) Query(f Filter) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entries, _, err := query(l.path, f)
+	return entries, err
+}
+
+// query reads the matching entries of the log at path and the lines they
+// were read from
+func query(path string, f Filter) ([]Entry, [][]byte, error) {
+	var entries []Entry
+	var lines [][]byte
+	err := scanLog(path, func(line []byte) error {
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return err
+		}
+		if !f.Match(e) {
+			return nil
+		}
+		entries = append(entries, e)
+		lines = append(lines, bytes.Clone(line))
+		if f.Limit > 0 && len(entries) > f.Limit {
+			entries, lines = entries[1:], lines[1:]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return entries, lines, nil
+}
+
+// Close closes the log file
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
@@ -0,0 +1,245 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// Link is a position in the chain: an entry's sequence number and hash.
+// The zero Link is the start, before entry 1.
+type Link struct {
+	Seq  uint64 `json:"seq"`
+	Hash string `json:"hash,omitempty"`
+}
+
+// entryHash hashes an encoded entry's canonical JSON without its hash
+// field. Working from the encoded fields rather than the Entry struct keeps
+// old entries verifiable after fields are added.
+func entryHash(line []byte) (string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return "", err
+	}
+	delete(fields, "hash")
+	canonical, err := intent.CanonicalJSON(fields)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// ChainError reports where a chain breaks
+type ChainError struct {
+	Seq    uint64 // the entry that doesn't follow, or the one expected
+	Reason string
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("audit chain broken at entry %d: %s", e.Seq, e.Reason)
+}
+
+// Next checks that an encoded entry is intact and directly follows l,
+// returning the entry and its link
+func (l Link) Next(line []byte) (Entry, Link, error) {
+	var e Entry
+	if err := json.Unmarshal(line, &e); err != nil {
+		return e, l, &ChainError{Seq: l.Seq + 1, Reason: "unreadable entry: " + err.Error()}
+	}
+	if e.Hash == "" {
+		return e, l, &ChainError{Seq: l.Seq + 1, Reason: "entry is not chained"}
+	}
+	hash, err := entryHash(line)
+	if err != nil {
+		return e, l, &ChainError{Seq: e.Seq, Reason: err.Error()}
+	}
+	switch {
+	case hash != e.Hash:
+		return e, l, &ChainError{Seq: e.Seq, Reason: "entry was altered (hash mismatch)"}
+	case e.Seq != l.Seq+1:
+		return e, l, &ChainError{Seq: l.Seq + 1, Reason: fmt.Sprintf("found entry %d instead (entries missing or reordered)", e.Seq)}
+	case e.Prev != l.Hash:
+		return e, l, &ChainError{Seq: e.Seq, Reason: "entry does not link to the one before"}
+	}
+	return e, Link{Seq: e.Seq, Hash: e.Hash}, nil
+}
+
+// lastLink finds the chain's tip from the log's last line; an empty log,
+// or one whose last entry predates chaining, starts a new chain
+func lastLink(path string) (Link, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Link{}, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return Link{}, err
+	}
+	// Entries are at most 1MB, as Query reads them
+	start := max(0, info.Size()-(1<<20+1))
+	data, err := io.ReadAll(io.NewSectionReader(file, start, info.Size()-start))
+	if err != nil {
+		return Link{}, err
+	}
+	data = bytes.TrimRight(data, "\n")
+	if len(data) == 0 {
+		return Link{}, nil
+	}
+	last := data[bytes.LastIndexByte(data, '\n')+1:]
+	var e Entry
+	if err := json.Unmarshal(last, &e); err != nil {
+		return Link{}, fmt.Errorf("audit log %s: last entry: %w", path, err)
+	}
+	return Link{Seq: e.Seq, Hash: e.Hash}, nil
+}
+
+// firstEntry returns the first chained entry of a log file, or a zero
+// Entry when it has none
+func firstEntry(path string) (Entry, error) {
+	var first Entry
+	err := scanFile(path, func(line []byte) error {
+		if !bytes.Contains(line, []byte(`"hash":`)) {
+			return nil
+		}
+		if err := json.Unmarshal(line, &first); err != nil {
+			return err
+		}
+		return errStop
+	})
+	if errors.Is(err, errStop) {
+		err = nil
+	}
+	return first, err
+}
+
+// errStop ends a scan early
+var errStop = errors.New("stop")
+
+// Verify checks the log's chain from its first chained entry, or the last
+// one retention removed, returning the last link. Entries written before
+// chaining are skipped.
+func (l *Log) Verify() (Link, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return VerifyLog(l.path, Link{})
+}
+
+// VerifyLog checks the chain of the audit log at path, across its rotated
+// files, without opening it for writing, returning the last link. When
+// retention has removed old files the chain is checked from the last entry
+// removed. When at is set, the log must also hold that link, as when
+// checking it continues an export.
+func VerifyLog(path string, at Link) (Link, error) {
+	tip, err := prunedLink(path)
+	if err != nil {
+		return tip, err
+	}
+	found := at.Seq == 0
+	switch {
+	case at.Seq != 0 && at.Seq < tip.Seq:
+		return tip, &ChainError{Seq: at.Seq + 1, Reason: fmt.Sprintf("entries up to %d were removed before they were exported", tip.Seq)}
+	case at.Seq != 0 && at.Seq == tip.Seq:
+		if tip.Hash != at.Hash {
+			return tip, &ChainError{Seq: at.Seq, Reason: "last removed entry differs from the exported one"}
+		}
+		found = true
+	}
+	err = scanLog(path, func(line []byte) error {
+		if tip.Seq == 0 && !bytes.Contains(line, []byte(`"hash":`)) {
+			return nil
+		}
+		var err error
+		if _, tip, err = tip.Next(line); err != nil {
+			return err
+		}
+		if tip.Seq == at.Seq {
+			if tip.Hash != at.Hash {
+				return &ChainError{Seq: at.Seq, Reason: "entry differs from the exported one"}
+			}
+			found = true
+		}
+		return nil
+	})
+	if err == nil && !found {
+		err = &ChainError{Seq: at.Seq, Reason: "exported entry is missing from the log"}
+	}
+	return tip, err
+}
+
+// chained returns the encoded entries after seq, as written
+func (l *Log) chained(after uint64) ([][]byte, error) {
+	var lines [][]byte
+	err := l.scan(func(line []byte) error {
+		var e struct {
+			Seq uint64 `json:"seq"`
+		}
+		if err := json.Unmarshal(line, &e); err != nil {
+			return err
+		}
+		if e.Seq > after {
+			lines = append(lines, bytes.Clone(line))
+		}
+		return nil
+	})
+	return lines, err
+}
+
+// scan calls fn with each line of the log
+func (l *Log) scan(fn func(line []byte) error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return scanLog(l.path, fn)
+}
+
+// scanLog calls fn with each non-empty line of the log at path, oldest
+// first: its rotated files, then the current one
+func scanLog(path string, fn func(line []byte) error) error {
+	rotated, err := rotatedFiles(path)
+	if err != nil {
+		return err
+	}
+	for _, r := range rotated {
+		if err := scanFile(r.path, fn); err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(r.path), err)
+		}
+	}
+	err = scanFile(path, fn)
+	if errors.Is(err, os.ErrNotExist) && len(rotated) > 0 {
+		return nil
+	}
+	return err
+}
+
+// scanFile calls fn with each non-empty line of a log file
+func scanFile(path string, fn func(line []byte) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if err := fn(scanner.Bytes()); err != nil {
+			if errors.Is(err, errStop) {
+				return err
+			}
+			return fmt.Errorf("audit log line %d: %w", line, err)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,229 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeLog records n entries to a new log, returning its path
+func writeLog(t *testing.T, n int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	for i := range n {
+		if err := l.Record(Entry{IntentID: fmt.Sprintf("intent-%d", i+1), IntentType: "light.on", Success: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func readLines(t *testing.T, path string) [][]byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Split(bytes.TrimRight(data, "\n"), []byte("\n"))
+}
+
+func writeLines(t *testing.T, path string, lines [][]byte) {
+	t.Helper()
+	if err := os.WriteFile(path, append(bytes.Join(lines, []byte("\n")), '\n'), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// edit changes an entry's success, rehashing it if asked so the change
+// isn't caught by its own hash
+func edit(t *testing.T, line []byte, rehash bool) []byte {
+	t.Helper()
+	var e Entry
+	if err := json.Unmarshal(line, &e); err != nil {
+		t.Fatal(err)
+	}
+	e.Success = !e.Success
+	if rehash {
+		e.Hash = ""
+		data, err := json.Marshal(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Hash, err = entryHash(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestVerifyLogDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(t *testing.T, lines [][]byte) [][]byte
+		seq    uint64 // the entry reported
+	}{
+		{"edited", func(t *testing.T, lines [][]byte) [][]byte {
+			lines[2] = edit(t, lines[2], false)
+			return lines
+		}, 3},
+		{"edited and rehashed", func(t *testing.T, lines [][]byte) [][]byte {
+			lines[2] = edit(t, lines[2], true)
+			return lines
+		}, 4},
+		{"deleted", func(t *testing.T, lines [][]byte) [][]byte {
+			return slices.Delete(lines, 2, 3)
+		}, 3},
+		{"first deleted", func(t *testing.T, lines [][]byte) [][]byte {
+			return lines[1:]
+		}, 1},
+		{"reordered", func(t *testing.T, lines [][]byte) [][]byte {
+			lines[1], lines[2] = lines[2], lines[1]
+			return lines
+		}, 2},
+		{"duplicated", func(t *testing.T, lines [][]byte) [][]byte {
+			return slices.Insert(lines, 3, lines[2])
+		}, 4},
+		{"hash removed", func(t *testing.T, lines [][]byte) [][]byte {
+			lines[2] = bytes.Replace(lines[2], []byte(`"hash":`), []byte(`"hash_":`), 1)
+			return lines
+		}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeLog(t, 5)
+			if _, err := VerifyLog(path, Link{}); err != nil {
+				t.Fatalf("intact log: %v", err)
+			}
+			writeLines(t, path, tt.tamper(t, readLines(t, path)))
+
+			_, err := VerifyLog(path, Link{})
+			var chainErr *ChainError
+			if !errors.As(err, &chainErr) {
+				t.Fatalf("got %v, want a ChainError", err)
+			}
+			if chainErr.Seq != tt.seq {
+				t.Errorf("reported entry %d, want %d: %v", chainErr.Seq, tt.seq, err)
+			}
+		})
+	}
+}
+
+func TestVerifyLogDetectsTruncation(t *testing.T) {
+	path := writeLog(t, 5)
+	tip, err := VerifyLog(path, Link{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := readLines(t, path)
+	writeLines(t, path, lines[:3])
+
+	// The truncated log is a valid chain of its own; only the link known
+	// from elsewhere, as from an export, shows entries are missing
+	if _, err := VerifyLog(path, Link{}); err != nil {
+		t.Fatalf("truncated log: %v", err)
+	}
+	if _, err := VerifyLog(path, tip); err == nil {
+		t.Error("truncation not detected against the last link")
+	}
+}
+
+func TestVerifyLogAcrossRotatedFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Rotate after every entry or two
+	if err := l.SetRotation(Rotation{MaxSize: 300}); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 6 {
+		if err := l.Record(Entry{IntentID: fmt.Sprintf("intent-%d", i+1), IntentType: "light.on"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.Close()
+	tip, err := VerifyLog(path, Link{})
+	if err != nil || tip.Seq != 6 {
+		t.Fatalf("got %v, %v; want entries up to 6", tip, err)
+	}
+
+	rotated, err := rotatedFiles(path)
+	if err != nil || len(rotated) < 3 {
+		t.Fatalf("got %d rotated files, %v; want at least 3", len(rotated), err)
+	}
+	if err := os.Remove(rotated[1].path); err != nil {
+		t.Fatal(err)
+	}
+	var chainErr *ChainError
+	if _, err := VerifyLog(path, Link{}); !errors.As(err, &chainErr) || chainErr.Seq != rotated[1].first {
+		t.Errorf("removed rotated file: got %v, want a ChainError at %d", err, rotated[1].first)
+	}
+}
+
+func TestExportDetectsTampering(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	record := func(n int) {
+		t.Helper()
+		for range n {
+			if err := l.Record(Entry{IntentType: "light.on", Success: true}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	sink := &DirSink{Dir: filepath.Join(t.TempDir(), "worm")}
+	x := NewExporter(l, sink, 0)
+
+	record(3)
+	if n, err := x.Export(ctx); err != nil || n != 3 {
+		t.Fatalf("export: %d, %v", n, err)
+	}
+	report, err := VerifyExport(ctx, sink)
+	if err != nil || report.Last.Seq != 3 {
+		t.Fatalf("verify export: %+v, %v", report, err)
+	}
+
+	// An exported entry edited locally no longer matches the export
+	lines := readLines(t, path)
+	original := lines[1]
+	lines[1] = edit(t, lines[1], true)
+	writeLines(t, path, lines)
+	if _, err := VerifyLog(path, report.Last); err == nil {
+		t.Error("an edited exported entry was not detected")
+	}
+	lines[1] = original
+	writeLines(t, path, lines)
+
+	// An entry edited before export is refused by the exporter
+	record(2)
+	lines = readLines(t, path)
+	lines[3] = edit(t, lines[3], false)
+	writeLines(t, path, lines)
+	if _, err := x.Export(ctx); err == nil {
+		t.Error("an edited entry was exported")
+	}
+	if report, err := VerifyExport(ctx, sink); err != nil || report.Last.Seq != 3 {
+		t.Errorf("export after refusal: %+v, %v; want it unchanged at 3", report, err)
+	}
+}
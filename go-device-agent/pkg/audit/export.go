@@ -0,0 +1,240 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrSegmentExists is returned, wrapped, by a Sink asked to store a
+// segment it already holds
+var ErrSegmentExists = errors.New("segment already exported")
+
+// Sink is append-only storage for exported segments. Segments are never
+// overwritten or deleted through it.
+type Sink interface {
+	// Put stores a segment under a new name
+	Put(ctx context.Context, name string, data []byte) error
+
+	// List returns the names of the stored segments
+	List(ctx context.Context) ([]string, error)
+
+	// Get reads a stored segment
+	Get(ctx context.Context, name string) ([]byte, error)
+}
+
+// maxSegmentEntries bounds one exported segment
+const maxSegmentEntries = 10000
+
+// segmentName names a segment by the sequence numbers it holds, padded so
+// names sort in chain order
+func segmentName(first, last uint64) string {
+	return fmt.Sprintf("audit-%020d-%020d.jsonl", first, last)
+}
+
+// parseSegmentName reads the range out of a segment's name
+func parseSegmentName(name string) (first, last uint64, ok bool) {
+	base := name[strings.LastIndexByte(name, '/')+1:]
+	n, err := fmt.Sscanf(base, "audit-%020d-%020d.jsonl", &first, &last)
+	return first, last, err == nil && n == 2 && first >= 1 && first <= last
+}
+
+// segments lists the sink's segments in chain order, ignoring other files
+func segments(ctx context.Context, sink Sink) ([]string, error) {
+	names, err := sink.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	kept := names[:0]
+	for _, name := range names {
+		if _, _, ok := parseSegmentName(name); ok {
+			kept = append(kept, name)
+		}
+	}
+	sort.Slice(kept, func(a, b int) bool {
+		fa, _, _ := parseSegmentName(kept[a])
+		fb, _, _ := parseSegmentName(kept[b])
+		return fa < fb
+	})
+	return kept, nil
+}
+
+// Exporter copies new stretches of a log's chain to a sink as segments
+type Exporter struct {
+	log      *Log
+	sink     Sink
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu         sync.Mutex
+	tip        Link
+	loaded     bool
+	lastExport time.Time
+	lastErr    error
+}
+
+// NewExporter creates an exporter that runs every interval (default 1h).
+// From then on the log's retention never deletes entries not yet exported.
+func NewExporter(l *Log, sink Sink, interval time.Duration) *Exporter {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	l.guard()
+	return &Exporter{log: l, sink: sink, interval: interval, logger: slog.Default()}
+}
+
+// SetLogger sets where export failures are logged
+func (x *Exporter) SetLogger(l *slog.Logger) {
+	x.logger = l
+}
+
+// Start exports every interval until ctx is done
+func (x *Exporter) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(x.interval)
+		defer ticker.Stop()
+		for {
+			if n, err := x.Export(ctx); err != nil {
+				x.logger.Error("Audit export failed", "error", err)
+			} else if n > 0 {
+				x.logger.Info("Exported audit entries", "entries", n)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Export stores the entries recorded since the last export, returning how
+// many. It refuses when the local log doesn't continue the exported chain,
+// as when it was edited, truncated, or replaced.
+func (x *Exporter) Export(ctx context.Context) (int, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	n, err := x.export(ctx)
+	x.lastErr = err
+	if err == nil {
+		x.lastExport = time.Now()
+	}
+	return n, err
+}
+
+func (x *Exporter) export(ctx context.Context) (int, error) {
+	if !x.loaded {
+		tip, err := exportedTip(ctx, x.sink)
+		if err != nil {
+			return 0, err
+		}
+		x.tip, x.loaded = tip, true
+		x.log.exportedThrough(tip.Seq)
+	}
+	lines, err := x.log.chained(x.tip.Seq)
+	if err != nil {
+		return 0, err
+	}
+	exported := 0
+	for len(lines) > 0 {
+		batch := lines[:min(len(lines), maxSegmentEntries)]
+		lines = lines[len(batch):]
+		tip := x.tip
+		first := tip.Seq + 1
+		for _, line := range batch {
+			if _, tip, err = tip.Next(line); err != nil {
+				return exported, fmt.Errorf("local audit log does not continue the exported chain: %w", err)
+			}
+		}
+		data := append(bytes.Join(batch, []byte("\n")), '\n')
+		if err := x.sink.Put(ctx, segmentName(first, tip.Seq), data); err != nil {
+			return exported, err
+		}
+		x.tip = tip
+		x.log.exportedThrough(tip.Seq)
+		exported += len(batch)
+	}
+	return exported, nil
+}
+
+// Status reports the last exported link, when the last export succeeded,
+// and the last error
+func (x *Exporter) Status() (Link, time.Time, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.tip, x.lastExport, x.lastErr
+}
+
+// exportedTip is the last link of the last stored segment
+func exportedTip(ctx context.Context, sink Sink) (Link, error) {
+	names, err := segments(ctx, sink)
+	if err != nil || len(names) == 0 {
+		return Link{}, err
+	}
+	name := names[len(names)-1]
+	data, err := sink.Get(ctx, name)
+	if err != nil {
+		return Link{}, err
+	}
+	data = bytes.TrimRight(data, "\n")
+	line := data[bytes.LastIndexByte(data, '\n')+1:]
+	var e Entry
+	if err := json.Unmarshal(line, &e); err != nil {
+		return Link{}, fmt.Errorf("segment %s: last entry: %w", name, err)
+	}
+	if hash, err := entryHash(line); err != nil || hash != e.Hash {
+		return Link{}, fmt.Errorf("segment %s: last entry was altered", name)
+	}
+	if _, last, _ := parseSegmentName(name); e.Seq != last {
+		return Link{}, fmt.Errorf("segment %s ends at entry %d", name, e.Seq)
+	}
+	return Link{Seq: e.Seq, Hash: e.Hash}, nil
+}
+
+// ExportReport summarizes a verified export
+type ExportReport struct {
+	Segments int    `json:"segments"`
+	Entries  uint64 `json:"entries"`
+	Last     Link   `json:"last"`
+}
+
+// VerifyExport checks every exported segment: each entry is intact and
+// links to the one before, across segment boundaries, from entry 1 with no
+// gaps, and each segment holds the range its name claims
+func VerifyExport(ctx context.Context, sink Sink) (ExportReport, error) {
+	var report ExportReport
+	names, err := segments(ctx, sink)
+	if err != nil {
+		return report, err
+	}
+	var tip Link
+	for _, name := range names {
+		first, last, _ := parseSegmentName(name)
+		if first != tip.Seq+1 {
+			return report, fmt.Errorf("segment %s: expected it to start at entry %d", name, tip.Seq+1)
+		}
+		data, err := sink.Get(ctx, name)
+		if err != nil {
+			return report, fmt.Errorf("segment %s: %w", name, err)
+		}
+		for _, line := range bytes.Split(bytes.TrimRight(data, "\n"), []byte("\n")) {
+			if _, tip, err = tip.Next(line); err != nil {
+				return report, fmt.Errorf("segment %s: %w", name, err)
+			}
+		}
+		if tip.Seq != last {
+			return report, fmt.Errorf("segment %s: ends at entry %d", name, tip.Seq)
+		}
+		report.Segments++
+		report.Entries = tip.Seq
+		report.Last = tip
+	}
+	return report, nil
+}
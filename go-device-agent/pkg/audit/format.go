@@ -0,0 +1,101 @@
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export formats
+const (
+	// FormatJSONL writes entries exactly as recorded, one per line, so each
+	// still verifies against its hash
+	FormatJSONL = "jsonl"
+
+	// FormatCSV writes a header row and one row per entry, for
+	// spreadsheets; details and provenance are JSON-encoded cells
+	FormatCSV = "csv"
+)
+
+// csvHeader names the CSV columns
+var csvHeader = []string{
+	"seq", "time", "intent_id", "intent_type", "module", "success", "error",
+	"identity", "roles", "guest", "speaker_id", "correlation_id", "session_id",
+	"provenance", "details", "prev", "hash",
+}
+
+// Export writes the matching entries of the log, oldest first, in format,
+// returning how many
+func (l *Log) Export(w io.Writer, f Filter, format string) (int, error) {
+	l.mu.Lock()
+	entries, lines, err := query(l.path, f)
+	l.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	return write(w, entries, lines, format)
+}
+
+// ExportLog writes the matching entries of the audit log at path, across
+// its rotated files, without opening it for writing
+func ExportLog(w io.Writer, path string, f Filter, format string) (int, error) {
+	entries, lines, err := query(path, f)
+	if err != nil {
+		return 0, err
+	}
+	return write(w, entries, lines, format)
+}
+
+func write(w io.Writer, entries []Entry, lines [][]byte, format string) (int, error) {
+	switch format {
+	case FormatJSONL, "":
+		for _, line := range lines {
+			if _, err := w.Write(append(line, '\n')); err != nil {
+				return 0, err
+			}
+		}
+		return len(lines), nil
+	case FormatCSV:
+		return len(entries), WriteCSV(w, entries)
+	}
+	return 0, fmt.Errorf("unknown audit export format %q (want %s or %s)", format, FormatJSONL, FormatCSV)
+}
+
+// WriteCSV writes entries as CSV with a header row
+func WriteCSV(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		provenance, details := "", ""
+		if len(e.Provenance) > 0 {
+			data, err := json.Marshal(e.Provenance)
+			if err != nil {
+				return err
+			}
+			provenance = string(data)
+		}
+		if len(e.Details) > 0 {
+			data, err := json.Marshal(e.Details)
+			if err != nil {
+				return err
+			}
+			details = string(data)
+		}
+		row := []string{
+			strconv.FormatUint(e.Seq, 10), e.Time.UTC().Format(time.RFC3339Nano), e.IntentID, e.IntentType,
+			e.Module, strconv.FormatBool(e.Success), e.Error, e.Identity, strings.Join(e.Roles, ";"),
+			e.Guest, e.SpeakerID, e.CorrelationID, e.SessionID, provenance, details, e.Prev, e.Hash,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
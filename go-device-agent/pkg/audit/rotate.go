@@ -0,0 +1,211 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Rotation is a log's rotation and retention policy. The current file is
+// renamed to "<name>.<first>-<last><ext>" when it is due, after the
+// entries it holds, and the chain carries on into a new file. Rotated
+// files past retention are deleted oldest first; the last entry deleted is
+// kept in "<name>.pruned.json" so the rest of the chain still verifies.
+type Rotation struct {
+	// MaxSize rotates the log once it reaches this many bytes (0 never)
+	MaxSize int64
+
+	// Interval rotates the log once its first entry is this old (0 never)
+	Interval time.Duration
+
+	// MaxAge deletes rotated files last written longer ago than this
+	// (0 keeps them)
+	MaxAge time.Duration
+
+	// MaxFiles keeps at most this many rotated files (0 keeps them all)
+	MaxFiles int
+}
+
+// due reports whether a current file of size whose first entry was
+// recorded at started should be rotated
+func (r Rotation) due(size int64, started time.Time) bool {
+	return (r.MaxSize > 0 && size >= r.MaxSize) ||
+		(r.Interval > 0 && !started.IsZero() && time.Since(started) >= r.Interval)
+}
+
+// SetRotation sets the log's rotation and retention policy and applies it
+// now. While an exporter is attached, rotated files holding entries it
+// has not exported yet are kept whatever the policy.
+func (l *Log) SetRotation(r Rotation) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rotation = r
+	if l.file != nil && l.first != 0 && r.due(l.size, l.started) {
+		return l.rotate()
+	}
+	return l.prune()
+}
+
+// guard keeps rotated files until exported, for an exporter
+func (l *Log) guard() {
+	l.mu.Lock()
+	l.guarded = true
+	l.mu.Unlock()
+}
+
+// exportedThrough records that entries up to seq are exported
+func (l *Log) exportedThrough(seq uint64) {
+	l.mu.Lock()
+	l.exported = max(l.exported, seq)
+	l.mu.Unlock()
+}
+
+// rotate renames the current file after the entries it holds, starts a
+// new one, and applies retention. Called with l.mu held.
+func (l *Log) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	renameErr := os.Rename(l.path, rotatedName(l.path, l.first, l.tip.Seq))
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		l.file = nil
+		return fmt.Errorf("audit log rotation: %w", err)
+	}
+	l.file = file
+	if renameErr != nil {
+		// Keep appending to the current file; rotation is retried after
+		// the next entry
+		return fmt.Errorf("audit log rotation: %w", renameErr)
+	}
+	l.size, l.first, l.started = 0, 0, time.Time{}
+	return l.prune()
+}
+
+// prune deletes rotated files past retention, oldest first, stopping at
+// the first one retention keeps or an exporter still needs. Called with
+// l.mu held.
+func (l *Log) prune() error {
+	r := l.rotation
+	if r.MaxAge <= 0 && r.MaxFiles <= 0 {
+		return nil
+	}
+	rotated, err := rotatedFiles(l.path)
+	if err != nil {
+		return err
+	}
+	for n, f := range rotated {
+		expired := false
+		if r.MaxAge > 0 {
+			info, err := os.Stat(f.path)
+			if err != nil {
+				return err
+			}
+			expired = time.Since(info.ModTime()) > r.MaxAge
+		}
+		if !expired && (r.MaxFiles <= 0 || len(rotated)-n <= r.MaxFiles) {
+			return nil
+		}
+		if l.guarded && f.last > l.exported {
+			return nil
+		}
+		last, err := lastLink(f.path)
+		if err != nil {
+			return err
+		}
+		if err := writePrunedLink(l.path, last); err != nil {
+			return err
+		}
+		if err := os.Remove(f.path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rotatedFile is a rotated log file and the entries it holds
+type rotatedFile struct {
+	path        string
+	first, last uint64
+}
+
+// rotatedName names the rotated file holding entries first to last
+func rotatedName(path string, first, last uint64) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s.%020d-%020d%s", strings.TrimSuffix(path, ext), first, last, ext)
+}
+
+// rotatedFiles lists the log's rotated files, oldest first
+func rotatedFiles(path string) ([]rotatedFile, error) {
+	ext := filepath.Ext(path)
+	matches, err := filepath.Glob(strings.TrimSuffix(path, ext) + ".*-*" + ext)
+	if err != nil {
+		return nil, err
+	}
+	var files []rotatedFile
+	for _, m := range matches {
+		var first, last uint64
+		if _, err := fmt.Sscanf(strings.TrimPrefix(m, strings.TrimSuffix(path, ext)+"."), "%d-%d", &first, &last); err != nil {
+			continue
+		}
+		if rotatedName(path, first, last) != m {
+			continue
+		}
+		files = append(files, rotatedFile{path: m, first: first, last: last})
+	}
+	sort.Slice(files, func(a, b int) bool { return files[a].first < files[b].first })
+	return files, nil
+}
+
+// prunedPath is where the last entry retention deleted is kept
+func prunedPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".pruned.json"
+}
+
+// prunedLink returns the last entry retention deleted, or the zero Link
+func prunedLink(path string) (Link, error) {
+	var link Link
+	data, err := os.ReadFile(prunedPath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return link, nil
+	}
+	if err != nil {
+		return link, err
+	}
+	if err := json.Unmarshal(data, &link); err != nil {
+		return link, fmt.Errorf("invalid %s: %w", prunedPath(path), err)
+	}
+	return link, nil
+}
+
+// writePrunedLink records the last entry retention deleted, replacing the
+// file atomically so a crash never leaves the chain without a start
+func writePrunedLink(path string, link Link) error {
+	data, err := json.MarshalIndent(link, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := prunedPath(path) + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, prunedPath(path))
+}
+
+// previousLink is where a new, empty current file continues the chain:
+// the last rotated file's last entry, or the last entry retention deleted
+func previousLink(path string) (Link, error) {
+	rotated, err := rotatedFiles(path)
+	if err != nil {
+		return Link{}, err
+	}
+	if len(rotated) > 0 {
+		return lastLink(rotated[len(rotated)-1].path)
+	}
+	return prunedLink(path)
+}
@@ -0,0 +1,341 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+)
+
+// DirSink stores segments as read-only files that are never replaced,
+// for a write-once mount (a WORM volume, or a directory made append-only
+// with chattr +a)
+type DirSink struct {
+	Dir string
+}
+
+func (d *DirSink) Put(ctx context.Context, name string, data []byte) error {
+	if err := os.MkdirAll(d.Dir, 0o700); err != nil {
+		return err
+	}
+	path := filepath.Join(d.Dir, name)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o444)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%s: %w", name, ErrSegmentExists)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	// The new name must survive a crash too
+	if dir, err := os.Open(d.Dir); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
+
+func (d *DirSink) List(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(d.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.Type().IsRegular() {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
+func (d *DirSink) Get(ctx context.Context, name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(d.Dir, name))
+}
+
+// S3Sink stores segments in an S3-compatible bucket (AWS S3, MinIO,
+// Ceph, Backblaze B2) with Object Lock, so not even the credentials
+// that wrote a segment can change or delete it before its retention ends.
+// The bucket must have Object Lock enabled.
+type S3Sink struct {
+	// Endpoint is the service URL (default https://s3.<region>.amazonaws.com);
+	// buckets are addressed by path
+	Endpoint string
+	Region   string // default us-east-1
+	Bucket   string
+	Prefix   string
+
+	AccessKey string
+	SecretKey string
+
+	// Retention is how long each segment is locked (0 to rely on the
+	// bucket's default retention)
+	Retention time.Duration
+
+	// LockMode is COMPLIANCE (the default; no one can shorten the lock) or
+	// GOVERNANCE (users with a bypass permission can)
+	LockMode string
+}
+
+func (s *S3Sink) Put(ctx context.Context, name string, data []byte) error {
+	sum := md5.Sum(data)
+	headers := map[string]string{
+		"Content-Type": "application/x-ndjson",
+		// Object Lock requires a checksum on every write
+		"Content-MD5": base64.StdEncoding.EncodeToString(sum[:]),
+		// Refuse to replace an existing segment
+		"If-None-Match": "*",
+	}
+	if s.Retention > 0 {
+		mode := strings.ToUpper(s.LockMode)
+		if mode == "" {
+			mode = "COMPLIANCE"
+		}
+		headers["x-amz-object-lock-mode"] = mode
+		headers["x-amz-object-lock-retain-until-date"] = time.Now().Add(s.Retention).UTC().Format(time.RFC3339)
+	}
+	resp, err := s.do(ctx, http.MethodPut, s.Prefix+name, nil, data, headers)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3Sink) List(ctx context.Context) ([]string, error) {
+	var names []string
+	query := url.Values{"list-type": {"2"}, "prefix": {s.Prefix}}
+	for {
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("listing s3://%s/%s: %w", s.Bucket, s.Prefix, err)
+		}
+		for _, c := range page.Contents {
+			names = append(names, strings.TrimPrefix(c.Key, s.Prefix))
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return names, nil
+		}
+		query.Set("continuation-token", page.NextContinuationToken)
+	}
+}
+
+func (s *S3Sink) Get(ctx context.Context, name string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, s.Prefix+name, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// do sends a request signed with AWS Signature Version 4, returning an
+// error for any status but 2xx
+func (s *S3Sink) do(ctx context.Context, method, key string, query url.Values, body []byte, headers map[string]string) (*http.Response, error) {
+	region := s.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	base, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	path := strings.TrimSuffix(base.Path, "/") + "/" + s.Bucket
+	if key != "" {
+		path += "/" + key
+	}
+	target := *base
+	target.Path = path
+	target.RawPath = awsEscape(path, true)
+	target.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	s.sign(req, body, region, time.Now().UTC())
+
+	resp, err := connpool.Default.HTTP(time.Minute).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	var failure struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&failure)
+	if resp.StatusCode == http.StatusPreconditionFailed || failure.Code == "PreconditionFailed" {
+		return nil, fmt.Errorf("%s: %w", key, ErrSegmentExists)
+	}
+	if failure.Code == "" {
+		failure.Code = resp.Status
+	}
+	return nil, fmt.Errorf("s3 %s %s: %s %s", method, path, failure.Code, failure.Message)
+}
+
+// sign adds SigV4 headers to req
+func (s *S3Sink) sign(req *http.Request, body []byte, region string, now time.Time) {
+	payload := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(payload[:])
+	amzDate := now.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	names := []string{"host"}
+	values := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		name := strings.ToLower(k)
+		names = append(names, name)
+		values[name] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + values[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	day := now.Format("20060102")
+	scope := day + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsEscape percent-encodes everything but unreserved characters (and
+// slashes in paths), as SigV4 requires
+func awsEscape(s string, path bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		case c == '/' && path:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// canonicalQuery encodes a query sorted by key, as SigV4 signs it
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, awsEscape(k, false)+"="+awsEscape(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// ParseSink reads an export target: "s3://bucket/prefix", completed from
+// s3 (endpoint, region, credentials, retention), or a directory path
+func ParseSink(target string, s3 S3Sink) (Sink, error) {
+	rest, ok := strings.CutPrefix(target, "s3://")
+	if !ok {
+		if target == "" {
+			return nil, errors.New("no export target")
+		}
+		return &DirSink{Dir: target}, nil
+	}
+	bucket, prefix, _ := strings.Cut(rest, "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("export target %s names no bucket", target)
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	if s3.AccessKey == "" || s3.SecretKey == "" {
+		return nil, errors.New("exporting to S3 needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	switch strings.ToUpper(s3.LockMode) {
+	case "", "COMPLIANCE", "GOVERNANCE":
+	default:
+		return nil, fmt.Errorf("unknown Object Lock mode %q (compliance or governance)", s3.LockMode)
+	}
+	s3.Bucket, s3.Prefix = bucket, prefix
+	return &s3, nil
+}
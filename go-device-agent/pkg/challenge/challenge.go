@@ -0,0 +1,287 @@
+// Package challenge verifies the PIN or spoken code an intent carries in
+// its challenge_response parameter before sensitive actions run, so "unlock
+// the front door, PIN 4321" is checked on the device rather than trusted
+// from the agent core. PINs are stored only as salted PBKDF2 hashes, and
+// repeated wrong answers lock every challenge for a while.
+package challenge
+
+import (
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/access"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// Param is the intent parameter the response is carried in
+const Param = "challenge_response"
+
+const (
+	hashScheme     = "pbkdf2-sha256"
+	hashIterations = 100_000
+	hashLength     = 32
+)
+
+// Config says which intents need a PIN and which PINs are accepted
+//
+//	{
+//	  "pins": {
+//	    "alice": "pbkdf2-sha256$100000$...$...",
+//	    "guest": "pbkdf2-sha256$100000$...$..."
+//	  },
+//	  "require": [
+//	    {"intent_types": ["lock.unlock", "security.disarm"]},
+//	    {"intent_types": ["garage.open"], "params": {"door": "main"}}
+//	  ],
+//	  "max_failures": 5,
+//	  "lockout": "15m"
+//	}
+type Config struct {
+	// PINs maps a name for each PIN, recorded in the audit log, to its
+	// hash from HashPIN
+	PINs map[string]string `json:"pins"`
+
+	// Require lists the intents that need a PIN
+	Require []access.Rule `json:"require"`
+
+	// MaxFailures is how many wrong answers in a row lock challenges
+	// (default 5)
+	MaxFailures int `json:"max_failures,omitempty"`
+
+	// Lockout is how long challenges stay locked, as a Go duration
+	// (default 15m). Each wrong answer after a lockout ends locks them
+	// again, until a right one.
+	Lockout string `json:"lockout,omitempty"`
+
+	lockout time.Duration
+}
+
+// LoadConfig reads a JSON challenge config
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid challenge config %s: %w", path, err)
+	}
+	if err := c.check(); err != nil {
+		return nil, fmt.Errorf("invalid challenge config %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+func (c *Config) check() error {
+	if len(c.PINs) == 0 {
+		return errors.New("no pins")
+	}
+	for name, hash := range c.PINs {
+		if _, err := parseHash(hash); err != nil {
+			return fmt.Errorf("pin %s: %w", name, err)
+		}
+	}
+	for n, r := range c.Require {
+		if len(r.IntentTypes) == 0 {
+			return fmt.Errorf("require %d needs intent_types", n)
+		}
+	}
+	if c.MaxFailures <= 0 {
+		c.MaxFailures = 5
+	}
+	c.lockout = 15 * time.Minute
+	if c.Lockout != "" {
+		d, err := time.ParseDuration(c.Lockout)
+		if err != nil || d <= 0 {
+			return errors.New("lockout must be a Go duration such as 10m")
+		}
+		c.lockout = d
+	}
+	return nil
+}
+
+// hash is a parsed PIN hash
+type hash struct {
+	iterations int
+	salt, key  []byte
+}
+
+func parseHash(s string) (*hash, error) {
+	parts := strings.Split(s, "$")
+	if len(parts) != 4 || parts[0] != hashScheme {
+		return nil, fmt.Errorf("hash must be %s$iterations$salt$key, as agent-pin prints", hashScheme)
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations < 1 {
+		return nil, errors.New("hash has invalid iterations")
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("hash has an invalid salt")
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(key) == 0 {
+		return nil, errors.New("hash has an invalid key")
+	}
+	return &hash{iterations: iterations, salt: salt, key: key}, nil
+}
+
+// HashPIN returns the hash a config stores for pin
+func HashPIN(pin string) (string, error) {
+	pin = Normalize(pin)
+	if pin == "" {
+		return "", errors.New("empty PIN")
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := pbkdf2.Key(sha256.New, pin, salt, hashIterations, hashLength)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s$%d$%s$%s", hashScheme, hashIterations,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Normalize makes spoken and typed answers comparable: case is ignored and
+// runs of spaces are one space, so a code phrase transcribed as "Blue
+// falcon" matches "blue  falcon"
+func Normalize(response string) string {
+	return strings.ToLower(strings.Join(strings.Fields(response), " "))
+}
+
+// Response returns an intent's challenge_response as text, so PINs the
+// core sends as JSON numbers work too (though leading zeros are lost that
+// way), and whether it had one
+func Response(i *intent.Intent) (string, bool) {
+	v, ok := i.Parameters[Param]
+	if !ok {
+		return "", false
+	}
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case json.Number:
+		return v.String(), true
+	}
+	return fmt.Sprint(v), true
+}
+
+// Verifier checks challenge responses against the configured PINs
+type Verifier struct {
+	require     []access.Rule
+	pins        map[string]*hash
+	maxFailures int
+	lockout     time.Duration
+
+	// OnLockout, if set, is called when wrong answers lock challenges,
+	// e.g. to warn someone that a PIN is being guessed
+	OnLockout func(until time.Time)
+
+	mu          sync.Mutex
+	failures    int
+	lockedUntil time.Time
+}
+
+// New creates a verifier for a config
+func New(cfg Config) (*Verifier, error) {
+	if err := cfg.check(); err != nil {
+		return nil, err
+	}
+	v := &Verifier{
+		require:     cfg.Require,
+		pins:        make(map[string]*hash, len(cfg.PINs)),
+		maxFailures: cfg.MaxFailures,
+		lockout:     cfg.lockout,
+	}
+	for name, s := range cfg.PINs {
+		v.pins[name], _ = parseHash(s)
+	}
+	return v, nil
+}
+
+// Required reports whether an intent needs a PIN
+func (v *Verifier) Required(i *intent.Intent) bool {
+	for _, r := range v.require {
+		if r.Matches(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify returns the name of the PIN response matches. It fails with
+// CONFIRMATION_REQUIRED for an empty response, UNAUTHORIZED for a wrong
+// one, and RATE_LIMITED while challenges are locked.
+func (v *Verifier) Verify(intentType, response string) (string, error) {
+	response = Normalize(response)
+	if response == "" {
+		return "", agenterrors.Newf(agenterrors.ConfirmationRequired,
+			"%s needs a PIN: resend it with %s", intentType, Param)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if until := v.lockedUntil; time.Now().Before(until) {
+		return "", agenterrors.Newf(agenterrors.RateLimited,
+			"too many wrong PINs; try again after %s", until.Local().Format("15:04"))
+	}
+
+	// Every PIN is tried, in a fixed order, so the time taken doesn't say
+	// which came close
+	names := make([]string, 0, len(v.pins))
+	for name := range v.pins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	matched := ""
+	for _, name := range names {
+		h := v.pins[name]
+		key, err := pbkdf2.Key(sha256.New, response, h.salt, h.iterations, len(h.key))
+		if err == nil && hmac.Equal(key, h.key) && matched == "" {
+			matched = name
+		}
+	}
+	if matched != "" {
+		v.failures = 0
+		return matched, nil
+	}
+
+	v.failures++
+	if v.failures < v.maxFailures {
+		return "", agenterrors.New(agenterrors.Unauthorized, "wrong PIN")
+	}
+	v.lockedUntil = time.Now().Add(v.lockout)
+	if v.OnLockout != nil {
+		go v.OnLockout(v.lockedUntil)
+	}
+	return "", agenterrors.Newf(agenterrors.RateLimited,
+		"wrong PIN; challenges are locked for %s", v.lockout)
+}
+
+// LockedUntil returns when challenges unlock, or the zero time if they
+// aren't locked
+func (v *Verifier) LockedUntil() time.Time {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if time.Now().Before(v.lockedUntil) {
+		return v.lockedUntil
+	}
+	return time.Time{}
+}
@@ -0,0 +1,464 @@
+// Package config loads the agent's YAML config file. The file groups the
+// agent's command-line flags into sections (transports, executors,
+// policies, thresholds, logging, and so on), so a deployment is described
+// in one reviewed file rather than a long command line:
+//
+//	transports:
+//	  http: 127.0.0.1:8080
+//	  tls:
+//	    dir: /etc/agent/tls
+//	    hosts: [agent.home.lan, 192.168.1.10]
+//	executors:
+//	  disable-executors: [frame]
+//	  hue:
+//	    bridge: 192.168.1.20
+//	logging:
+//	  log-format: json
+//
+// Nested keys join with dashes into a flag name, so tls.dir is -tls-dir and
+// hue.bridge is -hue-bridge, and lists join with commas. Each value is
+// checked against its flag's type, and every problem is reported with its
+// line, column, and key. Flags given on the command line override the file.
+//
+// Under profiles, named sets of the same sections replace or add to the
+// file's settings while the profile is active:
+//
+//	profiles:
+//	  travel:
+//	    description: Away from home; no home devices
+//	    executors:
+//	      disable-executors: [device, hue, mqtt]
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Schema lists the flags each section of the file may set
+type Schema map[string][]string
+
+// section returns the section a flag belongs to
+func (s Schema) section(name string) (string, bool) {
+	for section, flags := range s {
+		if slices.Contains(flags, name) {
+			return section, true
+		}
+	}
+	return "", false
+}
+
+// Setting is one flag the file sets
+type Setting struct {
+	Profile string // of the profile setting it, if one does
+	Section string
+	Key     string // as written, e.g. tls.dir
+	Flag    string // e.g. tls-dir
+	Value   string
+	Line    int
+	Column  int
+}
+
+// profilesKey is the top-level key of the file's profiles
+const profilesKey = "profiles"
+
+// Name returns the setting's key as written, with its section and profile
+func (s Setting) Name() string {
+	if s.Profile != "" {
+		return profilesKey + "." + s.Profile + "." + s.Section + "." + s.Key
+	}
+	return s.Section + "." + s.Key
+}
+
+// File is a loaded config file
+type File struct {
+	Path     string
+	Settings []Setting
+	Profiles []Profile
+}
+
+// Profile is a named set of settings that replace or add to the file's
+// while it is active
+type Profile struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Settings    []Setting `json:"-"`
+}
+
+// Profile returns the named profile, or nil if the file has none by that
+// name
+func (f *File) Profile(name string) *Profile {
+	for n := range f.Profiles {
+		if f.Profiles[n].Name == name {
+			return &f.Profiles[n]
+		}
+	}
+	return nil
+}
+
+// WithProfile returns the file's settings with the named profile's in
+// place of those setting the same flags. The empty name is the file's own
+// settings.
+func (f *File) WithProfile(name string) ([]Setting, error) {
+	if name == "" {
+		return f.Settings, nil
+	}
+	profile := f.Profile(name)
+	if profile == nil {
+		names := make([]string, len(f.Profiles))
+		for n, p := range f.Profiles {
+			names[n] = p.Name
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("%s has no profiles, so none named %q", f.Path, name)
+		}
+		return nil, fmt.Errorf("%s has no profile named %q; its profiles are %s", f.Path, name, strings.Join(names, ", "))
+	}
+	overridden := make(map[string]bool, len(profile.Settings))
+	for _, s := range profile.Settings {
+		overridden[s.Flag] = true
+	}
+	settings := make([]Setting, 0, len(f.Settings)+len(profile.Settings))
+	for _, s := range f.Settings {
+		if !overridden[s.Flag] {
+			settings = append(settings, s)
+		}
+	}
+	return append(settings, profile.Settings...), nil
+}
+
+// Error is a problem with one key of a config file
+type Error struct {
+	Path   string
+	Line   int
+	Column int
+	Key    string
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("%s:%d:%d: %s", e.Path, e.Line, e.Column, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", e.Path, e.Line, e.Column, e.Key, e.Msg)
+}
+
+// Load reads and validates the config file at path against the flags of
+// fs that schema places in sections. Every problem found is returned,
+// joined, so they can all be fixed at once.
+func Load(path string, fs *flag.FlagSet, schema Schema) (*File, error) {
+	for section, flags := range schema {
+		for _, name := range flags {
+			if fs.Lookup(name) == nil {
+				return nil, fmt.Errorf("config schema: section %s lists unknown flag %s", section, name)
+			}
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, data, fs, schema)
+}
+
+// Parse validates config file contents, as Load does
+func Parse(path string, data []byte, fs *flag.FlagSet, schema Schema) (*File, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	f := &File{Path: path}
+	if len(doc.Content) == 0 {
+		return f, nil
+	}
+	p := &parser{path: path, fs: fs, schema: schema, seen: make(map[string]string)}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		p.fail(root, "", "the file must be a mapping of sections, such as transports: and executors:")
+		return nil, errors.Join(p.errs...)
+	}
+	for n := 0; n+1 < len(root.Content); n += 2 {
+		key, value := root.Content[n], root.Content[n+1]
+		if key.Value == profilesKey {
+			p.profiles(value)
+			continue
+		}
+		p.section(key, value)
+	}
+	if len(p.errs) > 0 {
+		return nil, errors.Join(p.errs...)
+	}
+	f.Settings, f.Profiles = p.settings, p.parsed
+	return f, nil
+}
+
+type parser struct {
+	path     string
+	fs       *flag.FlagSet
+	schema   Schema
+	settings []Setting
+	seen     map[string]string // flag → key that set it
+	errs     []error
+
+	profile string // being read, if one is
+	prefix  string // of its keys, e.g. profiles.travel.
+	parsed  []Profile
+}
+
+// section reads one section of settings
+func (p *parser) section(key, value *yaml.Node) {
+	section := key.Value
+	if _, ok := p.schema[section]; !ok {
+		msg := "unknown section"
+		if s := suggest(section, slices.Collect(maps.Keys(p.schema))); s != "" {
+			msg += fmt.Sprintf(" (did you mean %s?)", s)
+		} else {
+			msg += "; sections are " + strings.Join(slices.Sorted(maps.Keys(p.schema)), ", ")
+		}
+		p.fail(key, p.prefix+section, msg)
+		return
+	}
+	if value.Kind == yaml.ScalarNode && value.Tag == "!!null" {
+		return
+	}
+	if value.Kind != yaml.MappingNode {
+		p.fail(value, p.prefix+section, "a section must be a mapping of settings")
+		return
+	}
+	p.mapping(section, nil, value)
+}
+
+// profiles reads the profiles, each a description and sections of its own
+func (p *parser) profiles(node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		p.fail(node, profilesKey, "profiles must be a mapping of profile names to their settings")
+		return
+	}
+	settings, seen := p.settings, p.seen
+	defer func() { p.settings, p.seen, p.profile, p.prefix = settings, seen, "", "" }()
+	for n := 0; n+1 < len(node.Content); n += 2 {
+		key, value := node.Content[n], node.Content[n+1]
+		profile := Profile{Name: key.Value}
+		p.profile, p.prefix = profile.Name, profilesKey+"."+profile.Name+"."
+		p.settings, p.seen = nil, make(map[string]string)
+		switch {
+		case !validProfile.MatchString(profile.Name):
+			p.fail(key, profilesKey+"."+profile.Name, "a profile name is letters, digits, dashes, and underscores")
+			continue
+		case value.Kind == yaml.ScalarNode && value.Tag == "!!null":
+		case value.Kind != yaml.MappingNode:
+			p.fail(value, profilesKey+"."+profile.Name, "a profile must be a mapping of a description and sections")
+			continue
+		}
+		for m := 0; m+1 < len(value.Content); m += 2 {
+			k, v := value.Content[m], value.Content[m+1]
+			if k.Value == "description" {
+				if v.Kind != yaml.ScalarNode {
+					p.fail(v, p.prefix+"description", "expected text")
+				}
+				profile.Description = v.Value
+				continue
+			}
+			p.section(k, v)
+		}
+		profile.Settings = p.settings
+		p.parsed = append(p.parsed, profile)
+	}
+}
+
+var validProfile = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func (p *parser) fail(n *yaml.Node, key, msg string) {
+	p.errs = append(p.errs, &Error{Path: p.path, Line: n.Line, Column: n.Column, Key: key, Msg: msg})
+}
+
+// mapping reads the settings of a section, or of a nested key within one
+func (p *parser) mapping(section string, path []string, node *yaml.Node) {
+	for n := 0; n+1 < len(node.Content); n += 2 {
+		key, value := node.Content[n], node.Content[n+1]
+		keyPath := append(slices.Clone(path), key.Value)
+		name := strings.Join(keyPath, "-")
+		display := p.prefix + section + "." + strings.Join(keyPath, ".")
+
+		if value.Kind == yaml.MappingNode {
+			if p.fs.Lookup(name) != nil {
+				p.fail(value, display, "expected a value, not a mapping")
+				continue
+			}
+			if !p.prefixOfSetting(section, name) {
+				p.unknown(key, section, path, name, display)
+				continue
+			}
+			p.mapping(section, keyPath, value)
+			continue
+		}
+
+		f := p.fs.Lookup(name)
+		if f == nil {
+			p.unknown(key, section, path, name, display)
+			continue
+		}
+		if owner, _ := p.schema.section(name); owner != section {
+			if owner == "" {
+				p.fail(key, display, "cannot be set in the config file")
+			} else {
+				p.fail(key, display, fmt.Sprintf("belongs under %s, not %s", owner, section))
+			}
+			continue
+		}
+		if earlier, ok := p.seen[name]; ok {
+			p.fail(key, display, fmt.Sprintf("is already set by %s", earlier))
+			continue
+		}
+		p.seen[name] = display
+
+		text, err := scalar(f, value)
+		if err != nil {
+			p.fail(value, display, err.Error())
+			continue
+		}
+		p.settings = append(p.settings, Setting{
+			Profile: p.profile,
+			Section: section,
+			Key:     strings.Join(keyPath, "."),
+			Flag:    name,
+			Value:   text,
+			Line:    value.Line,
+			Column:  value.Column,
+		})
+	}
+}
+
+// prefixOfSetting reports whether some setting of section starts with
+// prefix and a dash, so prefix can be written as a nested mapping
+func (p *parser) prefixOfSetting(section, prefix string) bool {
+	for _, name := range p.schema[section] {
+		if strings.HasPrefix(name, prefix+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) unknown(key *yaml.Node, section string, path []string, name, display string) {
+	if owner, ok := p.schema.section(name); ok {
+		p.fail(key, display, fmt.Sprintf("belongs under %s, not %s", owner, section))
+		return
+	}
+	msg := "unknown setting"
+	if s := suggest(name, p.schema[section]); s != "" {
+		// Written the way the key was, e.g. tls.hosts rather than tls-hosts
+		if prefix := strings.Join(path, "-") + "-"; len(path) > 0 && strings.HasPrefix(s, prefix) {
+			s = strings.Join(path, ".") + "." + strings.TrimPrefix(s, prefix)
+		}
+		msg += fmt.Sprintf(" (did you mean %s?)", s)
+	}
+	p.fail(key, display, msg)
+}
+
+// scalar checks a value against its flag's type, returning it as the flag
+// would be given on the command line
+func scalar(f *flag.Flag, node *yaml.Node) (string, error) {
+	var value string
+	switch node.Kind {
+	case yaml.ScalarNode:
+		value = node.Value
+	case yaml.SequenceNode:
+		if _, ok := f.Value.(flag.Getter).Get().(string); !ok {
+			return "", errors.New("expected a single value, not a list")
+		}
+		items := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return "", errors.New("list items must be plain values")
+			}
+			items = append(items, item.Value)
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", errors.New("expected a value")
+	}
+
+	switch f.Value.(flag.Getter).Get().(type) {
+	case bool:
+		if node.Tag != "!!bool" {
+			return "", fmt.Errorf("expected true or false, got %q", value)
+		}
+	case int, int64, uint, uint64:
+		if node.Tag != "!!int" {
+			return "", fmt.Errorf("expected a whole number, got %q", value)
+		}
+	case float64:
+		if node.Tag != "!!int" && node.Tag != "!!float" {
+			return "", fmt.Errorf("expected a number, got %q", value)
+		}
+	case time.Duration:
+		if _, err := time.ParseDuration(value); err != nil {
+			return "", fmt.Errorf("expected a duration such as 30s, 5m, or 24h, got %q", value)
+		}
+	}
+	return value, nil
+}
+
+// Apply sets the flags of fs the file sets, except those given on the
+// command line, which take precedence
+func (f *File) Apply(fs *flag.FlagSet) error {
+	return f.apply(fs, f.Settings)
+}
+
+func (f *File) apply(fs *flag.FlagSet, settings []Setting) error {
+	given := make(map[string]bool)
+	fs.Visit(func(fl *flag.Flag) { given[fl.Name] = true })
+	var errs []error
+	for _, s := range settings {
+		if given[s.Flag] {
+			continue
+		}
+		if err := fs.Set(s.Flag, s.Value); err != nil {
+			errs = append(errs, &Error{Path: f.Path, Line: s.Line, Column: s.Column, Key: s.Name(), Msg: err.Error()})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// suggest returns the candidate closest to name, if one is close enough
+// to be a typo
+func suggest(name string, candidates []string) string {
+	best, bestDist := "", 3
+	for _, c := range candidates {
+		if d := distance(name, c); d < bestDist || (d == bestDist && c < best) {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// distance is the Levenshtein distance between a and b
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
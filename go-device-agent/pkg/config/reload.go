@@ -0,0 +1,416 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRestart is returned by a Live setting's Apply when a change can only
+// take effect on restart, such as turning on a feature the agent started
+// without
+var ErrRestart = errors.New("takes effect on restart")
+
+// Live is a group of settings the agent applies while it runs. Apply is
+// called, with the flags already set to their new values, when any of
+// Flags changes or a file named by one of Files is written. If it fails
+// the flags are set back.
+type Live struct {
+	Flags []string
+	Files []string
+	Apply func() error
+}
+
+// Change is one setting of a reload report
+type Change struct {
+	Setting string `json:"setting"` // e.g. policies.risk
+	From    string `json:"from"`
+	To      string `json:"to"`
+
+	// FileChanged is set when the setting names a file whose contents
+	// changed, rather than the setting itself
+	FileChanged bool   `json:"file_changed,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+func (c Change) String() string {
+	if c.FileChanged {
+		return fmt.Sprintf("%s (%s changed)", c.Setting, c.To)
+	}
+	return fmt.Sprintf("%s (%q → %q)", c.Setting, c.From, c.To)
+}
+
+// Report says what a reload changed
+type Report struct {
+	Time    time.Time `json:"time"`
+	Trigger string    `json:"trigger"`
+
+	// Profile is the profile active after the reload, if one is
+	Profile string `json:"profile,omitempty"`
+
+	// Error is set when the file is invalid, in which case nothing changed
+	Error string `json:"error,omitempty"`
+
+	Applied []Change `json:"applied,omitempty"`
+	Failed  []Change `json:"failed,omitempty"`
+
+	// Restart lists changes that take effect on restart; they are listed
+	// by every reload until then
+	Restart []Change `json:"restart_required,omitempty"`
+
+	// Overridden lists changes to settings given on the command line,
+	// which keep their command-line values
+	Overridden []Change `json:"overridden,omitempty"`
+}
+
+// Err returns an error if the file was invalid or a change failed
+func (r *Report) Err() error {
+	if r.Error != "" {
+		return errors.New(r.Error)
+	}
+	if len(r.Failed) > 0 {
+		msgs := make([]string, len(r.Failed))
+		for n, c := range r.Failed {
+			msgs[n] = c.Setting + ": " + c.Error
+		}
+		return errors.New(strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// Reloader applies a config file's changes while the agent runs. Settings
+// registered with AddLive take effect at once; others are reported as
+// needing a restart. Without a file, a reload still reapplies the live
+// settings whose files changed. SetProfile switches to another of the
+// file's profiles the same way.
+type Reloader struct {
+	path   string
+	fs     *flag.FlagSet
+	schema Schema
+	given  map[string]bool // flags given on the command line
+
+	// OnReload, if set, is called with each reload's report
+	OnReload func(*Report)
+
+	mu         sync.Mutex
+	live       []Live
+	applied    map[string]string    // flag → value in effect, as written
+	fileValues map[string]string    // flag → value in the file last loaded
+	modTimes   map[string]time.Time // path → mod time when last read
+	last       *Report
+	profile    string
+	profiles   []Profile
+}
+
+// NewReloader loads the config file at path, if there is one, and applies
+// it to fs as File.Apply does, with the settings of the named profile if
+// profile isn't empty. Call it after fs is parsed.
+func NewReloader(path string, fs *flag.FlagSet, schema Schema, profile string) (*Reloader, error) {
+	r := &Reloader{
+		path:       path,
+		fs:         fs,
+		schema:     schema,
+		given:      make(map[string]bool),
+		applied:    make(map[string]string),
+		fileValues: make(map[string]string),
+		modTimes:   make(map[string]time.Time),
+	}
+	fs.Visit(func(f *flag.Flag) { r.given[f.Name] = true })
+	if path == "" {
+		if profile != "" {
+			return nil, fmt.Errorf("profile %s needs a config file (-config) defining it", profile)
+		}
+		return r, nil
+	}
+	r.modTimes[path] = modTime(path)
+	file, err := Load(path, fs, schema)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := file.WithProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.apply(fs, settings); err != nil {
+		return nil, err
+	}
+	r.profile, r.profiles = profile, file.Profiles
+	for _, s := range settings {
+		r.fileValues[s.Flag] = s.Value
+		if !r.given[s.Flag] {
+			r.applied[s.Flag] = s.Value
+		}
+	}
+	return r, nil
+}
+
+// AddLive registers settings that can change while the agent runs
+func (r *Reloader) AddLive(l Live) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, name := range l.Files {
+		if path := r.fs.Lookup(name).Value.String(); path != "" {
+			r.modTimes[path] = modTime(path)
+		}
+	}
+	r.live = append(r.live, l)
+}
+
+// Last returns the latest reload's report, or nil before the first
+func (r *Reloader) Last() *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.last
+}
+
+// Profile returns the active profile's name, or "" if none is
+func (r *Reloader) Profile() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.profile
+}
+
+// Profiles returns the profiles of the file as last loaded
+func (r *Reloader) Profiles() []Profile {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.profiles)
+}
+
+// Reload rereads the config file and applies what changed, saying what
+// caused the reload in the report. An invalid file changes nothing.
+func (r *Reloader) Reload(trigger string) *Report {
+	return r.finish(r.reload(trigger, nil))
+}
+
+// SetProfile rereads the config file and switches to the named profile,
+// applying the settings it changes as Reload does; "" switches to the
+// file's own settings. It fails if the file has no such profile. If the
+// file is invalid, the profile doesn't change and the report says why.
+func (r *Reloader) SetProfile(name string) (*Report, error) {
+	r.mu.Lock()
+	known := name == "" || slices.ContainsFunc(r.profiles, func(p Profile) bool { return p.Name == name })
+	r.mu.Unlock()
+	if !known {
+		return nil, fmt.Errorf("no profile named %q", name)
+	}
+	trigger := "profile " + name
+	if name == "" {
+		trigger = "no profile"
+	}
+	return r.finish(r.reload(trigger, &name)), nil
+}
+
+func (r *Reloader) finish(report *Report) *Report {
+	if r.OnReload != nil {
+		r.OnReload(report)
+	}
+	return report
+}
+
+// reload applies the file with the profile switchTo names, or the active
+// one if it is nil
+func (r *Reloader) reload(trigger string, switchTo *string) *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report := &Report{Time: time.Now().UTC(), Trigger: trigger, Profile: r.profile}
+	r.last = report
+	profile := r.profile
+	if switchTo != nil {
+		profile = *switchTo
+	}
+
+	values := make(map[string]string)
+	if r.path != "" {
+		// A bad file is reported once, not on every check
+		r.modTimes[r.path] = modTime(r.path)
+		file, err := Load(r.path, r.fs, r.schema)
+		if err == nil {
+			var settings []Setting
+			if settings, err = file.WithProfile(profile); err == nil {
+				for _, s := range settings {
+					values[s.Flag] = s.Value
+				}
+			}
+		}
+		if err != nil {
+			report.Error = err.Error()
+			return report
+		}
+		r.profiles = file.Profiles
+	}
+	r.profile, report.Profile = profile, profile
+
+	// What each flag the file may set should now be
+	want := make(map[string]string)
+	for _, flags := range r.schema {
+		for _, name := range flags {
+			value, inFile := values[name]
+			if r.given[name] {
+				if inFile && value != r.fileValues[name] {
+					report.Overridden = append(report.Overridden, r.change(name, r.fileValues[name], value))
+				}
+				continue
+			}
+			if !inFile {
+				value = r.fs.Lookup(name).DefValue
+			}
+			if value != r.current(name) {
+				want[name] = value
+			}
+		}
+	}
+	r.fileValues = values
+
+	for _, l := range r.live {
+		var changed, files []string
+		for _, name := range l.Flags {
+			if _, ok := want[name]; ok {
+				changed = append(changed, name)
+			}
+		}
+		for _, name := range l.Files {
+			path, ok := want[name]
+			if !ok {
+				path = r.current(name)
+			}
+			if path != "" && !modTime(path).Equal(r.modTimes[path]) {
+				files = append(files, name)
+			}
+		}
+		if len(changed) == 0 && len(files) == 0 {
+			continue
+		}
+		r.apply(l, changed, files, want, report)
+		for _, name := range changed {
+			delete(want, name)
+		}
+	}
+	for name, value := range want {
+		report.Restart = append(report.Restart, r.change(name, r.current(name), value))
+	}
+
+	for _, changes := range [][]Change{report.Applied, report.Failed, report.Restart, report.Overridden} {
+		slices.SortFunc(changes, func(a, b Change) int { return strings.Compare(a.Setting, b.Setting) })
+	}
+	return report
+}
+
+// apply sets the changed flags of a live group and applies it, setting
+// them back if that fails
+func (r *Reloader) apply(l Live, changed, files []string, want map[string]string, report *Report) {
+	previous := make(map[string]string, len(l.Flags))
+	for _, name := range l.Flags {
+		previous[name] = r.fs.Lookup(name).Value.String()
+	}
+	var changes []Change
+	for _, name := range changed {
+		changes = append(changes, r.change(name, r.current(name), want[name]))
+	}
+	for _, name := range files {
+		if !slices.Contains(changed, name) {
+			c := r.change(name, r.current(name), r.current(name))
+			c.FileChanged = true
+			changes = append(changes, c)
+		}
+	}
+
+	err := func() error {
+		for _, name := range changed {
+			if err := r.fs.Set(name, want[name]); err != nil {
+				return err
+			}
+		}
+		return l.Apply()
+	}()
+	// Files are only read again once they change again, like the config
+	for _, name := range files {
+		path := r.fs.Lookup(name).Value.String()
+		r.modTimes[path] = modTime(path)
+	}
+
+	if err != nil {
+		for name, value := range previous {
+			r.fs.Set(name, value)
+		}
+		if errors.Is(err, ErrRestart) {
+			report.Restart = append(report.Restart, changes...)
+			return
+		}
+		for n := range changes {
+			changes[n].Error = err.Error()
+		}
+		report.Failed = append(report.Failed, changes...)
+		return
+	}
+	for _, name := range changed {
+		if slices.Contains(l.Files, name) {
+			delete(r.modTimes, r.current(name))
+			r.modTimes[want[name]] = modTime(want[name])
+		}
+		r.applied[name] = want[name]
+	}
+	report.Applied = append(report.Applied, changes...)
+}
+
+// current returns the value in effect of a flag, as it was written
+func (r *Reloader) current(name string) string {
+	if r.given[name] {
+		return r.fs.Lookup(name).Value.String()
+	}
+	if value, ok := r.applied[name]; ok {
+		return value
+	}
+	return r.fs.Lookup(name).DefValue
+}
+
+func (r *Reloader) change(name, from, to string) Change {
+	section, _ := r.schema.section(name)
+	return Change{Setting: section + "." + name, From: from, To: to}
+}
+
+// Watch checks every interval, until ctx is cancelled, whether the config
+// file or a file named by a live setting was written, and reloads if so
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if r.changed() {
+					r.Reload("file changed")
+				}
+			}
+		}
+	}()
+}
+
+func (r *Reloader) changed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for path, t := range r.modTimes {
+		if !modTime(path).Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// modTime returns a file's modification time, or the zero time if it
+// can't be read
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,157 @@
+// Package connpool shares connections across executors so intents don't pay
+// connection setup on slow IoT links: keep-alive HTTP clients, and MQTT
+// broker sessions shared by the executors on each broker.
+//
+// Bluetooth is not pooled. BlueZ already keeps device connections up for
+// every client, and it scopes discovery to the D-Bus connection that
+// started it, so a shared connection would let one executor's scan stop
+// another's.
+package connpool
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Config limits a pool's HTTP connections
+type Config struct {
+	// MaxIdlePerHost is how many idle keep-alive connections are kept per
+	// host (default 4)
+	MaxIdlePerHost int
+
+	// MaxPerHost caps connections per host, including busy ones (0 for no
+	// limit); requests beyond it wait for a free connection
+	MaxPerHost int
+
+	// IdleTimeout closes keep-alive connections unused this long
+	// (default 90s)
+	IdleTimeout time.Duration
+
+	// DialTimeout bounds connection setup (default 5s)
+	DialTimeout time.Duration
+
+	// Egress, when set, makes the pool air-gapped: only the local network
+	// and the allowlist can be reached, and proxies are not used
+	Egress *Egress
+}
+
+// Pool hands out HTTP clients sharing one set of keep-alive connections,
+// and MQTT sessions
+type Pool struct {
+	transport *http.Transport
+	egress    *Egress
+	dialer    *net.Dialer
+
+	// mqttMu guards mqttOptions and the sessions' users, and is held
+	// while a session is dialed
+	mqttMu      sync.Mutex
+	mqtt        *Sessions[*MQTTSession]
+	mqttOptions map[string]MQTTOptions
+}
+
+// Default is the pool executors share unless configured otherwise
+var Default = New(Config{})
+
+// New creates a pool
+func New(cfg Config) *Pool {
+	if cfg.MaxIdlePerHost <= 0 {
+		cfg.MaxIdlePerHost = 4
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = 90 * time.Second
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = 5 * time.Second
+	}
+	dialer := &net.Dialer{Timeout: cfg.DialTimeout, KeepAlive: 30 * time.Second}
+	proxy, dial := http.ProxyFromEnvironment, dialer.DialContext
+	if cfg.Egress != nil {
+		proxy, dial = nil, cfg.Egress.dialContext(dialer)
+	}
+	p := &Pool{
+		egress:      cfg.Egress,
+		dialer:      dialer,
+		mqttOptions: make(map[string]MQTTOptions),
+		transport: &http.Transport{
+			Proxy:                 proxy,
+			DialContext:           dial,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			MaxIdleConnsPerHost:   cfg.MaxIdlePerHost,
+			MaxConnsPerHost:       cfg.MaxPerHost,
+			IdleConnTimeout:       cfg.IdleTimeout,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+	}
+	// MQTT sessions last as long as an executor uses them
+	p.mqtt = NewSessions(p.dialMQTT, 0, 0)
+	return p
+}
+
+// HTTP returns a client with the given overall request timeout (0 for
+// none) that reuses the pool's connections. Clients are cheap; executors
+// can hold one each.
+func (p *Pool) HTTP(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: metered{p.transport}}
+}
+
+// TLSClient is HTTP with its own TLS settings, such as a private CA for
+// a local service's self-signed certificate. It keeps the pool's limits
+// and egress rules but not its connections, so callers should hold on to
+// it.
+func (p *Pool) TLSClient(timeout time.Duration, tlsConfig *tls.Config) *http.Client {
+	transport := p.transport.Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Timeout: timeout, Transport: metered{transport}}
+}
+
+// PublicHTTP is HTTP for fetching pages from the internet on a user's
+// behalf. It refuses loopback, private, and link-local addresses, checked
+// as dialed so neither a name nor a redirect can lead into the local
+// network, and so skips proxies. It keeps the pool's egress rules but not
+// its connections, so callers should hold on to it.
+func (p *Pool) PublicHTTP(timeout time.Duration) *http.Client {
+	dialer := *p.dialer
+	dialer.Control = func(_, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if ip := net.ParseIP(host); ip == nil || !public(ip) {
+			return fmt.Errorf("%s: %w", host, ErrLocalAddress)
+		}
+		return nil
+	}
+	transport := p.transport.Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	if p.egress != nil {
+		transport.DialContext = p.egress.dialContext(&dialer)
+	}
+	return &http.Client{Timeout: timeout, Transport: metered{transport}}
+}
+
+// ErrLocalAddress is returned, wrapped, when a PublicHTTP client is led
+// to a local address
+var ErrLocalAddress = errors.New("local network address refused")
+
+// sharedAddressSpace is carrier-grade NAT, also used by VPNs such as
+// Tailscale to reach devices at home
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// public reports whether an address is on the internet
+func public(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast() && !sharedAddressSpace.Contains(ip)
+}
+
+// Close drops the pool's idle HTTP connections
+func (p *Pool) Close() {
+	p.transport.CloseIdleConnections()
+}
@@ -0,0 +1,187 @@
+package connpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrEgressBlocked is returned, wrapped, for connections an air-gapped
+// pool refuses
+var ErrEgressBlocked = errors.New("egress blocked in air-gapped mode")
+
+// resolveTTL is how long Reachable trusts a name's addresses
+const resolveTTL = time.Minute
+
+// Egress confines a pool to the local network (loopback, private, and
+// link-local addresses) and an explicit allowlist
+type Egress struct {
+	// Allow lists what may be reached beyond the local network: host
+	// names, "*.example.com" for a domain's subdomains, IP addresses, or
+	// CIDR blocks
+	Allow []string
+
+	// OnBlocked is called with each refused host, e.g. to log it
+	OnBlocked func(host string)
+
+	mu       sync.Mutex
+	resolved map[string]resolution
+}
+
+type resolution struct {
+	local bool
+	at    time.Time
+}
+
+// allowedName reports whether a host name is on the allowlist
+func (e *Egress) allowedName(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, entry := range e.Allow {
+		entry = strings.ToLower(entry)
+		if domain, ok := strings.CutPrefix(entry, "*."); ok {
+			if strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		} else if host == entry {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedIP reports whether an address is local or on the allowlist
+func (e *Egress) allowedIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() {
+		return true
+	}
+	for _, entry := range e.Allow {
+		if _, block, err := net.ParseCIDR(entry); err == nil {
+			if block.Contains(ip) {
+				return true
+			}
+		} else if allowed := net.ParseIP(entry); allowed != nil && allowed.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Egress) blocked(host string) error {
+	if e.OnBlocked != nil {
+		e.OnBlocked(host)
+	}
+	return fmt.Errorf("%s: %w", host, ErrEgressBlocked)
+}
+
+// dialContext wraps a dialer so that only allowlisted names, or addresses
+// that are local or allowlisted, are dialed. Addresses are checked as
+// dialed, after resolution, so a name can't be pointed elsewhere to get
+// out. The dialer's own Control, if any, still runs.
+func (e *Egress) dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	checked := *dialer
+	checked.Control = func(network, address string, c syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if ip := net.ParseIP(host); ip == nil || !e.allowedIP(ip) {
+			return e.blocked(host)
+		}
+		if dialer.Control != nil {
+			return dialer.Control(network, address, c)
+		}
+		return nil
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if net.ParseIP(host) == nil && e.allowedName(host) {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		conn, err := checked.DialContext(ctx, network, addr)
+		// Report the name asked for rather than each address refused
+		if err != nil && errors.Is(err, ErrEgressBlocked) {
+			return nil, fmt.Errorf("%s: %w", host, ErrEgressBlocked)
+		}
+		return conn, err
+	}
+}
+
+// reachable reports whether host may be connected to, resolving names
+// that aren't allowlisted to see whether they are local
+func (e *Egress) reachable(host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return e.allowedIP(ip)
+	}
+	if e.allowedName(host) {
+		return true
+	}
+
+	e.mu.Lock()
+	r, ok := e.resolved[host]
+	e.mu.Unlock()
+	if ok && time.Since(r.at) < resolveTTL {
+		return r.local
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	local := err == nil && len(addrs) > 0
+	for _, a := range addrs {
+		local = local && e.allowedIP(a.IP)
+	}
+	e.mu.Lock()
+	if e.resolved == nil {
+		e.resolved = make(map[string]resolution)
+	}
+	e.resolved[host] = resolution{local: local, at: time.Now()}
+	e.mu.Unlock()
+	return local
+}
+
+// AirGapped reports whether the pool is confined by an egress allowlist
+func (p *Pool) AirGapped() bool {
+	return p.egress != nil
+}
+
+// Reachable reports whether the pool may connect to a URL or host[:port],
+// so executors that depend on a remote service can report themselves
+// unavailable in air-gapped mode. It is always true otherwise.
+func (p *Pool) Reachable(target string) bool {
+	if p.egress == nil {
+		return true
+	}
+	host := target
+	if strings.Contains(target, "://") {
+		u, err := url.Parse(target)
+		if err != nil {
+			return false
+		}
+		host = u.Hostname()
+	} else if h, _, err := net.SplitHostPort(target); err == nil {
+		host = h
+	}
+	return host != "" && p.egress.reachable(host)
+}
+
+// CheckEgress returns an error wrapping ErrEgressBlocked, reporting the
+// attempt, when an executor that dials for itself (IMAP, raw TCP) may not
+// reach host[:port]
+func (p *Pool) CheckEgress(target string) error {
+	if p.Reachable(target) {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(target)
+	if err != nil {
+		host = target
+	}
+	return p.egress.blocked(host)
+}
@@ -0,0 +1,63 @@
+package connpool
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/tracing"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/usage"
+)
+
+// metered counts each request against the usage meter in its context: one
+// external call, the request body sent, and the response body as it is
+// read. When the context carries a span, the request gets a client span
+// of its own, until the response headers arrive, and a traceparent header
+// so the service can continue the trace.
+type metered struct {
+	base http.RoundTripper
+}
+
+func (t metered) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := tracing.Start(req.Context(), "HTTP "+req.Method)
+	if span != nil {
+		defer span.End()
+		span.SetKind(tracing.KindClient)
+		span.SetAttribute("http.request.method", req.Method)
+		span.SetAttribute("server.address", req.URL.Hostname())
+		span.SetAttribute("url.path", req.URL.Path)
+		req = req.Clone(ctx)
+		req.Header.Set("traceparent", span.Context().Traceparent())
+	}
+
+	m := usage.FromContext(req.Context())
+	if m != nil {
+		m.ExternalCall()
+		if req.ContentLength > 0 {
+			m.Transferred(req.ContentLength, 0)
+		}
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.Fail(err.Error())
+		return nil, err
+	}
+	span.SetAttribute("http.response.status_code", resp.StatusCode)
+	if resp.StatusCode >= 500 {
+		span.Fail(resp.Status)
+	}
+	if m != nil {
+		resp.Body = &countingBody{ReadCloser: resp.Body, meter: m}
+	}
+	return resp, nil
+}
+
+type countingBody struct {
+	io.ReadCloser
+	meter *usage.Meter
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.meter.Transferred(0, int64(n))
+	return n, err
+}
@@ -0,0 +1,149 @@
+package connpool
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// MQTTOptions identify a broker session. Executors asking for the same
+// broker, client ID, and login share one connection.
+type MQTTOptions struct {
+	// Broker URL (tcp://, ssl://, ws://, or wss://)
+	Broker   string
+	ClientID string
+	Username string
+	Password string
+
+	// ConnectTimeout bounds each connection attempt (default 5s)
+	ConnectTimeout time.Duration
+}
+
+// MQTTSession is a broker connection shared by the executors on the
+// broker. It reconnects by itself, making each executor's subscriptions
+// again, and disconnects once every executor has released it.
+type MQTTSession struct {
+	pool   *Pool
+	key    string
+	client paho.Client
+
+	mu        sync.Mutex
+	onConnect []func(paho.Client)
+	onLost    []func(error)
+
+	users int // guarded by the pool's mqttMu
+}
+
+// MQTT returns the session for opts, connecting in the background and
+// retrying until the broker answers. A broker an air-gapped pool may not
+// reach is refused with an error wrapping ErrEgressBlocked. Callers
+// Release the session when they are done with it.
+func (p *Pool) MQTT(opts MQTTOptions) (*MQTTSession, error) {
+	broker, err := url.Parse(opts.Broker)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.CheckEgress(broker.Host); err != nil {
+		return nil, err
+	}
+	key := strings.Join([]string{opts.Broker, opts.ClientID, opts.Username, opts.Password}, "\x00")
+
+	p.mqttMu.Lock()
+	defer p.mqttMu.Unlock()
+	p.mqttOptions[key] = opts
+	s, err := p.mqtt.Get(context.Background(), key)
+	if err != nil {
+		return nil, err
+	}
+	s.users++
+	return s, nil
+}
+
+// dialMQTT creates the session for a key. Connecting doesn't block, as
+// the client retries in the background, so MQTT holds mqttMu throughout.
+func (p *Pool) dialMQTT(_ context.Context, key string) (*MQTTSession, error) {
+	opts := p.mqttOptions[key]
+	if opts.ConnectTimeout <= 0 {
+		opts.ConnectTimeout = 5 * time.Second
+	}
+	s := &MQTTSession{pool: p, key: key}
+	s.client = paho.NewClient(paho.NewClientOptions().
+		AddBroker(opts.Broker).
+		SetClientID(opts.ClientID).
+		SetUsername(opts.Username).
+		SetPassword(opts.Password).
+		SetConnectTimeout(opts.ConnectTimeout).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(10 * time.Second).
+		SetMaxReconnectInterval(time.Minute).
+		SetOrderMatters(false).
+		SetOnConnectHandler(s.connected).
+		SetConnectionLostHandler(s.lost))
+	s.client.Connect()
+	return s, nil
+}
+
+// Client returns the session's client, for publishing and subscribing
+func (s *MQTTSession) Client() paho.Client {
+	return s.client
+}
+
+// OnConnect runs fn, which typically subscribes, each time the session
+// connects, and right away if it is connected now
+func (s *MQTTSession) OnConnect(fn func(paho.Client)) {
+	s.mu.Lock()
+	s.onConnect = append(s.onConnect, fn)
+	s.mu.Unlock()
+	if s.client.IsConnectionOpen() {
+		go fn(s.client)
+	}
+}
+
+// OnConnectionLost runs fn when the connection drops, before the client
+// reconnects
+func (s *MQTTSession) OnConnectionLost(fn func(error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onLost = append(s.onLost, fn)
+}
+
+func (s *MQTTSession) connected(client paho.Client) {
+	s.mu.Lock()
+	handlers := append([]func(paho.Client){}, s.onConnect...)
+	s.mu.Unlock()
+	for _, fn := range handlers {
+		fn(client)
+	}
+}
+
+func (s *MQTTSession) lost(_ paho.Client, err error) {
+	s.mu.Lock()
+	handlers := append([]func(error){}, s.onLost...)
+	s.mu.Unlock()
+	for _, fn := range handlers {
+		fn(err)
+	}
+}
+
+// Release ends the caller's use of the session, disconnecting it when no
+// other executor uses it
+func (s *MQTTSession) Release() {
+	p := s.pool
+	p.mqttMu.Lock()
+	defer p.mqttMu.Unlock()
+	if s.users--; s.users == 0 {
+		p.mqtt.Drop(s.key)
+		delete(p.mqttOptions, s.key)
+	}
+}
+
+// Close disconnects from the broker, waiting briefly for work in flight
+func (s *MQTTSession) Close() error {
+	s.client.Disconnect(250)
+	return nil
+}
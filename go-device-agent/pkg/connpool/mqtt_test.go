@@ -0,0 +1,57 @@
+package connpool
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMQTTSessionsAreShared(t *testing.T) {
+	p := New(Config{})
+	// Nothing listens on port 1, so the sessions keep retrying until released
+	opts := MQTTOptions{Broker: "tcp://127.0.0.1:1", ClientID: "agent", Username: "home"}
+	first, err := p.MQTT(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := p.MQTT(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Error("executors on one broker got separate sessions")
+	}
+
+	opts.Username = "guest"
+	other, err := p.MQTT(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == first {
+		t.Error("another login shared the session")
+	}
+	if n := p.mqtt.Len(); n != 2 {
+		t.Fatalf("%d sessions open, want 2", n)
+	}
+
+	// The session stays up until its last user releases it
+	first.Release()
+	if n := p.mqtt.Len(); n != 2 {
+		t.Fatalf("%d sessions open after one of two users released, want 2", n)
+	}
+	second.Release()
+	other.Release()
+	if n := p.mqtt.Len(); n != 0 {
+		t.Errorf("%d sessions open after every user released, want 0", n)
+	}
+}
+
+func TestMQTTChecksEgress(t *testing.T) {
+	p := New(Config{Egress: &Egress{}})
+	// 203.0.113.0/24 is reserved for documentation, so never local
+	if _, err := p.MQTT(MQTTOptions{Broker: "tcp://203.0.113.5:1883"}); !errors.Is(err, ErrEgressBlocked) {
+		t.Fatalf("got %v, want the broker refused in air-gapped mode", err)
+	}
+	if n := p.mqtt.Len(); n != 0 {
+		t.Errorf("%d sessions open for a blocked broker", n)
+	}
+}
@@ -0,0 +1,229 @@
+package connpool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by Get after Close
+var ErrClosed = errors.New("connection pool closed")
+
+// Checker is implemented by sessions that can tell when they have died, so
+// Get redials instead of handing them out
+type Checker interface {
+	Alive() bool
+}
+
+// DialFunc opens the session for a key, such as a broker or device address
+type DialFunc[T io.Closer] func(ctx context.Context, key string) (T, error)
+
+// Sessions keeps one long-lived session per key, shared by every caller:
+// MQTT broker sessions, BLE connections, and the like. Idle sessions are
+// closed after the idle timeout unless kept warm, and the least recently
+// used one is closed when the limit is reached.
+type Sessions[T io.Closer] struct {
+	dial  DialFunc[T]
+	limit int
+	idle  time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*session[T]
+	dialing  map[string]chan struct{}
+	closed   bool
+}
+
+type session[T io.Closer] struct {
+	conn     T
+	lastUsed time.Time
+	warm     bool
+}
+
+// NewSessions creates a session pool holding at most limit sessions (0 for
+// no limit) and closing ones idle longer than idle (0 to keep them)
+func NewSessions[T io.Closer](dial DialFunc[T], limit int, idle time.Duration) *Sessions[T] {
+	return &Sessions[T]{
+		dial:     dial,
+		limit:    limit,
+		idle:     idle,
+		sessions: make(map[string]*session[T]),
+		dialing:  make(map[string]chan struct{}),
+	}
+}
+
+// Get returns the session for key, dialing it if there is none or the
+// existing one has died. Concurrent callers for the same key share a dial.
+func (s *Sessions[T]) Get(ctx context.Context, key string) (T, error) {
+	var zero T
+	for {
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			return zero, ErrClosed
+		}
+		if sess, ok := s.sessions[key]; ok {
+			if alive(sess.conn) {
+				sess.lastUsed = time.Now()
+				s.mu.Unlock()
+				return sess.conn, nil
+			}
+			delete(s.sessions, key)
+			sess.conn.Close()
+		}
+		if wait, ok := s.dialing[key]; ok {
+			s.mu.Unlock()
+			select {
+			case <-wait:
+				continue
+			case <-ctx.Done():
+				return zero, ctx.Err()
+			}
+		}
+		done := make(chan struct{})
+		s.dialing[key] = done
+		s.mu.Unlock()
+
+		conn, err := s.dial(ctx, key)
+
+		s.mu.Lock()
+		delete(s.dialing, key)
+		close(done)
+		if err != nil {
+			s.mu.Unlock()
+			return zero, err
+		}
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return zero, ErrClosed
+		}
+		s.evictLocked()
+		s.sessions[key] = &session[T]{conn: conn, lastUsed: time.Now()}
+		s.mu.Unlock()
+		return conn, nil
+	}
+}
+
+// Warm dials the keys in advance and keeps their sessions open while idle,
+// for devices where the first intent must be fast
+func (s *Sessions[T]) Warm(ctx context.Context, keys ...string) error {
+	var errs []error
+	for _, key := range keys {
+		if _, err := s.Get(ctx, key); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.mu.Lock()
+		if sess, ok := s.sessions[key]; ok {
+			sess.warm = true
+		}
+		s.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// Drop closes the session for key, e.g. after it returned an error; the
+// next Get redials
+func (s *Sessions[T]) Drop(key string) {
+	s.mu.Lock()
+	sess, ok := s.sessions[key]
+	delete(s.sessions, key)
+	s.mu.Unlock()
+	if ok {
+		sess.conn.Close()
+	}
+}
+
+// Len returns the number of open sessions
+func (s *Sessions[T]) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
+
+// Start closes idle sessions until ctx is cancelled, then closes the pool
+func (s *Sessions[T]) Start(ctx context.Context) {
+	go func() {
+		interval := s.idle / 2
+		if interval <= 0 {
+			interval = time.Minute
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				s.Close()
+				return
+			case now := <-ticker.C:
+				s.reap(now)
+			}
+		}
+	}()
+}
+
+// Close closes every session; Get fails afterwards
+func (s *Sessions[T]) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	sessions := s.sessions
+	s.sessions = make(map[string]*session[T])
+	s.mu.Unlock()
+
+	var errs []error
+	for _, sess := range sessions {
+		errs = append(errs, sess.conn.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Sessions[T]) reap(now time.Time) {
+	if s.idle <= 0 {
+		return
+	}
+	var stale []T
+	s.mu.Lock()
+	for key, sess := range s.sessions {
+		if !sess.warm && now.Sub(sess.lastUsed) > s.idle {
+			stale = append(stale, sess.conn)
+			delete(s.sessions, key)
+		}
+	}
+	s.mu.Unlock()
+	for _, conn := range stale {
+		conn.Close()
+	}
+}
+
+// evictLocked makes room for one more session by closing the least
+// recently used one, preferring sessions that aren't kept warm
+func (s *Sessions[T]) evictLocked() {
+	if s.limit <= 0 || len(s.sessions) < s.limit {
+		return
+	}
+	var victim string
+	var oldest time.Time
+	for _, warm := range []bool{false, true} {
+		for key, sess := range s.sessions {
+			if sess.warm == warm && (victim == "" || sess.lastUsed.Before(oldest)) {
+				victim, oldest = key, sess.lastUsed
+			}
+		}
+		if victim != "" {
+			break
+		}
+	}
+	if sess, ok := s.sessions[victim]; ok {
+		delete(s.sessions, victim)
+		go sess.conn.Close()
+	}
+}
+
+func alive(conn interface{}) bool {
+	if c, ok := conn.(Checker); ok {
+		return c.Alive()
+	}
+	return true
+}
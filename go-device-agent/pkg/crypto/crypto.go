@@ -0,0 +1,172 @@
+// Package crypto verifies Ed25519 signatures on intents from the agent core
+// and signs the agent's execution receipts. Several keys can be trusted at
+// once so the core can rotate keys without downtime: publish the new key,
+// switch signing, then retire the old one.
+package crypto
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// Verification errors
+var (
+	ErrUnsigned     = errors.New("intent is not signed")
+	ErrUnknownKey   = errors.New("unknown signing key")
+	ErrKeyInactive  = errors.New("signing key is not valid at this time")
+	ErrBadSignature = errors.New("signature does not match")
+)
+
+// Key is a trusted agent core public key. NotBefore and NotAfter bound when
+// intents signed with it are accepted; zero values leave that side open.
+type Key struct {
+	ID        string            `json:"id"`
+	PublicKey ed25519.PublicKey `json:"public_key"`
+	NotBefore time.Time         `json:"not_before,omitzero"`
+	NotAfter  time.Time         `json:"not_after,omitzero"`
+}
+
+// Active reports whether the key may be used at t
+func (k Key) Active(t time.Time) bool {
+	if !k.NotBefore.IsZero() && t.Before(k.NotBefore) {
+		return false
+	}
+	if !k.NotAfter.IsZero() && !t.Before(k.NotAfter) {
+		return false
+	}
+	return true
+}
+
+// Verifier checks intent signatures against a set of trusted keys
+type Verifier struct {
+	mu   sync.RWMutex
+	keys map[string]Key
+}
+
+// NewVerifier creates a verifier trusting the given keys
+func NewVerifier(keys ...Key) (*Verifier, error) {
+	v := &Verifier{}
+	if err := v.SetKeys(keys); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// LoadVerifier creates a verifier from a key file (see LoadKeys)
+func LoadVerifier(path string) (*Verifier, error) {
+	keys, err := LoadKeys(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewVerifier(keys...)
+}
+
+// LoadKeys reads a JSON array of keys with base64 public keys:
+//
+//	[{"id": "core-2026-01", "public_key": "11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo=", "not_after": "2026-07-01T00:00:00Z"}]
+func LoadKeys(path string) ([]Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keys []Key
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("invalid key file %s: %w", path, err)
+	}
+	return keys, nil
+}
+
+// SetKeys atomically replaces the trusted keys, e.g. after rotating the key file
+func (v *Verifier) SetKeys(keys []Key) error {
+	m := make(map[string]Key, len(keys))
+	for _, k := range keys {
+		if k.ID == "" {
+			return errors.New("key has no id")
+		}
+		if len(k.PublicKey) != ed25519.PublicKeySize {
+			return fmt.Errorf("key %s: public key must be %d bytes", k.ID, ed25519.PublicKeySize)
+		}
+		if _, dup := m[k.ID]; dup {
+			return fmt.Errorf("duplicate key id: %s", k.ID)
+		}
+		m[k.ID] = k
+	}
+
+	v.mu.Lock()
+	v.keys = m
+	v.mu.Unlock()
+	return nil
+}
+
+// Keys returns the trusted keys
+func (v *Verifier) Keys() []Key {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	keys := make([]Key, 0, len(v.keys))
+	for _, k := range v.keys {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Verify checks sig over msg with the key named keyID
+func (v *Verifier) Verify(keyID string, msg, sig []byte) error {
+	return v.VerifyAt(keyID, msg, sig, time.Now())
+}
+
+// VerifyAt checks sig over msg with the key named keyID as of t, for
+// signatures made in the past such as receipts
+func (v *Verifier) VerifyAt(keyID string, msg, sig []byte, t time.Time) error {
+	v.mu.RLock()
+	key, ok := v.keys[keyID]
+	v.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+	if !key.Active(t) {
+		return fmt.Errorf("%w: %s", ErrKeyInactive, keyID)
+	}
+	if !ed25519.Verify(key.PublicKey, msg, sig) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// VerifyIntent checks the intent's signature. Unsigned intents return
+// ErrUnsigned so callers can decide whether to accept them.
+func (v *Verifier) VerifyIntent(i *intent.Intent) error {
+	if i.Signature == "" {
+		return ErrUnsigned
+	}
+	sig, err := base64.StdEncoding.DecodeString(i.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: signature is not base64", ErrBadSignature)
+	}
+	payload, err := i.SigningPayload()
+	if err != nil {
+		return err
+	}
+	return v.Verify(i.KeyID, payload, sig)
+}
+
+// SignIntent signs the intent in place, for tools and tests standing in
+// for the agent core
+func SignIntent(i *intent.Intent, keyID string, priv ed25519.PrivateKey) error {
+	i.KeyID = keyID
+	payload, err := i.SigningPayload()
+	if err != nil {
+		return err
+	}
+	i.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, payload))
+	return nil
+}
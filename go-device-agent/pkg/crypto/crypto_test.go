@@ -0,0 +1,151 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+func newKey(t *testing.T, id string) (Key, ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Key{ID: id, PublicKey: pub}, priv
+}
+
+// signedIntent returns an intent signed with priv as keyID
+func signedIntent(t *testing.T, keyID string, priv ed25519.PrivateKey) *intent.Intent {
+	t.Helper()
+	i := intent.New("device.control").
+		ID("intent-1").
+		Param("device", "front_door").
+		Param("action", "unlock").
+		Param("options", map[string]interface{}{"duration": 30}).
+		Confidence(0.9).
+		Reasoning("User asked to let the plumber in").
+		RequirePermission().
+		CreatedAt(time.Date(2026, 1, 3, 15, 0, 0, 0, time.UTC)).
+		MustBuild()
+	if err := SignIntent(i, keyID, priv); err != nil {
+		t.Fatal(err)
+	}
+	return i
+}
+
+func TestVerifyIntent(t *testing.T) {
+	key, priv := newKey(t, "core-1")
+	v, err := NewVerifier(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.VerifyIntent(signedIntent(t, "core-1", priv)); err != nil {
+		t.Fatalf("a correctly signed intent failed: %v", err)
+	}
+	// The codec an intent travels by doesn't matter
+	for _, codec := range []intent.Codec{intent.JSON, intent.CBOR, intent.Protobuf} {
+		data, err := intent.Encode(signedIntent(t, "core-1", priv), codec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := intent.Decode(data, codec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := v.VerifyIntent(decoded); err != nil {
+			t.Errorf("signed intent sent as %s failed: %v", codec.Name(), err)
+		}
+	}
+}
+
+func TestVerifyIntentDetectsTampering(t *testing.T) {
+	key, priv := newKey(t, "core-1")
+	v, err := NewVerifier(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tamper := map[string]func(i *intent.Intent){
+		"id":                  func(i *intent.Intent) { i.ID = "intent-2" },
+		"intent_type":         func(i *intent.Intent) { i.IntentType = "device.query" },
+		"parameter value":     func(i *intent.Intent) { i.Parameters["action"] = "lock" },
+		"added parameter":     func(i *intent.Intent) { i.Parameters["extra"] = true },
+		"removed parameter":   func(i *intent.Intent) { delete(i.Parameters, "device") },
+		"nested parameter":    func(i *intent.Intent) { i.Parameters["options"] = map[string]interface{}{"duration": 31} },
+		"parameter type":      func(i *intent.Intent) { i.Parameters["options"] = map[string]interface{}{"duration": "30"} },
+		"confidence":          func(i *intent.Intent) { i.Confidence = 0.91 },
+		"reasoning":           func(i *intent.Intent) { i.Reasoning += "." },
+		"requires_permission": func(i *intent.Intent) { i.RequiresPermission = false },
+		"target_module":       func(i *intent.Intent) { module := "security"; i.TargetModule = &module },
+		"created_at":          func(i *intent.Intent) { i.CreatedAt = i.CreatedAt.Add(time.Second) },
+		"guest_token":         func(i *intent.Intent) { i.GuestToken = "guest" },
+		"signature": func(i *intent.Intent) {
+			sig, _ := base64.StdEncoding.DecodeString(i.Signature)
+			sig[0] ^= 1
+			i.Signature = base64.StdEncoding.EncodeToString(sig)
+		},
+	}
+	for name, change := range tamper {
+		t.Run(name, func(t *testing.T) {
+			i := signedIntent(t, "core-1", priv)
+			change(i)
+			if err := v.VerifyIntent(i); !errors.Is(err, ErrBadSignature) {
+				t.Errorf("got %v, want ErrBadSignature", err)
+			}
+		})
+	}
+}
+
+func TestVerifyIntentKeys(t *testing.T) {
+	current, currentPriv := newKey(t, "core-2")
+	retired, retiredPriv := newKey(t, "core-1")
+	retired.NotAfter = time.Now().Add(-time.Hour)
+	_, strangerPriv := newKey(t, "stranger")
+	v, err := NewVerifier(current, retired)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := v.VerifyIntent(signedIntent(t, "core-2", currentPriv)); err != nil {
+		t.Errorf("current key: %v", err)
+	}
+	if err := v.VerifyIntent(signedIntent(t, "core-1", retiredPriv)); !errors.Is(err, ErrKeyInactive) {
+		t.Errorf("retired key: got %v, want ErrKeyInactive", err)
+	}
+	if err := v.VerifyIntent(signedIntent(t, "stranger", strangerPriv)); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("unknown key: got %v, want ErrUnknownKey", err)
+	}
+	// Signed by another key but claiming a trusted one
+	if err := v.VerifyIntent(signedIntent(t, "core-2", strangerPriv)); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("wrong key: got %v, want ErrBadSignature", err)
+	}
+
+	unsigned := signedIntent(t, "core-2", currentPriv)
+	unsigned.Signature = ""
+	if err := v.VerifyIntent(unsigned); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("unsigned: got %v, want ErrUnsigned", err)
+	}
+	garbled := signedIntent(t, "core-2", currentPriv)
+	garbled.Signature = "not base64!"
+	if err := v.VerifyIntent(garbled); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("garbled signature: got %v, want ErrBadSignature", err)
+	}
+}
+
+func TestSetKeysRejectsBadKeys(t *testing.T) {
+	key, _ := newKey(t, "core-1")
+	for name, keys := range map[string][]Key{
+		"no id":     {{PublicKey: key.PublicKey}},
+		"short key": {{ID: "core-1", PublicKey: key.PublicKey[:16]}},
+		"duplicate": {key, key},
+	} {
+		if _, err := NewVerifier(keys...); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
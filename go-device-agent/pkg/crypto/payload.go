@@ -0,0 +1,197 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// payloadInfo separates keys derived for intent parameters from any other
+// use of the same X25519 keys
+const payloadInfo = "local-agent-core intent parameters v1"
+
+// Payload errors
+var (
+	ErrWrongPayloadKey = errors.New("parameters are sealed to another payload key")
+	ErrCannotOpen      = errors.New("encrypted parameters can't be opened")
+)
+
+// PayloadKey is the agent's X25519 key that intent parameters are sealed
+// to, so they are readable only inside the gateway
+type PayloadKey struct {
+	id   string
+	priv *ecdh.PrivateKey
+}
+
+// payloadKeyFile is the on-disk form of the payload key
+type payloadKeyFile struct {
+	ID         string `json:"id"`
+	PrivateKey []byte `json:"private_key"`
+}
+
+func newPayloadKey(priv *ecdh.PrivateKey) *PayloadKey {
+	sum := sha256.Sum256(priv.PublicKey().Bytes())
+	return &PayloadKey{id: "payload-" + hex.EncodeToString(sum[:8]), priv: priv}
+}
+
+// LoadOrCreatePayloadKey reads the payload key at path, generating one on
+// first run. The file is written owner-only.
+func LoadOrCreatePayloadKey(path string) (*PayloadKey, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		k := newPayloadKey(priv)
+		data, err := json.MarshalIndent(payloadKeyFile{ID: k.id, PrivateKey: priv.Bytes()}, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			return nil, err
+		}
+		return k, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f payloadKeyFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid payload key %s: %w", path, err)
+	}
+	priv, err := ecdh.X25519().NewPrivateKey(f.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload key %s: %w", path, err)
+	}
+	return newPayloadKey(priv), nil
+}
+
+// KeyID names the payload key in sealed parameters
+func (k *PayloadKey) KeyID() string {
+	return k.id
+}
+
+// PublicKey returns the X25519 public key senders seal parameters to
+func (k *PayloadKey) PublicKey() []byte {
+	return k.priv.PublicKey().Bytes()
+}
+
+// Open decrypts the intent's sealed parameters into its Parameters and
+// clears them. Parameters sent in the clear too are kept, but one sent
+// both ways is refused as ambiguous.
+func (k *PayloadKey) Open(i *intent.Intent) error {
+	s := i.EncryptedParameters
+	if s == nil {
+		return nil
+	}
+	if s.KeyID != "" && s.KeyID != k.id {
+		return fmt.Errorf("%w %s", ErrWrongPayloadKey, s.KeyID)
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(s.EphemeralKey)
+	if err != nil {
+		return fmt.Errorf("%w: invalid ephemeral key", ErrCannotOpen)
+	}
+	aead, err := payloadAEAD(k.priv, ephemeral, ephemeral.Bytes(), k.PublicKey())
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCannotOpen, err)
+	}
+	if len(s.Nonce) != aead.NonceSize() {
+		return fmt.Errorf("%w: nonce must be %d bytes", ErrCannotOpen, aead.NonceSize())
+	}
+	plain, err := aead.Open(nil, s.Nonce, s.Ciphertext, payloadAAD(i))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCannotOpen, err)
+	}
+
+	var params map[string]interface{}
+	if err := json.Unmarshal(plain, &params); err != nil {
+		return fmt.Errorf("%w: not a JSON object: %v", ErrCannotOpen, err)
+	}
+	for name := range params {
+		if _, ok := i.Parameters[name]; ok {
+			return fmt.Errorf("parameter %s is sent both encrypted and in the clear", name)
+		}
+	}
+	if i.Parameters == nil {
+		i.Parameters = make(map[string]interface{}, len(params))
+	}
+	maps.Copy(i.Parameters, params)
+	i.EncryptedParameters = nil
+	return nil
+}
+
+// SealParameters encrypts params into the intent's EncryptedParameters for
+// the payload key with the given ID and public key, for tools and tests
+// standing in for the agent core. Seal before signing, and don't change
+// the intent's ID or type afterwards: both are bound to the ciphertext.
+func SealParameters(i *intent.Intent, params map[string]interface{}, keyID string, publicKey []byte) error {
+	recipient, err := ecdh.X25519().NewPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	aead, err := payloadAEAD(ephemeral, recipient, ephemeral.PublicKey().Bytes(), publicKey)
+	if err != nil {
+		return err
+	}
+	plain, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	i.EncryptedParameters = &intent.Sealed{
+		KeyID:        keyID,
+		EphemeralKey: ephemeral.PublicKey().Bytes(),
+		Nonce:        nonce,
+		Ciphertext:   aead.Seal(nil, nonce, plain, payloadAAD(i)),
+	}
+	return nil
+}
+
+// payloadAEAD derives the AES-256-GCM key for one sealed payload from the
+// X25519 shared secret, salted with both public keys
+func payloadAEAD(priv *ecdh.PrivateKey, peer *ecdh.PublicKey, ephemeral, recipient []byte) (cipher.AEAD, error) {
+	shared, err := priv.ECDH(peer)
+	if err != nil {
+		return nil, err
+	}
+	salt := append(append([]byte(nil), ephemeral...), recipient...)
+	key, err := hkdf.Key(sha256.New, shared, salt, payloadInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// payloadAAD binds sealed parameters to the intent they were sealed for,
+// so they can't be moved onto another
+func payloadAAD(i *intent.Intent) []byte {
+	return []byte(i.ID + "\n" + i.IntentType)
+}
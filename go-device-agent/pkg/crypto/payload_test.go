@@ -0,0 +1,123 @@
+package crypto
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+func newPayloadKeyAt(t *testing.T) (*PayloadKey, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keys", "payload.json")
+	k, err := LoadOrCreatePayloadKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k, path
+}
+
+// sealedIntent returns an intent with the PIN and code sealed to k, and
+// the room in the clear
+func sealedIntent(t *testing.T, k *PayloadKey) *intent.Intent {
+	t.Helper()
+	i := intent.New("security.disarm").
+		ID("intent-1").
+		Param("room", "hall").
+		Confidence(0.9).
+		Reasoning("User is home").
+		MustBuild()
+	secret := map[string]interface{}{"pin": "4711", "code": map[string]interface{}{"digits": []interface{}{1.0, 2.0}}}
+	if err := SealParameters(i, secret, k.KeyID(), k.PublicKey()); err != nil {
+		t.Fatal(err)
+	}
+	return i
+}
+
+func TestSealedParametersRoundTrip(t *testing.T) {
+	k, path := newPayloadKeyAt(t)
+	reloaded, err := LoadOrCreatePayloadKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.KeyID() != k.KeyID() || string(reloaded.PublicKey()) != string(k.PublicKey()) {
+		t.Fatal("reloading the payload key gave another key")
+	}
+
+	for _, codec := range []intent.Codec{intent.JSON, intent.CBOR, intent.Protobuf} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			data, err := intent.Encode(sealedIntent(t, k), codec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			i, err := intent.Decode(data, codec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := i.Parameters["pin"]; ok {
+				t.Fatal("sealed parameter travels in the clear")
+			}
+			if err := reloaded.Open(i); err != nil {
+				t.Fatal(err)
+			}
+			want := map[string]interface{}{
+				"room": "hall",
+				"pin":  "4711",
+				"code": map[string]interface{}{"digits": []interface{}{1.0, 2.0}},
+			}
+			if !reflect.DeepEqual(i.Parameters, want) {
+				t.Errorf("opened parameters %v, want %v", i.Parameters, want)
+			}
+			if i.EncryptedParameters != nil {
+				t.Error("sealed parameters kept after opening")
+			}
+		})
+	}
+}
+
+func TestOpenRejectsTampering(t *testing.T) {
+	k, _ := newPayloadKeyAt(t)
+	other, _ := newPayloadKeyAt(t)
+
+	tests := []struct {
+		name   string
+		change func(i *intent.Intent)
+		want   error // nil for any error
+	}{
+		{"sealed to another key", func(i *intent.Intent) {
+			*i = *sealedIntent(t, other)
+		}, ErrWrongPayloadKey},
+		{"sealed to another key, unnamed", func(i *intent.Intent) {
+			*i = *sealedIntent(t, other)
+			i.EncryptedParameters.KeyID = ""
+		}, ErrCannotOpen},
+		{"ciphertext", func(i *intent.Intent) { i.EncryptedParameters.Ciphertext[0] ^= 1 }, ErrCannotOpen},
+		{"truncated ciphertext", func(i *intent.Intent) {
+			s := i.EncryptedParameters
+			s.Ciphertext = s.Ciphertext[:len(s.Ciphertext)-1]
+		}, ErrCannotOpen},
+		{"nonce", func(i *intent.Intent) { i.EncryptedParameters.Nonce[0] ^= 1 }, ErrCannotOpen},
+		{"short nonce", func(i *intent.Intent) { i.EncryptedParameters.Nonce = i.EncryptedParameters.Nonce[1:] }, ErrCannotOpen},
+		{"ephemeral key", func(i *intent.Intent) { i.EncryptedParameters.EphemeralKey[0] ^= 1 }, ErrCannotOpen},
+		{"invalid ephemeral key", func(i *intent.Intent) { i.EncryptedParameters.EphemeralKey = []byte{1, 2, 3} }, ErrCannotOpen},
+		{"moved to another intent", func(i *intent.Intent) { i.ID = "intent-2" }, ErrCannotOpen},
+		{"intent type changed", func(i *intent.Intent) { i.IntentType = "security.arm" }, ErrCannotOpen},
+		{"sent in the clear too", func(i *intent.Intent) { i.Parameters["pin"] = "0000" }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := sealedIntent(t, k)
+			tt.change(i)
+			before := len(i.Parameters)
+			err := k.Open(i)
+			if err == nil || (tt.want != nil && !errors.Is(err, tt.want)) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+			if len(i.Parameters) != before || i.EncryptedParameters == nil {
+				t.Error("a refused intent's parameters were changed")
+			}
+		})
+	}
+}
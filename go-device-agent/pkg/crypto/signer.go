@@ -0,0 +1,91 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Signer holds the agent's own identity key, which signs execution receipts
+type Signer struct {
+	id   string
+	priv ed25519.PrivateKey
+}
+
+// identityFile is the on-disk form of the identity key
+type identityFile struct {
+	ID         string             `json:"id"`
+	PrivateKey ed25519.PrivateKey `json:"private_key"`
+}
+
+// NewSigner creates a signer for a private key. The key ID is derived from
+// the public key, so it stays the same wherever the key is used.
+func NewSigner(priv ed25519.PrivateKey) (*Signer, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("private key must be %d bytes", ed25519.PrivateKeySize)
+	}
+	sum := sha256.Sum256(priv.Public().(ed25519.PublicKey))
+	return &Signer{id: "agent-" + hex.EncodeToString(sum[:8]), priv: priv}, nil
+}
+
+// LoadOrCreateSigner reads the identity key at path, generating one on
+// first run. The file is written owner-only.
+func LoadOrCreateSigner(path string) (*Signer, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		s, err := NewSigner(priv)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.MarshalIndent(identityFile{ID: s.id, PrivateKey: priv}, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f identityFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid identity key %s: %w", path, err)
+	}
+	s, err := NewSigner(f.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity key %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// KeyID names the signing key in receipts
+func (s *Signer) KeyID() string {
+	return s.id
+}
+
+// PublicKey returns the key in the form LoadKeys reads, so auditors can
+// add it to a key file and check receipts with a Verifier
+func (s *Signer) PublicKey() Key {
+	return Key{ID: s.id, PublicKey: s.priv.Public().(ed25519.PublicKey)}
+}
+
+// Sign signs msg
+func (s *Signer) Sign(msg []byte) []byte {
+	return ed25519.Sign(s.priv, msg)
+}
@@ -0,0 +1,155 @@
+// Package daemon runs the agent as a background service: it generates and
+// installs a systemd unit on Linux or a launchd property list on macOS, or
+// registers a Windows service, tells systemd when the agent is ready and
+// that it is still alive, answers the Windows service manager, and keeps a
+// PID file.
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Scopes a service can be installed in
+const (
+	// SystemScope starts the agent at boot, as a systemd system unit or a
+	// launchd daemon
+	SystemScope = "system"
+
+	// UserScope starts the agent for the installing user, as a systemd user
+	// unit or a launchd agent
+	UserScope = "user"
+)
+
+// DefaultName is the service's name unless another is given
+const DefaultName = "device-agent"
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Config describes the service to install
+type Config struct {
+	Name        string
+	Description string
+	Scope       string
+
+	// Executable is the agent binary, and Args the arguments it is run
+	// with, such as serve -config /etc/agent.yaml
+	Executable string
+	Args       []string
+
+	// WorkingDir is where the agent runs, so relative paths among Args
+	// mean what they did when it was installed
+	WorkingDir string
+}
+
+// checkName checks the name and scope, which are all uninstalling needs
+func (c Config) checkName() error {
+	if !validName.MatchString(c.Name) {
+		return fmt.Errorf("invalid service name %q: use letters, digits, dots, dashes, and underscores", c.Name)
+	}
+	if c.Scope != SystemScope && c.Scope != UserScope {
+		return fmt.Errorf("invalid service scope %q: use %s or %s", c.Scope, SystemScope, UserScope)
+	}
+	return nil
+}
+
+func (c Config) check() error {
+	if err := c.checkName(); err != nil {
+		return err
+	}
+	if !filepath.IsAbs(c.Executable) {
+		return fmt.Errorf("the executable path %s is not absolute", c.Executable)
+	}
+	// go run builds into a temporary directory that is soon removed
+	if strings.Contains(c.Executable, string(filepath.Separator)+"go-build") {
+		return errors.New("the agent is running from go run's temporary directory; build it with go build and install the binary instead")
+	}
+	return nil
+}
+
+// Step is one thing installing or uninstalling does: writing a file,
+// removing one, running a command, or calling Func, described by Desc
+type Step struct {
+	Write   string // path written with Data
+	Data    []byte
+	Remove  string
+	Command []string
+	Desc    string
+	Func    func() error
+}
+
+func (s Step) String() string {
+	switch {
+	case s.Func != nil:
+		return s.Desc
+	case s.Write != "":
+		return "write " + s.Write
+	case s.Remove != "":
+		return "remove " + s.Remove
+	default:
+		return "run " + strings.Join(s.Command, " ")
+	}
+}
+
+// Do carries out the step
+func (s Step) Do() error {
+	switch {
+	case s.Func != nil:
+		return s.Func()
+	case s.Write != "":
+		if err := os.MkdirAll(filepath.Dir(s.Write), 0o755); err != nil {
+			return err
+		}
+		return os.WriteFile(s.Write, s.Data, 0o644)
+	case s.Remove != "":
+		return os.Remove(s.Remove)
+	default:
+		out, err := exec.Command(s.Command[0], s.Command[1:]...).CombinedOutput()
+		if err != nil {
+			if msg := strings.TrimSpace(string(out)); msg != "" {
+				return fmt.Errorf("%s: %w: %s", strings.Join(s.Command, " "), err, msg)
+			}
+			return fmt.Errorf("%s: %w", strings.Join(s.Command, " "), err)
+		}
+		return nil
+	}
+}
+
+// Install returns the steps that install the service and start it, now
+// and from then on at boot or login
+func Install(c Config) ([]Step, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	return installSteps(c)
+}
+
+// Uninstall returns the steps that stop the service and remove it
+func Uninstall(c Config) ([]Step, error) {
+	if err := c.checkName(); err != nil {
+		return nil, err
+	}
+	return uninstallSteps(c)
+}
+
+// Start returns the steps that start the installed service
+func Start(c Config) ([]Step, error) {
+	if err := c.checkName(); err != nil {
+		return nil, err
+	}
+	return startSteps(c)
+}
+
+// Stop returns the steps that stop the service, which starts again at the
+// next boot or login
+func Stop(c Config) ([]Step, error) {
+	if err := c.checkName(); err != nil {
+		return nil, err
+	}
+	return stopSteps(c)
+}
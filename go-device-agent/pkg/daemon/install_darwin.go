@@ -0,0 +1,83 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// plistPath is where the property list is installed: with the system's
+// daemons, or the user's agents
+func plistPath(c Config) (string, error) {
+	if c.Scope == UserScope {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, "Library", "LaunchAgents", Label(c.Name)+".plist"), nil
+	}
+	return filepath.Join("/Library/LaunchDaemons", Label(c.Name)+".plist"), nil
+}
+
+func logPath(c Config) (string, error) {
+	if c.Scope == UserScope {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, "Library", "Logs", c.Name+".log"), nil
+	}
+	return filepath.Join("/Library/Logs", c.Name+".log"), nil
+}
+
+// domain is the launchd domain the service is loaded into
+func domain(c Config) string {
+	if c.Scope == UserScope {
+		return fmt.Sprintf("gui/%d", os.Getuid())
+	}
+	return "system"
+}
+
+// installSteps writes the property list and loads it, which starts the
+// agent
+func installSteps(c Config) ([]Step, error) {
+	path, err := plistPath(c)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("%s is already installed at %s; uninstall it first", c.Name, path)
+	}
+	logs, err := logPath(c)
+	if err != nil {
+		return nil, err
+	}
+	return []Step{
+		{Write: path, Data: Plist(c, logs)},
+		{Command: []string{"launchctl", "bootstrap", domain(c), path}},
+	}, nil
+}
+
+func uninstallSteps(c Config) ([]Step, error) {
+	path, err := plistPath(c)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("%s is not installed: %w", c.Name, err)
+	}
+	return []Step{
+		{Command: []string{"launchctl", "bootout", domain(c) + "/" + Label(c.Name)}},
+		{Remove: path},
+	}, nil
+}
+
+func startSteps(c Config) ([]Step, error) {
+	return []Step{{Command: []string{"launchctl", "kickstart", domain(c) + "/" + Label(c.Name)}}}, nil
+}
+
+// stopSteps ask the agent to exit, which launchd leaves stopped as it
+// exits successfully
+func stopSteps(c Config) ([]Step, error) {
+	return []Step{{Command: []string{"launchctl", "kill", "SIGTERM", domain(c) + "/" + Label(c.Name)}}}, nil
+}
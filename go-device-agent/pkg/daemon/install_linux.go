@@ -0,0 +1,66 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// unitPath is where the unit is installed: with the system's units, or
+// in the user's systemd configuration
+func unitPath(c Config) (string, error) {
+	if c.Scope == UserScope {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, "systemd", "user", c.Name+".service"), nil
+	}
+	return filepath.Join("/etc/systemd/system", c.Name+".service"), nil
+}
+
+func systemctl(c Config, args ...string) []string {
+	if c.Scope == UserScope {
+		return append([]string{"systemctl", "--user"}, args...)
+	}
+	return append([]string{"systemctl"}, args...)
+}
+
+// installSteps writes the unit, enables it, and starts it, restarting it
+// if it was already running so a reinstall takes effect
+func installSteps(c Config) ([]Step, error) {
+	path, err := unitPath(c)
+	if err != nil {
+		return nil, err
+	}
+	unit := c.Name + ".service"
+	return []Step{
+		{Write: path, Data: Unit(c)},
+		{Command: systemctl(c, "daemon-reload")},
+		{Command: systemctl(c, "enable", unit)},
+		{Command: systemctl(c, "restart", unit)},
+	}, nil
+}
+
+func uninstallSteps(c Config) ([]Step, error) {
+	path, err := unitPath(c)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("%s is not installed: %w", c.Name, err)
+	}
+	return []Step{
+		{Command: systemctl(c, "disable", "--now", c.Name+".service")},
+		{Remove: path},
+		{Command: systemctl(c, "daemon-reload")},
+	}, nil
+}
+
+func startSteps(c Config) ([]Step, error) {
+	return []Step{{Command: systemctl(c, "start", c.Name+".service")}}, nil
+}
+
+func stopSteps(c Config) ([]Step, error) {
+	return []Step{{Command: systemctl(c, "stop", c.Name+".service")}}, nil
+}
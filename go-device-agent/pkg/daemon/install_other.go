@@ -0,0 +1,23 @@
+//go:build !linux && !darwin && !windows
+
+package daemon
+
+import "errors"
+
+var errUnsupported = errors.New("running the agent as a service needs systemd on Linux, launchd on macOS, or Windows")
+
+func installSteps(c Config) ([]Step, error) {
+	return nil, errUnsupported
+}
+
+func uninstallSteps(c Config) ([]Step, error) {
+	return nil, errUnsupported
+}
+
+func startSteps(c Config) ([]Step, error) {
+	return nil, errUnsupported
+}
+
+func stopSteps(c Config) ([]Step, error) {
+	return nil, errUnsupported
+}
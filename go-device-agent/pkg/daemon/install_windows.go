@@ -0,0 +1,167 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// eventTypes are the event log entry types the agent writes
+const eventTypes = eventlog.Error | eventlog.Warning | eventlog.Info
+
+// stopTimeout is how long stopping waits for the agent to exit
+const stopTimeout = 30 * time.Second
+
+var errUserScope = errors.New("Windows services run for the whole system; use -service-scope system")
+
+// serviceArgs are the arguments the service runs the agent with. Services
+// start in the system directory, so the agent first changes to the one it
+// was installed from, and it logs under the service's name.
+func serviceArgs(c Config) []string {
+	args := slices.Clone(c.Args)
+	if c.WorkingDir != "" {
+		args = append(args, "-chdir="+c.WorkingDir)
+	}
+	if c.Name != DefaultName {
+		args = append(args, "-service-name="+c.Name)
+	}
+	return args
+}
+
+// installSteps register the service to start at boot, restarting it when
+// it fails, register its event log source, and start it
+func installSteps(c Config) ([]Step, error) {
+	if c.Scope == UserScope {
+		return nil, errUserScope
+	}
+	args := serviceArgs(c)
+	commandLine := make([]string, 0, len(args)+1)
+	for _, arg := range append([]string{c.Executable}, args...) {
+		commandLine = append(commandLine, syscall.EscapeArg(arg))
+	}
+	return []Step{
+		{
+			Desc: fmt.Sprintf("create service %s running %s", c.Name, strings.Join(commandLine, " ")),
+			Func: func() error {
+				return withService(c.Name, false, func(m *mgr.Mgr, _ *mgr.Service) error {
+					s, err := m.CreateService(c.Name, c.Executable, mgr.Config{
+						DisplayName: c.Name,
+						Description: c.Description,
+						StartType:   mgr.StartAutomatic,
+					}, args...)
+					if err != nil {
+						return err
+					}
+					defer s.Close()
+					return s.SetRecoveryActions([]mgr.RecoveryAction{
+						{Type: mgr.ServiceRestart, Delay: 5 * time.Second},
+						{Type: mgr.ServiceRestart, Delay: 5 * time.Second},
+						{Type: mgr.ServiceRestart, Delay: time.Minute},
+					}, uint32((24 * time.Hour).Seconds()))
+				})
+			},
+		},
+		{
+			Desc: "register event log source " + c.Name,
+			Func: func() error {
+				// A source left by an earlier install is replaced
+				eventlog.Remove(c.Name)
+				return eventlog.InstallAsEventCreate(c.Name, eventTypes)
+			},
+		},
+		{Desc: "start service " + c.Name, Func: func() error { return start(c.Name) }},
+	}, nil
+}
+
+func uninstallSteps(c Config) ([]Step, error) {
+	if c.Scope == UserScope {
+		return nil, errUserScope
+	}
+	return []Step{
+		{Desc: "stop service " + c.Name, Func: func() error { return stop(c.Name) }},
+		{
+			Desc: "delete service " + c.Name,
+			Func: func() error {
+				return withService(c.Name, true, func(_ *mgr.Mgr, s *mgr.Service) error { return s.Delete() })
+			},
+		},
+		{Desc: "remove event log source " + c.Name, Func: func() error { return eventlog.Remove(c.Name) }},
+	}, nil
+}
+
+func startSteps(c Config) ([]Step, error) {
+	if c.Scope == UserScope {
+		return nil, errUserScope
+	}
+	return []Step{{Desc: "start service " + c.Name, Func: func() error { return start(c.Name) }}}, nil
+}
+
+func stopSteps(c Config) ([]Step, error) {
+	if c.Scope == UserScope {
+		return nil, errUserScope
+	}
+	return []Step{{Desc: "stop service " + c.Name, Func: func() error { return stop(c.Name) }}}, nil
+}
+
+// withService connects to the service manager and calls fn with the named
+// service, which must exist if installed is set and must not otherwise
+func withService(name string, installed bool, fn func(*mgr.Mgr, *mgr.Service) error) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connecting to the service manager (run as administrator): %w", err)
+	}
+	defer m.Disconnect()
+	s, err := m.OpenService(name)
+	switch {
+	case err == nil && !installed:
+		s.Close()
+		return fmt.Errorf("%s is already installed; uninstall it first", name)
+	case err == nil:
+		defer s.Close()
+	case installed:
+		return fmt.Errorf("%s is not installed: %w", name, err)
+	}
+	return fn(m, s)
+}
+
+func start(name string) error {
+	return withService(name, true, func(_ *mgr.Mgr, s *mgr.Service) error {
+		err := s.Start()
+		if errors.Is(err, windows.ERROR_SERVICE_ALREADY_RUNNING) {
+			return nil
+		}
+		return err
+	})
+}
+
+// stop asks the service to stop and waits until it has
+func stop(name string) error {
+	return withService(name, true, func(_ *mgr.Mgr, s *mgr.Service) error {
+		status, err := s.Control(svc.Stop)
+		if errors.Is(err, windows.ERROR_SERVICE_NOT_ACTIVE) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		deadline := time.Now().Add(stopTimeout)
+		for status.State != svc.Stopped {
+			if time.Now().After(deadline) {
+				return fmt.Errorf("%s did not stop within %s", name, stopTimeout)
+			}
+			time.Sleep(300 * time.Millisecond)
+			if status, err = s.Query(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,44 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+)
+
+// Label returns the launchd label of the service named name
+func Label(name string) string {
+	return "local-agent-core." + name
+}
+
+// Plist returns the launchd property list running the agent: at load, and
+// so at boot or login, and again whenever it exits with an error. Its
+// output is appended to logPath.
+func Plist(c Config, logPath string) []byte {
+	var b bytes.Buffer
+	str := func(s string) string {
+		var e bytes.Buffer
+		xml.EscapeText(&e, []byte(s))
+		return "<string>" + e.String() + "</string>"
+	}
+	fmt.Fprintf(&b, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(&b, "<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n")
+	fmt.Fprintf(&b, "<!-- Installed by agent install-service -->\n")
+	fmt.Fprintf(&b, "<plist version=\"1.0\">\n<dict>\n")
+	fmt.Fprintf(&b, "\t<key>Label</key>\n\t%s\n", str(Label(c.Name)))
+	fmt.Fprintf(&b, "\t<key>ProgramArguments</key>\n\t<array>\n")
+	for _, arg := range append([]string{c.Executable}, c.Args...) {
+		fmt.Fprintf(&b, "\t\t%s\n", str(arg))
+	}
+	fmt.Fprintf(&b, "\t</array>\n")
+	if c.WorkingDir != "" {
+		fmt.Fprintf(&b, "\t<key>WorkingDirectory</key>\n\t%s\n", str(c.WorkingDir))
+	}
+	fmt.Fprintf(&b, "\t<key>RunAtLoad</key>\n\t<true/>\n")
+	fmt.Fprintf(&b, "\t<key>KeepAlive</key>\n\t<dict>\n\t\t<key>SuccessfulExit</key>\n\t\t<false/>\n\t</dict>\n")
+	fmt.Fprintf(&b, "\t<key>ThrottleInterval</key>\n\t<integer>5</integer>\n")
+	fmt.Fprintf(&b, "\t<key>StandardOutPath</key>\n\t%s\n", str(logPath))
+	fmt.Fprintf(&b, "\t<key>StandardErrorPath</key>\n\t%s\n", str(logPath))
+	fmt.Fprintf(&b, "</dict>\n</plist>\n")
+	return b.Bytes()
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !windows
+
+package daemon
+
+// processAlive can't tell on this platform, so a PID file is taken to name a
+// running process and must be removed by hand if the agent died
+func processAlive(pid int) bool {
+	return true
+}
@@ -0,0 +1,21 @@
+package daemon
+
+import "golang.org/x/sys/windows"
+
+// stillActive is the exit code of a process that hasn't exited
+const stillActive = 259
+
+// processAlive reports whether a process with the given ID is running
+func processAlive(pid int) bool {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		// Access is denied to processes of other users, which still exist
+		return err == windows.ERROR_ACCESS_DENIED
+	}
+	defer windows.CloseHandle(h)
+	var code uint32
+	if err := windows.GetExitCodeProcess(h, &code); err != nil {
+		return true
+	}
+	return code == stillActive
+}
@@ -0,0 +1,36 @@
+//go:build !windows
+
+package daemon
+
+import (
+	"errors"
+	"log/slog"
+)
+
+var errNoEventLog = errors.New("the event log is only on Windows")
+
+// Service is the agent's side of the Windows service manager, when it was
+// started by it
+type Service struct{}
+
+// StartService reports the agent running to the Windows service manager,
+// if the manager started it, and returns nil otherwise and on other
+// platforms
+func StartService(name string) (*Service, error) {
+	return nil, nil
+}
+
+// Stopping is closed when the service manager asks the agent to stop
+func (s *Service) Stopping() <-chan struct{} {
+	return nil
+}
+
+// Exit tells the service manager the agent has stopped; call it as the
+// agent exits
+func (s *Service) Exit() {}
+
+// LogHandler returns a handler writing each record to the Windows event
+// log
+func (s *Service) LogHandler() (slog.Handler, error) {
+	return nil, errNoEventLog
+}
@@ -0,0 +1,148 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// eventID is the ID of every event the agent logs
+const eventID = 1
+
+// Service is the agent's side of the Windows service manager, when it was
+// started by it
+type Service struct {
+	name     string
+	stopping chan struct{}
+	exited   chan struct{} // closed by Exit
+	done     chan struct{} // closed once the manager is told the agent stopped
+}
+
+// StartService reports the agent running to the Windows service manager,
+// if the manager started it, and returns nil otherwise and on other
+// platforms
+func StartService(name string) (*Service, error) {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return nil, err
+	}
+	s := &Service{
+		name:     name,
+		stopping: make(chan struct{}),
+		exited:   make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go func() {
+		defer close(s.done)
+		svc.Run(name, s)
+	}()
+	return s, nil
+}
+
+// Execute answers the service manager until it asks the agent to stop and
+// the agent has exited, or the agent exits by itself
+func (s *Service) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case <-s.exited:
+			return false, 0
+		case r := <-requests:
+			switch r.Cmd {
+			case svc.Interrogate:
+				status <- r.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending, WaitHint: uint32(stopTimeout / time.Millisecond)}
+				close(s.stopping)
+				<-s.exited
+				return false, 0
+			}
+		}
+	}
+}
+
+// Stopping is closed when the service manager asks the agent to stop
+func (s *Service) Stopping() <-chan struct{} {
+	return s.stopping
+}
+
+// Exit tells the service manager the agent has stopped; call it as the
+// agent exits
+func (s *Service) Exit() {
+	close(s.exited)
+	select {
+	case <-s.done:
+	case <-time.After(5 * time.Second):
+	}
+}
+
+// LogHandler returns a handler writing each record to the Windows event
+// log, as text under the service's name, as an error, warning, or
+// information event by its level
+func (s *Service) LogHandler() (slog.Handler, error) {
+	l, err := eventlog.Open(s.name)
+	if err != nil {
+		return nil, err
+	}
+	h := &eventLogHandler{log: l, mu: new(sync.Mutex), buf: new(bytes.Buffer)}
+	h.text = slog.NewTextHandler(h.buf, &slog.HandlerOptions{
+		Level: slog.Level(-1 << 10),
+		// The event log records the time itself
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey) {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	return h, nil
+}
+
+// eventLogHandler formats records as text and writes each as an event
+type eventLogHandler struct {
+	log  *eventlog.Log
+	text slog.Handler
+
+	// shared by the handlers made with WithAttrs and WithGroup
+	mu  *sync.Mutex
+	buf *bytes.Buffer
+}
+
+func (h *eventLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return true
+}
+
+func (h *eventLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.buf.Reset()
+	if err := h.text.Handle(ctx, r); err != nil {
+		return err
+	}
+	msg := string(bytes.TrimRight(h.buf.Bytes(), "\n"))
+	switch {
+	case r.Level >= slog.LevelError:
+		return h.log.Error(eventID, msg)
+	case r.Level >= slog.LevelWarn:
+		return h.log.Warning(eventID, msg)
+	default:
+		return h.log.Info(eventID, msg)
+	}
+}
+
+func (h *eventLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.text = h.text.WithAttrs(attrs)
+	return &c
+}
+
+func (h *eventLogHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.text = h.text.WithGroup(name)
+	return &c
+}
@@ -0,0 +1,63 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// WatchdogSec is the watchdog timeout of the generated unit; the agent pings
+// at half of it
+const WatchdogSec = 30
+
+// Unit returns the systemd unit running the agent as a Type=notify service:
+// the agent reports when it is ready and pings the watchdog, systemd
+// restarts it if it fails or stops answering, and systemctl reload sends it
+// SIGHUP to reload its config file
+func Unit(c Config) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# Installed by agent install-service\n")
+	fmt.Fprintf(&b, "[Unit]\n")
+	fmt.Fprintf(&b, "Description=%s\n", systemdEscape(c.Description))
+	if c.Scope == SystemScope {
+		fmt.Fprintf(&b, "Wants=network-online.target\n")
+		fmt.Fprintf(&b, "After=network-online.target\n")
+	}
+	fmt.Fprintf(&b, "\n[Service]\n")
+	fmt.Fprintf(&b, "Type=notify\n")
+	fmt.Fprintf(&b, "NotifyAccess=main\n")
+	args := make([]string, 0, len(c.Args)+1)
+	for _, arg := range append([]string{c.Executable}, c.Args...) {
+		args = append(args, systemdQuote(arg))
+	}
+	fmt.Fprintf(&b, "ExecStart=%s\n", strings.Join(args, " "))
+	fmt.Fprintf(&b, "ExecReload=/bin/kill -HUP $MAINPID\n")
+	if c.WorkingDir != "" {
+		fmt.Fprintf(&b, "WorkingDirectory=%s\n", systemdEscape(c.WorkingDir))
+	}
+	fmt.Fprintf(&b, "Restart=on-failure\n")
+	fmt.Fprintf(&b, "RestartSec=5\n")
+	fmt.Fprintf(&b, "WatchdogSec=%d\n", WatchdogSec)
+	fmt.Fprintf(&b, "\n[Install]\n")
+	if c.Scope == SystemScope {
+		fmt.Fprintf(&b, "WantedBy=multi-user.target\n")
+	} else {
+		fmt.Fprintf(&b, "WantedBy=default.target\n")
+	}
+	return b.Bytes()
+}
+
+// systemdEscape keeps systemd from expanding specifiers such as %h in s
+func systemdEscape(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
+
+// systemdQuote quotes an ExecStart argument so systemd passes it through
+// unchanged, without splitting it or expanding variables in it
+func systemdQuote(arg string) string {
+	arg = strings.ReplaceAll(systemdEscape(arg), "$", "$$")
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\"'\\;") {
+		return arg
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(arg) + `"`
+}
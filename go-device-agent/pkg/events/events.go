@@ -0,0 +1,103 @@
+// Package events provides the in-process event bus executors use to report
+// things that happen outside of an intent (sensor triggers, alerts, etc.)
+package events
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/internal/uuid"
+)
+
+// Event is a single occurrence published on the bus
+type Event struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	Source    string                 `json:"source"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// Handler receives published events. Handlers run synchronously on the
+// publisher's goroutine, so they must not block.
+type Handler func(Event)
+
+type subscription struct {
+	pattern string
+	handler Handler
+}
+
+// Bus fans events out to subscribers
+type Bus struct {
+	subs   map[int]subscription
+	nextID int
+	mu     sync.RWMutex
+}
+
+// NewBus creates an event bus
+func NewBus() *Bus {
+	return &Bus{
+		subs: make(map[int]subscription),
+	}
+}
+
+// Subscribe registers a handler for events matching pattern and returns a
+// function that removes it. Patterns are an exact type, a prefix ending in
+// ".*" (e.g. "sound.*"), or "*" for everything.
+func (b *Bus) Subscribe(pattern string, handler Handler) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = subscription{pattern: pattern, handler: handler}
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs, id)
+	}
+}
+
+// Publish delivers an event to every matching subscriber, filling in the
+// ID and timestamp when they are not set
+func (b *Bus) Publish(e Event) {
+	if e.ID == "" {
+		e.ID = NewID()
+	}
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
+
+	b.mu.RLock()
+	handlers := make([]Handler, 0, len(b.subs))
+	for _, s := range b.subs {
+		if Match(s.pattern, e.Type) {
+			handlers = append(handlers, s.handler)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(e)
+	}
+}
+
+// Match reports whether an event type matches a subscription pattern
+func Match(pattern, eventType string) bool {
+	switch {
+	case pattern == "*":
+		return true
+	case strings.HasSuffix(pattern, ".*"):
+		return strings.HasPrefix(eventType, strings.TrimSuffix(pattern, "*"))
+	default:
+		return pattern == eventType
+	}
+}
+
+// NewID returns an ID for an event in the agent's ID strategy (a random
+// UUIDv4 unless intent.SetIDStrategy chose a time-ordered one)
+func NewID() string {
+	return uuid.NewID()
+}
@@ -0,0 +1,347 @@
+// Package audio controls the host's sound output: volume, mute, and which
+// device plays, through PulseAudio (or PipeWire's PulseAudio server) on
+// Linux, CoreAudio on macOS, and WASAPI on Windows
+package audio
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Output is a device sound can play on
+type Output struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Default bool   `json:"default"`
+	Volume  int    `json:"volume"` // percent
+	Muted   bool   `json:"muted"`
+}
+
+// backend is a platform's sound system
+type backend interface {
+	name() string
+	outputs(ctx context.Context) ([]Output, error)
+	setVolume(ctx context.Context, id string, percent int) error
+	setMute(ctx context.Context, id string, muted bool) error
+	setDefault(ctx context.Context, id string) error
+}
+
+// Config sets volume limits
+type Config struct {
+	// MaxVolume caps the volume intents may set, in percent (default 100)
+	MaxVolume int
+
+	// Step is how far "up" and "down" move the volume (default 10)
+	Step int
+}
+
+// Executor handles audio.volume, audio.mute, audio.set_output, and
+// audio.outputs
+type Executor struct {
+	cfg     Config
+	backend backend
+}
+
+// NewExecutor finds the platform's sound system, failing when there is
+// none
+func NewExecutor(cfg Config) (*Executor, error) {
+	if cfg.MaxVolume <= 0 {
+		cfg.MaxVolume = 100
+	}
+	if cfg.Step <= 0 {
+		cfg.Step = 10
+	}
+	b, err := newBackend()
+	if err != nil {
+		return nil, err
+	}
+	return &Executor{cfg: cfg, backend: b}, nil
+}
+
+// Backend names the sound system in use, e.g. "pulseaudio"
+func (e *Executor) Backend() string {
+	return e.backend.name()
+}
+
+func (e *Executor) Name() string {
+	return "audio"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"audio.volume", "audio.mute", "audio.set_output", "audio.outputs"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	// pactl on Linux, SwitchAudioSource on macOS; Windows uses WASAPI
+	return sandbox.Needs{Subprocess: runtime.GOOS != "windows"}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "audio",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	switch i.IntentType {
+	case "audio.outputs":
+		outputs, err := e.backend.outputs(ctx)
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"outputs": outputs,
+			"backend": e.backend.name(),
+		}
+
+	case "audio.volume":
+		var params struct {
+			Device    string `param:"device"`
+			Level     *int   `param:"level"`
+			Change    *int   `param:"change"`
+			Direction string `param:"direction"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		given := 0
+		for _, set := range []bool{params.Level != nil, params.Change != nil, params.Direction != ""} {
+			if set {
+				given++
+			}
+		}
+		if given > 1 {
+			return fail(agenterrors.New(agenterrors.InvalidParams, "give one of level, change, or direction"))
+		}
+		out, err := e.output(ctx, params.Device)
+		if err != nil {
+			return fail(err)
+		}
+		previous := out.Volume
+		level := out.Volume
+		switch {
+		case params.Level != nil:
+			level = *params.Level
+		case params.Change != nil:
+			level += *params.Change
+		case params.Direction == "up":
+			level += e.cfg.Step
+		case params.Direction == "down":
+			level -= e.cfg.Step
+		case params.Direction != "":
+			return fail(agenterrors.Newf(agenterrors.InvalidParams, "direction must be up or down, not %s", params.Direction))
+		}
+		if params.Level != nil && (level < 0 || level > e.cfg.MaxVolume) {
+			return fail(agenterrors.Newf(agenterrors.InvalidParams, "level must be between 0 and %d", e.cfg.MaxVolume))
+		}
+		level = max(0, min(level, e.cfg.MaxVolume))
+		if given > 0 {
+			// The gateway clamped a level given outright; a change or step
+			// is held to the same limit here
+			capped, adjustment := gateway.SafetyClamp(ctx, "level", float64(level))
+			if adjustment != nil {
+				level = int(capped)
+				result.Adjusted = map[string]interface{}{"level": adjustment}
+			}
+		}
+		if given > 0 && level != out.Volume {
+			if err := e.backend.setVolume(ctx, out.ID, level); err != nil {
+				return fail(err)
+			}
+			out.Volume = level
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"device":   out.ID,
+			"name":     out.Name,
+			"volume":   out.Volume,
+			"previous": previous,
+			"muted":    out.Muted,
+		}
+
+	case "audio.mute":
+		var params struct {
+			Device string `param:"device"`
+			Muted  *bool  `param:"muted"`
+			Toggle bool   `param:"toggle"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		if params.Toggle && params.Muted != nil {
+			return fail(agenterrors.New(agenterrors.InvalidParams, "give muted or toggle, not both"))
+		}
+		out, err := e.output(ctx, params.Device)
+		if err != nil {
+			return fail(err)
+		}
+		muted := true
+		switch {
+		case params.Toggle:
+			muted = !out.Muted
+		case params.Muted != nil:
+			muted = *params.Muted
+		}
+		if muted != out.Muted {
+			if err := e.backend.setMute(ctx, out.ID, muted); err != nil {
+				return fail(err)
+			}
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"device": out.ID,
+			"name":   out.Name,
+			"muted":  muted,
+			"volume": out.Volume,
+		}
+
+	case "audio.set_output":
+		var params struct {
+			Device string `param:"device,required"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		out, err := e.output(ctx, params.Device)
+		if err != nil {
+			return fail(err)
+		}
+		previous := ""
+		if current, err := e.output(ctx, ""); err == nil {
+			previous = current.Name
+		}
+		if !out.Default {
+			if err := e.backend.setDefault(ctx, out.ID); err != nil {
+				return fail(err)
+			}
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"device":   out.ID,
+			"name":     out.Name,
+			"previous": previous,
+			"changed":  !out.Default,
+		}
+
+	default:
+		return fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+	return result, nil
+}
+
+// output finds an output by ID or name (case-insensitively, or a unique
+// part of the name), or the default output when device is empty
+func (e *Executor) output(ctx context.Context, device string) (Output, error) {
+	outputs, err := e.backend.outputs(ctx)
+	if err != nil {
+		return Output{}, err
+	}
+	if len(outputs) == 0 {
+		return Output{}, agenterrors.New(agenterrors.Unavailable, "no sound outputs found")
+	}
+	if device == "" {
+		for _, o := range outputs {
+			if o.Default {
+				return o, nil
+			}
+		}
+		return outputs[0], nil
+	}
+	for _, o := range outputs {
+		if o.ID == device || strings.EqualFold(o.Name, device) {
+			return o, nil
+		}
+	}
+	var matches []Output
+	for _, o := range outputs {
+		if strings.Contains(strings.ToLower(o.Name), strings.ToLower(device)) {
+			matches = append(matches, o)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	names := make([]string, len(outputs))
+	for n, o := range outputs {
+		names[n] = o.Name
+	}
+	if len(matches) > 1 {
+		return Output{}, agenterrors.Newf(agenterrors.InvalidParams, "%q matches more than one output: %s", device, strings.Join(names, ", "))
+	}
+	return Output{}, agenterrors.Newf(agenterrors.NotFound, "no output named %q (outputs: %s)", device, strings.Join(names, ", "))
+}
+
+func (e *Executor) IsAvailable() bool {
+	return true
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"audio.volume": schema.MustParse(fmt.Sprintf(`{
+			"type": "object",
+			"properties": {
+				"device": {"type": "string"},
+				"level": {"type": "integer", "minimum": 0, "maximum": %d},
+				"change": {"type": "integer", "minimum": -100, "maximum": 100},
+				"direction": {"type": "string", "enum": ["up", "down"]}
+			}
+		}`, e.cfg.MaxVolume)),
+		"audio.mute": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"device": {"type": "string"},
+				"muted": {"type": "boolean"},
+				"toggle": {"type": "boolean"}
+			}
+		}`),
+		"audio.set_output": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"device": {"type": "string", "minLength": 1}
+			},
+			"required": ["device"]
+		}`),
+		"audio.outputs": schema.MustParse(`{"type": "object", "additionalProperties": false}`),
+	}
+}
+
+// run runs a sound tool in the C locale, so its output can be parsed,
+// returning its output
+func run(ctx context.Context, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", agenterrors.Newf(agenterrors.Timeout, "%s did not respond", name)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", agenterrors.Wrap(agenterrors.Unavailable, fmt.Errorf("%s: %w", name, err))
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,96 @@
+package audio
+
+import (
+	"context"
+	"testing"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// fakeBackend is one output whose volume the tests read back
+type fakeBackend struct {
+	out Output
+}
+
+func (b *fakeBackend) name() string { return "fake" }
+
+func (b *fakeBackend) outputs(ctx context.Context) ([]Output, error) {
+	return []Output{b.out}, nil
+}
+
+func (b *fakeBackend) setVolume(ctx context.Context, id string, percent int) error {
+	b.out.Volume = percent
+	return nil
+}
+
+func (b *fakeBackend) setMute(ctx context.Context, id string, muted bool) error {
+	b.out.Muted = muted
+	return nil
+}
+
+func (b *fakeBackend) setDefault(ctx context.Context, id string) error {
+	return nil
+}
+
+func TestChildSafetyClampsVolume(t *testing.T) {
+	tests := []struct {
+		name   string
+		start  int
+		params map[string]interface{}
+		steps  int
+	}{
+		{name: "level", start: 20, params: map[string]interface{}{"level": 100}, steps: 1},
+		{name: "change", start: 30, params: map[string]interface{}{"change": 50}, steps: 1},
+		{name: "repeated changes", start: 20, params: map[string]interface{}{"change": 15}, steps: 5},
+		{name: "repeated steps up", start: 20, params: map[string]interface{}{"direction": "up"}, steps: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &fakeBackend{out: Output{ID: "speakers", Name: "Speakers", Default: true, Volume: tt.start}}
+			gw := gateway.NewGateway(nil)
+			gw.RegisterExecutor(&Executor{cfg: Config{MaxVolume: 100, Step: 10}, backend: b})
+			gw.SetSafetyPolicy(gateway.ChildSafetyPolicy())
+			gw.SetSafetyMode(true)
+
+			var result *gateway.ExecutionResult
+			for range tt.steps {
+				data, err := intent.New("audio.volume").Params(tt.params).Confidence(0.9).Reasoning("test").JSON()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result, err = gw.ProcessIntent(context.Background(), data); err != nil {
+					t.Fatal(err)
+				}
+				if !result.Success {
+					t.Fatalf("failed: %s", result.Error)
+				}
+			}
+			if b.out.Volume != 40 {
+				t.Errorf("volume is %d, want it clamped to 40", b.out.Volume)
+			}
+			if result.Adjusted["level"] == nil {
+				t.Errorf("result doesn't report the clamped level: %v", result.Adjusted)
+			}
+		})
+	}
+}
+
+func TestVolumeUnclampedOutsideSafetyMode(t *testing.T) {
+	b := &fakeBackend{out: Output{ID: "speakers", Name: "Speakers", Default: true, Volume: 30}}
+	gw := gateway.NewGateway(nil)
+	gw.RegisterExecutor(&Executor{cfg: Config{MaxVolume: 100, Step: 10}, backend: b})
+	gw.SetSafetyPolicy(gateway.ChildSafetyPolicy())
+
+	data, err := intent.New("audio.volume").Param("change", 50).Confidence(0.9).Reasoning("test").JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := gw.ProcessIntent(context.Background(), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Success || b.out.Volume != 80 || result.Adjusted != nil {
+		t.Errorf("got success %v, volume %d, adjusted %v; want 80 unadjusted", result.Success, b.out.Volume, result.Adjusted)
+	}
+}
@@ -0,0 +1,102 @@
+package audio
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// coreAudio sets the volume of the current output through AppleScript's
+// volume settings, which CoreAudio applies. Listing and switching outputs
+// needs SwitchAudioSource (brew install switchaudio-osx).
+type coreAudio struct {
+	switcher bool
+}
+
+func newBackend() (backend, error) {
+	_, err := exec.LookPath("SwitchAudioSource")
+	return coreAudio{switcher: err == nil}, nil
+}
+
+func (coreAudio) name() string {
+	return "coreaudio"
+}
+
+func (c coreAudio) outputs(ctx context.Context) ([]Output, error) {
+	settings, err := run(ctx, "osascript", "-e", "get volume settings")
+	if err != nil {
+		return nil, err
+	}
+	// output volume:50, input volume:75, alert volume:100, output muted:false
+	current := Output{ID: "default", Name: "Current output", Default: true}
+	for _, field := range strings.Split(strings.TrimSpace(settings), ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(field), ":")
+		switch key {
+		case "output volume":
+			current.Volume, _ = strconv.Atoi(value)
+		case "output muted":
+			current.Muted = value == "true"
+		}
+	}
+	if !c.switcher {
+		return []Output{current}, nil
+	}
+
+	name, err := run(ctx, "SwitchAudioSource", "-c", "-t", "output")
+	if err != nil {
+		return nil, err
+	}
+	current.Name = strings.TrimSpace(name)
+	current.ID = current.Name
+	list, err := run(ctx, "SwitchAudioSource", "-a", "-t", "output")
+	if err != nil {
+		return nil, err
+	}
+	outputs := []Output{current}
+	for _, line := range strings.Split(strings.TrimSpace(list), "\n") {
+		if line = strings.TrimSpace(line); line != "" && line != current.Name {
+			outputs = append(outputs, Output{ID: line, Name: line})
+		}
+	}
+	return outputs, nil
+}
+
+// current fails for devices other than the current output, which
+// AppleScript can't reach
+func (c coreAudio) current(ctx context.Context, id string) error {
+	outputs, err := c.outputs(ctx)
+	if err != nil {
+		return err
+	}
+	if outputs[0].ID != id {
+		return agenterrors.New(agenterrors.Unsupported, "on macOS only the current output's volume can be changed; switch to it first")
+	}
+	return nil
+}
+
+func (c coreAudio) setVolume(ctx context.Context, id string, percent int) error {
+	if err := c.current(ctx, id); err != nil {
+		return err
+	}
+	_, err := run(ctx, "osascript", "-e", "set volume output volume "+strconv.Itoa(percent))
+	return err
+}
+
+func (c coreAudio) setMute(ctx context.Context, id string, muted bool) error {
+	if err := c.current(ctx, id); err != nil {
+		return err
+	}
+	_, err := run(ctx, "osascript", "-e", "set volume output muted "+strconv.FormatBool(muted))
+	return err
+}
+
+func (c coreAudio) setDefault(ctx context.Context, id string) error {
+	if !c.switcher {
+		return agenterrors.New(agenterrors.Unavailable, "switching outputs needs SwitchAudioSource (brew install switchaudio-osx)")
+	}
+	_, err := run(ctx, "SwitchAudioSource", "-t", "output", "-s", id)
+	return err
+}
@@ -0,0 +1,134 @@
+//go:build !windows && !darwin
+
+package audio
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// pulse drives PulseAudio, or PipeWire through pipewire-pulse, with pactl
+type pulse struct{}
+
+func newBackend() (backend, error) {
+	if _, err := exec.LookPath("pactl"); err != nil {
+		return nil, errors.New("pactl not found: install pulseaudio-utils (it works with PipeWire too)")
+	}
+	return pulse{}, nil
+}
+
+func (pulse) name() string {
+	return "pulseaudio"
+}
+
+// outputs parses `pactl list sinks`, whose blocks look like:
+//
+//	Sink #0
+//		Name: alsa_output.pci-0000_00_1f.3.analog-stereo
+//		Description: Built-in Audio Analog Stereo
+//		Mute: no
+//		Volume: front-left: 32768 /  50% / -18.06 dB,   front-right: ...
+func (pulse) outputs(ctx context.Context) ([]Output, error) {
+	info, err := run(ctx, "pactl", "info")
+	if err != nil {
+		return nil, err
+	}
+	defaultSink := ""
+	for _, line := range strings.Split(info, "\n") {
+		if v, ok := strings.CutPrefix(line, "Default Sink: "); ok {
+			defaultSink = strings.TrimSpace(v)
+		}
+	}
+
+	list, err := run(ctx, "pactl", "list", "sinks")
+	if err != nil {
+		return nil, err
+	}
+	var outputs []Output
+	var current *Output
+	for _, line := range strings.Split(list, "\n") {
+		if strings.HasPrefix(line, "Sink #") {
+			outputs = append(outputs, Output{})
+			current = &outputs[len(outputs)-1]
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ": ")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Name":
+			current.ID = value
+			current.Default = value == defaultSink
+		case "Description":
+			current.Name = value
+		case "Mute":
+			current.Muted = value == "yes"
+		case "Volume":
+			current.Volume = averagePercent(value)
+		}
+	}
+	for n := range outputs {
+		if outputs[n].Name == "" {
+			outputs[n].Name = outputs[n].ID
+		}
+	}
+	return outputs, nil
+}
+
+// averagePercent averages the channel percentages of a Volume line
+func averagePercent(volume string) int {
+	total, channels := 0, 0
+	for _, field := range strings.Split(volume, "/") {
+		field = strings.TrimSpace(field)
+		if p, ok := strings.CutSuffix(field, "%"); ok {
+			if n, err := strconv.Atoi(p); err == nil {
+				total += n
+				channels++
+			}
+		}
+	}
+	if channels == 0 {
+		return 0
+	}
+	return (total + channels/2) / channels
+}
+
+func (pulse) setVolume(ctx context.Context, id string, percent int) error {
+	_, err := run(ctx, "pactl", "set-sink-volume", id, strconv.Itoa(percent)+"%")
+	return err
+}
+
+func (pulse) setMute(ctx context.Context, id string, muted bool) error {
+	value := "0"
+	if muted {
+		value = "1"
+	}
+	_, err := run(ctx, "pactl", "set-sink-mute", id, value)
+	return err
+}
+
+// setDefault makes the sink the default and moves what is already playing
+// onto it, as switching outputs in the desktop's sound settings does
+func (pulse) setDefault(ctx context.Context, id string) error {
+	if _, err := run(ctx, "pactl", "set-default-sink", id); err != nil {
+		return err
+	}
+	inputs, err := run(ctx, "pactl", "list", "short", "sink-inputs")
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(strings.TrimSpace(inputs), "\n") {
+		if index, _, ok := strings.Cut(line, "\t"); ok {
+			// Streams can end in the meantime
+			run(ctx, "pactl", "move-sink-input", index, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// COM identifiers from mmdeviceapi.h, endpointvolume.h, and functiondiscoverykeys.h.
+// IPolicyConfig is undocumented but is what the Sound control panel uses
+// to change the default device.
+var (
+	clsidMMDeviceEnumerator = windows.GUID{Data1: 0xbcde0395, Data2: 0xe52f, Data3: 0x467c, Data4: [8]byte{0x8e, 0x3d, 0xc4, 0x57, 0x92, 0x91, 0x69, 0x2e}}
+	iidIMMDeviceEnumerator  = windows.GUID{Data1: 0xa95664d2, Data2: 0x9614, Data3: 0x4f35, Data4: [8]byte{0xa7, 0x46, 0xde, 0x8d, 0xb6, 0x36, 0x17, 0xe6}}
+	iidIAudioEndpointVolume = windows.GUID{Data1: 0x5cdf2c82, Data2: 0x841e, Data3: 0x4546, Data4: [8]byte{0x97, 0x22, 0x0c, 0xf7, 0x40, 0x78, 0x22, 0x9a}}
+	clsidPolicyConfigClient = windows.GUID{Data1: 0x870af99c, Data2: 0x171d, Data3: 0x4f9e, Data4: [8]byte{0xaf, 0x0d, 0xe6, 0x3d, 0xf4, 0x0c, 0x2b, 0xc9}}
+	iidIPolicyConfig        = windows.GUID{Data1: 0xf8679f50, Data2: 0x850a, Data3: 0x41cf, Data4: [8]byte{0x9c, 0x72, 0x43, 0x0f, 0x29, 0x02, 0x90, 0xc8}}
+	pkeyDeviceFriendlyName  = propertyKey{fmtid: windows.GUID{Data1: 0xa45c254e, Data2: 0xdf1c, Data3: 0x4efd, Data4: [8]byte{0x80, 0x20, 0x67, 0xd1, 0x46, 0xa8, 0x50, 0xe0}}, pid: 14}
+	ole32                   = windows.NewLazySystemDLL("ole32.dll")
+	procCoCreateInstance    = ole32.NewProc("CoCreateInstance")
+	procPropVariantClear    = ole32.NewProc("PropVariantClear")
+)
+
+const (
+	eRender             = 0
+	eConsole            = 0
+	eMultimedia         = 1
+	eCommunications     = 2
+	deviceStateActive   = 1
+	clsctxAll           = 0x17
+	stgmRead            = 0
+	vtLPWSTR            = 31
+	coinitMultithreaded = 0x0
+)
+
+// Method slots in each interface's vtable, after IUnknown's three
+const (
+	release = 2
+
+	enumEnumAudioEndpoints = 3
+	enumGetDefaultEndpoint = 4
+	enumGetDevice          = 5
+
+	collectionGetCount = 3
+	collectionItem     = 4
+
+	deviceActivate          = 3
+	deviceOpenPropertyStore = 4
+	deviceGetID             = 5
+
+	storeGetValue = 5
+
+	volumeSetMasterScalar = 7
+	volumeGetMasterScalar = 9
+	volumeSetMute         = 14
+	volumeGetMute         = 15
+
+	policySetDefaultEndpoint = 13
+)
+
+type propertyKey struct {
+	fmtid windows.GUID
+	pid   uint32
+}
+
+type propVariant struct {
+	vt       uint16
+	reserved [3]uint16
+	val      uintptr
+	pad      uintptr
+}
+
+// comObject is a COM interface pointer; its address is where COM
+// functions store one
+type comObject struct {
+	p *struct{ vtable *[32]uintptr }
+}
+
+// call invokes a method by its vtable slot, turning a failed HRESULT into
+// an error
+func (o comObject) call(slot int, args ...uintptr) error {
+	hr, _, _ := syscall.SyscallN(o.p.vtable[slot], append([]uintptr{uintptr(unsafe.Pointer(o.p))}, args...)...)
+	if int32(hr) < 0 {
+		return fmt.Errorf("HRESULT 0x%08x", uint32(hr))
+	}
+	return nil
+}
+
+func (o comObject) release() {
+	if o.p != nil {
+		o.call(release)
+	}
+}
+
+// wasapi uses the Core Audio APIs through COM
+type wasapi struct{}
+
+func newBackend() (backend, error) {
+	if err := ole32.Load(); err != nil {
+		return nil, err
+	}
+	return wasapi{}, nil
+}
+
+func (wasapi) name() string {
+	return "wasapi"
+}
+
+// withCOM runs fn on a thread with COM initialized, handing it the device
+// enumerator
+func withCOM(fn func(enum comObject) error) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if err := windows.CoInitializeEx(0, coinitMultithreaded); err != nil {
+		// Already initialized on this thread, perhaps in another mode
+		if errno, ok := err.(syscall.Errno); !ok || errno != 1 {
+			return agenterrors.Wrap(agenterrors.Unavailable, fmt.Errorf("CoInitializeEx: %w", err))
+		}
+	}
+	defer windows.CoUninitialize()
+
+	var enum comObject
+	hr, _, _ := procCoCreateInstance.Call(
+		uintptr(unsafe.Pointer(&clsidMMDeviceEnumerator)), 0, clsctxAll,
+		uintptr(unsafe.Pointer(&iidIMMDeviceEnumerator)), uintptr(unsafe.Pointer(&enum)))
+	if int32(hr) < 0 {
+		return agenterrors.Newf(agenterrors.Unavailable, "no audio device enumerator (HRESULT 0x%08x)", uint32(hr))
+	}
+	defer enum.release()
+	return fn(enum)
+}
+
+func deviceID(device comObject) (string, error) {
+	var id *uint16
+	if err := device.call(deviceGetID, uintptr(unsafe.Pointer(&id))); err != nil {
+		return "", err
+	}
+	defer windows.CoTaskMemFree(unsafe.Pointer(id))
+	return windows.UTF16PtrToString(id), nil
+}
+
+func friendlyName(device comObject) string {
+	var store comObject
+	if err := device.call(deviceOpenPropertyStore, stgmRead, uintptr(unsafe.Pointer(&store))); err != nil {
+		return ""
+	}
+	defer store.release()
+	var value propVariant
+	if err := store.call(storeGetValue, uintptr(unsafe.Pointer(&pkeyDeviceFriendlyName)), uintptr(unsafe.Pointer(&value))); err != nil {
+		return ""
+	}
+	defer procPropVariantClear.Call(uintptr(unsafe.Pointer(&value)))
+	if value.vt != vtLPWSTR || value.val == 0 {
+		return ""
+	}
+	return windows.UTF16PtrToString(*(**uint16)(unsafe.Pointer(&value.val)))
+}
+
+// endpointVolume activates a device's volume control
+func endpointVolume(device comObject) (comObject, error) {
+	var volume comObject
+	err := device.call(deviceActivate, uintptr(unsafe.Pointer(&iidIAudioEndpointVolume)), clsctxAll, 0, uintptr(unsafe.Pointer(&volume)))
+	return volume, err
+}
+
+func (wasapi) outputs(ctx context.Context) ([]Output, error) {
+	var outputs []Output
+	err := withCOM(func(enum comObject) error {
+		defaultID := ""
+		var def comObject
+		if enum.call(enumGetDefaultEndpoint, eRender, eConsole, uintptr(unsafe.Pointer(&def))) == nil {
+			defaultID, _ = deviceID(def)
+			def.release()
+		}
+
+		var collection comObject
+		if err := enum.call(enumEnumAudioEndpoints, eRender, deviceStateActive, uintptr(unsafe.Pointer(&collection))); err != nil {
+			return agenterrors.Wrap(agenterrors.Unavailable, fmt.Errorf("listing outputs: %w", err))
+		}
+		defer collection.release()
+		var count uint32
+		if err := collection.call(collectionGetCount, uintptr(unsafe.Pointer(&count))); err != nil {
+			return agenterrors.Wrap(agenterrors.Unavailable, err)
+		}
+		for n := uint32(0); n < count; n++ {
+			var device comObject
+			if collection.call(collectionItem, uintptr(n), uintptr(unsafe.Pointer(&device))) != nil {
+				continue
+			}
+			out, err := describe(device)
+			device.release()
+			if err != nil {
+				continue
+			}
+			out.Default = out.ID == defaultID
+			outputs = append(outputs, out)
+		}
+		return nil
+	})
+	return outputs, err
+}
+
+func describe(device comObject) (Output, error) {
+	id, err := deviceID(device)
+	if err != nil {
+		return Output{}, err
+	}
+	out := Output{ID: id, Name: friendlyName(device)}
+	if out.Name == "" {
+		out.Name = id
+	}
+	volume, err := endpointVolume(device)
+	if err != nil {
+		return out, nil
+	}
+	defer volume.release()
+	var level float32
+	if volume.call(volumeGetMasterScalar, uintptr(unsafe.Pointer(&level))) == nil {
+		out.Volume = int(math.Round(float64(level) * 100))
+	}
+	var muted int32
+	if volume.call(volumeGetMute, uintptr(unsafe.Pointer(&muted))) == nil {
+		out.Muted = muted != 0
+	}
+	return out, nil
+}
+
+// withVolume runs fn with the volume control of the device with an ID
+func withVolume(id string, fn func(volume comObject) error) error {
+	return withCOM(func(enum comObject) error {
+		wid, err := windows.UTF16PtrFromString(id)
+		if err != nil {
+			return agenterrors.Wrap(agenterrors.InvalidParams, err)
+		}
+		var device comObject
+		if err := enum.call(enumGetDevice, uintptr(unsafe.Pointer(wid)), uintptr(unsafe.Pointer(&device))); err != nil {
+			return agenterrors.Newf(agenterrors.NotFound, "no output %s", id)
+		}
+		defer device.release()
+		volume, err := endpointVolume(device)
+		if err != nil {
+			return agenterrors.Wrap(agenterrors.Unavailable, fmt.Errorf("volume control: %w", err))
+		}
+		defer volume.release()
+		return fn(volume)
+	})
+}
+
+// The float is passed in an integer slot; on amd64 Go's syscall trampoline
+// copies the first four arguments into the XMM registers as well
+func (wasapi) setVolume(ctx context.Context, id string, percent int) error {
+	return withVolume(id, func(volume comObject) error {
+		level := math.Float32bits(float32(percent) / 100)
+		if err := volume.call(volumeSetMasterScalar, uintptr(level), 0); err != nil {
+			return agenterrors.Wrap(agenterrors.Unavailable, fmt.Errorf("setting volume: %w", err))
+		}
+		return nil
+	})
+}
+
+func (wasapi) setMute(ctx context.Context, id string, muted bool) error {
+	return withVolume(id, func(volume comObject) error {
+		value := uintptr(0)
+		if muted {
+			value = 1
+		}
+		if err := volume.call(volumeSetMute, value, 0); err != nil {
+			return agenterrors.Wrap(agenterrors.Unavailable, fmt.Errorf("setting mute: %w", err))
+		}
+		return nil
+	})
+}
+
+func (wasapi) setDefault(ctx context.Context, id string) error {
+	return withCOM(func(comObject) error {
+		var policy comObject
+		hr, _, _ := procCoCreateInstance.Call(
+			uintptr(unsafe.Pointer(&clsidPolicyConfigClient)), 0, clsctxAll,
+			uintptr(unsafe.Pointer(&iidIPolicyConfig)), uintptr(unsafe.Pointer(&policy)))
+		if int32(hr) < 0 {
+			return agenterrors.Newf(agenterrors.Unavailable, "no policy config (HRESULT 0x%08x)", uint32(hr))
+		}
+		defer policy.release()
+		wid, err := windows.UTF16PtrFromString(id)
+		if err != nil {
+			return agenterrors.Wrap(agenterrors.InvalidParams, err)
+		}
+		// Switch every role, as the Sound control panel does
+		for _, role := range []uintptr{eConsole, eMultimedia, eCommunications} {
+			if err := policy.call(policySetDefaultEndpoint, uintptr(unsafe.Pointer(wid)), role); err != nil {
+				return agenterrors.Wrap(agenterrors.Unavailable, fmt.Errorf("setting the default output: %w", err))
+			}
+		}
+		return nil
+	})
+}
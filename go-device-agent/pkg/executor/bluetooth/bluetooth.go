@@ -0,0 +1,320 @@
+// Package bluetooth finds, pairs, and connects Bluetooth devices such as
+// headphones and speakers through BlueZ on Linux
+package bluetooth
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/usage"
+)
+
+// Device is a Bluetooth device the adapter knows about
+type Device struct {
+	Address   string `json:"address"`
+	Name      string `json:"name"`
+	Type      string `json:"type,omitempty"` // e.g. audio-headset, input-keyboard
+	Paired    bool   `json:"paired"`
+	Trusted   bool   `json:"trusted"`
+	Connected bool   `json:"connected"`
+	RSSI      *int   `json:"rssi,omitempty"` // signal strength in dBm, when in range
+
+	path dbus.ObjectPath
+}
+
+// Config selects the adapter and scan length
+type Config struct {
+	// Adapter is the adapter to use, e.g. hci1 (default the first)
+	Adapter string
+
+	// ScanTime is how long bluetooth.scan listens by default, and how long
+	// bluetooth.connect looks for a device it hasn't seen (default 10s)
+	ScanTime time.Duration
+}
+
+// Executor handles bluetooth.scan, bluetooth.connect, and
+// bluetooth.disconnect
+type Executor struct {
+	cfg   Config
+	bluez *bluez
+}
+
+var addressPattern = regexp.MustCompile(`^[0-9A-Fa-f]{2}(:[0-9A-Fa-f]{2}){5}$`)
+
+// NewExecutor connects to BlueZ, failing when it or an adapter is missing
+func NewExecutor(cfg Config) (*Executor, error) {
+	if cfg.ScanTime <= 0 {
+		cfg.ScanTime = 10 * time.Second
+	}
+	b, err := newBlueZ(cfg.Adapter)
+	if err != nil {
+		return nil, err
+	}
+	return &Executor{cfg: cfg, bluez: b}, nil
+}
+
+// Adapter is the address of the adapter in use
+func (e *Executor) Adapter() string {
+	return e.bluez.address
+}
+
+func (e *Executor) Name() string {
+	return "bluetooth"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"bluetooth.scan", "bluetooth.connect", "bluetooth.disconnect"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{} // BlueZ over the system bus
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "bluetooth",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	switch i.IntentType {
+	case "bluetooth.scan":
+		var params struct {
+			Seconds int `param:"duration_seconds"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		scan := e.cfg.ScanTime
+		if params.Seconds > 0 {
+			scan = time.Duration(params.Seconds) * time.Second
+		}
+		devices, err := e.bluez.discover(ctx, scan, nil)
+		if err != nil {
+			return fail(err)
+		}
+		sortDevices(devices)
+		nearby := 0
+		for _, d := range devices {
+			if d.RSSI != nil {
+				nearby++
+			}
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"devices": devices,
+			"nearby":  nearby,
+		}
+
+	case "bluetooth.connect":
+		var params struct {
+			Device string `param:"device,required"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		d, err := e.device(ctx, params.Device, true)
+		if err != nil {
+			return fail(err)
+		}
+		paired := false
+		if !d.Paired {
+			// A new device gets to talk to the host from now on
+			if !i.RequiresPermission {
+				return fail(agenterrors.Newf(agenterrors.ConfirmationRequired,
+					"%s isn't paired yet; resend with requires_permission to pair it", d.Name))
+			}
+			if err := e.bluez.pair(ctx, d); err != nil {
+				return fail(err)
+			}
+			usage.DeviceCommand(ctx)
+			paired = true
+		}
+		if err := e.bluez.connect(ctx, d); err != nil {
+			return fail(err)
+		}
+		usage.DeviceCommand(ctx)
+		if d, err = e.refresh(ctx, d); err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"device":  d,
+			"paired":  paired,
+			"adapter": e.bluez.address,
+		}
+
+	case "bluetooth.disconnect":
+		var params struct {
+			Device string `param:"device,required"`
+			Forget bool   `param:"forget"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		d, err := e.device(ctx, params.Device, false)
+		if err != nil {
+			return fail(err)
+		}
+		wasConnected := d.Connected
+		if err := e.bluez.disconnect(ctx, d); err != nil {
+			return fail(err)
+		}
+		usage.DeviceCommand(ctx)
+		if params.Forget {
+			if err := e.bluez.forget(ctx, d); err != nil {
+				return fail(err)
+			}
+			usage.DeviceCommand(ctx)
+			d.Paired, d.Trusted, d.Connected = false, false, false
+		} else if d, err = e.refresh(ctx, d); err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"device":        d,
+			"was_connected": wasConnected,
+			"forgotten":     params.Forget,
+		}
+
+	default:
+		return fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+	return result, nil
+}
+
+// sortDevices puts connected, then paired, then the strongest signals first
+func sortDevices(devices []Device) {
+	rssi := func(d Device) int {
+		if d.RSSI == nil {
+			return -1000
+		}
+		return *d.RSSI
+	}
+	sort.SliceStable(devices, func(a, b int) bool {
+		da, db := devices[a], devices[b]
+		if da.Connected != db.Connected {
+			return da.Connected
+		}
+		if da.Paired != db.Paired {
+			return da.Paired
+		}
+		if rssi(da) != rssi(db) {
+			return rssi(da) > rssi(db)
+		}
+		return da.Name < db.Name
+	})
+}
+
+// device finds a device by address, name, or a unique part of its name.
+// With scan, a device that isn't known yet is looked for, as headphones
+// just put into pairing mode won't be.
+func (e *Executor) device(ctx context.Context, name string, scan bool) (Device, error) {
+	devices, err := e.bluez.devices(ctx)
+	if err != nil {
+		return Device{}, err
+	}
+	d, err := match(devices, name)
+	if err == nil || !scan || agenterrors.CodeOf(err) != agenterrors.NotFound {
+		return d, err
+	}
+	devices, err = e.bluez.discover(ctx, e.cfg.ScanTime, func(devices []Device) bool {
+		_, err := match(devices, name)
+		return err == nil
+	})
+	if err != nil {
+		return Device{}, err
+	}
+	return match(devices, name)
+}
+
+func match(devices []Device, name string) (Device, error) {
+	if addressPattern.MatchString(name) {
+		for _, d := range devices {
+			if strings.EqualFold(d.Address, name) {
+				return d, nil
+			}
+		}
+		return Device{}, agenterrors.Newf(agenterrors.NotFound, "no device %s in range; is it in pairing mode?", name)
+	}
+	for _, d := range devices {
+		if strings.EqualFold(d.Name, name) {
+			return d, nil
+		}
+	}
+	var matches []string
+	var found Device
+	for _, d := range devices {
+		if strings.Contains(strings.ToLower(d.Name), strings.ToLower(name)) {
+			matches = append(matches, d.Name+" ("+d.Address+")")
+			found = d
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Device{}, agenterrors.Newf(agenterrors.NotFound, "no device named %q in range; is it in pairing mode?", name)
+	case 1:
+		return found, nil
+	}
+	return Device{}, agenterrors.Newf(agenterrors.InvalidParams, "%q matches more than one device: %s", name, strings.Join(matches, ", "))
+}
+
+// refresh rereads a device's state after a change
+func (e *Executor) refresh(ctx context.Context, d Device) (Device, error) {
+	devices, err := e.bluez.devices(ctx)
+	if err != nil {
+		return d, err
+	}
+	for _, fresh := range devices {
+		if fresh.path == d.path {
+			return fresh, nil
+		}
+	}
+	return d, nil
+}
+
+func (e *Executor) IsAvailable() bool {
+	return e.bluez.conn.Connected()
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"bluetooth.scan": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"duration_seconds": {"type": "integer", "minimum": 1, "maximum": 60}
+			}
+		}`),
+		"bluetooth.connect": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"device": {"type": "string", "minLength": 1}
+			},
+			"required": ["device"]
+		}`),
+		"bluetooth.disconnect": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"device": {"type": "string", "minLength": 1},
+				"forget": {"type": "boolean"}
+			},
+			"required": ["device"]
+		}`),
+	}
+}
@@ -0,0 +1,235 @@
+package bluetooth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+const (
+	bluezService   = "org.bluez"
+	bluezAdapter   = "org.bluez.Adapter1"
+	bluezDevice    = "org.bluez.Device1"
+	objectManager  = "org.freedesktop.DBus.ObjectManager.GetManagedObjects"
+	discoveryPause = 500 * time.Millisecond
+)
+
+// bluez talks to the BlueZ daemon over the D-Bus system bus
+type bluez struct {
+	conn    *dbus.Conn
+	adapter dbus.ObjectPath
+	address string
+}
+
+type managedObjects map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+
+// newBlueZ connects to the system bus and picks the named adapter (e.g.
+// hci0), or the first one
+func newBlueZ(adapter string) (*bluez, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, fmt.Errorf("no D-Bus system bus: %w", err)
+	}
+	b := &bluez{conn: conn}
+	objects, err := b.objects(context.Background())
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("BlueZ isn't running: %w", err)
+	}
+	adapters := make(map[string]dbus.ObjectPath)
+	var names []string
+	for p, ifaces := range objects {
+		if _, ok := ifaces[bluezAdapter]; ok {
+			adapters[path.Base(string(p))] = p
+			names = append(names, path.Base(string(p)))
+		}
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		conn.Close()
+		return nil, errors.New("no Bluetooth adapter found")
+	}
+	if adapter == "" {
+		adapter = names[0]
+	}
+	if b.adapter = adapters[adapter]; b.adapter == "" {
+		conn.Close()
+		return nil, fmt.Errorf("no Bluetooth adapter %s (adapters: %s)", adapter, strings.Join(names, ", "))
+	}
+	b.address, _ = objects[b.adapter][bluezAdapter]["Address"].Value().(string)
+	return b, nil
+}
+
+func (b *bluez) objects(ctx context.Context) (managedObjects, error) {
+	var objects managedObjects
+	err := b.conn.Object(bluezService, "/").CallWithContext(ctx, objectManager, 0).Store(&objects)
+	return objects, err
+}
+
+// devices lists the devices the adapter knows: paired ones, and those
+// seen by a recent scan
+func (b *bluez) devices(ctx context.Context) ([]Device, error) {
+	objects, err := b.objects(ctx)
+	if err != nil {
+		return nil, bluezError(err)
+	}
+	var devices []Device
+	for p, ifaces := range objects {
+		props, ok := ifaces[bluezDevice]
+		if !ok {
+			continue
+		}
+		if adapter, _ := props["Adapter"].Value().(dbus.ObjectPath); adapter != b.adapter {
+			continue
+		}
+		d := Device{path: p}
+		d.Address, _ = props["Address"].Value().(string)
+		d.Name, _ = props["Alias"].Value().(string)
+		d.Type, _ = props["Icon"].Value().(string)
+		d.Paired, _ = props["Paired"].Value().(bool)
+		d.Trusted, _ = props["Trusted"].Value().(bool)
+		d.Connected, _ = props["Connected"].Value().(bool)
+		if rssi, ok := props["RSSI"].Value().(int16); ok {
+			r := int(rssi)
+			d.RSSI = &r
+		}
+		devices = append(devices, d)
+	}
+	return devices, nil
+}
+
+// powerOn turns the adapter on if it's off
+func (b *bluez) powerOn() error {
+	adapter := b.conn.Object(bluezService, b.adapter)
+	if v, err := adapter.GetProperty(bluezAdapter + ".Powered"); err == nil && v.Value() == true {
+		return nil
+	}
+	if err := adapter.SetProperty(bluezAdapter+".Powered", dbus.MakeVariant(true)); err != nil {
+		return agenterrors.Newf(agenterrors.Unavailable, "can't turn on the Bluetooth adapter (is it blocked by rfkill?): %v", err)
+	}
+	return nil
+}
+
+// discover scans for up to d, or until found reports what it's looking for
+func (b *bluez) discover(ctx context.Context, d time.Duration, found func([]Device) bool) ([]Device, error) {
+	if err := b.powerOn(); err != nil {
+		return nil, err
+	}
+	adapter := b.conn.Object(bluezService, b.adapter)
+	if err := adapter.CallWithContext(ctx, bluezAdapter+".StartDiscovery", 0).Err; err != nil {
+		// Another client scanning already is fine: its results are shared
+		if !isBluezError(err, "InProgress") {
+			return nil, bluezError(err)
+		}
+	} else {
+		defer adapter.Call(bluezAdapter+".StopDiscovery", 0)
+	}
+
+	deadline := time.NewTimer(d)
+	defer deadline.Stop()
+	tick := time.NewTicker(discoveryPause)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, agenterrors.Wrap(agenterrors.Cancelled, ctx.Err())
+		case <-deadline.C:
+			return b.devices(ctx)
+		case <-tick.C:
+			if found == nil {
+				continue
+			}
+			devices, err := b.devices(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if found(devices) {
+				return devices, nil
+			}
+		}
+	}
+}
+
+func (b *bluez) call(ctx context.Context, d Device, method string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return b.conn.Object(bluezService, d.path).CallWithContext(ctx, bluezDevice+"."+method, 0).Err
+}
+
+func (b *bluez) pair(ctx context.Context, d Device) error {
+	if err := b.call(ctx, d, "Pair", time.Minute); err != nil && !isBluezError(err, "AlreadyExists") {
+		return bluezError(err)
+	}
+	// Trusted devices may reconnect by themselves, as headphones do when
+	// switched on
+	if err := b.conn.Object(bluezService, d.path).SetProperty(bluezDevice+".Trusted", dbus.MakeVariant(true)); err != nil {
+		return bluezError(err)
+	}
+	return nil
+}
+
+func (b *bluez) connect(ctx context.Context, d Device) error {
+	if err := b.call(ctx, d, "Connect", 30*time.Second); err != nil && !isBluezError(err, "AlreadyConnected") {
+		return bluezError(err)
+	}
+	return nil
+}
+
+func (b *bluez) disconnect(ctx context.Context, d Device) error {
+	if err := b.call(ctx, d, "Disconnect", 10*time.Second); err != nil && !isBluezError(err, "NotConnected") {
+		return bluezError(err)
+	}
+	return nil
+}
+
+// forget unpairs a device and removes it from the adapter
+func (b *bluez) forget(ctx context.Context, d Device) error {
+	err := b.conn.Object(bluezService, b.adapter).CallWithContext(ctx, bluezAdapter+".RemoveDevice", 0, d.path).Err
+	return bluezError(err)
+}
+
+func isBluezError(err error, name string) bool {
+	var e dbus.Error
+	return errors.As(err, &e) && e.Name == "org.bluez.Error."+name
+}
+
+// bluezError gives a BlueZ error its code
+func bluezError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return agenterrors.Wrap(agenterrors.Timeout, err)
+	}
+	var e dbus.Error
+	if !errors.As(err, &e) {
+		return agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	msg := strings.TrimPrefix(e.Name, "org.bluez.Error.")
+	if len(e.Body) > 0 {
+		if detail, ok := e.Body[0].(string); ok && detail != "" {
+			msg += ": " + detail
+		}
+	}
+	switch strings.TrimPrefix(e.Name, "org.bluez.Error.") {
+	case "DoesNotExist":
+		return agenterrors.New(agenterrors.NotFound, msg)
+	case "InProgress", "Busy":
+		return agenterrors.New(agenterrors.Conflict, msg)
+	case "AuthenticationFailed", "AuthenticationRejected", "AuthenticationCanceled", "AuthenticationTimeout", "NotAuthorized":
+		return agenterrors.New(agenterrors.Unauthorized, msg)
+	case "InvalidArguments":
+		return agenterrors.New(agenterrors.InvalidParams, msg)
+	case "NotSupported":
+		return agenterrors.New(agenterrors.Unsupported, msg)
+	}
+	return agenterrors.New(agenterrors.Unavailable, msg)
+}
@@ -0,0 +1,360 @@
+// Package browser opens links in the desktop's default browser and, when
+// enabled, fetches pages as plain text for the agent core to read
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// refusedSchemes run code or open local files, so they can't be allowed
+var refusedSchemes = []string{"javascript", "vbscript", "data", "file"}
+
+// maxRedirects bounds the redirects browser.fetch follows
+const maxRedirects = 5
+
+// openGrace is how long browser.open waits for the opener to fail
+const openGrace = 3 * time.Second
+
+// Config sets what may be opened and fetched
+type Config struct {
+	// Schemes are the URL schemes browser.open accepts (default http and
+	// https), e.g. mailto or spotify as well
+	Schemes []string
+
+	// Fetch enables browser.fetch
+	Fetch bool
+
+	// MaxFetch caps the page read, in bytes (default 2 MiB)
+	MaxFetch int64
+
+	// MaxText caps the text browser.fetch returns, in characters (default
+	// 20,000); intents may ask for less
+	MaxText int
+
+	// Timeout bounds a fetch (default 15s)
+	Timeout time.Duration
+}
+
+// Executor handles browser.open and browser.fetch
+type Executor struct {
+	cfg     Config
+	opener  []string
+	openErr error
+	client  *http.Client
+}
+
+// NewExecutor finds the desktop's URL opener. It fails when there is none
+// and fetching isn't enabled either.
+func NewExecutor(cfg Config) (*Executor, error) {
+	if len(cfg.Schemes) == 0 {
+		cfg.Schemes = []string{"http", "https"}
+	}
+	for n, s := range cfg.Schemes {
+		s = strings.ToLower(strings.TrimSuffix(s, ":"))
+		for _, refused := range refusedSchemes {
+			if s == refused {
+				return nil, fmt.Errorf("%s: URLs can't be allowed", s)
+			}
+		}
+		cfg.Schemes[n] = s
+	}
+	if cfg.MaxFetch <= 0 {
+		cfg.MaxFetch = 2 << 20
+	}
+	if cfg.MaxText <= 0 {
+		cfg.MaxText = 20000
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 15 * time.Second
+	}
+
+	e := &Executor{cfg: cfg}
+	e.opener, e.openErr = opener()
+	if e.openErr != nil && !cfg.Fetch {
+		return nil, e.openErr
+	}
+	if cfg.Fetch {
+		e.client = connpool.Default.PublicHTTP(cfg.Timeout)
+		e.client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("more than %d redirects", maxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to a %s URL", req.URL.Scheme)
+			}
+			return nil
+		}
+	}
+	return e, nil
+}
+
+func (e *Executor) Name() string {
+	return "browser"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"browser.open", "browser.fetch"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{Network: e.cfg.Fetch, Subprocess: len(e.opener) > 0}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "browser",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	switch i.IntentType {
+	case "browser.open":
+		if e.openErr != nil {
+			return fail(agenterrors.Wrap(agenterrors.Unavailable, e.openErr))
+		}
+		link, err := i.StringParam("url")
+		if err != nil {
+			return fail(err)
+		}
+		target, err := e.checkOpen(link)
+		if err != nil {
+			return fail(err)
+		}
+		if err := e.open(target.String()); err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"url":    target.Redacted(),
+			"opener": e.opener[0],
+		}
+
+	case "browser.fetch":
+		if e.client == nil {
+			return fail(agenterrors.New(agenterrors.ExecutorDisabled, "browser.fetch is not enabled (-browser-fetch)"))
+		}
+		var params struct {
+			URL      string `param:"url,required"`
+			MaxChars int    `param:"max_chars"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		target, err := url.Parse(params.URL)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return fail(agenterrors.Newf(agenterrors.InvalidParams, "url must be an absolute http or https URL: %s", params.URL))
+		}
+		if target.User != nil {
+			return fail(agenterrors.New(agenterrors.InvalidParams, "credentials in the url are not allowed"))
+		}
+		maxChars := e.cfg.MaxText
+		if params.MaxChars > 0 && params.MaxChars < maxChars {
+			maxChars = params.MaxChars
+		}
+		page, err := e.fetch(ctx, target)
+		if err != nil {
+			return fail(err)
+		}
+		text, cut := truncate(page.text, maxChars)
+		out := map[string]interface{}{
+			"url":          page.url,
+			"content_type": page.contentType,
+			"text":         text,
+			"chars":        utf8.RuneCountInString(text),
+			"truncated":    cut || page.truncated,
+		}
+		if page.title != "" {
+			out["title"] = page.title
+		}
+		result.Success = true
+		result.Result = out
+
+	default:
+		return fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+	return result, nil
+}
+
+// checkOpen parses a URL browser.open may hand to the desktop
+func (e *Executor) checkOpen(link string) (*url.URL, error) {
+	if strings.IndexFunc(link, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0 {
+		return nil, agenterrors.New(agenterrors.InvalidParams, "url can't contain spaces or control characters")
+	}
+	target, err := url.Parse(link)
+	if err != nil || target.Scheme == "" {
+		return nil, agenterrors.Newf(agenterrors.InvalidParams, "url must be absolute: %s", link)
+	}
+	scheme := strings.ToLower(target.Scheme)
+	allowed := false
+	for _, s := range e.cfg.Schemes {
+		allowed = allowed || s == scheme
+	}
+	if !allowed {
+		return nil, agenterrors.Newf(agenterrors.DeniedByPolicy, "%s URLs are not allowed; allowed schemes are %s", scheme, strings.Join(e.cfg.Schemes, ", "))
+	}
+	if (scheme == "http" || scheme == "https") && target.Host == "" {
+		return nil, agenterrors.Newf(agenterrors.InvalidParams, "url has no host: %s", link)
+	}
+	return target, nil
+}
+
+// open hands a URL to the opener. Openers return once the browser has it,
+// but some wait for the browser to exit instead, so one still running
+// after openGrace is taken to have worked and is left to finish.
+func (e *Executor) open(link string) error {
+	cmd := exec.Command(e.opener[0], append(e.opener[1:], link)...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				err = fmt.Errorf("%w: %s", err, msg)
+			}
+			return agenterrors.Wrap(agenterrors.Unavailable, fmt.Errorf("%s failed: %w", e.opener[0], err))
+		}
+	case <-time.After(openGrace):
+	}
+	return nil
+}
+
+// page is a fetched page's text
+type page struct {
+	url         string
+	contentType string
+	title       string
+	text        string
+	truncated   bool
+}
+
+// fetch reads a page, keeping up to MaxFetch bytes of it, and extracts its
+// text
+func (e *Executor) fetch(ctx context.Context, target *url.URL) (*page, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, agenterrors.Wrap(agenterrors.InvalidParams, err)
+	}
+	req.Header.Set("Accept", "text/html, application/xhtml+xml, text/plain;q=0.9, */*;q=0.5")
+	req.Header.Set("User-Agent", "device-agent")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			return nil, agenterrors.Newf(agenterrors.Timeout, "%s took longer than %s", target.Redacted(), e.cfg.Timeout)
+		case errors.Is(err, connpool.ErrLocalAddress), errors.Is(err, connpool.ErrEgressBlocked):
+			return nil, agenterrors.Wrap(agenterrors.DeniedByPolicy, fmt.Errorf("fetch blocked: %w", err))
+		}
+		return nil, agenterrors.Wrap(agenterrors.Unavailable, fmt.Errorf("fetch failed: %w", err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		code := agenterrors.Unavailable
+		switch {
+		case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+			code = agenterrors.NotFound
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			code = agenterrors.Unauthorized
+		case resp.StatusCode == http.StatusTooManyRequests:
+			code = agenterrors.RateLimited
+		}
+		return nil, agenterrors.Newf(code, "GET %s: %s", target.Redacted(), resp.Status)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	isHTML := mediaType == "text/html" || mediaType == "application/xhtml+xml"
+	if !isHTML && !isText(mediaType) {
+		return nil, agenterrors.Newf(agenterrors.Unsupported, "%s is %s, not a page with text", target.Redacted(), mediaType)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, e.cfg.MaxFetch+1))
+	if err != nil {
+		return nil, agenterrors.Wrap(agenterrors.Unavailable, fmt.Errorf("reading page: %w", err))
+	}
+	p := &page{url: resp.Request.URL.Redacted(), contentType: mediaType}
+	if int64(len(data)) > e.cfg.MaxFetch {
+		data, p.truncated = data[:e.cfg.MaxFetch], true
+	}
+	body := strings.ToValidUTF8(string(data), "")
+	if isHTML {
+		p.title, p.text = extract(body)
+	} else {
+		p.text = strings.TrimSpace(body)
+	}
+	return p, nil
+}
+
+// isText reports whether a media type other than HTML is readable as is
+func isText(mediaType string) bool {
+	return strings.HasPrefix(mediaType, "text/") || mediaType == "application/json" ||
+		strings.HasSuffix(mediaType, "+json") || mediaType == "application/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+// truncate cuts text to at most max characters, at a word boundary where
+// there is one near the end
+func truncate(text string, max int) (string, bool) {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text, false
+	}
+	cut := max
+	for n := max; n > max*9/10; n-- {
+		if unicode.IsSpace(runes[n]) {
+			cut = n
+			break
+		}
+	}
+	return strings.TrimSpace(string(runes[:cut])) + "…", true
+}
+
+func (e *Executor) IsAvailable() bool {
+	return e.openErr == nil || e.client != nil
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"browser.open": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"url": {"type": "string", "minLength": 1, "maxLength": 8192}
+			},
+			"required": ["url"]
+		}`),
+		"browser.fetch": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"url": {"type": "string", "pattern": "^https?://", "maxLength": 8192},
+				"max_chars": {"type": "integer", "minimum": 1}
+			},
+			"required": ["url"]
+		}`),
+	}
+}
@@ -0,0 +1,6 @@
+package browser
+
+// opener uses open, which hands URLs to the default handler
+func opener() ([]string, error) {
+	return []string{"open"}, nil
+}
@@ -0,0 +1,20 @@
+//go:build !windows && !darwin
+
+package browser
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// opener uses xdg-open, which hands URLs to the desktop's default handler
+func opener() ([]string, error) {
+	if os.Getenv("WAYLAND_DISPLAY") == "" && os.Getenv("DISPLAY") == "" {
+		return nil, errors.New("no graphical session (neither WAYLAND_DISPLAY nor DISPLAY is set)")
+	}
+	if _, err := exec.LookPath("xdg-open"); err != nil {
+		return nil, errors.New("xdg-open not found: install xdg-utils")
+	}
+	return []string{"xdg-open"}, nil
+}
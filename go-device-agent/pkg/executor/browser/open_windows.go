@@ -0,0 +1,7 @@
+package browser
+
+// opener hands URLs to the shell's default handler, as start does. cmd's
+// start isn't used because cmd would read & and ^ in a URL as its own.
+func opener() ([]string, error) {
+	return []string{"rundll32", "url.dll,FileProtocolHandler"}, nil
+}
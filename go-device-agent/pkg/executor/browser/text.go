@@ -0,0 +1,98 @@
+package browser
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// skipped elements hold no text worth reading: code, styling, and the
+// navigation around an article
+var skipped = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Svg: true, atom.Iframe: true, atom.Canvas: true,
+	atom.Nav: true, atom.Footer: true, atom.Aside: true, atom.Form: true,
+	atom.Button: true, atom.Select: true,
+}
+
+// blocks start a new line
+var blocks = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Br: true, atom.Li: true, atom.Tr: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Section: true, atom.Article: true, atom.Header: true, atom.Main: true,
+	atom.Blockquote: true, atom.Pre: true, atom.Table: true, atom.Ul: true, atom.Ol: true,
+	atom.Dl: true, atom.Dt: true, atom.Dd: true, atom.Figcaption: true, atom.Hr: true,
+}
+
+// extract returns an HTML page's title and readable text: one line per
+// block, with list items marked with "- "
+func extract(page string) (title, text string) {
+	z := html.NewTokenizer(strings.NewReader(page))
+	var b strings.Builder
+	depth := 0 // inside skipped elements
+	inTitle := false
+	newline := func() {
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+	}
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return strings.Join(strings.Fields(title), " "), tidy(b.String())
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			a := atom.Lookup(name)
+			switch {
+			case a == atom.Title && depth == 0:
+				inTitle = true
+			case skipped[a]:
+				if tt == html.StartTagToken {
+					depth++
+				}
+			case depth == 0 && blocks[a]:
+				newline()
+				if a == atom.Li {
+					b.WriteString("- ")
+				}
+			case depth == 0 && (a == atom.Td || a == atom.Th):
+				b.WriteByte(' ')
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			a := atom.Lookup(name)
+			switch {
+			case a == atom.Title:
+				inTitle = false
+			case skipped[a]:
+				depth = max(depth-1, 0)
+			case depth == 0 && blocks[a]:
+				newline()
+			}
+
+		case html.TextToken:
+			switch {
+			case inTitle:
+				title += string(z.Text())
+			case depth == 0:
+				b.Write(z.Text())
+			}
+		}
+	}
+}
+
+// tidy collapses the whitespace in each line and drops empty ones
+func tidy(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line != "" && line != "-" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,161 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// calendarQuery asks for the objects of a collection with events
+// overlapping a time range; {{filter}} narrows it further
+const calendarQuery = `<?xml version="1.0" encoding="utf-8"?>
+<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop><D:getetag/><C:calendar-data/></D:prop>
+  <C:filter>
+    <C:comp-filter name="VCALENDAR">
+      <C:comp-filter name="VEVENT">{{filter}}</C:comp-filter>
+    </C:comp-filter>
+  </C:filter>
+</C:calendar-query>`
+
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"DAV: href"`
+		Propstat []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				ETag string `xml:"DAV: getetag"`
+				Data string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+// object is one calendar resource on the server
+type object struct {
+	href string
+	data string
+}
+
+// report runs a calendar-query REPORT against the calendar's collection
+func (c *Calendar) report(ctx context.Context, client *http.Client, filter string) ([]object, error) {
+	body := strings.Replace(calendarQuery, "{{filter}}", filter, 1)
+	req, err := c.request(ctx, "REPORT", c.URL, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	req.Header.Set("Depth", "1")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, fmt.Errorf("REPORT %s returned %s", c.URL, resp.Status)
+	}
+
+	var ms multistatus
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, maxResponse)).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("invalid REPORT response from %s: %w", c.URL, err)
+	}
+	base, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, err
+	}
+	var objects []object
+	for _, r := range ms.Responses {
+		for _, ps := range r.Propstat {
+			if ps.Prop.Data == "" || (ps.Status != "" && !strings.Contains(ps.Status, " 200 ")) {
+				continue
+			}
+			href, err := base.Parse(r.Href)
+			if err != nil {
+				continue
+			}
+			objects = append(objects, object{href: href.String(), data: ps.Prop.Data})
+		}
+	}
+	return objects, nil
+}
+
+// timeRange filters to events overlapping [from, to)
+func timeRange(from, to time.Time) string {
+	return fmt.Sprintf(`<C:time-range start="%s" end="%s"/>`,
+		from.UTC().Format("20060102T150405Z"), to.UTC().Format("20060102T150405Z"))
+}
+
+// uidMatch filters to the event with a UID
+func uidMatch(uid string) string {
+	var escaped bytes.Buffer
+	xml.EscapeText(&escaped, []byte(uid))
+	return `<C:prop-filter name="UID"><C:text-match collation="i;octet">` + escaped.String() + `</C:text-match></C:prop-filter>`
+}
+
+// put stores a new event, refusing to overwrite an existing one
+func (c *Calendar) put(ctx context.Context, client *http.Client, uid, ics string) error {
+	target := strings.TrimSuffix(c.URL, "/") + "/" + url.PathEscape(uid) + ".ics"
+	req, err := c.request(ctx, http.MethodPut, target, strings.NewReader(ics))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/calendar; charset=utf-8")
+	req.Header.Set("If-None-Match", "*")
+	return c.do(client, req)
+}
+
+// remove deletes an event's resource
+func (c *Calendar) remove(ctx context.Context, client *http.Client, href string) error {
+	req, err := c.request(ctx, http.MethodDelete, href, nil)
+	if err != nil {
+		return err
+	}
+	return c.do(client, req)
+}
+
+func (c *Calendar) do(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s returned %s", req.Method, req.URL, resp.Status)
+	}
+	return nil
+}
+
+// fetch downloads a read-only ICS feed
+func (c *Calendar) fetch(ctx context.Context, client *http.Client) ([]byte, error) {
+	req, err := c.request(ctx, http.MethodGet, c.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s returned %s", c.URL, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxResponse))
+}
+
+func (c *Calendar) request(ctx context.Context, method, target string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	return req, nil
+}
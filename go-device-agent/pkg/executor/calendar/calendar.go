@@ -0,0 +1,454 @@
+// Package calendar reads and writes events on CalDAV calendars and reads
+// ICS feeds, reporting them in one structure with times in the household's
+// time zone so the agent core can reason over them
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/internal/uuid"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Calendar is a CalDAV calendar collection or a read-only ICS feed
+type Calendar struct {
+	Name     string
+	URL      string
+	Username string
+	Password string
+
+	// Feed marks a plain ICS URL, fetched whole and never written
+	Feed bool
+}
+
+// Config lists the calendars
+type Config struct {
+	Calendars []Calendar
+
+	// Location is the time zone results are given in and that times
+	// without an offset are read in (default the system's)
+	Location *time.Location
+}
+
+// Limits on what a query reads
+const (
+	maxResponse        = 10 << 20
+	defaultSpan        = 7 * 24 * time.Hour
+	maxSpan            = 366 * 24 * time.Hour
+	defaultEventLength = time.Hour
+)
+
+// Executor handles calendar.query, calendar.create_event, and
+// calendar.delete_event
+type Executor struct {
+	calendars []Calendar
+	loc       *time.Location
+}
+
+// NewExecutor creates a calendar executor
+func NewExecutor(cfg Config) (*Executor, error) {
+	if len(cfg.Calendars) == 0 {
+		return nil, errors.New("no calendars configured")
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
+	seen := make(map[string]bool, len(cfg.Calendars))
+	for _, c := range cfg.Calendars {
+		if c.Name == "" || c.URL == "" {
+			return nil, errors.New("calendars need a name and a URL")
+		}
+		if seen[c.Name] {
+			return nil, errors.New("calendar " + c.Name + " is listed twice")
+		}
+		seen[c.Name] = true
+	}
+	return &Executor{calendars: cfg.Calendars, loc: cfg.Location}, nil
+}
+
+func (e *Executor) Name() string {
+	return "calendar"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"calendar.query", "calendar.create_event", "calendar.delete_event"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{Network: true}
+}
+
+// PermissionRequired guards deleting events
+func (e *Executor) PermissionRequired() []string {
+	return []string{"calendar.delete_event"}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "calendar",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	var params struct {
+		Calendar    string        `param:"calendar"`
+		From        string        `param:"from"`
+		To          string        `param:"to"`
+		Text        string        `param:"text"`
+		ID          string        `param:"id"`
+		Title       string        `param:"title"`
+		Start       string        `param:"start"`
+		End         string        `param:"end"`
+		Duration    time.Duration `param:"duration"`
+		AllDay      bool          `param:"all_day"`
+		Location    string        `param:"location"`
+		Description string        `param:"description"`
+		Timezone    string        `param:"timezone"`
+	}
+	if err := i.DecodeParams(&params); err != nil {
+		return fail(err)
+	}
+	loc := e.loc
+	if params.Timezone != "" {
+		l, err := time.LoadLocation(params.Timezone)
+		if err != nil {
+			return fail(agenterrors.Newf(agenterrors.InvalidParams, "unknown timezone '%s'", params.Timezone))
+		}
+		loc = l
+	}
+	calendars, err := e.pick(params.Calendar)
+	if err != nil {
+		return fail(err)
+	}
+
+	switch i.IntentType {
+	case "calendar.query":
+		from, to, err := span(params.From, params.To, loc)
+		if err != nil {
+			return fail(err)
+		}
+		events, problems := e.query(ctx, calendars, from, to, params.Text)
+		if len(problems) == len(calendars) {
+			return fail(agenterrors.Newf(agenterrors.Unavailable, "no calendar could be read: %s", strings.Join(problems, "; ")))
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"events":   events,
+			"count":    len(events),
+			"from":     from.In(e.loc).Format(time.RFC3339),
+			"to":       to.In(e.loc).Format(time.RFC3339),
+			"timezone": e.loc.String(),
+		}
+		if len(problems) > 0 {
+			result.Result["errors"] = problems
+		}
+		return result, nil
+
+	case "calendar.create_event":
+		if params.Title == "" || params.Start == "" {
+			return fail(agenterrors.New(agenterrors.InvalidParams, "an event needs a title and a start"))
+		}
+		c, err := writable(calendars)
+		if err != nil {
+			return fail(err)
+		}
+		event, err := e.create(ctx, c, params.Title, params.Location, params.Description,
+			params.Start, params.End, params.Duration, params.AllDay, loc)
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{"event": event}
+		return result, nil
+
+	case "calendar.delete_event":
+		if params.ID == "" {
+			return fail(agenterrors.New(agenterrors.InvalidParams, "missing required parameter: id"))
+		}
+		deleted, err := e.delete(ctx, calendars, params.ID)
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{"id": params.ID, "deleted": deleted}
+		return result, nil
+
+	default:
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+		return result, nil
+	}
+}
+
+// pick returns the named calendar, or all of them
+func (e *Executor) pick(name string) ([]Calendar, error) {
+	if name == "" {
+		return e.calendars, nil
+	}
+	for _, c := range e.calendars {
+		if strings.EqualFold(c.Name, name) {
+			return []Calendar{c}, nil
+		}
+	}
+	return nil, agenterrors.Newf(agenterrors.NotFound, "no calendar named '%s'", name)
+}
+
+// writable returns the first CalDAV calendar
+func writable(calendars []Calendar) (*Calendar, error) {
+	for n := range calendars {
+		if !calendars[n].Feed {
+			return &calendars[n], nil
+		}
+	}
+	if len(calendars) == 1 {
+		return nil, agenterrors.Newf(agenterrors.DeniedByPolicy, "%s is a read-only feed", calendars[0].Name)
+	}
+	return nil, agenterrors.New(agenterrors.DeniedByPolicy, "every calendar is a read-only feed")
+}
+
+// span reads the query range, by default from the start of today for a week
+func span(fromParam, toParam string, loc *time.Location) (time.Time, time.Time, error) {
+	now := time.Now().In(loc)
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if fromParam != "" {
+		t, _, err := parseTime(fromParam, loc)
+		if err != nil {
+			return from, from, agenterrors.Wrap(agenterrors.InvalidParams, err)
+		}
+		from = t
+	}
+	to := from.Add(defaultSpan)
+	if toParam != "" {
+		t, dateOnly, err := parseTime(toParam, loc)
+		if err != nil {
+			return from, from, agenterrors.Wrap(agenterrors.InvalidParams, err)
+		}
+		// A date as the end includes that whole day
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		to = t
+	}
+	if !to.After(from) {
+		return from, to, agenterrors.New(agenterrors.InvalidParams, "to must be after from")
+	}
+	if to.Sub(from) > maxSpan {
+		return from, to, agenterrors.New(agenterrors.InvalidParams, "queries can span at most a year")
+	}
+	return from, to, nil
+}
+
+// query collects the events of every calendar, noting the ones that
+// couldn't be read rather than failing outright
+func (e *Executor) query(ctx context.Context, calendars []Calendar, from, to time.Time, text string) ([]Event, []string) {
+	client := connpool.Default.HTTP(15 * time.Second)
+	events := []Event{}
+	var problems []string
+	for _, c := range calendars {
+		found, err := e.events(ctx, client, &c, from, to)
+		if err != nil {
+			problems = append(problems, c.Name+": "+err.Error())
+			continue
+		}
+		for _, ev := range found {
+			if text == "" || containsFold(ev.Title, text) || containsFold(ev.Location, text) || containsFold(ev.Description, text) {
+				events = append(events, ev)
+			}
+		}
+	}
+	sortEvents(events)
+	return events, problems
+}
+
+// events reads one calendar's occurrences in [from, to). CalDAV servers
+// filter by the range; the whole document is still parsed to expand
+// recurrences.
+func (e *Executor) events(ctx context.Context, client *http.Client, c *Calendar, from, to time.Time) ([]Event, error) {
+	if c.Feed {
+		data, err := c.fetch(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		return parseEvents(bytes.NewReader(data), c.Name, from, to, e.loc)
+	}
+	objects, err := c.report(ctx, client, timeRange(from, to))
+	if err != nil {
+		return nil, err
+	}
+	var events []Event
+	for _, o := range objects {
+		found, err := parseEvents(strings.NewReader(o.data), c.Name, from, to, e.loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid event at %s: %w", o.href, err)
+		}
+		events = append(events, found...)
+	}
+	return events, nil
+}
+
+// create stores a new event. Times are read in loc; an event without an
+// end lasts the duration, an hour by default, or the one day if all-day.
+func (e *Executor) create(ctx context.Context, c *Calendar, title, location, description, startParam, endParam string, duration time.Duration, allDay bool, loc *time.Location) (Event, error) {
+	start, dateOnly, err := parseTime(startParam, loc)
+	if err != nil {
+		return Event{}, agenterrors.Wrap(agenterrors.InvalidParams, err)
+	}
+	allDay = allDay || dateOnly
+	if allDay {
+		y, m, d := start.Date()
+		start = time.Date(y, m, d, 0, 0, 0, 0, loc)
+	}
+
+	var end time.Time
+	switch {
+	case endParam != "":
+		t, endDateOnly, err := parseTime(endParam, loc)
+		if err != nil {
+			return Event{}, agenterrors.Wrap(agenterrors.InvalidParams, err)
+		}
+		end = t
+		if allDay {
+			// The end date given is the last day of the event
+			y, m, d := end.Date()
+			end = time.Date(y, m, d, 0, 0, 0, 0, loc).AddDate(0, 0, 1)
+		} else if endDateOnly {
+			return Event{}, agenterrors.New(agenterrors.InvalidParams, "a timed event needs an end time, not a date")
+		}
+	case allDay:
+		days := int(duration / (24 * time.Hour))
+		if days < 1 {
+			days = 1
+		}
+		end = start.AddDate(0, 0, days)
+	case duration > 0:
+		end = start.Add(duration)
+	default:
+		end = start.Add(defaultEventLength)
+	}
+	if !end.After(start) {
+		return Event{}, agenterrors.New(agenterrors.InvalidParams, "an event must end after it starts")
+	}
+
+	uid := uuid.New()
+	client := connpool.Default.HTTP(15 * time.Second)
+	if err := c.put(ctx, client, uid, vevent(uid, title, location, description, start, end, allDay)); err != nil {
+		return Event{}, agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+
+	event := Event{
+		ID:          uid,
+		Calendar:    c.Name,
+		Title:       title,
+		AllDay:      allDay,
+		Location:    location,
+		Description: description,
+	}
+	if allDay {
+		event.Start = start.Format(time.DateOnly)
+		event.End = end.Format(time.DateOnly)
+	} else {
+		event.Start = start.In(e.loc).Format(time.RFC3339)
+		event.End = end.In(e.loc).Format(time.RFC3339)
+	}
+	return event, nil
+}
+
+// delete removes the event with a UID, and every occurrence of it, from
+// the CalDAV calendars given
+func (e *Executor) delete(ctx context.Context, calendars []Calendar, uid string) (int, error) {
+	client := connpool.Default.HTTP(15 * time.Second)
+	deleted := 0
+	searched := false
+	for _, c := range calendars {
+		if c.Feed {
+			continue
+		}
+		searched = true
+		objects, err := c.report(ctx, client, uidMatch(uid))
+		if err != nil {
+			return deleted, agenterrors.Wrap(agenterrors.Unavailable, err)
+		}
+		for _, o := range objects {
+			if err := c.remove(ctx, client, o.href); err != nil {
+				return deleted, agenterrors.Wrap(agenterrors.Unavailable, err)
+			}
+			deleted++
+		}
+	}
+	if !searched {
+		return 0, agenterrors.New(agenterrors.DeniedByPolicy, "events on read-only feeds can't be deleted")
+	}
+	if deleted == 0 {
+		return 0, agenterrors.Newf(agenterrors.NotFound, "no event with id '%s'", uid)
+	}
+	return deleted, nil
+}
+
+func containsFold(s, sub string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(sub))
+}
+
+// IsAvailable is false when no calendar can be reached, as in air-gapped
+// mode with only cloud calendars
+func (e *Executor) IsAvailable() bool {
+	for _, c := range e.calendars {
+		if connpool.Default.Reachable(c.URL) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"calendar.query": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"calendar": {"type": "string"},
+				"from": {"type": "string"},
+				"to": {"type": "string"},
+				"text": {"type": "string"},
+				"timezone": {"type": "string"}
+			}
+		}`),
+		"calendar.create_event": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"calendar": {"type": "string"},
+				"title": {"type": "string", "minLength": 1},
+				"start": {"type": "string", "minLength": 1},
+				"end": {"type": "string"},
+				"duration": {"type": "string"},
+				"all_day": {"type": "boolean"},
+				"location": {"type": "string"},
+				"description": {"type": "string"},
+				"timezone": {"type": "string"}
+			},
+			"required": ["title", "start"]
+		}`),
+		"calendar.delete_event": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"calendar": {"type": "string"},
+				"id": {"type": "string", "minLength": 1}
+			},
+			"required": ["id"]
+		}`),
+	}
+}
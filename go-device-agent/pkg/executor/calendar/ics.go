@@ -0,0 +1,130 @@
+package calendar
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apognu/gocal"
+)
+
+// Event is one occurrence of a calendar event. Recurring events produce an
+// occurrence per instance, all with the same ID.
+type Event struct {
+	ID          string `json:"id"`
+	Calendar    string `json:"calendar"`
+	Title       string `json:"title"`
+	Start       string `json:"start"`
+	End         string `json:"end"`
+	AllDay      bool   `json:"all_day,omitempty"`
+	Location    string `json:"location,omitempty"`
+	Description string `json:"description,omitempty"`
+	Recurring   bool   `json:"recurring,omitempty"`
+
+	start time.Time
+}
+
+// parseEvents reads the VEVENTs of an iCalendar document overlapping
+// [from, to), expanding recurrences. Times are reported in loc, which also
+// anchors all-day events.
+func parseEvents(r io.Reader, calendar string, from, to time.Time, loc *time.Location) ([]Event, error) {
+	// gocal skips events starting exactly at the range start
+	start, end := from.Add(-time.Nanosecond), to
+	parser := gocal.NewParser(r)
+	parser.Start, parser.End = &start, &end
+	parser.AllDayEventsTZ = loc
+	parser.Strict.Mode = gocal.StrictModeFailEvent
+	if err := parser.Parse(); err != nil {
+		return nil, err
+	}
+
+	events := make([]Event, 0, len(parser.Events))
+	for _, e := range parser.Events {
+		if e.Start == nil || e.End == nil || strings.EqualFold(e.Status, "CANCELLED") {
+			continue
+		}
+		allDay := e.RawStart.Params["VALUE"] == "DATE" || len(e.RawStart.Value) == 8
+		ev := Event{
+			ID:          e.Uid,
+			Calendar:    calendar,
+			Title:       e.Summary,
+			AllDay:      allDay,
+			Location:    e.Location,
+			Description: e.Description,
+			Recurring:   e.IsRecurring || e.RecurrenceID != "",
+			start:       e.Start.In(loc),
+		}
+		if allDay {
+			// All-day ends are exclusive, the day after the last one
+			end := e.End.In(loc)
+			y, m, d := end.Date()
+			day := time.Date(y, m, d, 0, 0, 0, 0, loc)
+			if !day.Equal(end) {
+				day = day.AddDate(0, 0, 1)
+			}
+			ev.Start = e.Start.In(loc).Format(time.DateOnly)
+			ev.End = day.Format(time.DateOnly)
+		} else {
+			ev.Start = e.Start.In(loc).Format(time.RFC3339)
+			ev.End = e.End.In(loc).Format(time.RFC3339)
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+func sortEvents(events []Event) {
+	sort.SliceStable(events, func(a, b int) bool { return events[a].start.Before(events[b].start) })
+}
+
+// vevent renders a new event as an iCalendar document. Timed events are
+// written in UTC, so no VTIMEZONE is needed; all-day events as dates.
+func vevent(uid, title, location, description string, start, end time.Time, allDay bool) string {
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	var b strings.Builder
+	b.WriteString("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//local-agent-core//calendar//EN\r\n")
+	b.WriteString("BEGIN:VEVENT\r\n")
+	b.WriteString("UID:" + uid + "\r\n")
+	b.WriteString("DTSTAMP:" + stamp + "\r\n")
+	if allDay {
+		b.WriteString("DTSTART;VALUE=DATE:" + start.Format("20060102") + "\r\n")
+		b.WriteString("DTEND;VALUE=DATE:" + end.Format("20060102") + "\r\n")
+	} else {
+		b.WriteString("DTSTART:" + start.UTC().Format("20060102T150405Z") + "\r\n")
+		b.WriteString("DTEND:" + end.UTC().Format("20060102T150405Z") + "\r\n")
+	}
+	b.WriteString("SUMMARY:" + escapeText(title) + "\r\n")
+	if location != "" {
+		b.WriteString("LOCATION:" + escapeText(location) + "\r\n")
+	}
+	if description != "" {
+		b.WriteString("DESCRIPTION:" + escapeText(description) + "\r\n")
+	}
+	b.WriteString("END:VEVENT\r\nEND:VCALENDAR\r\n")
+	return b.String()
+}
+
+// escapeText escapes an iCalendar TEXT value
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// parseTime reads a time parameter: RFC 3339, or a local date and time
+// ("2026-10-17T09:30" or "2026-10-17 09:30") in loc, or a date
+func parseTime(s string, loc *time.Location) (t time.Time, dateOnly bool, err error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.In(loc), false, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, false, nil
+		}
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, s, loc); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("'%s' is not a date or time (use RFC 3339, 2006-01-02T15:04, or 2006-01-02)", s)
+}
@@ -0,0 +1,395 @@
+// Package camera takes snapshots from IP cameras and hands out their
+// stream URLs, finding the URIs over ONVIF or taking them from config
+package camera
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/registry"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Config lists the cameras and where their snapshots go
+type Config struct {
+	Cameras map[string]*Camera
+
+	// Dir is where snapshots are written, as <camera>-<time>.jpg
+	Dir string
+
+	// Keep is how many snapshots of each camera are kept (default 100)
+	Keep int
+
+	// Timeout bounds talking to a camera (default 10s)
+	Timeout time.Duration
+}
+
+// Executor handles camera.list, camera.discover, camera.snapshot, and
+// camera.stream_url
+type Executor struct {
+	cfg      Config
+	registry *registry.Registry
+
+	mu    sync.Mutex
+	onvif map[string]*onvif // by camera name
+	media map[string]media  // resolved URIs by camera name
+}
+
+// NewExecutor creates the snapshot directory and lists the cameras in reg
+// as "camera:<name>" when it is not nil
+func NewExecutor(cfg Config, reg *registry.Registry) (*Executor, error) {
+	if cfg.Dir == "" {
+		return nil, agenterrors.New(agenterrors.InvalidParams, "no snapshot directory configured")
+	}
+	if cfg.Keep <= 0 {
+		cfg.Keep = 100
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o750); err != nil {
+		return nil, err
+	}
+	e := &Executor{
+		cfg:      cfg,
+		registry: reg,
+		onvif:    make(map[string]*onvif),
+		media:    make(map[string]media),
+	}
+	client := connpool.Default.HTTP(cfg.Timeout)
+	for name, c := range cfg.Cameras {
+		if c.name == "" {
+			if err := c.init(name); err != nil {
+				return nil, err
+			}
+		}
+		if service := c.deviceService(); service != "" {
+			e.onvif[name] = &onvif{client: client, device: service, username: c.Username, password: c.password}
+		}
+		if reg != nil {
+			reg.Upsert(registry.Device{
+				ID:     "camera:" + name,
+				Name:   name,
+				Kind:   registry.KindCamera,
+				Room:   c.Room,
+				Module: "camera",
+			})
+		}
+	}
+	return e, nil
+}
+
+func (e *Executor) Name() string {
+	return "camera"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"camera.list", "camera.discover", "camera.snapshot", "camera.stream_url"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	needs := sandbox.Needs{Network: true, Write: []string{e.cfg.Dir}}
+	for _, c := range e.cfg.Cameras {
+		// Without an HTTP snapshot, frames are grabbed with ffmpeg
+		if c.SnapshotURL == "" {
+			needs.Subprocess = true
+		}
+	}
+	return needs
+}
+
+// PermissionRequired makes looking through a camera need requires_permission
+func (e *Executor) PermissionRequired() []string {
+	return []string{"camera.snapshot", "camera.stream_url"}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "camera",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	switch i.IntentType {
+	case "camera.list":
+		names := e.names()
+		cameras := make([]map[string]interface{}, 0, len(names))
+		for _, name := range names {
+			c := e.cfg.Cameras[name]
+			entry := map[string]interface{}{
+				"name":  name,
+				"onvif": c.deviceService() != "",
+			}
+			if c.Room != "" {
+				entry["room"] = c.Room
+			}
+			if e.registry != nil {
+				if d, ok := e.registry.Get("camera:" + name); ok {
+					if online, ok := d.State["online"].(bool); ok {
+						entry["online"] = online
+					}
+				}
+			}
+			cameras = append(cameras, entry)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"cameras": cameras,
+		}
+
+	case "camera.discover":
+		var params struct {
+			Seconds int `param:"timeout_seconds"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		if params.Seconds <= 0 {
+			params.Seconds = 3
+		}
+		listen, cancel := context.WithTimeout(ctx, time.Duration(params.Seconds)*time.Second)
+		found, err := Discover(listen)
+		cancel()
+		if err != nil {
+			return fail(agenterrors.Wrap(agenterrors.Unavailable, err))
+		}
+		for n, d := range found {
+			for _, name := range e.names() {
+				if host := e.cfg.Cameras[name].host(); host != "" && host == hostname(d.Address) {
+					found[n].Configured = name
+				}
+			}
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"cameras": found,
+		}
+
+	case "camera.snapshot":
+		var params struct {
+			Camera string `param:"camera"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		c, err := e.camera(params.Camera)
+		if err != nil {
+			return fail(err)
+		}
+		snapshot, err := e.snapshot(ctx, c)
+		e.seen(c, err)
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"camera":      c.name,
+			"path":        snapshot.Path,
+			"bytes":       snapshot.Bytes,
+			"width":       snapshot.Width,
+			"height":      snapshot.Height,
+			"captured_at": snapshot.CapturedAt,
+			"source":      snapshot.Source,
+		}
+
+	case "camera.stream_url":
+		var params struct {
+			Camera             string `param:"camera"`
+			IncludeCredentials bool   `param:"include_credentials"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		c, err := e.camera(params.Camera)
+		if err != nil {
+			return fail(err)
+		}
+		m, err := e.resolve(ctx, c)
+		e.seen(c, err)
+		if err != nil {
+			return fail(err)
+		}
+		if m.StreamURI == "" {
+			return fail(agenterrors.Newf(agenterrors.Unsupported, "%s has no stream; set its stream_url", c.name))
+		}
+		uri := m.StreamURI
+		if params.IncludeCredentials {
+			uri = c.withCredentials(uri)
+		}
+		out := map[string]interface{}{
+			"camera": c.name,
+			"url":    uri,
+		}
+		if m.Profile != "" {
+			out["profile"] = m.Profile
+		}
+		if c.Username != "" && !params.IncludeCredentials {
+			// The player will be asked for these
+			out["username"] = c.Username
+		}
+		result.Success = true
+		result.Result = out
+
+	default:
+		return fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+	return result, nil
+}
+
+func (e *Executor) names() []string {
+	names := make([]string, 0, len(e.cfg.Cameras))
+	for name := range e.cfg.Cameras {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// camera finds a camera by name; the name can be left out when there is
+// only one
+func (e *Executor) camera(name string) (*Camera, error) {
+	if name == "" {
+		if len(e.cfg.Cameras) == 1 {
+			for _, c := range e.cfg.Cameras {
+				return c, nil
+			}
+		}
+		return nil, agenterrors.Newf(agenterrors.InvalidParams, "which camera? (%s)", strings.Join(e.names(), ", "))
+	}
+	c, ok := e.cfg.Cameras[strings.ToLower(name)]
+	if !ok {
+		return nil, agenterrors.Newf(agenterrors.NotFound, "no camera named %q (cameras: %s)", name, strings.Join(e.names(), ", "))
+	}
+	return c, nil
+}
+
+// resolve finds a camera's snapshot and stream URIs, asking ONVIF once
+// and keeping the answer. Configured URLs win over ONVIF's.
+func (e *Executor) resolve(ctx context.Context, c *Camera) (media, error) {
+	e.mu.Lock()
+	m, ok := e.media[c.name]
+	o := e.onvif[c.name]
+	e.mu.Unlock()
+	if ok {
+		return m, nil
+	}
+	if o != nil && (c.SnapshotURL == "" || c.StreamURL == "") {
+		var err error
+		ctx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+		m, err = o.resolve(ctx, c.Profile)
+		cancel()
+		if err != nil {
+			return media{}, err
+		}
+	}
+	if c.SnapshotURL != "" {
+		m.SnapshotURI = c.SnapshotURL
+	}
+	if c.StreamURL != "" {
+		m.StreamURI = c.StreamURL
+	}
+	e.mu.Lock()
+	e.media[c.name] = m
+	e.mu.Unlock()
+	return m, nil
+}
+
+// forget drops a camera's resolved URIs, so that a camera that changed
+// them (after a firmware update, say) is asked again
+func (e *Executor) forget(c *Camera) {
+	e.mu.Lock()
+	delete(e.media, c.name)
+	e.mu.Unlock()
+}
+
+// snapshot fetches a JPEG over HTTP, or grabs a frame from the stream
+// when the camera has no snapshot URI, and saves it
+func (e *Executor) snapshot(ctx context.Context, c *Camera) (Snapshot, error) {
+	m, err := e.resolve(ctx, c)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+	defer cancel()
+	var data []byte
+	source := "http"
+	switch {
+	case m.SnapshotURI != "":
+		data, err = fetchHTTP(ctx, connpool.Default.HTTP(e.cfg.Timeout), c, m.SnapshotURI)
+	case strings.HasPrefix(m.StreamURI, "rtsp://") || strings.HasPrefix(m.StreamURI, "rtsps://"):
+		source = "rtsp"
+		data, err = grabFrame(ctx, c, m.StreamURI)
+	default:
+		return Snapshot{}, agenterrors.Newf(agenterrors.Unsupported, "%s has neither a snapshot URI nor an RTSP stream", c.name)
+	}
+	if err != nil {
+		if agenterrors.CodeOf(err) != agenterrors.Unauthorized {
+			e.forget(c)
+		}
+		return Snapshot{}, err
+	}
+	snapshot, err := save(e.cfg.Dir, c.name, time.Now(), data, e.cfg.Keep)
+	snapshot.Source = source
+	return snapshot, err
+}
+
+// seen records in the registry whether a camera answered. A refused
+// password still means it is up; other errors say nothing either way.
+func (e *Executor) seen(c *Camera, err error) {
+	if e.registry == nil {
+		return
+	}
+	online := true
+	switch agenterrors.CodeOf(err) {
+	case "", agenterrors.Unauthorized:
+	case agenterrors.Unavailable, agenterrors.Timeout:
+		online = false
+	default:
+		return
+	}
+	e.registry.UpdateState("camera:"+c.name, map[string]interface{}{"online": online})
+}
+
+func (e *Executor) IsAvailable() bool {
+	return len(e.cfg.Cameras) > 0
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"camera.list": schema.MustParse(`{"type": "object", "properties": {}}`),
+		"camera.discover": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"timeout_seconds": {"type": "integer", "minimum": 1, "maximum": 30}
+			}
+		}`),
+		"camera.snapshot": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"camera": {"type": "string", "minLength": 1}
+			}
+		}`),
+		"camera.stream_url": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"camera": {"type": "string", "minLength": 1},
+				"include_credentials": {"type": "boolean"}
+			}
+		}`),
+	}
+}
@@ -0,0 +1,155 @@
+package camera
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/secrets"
+)
+
+// Camera is one configured camera. ONVIF cameras need only an address;
+// the snapshot and stream URIs are asked for on first use. Cameras
+// without ONVIF are given their URLs directly.
+type Camera struct {
+	// Address is the camera's host[:port], or the full URL of its ONVIF
+	// device service when that isn't at /onvif/device_service
+	Address string `json:"address,omitempty"`
+
+	Username string `json:"username,omitempty"`
+
+	// PasswordEnv names the environment variable holding the password,
+	// keeping it out of the cameras file. Password may instead name a
+	// secret, as secret://cameras/driveway, but may not hold the password
+	// itself.
+	PasswordEnv string `json:"password_env,omitempty"`
+	Password    string `json:"password,omitempty"`
+
+	// Profile picks the ONVIF media profile by name or token (default the
+	// first, normally the main stream)
+	Profile string `json:"profile,omitempty"`
+
+	// SnapshotURL and StreamURL override what ONVIF reports. A camera with
+	// only a stream URL has its snapshots grabbed from the stream.
+	SnapshotURL string `json:"snapshot_url,omitempty"`
+	StreamURL   string `json:"stream_url,omitempty"`
+
+	Room string `json:"room,omitempty"`
+
+	name     string
+	password string
+}
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// LoadCameras reads a JSON object of cameras by name, e.g.
+//
+//	{"driveway": {"address": "192.168.1.20", "username": "admin",
+//	              "password_env": "DRIVEWAY_CAMERA_PASSWORD", "room": "outside"}}
+func LoadCameras(path string) (map[string]*Camera, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cameras map[string]*Camera
+	if err := json.Unmarshal(data, &cameras); err != nil {
+		return nil, fmt.Errorf("invalid cameras file %s: %w", path, err)
+	}
+	for name, c := range cameras {
+		if err := c.init(name); err != nil {
+			return nil, fmt.Errorf("invalid cameras file %s: %w", path, err)
+		}
+	}
+	return cameras, nil
+}
+
+func (c *Camera) init(name string) error {
+	// Names end up in snapshot file names
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("camera name %q must be lowercase letters, digits, '-' and '_'", name)
+	}
+	if c.Address == "" && c.SnapshotURL == "" && c.StreamURL == "" {
+		return fmt.Errorf("camera %q needs an address or a snapshot or stream URL", name)
+	}
+	for _, u := range []string{c.SnapshotURL, c.StreamURL} {
+		if u == "" {
+			continue
+		}
+		parsed, err := url.Parse(u)
+		if err != nil || parsed.Host == "" {
+			return fmt.Errorf("camera %q: invalid URL %q", name, u)
+		}
+		if parsed.User != nil {
+			return fmt.Errorf("camera %q: put credentials in username and password_env or password, not the URL", name)
+		}
+	}
+	switch {
+	case c.Password != "" && !secrets.IsReference(c.Password):
+		return fmt.Errorf("camera %q: password must be a secret:// reference; use password_env for a plain password", name)
+	case c.Password != "":
+		password, err := secrets.Resolve(c.Password)
+		if err != nil {
+			return fmt.Errorf("camera %q: %w", name, err)
+		}
+		c.password = password
+	case c.PasswordEnv != "":
+		password, err := secrets.Resolve(os.Getenv(c.PasswordEnv))
+		if err != nil {
+			return fmt.Errorf("camera %q: %s: %w", name, c.PasswordEnv, err)
+		}
+		if password == "" {
+			return fmt.Errorf("camera %q: %s is not set", name, c.PasswordEnv)
+		}
+		c.password = password
+	}
+	c.name = name
+	return nil
+}
+
+// deviceService is the URL of the camera's ONVIF device service, or ""
+// for cameras configured by URL alone
+func (c *Camera) deviceService() string {
+	switch {
+	case c.Address == "":
+		return ""
+	case strings.Contains(c.Address, "://"):
+		return c.Address
+	}
+	return "http://" + c.Address + "/onvif/device_service"
+}
+
+// host is the camera's host without a port, for matching discovered
+// cameras to configured ones
+func (c *Camera) host() string {
+	for _, u := range []string{c.deviceService(), c.SnapshotURL, c.StreamURL} {
+		if h := hostname(u); h != "" {
+			return h
+		}
+	}
+	return ""
+}
+
+func hostname(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	return parsed.Hostname()
+}
+
+// withCredentials embeds the camera's credentials in a URL, as RTSP
+// players expect them
+func (c *Camera) withCredentials(u string) string {
+	if c.Username == "" {
+		return u
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	parsed.User = url.UserPassword(c.Username, c.password)
+	return parsed.String()
+}
@@ -0,0 +1,372 @@
+package camera
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/internal/uuid"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// Discovered is an ONVIF camera that answered a WS-Discovery probe
+type Discovered struct {
+	Address  string   `json:"address"` // device service URL
+	Name     string   `json:"name,omitempty"`
+	Hardware string   `json:"hardware,omitempty"`
+	Location string   `json:"location,omitempty"`
+	XAddrs   []string `json:"xaddrs,omitempty"`
+
+	// Configured is the name of the configured camera at this address
+	Configured string `json:"configured,omitempty"`
+}
+
+var wsDiscoveryAddr = &net.UDPAddr{IP: net.IPv4(239, 255, 255, 250), Port: 3702}
+
+const probe = `<?xml version="1.0" encoding="UTF-8"?>
+<e:Envelope xmlns:e="http://www.w3.org/2003/05/soap-envelope" xmlns:w="http://schemas.xmlsoap.org/ws/2004/08/addressing" xmlns:d="http://schemas.xmlsoap.org/ws/2005/04/discovery" xmlns:dn="http://www.onvif.org/ver10/network/wsdl">
+<e:Header><w:MessageID>uuid:%s</w:MessageID><w:To e:mustUnderstand="true">urn:schemas-xmlsoap-org:ws:2005:04:discovery</w:To><w:Action e:mustUnderstand="true">http://schemas.xmlsoap.org/ws/2005/04/discovery/Probe</w:Action></e:Header>
+<e:Body><d:Probe><d:Types>dn:NetworkVideoTransmitter</d:Types></d:Probe></e:Body>
+</e:Envelope>`
+
+// probeMatches is the body of a WS-Discovery ProbeMatches message
+type probeMatches struct {
+	Matches []struct {
+		Scopes string `xml:"Scopes"`
+		XAddrs string `xml:"XAddrs"`
+	} `xml:"Body>ProbeMatches>ProbeMatch"`
+}
+
+// Discover probes the local network for ONVIF cameras until ctx is done
+func Discover(ctx context.Context) ([]Discovered, error) {
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	stopOnDone(ctx, conn)
+
+	if _, err := conn.WriteToUDP([]byte(fmt.Sprintf(probe, uuid.New())), wsDiscoveryAddr); err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]Discovered)
+	buf := make([]byte, 65536)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			break
+		}
+		var resp probeMatches
+		if xml.Unmarshal(buf[:n], &resp) != nil {
+			continue
+		}
+		for _, m := range resp.Matches {
+			xaddrs := strings.Fields(m.XAddrs)
+			if len(xaddrs) == 0 {
+				continue
+			}
+			d := Discovered{Address: xaddrs[0], XAddrs: xaddrs}
+			for _, scope := range strings.Fields(m.Scopes) {
+				key, value, ok := strings.Cut(strings.TrimPrefix(scope, "onvif://www.onvif.org/"), "/")
+				if !ok {
+					continue
+				}
+				value, _ = url.PathUnescape(value)
+				switch key {
+				case "name":
+					d.Name = value
+				case "hardware":
+					d.Hardware = value
+				case "location":
+					d.Location = value
+				}
+			}
+			found[d.Address] = d
+		}
+	}
+
+	cameras := make([]Discovered, 0, len(found))
+	for _, d := range found {
+		cameras = append(cameras, d)
+	}
+	sort.Slice(cameras, func(a, b int) bool { return cameras[a].Address < cameras[b].Address })
+	return cameras, nil
+}
+
+// stopOnDone unblocks reads on conn once ctx is done
+func stopOnDone(ctx context.Context, conn *net.UDPConn) {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetReadDeadline(deadline)
+		return
+	}
+	go func() {
+		<-ctx.Done()
+		conn.SetReadDeadline(time.Now())
+	}()
+}
+
+// onvif speaks SOAP to one camera's device and media services
+type onvif struct {
+	client   *http.Client
+	device   string
+	username string
+	password string
+
+	mu     sync.Mutex
+	offset time.Duration // camera clock minus ours, for WS-Security timestamps
+	synced bool
+}
+
+const (
+	deviceNS = "http://www.onvif.org/ver10/device/wsdl"
+	mediaNS  = "http://www.onvif.org/ver10/media/wsdl"
+	schemaNS = "http://www.onvif.org/ver10/schema"
+)
+
+// media is what the media service reports for the chosen profile
+type media struct {
+	Profile     string
+	SnapshotURI string
+	StreamURI   string
+}
+
+// resolve asks the camera for its media service, picks a profile by name
+// or token (the first when profile is empty), and gets its URIs. A
+// camera without a snapshot URI still resolves; its stream has to do.
+func (o *onvif) resolve(ctx context.Context, profile string) (media, error) {
+	var caps struct {
+		XAddr string `xml:"Body>GetCapabilitiesResponse>Capabilities>Media>XAddr"`
+	}
+	err := o.call(ctx, o.device, `<GetCapabilities xmlns="`+deviceNS+`"><Category>Media</Category></GetCapabilities>`, &caps)
+	if err != nil {
+		return media{}, err
+	}
+	if caps.XAddr == "" {
+		return media{}, agenterrors.New(agenterrors.Unsupported, "the camera has no ONVIF media service")
+	}
+	service := o.sameHost(caps.XAddr)
+
+	var profiles struct {
+		Profiles []struct {
+			Token string `xml:"token,attr"`
+			Name  string `xml:"Name"`
+		} `xml:"Body>GetProfilesResponse>Profiles"`
+	}
+	if err := o.call(ctx, service, `<GetProfiles xmlns="`+mediaNS+`"/>`, &profiles); err != nil {
+		return media{}, err
+	}
+	if len(profiles.Profiles) == 0 {
+		return media{}, agenterrors.New(agenterrors.Unsupported, "the camera has no media profiles")
+	}
+	token := profiles.Profiles[0].Token
+	if profile != "" {
+		token = ""
+		var names []string
+		for _, p := range profiles.Profiles {
+			if strings.EqualFold(p.Name, profile) || p.Token == profile {
+				token = p.Token
+				break
+			}
+			names = append(names, p.Name)
+		}
+		if token == "" {
+			return media{}, agenterrors.Newf(agenterrors.NotFound, "the camera has no profile %q (has %s)", profile, strings.Join(names, ", "))
+		}
+	}
+	m := media{Profile: token}
+
+	var uri struct {
+		URI string `xml:"Body>GetStreamUriResponse>MediaUri>Uri"`
+	}
+	streamReq := `<GetStreamUri xmlns="` + mediaNS + `"><StreamSetup>` +
+		`<Stream xmlns="` + schemaNS + `">RTP-Unicast</Stream>` +
+		`<Transport xmlns="` + schemaNS + `"><Protocol>RTSP</Protocol></Transport>` +
+		`</StreamSetup><ProfileToken>` + xmlEscape(token) + `</ProfileToken></GetStreamUri>`
+	if err := o.call(ctx, service, streamReq, &uri); err != nil {
+		return media{}, err
+	}
+	m.StreamURI = o.sameHost(uri.URI)
+
+	var snapshot struct {
+		URI string `xml:"Body>GetSnapshotUriResponse>MediaUri>Uri"`
+	}
+	snapshotReq := `<GetSnapshotUri xmlns="` + mediaNS + `"><ProfileToken>` + xmlEscape(token) + `</ProfileToken></GetSnapshotUri>`
+	if err := o.call(ctx, service, snapshotReq, &snapshot); err == nil {
+		m.SnapshotURI = o.sameHost(snapshot.URI)
+	} else if agenterrors.CodeOf(err) == agenterrors.Unavailable || agenterrors.CodeOf(err) == agenterrors.Unauthorized {
+		return media{}, err
+	}
+	return m, nil
+}
+
+// sameHost points a URI the camera reports at the address it was reached
+// on. Cameras behind NAT or with several interfaces often report an
+// address the agent can't reach; the path and port are kept.
+func (o *onvif) sameHost(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Host == "" {
+		return uri
+	}
+	device, err := url.Parse(o.device)
+	if err != nil {
+		return uri
+	}
+	switch {
+	case parsed.Port() != "":
+		parsed.Host = net.JoinHostPort(device.Hostname(), parsed.Port())
+	case parsed.Scheme == device.Scheme:
+		// Services on the default port are where the device service is
+		parsed.Host = device.Host
+	default:
+		parsed.Host = device.Hostname()
+	}
+	return parsed.String()
+}
+
+// call posts a SOAP request and decodes the response envelope into out
+func (o *onvif) call(ctx context.Context, service, body string, out interface{}) error {
+	if err := o.sync(ctx); err != nil {
+		return err
+	}
+	envelope := `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope">` +
+		o.header() + `<s:Body>` + body + `</s:Body></s:Envelope>`
+	data, err := o.post(ctx, service, envelope)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(data, out)
+}
+
+func (o *onvif) post(ctx context.Context, service, envelope string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, service, strings.NewReader(envelope))
+	if err != nil {
+		return nil, agenterrors.Wrap(agenterrors.InvalidParams, err)
+	}
+	req.Header.Set("Content-Type", "application/soap+xml; charset=utf-8")
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, faultError(resp.StatusCode, data)
+	}
+	return data, nil
+}
+
+// sync learns the camera's clock once. WS-Security timestamps outside the
+// camera's window are refused, and cameras are rarely on NTP.
+func (o *onvif) sync(ctx context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.synced || o.username == "" {
+		return nil
+	}
+	envelope := `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Body>` +
+		`<GetSystemDateAndTime xmlns="` + deviceNS + `"/></s:Body></s:Envelope>`
+	data, err := o.post(ctx, o.device, envelope)
+	if err != nil {
+		if agenterrors.CodeOf(err) == agenterrors.Unavailable {
+			return err
+		}
+		// Without the camera's time ours has to do
+		o.synced = true
+		return nil
+	}
+	var resp struct {
+		UTC struct {
+			Year   int `xml:"Date>Year"`
+			Month  int `xml:"Date>Month"`
+			Day    int `xml:"Date>Day"`
+			Hour   int `xml:"Time>Hour"`
+			Minute int `xml:"Time>Minute"`
+			Second int `xml:"Time>Second"`
+		} `xml:"Body>GetSystemDateAndTimeResponse>SystemDateAndTime>UTCDateTime"`
+	}
+	if xml.Unmarshal(data, &resp) == nil && resp.UTC.Year > 0 {
+		u := resp.UTC
+		camera := time.Date(u.Year, time.Month(u.Month), u.Day, u.Hour, u.Minute, u.Second, 0, time.UTC)
+		o.offset = time.Until(camera)
+	}
+	o.synced = true
+	return nil
+}
+
+// header is a WS-Security UsernameToken with a password digest, or
+// nothing for cameras without credentials
+func (o *onvif) header() string {
+	if o.username == "" {
+		return ""
+	}
+	o.mu.Lock()
+	created := time.Now().Add(o.offset).UTC().Format("2006-01-02T15:04:05.000Z")
+	o.mu.Unlock()
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	h := sha1.New()
+	h.Write(nonce)
+	h.Write([]byte(created))
+	h.Write([]byte(o.password))
+	digest := base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+	return `<s:Header><Security s:mustUnderstand="1" xmlns="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd">` +
+		`<UsernameToken><Username>` + xmlEscape(o.username) + `</Username>` +
+		`<Password Type="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordDigest">` + digest + `</Password>` +
+		`<Nonce EncodingType="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0#Base64Binary">` + base64.StdEncoding.EncodeToString(nonce) + `</Nonce>` +
+		`<Created xmlns="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd">` + created + `</Created>` +
+		`</UsernameToken></Security></s:Header>`
+}
+
+// faultError turns a failed SOAP response into an agent error
+func faultError(status int, data []byte) error {
+	var fault struct {
+		Subcodes []string `xml:"Body>Fault>Code>Subcode>Value"`
+		Reason   string   `xml:"Body>Fault>Reason>Text"`
+	}
+	xml.Unmarshal(data, &fault)
+	text := fmt.Sprintf("ONVIF request failed with HTTP %d", status)
+	if fault.Reason != "" {
+		text = "ONVIF request failed: " + strings.TrimSpace(fault.Reason)
+	}
+	for _, code := range fault.Subcodes {
+		if strings.HasSuffix(code, "NotAuthorized") {
+			return agenterrors.New(agenterrors.Unauthorized, "the camera refused its username or password")
+		}
+		if strings.HasSuffix(code, "ActionNotSupported") {
+			return agenterrors.New(agenterrors.Unsupported, text)
+		}
+	}
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return agenterrors.New(agenterrors.Unauthorized, "the camera refused its username or password")
+	case http.StatusBadRequest, http.StatusInternalServerError:
+		// SOAP faults for bad arguments come back as 400 or 500
+		return agenterrors.New(agenterrors.InvalidParams, text)
+	}
+	return agenterrors.New(agenterrors.Unavailable, text)
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
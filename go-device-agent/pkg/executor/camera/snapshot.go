@@ -0,0 +1,276 @@
+package camera
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"image/jpeg"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// maxSnapshot bounds a snapshot download
+const maxSnapshot = 20 << 20
+
+// stampLayout names snapshot files; it sorts in time order
+const stampLayout = "20060102T150405.000Z"
+
+// fetchHTTP downloads a JPEG snapshot, answering a Basic or Digest
+// challenge with the camera's credentials
+func fetchHTTP(ctx context.Context, client *http.Client, c *Camera, uri string) ([]byte, error) {
+	resp, err := get(ctx, client, uri, "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && c.Username != "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		auth, err := authorization(challenge, c.Username, c.password, uri)
+		if err != nil {
+			return nil, err
+		}
+		if resp, err = get(ctx, client, uri, auth); err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, agenterrors.New(agenterrors.Unauthorized, "the camera refused its username or password")
+	case resp.StatusCode != http.StatusOK:
+		return nil, agenterrors.Newf(agenterrors.Unavailable, "the camera answered the snapshot request with HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSnapshot+1))
+	if err != nil {
+		return nil, agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	if len(data) > maxSnapshot {
+		return nil, agenterrors.New(agenterrors.Unavailable, "the snapshot is larger than 20 MiB")
+	}
+	return data, nil
+}
+
+func get(ctx context.Context, client *http.Client, uri, auth string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, agenterrors.Wrap(agenterrors.InvalidParams, err)
+	}
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	return resp, nil
+}
+
+// authorization answers a WWW-Authenticate challenge. Digest supports
+// MD5 and SHA-256 with qop=auth, which is what cameras ask for.
+func authorization(challenge, username, password, uri string) (string, error) {
+	scheme, rest, _ := strings.Cut(challenge, " ")
+	switch strings.ToLower(scheme) {
+	case "basic":
+		req, _ := http.NewRequest(http.MethodGet, uri, nil)
+		req.SetBasicAuth(username, password)
+		return req.Header.Get("Authorization"), nil
+	case "digest":
+	default:
+		return "", agenterrors.Newf(agenterrors.Unsupported, "the camera asks for %q authentication", scheme)
+	}
+
+	p := parseChallenge(rest)
+	var newHash func() hash.Hash
+	switch strings.ToUpper(p["algorithm"]) {
+	case "", "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return "", agenterrors.Newf(agenterrors.Unsupported, "the camera asks for digest algorithm %s", p["algorithm"])
+	}
+	h := func(s string) string {
+		d := newHash()
+		d.Write([]byte(s))
+		return hex.EncodeToString(d.Sum(nil))
+	}
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "", agenterrors.Wrap(agenterrors.InvalidParams, err)
+	}
+	path := parsed.RequestURI()
+	ha1 := h(username + ":" + p["realm"] + ":" + password)
+	ha2 := h(http.MethodGet + ":" + path)
+
+	fields := []string{
+		fmt.Sprintf(`username="%s"`, username),
+		fmt.Sprintf(`realm="%s"`, p["realm"]),
+		fmt.Sprintf(`nonce="%s"`, p["nonce"]),
+		fmt.Sprintf(`uri="%s"`, path),
+	}
+	qopAuth := false
+	for _, q := range strings.Split(p["qop"], ",") {
+		qopAuth = qopAuth || strings.TrimSpace(q) == "auth"
+	}
+	if qopAuth {
+		cnonce := make([]byte, 8)
+		rand.Read(cnonce)
+		cn := hex.EncodeToString(cnonce)
+		response := h(ha1 + ":" + p["nonce"] + ":00000001:" + cn + ":auth:" + ha2)
+		fields = append(fields, `qop=auth`, `nc=00000001`, fmt.Sprintf(`cnonce="%s"`, cn), fmt.Sprintf(`response="%s"`, response))
+	} else if p["qop"] == "" {
+		fields = append(fields, fmt.Sprintf(`response="%s"`, h(ha1+":"+p["nonce"]+":"+ha2)))
+	} else {
+		return "", agenterrors.Newf(agenterrors.Unsupported, "the camera asks for digest qop %s", p["qop"])
+	}
+	if p["algorithm"] != "" {
+		fields = append(fields, "algorithm="+p["algorithm"])
+	}
+	if p["opaque"] != "" {
+		fields = append(fields, fmt.Sprintf(`opaque="%s"`, p["opaque"]))
+	}
+	return "Digest " + strings.Join(fields, ", "), nil
+}
+
+// parseChallenge splits the key="value" pairs of a challenge, allowing
+// commas inside quoted values
+func parseChallenge(s string) map[string]string {
+	params := make(map[string]string)
+	for s = strings.TrimSpace(s); s != ""; {
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		rest = strings.TrimSpace(rest)
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			value, rest = rest[1:end+1], rest[min(end+2, len(rest)):]
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+			value = strings.TrimSpace(value)
+			rest = "," + rest
+		}
+		params[key] = value
+		_, s, _ = strings.Cut(rest, ",")
+		s = strings.TrimSpace(s)
+	}
+	return params
+}
+
+// grabFrame takes one frame from an RTSP stream with ffmpeg
+func grabFrame(ctx context.Context, c *Camera, stream string) ([]byte, error) {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, agenterrors.New(agenterrors.Unsupported,
+			"the camera has no HTTP snapshot; install ffmpeg to take snapshots from its RTSP stream")
+	}
+	cmd := exec.CommandContext(ctx, ffmpeg, "-hide_banner", "-loglevel", "error",
+		"-rtsp_transport", "tcp", "-i", c.withCredentials(stream),
+		"-frames:v", "1", "-q:v", "2", "-f", "image2", "-c:v", "mjpeg", "-")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, agenterrors.New(agenterrors.Timeout, "no frame arrived from the camera's stream in time")
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if c.password != "" {
+			// ffmpeg repeats the URL it was given in its errors
+			msg = strings.ReplaceAll(msg, url.UserPassword(c.Username, c.password).String(), c.Username)
+			msg = strings.ReplaceAll(msg, c.password, "***")
+		}
+		if strings.Contains(msg, "401") {
+			return nil, agenterrors.New(agenterrors.Unauthorized, "the camera refused its username or password")
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && msg != "" {
+			return nil, agenterrors.Newf(agenterrors.Unavailable, "ffmpeg could not read the stream: %s", lastLine(msg))
+		}
+		return nil, agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+func lastLine(s string) string {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
+
+// Snapshot is a saved snapshot
+type Snapshot struct {
+	Path       string `json:"path"`
+	Bytes      int    `json:"bytes"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	CapturedAt string `json:"captured_at"`
+	Source     string `json:"source"` // http or rtsp
+}
+
+// save checks that data is a JPEG and writes it to dir, keeping at most
+// keep snapshots per camera
+func save(dir, camera string, at time.Time, data []byte, keep int) (Snapshot, error) {
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Snapshot{}, agenterrors.New(agenterrors.Unavailable, "the camera did not send a JPEG image")
+	}
+	at = at.UTC()
+	path := filepath.Join(dir, camera+"-"+at.Format(stampLayout)+".jpg")
+	if err := os.WriteFile(path, data, 0o640); err != nil {
+		return Snapshot{}, agenterrors.Wrap(agenterrors.Internal, err)
+	}
+	prune(dir, camera, keep)
+	return Snapshot{Path: path, Bytes: len(data), Width: cfg.Width, Height: cfg.Height, CapturedAt: at.Format(time.RFC3339)}, nil
+}
+
+// prune removes a camera's oldest snapshots beyond keep
+func prune(dir, camera string, keep int) {
+	if keep <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	var names []string
+	for _, entry := range entries {
+		// Only this camera's, not those of a camera whose name it prefixes
+		stamp, ok := strings.CutPrefix(entry.Name(), camera+"-")
+		if !ok || !strings.HasSuffix(stamp, ".jpg") {
+			continue
+		}
+		if _, err := time.Parse(stampLayout, strings.TrimSuffix(stamp, ".jpg")); err == nil {
+			names = append(names, entry.Name())
+		}
+	}
+	if len(names) <= keep {
+		return
+	}
+	sort.Strings(names)
+	for _, name := range names[:len(names)-keep] {
+		os.Remove(filepath.Join(dir, name))
+	}
+}
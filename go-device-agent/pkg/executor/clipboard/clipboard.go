@@ -0,0 +1,219 @@
+// Package clipboard reads and writes the desktop clipboard's text:
+// wl-clipboard or xclip/xsel on Linux, pbcopy/pbpaste on macOS, and the
+// Win32 clipboard API on Windows
+package clipboard
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Config sets the size limit
+type Config struct {
+	// MaxBytes caps the text set, and the text returned by clipboard.get,
+	// which is cut short beyond it (default 64 KiB)
+	MaxBytes int
+}
+
+// backend is a platform's clipboard
+type backend interface {
+	name() string
+	get(ctx context.Context) (string, error)
+	set(ctx context.Context, text string) error
+}
+
+// Executor handles clipboard.get and clipboard.set
+type Executor struct {
+	cfg     Config
+	backend backend
+}
+
+// NewExecutor finds the platform's clipboard, failing when there is none
+// (as on a headless machine)
+func NewExecutor(cfg Config) (*Executor, error) {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = 64 << 10
+	}
+	b, err := newBackend()
+	if err != nil {
+		return nil, err
+	}
+	return &Executor{cfg: cfg, backend: b}, nil
+}
+
+// Backend names the clipboard in use, e.g. "wl-clipboard"
+func (e *Executor) Backend() string {
+	return e.backend.name()
+}
+
+func (e *Executor) Name() string {
+	return "clipboard"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"clipboard.get", "clipboard.set"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	// wl-clipboard, xclip, or pbcopy; Windows uses the Win32 API
+	return sandbox.Needs{Subprocess: runtime.GOOS != "windows"}
+}
+
+// PermissionRequired guards reads: the clipboard often holds passwords and
+// other text copied from elsewhere
+func (e *Executor) PermissionRequired() []string {
+	return []string{"clipboard.get"}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "clipboard",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	switch i.IntentType {
+	case "clipboard.get":
+		text, err := e.backend.get(ctx)
+		if err != nil {
+			return fail(err)
+		}
+		size := len(text)
+		truncated := size > e.cfg.MaxBytes
+		if truncated {
+			text = truncate(text, e.cfg.MaxBytes)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"text":      text,
+			"bytes":     size,
+			"truncated": truncated,
+			"empty":     size == 0,
+		}
+
+	case "clipboard.set":
+		var params struct {
+			Text string `param:"text,required"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		if len(params.Text) > e.cfg.MaxBytes {
+			return fail(agenterrors.Newf(agenterrors.InvalidParams, "text is %d bytes; the limit is %d", len(params.Text), e.cfg.MaxBytes))
+		}
+		if !utf8.ValidString(params.Text) {
+			return fail(agenterrors.New(agenterrors.InvalidParams, "text must be valid UTF-8"))
+		}
+		if err := e.backend.set(ctx, params.Text); err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"bytes": len(params.Text),
+		}
+
+	default:
+		return fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+	return result, nil
+}
+
+// truncate cuts text to at most n bytes without splitting a character
+func truncate(text string, n int) string {
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return text[:n]
+}
+
+func (e *Executor) IsAvailable() bool {
+	return true
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"clipboard.get": schema.MustParse(`{"type": "object", "additionalProperties": false}`),
+		"clipboard.set": schema.MustParse(fmt.Sprintf(`{
+			"type": "object",
+			"properties": {
+				"text": {"type": "string", "maxLength": %d}
+			},
+			"required": ["text"]
+		}`, e.cfg.MaxBytes)),
+	}
+}
+
+// command is a clipboard driven by a pair of programs reading from stdout
+// and writing to stdin
+type command struct {
+	tool     string
+	paste    []string
+	copy     []string
+	env      []string // added to the agent's environment
+	emptyErr []string // stderr text meaning the clipboard is empty
+}
+
+func (c *command) name() string {
+	return c.tool
+}
+
+func (c *command) get(ctx context.Context) (string, error) {
+	cmd := exec.CommandContext(ctx, c.paste[0], c.paste[1:]...)
+	cmd.Env = append(cmd.Environ(), c.env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		for _, empty := range c.emptyErr {
+			if strings.Contains(stderr.String(), empty) {
+				return "", nil
+			}
+		}
+		return "", c.failure(ctx, err, stderr.String())
+	}
+	return stdout.String(), nil
+}
+
+func (c *command) set(ctx context.Context, text string) error {
+	cmd := exec.CommandContext(ctx, c.copy[0], c.copy[1:]...)
+	cmd.Env = append(cmd.Environ(), c.env...)
+	cmd.Stdin = strings.NewReader(text)
+	// xclip and wl-copy stay behind to serve the selection; capturing their
+	// output would wait for them to exit
+	if err := cmd.Run(); err != nil {
+		return c.failure(ctx, err, "")
+	}
+	return nil
+}
+
+func (c *command) failure(ctx context.Context, err error, stderr string) error {
+	if ctx.Err() != nil {
+		return agenterrors.Newf(agenterrors.Timeout, "%s did not respond", c.tool)
+	}
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && strings.TrimSpace(stderr) != "" {
+		err = fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr))
+	}
+	return agenterrors.Wrap(agenterrors.Unavailable, fmt.Errorf("%s: %w", c.tool, err))
+}
@@ -0,0 +1,12 @@
+package clipboard
+
+// newBackend uses pbcopy and pbpaste, which are always present. They
+// follow the locale, so UTF-8 is asked for explicitly.
+func newBackend() (backend, error) {
+	return &command{
+		tool:  "pbcopy",
+		paste: []string{"pbpaste", "-Prefer", "txt"},
+		copy:  []string{"pbcopy"},
+		env:   []string{"LANG=en_US.UTF-8", "LC_CTYPE=UTF-8"},
+	}, nil
+}
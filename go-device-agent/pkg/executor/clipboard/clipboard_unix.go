@@ -0,0 +1,52 @@
+//go:build !windows && !darwin
+
+package clipboard
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// newBackend prefers wl-clipboard under Wayland, then xclip or xsel
+// under X11 (including XWayland)
+func newBackend() (backend, error) {
+	if os.Getenv("WAYLAND_DISPLAY") != "" && found("wl-paste", "wl-copy") {
+		return &command{
+			tool:     "wl-clipboard",
+			paste:    []string{"wl-paste", "--no-newline", "--type", "text"},
+			copy:     []string{"wl-copy", "--type", "text/plain;charset=utf-8"},
+			emptyErr: []string{"Nothing is copied", "No selection", "No suitable type"},
+		}, nil
+	}
+	if os.Getenv("DISPLAY") != "" {
+		switch {
+		case found("xclip"):
+			return &command{
+				tool:     "xclip",
+				paste:    []string{"xclip", "-selection", "clipboard", "-out", "-target", "UTF8_STRING"},
+				copy:     []string{"xclip", "-selection", "clipboard", "-in"},
+				emptyErr: []string{"target UTF8_STRING not available", "target STRING not available"},
+			}, nil
+		case found("xsel"):
+			return &command{
+				tool:  "xsel",
+				paste: []string{"xsel", "--clipboard", "--output"},
+				copy:  []string{"xsel", "--clipboard", "--input"},
+			}, nil
+		}
+	}
+	if os.Getenv("WAYLAND_DISPLAY") == "" && os.Getenv("DISPLAY") == "" {
+		return nil, errors.New("no graphical session (neither WAYLAND_DISPLAY nor DISPLAY is set)")
+	}
+	return nil, errors.New("no clipboard tool found: install wl-clipboard, xclip, or xsel")
+}
+
+func found(programs ...string) bool {
+	for _, p := range programs {
+		if _, err := exec.LookPath(p); err != nil {
+			return false
+		}
+	}
+	return true
+}
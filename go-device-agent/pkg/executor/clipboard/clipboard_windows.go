@@ -0,0 +1,122 @@
+package clipboard
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+const (
+	cfUnicodeText = 13
+	gmemMoveable  = 0x0002
+)
+
+var (
+	user32   = windows.NewLazySystemDLL("user32.dll")
+	kernel32 = windows.NewLazySystemDLL("kernel32.dll")
+
+	openClipboard    = user32.NewProc("OpenClipboard")
+	closeClipboard   = user32.NewProc("CloseClipboard")
+	emptyClipboard   = user32.NewProc("EmptyClipboard")
+	getClipboardData = user32.NewProc("GetClipboardData")
+	setClipboardData = user32.NewProc("SetClipboardData")
+	isFormatAvail    = user32.NewProc("IsClipboardFormatAvailable")
+
+	globalAlloc  = kernel32.NewProc("GlobalAlloc")
+	globalFree   = kernel32.NewProc("GlobalFree")
+	globalLock   = kernel32.NewProc("GlobalLock")
+	globalUnlock = kernel32.NewProc("GlobalUnlock")
+)
+
+// win32 uses the clipboard API directly
+type win32 struct{}
+
+func newBackend() (backend, error) {
+	if err := user32.Load(); err != nil {
+		return nil, err
+	}
+	return win32{}, nil
+}
+
+func (win32) name() string {
+	return "win32"
+}
+
+// open opens the clipboard on the calling thread, retrying while another
+// program holds it. The caller must stay on the thread until it closes it.
+func open(ctx context.Context) error {
+	for {
+		if r, _, _ := openClipboard.Call(0); r != 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return agenterrors.New(agenterrors.Timeout, "the clipboard is held by another program")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
+
+func (win32) get(ctx context.Context) (string, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if err := open(ctx); err != nil {
+		return "", err
+	}
+	defer closeClipboard.Call()
+
+	if r, _, _ := isFormatAvail.Call(cfUnicodeText); r == 0 {
+		return "", nil
+	}
+	handle, _, err := getClipboardData.Call(cfUnicodeText)
+	if handle == 0 {
+		return "", agenterrors.Wrap(agenterrors.Unavailable, fmt.Errorf("GetClipboardData: %w", err))
+	}
+	ptr, _, err := globalLock.Call(handle)
+	if ptr == 0 {
+		return "", agenterrors.Wrap(agenterrors.Unavailable, fmt.Errorf("GlobalLock: %w", err))
+	}
+	defer globalUnlock.Call(handle)
+	return windows.UTF16PtrToString(*(**uint16)(unsafe.Pointer(&ptr))), nil
+}
+
+func (win32) set(ctx context.Context, text string) error {
+	utf16, err := windows.UTF16FromString(text)
+	if err != nil {
+		return agenterrors.New(agenterrors.InvalidParams, "text can't contain NUL characters")
+	}
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if err := open(ctx); err != nil {
+		return err
+	}
+	defer closeClipboard.Call()
+
+	if r, _, err := emptyClipboard.Call(); r == 0 {
+		return agenterrors.Wrap(agenterrors.Unavailable, fmt.Errorf("EmptyClipboard: %w", err))
+	}
+	size := uintptr(len(utf16)) * 2
+	handle, _, err := globalAlloc.Call(gmemMoveable, size)
+	if handle == 0 {
+		return agenterrors.Wrap(agenterrors.Internal, fmt.Errorf("GlobalAlloc: %w", err))
+	}
+	ptr, _, err := globalLock.Call(handle)
+	if ptr == 0 {
+		globalFree.Call(handle)
+		return agenterrors.Wrap(agenterrors.Internal, fmt.Errorf("GlobalLock: %w", err))
+	}
+	copy(unsafe.Slice(*(**uint16)(unsafe.Pointer(&ptr)), len(utf16)), utf16)
+	globalUnlock.Call(handle)
+	// The clipboard owns the memory once SetClipboardData succeeds
+	if r, _, err := setClipboardData.Call(cfUnicodeText, handle); r == 0 {
+		globalFree.Call(handle)
+		return agenterrors.Wrap(agenterrors.Unavailable, fmt.Errorf("SetClipboardData: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,384 @@
+// Package clock answers time.query, time.convert, and time.until with
+// structured times in any zone, resolving zones from IANA names,
+// abbreviations, UTC offsets, cities, and countries without the network
+package clock
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	// Zones resolve even where the system has no zoneinfo
+	_ "time/tzdata"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Config sets the home zone
+type Config struct {
+	// Location is the zone used when an intent names none (default the
+	// system's)
+	Location *time.Location
+
+	// ZoneInfo is the zoneinfo directory place names are looked up in
+	// (default /usr/share/zoneinfo)
+	ZoneInfo string
+}
+
+// maxClocks bounds a world clock
+const maxClocks = 24
+
+// Executor handles time.query, time.convert, and time.until
+type Executor struct {
+	home  *time.Location
+	zones *Zones
+}
+
+// NewExecutor creates a time executor
+func NewExecutor(cfg Config) *Executor {
+	if cfg.Location == nil {
+		cfg.Location = systemZone()
+	}
+	return &Executor{home: cfg.Location, zones: &Zones{Dir: cfg.ZoneInfo}}
+}
+
+// systemZone is the local zone under its IANA name where that can be
+// found, since time.Local is just "Local"
+func systemZone() *time.Location {
+	name := os.Getenv("TZ")
+	if name == "" {
+		if target, err := os.Readlink("/etc/localtime"); err == nil {
+			if _, zone, ok := strings.Cut(target, "zoneinfo/"); ok {
+				name = zone
+			}
+		}
+	}
+	if loc, err := time.LoadLocation(strings.TrimPrefix(name, ":")); err == nil && name != "" {
+		return loc
+	}
+	return time.Local
+}
+
+// Clock is a moment as seen in one zone
+type Clock struct {
+	// Location is the name asked for, when it wasn't the zone's own
+	Location     string `json:"location,omitempty"`
+	Time         string `json:"time"`
+	Date         string `json:"date"`
+	Clock        string `json:"clock"`
+	Weekday      string `json:"weekday"`
+	Timezone     string `json:"timezone"`
+	Abbreviation string `json:"abbreviation"`
+	UTCOffset    string `json:"utc_offset"`
+	DST          bool   `json:"dst"`
+	Unix         int64  `json:"unix"`
+}
+
+func clockAt(t time.Time, location string) Clock {
+	abbreviation, offset := t.Zone()
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	c := Clock{
+		Time:         t.Format(time.RFC3339),
+		Date:         t.Format(time.DateOnly),
+		Clock:        t.Format("15:04"),
+		Weekday:      t.Weekday().String(),
+		Timezone:     t.Location().String(),
+		Abbreviation: abbreviation,
+		UTCOffset:    fmt.Sprintf("%s%02d:%02d", sign, offset/3600, offset%3600/60),
+		DST:          t.IsDST(),
+		Unix:         t.Unix(),
+	}
+	if location != "" && !strings.EqualFold(location, c.Timezone) {
+		c.Location = location
+	}
+	return c
+}
+
+func (e *Executor) Name() string {
+	return "time"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"time.query", "time.convert", "time.until"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "time",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	now := time.Now()
+	switch i.IntentType {
+	case "time.query":
+		var params struct {
+			Timezone  string   `param:"timezone"`
+			Location  string   `param:"location"`
+			Locations []string `param:"locations"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		if len(params.Locations) > 0 {
+			if len(params.Locations) > maxClocks {
+				return fail(agenterrors.Newf(agenterrors.InvalidParams, "at most %d locations", maxClocks))
+			}
+			clocks := make([]Clock, 0, len(params.Locations))
+			for _, name := range params.Locations {
+				loc, err := e.zones.Resolve(name)
+				if err != nil {
+					return fail(err)
+				}
+				clocks = append(clocks, clockAt(now.In(loc), name))
+			}
+			result.Success = true
+			result.Result = map[string]interface{}{"clocks": clocks}
+			return result, nil
+		}
+
+		name := params.Location
+		if name == "" {
+			name = params.Timezone
+		}
+		loc, err := e.zone(name)
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{"clock": clockAt(now.In(loc), name)}
+		return result, nil
+
+	case "time.convert":
+		var params struct {
+			Time string `param:"time"`
+			From string `param:"from"`
+			To   string `param:"to,required"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		from, err := e.zone(params.From)
+		if err != nil {
+			return fail(err)
+		}
+		to, err := e.zones.Resolve(params.To)
+		if err != nil {
+			return fail(err)
+		}
+		t := now.In(from)
+		if params.Time != "" {
+			if t, err = parseTime(params.Time, now, from, false); err != nil {
+				return fail(err)
+			}
+		}
+		converted := t.In(to)
+		_, fromOffset := t.Zone()
+		_, toOffset := converted.Zone()
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"from": clockAt(t, params.From),
+			"to":   clockAt(converted, params.To),
+			// How far ahead the target zone's clocks are, e.g. "+7h" or "-3h30m"
+			"difference": offsetText(toOffset - fromOffset),
+			"day_change": dayChange(t, converted),
+		}
+		return result, nil
+
+	case "time.until":
+		var params struct {
+			Time     string `param:"time,required"`
+			Timezone string `param:"timezone"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		loc, err := e.zone(params.Timezone)
+		if err != nil {
+			return fail(err)
+		}
+		target, err := parseTime(params.Time, now, loc, true)
+		if err != nil {
+			return fail(err)
+		}
+		// Whole seconds, so "in 3 minutes" isn't 2m59.9s
+		d := target.Sub(now).Round(time.Second)
+		past := d < 0
+		if past {
+			d = -d
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"target":   clockAt(target, params.Timezone),
+			"seconds":  int64(d / time.Second),
+			"days":     int64(d / (24 * time.Hour)),
+			"hours":    int64(d % (24 * time.Hour) / time.Hour),
+			"minutes":  int64(d % time.Hour / time.Minute),
+			"duration": durationText(d),
+			"past":     past,
+		}
+		return result, nil
+
+	default:
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+		return result, nil
+	}
+}
+
+// zone resolves a name, or the home zone for none
+func (e *Executor) zone(name string) (*time.Location, error) {
+	if name == "" {
+		return e.home, nil
+	}
+	return e.zones.Resolve(name)
+}
+
+// timeLayouts are the absolute forms parseTime accepts after RFC 3339
+var timeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02 3:04pm",
+	"2006-01-02 3pm",
+}
+
+// clockLayouts are times of day
+var clockLayouts = []string{"15:04:05", "15:04", "3:04pm", "3pm"}
+
+// parseTime reads a timestamp, a date (its midnight), or a time of day in
+// loc; RFC 3339 keeps its own offset. A time of day is today's, or with
+// upcoming tomorrow's once today's has passed.
+func parseTime(s string, now time.Time, loc *time.Location, upcoming bool) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.In(loc), nil
+	}
+	normalized := strings.NewReplacer(" am", "am", " pm", "pm").Replace(strings.ToLower(s))
+	switch normalized {
+	case "noon", "midday":
+		normalized = "12:00"
+	case "midnight":
+		normalized = "00:00"
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, normalized, loc); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, normalized, loc); err == nil {
+		return t, nil
+	}
+	for _, layout := range clockLayouts {
+		clock, err := time.Parse(layout, normalized)
+		if err != nil {
+			continue
+		}
+		today := now.In(loc)
+		t := time.Date(today.Year(), today.Month(), today.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, loc)
+		if upcoming && !t.After(today) {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Time{}, agenterrors.Newf(agenterrors.InvalidParams, "can't read '%s' as a time (e.g. 2026-12-25, 15:30, 3pm, or RFC 3339)", s)
+}
+
+// offsetText writes an offset difference in seconds like "+7h" or "-3h30m"
+func offsetText(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	text := fmt.Sprintf("%s%dh", sign, seconds/3600)
+	if m := seconds % 3600 / 60; m != 0 {
+		text += fmt.Sprintf("%dm", m)
+	}
+	return text
+}
+
+// dayChange is how many calendar days the converted time is ahead (+1)
+// or behind (-1) the original
+func dayChange(from, to time.Time) int {
+	a := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	b := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a) / (24 * time.Hour))
+}
+
+// durationText writes a duration as days, hours, and minutes, e.g.
+// "2 days 3 hours 5 minutes", or seconds when under a minute
+func durationText(d time.Duration) string {
+	if d < time.Minute {
+		return plural(int64(d/time.Second), "second")
+	}
+	var parts []string
+	if days := int64(d / (24 * time.Hour)); days > 0 {
+		parts = append(parts, plural(days, "day"))
+	}
+	if hours := int64(d % (24 * time.Hour) / time.Hour); hours > 0 {
+		parts = append(parts, plural(hours, "hour"))
+	}
+	if minutes := int64(d % time.Hour / time.Minute); minutes > 0 {
+		parts = append(parts, plural(minutes, "minute"))
+	}
+	return strings.Join(parts, " ")
+}
+
+func plural(n int64, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+func (e *Executor) IsAvailable() bool {
+	return true
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"time.query": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"timezone": {"type": "string"},
+				"location": {"type": "string"},
+				"locations": {"type": "array", "items": {"type": "string"}}
+			}
+		}`),
+		"time.convert": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"time": {"type": "string"},
+				"from": {"type": "string"},
+				"to": {"type": "string"}
+			},
+			"required": ["to"]
+		}`),
+		"time.until": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"time": {"type": "string"},
+				"timezone": {"type": "string"}
+			},
+			"required": ["time"]
+		}`),
+	}
+}
@@ -0,0 +1,217 @@
+package clock
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// aliases maps abbreviations, US time zone names, and big cities that no
+// IANA zone is named after to zones
+var aliases = map[string]string{
+	"utc": "UTC", "gmt": "UTC", "z": "UTC",
+	"eastern": "America/New_York", "et": "America/New_York", "est": "America/New_York", "edt": "America/New_York",
+	"central": "America/Chicago", "ct": "America/Chicago", "cst": "America/Chicago", "cdt": "America/Chicago",
+	"mountain": "America/Denver", "mt": "America/Denver", "mst": "America/Denver", "mdt": "America/Denver",
+	"pacific": "America/Los_Angeles", "pt": "America/Los_Angeles", "pst": "America/Los_Angeles", "pdt": "America/Los_Angeles",
+	"akst": "America/Anchorage", "hst": "Pacific/Honolulu",
+	"bst": "Europe/London", "cet": "Europe/Paris", "cest": "Europe/Paris", "eet": "Europe/Athens", "eest": "Europe/Athens",
+	"ist": "Asia/Kolkata", "jst": "Asia/Tokyo", "kst": "Asia/Seoul", "hkt": "Asia/Hong_Kong", "sgt": "Asia/Singapore",
+	"aest": "Australia/Sydney", "aedt": "Australia/Sydney", "awst": "Australia/Perth", "nzst": "Pacific/Auckland", "nzdt": "Pacific/Auckland",
+
+	"washington": "America/New_York", "washington dc": "America/New_York", "boston": "America/New_York",
+	"philadelphia": "America/New_York", "atlanta": "America/New_York", "miami": "America/New_York",
+	"dallas": "America/Chicago", "houston": "America/Chicago", "austin": "America/Chicago", "san antonio": "America/Chicago",
+	"seattle": "America/Los_Angeles", "san francisco": "America/Los_Angeles", "san diego": "America/Los_Angeles",
+	"las vegas": "America/Los_Angeles", "portland": "America/Los_Angeles", "salt lake city": "America/Denver",
+	"montreal": "America/Toronto", "ottawa": "America/Toronto", "rio de janeiro": "America/Sao_Paulo",
+	"beijing": "Asia/Shanghai", "shenzhen": "Asia/Shanghai", "delhi": "Asia/Kolkata", "new delhi": "Asia/Kolkata",
+	"mumbai": "Asia/Kolkata", "bangalore": "Asia/Kolkata", "bengaluru": "Asia/Kolkata", "chennai": "Asia/Kolkata",
+	"hyderabad": "Asia/Kolkata", "osaka": "Asia/Tokyo", "kyoto": "Asia/Tokyo", "abu dhabi": "Asia/Dubai",
+	"munich": "Europe/Berlin", "frankfurt": "Europe/Berlin", "hamburg": "Europe/Berlin", "barcelona": "Europe/Madrid",
+	"milan": "Europe/Rome", "geneva": "Europe/Zurich", "st petersburg": "Europe/Moscow", "cape town": "Africa/Johannesburg",
+}
+
+// regions are the IANA areas tried for a bare city when the zone table
+// can't be read
+var regions = []string{"Europe", "America", "Asia", "Africa", "Australia", "Pacific", "Atlantic", "Indian", "Antarctica"}
+
+var offsetPattern = regexp.MustCompile(`^(?i:utc|gmt)?\s*([+-])(\d{1,2})(?::?(\d{2}))?$`)
+
+// zoneTable indexes the system's zone.tab: zones by lower-case name and
+// by city, and countries' zones by lower-case name and code
+type zoneTable struct {
+	byName    map[string]string
+	byCity    map[string]string
+	byCountry map[string][]string
+}
+
+// Zones resolves what people call a time zone: IANA names, abbreviations,
+// UTC offsets, cities, and countries
+type Zones struct {
+	// Dir is the zoneinfo directory holding zone.tab and iso3166.tab
+	// (default /usr/share/zoneinfo); without them, cities are found by
+	// trying IANA areas
+	Dir string
+
+	once  sync.Once
+	table zoneTable
+}
+
+// Resolve finds the zone for a name
+func (z *Zones) Resolve(name string) (*time.Location, error) {
+	query := strings.TrimSpace(name)
+	if query == "" {
+		return nil, agenterrors.New(agenterrors.InvalidParams, "empty time zone")
+	}
+	key := strings.Join(strings.Fields(strings.ToLower(strings.NewReplacer("_", " ", ".", "", ",", " ").Replace(query))), " ")
+
+	if m := offsetPattern.FindStringSubmatch(query); m != nil {
+		hours, _ := strconv.Atoi(m[2])
+		minutes, _ := strconv.Atoi(m[3])
+		if hours > 14 || minutes > 59 {
+			return nil, agenterrors.Newf(agenterrors.InvalidParams, "'%s' is not a UTC offset", name)
+		}
+		offset := hours*3600 + minutes*60
+		if m[1] == "-" {
+			offset = -offset
+		}
+		label := fmt.Sprintf("UTC%s%02d:%02d", m[1], hours, minutes)
+		return time.FixedZone(label, offset), nil
+	}
+	if zone, ok := aliases[key]; ok {
+		return time.LoadLocation(zone)
+	}
+	if strings.Contains(query, "/") {
+		if loc, err := time.LoadLocation(query); err == nil {
+			return loc, nil
+		}
+	}
+
+	t := z.load()
+	if zone, ok := t.byName[strings.ToLower(query)]; ok {
+		return time.LoadLocation(zone)
+	}
+	if zone, ok := t.byCity[key]; ok {
+		return time.LoadLocation(zone)
+	}
+	if zones, ok := t.byCountry[key]; ok {
+		// zone.tab lists a country's principal zone first, which will do
+		// when the others keep the same time (e.g. Germany's Busingen)
+		if loc, ok := sameTime(zones); ok {
+			return loc, nil
+		}
+		listed := zones
+		if len(listed) > 5 {
+			listed = append(listed[:5:5], "...")
+		}
+		return nil, agenterrors.Newf(agenterrors.InvalidParams, "%s spans several time zones (%s); name a city", query, strings.Join(listed, ", "))
+	}
+	if len(t.byCity) == 0 {
+		city := strings.ReplaceAll(titleCase(key), " ", "_")
+		for _, region := range regions {
+			if loc, err := time.LoadLocation(region + "/" + city); err == nil {
+				return loc, nil
+			}
+		}
+	}
+	return nil, agenterrors.Newf(agenterrors.NotFound, "unknown time zone or place '%s'", name)
+}
+
+// sameTime returns the first zone when all of them currently keep the
+// same time
+func sameTime(zones []string) (*time.Location, bool) {
+	var first *time.Location
+	var offset int
+	now := time.Now()
+	for _, zone := range zones {
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			continue
+		}
+		_, o := now.In(loc).Zone()
+		if first == nil {
+			first, offset = loc, o
+		} else if o != offset {
+			return nil, false
+		}
+	}
+	return first, first != nil
+}
+
+// load reads the zone and country tables once
+func (z *Zones) load() zoneTable {
+	z.once.Do(func() {
+		dir := z.Dir
+		if dir == "" {
+			dir = "/usr/share/zoneinfo"
+		}
+		z.table = zoneTable{
+			byName:    make(map[string]string),
+			byCity:    make(map[string]string),
+			byCountry: make(map[string][]string),
+		}
+		countries := make(map[string]string)
+		readTab(filepath.Join(dir, "iso3166.tab"), func(fields []string) {
+			if len(fields) >= 2 {
+				countries[fields[0]] = strings.ToLower(fields[1])
+			}
+		})
+		readTab(filepath.Join(dir, "zone.tab"), func(fields []string) {
+			if len(fields) < 3 {
+				return
+			}
+			code, zone := fields[0], fields[2]
+			z.table.byName[strings.ToLower(zone)] = zone
+			city := zone[strings.LastIndex(zone, "/")+1:]
+			city = strings.ToLower(strings.ReplaceAll(city, "_", " "))
+			if _, taken := z.table.byCity[city]; !taken {
+				z.table.byCity[city] = zone
+			}
+			z.table.byCountry[strings.ToLower(code)] = append(z.table.byCountry[strings.ToLower(code)], zone)
+			if name, ok := countries[code]; ok {
+				z.table.byCountry[name] = append(z.table.byCountry[name], zone)
+			}
+		})
+	})
+	return z.table
+}
+
+// readTab calls fn with the tab-separated fields of each line of a
+// zoneinfo table, skipping comments; a missing file is skipped
+func readTab(path string, fn func(fields []string)) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fn(strings.Split(line, "\t"))
+	}
+}
+
+// titleCase capitalizes words, leaving the joining words IANA keeps lower
+// case ("Port of Spain")
+func titleCase(s string) string {
+	words := strings.Fields(s)
+	for n, w := range words {
+		if n > 0 && (w == "of" || w == "es" || w == "de") {
+			continue
+		}
+		words[n] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,200 @@
+// Package convert handles deterministic computation the agent core should
+// not leave to its language model: unit conversion, currency conversion
+// with cached ECB reference rates, and safe arithmetic evaluation
+package convert
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Config controls where exchange rates come from
+type Config struct {
+	RatesURL  string        // defaults to ECBDailyURL
+	RatesFile string        // optional on-disk cache of the last fetched rates
+	RatesTTL  time.Duration // defaults to 12h
+}
+
+// Executor handles convert.unit, convert.currency, and convert.math
+type Executor struct {
+	rates *rateSource
+}
+
+// NewExecutor creates a convert executor
+func NewExecutor(cfg Config) *Executor {
+	if cfg.RatesURL == "" {
+		cfg.RatesURL = ECBDailyURL
+	}
+	if cfg.RatesTTL <= 0 {
+		cfg.RatesTTL = 12 * time.Hour
+	}
+	return &Executor{
+		rates: newRateSource(cfg.RatesURL, cfg.RatesFile, cfg.RatesTTL),
+	}
+}
+
+func (e *Executor) Name() string {
+	return "convert"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"convert.unit", "convert.currency", "convert.math"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{Network: true, Write: []string{filepath.Dir(e.rates.cachePath)}}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "convert",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	switch i.IntentType {
+	case "convert.unit":
+		var params struct {
+			Value float64 `param:"value,required"`
+			From  string  `param:"from,required"`
+			To    string  `param:"to,required"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		converted, from, to, err := convertUnit(params.Value, params.From, params.To)
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"value":     params.Value,
+			"from":      from.name,
+			"to":        to.name,
+			"dimension": from.dimension,
+			"result":    converted,
+			"formatted": format(converted) + " " + to.name,
+		}
+
+	case "convert.currency":
+		var params struct {
+			Amount float64 `param:"amount,required"`
+			From   string  `param:"from,required"`
+			To     string  `param:"to,required"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		rates, stale, err := e.rates.get(ctx)
+		if err != nil {
+			return fail(err)
+		}
+		from, to := currencyCode(params.From), currencyCode(params.To)
+		converted, err := rates.convert(params.Amount, from, to)
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"amount":     params.Amount,
+			"from":       from,
+			"to":         to,
+			"result":     converted,
+			"formatted":  strconv.FormatFloat(converted, 'f', 2, 64) + " " + to,
+			"rates_date": rates.Date,
+			"stale":      stale,
+		}
+
+	case "convert.math":
+		expression, err := i.StringParam("expression")
+		if err != nil {
+			return fail(err)
+		}
+		value, err := evaluate(expression)
+		if err != nil {
+			return fail(agenterrors.Newf(agenterrors.InvalidParams, "cannot evaluate expression: %w", err))
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"expression": expression,
+			"result":     value,
+			"formatted":  format(value),
+		}
+
+	default:
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+
+	return result, nil
+}
+
+func (e *Executor) IsAvailable() bool {
+	return true
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"convert.unit": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"value": {"type": "number"},
+				"from": {"type": "string", "minLength": 1},
+				"to": {"type": "string", "minLength": 1}
+			},
+			"required": ["value", "from", "to"]
+		}`),
+		"convert.currency": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"amount": {"type": "number"},
+				"from": {"type": "string", "minLength": 1},
+				"to": {"type": "string", "minLength": 1}
+			},
+			"required": ["amount", "from", "to"]
+		}`),
+		"convert.math": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"expression": {"type": "string", "minLength": 1, "maxLength": 256}
+			},
+			"required": ["expression"]
+		}`),
+	}
+}
+
+// format renders a number for speech: at most 6 decimals without trailing
+// zeros, and an exponent only for very large or very small magnitudes
+func format(v float64) string {
+	if v != 0 && (v > -1e-4 && v < 1e-4 || v > 1e15 || v < -1e15) {
+		return strconv.FormatFloat(v, 'g', 6, 64)
+	}
+	return trimZeros(strconv.FormatFloat(v, 'f', 6, 64))
+}
+
+// trimZeros drops trailing fractional zeros from a number formatted with 'f'
+func trimZeros(s string) string {
+	for len(s) > 0 && s[len(s)-1] == '0' {
+		s = s[:len(s)-1]
+	}
+	if len(s) > 0 && s[len(s)-1] == '.' {
+		s = s[:len(s)-1]
+	}
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
@@ -0,0 +1,177 @@
+package convert
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+)
+
+// ECBDailyURL publishes euro reference rates once per working day
+const ECBDailyURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+
+// Rates are euro reference rates for one day
+type Rates struct {
+	Date  string             `json:"date"`
+	Rates map[string]float64 `json:"rates"` // units of currency per euro
+}
+
+// convert converts amount between two currencies via the euro
+func (r *Rates) convert(amount float64, from, to string) (float64, error) {
+	fromRate, ok := r.rate(from)
+	if !ok {
+		return 0, agenterrors.Newf(agenterrors.InvalidParams, "no exchange rate for %s", from)
+	}
+	toRate, ok := r.rate(to)
+	if !ok {
+		return 0, agenterrors.Newf(agenterrors.InvalidParams, "no exchange rate for %s", to)
+	}
+	return amount / fromRate * toRate, nil
+}
+
+func (r *Rates) rate(code string) (float64, bool) {
+	if code == "EUR" {
+		return 1, true
+	}
+	rate, ok := r.Rates[code]
+	return rate, ok
+}
+
+// rateSource fetches ECB rates, caching them in memory and optionally on
+// disk so conversions keep working offline with the last known rates
+type rateSource struct {
+	url       string
+	cachePath string
+	ttl       time.Duration
+	client    *http.Client
+
+	mu      sync.Mutex
+	rates   *Rates
+	fetched time.Time
+}
+
+func newRateSource(url, cachePath string, ttl time.Duration) *rateSource {
+	s := &rateSource{
+		url:       url,
+		cachePath: cachePath,
+		ttl:       ttl,
+		client:    connpool.Default.HTTP(10 * time.Second),
+	}
+	if cachePath != "" {
+		if data, err := os.ReadFile(cachePath); err == nil {
+			var cached Rates
+			if json.Unmarshal(data, &cached) == nil && len(cached.Rates) > 0 {
+				s.rates = &cached
+				if info, err := os.Stat(cachePath); err == nil {
+					s.fetched = info.ModTime()
+				}
+			}
+		}
+	}
+	return s
+}
+
+// get returns current rates and whether they are stale (a refresh failed)
+func (s *rateSource) get(ctx context.Context) (*Rates, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.rates != nil && time.Since(s.fetched) < s.ttl {
+		return s.rates, false, nil
+	}
+
+	rates, err := s.fetch(ctx)
+	if err != nil {
+		if s.rates != nil {
+			return s.rates, true, nil
+		}
+		return nil, false, agenterrors.Newf(agenterrors.Unavailable, "exchange rates unavailable: %w", err)
+	}
+
+	s.rates = rates
+	s.fetched = time.Now()
+	if s.cachePath != "" {
+		if data, err := json.Marshal(rates); err == nil {
+			os.MkdirAll(filepath.Dir(s.cachePath), 0o700)
+			os.WriteFile(s.cachePath, data, 0o600)
+		}
+	}
+	return rates, false, nil
+}
+
+// ecbEnvelope is the eurofxref-daily.xml layout: Cube > Cube[time] > Cube[currency, rate]
+type ecbEnvelope struct {
+	Cube struct {
+		Day struct {
+			Time  string `xml:"time,attr"`
+			Rates []struct {
+				Currency string `xml:"currency,attr"`
+				Rate     string `xml:"rate,attr"`
+			} `xml:"Cube"`
+		} `xml:"Cube"`
+	} `xml:"Cube"`
+}
+
+func (s *rateSource) fetch(ctx context.Context) (*Rates, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rates feed returned %s", resp.Status)
+	}
+
+	var env ecbEnvelope
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&env); err != nil {
+		return nil, fmt.Errorf("invalid rates feed: %w", err)
+	}
+	rates := &Rates{Date: env.Cube.Day.Time, Rates: make(map[string]float64)}
+	for _, r := range env.Cube.Day.Rates {
+		if v, err := strconv.ParseFloat(r.Rate, 64); err == nil && v > 0 {
+			rates.Rates[strings.ToUpper(r.Currency)] = v
+		}
+	}
+	if len(rates.Rates) == 0 {
+		return nil, fmt.Errorf("rates feed contained no rates")
+	}
+	return rates, nil
+}
+
+// currencyNames maps spoken currency names to ISO codes
+var currencyNames = map[string]string{
+	"euro": "EUR", "euros": "EUR", "€": "EUR",
+	"dollar": "USD", "dollars": "USD", "us dollar": "USD", "us dollars": "USD", "$": "USD",
+	"pound": "GBP", "pounds": "GBP", "sterling": "GBP", "£": "GBP",
+	"yen": "JPY", "¥": "JPY",
+	"franc": "CHF", "francs": "CHF", "swiss franc": "CHF", "swiss francs": "CHF",
+	"rupee": "INR", "rupees": "INR", "₹": "INR",
+	"yuan": "CNY", "renminbi": "CNY",
+	"canadian dollar": "CAD", "canadian dollars": "CAD",
+	"australian dollar": "AUD", "australian dollars": "AUD",
+	"krona": "SEK", "kronor": "SEK", "krone": "NOK", "kroner": "NOK",
+	"zloty": "PLN", "real": "BRL", "reais": "BRL", "peso": "MXN", "pesos": "MXN",
+}
+
+func currencyCode(name string) string {
+	key := strings.ToLower(strings.Join(strings.Fields(name), " "))
+	if code, ok := currencyNames[key]; ok {
+		return code
+	}
+	return strings.ToUpper(strings.TrimSpace(name))
+}
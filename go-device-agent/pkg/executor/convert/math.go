@@ -0,0 +1,285 @@
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Limits that keep evaluation cheap whatever the input
+const (
+	maxExpressionLength = 256
+	maxDepth            = 32
+)
+
+var constants = map[string]float64{
+	"pi": math.Pi,
+	"e":  math.E,
+}
+
+var functions = map[string]func(args []float64) (float64, error){
+	"sqrt":  unary(math.Sqrt),
+	"abs":   unary(math.Abs),
+	"floor": unary(math.Floor),
+	"ceil":  unary(math.Ceil),
+	"round": unary(math.Round),
+	"ln":    unary(math.Log),
+	"log":   unary(math.Log10),
+	"sin":   unary(math.Sin),
+	"cos":   unary(math.Cos),
+	"tan":   unary(math.Tan),
+	"min":   variadic(math.Min),
+	"max":   variadic(math.Max),
+}
+
+func unary(fn func(float64) float64) func([]float64) (float64, error) {
+	return func(args []float64) (float64, error) {
+		if len(args) != 1 {
+			return 0, fmt.Errorf("expects 1 argument, got %d", len(args))
+		}
+		return fn(args[0]), nil
+	}
+}
+
+func variadic(fn func(a, b float64) float64) func([]float64) (float64, error) {
+	return func(args []float64) (float64, error) {
+		if len(args) == 0 {
+			return 0, errors.New("expects at least 1 argument")
+		}
+		v := args[0]
+		for _, a := range args[1:] {
+			v = fn(v, a)
+		}
+		return v, nil
+	}
+}
+
+// evaluate computes an arithmetic expression with + - * / % ^, parentheses,
+// the constants pi and e, and a fixed set of functions. Nothing else is
+// reachable, so untrusted input can't do more than burn a few microseconds.
+func evaluate(expr string) (float64, error) {
+	if len(expr) > maxExpressionLength {
+		return 0, fmt.Errorf("expression longer than %d characters", maxExpressionLength)
+	}
+	p := &parser{input: expr}
+	v, err := p.expression(0)
+	if err != nil {
+		return 0, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return 0, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos+1)
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, errors.New("result is not a finite number")
+	}
+	return v, nil
+}
+
+type parser struct {
+	input string
+	pos   int
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *parser) peek() byte {
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return p.input[p.pos]
+	}
+	return 0
+}
+
+// expression parses sums
+func (p *parser) expression(depth int) (float64, error) {
+	if depth > maxDepth {
+		return 0, errors.New("expression nested too deeply")
+	}
+	left, err := p.term(depth)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		switch p.peek() {
+		case '+':
+			p.pos++
+			right, err := p.term(depth)
+			if err != nil {
+				return 0, err
+			}
+			left += right
+		case '-':
+			p.pos++
+			right, err := p.term(depth)
+			if err != nil {
+				return 0, err
+			}
+			left -= right
+		default:
+			return left, nil
+		}
+	}
+}
+
+// term parses products
+func (p *parser) term(depth int) (float64, error) {
+	left, err := p.unary(depth)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' && op != '%' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.unary(depth)
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case '*':
+			left *= right
+		case '/':
+			if right == 0 {
+				return 0, errors.New("division by zero")
+			}
+			left /= right
+		case '%':
+			if right == 0 {
+				return 0, errors.New("division by zero")
+			}
+			left = math.Mod(left, right)
+		}
+	}
+}
+
+// unary parses signs, which bind looser than ^ so -2^2 is -4
+func (p *parser) unary(depth int) (float64, error) {
+	switch p.peek() {
+	case '-':
+		p.pos++
+		v, err := p.unary(depth + 1)
+		return -v, err
+	case '+':
+		p.pos++
+		return p.unary(depth + 1)
+	}
+	return p.power(depth)
+}
+
+// power parses right-associative exponentiation
+func (p *parser) power(depth int) (float64, error) {
+	base, err := p.primary(depth)
+	if err != nil {
+		return 0, err
+	}
+	if p.peek() != '^' {
+		return base, nil
+	}
+	p.pos++
+	exp, err := p.unary(depth + 1)
+	if err != nil {
+		return 0, err
+	}
+	return math.Pow(base, exp), nil
+}
+
+func (p *parser) primary(depth int) (float64, error) {
+	c := p.peek()
+	switch {
+	case c == '(':
+		p.pos++
+		v, err := p.expression(depth + 1)
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ')' {
+			return 0, errors.New("missing closing parenthesis")
+		}
+		p.pos++
+		return v, nil
+
+	case c == '.' || (c >= '0' && c <= '9'):
+		start := p.pos
+		for p.pos < len(p.input) && (p.input[p.pos] == '.' || (p.input[p.pos] >= '0' && p.input[p.pos] <= '9')) {
+			p.pos++
+		}
+		// Scientific notation such as 1.5e3
+		if p.pos < len(p.input) && (p.input[p.pos] == 'e' || p.input[p.pos] == 'E') &&
+			p.pos+1 < len(p.input) && strings.ContainsRune("0123456789+-", rune(p.input[p.pos+1])) {
+			p.pos += 2
+			for p.pos < len(p.input) && p.input[p.pos] >= '0' && p.input[p.pos] <= '9' {
+				p.pos++
+			}
+		}
+		v, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number %q", p.input[start:p.pos])
+		}
+		return v, nil
+
+	case unicode.IsLetter(rune(c)):
+		start := p.pos
+		for p.pos < len(p.input) && unicode.IsLetter(rune(p.input[p.pos])) {
+			p.pos++
+		}
+		name := strings.ToLower(p.input[start:p.pos])
+		if v, ok := constants[name]; ok {
+			return v, nil
+		}
+		fn, ok := functions[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown name %q", name)
+		}
+		args, err := p.arguments(depth)
+		if err != nil {
+			return 0, err
+		}
+		v, err := fn(args)
+		if err != nil {
+			return 0, fmt.Errorf("%s %v", name, err)
+		}
+		return v, nil
+
+	case c == 0:
+		return 0, errors.New("unexpected end of expression")
+	}
+	return 0, fmt.Errorf("unexpected %q at position %d", c, p.pos+1)
+}
+
+func (p *parser) arguments(depth int) ([]float64, error) {
+	if p.peek() != '(' {
+		return nil, errors.New("expected ( after function name")
+	}
+	p.pos++
+	var args []float64
+	if p.peek() == ')' {
+		p.pos++
+		return args, nil
+	}
+	for {
+		v, err := p.expression(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ')':
+			p.pos++
+			return args, nil
+		default:
+			return nil, errors.New("expected , or ) in function arguments")
+		}
+	}
+}
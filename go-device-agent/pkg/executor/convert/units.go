@@ -0,0 +1,130 @@
+package convert
+
+import (
+	"strings"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// unit converts to and from its dimension's base unit as base = value*factor + offset
+type unit struct {
+	name      string
+	dimension string
+	factor    float64
+	offset    float64
+}
+
+func (u unit) toBase(v float64) float64   { return v*u.factor + u.offset }
+func (u unit) fromBase(v float64) float64 { return (v - u.offset) / u.factor }
+
+// units maps every accepted spelling to its unit. Base units: metre,
+// kilogram, litre, kelvin, second, metre/second, square metre, byte,
+// joule, and pascal.
+var units = map[string]unit{}
+
+func define(dimension string, factor, offset float64, names ...string) {
+	u := unit{name: names[0], dimension: dimension, factor: factor, offset: offset}
+	for _, n := range names {
+		units[strings.ToLower(n)] = u
+	}
+}
+
+func init() {
+	define("length", 1, 0, "m", "meter", "meters", "metre", "metres")
+	define("length", 1e-3, 0, "mm", "millimeter", "millimeters", "millimetre", "millimetres")
+	define("length", 1e-2, 0, "cm", "centimeter", "centimeters", "centimetre", "centimetres")
+	define("length", 1e3, 0, "km", "kilometer", "kilometers", "kilometre", "kilometres")
+	define("length", 0.0254, 0, "in", "inch", "inches")
+	define("length", 0.3048, 0, "ft", "foot", "feet")
+	define("length", 0.9144, 0, "yd", "yard", "yards")
+	define("length", 1609.344, 0, "mi", "mile", "miles")
+	define("length", 1852, 0, "nmi", "nautical mile", "nautical miles")
+
+	define("mass", 1, 0, "kg", "kilogram", "kilograms", "kilo", "kilos")
+	define("mass", 1e-3, 0, "g", "gram", "grams")
+	define("mass", 1e-6, 0, "mg", "milligram", "milligrams")
+	define("mass", 1e3, 0, "t", "tonne", "tonnes", "metric ton", "metric tons")
+	define("mass", 0.45359237, 0, "lb", "lbs", "pound", "pounds")
+	define("mass", 0.028349523125, 0, "oz", "ounce", "ounces")
+	define("mass", 6.35029318, 0, "st", "stone", "stones")
+
+	define("volume", 1, 0, "l", "liter", "liters", "litre", "litres")
+	define("volume", 1e-3, 0, "ml", "milliliter", "milliliters", "millilitre", "millilitres")
+	define("volume", 1e-2, 0, "cl", "centiliter", "centiliters", "centilitre", "centilitres")
+	define("volume", 1e3, 0, "m3", "cubic meter", "cubic meters", "cubic metre", "cubic metres")
+	define("volume", 3.785411784, 0, "gal", "gallon", "gallons", "us gallon", "us gallons")
+	define("volume", 4.54609, 0, "imp gal", "imperial gallon", "imperial gallons")
+	define("volume", 0.946352946, 0, "qt", "quart", "quarts")
+	define("volume", 0.473176473, 0, "pt", "pint", "pints")
+	define("volume", 0.2365882365, 0, "cup", "cups")
+	define("volume", 0.0295735295625, 0, "fl oz", "fluid ounce", "fluid ounces")
+	define("volume", 0.01478676478125, 0, "tbsp", "tablespoon", "tablespoons")
+	define("volume", 0.00492892159375, 0, "tsp", "teaspoon", "teaspoons")
+
+	define("temperature", 1, 0, "K", "kelvin")
+	define("temperature", 1, 273.15, "°C", "c", "celsius", "degrees celsius", "centigrade")
+	define("temperature", 5.0/9.0, 273.15-32*5.0/9.0, "°F", "f", "fahrenheit", "degrees fahrenheit")
+
+	define("time", 1, 0, "s", "sec", "secs", "second", "seconds")
+	define("time", 1e-3, 0, "ms", "millisecond", "milliseconds")
+	define("time", 60, 0, "min", "mins", "minute", "minutes")
+	define("time", 3600, 0, "h", "hr", "hrs", "hour", "hours")
+	define("time", 86400, 0, "d", "day", "days")
+	define("time", 604800, 0, "wk", "week", "weeks")
+
+	define("speed", 1, 0, "m/s", "meters per second", "metres per second")
+	define("speed", 1/3.6, 0, "km/h", "kph", "kmh", "kilometers per hour", "kilometres per hour")
+	define("speed", 0.44704, 0, "mph", "miles per hour")
+	define("speed", 1852.0/3600, 0, "kn", "knot", "knots")
+
+	define("area", 1, 0, "m2", "square meter", "square meters", "square metre", "square metres")
+	define("area", 1e6, 0, "km2", "square kilometer", "square kilometers", "square kilometre", "square kilometres")
+	define("area", 0.09290304, 0, "ft2", "sq ft", "square foot", "square feet")
+	define("area", 4046.8564224, 0, "acre", "acres")
+	define("area", 1e4, 0, "ha", "hectare", "hectares")
+
+	define("data", 1, 0, "b", "byte", "bytes")
+	define("data", 1e3, 0, "kb", "kilobyte", "kilobytes")
+	define("data", 1e6, 0, "mb", "megabyte", "megabytes")
+	define("data", 1e9, 0, "gb", "gigabyte", "gigabytes")
+	define("data", 1e12, 0, "tb", "terabyte", "terabytes")
+	define("data", 1<<10, 0, "kib", "kibibyte", "kibibytes")
+	define("data", 1<<20, 0, "mib", "mebibyte", "mebibytes")
+	define("data", 1<<30, 0, "gib", "gibibyte", "gibibytes")
+
+	define("energy", 1, 0, "j", "joule", "joules")
+	define("energy", 1e3, 0, "kj", "kilojoule", "kilojoules")
+	define("energy", 4184, 0, "kcal", "kilocalorie", "kilocalories", "calorie", "calories")
+	define("energy", 3.6e6, 0, "kwh", "kilowatt hour", "kilowatt hours")
+
+	define("pressure", 1, 0, "pa", "pascal", "pascals")
+	define("pressure", 1e3, 0, "kpa", "kilopascal", "kilopascals")
+	define("pressure", 1e5, 0, "bar")
+	define("pressure", 100, 0, "hpa", "mbar", "millibar")
+	define("pressure", 6894.757293168, 0, "psi")
+	define("pressure", 101325, 0, "atm", "atmosphere", "atmospheres")
+}
+
+func lookupUnit(name string) (unit, error) {
+	key := strings.ToLower(strings.Join(strings.Fields(name), " "))
+	if u, ok := units[key]; ok {
+		return u, nil
+	}
+	return unit{}, agenterrors.Newf(agenterrors.InvalidParams, "unknown unit: %s", name)
+}
+
+// convertUnit converts value between two units of the same dimension
+func convertUnit(value float64, from, to string) (float64, unit, unit, error) {
+	src, err := lookupUnit(from)
+	if err != nil {
+		return 0, unit{}, unit{}, err
+	}
+	dst, err := lookupUnit(to)
+	if err != nil {
+		return 0, unit{}, unit{}, err
+	}
+	if src.dimension != dst.dimension {
+		return 0, unit{}, unit{}, agenterrors.Newf(agenterrors.InvalidParams, "cannot convert %s (%s) to %s (%s)", src.name, src.dimension, dst.name, dst.dimension)
+	}
+	return dst.fromBase(src.toBase(value)), src, dst, nil
+}
@@ -0,0 +1,283 @@
+// Package deliveries tracks packages by polling carrier notification emails
+// from allowlisted IMAP folders and answers deliveries.query intents
+package deliveries
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/internal/imap"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Config configures the mailbox to poll
+type Config struct {
+	// Server is the IMAPS host, with optional port (default 993)
+	Server   string
+	Username string
+	Password string
+
+	// Folders are the only folders the executor may read (default INBOX)
+	Folders []string
+
+	// PollInterval between mailbox checks (default 15m)
+	PollInterval time.Duration
+
+	// LookbackDays is how far back notifications are considered (default 7)
+	LookbackDays int
+}
+
+// Executor handles deliveries.query and polls the mailbox in the background
+type Executor struct {
+	cfg Config
+	bus *events.Bus
+
+	mu         sync.RWMutex
+	deliveries map[string]Delivery
+	announced  map[string]string // delivery key -> date of last "arriving today" event
+	lastPoll   time.Time
+	lastError  string
+}
+
+// NewExecutor creates a deliveries executor publishing events to bus
+func NewExecutor(cfg Config, bus *events.Bus) *Executor {
+	if len(cfg.Folders) == 0 {
+		cfg.Folders = []string{"INBOX"}
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 15 * time.Minute
+	}
+	if cfg.LookbackDays <= 0 {
+		cfg.LookbackDays = 7
+	}
+	return &Executor{
+		cfg:        cfg,
+		bus:        bus,
+		deliveries: make(map[string]Delivery),
+		announced:  make(map[string]string),
+	}
+}
+
+// Start polls the mailbox immediately and then every PollInterval until ctx is cancelled
+func (e *Executor) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(e.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			e.Poll(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Poll checks every allowlisted folder once
+func (e *Executor) Poll(ctx context.Context) error {
+	err := e.poll(ctx)
+
+	e.mu.Lock()
+	e.lastPoll = time.Now()
+	e.lastError = ""
+	if err != nil {
+		e.lastError = err.Error()
+	}
+	e.mu.Unlock()
+
+	if err == nil {
+		e.announceArrivals()
+	}
+	return err
+}
+
+func (e *Executor) poll(ctx context.Context) error {
+	if e.cfg.Server == "" {
+		return agenterrors.New(agenterrors.Unavailable, "no IMAP server configured")
+	}
+
+	if err := connpool.Default.CheckEgress(e.cfg.Server); err != nil {
+		return agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	client, err := imap.Dial(e.cfg.Server, 30*time.Second)
+	if err != nil {
+		return agenterrors.Newf(agenterrors.Unavailable, "failed to connect to %s: %w", e.cfg.Server, err)
+	}
+	defer client.Close()
+
+	if err := client.Login(e.cfg.Username, e.cfg.Password); err != nil {
+		return err
+	}
+
+	since := time.Now().AddDate(0, 0, -e.cfg.LookbackDays)
+	found := make(map[string]Delivery)
+	for _, folder := range e.cfg.Folders {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := client.Examine(folder); err != nil {
+			return fmt.Errorf("folder %s: %w", folder, err)
+		}
+		uids, err := client.SearchSince(since)
+		if err != nil {
+			return fmt.Errorf("folder %s: %w", folder, err)
+		}
+		// Only the newest messages matter for current status
+		if len(uids) > 100 {
+			uids = uids[len(uids)-100:]
+		}
+		messages, err := client.Fetch(uids, 16*1024)
+		if err != nil {
+			return fmt.Errorf("folder %s: %w", folder, err)
+		}
+		for _, m := range messages {
+			d, ok := parseMessage(m)
+			if !ok {
+				continue
+			}
+			key := deliveryKey(d)
+			// Later notifications supersede earlier ones for the same package
+			if prev, ok := found[key]; !ok || d.ReceivedAt.After(prev.ReceivedAt) {
+				found[key] = d
+			}
+		}
+	}
+
+	e.mu.Lock()
+	e.deliveries = found
+	e.mu.Unlock()
+	return nil
+}
+
+// announceArrivals publishes one event per package arriving today
+func (e *Executor) announceArrivals() {
+	today := time.Now().Format("2006-01-02")
+
+	var arriving []Delivery
+	e.mu.Lock()
+	for key, d := range e.deliveries {
+		if d.Status == StatusDelivered || d.ExpectedDate != today || e.announced[key] == today {
+			continue
+		}
+		e.announced[key] = today
+		arriving = append(arriving, d)
+	}
+	e.mu.Unlock()
+
+	if e.bus == nil {
+		return
+	}
+	for _, d := range arriving {
+		e.bus.Publish(events.Event{
+			Type:   "deliveries.arriving_today",
+			Source: "deliveries",
+			Data: map[string]interface{}{
+				"carrier":         d.Carrier,
+				"tracking_number": d.TrackingNumber,
+				"status":          d.Status,
+				"subject":         d.Subject,
+			},
+		})
+	}
+}
+
+func (e *Executor) Name() string {
+	return "deliveries"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"deliveries.query"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{Network: true}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "deliveries",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	if i.IntentType != "deliveries.query" {
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+		return result, nil
+	}
+
+	filter, err := i.StringParamOr("filter", "pending")
+	if err != nil {
+		result.Fail(err)
+		return result, nil
+	}
+	today := time.Now().Format("2006-01-02")
+
+	e.mu.RLock()
+	matched := make([]Delivery, 0, len(e.deliveries))
+	for _, d := range e.deliveries {
+		switch filter {
+		case "today":
+			if d.ExpectedDate != today || d.Status == StatusDelivered {
+				continue
+			}
+		case "pending":
+			if d.Status == StatusDelivered {
+				continue
+			}
+		}
+		matched = append(matched, d)
+	}
+	lastPoll, lastError := e.lastPoll, e.lastError
+	e.mu.RUnlock()
+
+	sort.Slice(matched, func(a, b int) bool {
+		return matched[a].ReceivedAt.After(matched[b].ReceivedAt)
+	})
+
+	result.Success = true
+	result.Result = map[string]interface{}{
+		"filter":     filter,
+		"count":      len(matched),
+		"deliveries": matched,
+	}
+	if !lastPoll.IsZero() {
+		result.Result["last_checked"] = lastPoll.Format(time.RFC3339)
+	}
+	if lastError != "" {
+		result.Result["last_error"] = lastError
+	}
+	return result, nil
+}
+
+func (e *Executor) IsAvailable() bool {
+	return e.cfg.Server != "" && connpool.Default.Reachable(e.cfg.Server)
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"deliveries.query": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"filter": {"type": "string", "enum": ["pending", "today", "all"]}
+			}
+		}`),
+	}
+}
+
+func deliveryKey(d Delivery) string {
+	if d.TrackingNumber != "" {
+		return d.Carrier + ":" + d.TrackingNumber
+	}
+	return d.Carrier + ":" + d.Subject
+}
@@ -0,0 +1,156 @@
+package deliveries
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/internal/imap"
+)
+
+// Delivery statuses, in the order a package moves through them
+const (
+	StatusShipped        = "shipped"
+	StatusArriving       = "arriving"
+	StatusOutForDelivery = "out_for_delivery"
+	StatusDelivered      = "delivered"
+)
+
+// Delivery is a package parsed from a carrier notification
+type Delivery struct {
+	Carrier        string    `json:"carrier"`
+	TrackingNumber string    `json:"tracking_number,omitempty"`
+	Status         string    `json:"status"`
+	ExpectedDate   string    `json:"expected_date,omitempty"`
+	Subject        string    `json:"subject"`
+	From           string    `json:"from"`
+	ReceivedAt     time.Time `json:"received_at"`
+}
+
+// carrier recognises notifications from one shipping company
+type carrier struct {
+	name     string
+	domains  []string
+	tracking *regexp.Regexp
+}
+
+var carriers = []carrier{
+	{name: "ups", domains: []string{"ups.com"}, tracking: regexp.MustCompile(`\b1Z[0-9A-Z]{16}\b`)},
+	{name: "fedex", domains: []string{"fedex.com"}, tracking: regexp.MustCompile(`\b(\d{12}|\d{15})\b`)},
+	{name: "usps", domains: []string{"usps.com"}, tracking: regexp.MustCompile(`\b9[2-5]\d{20}\b`)},
+	{name: "dhl", domains: []string{"dhl.com", "dhl.de"}, tracking: regexp.MustCompile(`\b\d{10}\b`)},
+	{name: "amazon", domains: []string{"amazon.com", "amazon.co.uk", "amazon.de", "amazon.in"}, tracking: regexp.MustCompile(`\bTBA\d{12}\b`)},
+	{name: "royalmail", domains: []string{"royalmail.com"}, tracking: regexp.MustCompile(`\b[A-Z]{2}\d{9}GB\b`)},
+}
+
+var (
+	htmlTag    = regexp.MustCompile(`<[^>]*>`)
+	whitespace = regexp.MustCompile(`\s+`)
+)
+
+// statusPhrases map notification wording to a status, most specific first
+var statusPhrases = []struct {
+	phrase string
+	status string
+	days   int
+}{
+	{"has been delivered", StatusDelivered, 0},
+	{"was delivered", StatusDelivered, 0},
+	{"out for delivery", StatusOutForDelivery, 0},
+	{"arriving today", StatusArriving, 0},
+	{"arrives today", StatusArriving, 0},
+	{"will arrive today", StatusArriving, 0},
+	{"arriving tomorrow", StatusArriving, 1},
+	{"arrives tomorrow", StatusArriving, 1},
+	{"will arrive tomorrow", StatusArriving, 1},
+	{"has shipped", StatusShipped, -1},
+	{"shipped", StatusShipped, -1},
+	{"on its way", StatusShipped, -1},
+}
+
+// parseMessage extracts a delivery from a carrier notification.
+// It returns false for mail that isn't from a known carrier.
+func parseMessage(m imap.Message) (Delivery, bool) {
+	header, err := mail.ReadMessage(io.MultiReader(bytes.NewReader(m.Header), strings.NewReader("\r\n")))
+	if err != nil {
+		return Delivery{}, false
+	}
+
+	from := header.Header.Get("From")
+	c, ok := matchCarrier(from)
+	if !ok {
+		return Delivery{}, false
+	}
+
+	subject := decodeHeader(header.Header.Get("Subject"))
+	received, err := header.Header.Date()
+	if err != nil {
+		received = time.Now()
+	}
+
+	text := subject + " " + plainText(m.Text)
+	lower := strings.ToLower(text)
+
+	d := Delivery{
+		Carrier:    c.name,
+		Subject:    subject,
+		From:       from,
+		ReceivedAt: received,
+		Status:     StatusShipped,
+	}
+	if match := c.tracking.FindString(text); match != "" {
+		d.TrackingNumber = match
+	}
+	for _, p := range statusPhrases {
+		if strings.Contains(lower, p.phrase) {
+			d.Status = p.status
+			if p.days >= 0 {
+				d.ExpectedDate = received.AddDate(0, 0, p.days).Format("2006-01-02")
+			}
+			break
+		}
+	}
+	return d, true
+}
+
+func matchCarrier(from string) (carrier, bool) {
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		return carrier{}, false
+	}
+	domain := strings.ToLower(addr.Address[strings.LastIndex(addr.Address, "@")+1:])
+	for _, c := range carriers {
+		for _, d := range c.domains {
+			if domain == d || strings.HasSuffix(domain, "."+d) {
+				return c, true
+			}
+		}
+	}
+	return carrier{}, false
+}
+
+func decodeHeader(s string) string {
+	decoded, err := new(mime.WordDecoder).DecodeHeader(s)
+	if err != nil {
+		return s
+	}
+	return decoded
+}
+
+// plainText turns a raw message body into searchable text. Bodies are
+// often quoted-printable HTML, so soft line breaks are undone and tags
+// stripped; exact MIME structure doesn't matter for phrase matching.
+func plainText(body []byte) string {
+	if bytes.Contains(body, []byte("=\r\n")) || bytes.Contains(body, []byte("=3D")) {
+		if decoded, err := io.ReadAll(quotedprintable.NewReader(bytes.NewReader(body))); err == nil {
+			body = decoded
+		}
+	}
+	text := htmlTag.ReplaceAllString(string(body), " ")
+	return whitespace.ReplaceAllString(text, " ")
+}
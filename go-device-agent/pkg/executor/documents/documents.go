@@ -0,0 +1,286 @@
+// Package documents OCRs scans and PDFs dropped into a watched folder,
+// keeps their text in a local search index, and answers documents.search
+// with snippets and file references. Nothing leaves the machine.
+package documents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Config configures the watched folder and OCR tools
+type Config struct {
+	// Dir is the folder scanned for documents, including subfolders
+	Dir string
+
+	// IndexFile stores extracted text so restarts don't re-OCR everything
+	IndexFile string
+
+	// Languages passed to tesseract's -l option (default "eng")
+	Languages string
+
+	// PollInterval between folder scans (default 30s)
+	PollInterval time.Duration
+
+	// MaxResults caps documents.search (default 5, hard cap 20)
+	MaxResults int
+}
+
+// maxResultsCap bounds search results regardless of configuration
+const maxResultsCap = 20
+
+// Executor handles documents.search and runs the folder watcher
+type Executor struct {
+	cfg   Config
+	ocr   Extractor
+	index *index
+
+	mu        sync.Mutex
+	running   bool
+	lastScan  time.Time
+	lastError string
+	failed    map[string]string // path -> extraction error, until the file changes
+}
+
+// NewExecutor creates a documents executor, loading any saved index
+func NewExecutor(cfg Config) (*Executor, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("documents folder not configured")
+	}
+	if cfg.Languages == "" {
+		cfg.Languages = "eng"
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 30 * time.Second
+	}
+	if cfg.MaxResults <= 0 {
+		cfg.MaxResults = 5
+	}
+	if cfg.MaxResults > maxResultsCap {
+		cfg.MaxResults = maxResultsCap
+	}
+
+	idx, err := loadIndex(cfg.IndexFile)
+	if err != nil {
+		return nil, err
+	}
+	return &Executor{
+		cfg:    cfg,
+		ocr:    NewTesseract(cfg.Languages),
+		index:  idx,
+		failed: make(map[string]string),
+	}, nil
+}
+
+// SetExtractor replaces the text extractor
+func (e *Executor) SetExtractor(x Extractor) {
+	e.ocr = x
+}
+
+// Start scans the folder now and then every PollInterval until ctx is
+// cancelled
+func (e *Executor) Start(ctx context.Context) error {
+	e.mu.Lock()
+	if e.running {
+		e.mu.Unlock()
+		return errors.New("document watcher already running")
+	}
+	e.running = true
+	e.mu.Unlock()
+
+	if err := os.MkdirAll(e.cfg.Dir, 0o700); err != nil {
+		e.mu.Lock()
+		e.running = false
+		e.mu.Unlock()
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(e.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			e.Scan(ctx)
+			select {
+			case <-ctx.Done():
+				e.mu.Lock()
+				e.running = false
+				e.mu.Unlock()
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// Scan indexes new and changed documents and drops deleted ones
+func (e *Executor) Scan(ctx context.Context) {
+	seen := make(map[string]bool)
+	err := filepath.WalkDir(e.cfg.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // unreadable entries are skipped, not fatal
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			if path != e.cfg.Dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !supported(path) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		seen[path] = true
+		if e.index.current(path, info) {
+			return nil
+		}
+
+		key := fmt.Sprintf("%d/%d", info.Size(), info.ModTime().UnixNano())
+		e.mu.Lock()
+		failedKey, failed := e.failed[path]
+		e.mu.Unlock()
+		if failed && failedKey == key {
+			return nil
+		}
+
+		text, err := e.ocr.Extract(ctx, path)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			e.mu.Lock()
+			e.failed[path] = key
+			e.lastError = fmt.Sprintf("%s: %v", filepath.Base(path), err)
+			e.mu.Unlock()
+			return nil
+		}
+		e.index.put(path, info, text)
+		return nil
+	})
+
+	e.index.prune(seen)
+	if saveErr := e.index.save(); saveErr != nil && err == nil {
+		err = saveErr
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lastScan = time.Now()
+	if err != nil && ctx.Err() == nil {
+		e.lastError = err.Error()
+	}
+}
+
+func (e *Executor) Name() string {
+	return "documents"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"documents.search"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{
+		Read:       []string{e.cfg.Dir},
+		Write:      []string{filepath.Dir(e.cfg.IndexFile)},
+		Subprocess: true, // tesseract and pdftotext
+	}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "documents",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	if i.IntentType != "documents.search" {
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+		return result, nil
+	}
+
+	var params struct {
+		Query string `param:"query,required"`
+		Limit int    `param:"limit"`
+	}
+	if err := i.DecodeParams(&params); err != nil {
+		result.Fail(err)
+		return result, nil
+	}
+	limit := params.Limit
+	if limit <= 0 || limit > e.cfg.MaxResults {
+		limit = e.cfg.MaxResults
+	}
+
+	hits := e.index.search(params.Query, limit)
+	matches := make([]map[string]interface{}, 0, len(hits))
+	for _, h := range hits {
+		rel, err := filepath.Rel(e.cfg.Dir, h.Path)
+		if err != nil {
+			rel = h.Path
+		}
+		matches = append(matches, map[string]interface{}{
+			"file":     rel,
+			"path":     h.Path,
+			"snippet":  h.Snippet,
+			"score":    h.Score,
+			"modified": h.Modified.Format(time.RFC3339),
+		})
+	}
+
+	e.mu.Lock()
+	lastScan, lastError := e.lastScan, e.lastError
+	e.mu.Unlock()
+
+	result.Success = true
+	result.Result = map[string]interface{}{
+		"query":     params.Query,
+		"results":   matches,
+		"count":     len(matches),
+		"documents": e.index.size(),
+	}
+	if !lastScan.IsZero() {
+		result.Result["indexed_at"] = lastScan.Format(time.RFC3339)
+	}
+	if lastError != "" {
+		result.Result["last_error"] = lastError
+	}
+	return result, nil
+}
+
+func (e *Executor) IsAvailable() bool {
+	return e.ocr.Available() || e.index.size() > 0
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"documents.search": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"query": {"type": "string", "minLength": 1, "maxLength": 200},
+				"limit": {"type": "integer", "minimum": 1, "maximum": 20}
+			},
+			"required": ["query"]
+		}`),
+	}
+}
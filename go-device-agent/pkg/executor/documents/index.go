@@ -0,0 +1,296 @@
+package documents
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Limits that keep the index and results small
+const (
+	maxTextBytes = 1 << 20
+	snippetChars = 160
+)
+
+// document is one indexed file
+type document struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	Text     string    `json:"text"`
+
+	terms  map[string]int
+	length int
+}
+
+// hit is one search result
+type hit struct {
+	Path     string
+	Modified time.Time
+	Snippet  string
+	Score    float64
+}
+
+// index is an in-memory BM25 index over document text, saved as JSON
+type index struct {
+	path  string
+	mu    sync.RWMutex
+	docs  map[string]*document
+	dirty bool
+}
+
+func loadIndex(path string) (*index, error) {
+	idx := &index{path: path, docs: make(map[string]*document)}
+	if path == "" {
+		return idx, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var docs []*document
+	if err := json.Unmarshal(data, &docs); err != nil {
+		return nil, fmt.Errorf("invalid document index %s: %w", path, err)
+	}
+	for _, d := range docs {
+		d.analyze()
+		idx.docs[d.Path] = d
+	}
+	return idx, nil
+}
+
+func (d *document) analyze() {
+	d.terms = make(map[string]int)
+	d.length = 0
+	for _, t := range tokenize(d.Text) {
+		d.terms[t]++
+		d.length++
+	}
+}
+
+// current reports whether path is indexed at its present size and mtime
+func (idx *index) current(path string, info os.FileInfo) bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	d, ok := idx.docs[path]
+	return ok && d.Size == info.Size() && d.Modified.Equal(info.ModTime())
+}
+
+func (idx *index) put(path string, info os.FileInfo, text string) {
+	if len(text) > maxTextBytes {
+		text = strings.ToValidUTF8(text[:maxTextBytes], "")
+	}
+	d := &document{Path: path, Size: info.Size(), Modified: info.ModTime(), Text: text}
+	d.analyze()
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.docs[path] = d
+	idx.dirty = true
+}
+
+// prune drops documents whose files are gone
+func (idx *index) prune(seen map[string]bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for path := range idx.docs {
+		if !seen[path] {
+			delete(idx.docs, path)
+			idx.dirty = true
+		}
+	}
+}
+
+func (idx *index) size() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+// save writes the index atomically if it changed since the last save
+func (idx *index) save() error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.path == "" || !idx.dirty {
+		return nil
+	}
+
+	docs := make([]*document, 0, len(idx.docs))
+	for _, d := range idx.docs {
+		docs = append(docs, d)
+	}
+	sort.Slice(docs, func(a, b int) bool { return docs[a].Path < docs[b].Path })
+	data, err := json.Marshal(docs)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0o700); err != nil {
+		return err
+	}
+	tmp := idx.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, idx.path); err != nil {
+		return err
+	}
+	idx.dirty = false
+	return nil
+}
+
+// search ranks documents by BM25 over the query terms, with a bonus when
+// the query appears verbatim
+func (idx *index) search(query string, limit int) []hit {
+	terms := tokenize(query)
+	if len(terms) == 0 {
+		return nil
+	}
+	phrase := strings.ToLower(strings.Join(strings.Fields(query), " "))
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	n := float64(len(idx.docs))
+	if n == 0 {
+		return nil
+	}
+	var total int
+	df := make(map[string]int, len(terms))
+	for _, d := range idx.docs {
+		total += d.length
+		for _, t := range terms {
+			if d.terms[t] > 0 {
+				df[t]++
+			}
+		}
+	}
+	avgLength := float64(total) / n
+
+	const k1, b = 1.2, 0.75
+	var hits []hit
+	for _, d := range idx.docs {
+		var score float64
+		for _, t := range terms {
+			tf := float64(d.terms[t])
+			if tf == 0 {
+				continue
+			}
+			idf := math.Log(1 + (n-float64(df[t])+0.5)/(float64(df[t])+0.5))
+			score += idf * tf * (k1 + 1) / (tf + k1*(1-b+b*float64(d.length)/avgLength))
+		}
+		if score == 0 {
+			continue
+		}
+		lower := strings.ToLower(d.Text)
+		if len(terms) > 1 && strings.Contains(collapse(lower), phrase) {
+			score *= 1.5
+		}
+		hits = append(hits, hit{
+			Path:     d.Path,
+			Modified: d.Modified,
+			Snippet:  snippet(d.Text, lower, terms),
+			Score:    math.Round(score*1000) / 1000,
+		})
+	}
+
+	sort.Slice(hits, func(a, b int) bool {
+		if hits[a].Score != hits[b].Score {
+			return hits[a].Score > hits[b].Score
+		}
+		return hits[a].Modified.After(hits[b].Modified)
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// tokenize lowercases text and splits it into words of two or more letters
+// or digits
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tokens := fields[:0]
+	for _, f := range fields {
+		if len([]rune(f)) >= 2 {
+			tokens = append(tokens, f)
+		}
+	}
+	return tokens
+}
+
+// snippet returns about snippetChars of text around the first query term
+func snippet(text, lower string, terms []string) string {
+	pos := -1
+	for _, t := range terms {
+		if p := indexWord(lower, t); p >= 0 && (pos < 0 || p < pos) {
+			pos = p
+		}
+	}
+	// Lowercasing can change byte lengths, so keep pos inside text
+	if pos < 0 || pos > len(text) {
+		pos = 0
+	}
+
+	start := pos - snippetChars/3
+	if start < 0 {
+		start = 0
+	}
+	end := start + snippetChars
+	if end > len(text) {
+		end = len(text)
+	}
+	// Widen to whole words
+	for start > 0 && !unicode.IsSpace(rune(text[start-1])) {
+		start--
+	}
+	for end < len(text) && !unicode.IsSpace(rune(text[end])) {
+		end++
+	}
+
+	s := collapse(strings.ToValidUTF8(text[start:end], ""))
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(text) {
+		s += "…"
+	}
+	return s
+}
+
+// indexWord finds term at a word boundary in lower
+func indexWord(lower, term string) int {
+	for offset := 0; ; {
+		p := strings.Index(lower[offset:], term)
+		if p < 0 {
+			return -1
+		}
+		p += offset
+		if p == 0 || !isWordByte(lower[p-1]) {
+			return p
+		}
+		offset = p + len(term)
+	}
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// collapse replaces runs of whitespace, including OCR line breaks, with a
+// single space
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
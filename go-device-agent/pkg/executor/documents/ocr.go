@@ -0,0 +1,111 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Extractor turns a document into plain text
+type Extractor interface {
+	Extract(ctx context.Context, path string) (string, error)
+
+	// Available reports whether the tools the extractor needs are installed
+	Available() bool
+}
+
+// imageExtensions are passed straight to tesseract
+var imageExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".tif": true, ".tiff": true,
+	".bmp": true, ".gif": true, ".webp": true, ".pnm": true,
+}
+
+func supported(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".pdf" || imageExtensions[ext]
+}
+
+// minPDFText is how much embedded text a PDF needs before its pages are
+// treated as text rather than scans to OCR
+const minPDFText = 32
+
+// tesseract OCRs images with the tesseract CLI. PDFs use their embedded
+// text (pdftotext) when present and are otherwise rasterised page by page
+// (pdftoppm) and OCRed.
+type tesseract struct {
+	languages string
+}
+
+// NewTesseract returns an extractor using the tesseract and poppler CLIs
+func NewTesseract(languages string) Extractor {
+	return &tesseract{languages: languages}
+}
+
+func (t *tesseract) Available() bool {
+	_, err := exec.LookPath("tesseract")
+	return err == nil
+}
+
+func (t *tesseract) Extract(ctx context.Context, path string) (string, error) {
+	if strings.EqualFold(filepath.Ext(path), ".pdf") {
+		return t.pdf(ctx, path)
+	}
+	return t.image(ctx, path)
+}
+
+func (t *tesseract) image(ctx context.Context, path string) (string, error) {
+	return run(ctx, "tesseract", path, "stdout", "-l", t.languages)
+}
+
+func (t *tesseract) pdf(ctx context.Context, path string) (string, error) {
+	if text, err := run(ctx, "pdftotext", "-layout", path, "-"); err == nil && len(strings.TrimSpace(text)) >= minPDFText {
+		return text, nil
+	}
+
+	dir, err := os.MkdirTemp("", "documents-ocr-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := run(ctx, "pdftoppm", "-r", "300", "-png", path, filepath.Join(dir, "page")); err != nil {
+		return "", err
+	}
+	pages, err := filepath.Glob(filepath.Join(dir, "page*.png"))
+	if err != nil {
+		return "", err
+	}
+	// pdftoppm zero-pads page numbers, so lexical order is page order
+	sort.Strings(pages)
+
+	var text strings.Builder
+	for _, page := range pages {
+		pageText, err := t.image(ctx, page)
+		if err != nil {
+			return "", err
+		}
+		text.WriteString(pageText)
+		text.WriteString("\n\f")
+	}
+	return text.String(), nil
+}
+
+// run executes a command and returns its stdout, folding stderr into errors
+func run(ctx context.Context, name string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s failed: %s", name, msg)
+		}
+		return "", fmt.Errorf("%s failed: %w", name, err)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,571 @@
+// Package email sends mail through an SMTP server (email.send) and
+// summarizes a mailbox's unread messages over IMAP (email.unread_summary)
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/internal/imap"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// SMTP is the server mail is sent through
+type SMTP struct {
+	// Server is host:port; port 465 uses implicit TLS, others STARTTLS,
+	// which is required unless the server is on loopback
+	Server   string
+	Username string
+	Password string
+
+	// From is the sender, e.g. "Home <home@example.com>"
+	From string
+}
+
+// IMAP is the mailbox email.unread_summary reads
+type IMAP struct {
+	// Server is the IMAPS host, with optional port (default 993)
+	Server   string
+	Username string
+	Password string
+
+	// Folder is read unless the intent names another (default INBOX)
+	Folder string
+}
+
+// Config sets the servers, templates, and limits
+type Config struct {
+	SMTP SMTP
+
+	// IMAP enables email.unread_summary when its Server is set
+	IMAP IMAP
+
+	// Templates are the named messages email.send can fill in
+	Templates map[string]*Template
+
+	// AttachmentDir is the directory attachments given by path are read
+	// from; paths can't leave it. Attachments may also be sent inline.
+	AttachmentDir string
+
+	// MaxAttachments caps the total size of a message's attachments
+	// (default 10 MiB)
+	MaxAttachments int
+
+	// MaxRecipients caps To, Cc, and Bcc together (default 20)
+	MaxRecipients int
+}
+
+// Executor handles email.send and email.unread_summary
+type Executor struct {
+	cfg  Config
+	from *mail.Address
+}
+
+// NewExecutor checks the sender address
+func NewExecutor(cfg Config) (*Executor, error) {
+	if cfg.SMTP.Server == "" && cfg.IMAP.Server == "" {
+		return nil, errors.New("no SMTP or IMAP server configured")
+	}
+	if cfg.MaxAttachments <= 0 {
+		cfg.MaxAttachments = 10 << 20
+	}
+	if cfg.MaxRecipients <= 0 {
+		cfg.MaxRecipients = 20
+	}
+	if cfg.IMAP.Folder == "" {
+		cfg.IMAP.Folder = "INBOX"
+	}
+	e := &Executor{cfg: cfg}
+	if cfg.SMTP.Server != "" {
+		if _, _, err := net.SplitHostPort(cfg.SMTP.Server); err != nil {
+			return nil, fmt.Errorf("SMTP server must be host:port: %w", err)
+		}
+		from := cfg.SMTP.From
+		if from == "" {
+			from = cfg.SMTP.Username
+		}
+		addr, err := mail.ParseAddress(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sender address %q: %w", from, err)
+		}
+		e.from = addr
+	}
+	for name, t := range cfg.Templates {
+		if t.subject == nil {
+			if err := t.parse(name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return e, nil
+}
+
+func (e *Executor) Name() string {
+	return "email"
+}
+
+func (e *Executor) SupportedActions() []string {
+	var actions []string
+	if e.cfg.SMTP.Server != "" {
+		actions = append(actions, "email.send")
+	}
+	if e.cfg.IMAP.Server != "" {
+		actions = append(actions, "email.unread_summary")
+	}
+	return actions
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	needs := sandbox.Needs{Network: true}
+	if e.cfg.AttachmentDir != "" {
+		needs.Read = []string{e.cfg.AttachmentDir}
+	}
+	return needs
+}
+
+// PermissionRequired makes every message sent need requires_permission
+func (e *Executor) PermissionRequired() []string {
+	return []string{"email.send"}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "email",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	switch {
+	case i.IntentType == "email.send" && e.cfg.SMTP.Server != "":
+		// The gateway enforces this too; a send is never implicit
+		if !i.RequiresPermission {
+			return fail(agenterrors.New(agenterrors.ConfirmationRequired, "email.send requires permission"))
+		}
+		sent, err := e.send(ctx, i)
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = sent
+
+	case i.IntentType == "email.unread_summary" && e.cfg.IMAP.Server != "":
+		var params struct {
+			Folder string `param:"folder"`
+			Limit  int    `param:"limit"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		if params.Folder == "" {
+			params.Folder = e.cfg.IMAP.Folder
+		}
+		if params.Limit <= 0 {
+			params.Limit = 20
+		}
+		summary, err := e.unreadSummary(ctx, params.Folder, params.Limit)
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = summary
+
+	default:
+		return fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+	return result, nil
+}
+
+// attachmentParam is an attachment as an intent gives it: a path in the
+// attachment directory, or inline base64 content
+type attachmentParam struct {
+	Path        string `json:"path"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Content     string `json:"content"`
+}
+
+func (e *Executor) send(ctx context.Context, i *intent.Intent) (map[string]interface{}, error) {
+	var params struct {
+		To          []string               `param:"to"`
+		Cc          []string               `param:"cc"`
+		Bcc         []string               `param:"bcc"`
+		Subject     string                 `param:"subject"`
+		Body        string                 `param:"body"`
+		HTML        string                 `param:"html"`
+		Template    string                 `param:"template"`
+		Data        map[string]interface{} `param:"data"`
+		Attachments []attachmentParam      `param:"attachments"`
+	}
+	if err := i.DecodeParams(&params); err != nil {
+		return nil, err
+	}
+
+	msg := &message{From: e.from}
+	if params.Template != "" {
+		t, ok := e.cfg.Templates[params.Template]
+		if !ok {
+			return nil, agenterrors.Newf(agenterrors.NotFound, "no email template named %s", params.Template)
+		}
+		if params.Subject != "" || params.Body != "" || params.HTML != "" {
+			return nil, agenterrors.New(agenterrors.InvalidParams, "give a template or a subject and body, not both")
+		}
+		var err error
+		if msg.Subject, msg.Body, msg.HTML, err = t.render(params.Data); err != nil {
+			return nil, err
+		}
+		if len(params.To) == 0 {
+			params.To = t.To
+		}
+	} else {
+		if params.Subject == "" || (params.Body == "" && params.HTML == "") {
+			return nil, agenterrors.New(agenterrors.InvalidParams, "give a subject and a body (or html), or a template")
+		}
+		msg.Subject, msg.Body, msg.HTML = params.Subject, params.Body, params.HTML
+	}
+
+	var err error
+	if len(params.To) == 0 {
+		return nil, agenterrors.New(agenterrors.InvalidParams, "no recipients")
+	}
+	if len(params.To)+len(params.Cc)+len(params.Bcc) > e.cfg.MaxRecipients {
+		return nil, agenterrors.Newf(agenterrors.InvalidParams, "at most %d recipients", e.cfg.MaxRecipients)
+	}
+	if msg.To, err = parseAddresses(params.To); err != nil {
+		return nil, err
+	}
+	if msg.Cc, err = parseAddresses(params.Cc); err != nil {
+		return nil, err
+	}
+	bcc, err := parseAddresses(params.Bcc)
+	if err != nil {
+		return nil, err
+	}
+
+	total := 0
+	for _, a := range params.Attachments {
+		attachment, err := e.attachment(a)
+		if err != nil {
+			return nil, err
+		}
+		if total += len(attachment.Data); total > e.cfg.MaxAttachments {
+			return nil, agenterrors.Newf(agenterrors.InvalidParams, "attachments exceed %d bytes", e.cfg.MaxAttachments)
+		}
+		msg.Attachments = append(msg.Attachments, attachment)
+	}
+
+	data, err := msg.encode()
+	if err != nil {
+		return nil, agenterrors.Wrap(agenterrors.Internal, err)
+	}
+	var recipients []string
+	for _, list := range [][]*mail.Address{msg.To, msg.Cc, bcc} {
+		for _, a := range list {
+			recipients = append(recipients, a.Address)
+		}
+	}
+	if err := e.deliver(ctx, recipients, data); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(msg.Attachments))
+	for n, a := range msg.Attachments {
+		names[n] = a.Filename
+	}
+	return map[string]interface{}{
+		"to":          addressList(msg.To),
+		"recipients":  len(recipients),
+		"subject":     msg.Subject,
+		"bytes":       len(data),
+		"attachments": names,
+	}, nil
+}
+
+func parseAddresses(list []string) ([]*mail.Address, error) {
+	var addrs []*mail.Address
+	for _, s := range list {
+		a, err := mail.ParseAddress(s)
+		if err != nil {
+			return nil, agenterrors.Newf(agenterrors.InvalidParams, "invalid address %q: %v", s, err)
+		}
+		addrs = append(addrs, a)
+	}
+	return addrs, nil
+}
+
+// attachment reads an attachment from the attachment directory or decodes
+// it from the intent
+func (e *Executor) attachment(a attachmentParam) (Attachment, error) {
+	var data []byte
+	switch {
+	case a.Path != "" && a.Content != "":
+		return Attachment{}, agenterrors.New(agenterrors.InvalidParams, "an attachment has a path or content, not both")
+	case a.Path != "":
+		if e.cfg.AttachmentDir == "" {
+			return Attachment{}, agenterrors.New(agenterrors.Unsupported, "no attachment directory is configured; send the content inline")
+		}
+		root, err := os.OpenRoot(e.cfg.AttachmentDir)
+		if err != nil {
+			return Attachment{}, agenterrors.Wrap(agenterrors.Unavailable, err)
+		}
+		defer root.Close()
+		file, err := root.Open(filepath.FromSlash(strings.TrimPrefix(a.Path, "/")))
+		if err != nil {
+			return Attachment{}, agenterrors.Newf(agenterrors.NotFound, "attachment %s: %v", a.Path, err)
+		}
+		defer file.Close()
+		if data, err = io.ReadAll(io.LimitReader(file, int64(e.cfg.MaxAttachments)+1)); err != nil {
+			return Attachment{}, agenterrors.Wrap(agenterrors.Unavailable, err)
+		}
+		if a.Filename == "" {
+			a.Filename = filepath.Base(a.Path)
+		}
+	case a.Content != "":
+		var err error
+		if data, err = base64.StdEncoding.DecodeString(a.Content); err != nil {
+			return Attachment{}, agenterrors.Newf(agenterrors.InvalidParams, "attachment %s: content is not base64: %v", a.Filename, err)
+		}
+		if a.Filename == "" {
+			return Attachment{}, agenterrors.New(agenterrors.InvalidParams, "inline attachments need a filename")
+		}
+	default:
+		return Attachment{}, agenterrors.New(agenterrors.InvalidParams, "an attachment needs a path or content")
+	}
+	if a.ContentType == "" {
+		a.ContentType = mime.TypeByExtension(filepath.Ext(a.Filename))
+		if a.ContentType == "" {
+			a.ContentType = "application/octet-stream"
+		}
+	}
+	return Attachment{Filename: a.Filename, ContentType: a.ContentType, Data: data}, nil
+}
+
+// deliver sends an encoded message through the SMTP server
+func (e *Executor) deliver(ctx context.Context, recipients []string, data []byte) error {
+	cfg := e.cfg.SMTP
+	host, port, _ := net.SplitHostPort(cfg.Server)
+	if err := connpool.Default.CheckEgress(cfg.Server); err != nil {
+		return agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	deadline := time.Now().Add(time.Minute)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	dialer := &net.Dialer{Deadline: deadline}
+	var conn net.Conn
+	var err error
+	tlsConfig := &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+	if port == "465" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", cfg.Server, tlsConfig)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", cfg.Server)
+	}
+	if err != nil {
+		return agenterrors.Newf(agenterrors.Unavailable, "connecting to %s: %v", cfg.Server, err)
+	}
+	conn.SetDeadline(deadline)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return agenterrors.Newf(agenterrors.Unavailable, "%s: %v", cfg.Server, err)
+	}
+	defer client.Close()
+
+	if port != "465" {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return agenterrors.Newf(agenterrors.Unavailable, "%s: STARTTLS: %v", cfg.Server, err)
+			}
+		} else if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return agenterrors.Newf(agenterrors.Unavailable, "%s doesn't offer STARTTLS; refusing to send in the clear", cfg.Server)
+		}
+	}
+	if cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, host)); err != nil {
+			return agenterrors.Newf(agenterrors.Unauthorized, "%s: %v", cfg.Server, err)
+		}
+	}
+	if err := client.Mail(e.from.Address); err != nil {
+		return agenterrors.Newf(agenterrors.Unavailable, "%s: %v", cfg.Server, err)
+	}
+	for _, r := range recipients {
+		if err := client.Rcpt(r); err != nil {
+			return agenterrors.Newf(agenterrors.InvalidParams, "%s refused %s: %v", cfg.Server, r, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return agenterrors.Newf(agenterrors.Unavailable, "%s: %v", cfg.Server, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return agenterrors.Newf(agenterrors.Unavailable, "%s: %v", cfg.Server, err)
+	}
+	if err := w.Close(); err != nil {
+		return agenterrors.Newf(agenterrors.Unavailable, "%s: %v", cfg.Server, err)
+	}
+	return client.Quit()
+}
+
+// unreadSummary lists the newest unread messages of a folder without
+// marking them read
+func (e *Executor) unreadSummary(ctx context.Context, folder string, limit int) (map[string]interface{}, error) {
+	cfg := e.cfg.IMAP
+	if err := connpool.Default.CheckEgress(cfg.Server); err != nil {
+		return nil, agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	client, err := imap.Dial(cfg.Server, 30*time.Second)
+	if err != nil {
+		return nil, agenterrors.Newf(agenterrors.Unavailable, "failed to connect to %s: %v", cfg.Server, err)
+	}
+	defer client.Close()
+	if err := client.Login(cfg.Username, cfg.Password); err != nil {
+		return nil, agenterrors.Wrap(agenterrors.Unauthorized, err)
+	}
+	if err := client.Examine(folder); err != nil {
+		return nil, agenterrors.Newf(agenterrors.NotFound, "folder %s: %v", folder, err)
+	}
+	uids, err := client.Search("UNSEEN")
+	if err != nil {
+		return nil, agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	unread := len(uids)
+	if len(uids) > limit {
+		uids = uids[len(uids)-limit:]
+	}
+	fetched, err := client.Fetch(uids, 0)
+	if err != nil {
+		return nil, agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+
+	type summary struct {
+		From     string    `json:"from"`
+		Address  string    `json:"address"`
+		Subject  string    `json:"subject"`
+		Received time.Time `json:"received,omitempty"`
+	}
+	messages := make([]summary, 0, len(fetched))
+	bySender := make(map[string]int)
+	for _, m := range fetched {
+		header, err := mail.ReadMessage(io.MultiReader(bytes.NewReader(m.Header), strings.NewReader("\r\n")))
+		if err != nil {
+			continue
+		}
+		s := summary{Subject: decodeHeader(header.Header.Get("Subject"))}
+		if addr, err := mail.ParseAddress(header.Header.Get("From")); err == nil {
+			s.From, s.Address = addr.Name, addr.Address
+		} else {
+			s.From = decodeHeader(header.Header.Get("From"))
+		}
+		if s.From == "" {
+			s.From = s.Address
+		}
+		s.Received, _ = header.Header.Date()
+		messages = append(messages, s)
+		bySender[s.From]++
+	}
+	// Newest first
+	sort.SliceStable(messages, func(a, b int) bool { return messages[a].Received.After(messages[b].Received) })
+
+	type sender struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	senders := make([]sender, 0, len(bySender))
+	for name, count := range bySender {
+		senders = append(senders, sender{name, count})
+	}
+	sort.Slice(senders, func(a, b int) bool {
+		if senders[a].Count != senders[b].Count {
+			return senders[a].Count > senders[b].Count
+		}
+		return senders[a].Name < senders[b].Name
+	})
+	return map[string]interface{}{
+		"folder":   folder,
+		"unread":   unread,
+		"messages": messages,
+		"senders":  senders,
+	}, nil
+}
+
+func decodeHeader(s string) string {
+	decoded, err := new(mime.WordDecoder).DecodeHeader(s)
+	if err != nil {
+		return s
+	}
+	return decoded
+}
+
+func (e *Executor) IsAvailable() bool {
+	return true
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	names := make([]string, 0, len(e.cfg.Templates))
+	for name := range e.cfg.Templates {
+		names = append(names, fmt.Sprintf("%q", name))
+	}
+	sort.Strings(names)
+	template := `{"type": "string"}`
+	if len(names) > 0 {
+		template = `{"type": "string", "enum": [` + strings.Join(names, ", ") + `]}`
+	}
+	return map[string]*schema.Schema{
+		"email.send": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"to": {"type": "array", "items": {"type": "string"}},
+				"cc": {"type": "array", "items": {"type": "string"}},
+				"bcc": {"type": "array", "items": {"type": "string"}},
+				"subject": {"type": "string"},
+				"body": {"type": "string"},
+				"html": {"type": "string"},
+				"template": ` + template + `,
+				"data": {"type": "object"},
+				"attachments": {
+					"type": "array",
+					"items": {
+						"type": "object",
+						"properties": {
+							"path": {"type": "string"},
+							"filename": {"type": "string"},
+							"content_type": {"type": "string"},
+							"content": {"type": "string", "description": "base64"}
+						}
+					}
+				}
+			}
+		}`),
+		"email.unread_summary": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"folder": {"type": "string"},
+				"limit": {"type": "integer", "minimum": 1, "maximum": 100}
+			}
+		}`),
+	}
+}
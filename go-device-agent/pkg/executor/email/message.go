@@ -0,0 +1,157 @@
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/internal/uuid"
+)
+
+// Attachment is a file sent with a message
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// message is an outgoing message before encoding
+type message struct {
+	From        *mail.Address
+	To, Cc      []*mail.Address
+	Subject     string
+	Body        string
+	HTML        string
+	Attachments []Attachment
+}
+
+// encode writes the message in RFC 5322 form: a text part, with an HTML
+// alternative when there is one, and attachments in a multipart/mixed
+// wrapper when there are any
+func (m *message) encode() ([]byte, error) {
+	var b bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&b, "%s: %s\r\n", name, value)
+	}
+	header("From", m.From.String())
+	header("To", addressList(m.To))
+	if len(m.Cc) > 0 {
+		header("Cc", addressList(m.Cc))
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	domain := m.From.Address[strings.LastIndexByte(m.From.Address, '@')+1:]
+	header("Message-ID", "<"+uuid.New()+"@"+domain+">")
+	header("MIME-Version", "1.0")
+	header("X-Mailer", "device-agent")
+
+	contentHeader, content, err := m.content()
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Attachments) == 0 {
+		for name, values := range contentHeader {
+			header(name, values[0])
+		}
+		b.WriteString("\r\n")
+		b.Write(content)
+		return b.Bytes(), nil
+	}
+	w := multipart.NewWriter(&b)
+	header("Content-Type", "multipart/mixed; boundary="+w.Boundary())
+	b.WriteString("\r\n")
+	part, err := w.CreatePart(contentHeader)
+	if err != nil {
+		return nil, err
+	}
+	part.Write(content)
+	for _, a := range m.Attachments {
+		// Content types can carry parameters, e.g. text/plain; charset=utf-8
+		kind, params, err := mime.ParseMediaType(a.ContentType)
+		if err != nil {
+			kind, params = "application/octet-stream", map[string]string{}
+		}
+		params["name"] = a.Filename
+		part, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(kind, params)},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		writeBase64(part, a.Data)
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// content encodes the text part, or the text and HTML parts as
+// alternatives, returning its headers and body
+func (m *message) content() (textproto.MIMEHeader, []byte, error) {
+	var b bytes.Buffer
+	if m.HTML == "" {
+		if err := writeQP(&b, m.Body); err != nil {
+			return nil, nil, err
+		}
+		return textproto.MIMEHeader{
+			"Content-Type":              {"text/plain; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		}, b.Bytes(), nil
+	}
+	alt := multipart.NewWriter(&b)
+	for _, p := range []struct{ kind, text string }{{"text/plain", m.Body}, {"text/html", m.HTML}} {
+		part, err := alt.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.kind + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := writeQP(part, p.text); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := alt.Close(); err != nil {
+		return nil, nil, err
+	}
+	return textproto.MIMEHeader{
+		"Content-Type": {"multipart/alternative; boundary=" + alt.Boundary()},
+	}, b.Bytes(), nil
+}
+
+func writeQP(w io.Writer, text string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\n", "\r\n"))); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// writeBase64 writes data in 76-character lines, as MIME requires
+func writeBase64(w io.Writer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		io.WriteString(w, encoded[:76]+"\r\n")
+		encoded = encoded[76:]
+	}
+	io.WriteString(w, encoded+"\r\n")
+}
+
+func addressList(addrs []*mail.Address) string {
+	s := make([]string, len(addrs))
+	for n, a := range addrs {
+		s[n] = a.String()
+	}
+	return strings.Join(s, ", ")
+}
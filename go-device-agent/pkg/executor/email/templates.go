@@ -0,0 +1,93 @@
+package email
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"text/template"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// Template is a reusable message, listed by name in a JSON file:
+//
+//	{
+//	  "daily-report": {
+//	    "subject": "Home report for {{.date}}",
+//	    "body": "Energy used: {{.kwh}} kWh\n{{range .alerts}}- {{.}}\n{{end}}"
+//	  }
+//	}
+//
+// Subject and body are Go templates filled from the intent's data; HTML,
+// when given, is sent as an alternative to body with its values escaped.
+type Template struct {
+	Subject string   `json:"subject"`
+	Body    string   `json:"body"`
+	HTML    string   `json:"html,omitempty"`
+	To      []string `json:"to,omitempty"` // default recipients
+
+	subject, body *template.Template
+	html          *htmltemplate.Template
+}
+
+// LoadTemplates reads a JSON object of templates by name
+func LoadTemplates(path string) (map[string]*Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var templates map[string]*Template
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, fmt.Errorf("invalid templates file %s: %w", path, err)
+	}
+	for name, t := range templates {
+		if err := t.parse(name); err != nil {
+			return nil, fmt.Errorf("invalid templates file %s: %w", path, err)
+		}
+	}
+	return templates, nil
+}
+
+func (t *Template) parse(name string) error {
+	if t.Subject == "" || (t.Body == "" && t.HTML == "") {
+		return fmt.Errorf("template %q needs a subject and a body or html", name)
+	}
+	var err error
+	// Missing data is an error rather than "<no value>" in a sent message
+	if t.subject, err = template.New(name).Option("missingkey=error").Parse(t.Subject); err != nil {
+		return fmt.Errorf("template %q subject: %w", name, err)
+	}
+	if t.body, err = template.New(name).Option("missingkey=error").Parse(t.Body); err != nil {
+		return fmt.Errorf("template %q body: %w", name, err)
+	}
+	if t.HTML != "" {
+		if t.html, err = htmltemplate.New(name).Option("missingkey=error").Parse(t.HTML); err != nil {
+			return fmt.Errorf("template %q html: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// render fills in the template
+func (t *Template) render(data map[string]interface{}) (subject, body, html string, err error) {
+	var b bytes.Buffer
+	if err := t.subject.Execute(&b, data); err != nil {
+		return "", "", "", agenterrors.Wrap(agenterrors.InvalidParams, err)
+	}
+	subject = b.String()
+	b.Reset()
+	if err := t.body.Execute(&b, data); err != nil {
+		return "", "", "", agenterrors.Wrap(agenterrors.InvalidParams, err)
+	}
+	body = b.String()
+	if t.html != nil {
+		b.Reset()
+		if err := t.html.Execute(&b, data); err != nil {
+			return "", "", "", agenterrors.Wrap(agenterrors.InvalidParams, err)
+		}
+		html = b.String()
+	}
+	return subject, body, html, nil
+}
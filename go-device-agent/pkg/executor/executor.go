@@ -4,10 +4,20 @@ package executor
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"sync"
 	"time"
 
+	"github.com/vinod901/local-agent-core/go-device-agent/internal/uuid"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
 	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
 	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/usage"
 )
 
 // MockExecutor is a simple mock executor for testing
@@ -32,6 +42,10 @@ func (e *MockExecutor) SupportedActions() []string {
 	return e.actions
 }
 
+func (e *MockExecutor) Needs() sandbox.Needs {
+	return sandbox.Needs{}
+}
+
 func (e *MockExecutor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
 	// Mock execution - just return success
 	return &gateway.ExecutionResult{
@@ -69,6 +83,10 @@ func (e *DeviceExecutor) SupportedActions() []string {
 	return []string{"device.control", "device.query"}
 }
 
+func (e *DeviceExecutor) Needs() sandbox.Needs {
+	return sandbox.Needs{}
+}
+
 func (e *DeviceExecutor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
 	result := &gateway.ExecutionResult{
 		IntentID:  i.ID,
@@ -79,21 +97,20 @@ func (e *DeviceExecutor) Execute(ctx context.Context, i *intent.Intent) (*gatewa
 
 	switch i.IntentType {
 	case "device.control":
-		deviceName, ok := i.Parameters["device"].(string)
-		if !ok {
-			result.Success = false
-			result.Error = "missing or invalid 'device' parameter"
+		deviceName, err := i.StringParam("device")
+		if err != nil {
+			result.Fail(err)
 			return result, nil
 		}
 
-		action, ok := i.Parameters["action"].(string)
-		if !ok {
-			result.Success = false
-			result.Error = "missing or invalid 'action' parameter"
+		action, err := i.StringParam("action")
+		if err != nil {
+			result.Fail(err)
 			return result, nil
 		}
 
 		// Mock device control
+		usage.DeviceCommand(ctx)
 		if action == "on" {
 			e.devices[deviceName] = true
 		} else if action == "off" {
@@ -108,10 +125,9 @@ func (e *DeviceExecutor) Execute(ctx context.Context, i *intent.Intent) (*gatewa
 		}
 
 	case "device.query":
-		deviceName, ok := i.Parameters["device"].(string)
-		if !ok {
-			result.Success = false
-			result.Error = "missing or invalid 'device' parameter"
+		deviceName, err := i.StringParam("device")
+		if err != nil {
+			result.Fail(err)
 			return result, nil
 		}
 
@@ -124,8 +140,7 @@ func (e *DeviceExecutor) Execute(ctx context.Context, i *intent.Intent) (*gatewa
 		}
 
 	default:
-		result.Success = false
-		result.Error = fmt.Sprintf("unsupported action: %s", i.IntentType)
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
 	}
 
 	return result, nil
@@ -135,12 +150,77 @@ func (e *DeviceExecutor) IsAvailable() bool {
 	return true
 }
 
-// NotificationExecutor handles notification actions
-type NotificationExecutor struct{}
+func (e *DeviceExecutor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"device.control": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"device": {"type": "string", "minLength": 1},
+				"action": {"type": "string", "enum": ["on", "off"]}
+			},
+			"required": ["device", "action"]
+		}`),
+		"device.query": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"device": {"type": "string", "minLength": 1}
+			},
+			"required": ["device"]
+		}`),
+	}
+}
+
+// NotificationExecutor handles notification actions. Notifications are
+// written to the output unless UseDesktop finds a desktop to show them on.
+type NotificationExecutor struct {
+	out     io.Writer
+	desktop notifier
+	bus     *events.Bus
+
+	mu   sync.Mutex
+	open map[string]*Notification // shown and not yet gone, by ID
+}
 
 // NewNotificationExecutor creates a new notification executor
 func NewNotificationExecutor() *NotificationExecutor {
-	return &NotificationExecutor{}
+	return &NotificationExecutor{
+		out:  os.Stdout,
+		open: make(map[string]*Notification),
+	}
+}
+
+// SetOutput sets where notifications are written.
+// Pipe mode uses this to keep stdout reserved for results.
+func (e *NotificationExecutor) SetOutput(w io.Writer) {
+	e.out = w
+}
+
+// SetEventBus publishes a notification.action event whenever a
+// notification's button is clicked
+func (e *NotificationExecutor) SetEventBus(bus *events.Bus) {
+	e.bus = bus
+}
+
+// UseDesktop shows notifications on the desktop: through the notification
+// server on the D-Bus session bus on Linux, Notification Center on macOS,
+// and toasts on Windows. It fails, leaving notifications written to the
+// output, when there is no desktop to reach.
+func (e *NotificationExecutor) UseDesktop() error {
+	desktop, err := newDesktopNotifier()
+	if err != nil {
+		return err
+	}
+	e.desktop = desktop
+	return nil
+}
+
+// Backend names where notifications go: "dbus", "osascript", "toast", or
+// "log"
+func (e *NotificationExecutor) Backend() string {
+	if e.desktop == nil {
+		return "log"
+	}
+	return e.desktop.name()
 }
 
 func (e *NotificationExecutor) Name() string {
@@ -151,6 +231,11 @@ func (e *NotificationExecutor) SupportedActions() []string {
 	return []string{"notification.send", "notification.clear"}
 }
 
+func (e *NotificationExecutor) Needs() sandbox.Needs {
+	// osascript on macOS and PowerShell on Windows; D-Bus on Linux
+	return sandbox.Needs{Subprocess: e.desktop != nil && e.desktop.name() != "dbus"}
+}
+
 func (e *NotificationExecutor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
 	result := &gateway.ExecutionResult{
 		IntentID:  i.ID,
@@ -159,39 +244,215 @@ func (e *NotificationExecutor) Execute(ctx context.Context, i *intent.Intent) (*
 		Timestamp: time.Now().Format(time.RFC3339),
 	}
 
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
 	switch i.IntentType {
 	case "notification.send":
-		message, ok := i.Parameters["message"].(string)
-		if !ok {
-			result.Success = false
-			result.Error = "missing or invalid 'message' parameter"
-			return result, nil
+		var params struct {
+			Message string               `param:"message,required"`
+			Title   string               `param:"title"`
+			Urgency string               `param:"urgency"`
+			Icon    string               `param:"icon"`
+			Actions []NotificationAction `param:"actions"`
+			Timeout int                  `param:"timeout"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		n := &Notification{
+			ID:      uuid.NewID(),
+			Title:   params.Title,
+			Message: params.Message,
+			Urgency: params.Urgency,
+			Icon:    params.Icon,
+			Actions: params.Actions,
+			Timeout: params.Timeout,
+		}
+		if n.Urgency == "" {
+			n.Urgency = "normal"
+		}
+		if err := n.validate(); err != nil {
+			return fail(err)
 		}
 
-		// Mock notification send
-		fmt.Printf("📢 Notification: %s\n", message)
-
+		actionsShown, err := e.send(ctx, n, i)
+		if err != nil {
+			return fail(agenterrors.Newf(agenterrors.Unavailable, "notification not shown: %w", err))
+		}
 		result.Success = true
 		result.Result = map[string]interface{}{
-			"message": message,
-			"sent":    true,
+			"id":            n.ID,
+			"message":       n.Message,
+			"sent":          true,
+			"backend":       e.Backend(),
+			"actions_shown": actionsShown,
 		}
 
 	case "notification.clear":
-		// Mock notification clear
+		id, _ := i.StringParam("id")
+		cleared, err := e.clear(id)
+		if err != nil {
+			return fail(err)
+		}
 		result.Success = true
 		result.Result = map[string]interface{}{
 			"cleared": true,
+			"count":   cleared,
 		}
 
 	default:
-		result.Success = false
-		result.Error = fmt.Sprintf("unsupported action: %s", i.IntentType)
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
 	}
 
 	return result, nil
 }
 
+// Notify shows a notification raised by the agent itself, such as a
+// low-battery warning, returning its ID
+func (e *NotificationExecutor) Notify(ctx context.Context, n Notification) (string, error) {
+	n.ID = uuid.NewID()
+	if n.Urgency == "" {
+		n.Urgency = "normal"
+	}
+	if err := n.validate(); err != nil {
+		return "", err
+	}
+	if _, err := e.send(ctx, &n, &intent.Intent{}); err != nil {
+		return "", err
+	}
+	return n.ID, nil
+}
+
+// validate checks the urgency and actions
+func (n *Notification) validate() error {
+	switch n.Urgency {
+	case "low", "normal", "critical":
+	default:
+		return agenterrors.Newf(agenterrors.InvalidParams, "urgency must be low, normal, or critical, not '%s'", n.Urgency)
+	}
+	if len(n.Actions) > maxNotificationActions {
+		return agenterrors.Newf(agenterrors.InvalidParams, "a notification can have at most %d actions", maxNotificationActions)
+	}
+	seen := make(map[string]bool, len(n.Actions))
+	for _, a := range n.Actions {
+		if a.ID == "" || a.Label == "" {
+			return agenterrors.New(agenterrors.InvalidParams, "every action needs an id and a label")
+		}
+		if seen[a.ID] || seen["label:"+a.Label] {
+			return agenterrors.Newf(agenterrors.InvalidParams, "action '%s' is listed twice", a.ID)
+		}
+		seen[a.ID], seen["label:"+a.Label] = true, true
+	}
+	return nil
+}
+
+// send shows n, reporting whether its actions were shown. The intent is
+// carried into the notification.action event so the core can tie a click
+// to the conversation that raised it.
+func (e *NotificationExecutor) send(ctx context.Context, n *Notification, i *intent.Intent) (bool, error) {
+	if e.desktop == nil {
+		if n.Title != "" {
+			fmt.Fprintf(e.out, "📢 Notification: %s: %s\n", n.Title, n.Message)
+		} else {
+			fmt.Fprintf(e.out, "📢 Notification: %s\n", n.Message)
+		}
+		return false, nil
+	}
+
+	e.mu.Lock()
+	e.open[n.ID] = n
+	e.mu.Unlock()
+	done := func(action string) {
+		e.mu.Lock()
+		delete(e.open, n.ID)
+		e.mu.Unlock()
+		if action != "" && e.bus != nil {
+			e.bus.Publish(events.Event{
+				ID:     events.NewID(),
+				Type:   "notification.action",
+				Source: "notification",
+				Data: map[string]interface{}{
+					"notification_id": n.ID,
+					"action":          action,
+					"intent_id":       i.ID,
+					"correlation_id":  i.CorrelationID,
+					"session_id":      i.SessionID,
+				},
+				Timestamp: time.Now(),
+			})
+		}
+	}
+	shown, err := e.desktop.send(ctx, n, done)
+	if err != nil {
+		e.mu.Lock()
+		delete(e.open, n.ID)
+		e.mu.Unlock()
+	}
+	return shown, err
+}
+
+// clear closes one notification, or every open one when id is empty
+func (e *NotificationExecutor) clear(id string) (int, error) {
+	e.mu.Lock()
+	var ids []string
+	if id != "" {
+		if _, ok := e.open[id]; !ok {
+			e.mu.Unlock()
+			return 0, agenterrors.Newf(agenterrors.NotFound, "no open notification with id %s", id)
+		}
+		ids = []string{id}
+	} else {
+		for id := range e.open {
+			ids = append(ids, id)
+		}
+	}
+	e.mu.Unlock()
+
+	for _, id := range ids {
+		if err := e.desktop.close(id); err != nil {
+			return 0, agenterrors.Newf(agenterrors.Unavailable, "cannot clear notification %s: %w", id, err)
+		}
+	}
+	return len(ids), nil
+}
+
 func (e *NotificationExecutor) IsAvailable() bool {
 	return true
 }
+
+func (e *NotificationExecutor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"notification.send": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"message": {"type": "string", "minLength": 1},
+				"title": {"type": "string"},
+				"urgency": {"type": "string", "enum": ["low", "normal", "critical"]},
+				"icon": {"type": "string"},
+				"actions": {
+					"type": "array",
+					"maxItems": 3,
+					"items": {
+						"type": "object",
+						"properties": {
+							"id": {"type": "string", "minLength": 1},
+							"label": {"type": "string", "minLength": 1}
+						},
+						"required": ["id", "label"]
+					}
+				},
+				"timeout": {"type": "integer", "minimum": 0}
+			},
+			"required": ["message"]
+		}`),
+		"notification.clear": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"id": {"type": "string"}
+			}
+		}`),
+	}
+}
@@ -0,0 +1,443 @@
+// Package files reads, lists, and writes small files inside configured root
+// directories, so the agent can fetch or store local files without reaching
+// the rest of the filesystem
+package files
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Root is a directory intents may use, addressed by name
+type Root struct {
+	Name     string
+	Path     string
+	Writable bool
+}
+
+// Config sets the roots and size caps
+type Config struct {
+	Roots []Root
+
+	// MaxSize caps the bytes read or written at once (default 1 MiB)
+	MaxSize int64
+
+	// MaxEntries caps file.list results (default 1000)
+	MaxEntries int
+}
+
+type root struct {
+	Root
+	dir *os.Root
+}
+
+// Executor handles file.read, file.list, and file.write. Every access goes
+// through os.Root, so ".." and symlinks cannot leave a root.
+type Executor struct {
+	cfg   Config
+	roots map[string]*root
+	names []string
+}
+
+// NewExecutor opens the roots, which must exist
+func NewExecutor(cfg Config) (*Executor, error) {
+	if len(cfg.Roots) == 0 {
+		return nil, errors.New("no file roots configured")
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 1 << 20
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 1000
+	}
+	e := &Executor{cfg: cfg, roots: make(map[string]*root, len(cfg.Roots))}
+	for _, r := range cfg.Roots {
+		if _, dup := e.roots[r.Name]; dup || r.Name == "" {
+			e.Close()
+			return nil, fmt.Errorf("file root names must be unique and non-empty: %q", r.Name)
+		}
+		dir, err := os.OpenRoot(r.Path)
+		if err != nil {
+			e.Close()
+			return nil, err
+		}
+		e.roots[r.Name] = &root{Root: r, dir: dir}
+		e.names = append(e.names, r.Name)
+	}
+	sort.Strings(e.names)
+	return e, nil
+}
+
+// Close releases the root directories
+func (e *Executor) Close() error {
+	for _, r := range e.roots {
+		r.dir.Close()
+	}
+	return nil
+}
+
+func (e *Executor) Name() string {
+	return "file"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"file.read", "file.list", "file.write"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	var needs sandbox.Needs
+	for _, r := range e.cfg.Roots {
+		if r.Writable {
+			needs.Write = append(needs.Write, r.Path)
+		} else {
+			needs.Read = append(needs.Read, r.Path)
+		}
+	}
+	return needs
+}
+
+// PermissionRequired guards writes
+func (e *Executor) PermissionRequired() []string {
+	return []string{"file.write"}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "file",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	var params struct {
+		Root       string `param:"root"`
+		Path       string `param:"path"`
+		Pattern    string `param:"pattern"`
+		Content    string `param:"content"`
+		Data       string `param:"data"`
+		Mode       string `param:"mode"`
+		CreateDirs bool   `param:"create_dirs"`
+	}
+	if err := i.DecodeParams(&params); err != nil {
+		return fail(err)
+	}
+	r, err := e.root(params.Root)
+	if err != nil {
+		return fail(err)
+	}
+	name, err := clean(params.Path)
+	if err != nil {
+		return fail(err)
+	}
+
+	switch i.IntentType {
+	case "file.read":
+		out, err := e.read(r, name)
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = out
+		return result, nil
+
+	case "file.list":
+		entries, truncated, err := e.list(r, name, params.Pattern)
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{"root": r.Name, "entries": entries}
+		if truncated {
+			result.Result["truncated"] = true
+		}
+		return result, nil
+
+	case "file.write":
+		if !r.Writable {
+			return fail(agenterrors.Newf(agenterrors.DeniedByPolicy, "file root %s is read-only", r.Name))
+		}
+		data := []byte(params.Content)
+		if params.Data != "" {
+			if params.Content != "" {
+				return fail(agenterrors.New(agenterrors.InvalidParams, "give content or data, not both"))
+			}
+			if data, err = base64.StdEncoding.DecodeString(params.Data); err != nil {
+				return fail(agenterrors.New(agenterrors.InvalidParams, "data must be base64"))
+			}
+		}
+		size, err := e.write(r, name, data, params.Mode, params.CreateDirs)
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{"root": r.Name, "path": name, "size": size}
+		return result, nil
+
+	default:
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+		return result, nil
+	}
+}
+
+// root finds a root by name; the name may be left out when there is only one
+func (e *Executor) root(name string) (*root, error) {
+	if name == "" {
+		if len(e.names) == 1 {
+			return e.roots[e.names[0]], nil
+		}
+		return nil, agenterrors.Newf(agenterrors.InvalidParams, "name a root: %s", strings.Join(e.names, ", "))
+	}
+	r, ok := e.roots[name]
+	if !ok {
+		return nil, agenterrors.Newf(agenterrors.NotFound, "no file root named '%s' (have %s)", name, strings.Join(e.names, ", "))
+	}
+	return r, nil
+}
+
+// clean turns a request path into a root-relative name. os.Root refuses
+// escapes anyway; this only gives a clearer error and accepts "/x" as "x".
+func clean(p string) (string, error) {
+	p = path.Clean("/" + strings.ReplaceAll(p, "\\", "/"))
+	if strings.Contains(p, "\x00") {
+		return "", agenterrors.New(agenterrors.InvalidParams, "invalid path")
+	}
+	if p == "/" {
+		return ".", nil
+	}
+	return p[1:], nil
+}
+
+// rootError classifies filesystem errors, including attempts to escape
+func rootError(name string, err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return agenterrors.Newf(agenterrors.NotFound, "%s does not exist", name)
+	case errors.Is(err, fs.ErrExist):
+		return agenterrors.Newf(agenterrors.Conflict, "%s already exists", name)
+	case errors.Is(err, fs.ErrPermission):
+		return agenterrors.Newf(agenterrors.DeniedByPolicy, "%s: permission denied", name)
+	case strings.Contains(err.Error(), "path escapes from parent"):
+		return agenterrors.Newf(agenterrors.DeniedByPolicy, "%s is outside the root", name)
+	}
+	return agenterrors.Newf(agenterrors.Internal, "%s: %w", name, err)
+}
+
+// read returns a regular file's content, as text when it is UTF-8 and
+// base64 otherwise
+func (e *Executor) read(r *root, name string) (map[string]interface{}, error) {
+	f, err := r.dir.Open(name)
+	if err != nil {
+		return nil, rootError(name, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, rootError(name, err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, agenterrors.Newf(agenterrors.InvalidParams, "%s is not a regular file", name)
+	}
+	if info.Size() > e.cfg.MaxSize {
+		return nil, agenterrors.Newf(agenterrors.InvalidParams, "%s is %d bytes; the limit is %d", name, info.Size(), e.cfg.MaxSize)
+	}
+	data, err := io.ReadAll(io.LimitReader(f, e.cfg.MaxSize+1))
+	if err != nil {
+		return nil, rootError(name, err)
+	}
+	if int64(len(data)) > e.cfg.MaxSize {
+		return nil, agenterrors.Newf(agenterrors.InvalidParams, "%s grew past the %d byte limit while reading", name, e.cfg.MaxSize)
+	}
+
+	out := map[string]interface{}{
+		"root":     r.Name,
+		"path":     name,
+		"size":     len(data),
+		"modified": info.ModTime().UTC().Format(time.RFC3339),
+	}
+	if utf8.Valid(data) {
+		out["content"] = string(data)
+	} else {
+		out["data"] = base64.StdEncoding.EncodeToString(data)
+	}
+	return out, nil
+}
+
+// list returns the entries of a directory, or with a pattern the paths
+// under it matching a glob ("*.txt", "*/notes/*.md")
+func (e *Executor) list(r *root, dir, pattern string) ([]map[string]interface{}, bool, error) {
+	fsys := r.dir.FS()
+	var names []string
+	if pattern == "" {
+		entries, err := fs.ReadDir(fsys, dir)
+		if err != nil {
+			return nil, false, rootError(dir, err)
+		}
+		for _, entry := range entries {
+			names = append(names, path.Join(dir, entry.Name()))
+		}
+	} else {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, false, agenterrors.Newf(agenterrors.InvalidParams, "invalid pattern %q", pattern)
+		}
+		matches, err := fs.Glob(fsys, path.Join(dir, pattern))
+		if err != nil {
+			return nil, false, rootError(dir, err)
+		}
+		names = matches
+	}
+	sort.Strings(names)
+
+	truncated := len(names) > e.cfg.MaxEntries
+	if truncated {
+		names = names[:e.cfg.MaxEntries]
+	}
+	entries := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		info, err := fs.Stat(fsys, name)
+		if err != nil {
+			continue // dangling or escaping symlink
+		}
+		entry := map[string]interface{}{
+			"path":     name,
+			"name":     info.Name(),
+			"dir":      info.IsDir(),
+			"modified": info.ModTime().UTC().Format(time.RFC3339),
+		}
+		if !info.IsDir() {
+			entry["size"] = info.Size()
+		}
+		entries = append(entries, entry)
+	}
+	return entries, truncated, nil
+}
+
+// write stores data with mode "overwrite" (the default), "append", or
+// "create" (fail if the file exists)
+func (e *Executor) write(r *root, name string, data []byte, mode string, createDirs bool) (int64, error) {
+	if name == "." {
+		return 0, agenterrors.New(agenterrors.InvalidParams, "path names a directory")
+	}
+	if int64(len(data)) > e.cfg.MaxSize {
+		return 0, agenterrors.Newf(agenterrors.InvalidParams, "content is %d bytes; the limit is %d", len(data), e.cfg.MaxSize)
+	}
+	flag := os.O_WRONLY | os.O_CREATE
+	switch mode {
+	case "", "overwrite":
+		flag |= os.O_TRUNC
+	case "append":
+		flag |= os.O_APPEND
+	case "create":
+		flag |= os.O_EXCL
+	default:
+		return 0, agenterrors.Newf(agenterrors.InvalidParams, "mode must be overwrite, append, or create, not '%s'", mode)
+	}
+	if createDirs {
+		if err := mkdirAll(r.dir, path.Dir(name)); err != nil {
+			return 0, rootError(path.Dir(name), err)
+		}
+	}
+
+	if info, err := r.dir.Stat(name); err == nil && !info.Mode().IsRegular() {
+		return 0, agenterrors.Newf(agenterrors.InvalidParams, "%s is not a regular file", name)
+	}
+	f, err := r.dir.OpenFile(name, flag, 0o644)
+	if err != nil {
+		return 0, rootError(name, err)
+	}
+	if mode == "append" {
+		if info, err := f.Stat(); err == nil && info.Size()+int64(len(data)) > e.cfg.MaxSize {
+			f.Close()
+			return 0, agenterrors.Newf(agenterrors.InvalidParams, "appending would grow %s past the %d byte limit", name, e.cfg.MaxSize)
+		}
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return 0, rootError(name, err)
+	}
+	if err := f.Close(); err != nil {
+		return 0, rootError(name, err)
+	}
+	info, err := r.dir.Stat(name)
+	if err != nil {
+		return 0, rootError(name, err)
+	}
+	return info.Size(), nil
+}
+
+// mkdirAll creates dir and its parents inside the root
+func mkdirAll(dir *os.Root, name string) error {
+	if name == "." {
+		return nil
+	}
+	current := ""
+	for _, part := range strings.Split(name, "/") {
+		current = path.Join(current, part)
+		if err := dir.Mkdir(current, 0o755); err != nil && !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Executor) IsAvailable() bool {
+	return true
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	roots := make([]string, len(e.names))
+	for n, name := range e.names {
+		roots[n] = fmt.Sprintf("%q", name)
+	}
+	root := `"root": {"type": "string", "enum": [` + strings.Join(roots, ", ") + `]},`
+	return map[string]*schema.Schema{
+		"file.read": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				` + root + `
+				"path": {"type": "string", "minLength": 1}
+			},
+			"required": ["path"]
+		}`),
+		"file.list": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				` + root + `
+				"path": {"type": "string"},
+				"pattern": {"type": "string", "minLength": 1}
+			}
+		}`),
+		"file.write": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				` + root + `
+				"path": {"type": "string", "minLength": 1},
+				"content": {"type": "string"},
+				"data": {"type": "string"},
+				"mode": {"type": "string", "enum": ["overwrite", "append", "create"]},
+				"create_dirs": {"type": "boolean"}
+			},
+			"required": ["path"]
+		}`),
+	}
+}
@@ -0,0 +1,442 @@
+// Package firmware rolls firmware updates out to devices as background
+// jobs, one device at a time, checking each comes back healthy on the new
+// version before moving to the next and rolling back or alerting when one
+// doesn't
+package firmware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/internal/uuid"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/registry"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Updater is a backend that can update the firmware of its devices, which
+// it is given by registry ID
+type Updater interface {
+	// CheckFirmware reports the installed firmware version and a newer
+	// available one; available is the installed version when the device
+	// is up to date, and empty when the backend can't tell
+	CheckFirmware(ctx context.Context, device string) (installed, available string, err error)
+
+	// InstallFirmware updates the device to the newest firmware, or the
+	// image at a URL when one is given, returning once the device has
+	// taken it
+	InstallFirmware(ctx context.Context, device, image string) error
+}
+
+// Config sets the backends and how updated devices are checked
+type Config struct {
+	// Updaters by the module their registry devices belong to, e.g. "mqtt"
+	Updaters map[string]Updater
+
+	// HealthTimeout is how long an updated device has to come back
+	// reporting new firmware (default 10m)
+	HealthTimeout time.Duration
+
+	// Settle is how long it must then keep answering to count as healthy
+	// (default 1m)
+	Settle time.Duration
+
+	// InstallTimeout bounds an install, which over Zigbee can take most of
+	// an hour (default 2h)
+	InstallTimeout time.Duration
+
+	// PollInterval is how often an updating device is checked (default 15s)
+	PollInterval time.Duration
+}
+
+// Limits on jobs
+const (
+	maxJobs = 20
+
+	// busyWait is how long a job waits for a device other intents are using
+	busyWait = 2 * time.Minute
+)
+
+// Step statuses, in the order a device goes through them
+const (
+	StepPending     = "pending"
+	StepUpdating    = "updating"
+	StepVerifying   = "verifying"
+	StepRollingBack = "rolling_back"
+	StepUpdated     = "updated"
+	StepUpToDate    = "up_to_date"
+	StepFailed      = "failed"
+	StepRolledBack  = "rolled_back"
+	StepSkipped     = "skipped"
+)
+
+// Job statuses
+const (
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job is a rollout to one or more devices
+type Job struct {
+	ID         string    `json:"id"`
+	Status     string    `json:"status"`
+	Image      string    `json:"image,omitempty"`
+	Rollback   string    `json:"rollback_image,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+	Steps      []*Step   `json:"devices"`
+
+	continueOnFailure bool
+	cancelled         bool
+	intent            *intent.Intent
+}
+
+// Step is one device's part of a job
+type Step struct {
+	Device    string `json:"device"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	From      string `json:"from,omitempty"`
+	Available string `json:"available,omitempty"`
+	To        string `json:"to,omitempty"`
+	Error     string `json:"error,omitempty"`
+
+	updater Updater
+}
+
+// Executor handles device.update_firmware, device.firmware_status, and
+// device.firmware_cancel
+type Executor struct {
+	cfg        Config
+	devices    *registry.Registry
+	exclusions *gateway.Exclusions
+	bus        *events.Bus
+	logger     *slog.Logger
+	ctx        context.Context
+
+	mu   sync.Mutex
+	jobs []*Job // oldest first
+}
+
+// NewExecutor creates a firmware executor for the registry's devices.
+// Devices being updated are held exclusively in exclusions, so nothing
+// else controls them meanwhile; events go to bus.
+func NewExecutor(cfg Config, devices *registry.Registry, exclusions *gateway.Exclusions, bus *events.Bus) (*Executor, error) {
+	if len(cfg.Updaters) == 0 {
+		return nil, errors.New("no backend can update firmware")
+	}
+	if cfg.HealthTimeout <= 0 {
+		cfg.HealthTimeout = 10 * time.Minute
+	}
+	if cfg.Settle <= 0 {
+		cfg.Settle = time.Minute
+	}
+	if cfg.InstallTimeout <= 0 {
+		cfg.InstallTimeout = 2 * time.Hour
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 15 * time.Second
+	}
+	if exclusions == nil {
+		exclusions = gateway.NewExclusions()
+	}
+	return &Executor{
+		cfg:        cfg,
+		devices:    devices,
+		exclusions: exclusions,
+		bus:        bus,
+		logger:     slog.Default(),
+		ctx:        context.Background(),
+	}, nil
+}
+
+// SetLogger sets where job progress is logged
+func (e *Executor) SetLogger(logger *slog.Logger) {
+	e.logger = logger
+}
+
+// Start ties jobs to ctx; when it is cancelled, running installs are
+// abandoned
+func (e *Executor) Start(ctx context.Context) {
+	e.ctx = ctx
+}
+
+func (e *Executor) Name() string {
+	return "firmware"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"device.update_firmware", "device.firmware_status", "device.firmware_cancel"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{} // updaters reach devices through their own executors
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "firmware",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	switch i.IntentType {
+	case "device.update_firmware":
+		var params struct {
+			Device            string   `param:"device"`
+			Devices           []string `param:"devices"`
+			Image             string   `param:"image"`
+			RollbackImage     string   `param:"rollback_image"`
+			ContinueOnFailure bool     `param:"continue_on_failure"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		if params.Device != "" {
+			params.Devices = append([]string{params.Device}, params.Devices...)
+		}
+		if len(params.Devices) == 0 {
+			return fail(agenterrors.New(agenterrors.InvalidParams, "name a device or devices to update"))
+		}
+		steps, err := e.plan(ctx, params.Devices, params.Image)
+		if err != nil {
+			return fail(err)
+		}
+
+		pending := 0
+		for _, s := range steps {
+			if s.Status == StepPending {
+				pending++
+			}
+		}
+		if pending == 0 {
+			result.Success = true
+			result.Result = map[string]interface{}{"status": StepUpToDate, "devices": steps}
+			return result, nil
+		}
+
+		// Flashing can brick a device, so the user confirms the plan first
+		if !i.RequiresPermission {
+			result.Result = map[string]interface{}{"devices": steps}
+			return fail(agenterrors.Newf(agenterrors.ConfirmationRequired,
+				"updating firmware on %d device(s) needs confirmation; resend with requires_permission set", pending))
+		}
+
+		job := &Job{
+			ID:                uuid.NewID(),
+			Status:            JobRunning,
+			Image:             params.Image,
+			Rollback:          params.RollbackImage,
+			CreatedAt:         time.Now(),
+			Steps:             steps,
+			continueOnFailure: params.ContinueOnFailure,
+			intent:            i,
+		}
+		e.mu.Lock()
+		e.jobs = append(e.jobs, job)
+		if len(e.jobs) > maxJobs {
+			e.prune()
+		}
+		summary := job.summary()
+		e.mu.Unlock()
+
+		go e.run(job)
+
+		result.Success = true
+		result.Result = summary
+		return result, nil
+
+	case "device.firmware_status":
+		var params struct {
+			JobID string `param:"job_id"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if params.JobID != "" {
+			job := e.job(params.JobID)
+			if job == nil {
+				return fail(agenterrors.Newf(agenterrors.NotFound, "no firmware job '%s'", params.JobID))
+			}
+			result.Success = true
+			result.Result = job.summary()
+			return result, nil
+		}
+		jobs := make([]map[string]interface{}, 0, len(e.jobs))
+		for n := len(e.jobs) - 1; n >= 0; n-- {
+			jobs = append(jobs, e.jobs[n].summary())
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{"jobs": jobs}
+		return result, nil
+
+	case "device.firmware_cancel":
+		var params struct {
+			JobID string `param:"job_id,required"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		job := e.job(params.JobID)
+		if job == nil {
+			return fail(agenterrors.Newf(agenterrors.NotFound, "no firmware job '%s'", params.JobID))
+		}
+		if job.Status != JobRunning {
+			return fail(agenterrors.Newf(agenterrors.Conflict, "firmware job %s already %s", job.ID, job.Status))
+		}
+		// A device mid-install is left to finish; stopping a flash
+		// halfway is what bricks devices
+		job.cancelled = true
+		result.Success = true
+		result.Result = job.summary()
+		return result, nil
+
+	default:
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+		return result, nil
+	}
+}
+
+// plan resolves the devices and checks what each runs. Devices already on
+// the newest firmware are marked up to date, unless an image is given.
+func (e *Executor) plan(ctx context.Context, names []string, image string) ([]*Step, error) {
+	var steps []*Step
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		d, err := e.find(name)
+		if err != nil {
+			return nil, err
+		}
+		if seen[d.ID] {
+			continue
+		}
+		seen[d.ID] = true
+		u, ok := e.cfg.Updaters[d.Module]
+		if !ok {
+			return nil, agenterrors.Newf(agenterrors.Unsupported, "%s's firmware can't be updated by the agent", d.Name)
+		}
+
+		installed, available, err := u.CheckFirmware(ctx, d.ID)
+		if err != nil {
+			return nil, fmt.Errorf("checking %s: %w", d.Name, err)
+		}
+		s := &Step{Device: d.ID, Name: d.Name, Status: StepPending, From: installed, Available: available, updater: u}
+		if image == "" && available == installed {
+			s.Status = StepUpToDate
+		}
+		steps = append(steps, s)
+	}
+	return steps, nil
+}
+
+// find looks a device up by ID or name
+func (e *Executor) find(name string) (registry.Device, error) {
+	if d, ok := e.devices.Get(name); ok {
+		return d, nil
+	}
+	for _, d := range e.devices.List() {
+		if strings.EqualFold(d.ID, name) || strings.EqualFold(d.Name, name) {
+			return d, nil
+		}
+	}
+	return registry.Device{}, agenterrors.Newf(agenterrors.NotFound, "no device named '%s'", name)
+}
+
+// job finds a job by ID; the caller holds e.mu
+func (e *Executor) job(id string) *Job {
+	for _, j := range e.jobs {
+		if j.ID == id {
+			return j
+		}
+	}
+	return nil
+}
+
+// prune forgets the oldest finished jobs beyond maxJobs; the caller holds
+// e.mu
+func (e *Executor) prune() {
+	kept := e.jobs[:0]
+	excess := len(e.jobs) - maxJobs
+	for _, j := range e.jobs {
+		if excess > 0 && j.Status != JobRunning {
+			excess--
+			continue
+		}
+		kept = append(kept, j)
+	}
+	e.jobs = kept
+}
+
+// summary describes a job; the caller holds e.mu
+func (j *Job) summary() map[string]interface{} {
+	steps := make([]Step, len(j.Steps))
+	for n, s := range j.Steps {
+		steps[n] = *s
+	}
+	summary := map[string]interface{}{
+		"job_id":     j.ID,
+		"status":     j.Status,
+		"created_at": j.CreatedAt.Format(time.RFC3339),
+		"devices":    steps,
+	}
+	if !j.FinishedAt.IsZero() {
+		summary["finished_at"] = j.FinishedAt.Format(time.RFC3339)
+	}
+	if j.cancelled && j.Status == JobRunning {
+		summary["cancelling"] = true
+	}
+	return summary
+}
+
+func (e *Executor) IsAvailable() bool {
+	return true
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"device.update_firmware": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"device": {"type": "string", "minLength": 1},
+				"devices": {"type": "array", "items": {"type": "string", "minLength": 1}},
+				"image": {"type": "string"},
+				"rollback_image": {"type": "string"},
+				"continue_on_failure": {"type": "boolean"}
+			}
+		}`),
+		"device.firmware_status": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"job_id": {"type": "string"}
+			}
+		}`),
+		"device.firmware_cancel": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"job_id": {"type": "string", "minLength": 1}
+			},
+			"required": ["job_id"]
+		}`),
+	}
+}
@@ -0,0 +1,253 @@
+package firmware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+)
+
+// errUnchanged is a health check finding the old firmware still running
+var errUnchanged = errors.New("the update didn't take")
+
+// run updates a job's devices in order. A device that fails stops the
+// rollout, unless the job continues on failure, so one bad image doesn't
+// reach every device.
+func (e *Executor) run(job *Job) {
+	failed := false
+	for n, s := range job.Steps {
+		e.mu.Lock()
+		cancelled, status := job.cancelled, s.Status
+		e.mu.Unlock()
+		if status != StepPending {
+			continue
+		}
+		if cancelled || e.ctx.Err() != nil {
+			e.skip(job.Steps[n:], "the job was cancelled")
+			break
+		}
+
+		if !e.update(job, s) {
+			failed = true
+			if !job.continueOnFailure {
+				e.skip(job.Steps[n+1:], "the rollout stopped after "+s.Name+" failed")
+				break
+			}
+		}
+	}
+
+	e.mu.Lock()
+	switch {
+	case job.cancelled || e.ctx.Err() != nil:
+		job.Status = JobCancelled
+	case failed:
+		job.Status = JobFailed
+	default:
+		job.Status = JobCompleted
+	}
+	job.FinishedAt = time.Now()
+	summary := job.summary()
+	e.mu.Unlock()
+
+	e.logger.Info("Firmware job finished", "job", job.ID, "status", job.Status)
+	e.publish("firmware.job_finished", summary)
+}
+
+// update takes one device through install, verification, and on failure
+// rollback, reporting whether it ended up healthy on new firmware
+func (e *Executor) update(job *Job, s *Step) bool {
+	release, err := e.hold(job, s)
+	if err != nil {
+		e.finish(job, s, StepSkipped, "", err)
+		return true
+	}
+	defer release()
+
+	e.setStatus(s, StepUpdating)
+	e.logger.Info("Updating firmware", "device", s.Name, "from", s.From)
+	ctx, cancel := context.WithTimeout(e.ctx, e.cfg.InstallTimeout)
+	err = s.updater.InstallFirmware(ctx, s.Device, job.Image)
+	cancel()
+	if err != nil {
+		// The device refused or never started, so it still runs the old
+		// firmware and there's nothing to roll back
+		e.finish(job, s, StepFailed, "", fmt.Errorf("install failed: %w", err))
+		return false
+	}
+
+	e.setStatus(s, StepVerifying)
+	installed, err := e.verify(s, s.From)
+	if err == nil {
+		e.finish(job, s, StepUpdated, installed, nil)
+		return true
+	}
+	if errors.Is(err, errUnchanged) || job.Rollback == "" {
+		e.finish(job, s, StepFailed, installed, err)
+		return false
+	}
+
+	e.setStatus(s, StepRollingBack)
+	e.logger.Warn("Rolling firmware back", "device", s.Name, "error", err)
+	ctx, cancel = context.WithTimeout(e.ctx, e.cfg.InstallTimeout)
+	rollbackErr := s.updater.InstallFirmware(ctx, s.Device, job.Rollback)
+	cancel()
+	if rollbackErr == nil {
+		installed, rollbackErr = e.verify(s, "")
+	}
+	if rollbackErr != nil {
+		e.finish(job, s, StepFailed, installed, fmt.Errorf("%v, and rolling back failed: %w", err, rollbackErr))
+		return false
+	}
+	e.finish(job, s, StepRolledBack, installed, err)
+	return false
+}
+
+// hold takes the device exclusively, waiting a while for intents already
+// using it
+func (e *Executor) hold(job *Job, s *Step) (func(), error) {
+	deadline := time.Now().Add(busyWait)
+	for {
+		release, err := e.exclusions.Acquire(job.intent, []gateway.Exclusion{{Group: "device:" + s.Device}})
+		if err == nil || time.Now().After(deadline) {
+			return release, err
+		}
+		select {
+		case <-e.ctx.Done():
+			return nil, e.ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// verify waits for the device to report firmware other than from (any,
+// when from is empty) and then keep answering for the settle time,
+// returning the version it settled on
+func (e *Executor) verify(s *Step, from string) (string, error) {
+	deadline := time.Now().Add(e.cfg.HealthTimeout)
+	var installed string
+	var lastErr error
+	for {
+		if err := e.wait(e.cfg.PollInterval); err != nil {
+			return installed, err
+		}
+		v, err := e.check(s)
+		switch {
+		case err != nil:
+			lastErr = err
+		case from == "" || v != from:
+			// Back on new firmware; make sure it stays up
+			if err := e.wait(e.cfg.Settle); err != nil {
+				return v, err
+			}
+			settled, err := e.check(s)
+			if err != nil {
+				return v, fmt.Errorf("%s stopped answering after restarting on %s: %w", s.Name, v, err)
+			}
+			if settled != v {
+				return settled, fmt.Errorf("%s went from %s to %s after the update", s.Name, v, settled)
+			}
+			return v, nil
+		default:
+			installed, lastErr = v, nil
+		}
+
+		if time.Now().After(deadline) {
+			if lastErr == nil {
+				return installed, fmt.Errorf("%s still runs %s: %w", s.Name, installed, errUnchanged)
+			}
+			return installed, agenterrors.Newf(agenterrors.Timeout, "%s hasn't come back healthy since the update: %v", s.Name, lastErr)
+		}
+	}
+}
+
+func (e *Executor) check(s *Step) (string, error) {
+	ctx, cancel := context.WithTimeout(e.ctx, 30*time.Second)
+	defer cancel()
+	installed, _, err := s.updater.CheckFirmware(ctx, s.Device)
+	return installed, err
+}
+
+func (e *Executor) wait(d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-e.ctx.Done():
+		return e.ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (e *Executor) setStatus(s *Step, status string) {
+	e.mu.Lock()
+	s.Status = status
+	e.mu.Unlock()
+}
+
+// skip marks steps not yet started as skipped
+func (e *Executor) skip(steps []*Step, reason string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, s := range steps {
+		if s.Status == StepPending {
+			s.Status = StepSkipped
+			s.Error = reason
+		}
+	}
+}
+
+// finish records how a device's update ended and raises
+// firmware.device_updated or, on failure, firmware.update_failed
+func (e *Executor) finish(job *Job, s *Step, status, installed string, err error) {
+	e.mu.Lock()
+	s.Status = status
+	s.To = installed
+	if err != nil {
+		s.Error = err.Error()
+	}
+	step := *s
+	e.mu.Unlock()
+
+	data := map[string]interface{}{
+		"job_id": job.ID,
+		"device": step.Device,
+		"name":   step.Name,
+		"status": step.Status,
+		"from":   step.From,
+	}
+	if step.To != "" {
+		data["to"] = step.To
+	}
+	switch status {
+	case StepUpdated:
+		e.logger.Info("Updated firmware", "device", s.Name, "from", s.From, "to", installed)
+		e.publish("firmware.device_updated", data)
+	case StepSkipped:
+		e.logger.Info("Skipped firmware update", "device", s.Name, "reason", err)
+	default:
+		e.logger.Error("Firmware update failed", "device", s.Name, "status", status, "error", err)
+		data["error"] = step.Error
+		data["message"] = fmt.Sprintf("Firmware update on %s failed: %s", step.Name, step.Error)
+		if status == StepRolledBack {
+			data["message"] = fmt.Sprintf("Firmware update on %s failed and was rolled back to %s: %s", step.Name, step.To, step.Error)
+		}
+		e.publish("firmware.update_failed", data)
+	}
+}
+
+func (e *Executor) publish(eventType string, data map[string]interface{}) {
+	if e.bus == nil {
+		return
+	}
+	e.bus.Publish(events.Event{
+		ID:        events.NewID(),
+		Type:      eventType,
+		Source:    "firmware",
+		Data:      data,
+		Timestamp: time.Now(),
+	})
+}
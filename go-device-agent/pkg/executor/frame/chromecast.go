@@ -0,0 +1,128 @@
+package frame
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/internal/cast"
+	"github.com/vinod901/local-agent-core/go-device-agent/internal/uuid"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// castTimeout bounds showing one image
+const castTimeout = 20 * time.Second
+
+// Chromecast shows images on a Cast device with the default media
+// receiver. The device fetches each image from the MediaServer over the
+// local network.
+type Chromecast struct {
+	DisplayName string
+	Addr        string // host or host:port of the Cast device
+	Media       *MediaServer
+}
+
+func (c *Chromecast) Name() string {
+	return c.DisplayName
+}
+
+func (c *Chromecast) Show(ctx context.Context, path string) error {
+	ctx, cancel := context.WithTimeout(ctx, castTimeout)
+	defer cancel()
+
+	conn, err := cast.Dial(ctx, c.Addr)
+	if err != nil {
+		return agenterrors.Newf(agenterrors.Unavailable, "cannot reach %s: %w", c.DisplayName, err)
+	}
+	defer conn.Close()
+
+	// Serve the image on the interface the Cast device can reach us on
+	mediaURL, err := c.Media.publish(conn.LocalIP(), path)
+	if err != nil {
+		return err
+	}
+
+	app, err := conn.Launch(cast.DefaultMediaReceiver)
+	if err != nil {
+		return fmt.Errorf("%s could not start the media receiver: %w", c.DisplayName, err)
+	}
+	if err := conn.Load(app.TransportID, mediaURL, contentType(path), "NONE"); err != nil {
+		return fmt.Errorf("%s could not load the image: %w", c.DisplayName, err)
+	}
+	return nil
+}
+
+// MediaServer serves the images currently published to Cast devices under
+// unguessable URLs. Only the most recent images are reachable.
+type MediaServer struct {
+	addr string
+
+	mu     sync.Mutex
+	port   int
+	files  map[string]string
+	tokens []string
+}
+
+// maxPublished is how many recent images stay reachable
+const maxPublished = 16
+
+// NewMediaServer creates a server listening on addr (e.g. ":8099") once
+// started
+func NewMediaServer(addr string) *MediaServer {
+	return &MediaServer{addr: addr, files: make(map[string]string)}
+}
+
+// Start serves until ctx is cancelled
+func (m *MediaServer) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.port = listener.Addr().(*net.TCPAddr).Port
+	m.mu.Unlock()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /frame/{token}", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		path, ok := m.files[r.PathValue("token")]
+		m.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", contentType(path))
+		http.ServeFile(w, r, path)
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	go server.Serve(listener)
+	return nil
+}
+
+// publish makes path reachable and returns its URL on the given local IP
+func (m *MediaServer) publish(ip net.IP, path string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.port == 0 {
+		return "", errors.New("frame media server is not running")
+	}
+
+	token := uuid.New()
+	m.files[token] = path
+	m.tokens = append(m.tokens, token)
+	if len(m.tokens) > maxPublished {
+		delete(m.files, m.tokens[0])
+		m.tokens = m.tokens[1:]
+	}
+	return "http://" + net.JoinHostPort(ip.String(), strconv.Itoa(m.port)) + "/frame/" + token, nil
+}
@@ -0,0 +1,75 @@
+package frame
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Display shows one image at a time
+type Display interface {
+	// Name identifies the display in intents (e.g. "kitchen")
+	Name() string
+
+	// Show replaces what the display shows with the image at path
+	Show(ctx context.Context, path string) error
+}
+
+// Command shows images on a locally connected screen by running a viewer,
+// with "{}" in Args replaced by the image path. A viewer that keeps running
+// (e.g. "feh -F {}") is stopped once the next one has started; one that
+// exits (e.g. "feh --bg-fill {}") is simply run again.
+type Command struct {
+	DisplayName string
+	Program     string
+	Args        []string
+
+	mu      sync.Mutex
+	current *exec.Cmd
+}
+
+// NewCommand parses a viewer command line such as "feh -F {}"
+func NewCommand(name, commandLine string) (*Command, error) {
+	fields := strings.Fields(commandLine)
+	if len(fields) == 0 {
+		return nil, errors.New("empty viewer command")
+	}
+	return &Command{DisplayName: name, Program: fields[0], Args: fields[1:]}, nil
+}
+
+func (c *Command) Name() string {
+	return c.DisplayName
+}
+
+func (c *Command) Show(ctx context.Context, path string) error {
+	args := make([]string, len(c.Args))
+	replaced := false
+	for i, a := range c.Args {
+		if strings.Contains(a, "{}") {
+			replaced = true
+		}
+		args[i] = strings.ReplaceAll(a, "{}", path)
+	}
+	if !replaced {
+		args = append(args, path)
+	}
+
+	// Not tied to ctx: the viewer must outlive the intent that started it
+	cmd := exec.Command(c.Program, args...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", c.Program, err)
+	}
+	go cmd.Wait()
+
+	c.mu.Lock()
+	previous := c.current
+	c.current = cmd
+	c.mu.Unlock()
+	if previous != nil {
+		previous.Process.Kill() // fails harmlessly if it already exited
+	}
+	return nil
+}
@@ -0,0 +1,346 @@
+// Package frame turns connected screens and Chromecasts into photo frames,
+// showing images and rotating playlists drawn only from allowlisted local
+// folders
+package frame
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Config sets the image folders and default rotation
+type Config struct {
+	// Folders are the only places images are read from
+	Folders []string
+
+	// Interval between images when a folder is shown (default 1m)
+	Interval time.Duration
+}
+
+// minInterval keeps rotation from hammering displays
+const minInterval = 5 * time.Second
+
+// slideshow is what one display is showing
+type slideshow struct {
+	images   []string
+	pos      int
+	interval time.Duration // 0 when not rotating
+	nextAt   time.Time
+}
+
+// Executor handles frame.show, frame.next, and frame.stop
+type Executor struct {
+	cfg      Config
+	lib      *library
+	displays map[string]Display
+	order    []string
+
+	mu    sync.Mutex
+	shows map[string]*slideshow
+}
+
+// NewExecutor creates a frame executor for the allowlisted folders
+func NewExecutor(cfg Config) (*Executor, error) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.Interval < minInterval {
+		cfg.Interval = minInterval
+	}
+	lib, err := newLibrary(cfg.Folders)
+	if err != nil {
+		return nil, err
+	}
+	return &Executor{
+		cfg:      cfg,
+		lib:      lib,
+		displays: make(map[string]Display),
+		shows:    make(map[string]*slideshow),
+	}, nil
+}
+
+// AddDisplay registers a display. The first one added is the default.
+func (e *Executor) AddDisplay(d Display) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.displays[d.Name()]; !ok {
+		e.order = append(e.order, d.Name())
+	}
+	e.displays[d.Name()] = d
+}
+
+// Start rotates slideshows until ctx is cancelled
+func (e *Executor) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				e.rotate(ctx, now)
+			}
+		}
+	}()
+}
+
+func (e *Executor) rotate(ctx context.Context, now time.Time) {
+	type pending struct {
+		display Display
+		path    string
+	}
+	var due []pending
+
+	e.mu.Lock()
+	for name, s := range e.shows {
+		if s.interval == 0 || now.Before(s.nextAt) {
+			continue
+		}
+		s.pos = (s.pos + 1) % len(s.images)
+		s.nextAt = now.Add(s.interval)
+		due = append(due, pending{e.displays[name], s.images[s.pos]})
+	}
+	e.mu.Unlock()
+
+	for _, p := range due {
+		go p.display.Show(ctx, p.path)
+	}
+}
+
+func (e *Executor) Name() string {
+	return "frame"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"frame.show", "frame.next", "frame.stop"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	needs := sandbox.Needs{Read: e.cfg.Folders}
+	for _, d := range e.displays {
+		switch d.(type) {
+		case *Command:
+			needs.Subprocess = true
+		case *Chromecast:
+			needs.Network = true
+		}
+	}
+	return needs
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "frame",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	displayName, err := i.StringParamOr("display", "")
+	if err != nil {
+		return fail(err)
+	}
+	display, err := e.display(displayName)
+	if err != nil {
+		return fail(err)
+	}
+	name := display.Name()
+
+	switch i.IntentType {
+	case "frame.show":
+		var params struct {
+			Folder   string        `param:"folder"`
+			Image    string        `param:"image"`
+			Shuffle  bool          `param:"shuffle"`
+			Interval time.Duration `param:"interval"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		source := params.Folder
+		if params.Image != "" {
+			source = params.Image
+		}
+		path, err := e.lib.resolve(source)
+		if err != nil {
+			return fail(err)
+		}
+		images, err := e.lib.playlist(path, params.Shuffle)
+		if err != nil {
+			return fail(err)
+		}
+
+		interval := params.Interval
+		if interval <= 0 {
+			interval = e.cfg.Interval
+		}
+		if interval < minInterval {
+			interval = minInterval
+		}
+		if len(images) == 1 {
+			interval = 0
+		}
+
+		if err := display.Show(ctx, images[0]); err != nil {
+			return fail(err)
+		}
+		s := &slideshow{images: images, interval: interval, nextAt: time.Now().Add(interval)}
+		e.mu.Lock()
+		e.shows[name] = s
+		e.mu.Unlock()
+
+		result.Success = true
+		result.Result = e.describe(name, s)
+
+	case "frame.next":
+		back, err := i.BoolParamOr("previous", false)
+		if err != nil {
+			return fail(err)
+		}
+		e.mu.Lock()
+		s, ok := e.shows[name]
+		if !ok {
+			e.mu.Unlock()
+			return fail(agenterrors.Newf(agenterrors.Conflict, "nothing is showing on %s", name))
+		}
+		if back {
+			s.pos = (s.pos - 1 + len(s.images)) % len(s.images)
+		} else {
+			s.pos = (s.pos + 1) % len(s.images)
+		}
+		if s.interval > 0 {
+			s.nextAt = time.Now().Add(s.interval)
+		}
+		path := s.images[s.pos]
+		e.mu.Unlock()
+
+		if err := display.Show(ctx, path); err != nil {
+			return fail(err)
+		}
+		e.mu.Lock()
+		result.Result = e.describe(name, s)
+		e.mu.Unlock()
+		result.Success = true
+
+	case "frame.stop":
+		e.mu.Lock()
+		s, ok := e.shows[name]
+		if ok {
+			s.interval = 0
+		}
+		e.mu.Unlock()
+		result.Success = true
+		result.Result = map[string]interface{}{"display": name, "rotating": false}
+
+	default:
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+
+	return result, nil
+}
+
+// ShowFile shows a generated image, such as a QR code, on a display and
+// stops its slideshow. Unlike frame.show it isn't limited to the frame
+// folders, so it is for other executors rather than intents.
+func (e *Executor) ShowFile(ctx context.Context, displayName, path string) (string, error) {
+	display, err := e.display(displayName)
+	if err != nil {
+		return "", err
+	}
+	e.mu.Lock()
+	delete(e.shows, display.Name())
+	e.mu.Unlock()
+	return display.Name(), display.Show(ctx, path)
+}
+
+// display picks the named display, or the default when name is empty
+func (e *Executor) display(name string) (Display, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.order) == 0 {
+		return nil, agenterrors.New(agenterrors.Unavailable, "no displays configured")
+	}
+	if name == "" {
+		name = e.order[0]
+	}
+	d, ok := e.displays[name]
+	if !ok {
+		return nil, agenterrors.Newf(agenterrors.NotFound, "unknown display '%s' (have %v)", name, e.order)
+	}
+	return d, nil
+}
+
+// describe reports a slideshow's state; callers hold e.mu or own s
+func (e *Executor) describe(name string, s *slideshow) map[string]interface{} {
+	r := map[string]interface{}{
+		"display":  name,
+		"image":    e.relative(s.images[s.pos]),
+		"position": s.pos + 1,
+		"count":    len(s.images),
+		"rotating": s.interval > 0,
+	}
+	if s.interval > 0 {
+		r["interval"] = s.interval.String()
+	}
+	return r
+}
+
+// relative names an image by its folder-relative path, never revealing
+// anything outside the allowlist
+func (e *Executor) relative(path string) string {
+	for _, root := range e.lib.roots {
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return filepath.Join(filepath.Base(root), rel)
+		}
+	}
+	return filepath.Base(path)
+}
+
+func (e *Executor) IsAvailable() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.order) > 0
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"frame.show": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"display": {"type": "string"},
+				"folder": {"type": "string"},
+				"image": {"type": "string"},
+				"shuffle": {"type": "boolean"}
+			}
+		}`),
+		"frame.next": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"display": {"type": "string"},
+				"previous": {"type": "boolean"}
+			}
+		}`),
+		"frame.stop": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"display": {"type": "string"}
+			}
+		}`),
+	}
+}
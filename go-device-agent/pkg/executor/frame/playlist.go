@@ -0,0 +1,153 @@
+package frame
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// imageTypes maps the extensions shown on a frame to their MIME types
+var imageTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".bmp":  "image/bmp",
+}
+
+// maxPlaylist bounds how many images one playlist holds
+const maxPlaylist = 5000
+
+// library resolves folders and images inside the allowlisted roots
+type library struct {
+	roots []string // absolute, symlink-free
+}
+
+func newLibrary(folders []string) (*library, error) {
+	l := &library{}
+	for _, f := range folders {
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			return nil, err
+		}
+		real, err := filepath.EvalSymlinks(abs)
+		if err != nil {
+			return nil, fmt.Errorf("frame folder %s: %w", f, err)
+		}
+		l.roots = append(l.roots, real)
+	}
+	if len(l.roots) == 0 {
+		return nil, errors.New("no frame folders configured")
+	}
+	return l, nil
+}
+
+// resolve maps a name to a real path inside a root. Names are paths
+// relative to a root, optionally prefixed with the root's base name
+// ("Photos/2024"); an empty name is the first root.
+func (l *library) resolve(name string) (string, error) {
+	name = filepath.Clean("/" + strings.TrimSpace(name))[1:]
+	if name == "" {
+		return l.roots[0], nil
+	}
+	for _, root := range l.roots {
+		candidates := []string{filepath.Join(root, name)}
+		if first, rest, _ := strings.Cut(name, string(filepath.Separator)); strings.EqualFold(first, filepath.Base(root)) {
+			candidates = append(candidates, filepath.Join(root, rest))
+		}
+		for _, c := range candidates {
+			real, err := filepath.EvalSymlinks(c)
+			if err != nil {
+				continue
+			}
+			if l.contains(real) {
+				return real, nil
+			}
+		}
+	}
+	return "", agenterrors.Newf(agenterrors.DeniedByPolicy, "'%s' is not in an allowed frame folder", name)
+}
+
+// contains reports whether path lies inside an allowlisted root
+func (l *library) contains(path string) bool {
+	for _, root := range l.roots {
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// playlist lists the images under path (or path itself if it is an
+// image), skipping hidden files and anything that escapes the roots
+func (l *library) playlist(path string, shuffle bool) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		if !isImage(path) {
+			return nil, fmt.Errorf("%s is not an image", filepath.Base(path))
+		}
+		return []string{path}, nil
+	}
+
+	var images []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") && p != path {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !isImage(p) {
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			real, err := filepath.EvalSymlinks(p)
+			if err != nil || !l.contains(real) {
+				return nil
+			}
+			p = real
+		}
+		images = append(images, p)
+		if len(images) >= maxPlaylist {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("no images in %s", filepath.Base(path))
+	}
+
+	if shuffle {
+		rand.Shuffle(len(images), func(a, b int) { images[a], images[b] = images[b], images[a] })
+	} else {
+		sort.Strings(images)
+	}
+	return images, nil
+}
+
+func isImage(path string) bool {
+	_, ok := imageTypes[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+func contentType(path string) string {
+	return imageTypes[strings.ToLower(filepath.Ext(path))]
+}
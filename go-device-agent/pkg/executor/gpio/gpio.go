@@ -0,0 +1,250 @@
+// Package gpio drives and reads GPIO lines through Linux's gpiod character
+// device, for relays and sensors wired to a single-board computer
+package gpio
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/usage"
+)
+
+// maxPulse bounds a gpio.set pulse, which holds the intent open
+const maxPulse = 10 * time.Second
+
+// Executor handles gpio.set and gpio.read
+type Executor struct {
+	pins  map[string]*Pin
+	lines map[string]*line
+	names []string
+}
+
+// NewExecutor claims every pin's line, setting outputs to their initial
+// value, and holds them until Close
+func NewExecutor(pins map[string]*Pin) (*Executor, error) {
+	if len(pins) == 0 {
+		return nil, errors.New("no GPIO pins configured")
+	}
+	e := &Executor{pins: pins, lines: make(map[string]*line, len(pins))}
+	for name, p := range pins {
+		l, err := request(p)
+		if err != nil {
+			e.Close()
+			return nil, err
+		}
+		e.lines[name] = l
+		e.names = append(e.names, name)
+	}
+	sort.Strings(e.names)
+	return e, nil
+}
+
+// Close releases the lines
+func (e *Executor) Close() error {
+	for _, l := range e.lines {
+		l.close()
+	}
+	return nil
+}
+
+func (e *Executor) Name() string {
+	return "gpio"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"gpio.set", "gpio.read"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{Write: []string{"/dev"}} // the GPIO chips
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "gpio",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	switch i.IntentType {
+	case "gpio.set":
+		var params struct {
+			Pin     string `param:"pin,required"`
+			Value   bool   `param:"value,required"`
+			PulseMS int    `param:"pulse_ms"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		p, l, err := e.pin(params.Pin)
+		if err != nil {
+			return fail(err)
+		}
+		if p.Direction != "output" {
+			return fail(agenterrors.Newf(agenterrors.InvalidParams, "%s is an input", params.Pin))
+		}
+		if p.Confirm && !i.RequiresPermission {
+			return fail(agenterrors.Newf(agenterrors.ConfirmationRequired,
+				"switching %s needs requires_permission", params.Pin))
+		}
+		pulse := time.Duration(params.PulseMS) * time.Millisecond
+		if pulse > maxPulse {
+			return fail(agenterrors.Newf(agenterrors.InvalidParams, "pulses are at most %s", maxPulse))
+		}
+		previous, err := l.get()
+		if err != nil {
+			return fail(err)
+		}
+		if err := l.set(params.Value); err != nil {
+			return fail(err)
+		}
+		usage.DeviceCommand(ctx)
+		value := params.Value
+		if pulse > 0 {
+			// A pulse presses a button, such as a garage door's: the line
+			// goes back however the intent ends
+			timer := time.NewTimer(pulse)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+			}
+			if err := l.set(previous); err != nil {
+				return fail(err)
+			}
+			usage.DeviceCommand(ctx)
+			value = previous
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"pin":      params.Pin,
+			"value":    value,
+			"previous": previous,
+			"pulse_ms": params.PulseMS,
+		}
+
+	case "gpio.read":
+		var params struct {
+			Pin string `param:"pin"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		names := e.names
+		if params.Pin != "" {
+			if _, _, err := e.pin(params.Pin); err != nil {
+				return fail(err)
+			}
+			names = []string{params.Pin}
+		}
+		pins := make([]map[string]interface{}, 0, len(names))
+		for _, name := range names {
+			value, err := e.lines[name].get()
+			if err != nil {
+				return fail(err)
+			}
+			pin := map[string]interface{}{
+				"pin":       name,
+				"value":     value,
+				"direction": e.pins[name].Direction,
+			}
+			if d := e.pins[name].Description; d != "" {
+				pin["description"] = d
+			}
+			pins = append(pins, pin)
+		}
+		result.Success = true
+		if params.Pin != "" {
+			result.Result = pins[0]
+		} else {
+			result.Result = map[string]interface{}{
+				"pins": pins,
+			}
+		}
+
+	default:
+		return fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+	return result, nil
+}
+
+func (e *Executor) pin(name string) (*Pin, *line, error) {
+	p, ok := e.pins[name]
+	if !ok {
+		return nil, nil, agenterrors.Newf(agenterrors.NotFound, "no pin named %q (pins: %s)", name, strings.Join(e.names, ", "))
+	}
+	return p, e.lines[name], nil
+}
+
+// pressPoll is how often WaitPress reads its pin
+const pressPoll = 20 * time.Millisecond
+
+// WaitPress waits until the input pin is released and then pressed, as a
+// push button wired to it is, or ctx ends. A button held down when the
+// wait starts must be let go first, so a stuck one never counts.
+func (e *Executor) WaitPress(ctx context.Context, name string) error {
+	p, l, err := e.pin(name)
+	if err != nil {
+		return err
+	}
+	if p.Direction != "input" {
+		return agenterrors.Newf(agenterrors.InvalidParams, "%s is an output", name)
+	}
+	ticker := time.NewTicker(pressPoll)
+	defer ticker.Stop()
+	released := false
+	for {
+		active, err := l.get()
+		if err != nil {
+			return err
+		}
+		if !active {
+			released = true
+		} else if released {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Executor) IsAvailable() bool {
+	return len(e.lines) > 0
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"gpio.set": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"pin": {"type": "string", "minLength": 1},
+				"value": {"type": "boolean"},
+				"pulse_ms": {"type": "integer", "minimum": 1, "maximum": 10000}
+			},
+			"required": ["pin", "value"]
+		}`),
+		"gpio.read": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"pin": {"type": "string", "minLength": 1}
+			}
+		}`),
+	}
+}
@@ -0,0 +1,216 @@
+package gpio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// The GPIO character device uAPI, v2 (Linux 5.10 and later)
+
+const (
+	flagActiveLow   = 1 << 1
+	flagInput       = 1 << 2
+	flagOutput      = 1 << 3
+	flagOpenDrain   = 1 << 6
+	flagOpenSource  = 1 << 7
+	flagPullUp      = 1 << 8
+	flagPullDown    = 1 << 9
+	flagBiasDisable = 1 << 10
+
+	attrOutputValues = 2
+)
+
+type lineAttribute struct {
+	ID    uint32
+	_     uint32
+	Value uint64
+}
+
+type lineConfigAttribute struct {
+	Attr lineAttribute
+	Mask uint64
+}
+
+type lineConfig struct {
+	Flags    uint64
+	NumAttrs uint32
+	_        [5]uint32
+	Attrs    [10]lineConfigAttribute
+}
+
+type lineRequest struct {
+	Offsets         [64]uint32
+	Consumer        [32]byte
+	Config          lineConfig
+	NumLines        uint32
+	EventBufferSize uint32
+	_               [5]uint32
+	Fd              int32
+}
+
+type lineValues struct {
+	Bits uint64
+	Mask uint64
+}
+
+type chipInfo struct {
+	Name  [32]byte
+	Label [32]byte
+	Lines uint32
+}
+
+func iowr(nr, size uintptr) uintptr {
+	return 3<<30 | size<<16 | 0xB4<<8 | nr
+}
+
+var (
+	getChipInfo   = 2<<30 | unsafe.Sizeof(chipInfo{})<<16 | 0xB4<<8 | 0x01
+	getLine       = iowr(0x07, unsafe.Sizeof(lineRequest{}))
+	getLineValues = iowr(0x0E, unsafe.Sizeof(lineValues{}))
+	setLineValues = iowr(0x0F, unsafe.Sizeof(lineValues{}))
+)
+
+func ioctl(fd int, req uintptr, arg unsafe.Pointer) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), req, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// line is a requested GPIO line, held until closed so outputs keep their
+// value and nothing else drives them
+type line struct {
+	mu sync.Mutex
+	fd int
+}
+
+// request claims a pin's line with its direction, bias, and drive
+func request(p *Pin) (*line, error) {
+	path, err := chipPath(p.Chip)
+	if err != nil {
+		return nil, err
+	}
+	chip, err := unix.Open(path, unix.O_RDWR|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, lineError(p, err)
+	}
+	defer unix.Close(chip)
+
+	var req lineRequest
+	req.Offsets[0] = uint32(p.Line)
+	req.NumLines = 1
+	copy(req.Consumer[:], "device-agent")
+	flags := uint64(flagInput)
+	if p.Direction == "output" {
+		flags = flagOutput
+		switch p.Drive {
+		case "open-drain":
+			flags |= flagOpenDrain
+		case "open-source":
+			flags |= flagOpenSource
+		}
+		req.Config.NumAttrs = 1
+		req.Config.Attrs[0] = lineConfigAttribute{
+			Attr: lineAttribute{ID: attrOutputValues, Value: boolBit(p.Initial)},
+			Mask: 1,
+		}
+	}
+	switch p.Bias {
+	case "pull-up":
+		flags |= flagPullUp
+	case "pull-down":
+		flags |= flagPullDown
+	case "disabled":
+		flags |= flagBiasDisable
+	}
+	if p.ActiveLow {
+		flags |= flagActiveLow
+	}
+	req.Config.Flags = flags
+	if err := ioctl(chip, getLine, unsafe.Pointer(&req)); err != nil {
+		return nil, lineError(p, err)
+	}
+	return &line{fd: int(req.Fd)}, nil
+}
+
+func (l *line) get() (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	v := lineValues{Mask: 1}
+	if err := ioctl(l.fd, getLineValues, unsafe.Pointer(&v)); err != nil {
+		return false, agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	return v.Bits&1 != 0, nil
+}
+
+func (l *line) set(value bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	v := lineValues{Bits: boolBit(value), Mask: 1}
+	if err := ioctl(l.fd, setLineValues, unsafe.Pointer(&v)); err != nil {
+		return agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	return nil
+}
+
+func (l *line) close() error {
+	return unix.Close(l.fd)
+}
+
+func boolBit(v bool) uint64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// chipPath finds a chip by name, path, or label
+func chipPath(chip string) (string, error) {
+	if strings.HasPrefix(chip, "/") {
+		return chip, nil
+	}
+	if _, err := os.Stat("/dev/" + chip); err == nil {
+		return "/dev/" + chip, nil
+	}
+	paths, _ := filepath.Glob("/dev/gpiochip*")
+	for _, path := range paths {
+		fd, err := unix.Open(path, unix.O_RDONLY|unix.O_CLOEXEC, 0)
+		if err != nil {
+			continue
+		}
+		var info chipInfo
+		err = ioctl(fd, getChipInfo, unsafe.Pointer(&info))
+		unix.Close(fd)
+		if err == nil && string(bytes.TrimRight(info.Label[:], "\x00")) == chip {
+			return path, nil
+		}
+	}
+	return "", agenterrors.Newf(agenterrors.NotFound, "no GPIO chip %s", chip)
+}
+
+// lineError explains why a line couldn't be claimed
+func lineError(p *Pin, err error) error {
+	where := fmt.Sprintf("%s line %d", p.Chip, p.Line)
+	switch {
+	case errors.Is(err, unix.EBUSY):
+		return agenterrors.Newf(agenterrors.Conflict, "%s is in use by another program or driver", where)
+	case errors.Is(err, unix.EACCES), errors.Is(err, unix.EPERM):
+		return agenterrors.Newf(agenterrors.Unauthorized, "%s: permission denied (add the agent's user to the gpio group)", where)
+	case errors.Is(err, unix.ENOENT):
+		return agenterrors.Newf(agenterrors.NotFound, "no GPIO chip %s", p.Chip)
+	case errors.Is(err, unix.EINVAL):
+		return agenterrors.Newf(agenterrors.InvalidParams, "%s doesn't exist or doesn't support this configuration", where)
+	}
+	return agenterrors.Newf(agenterrors.Unavailable, "%s: %v", where, err)
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+
+package gpio
+
+import "github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+
+// line is never claimed here; GPIO goes through Linux's character device
+type line struct{}
+
+func request(p *Pin) (*line, error) {
+	return nil, agenterrors.New(agenterrors.Unsupported, "GPIO needs Linux")
+}
+
+func (l *line) get() (bool, error) {
+	return false, agenterrors.New(agenterrors.Unsupported, "GPIO needs Linux")
+}
+
+func (l *line) set(value bool) error {
+	return agenterrors.New(agenterrors.Unsupported, "GPIO needs Linux")
+}
+
+func (l *line) close() error {
+	return nil
+}
@@ -0,0 +1,96 @@
+package gpio
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Pin is one GPIO line, addressed by chip and line offset as gpioinfo
+// lists them (GPIO17 on a Raspberry Pi header is line 17 of gpiochip0,
+// or of pinctrl-rp1 on a Pi 5)
+type Pin struct {
+	// Chip is a chip name (gpiochip0), device path, or label (default
+	// gpiochip0)
+	Chip string `json:"chip,omitempty"`
+	Line int    `json:"line"`
+
+	// Direction is input or output
+	Direction string `json:"direction"`
+
+	// ActiveLow inverts the line, as for relay boards that switch on low
+	ActiveLow bool `json:"active_low,omitempty"`
+
+	// Bias is pull-up, pull-down, or disabled for inputs (default as is)
+	Bias string `json:"bias,omitempty"`
+
+	// Drive is open-drain or open-source for outputs (default push-pull)
+	Drive string `json:"drive,omitempty"`
+
+	// Initial is an output's value when the agent starts (default off)
+	Initial bool `json:"initial,omitempty"`
+
+	// Confirm makes gpio.set on this pin need requires_permission, for
+	// relays switching mains power or locks
+	Confirm bool `json:"confirm,omitempty"`
+
+	Description string `json:"description,omitempty"`
+}
+
+// LoadPins reads the "pins" object of a hardware file, keyed by name:
+//
+//	{"pins": {"garden-pump": {"line": 17, "direction": "output", "active_low": true}}}
+//
+// Other keys, such as the serial ports, are ignored.
+func LoadPins(path string) (map[string]*Pin, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Pins map[string]*Pin `json:"pins"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid hardware file %s: %w", path, err)
+	}
+	for name, p := range file.Pins {
+		if err := p.check(); err != nil {
+			return nil, fmt.Errorf("invalid hardware file %s: pin %q: %w", path, name, err)
+		}
+	}
+	return file.Pins, nil
+}
+
+func (p *Pin) check() error {
+	if p.Chip == "" {
+		p.Chip = "gpiochip0"
+	}
+	if p.Line < 0 {
+		return fmt.Errorf("line must not be negative")
+	}
+	p.Direction = strings.ToLower(p.Direction)
+	switch p.Direction {
+	case "input":
+		if p.Drive != "" {
+			return fmt.Errorf("drive is for outputs")
+		}
+	case "output":
+		if p.Bias != "" {
+			return fmt.Errorf("bias is for inputs")
+		}
+	default:
+		return fmt.Errorf("direction must be input or output")
+	}
+	switch p.Bias {
+	case "", "pull-up", "pull-down", "disabled":
+	default:
+		return fmt.Errorf("bias must be pull-up, pull-down, or disabled")
+	}
+	switch p.Drive {
+	case "", "push-pull", "open-drain", "open-source":
+	default:
+		return fmt.Errorf("drive must be push-pull, open-drain, or open-source")
+	}
+	return nil
+}
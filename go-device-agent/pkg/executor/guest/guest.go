@@ -0,0 +1,174 @@
+// Package guest lets the household hand out time-boxed, scoped access,
+// e.g. the guest room's lights and media for a weekend
+package guest
+
+import (
+	"context"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/access"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Executor handles guest.grant, guest.revoke, and guest.list
+type Executor struct {
+	grants *access.Grants
+}
+
+// NewExecutor creates a guest executor over the grant store
+func NewExecutor(grants *access.Grants) *Executor {
+	return &Executor{grants: grants}
+}
+
+func (e *Executor) Name() string {
+	return "guest"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"guest.grant", "guest.revoke", "guest.list"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{}
+}
+
+// PermissionRequired keeps grants from being minted or revoked implicitly
+func (e *Executor) PermissionRequired() []string {
+	return []string{"guest.grant", "guest.revoke"}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "guest",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	// Guests never manage access, whatever their grant says
+	if i.GuestToken != "" && i.IntentType != "guest.list" {
+		return fail(agenterrors.New(agenterrors.DeniedByPolicy, "guests cannot manage grants"))
+	}
+
+	switch i.IntentType {
+	case "guest.grant":
+		var params struct {
+			Name      string        `param:"name,required"`
+			Duration  time.Duration `param:"duration,required"`
+			SpeakerID string        `param:"speaker_id"`
+			Rules     []access.Rule `param:"rules,required"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		grant, token, err := e.grants.Create(params.Name, params.SpeakerID, params.Rules, params.Duration)
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = describe(grant)
+		if token != "" {
+			result.Result["token"] = token
+		}
+
+	case "guest.revoke":
+		id, err := i.StringParam("id")
+		if err != nil {
+			return fail(err)
+		}
+		grant, err := e.grants.Revoke(id)
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = describe(grant)
+
+	case "guest.list":
+		// A guest only sees their own grant
+		own, err := e.grants.Lookup(i)
+		if err != nil {
+			return fail(err)
+		}
+		now := time.Now()
+		list := []map[string]interface{}{}
+		for _, grant := range e.grants.List() {
+			if own != nil && own.ID != grant.ID {
+				continue
+			}
+			g := describe(grant)
+			g["active"] = grant.Active(now)
+			list = append(list, g)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{"grants": list}
+
+	default:
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+
+	return result, nil
+}
+
+// describe reports a grant without its token hash
+func describe(g access.Grant) map[string]interface{} {
+	r := map[string]interface{}{
+		"id":         g.ID,
+		"name":       g.Name,
+		"rules":      g.Rules,
+		"created_at": g.CreatedAt.Format(time.RFC3339),
+		"expires_at": g.ExpiresAt.Format(time.RFC3339),
+	}
+	if g.SpeakerID != "" {
+		r["speaker_id"] = g.SpeakerID
+	}
+	if !g.RevokedAt.IsZero() {
+		r["revoked_at"] = g.RevokedAt.Format(time.RFC3339)
+	}
+	return r
+}
+
+func (e *Executor) IsAvailable() bool {
+	return true
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"guest.grant": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"name": {"type": "string", "minLength": 1, "maxLength": 64},
+				"duration": {"type": "string", "minLength": 1},
+				"speaker_id": {"type": "string"},
+				"rules": {
+					"type": "array",
+					"items": {
+						"type": "object",
+						"properties": {
+							"intent_types": {"type": "array", "items": {"type": "string", "minLength": 1}},
+							"params": {"type": "object"}
+						},
+						"required": ["intent_types"]
+					}
+				}
+			},
+			"required": ["name", "duration", "rules"]
+		}`),
+		"guest.revoke": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"id": {"type": "string", "minLength": 1}
+			},
+			"required": ["id"]
+		}`),
+		"guest.list": schema.MustParse(`{"type": "object", "properties": {}}`),
+	}
+}
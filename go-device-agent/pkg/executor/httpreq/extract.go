@@ -0,0 +1,70 @@
+package httpreq
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// parsePath splits a JSON path such as "data.items[0].name", "$.state", or
+// "attributes.friendly name" into object keys and array indexes
+func parsePath(path string) ([]string, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return nil, nil
+	}
+	var steps []string
+	for _, part := range strings.Split(path, ".") {
+		key, rest, _ := strings.Cut(part, "[")
+		if key == "" && rest == "" {
+			return nil, agenterrors.Newf(agenterrors.InvalidParams, "invalid path %q: empty key", path)
+		}
+		if key != "" {
+			steps = append(steps, key)
+		}
+		for rest != "" {
+			index, after, ok := strings.Cut(rest, "]")
+			if _, err := strconv.Atoi(index); !ok || err != nil {
+				return nil, agenterrors.Newf(agenterrors.InvalidParams, "invalid path %q: bad index [%s", path, rest)
+			}
+			steps = append(steps, "["+index)
+			rest = strings.TrimPrefix(after, "[")
+			if after != "" && !strings.HasPrefix(after, "[") {
+				return nil, agenterrors.Newf(agenterrors.InvalidParams, "invalid path %q: unexpected %q", path, after)
+			}
+		}
+	}
+	return steps, nil
+}
+
+// lookup follows a parsed path through decoded JSON. A plain number also
+// indexes an array, so "items.0" works like "items[0]"; negative indexes
+// count from the end.
+func lookup(v interface{}, steps []string) (interface{}, bool) {
+	for _, step := range steps {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			next, ok := node[step]
+			if !ok || strings.HasPrefix(step, "[") {
+				return nil, false
+			}
+			v = next
+		case []interface{}:
+			n, err := strconv.Atoi(strings.TrimPrefix(step, "["))
+			if err != nil {
+				return nil, false
+			}
+			if n < 0 {
+				n += len(node)
+			}
+			if n < 0 || n >= len(node) {
+				return nil, false
+			}
+			v = node[n]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
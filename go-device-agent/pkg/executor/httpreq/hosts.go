@@ -0,0 +1,196 @@
+package httpreq
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/secrets"
+)
+
+// Host is a service http.request may call. Hosts are listed in a JSON file:
+//
+//	[
+//	  {"host": "homeassistant.local:8123",
+//	   "headers": {"Authorization": "Bearer {{env:HA_TOKEN}}"}},
+//	  {"host": "*.lan", "methods": ["GET"]},
+//	  {"host": "nas.lan:5001", "ca_file": "/etc/agent/nas-ca.pem"}
+//	]
+type Host struct {
+	// Host is a host name, "*.example.lan" for a domain's subdomains, or an
+	// IP address, optionally with a port; without one any port matches
+	Host string `json:"host"`
+
+	// Methods limits the methods allowed (default all)
+	Methods []string `json:"methods,omitempty"`
+
+	// Headers are added to every request to the host, replacing any the
+	// intent gives. Values are templates: {{env:NAME}} expands to an
+	// environment variable and {{secret:name}} to a secret (see package
+	// secrets), keeping tokens out of intents, and
+	// {{intent_id}}, {{correlation_id}}, {{session_id}}, and {{host}} to the
+	// request's.
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// CAFile is a PEM bundle of certificate authorities trusted for the
+	// host instead of the system's, for self-signed local services.
+	// Certificates are always verified.
+	CAFile string `json:"ca_file,omitempty"`
+
+	name, port string
+	client     *http.Client
+}
+
+// LoadHosts reads a JSON list of hosts
+func LoadHosts(path string) ([]Host, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var hosts []Host
+	if err := json.Unmarshal(data, &hosts); err != nil {
+		return nil, fmt.Errorf("invalid hosts file %s: %w", path, err)
+	}
+	for n := range hosts {
+		if err := hosts[n].prepare(); err != nil {
+			return nil, fmt.Errorf("invalid hosts file %s: %w", path, err)
+		}
+	}
+	return hosts, nil
+}
+
+// prepare splits the pattern and checks the methods and headers
+func (h *Host) prepare() error {
+	if h.Host == "" {
+		return fmt.Errorf("host entry missing a host")
+	}
+	h.name, h.port = strings.ToLower(h.Host), ""
+	if name, port, err := net.SplitHostPort(h.Host); err == nil {
+		h.name, h.port = strings.ToLower(name), port
+	}
+	h.name = strings.Trim(h.name, "[]")
+	for n, m := range h.Methods {
+		h.Methods[n] = strings.ToUpper(m)
+		if !slices.Contains(methods, h.Methods[n]) {
+			return fmt.Errorf("host %s: unknown method %s", h.Host, m)
+		}
+	}
+	for name, value := range h.Headers {
+		for _, v := range placeholders(value) {
+			if !strings.HasPrefix(v, "env:") && !strings.HasPrefix(v, "secret:") && !slices.Contains(templateVars, v) {
+				return fmt.Errorf("host %s: header %s: unknown template {{%s}}", h.Host, name, v)
+			}
+		}
+	}
+	return nil
+}
+
+// tlsConfig loads the host's CA bundle, if it has one
+func (h *Host) tlsConfig() (*tls.Config, error) {
+	if h.CAFile == "" {
+		return nil, nil
+	}
+	pem, err := os.ReadFile(h.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("host %s: %w", h.Host, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("host %s: no certificates in %s", h.Host, h.CAFile)
+	}
+	return &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}, nil
+}
+
+// matches reports whether the host covers a URL's host name and port
+func (h *Host) matches(name, port string) bool {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if h.port != "" && h.port != port {
+		return false
+	}
+	if domain, ok := strings.CutPrefix(h.name, "*."); ok {
+		return strings.HasSuffix(name, "."+domain)
+	}
+	if ip := net.ParseIP(h.name); ip != nil {
+		return ip.Equal(net.ParseIP(name))
+	}
+	return name == h.name
+}
+
+// allows reports whether the host may be sent a method
+func (h *Host) allows(method string) bool {
+	return len(h.Methods) == 0 || slices.Contains(h.Methods, method)
+}
+
+// templateVars are the request details headers may use
+var templateVars = []string{"intent_id", "correlation_id", "session_id", "host"}
+
+// placeholders lists the {{names}} in a template
+func placeholders(tmpl string) []string {
+	var names []string
+	for {
+		start := strings.Index(tmpl, "{{")
+		if start < 0 {
+			return names
+		}
+		end := strings.Index(tmpl[start:], "}}")
+		if end < 0 {
+			return names
+		}
+		names = append(names, strings.TrimSpace(tmpl[start+2:start+end]))
+		tmpl = tmpl[start+end+2:]
+	}
+}
+
+// expand fills in a header template. {{env:NAME}} and {{secret:name}} are
+// only expanded when env is set, for headers from the hosts file; intents
+// can't read the agent's environment or secrets.
+func expand(tmpl string, vars map[string]string, env bool) (string, error) {
+	var b strings.Builder
+	for {
+		start := strings.Index(tmpl, "{{")
+		if start < 0 {
+			b.WriteString(tmpl)
+			return b.String(), nil
+		}
+		end := strings.Index(tmpl[start:], "}}")
+		if end < 0 {
+			b.WriteString(tmpl)
+			return b.String(), nil
+		}
+		b.WriteString(tmpl[:start])
+		name := strings.TrimSpace(tmpl[start+2 : start+end])
+		if variable, ok := strings.CutPrefix(name, "env:"); ok {
+			if !env {
+				return "", agenterrors.Newf(agenterrors.DeniedByPolicy, "{{%s}}: only configured headers may use environment variables", name)
+			}
+			value, set := os.LookupEnv(variable)
+			if !set {
+				return "", agenterrors.Newf(agenterrors.Unavailable, "environment variable %s is not set", variable)
+			}
+			b.WriteString(value)
+		} else if secret, ok := strings.CutPrefix(name, "secret:"); ok {
+			if !env {
+				return "", agenterrors.Newf(agenterrors.DeniedByPolicy, "{{%s}}: only configured headers may use secrets", name)
+			}
+			value, err := secrets.Default.Get(secret)
+			if err != nil {
+				return "", agenterrors.Wrap(agenterrors.Unavailable, err)
+			}
+			b.WriteString(value)
+		} else {
+			value, ok := vars[name]
+			if !ok {
+				return "", agenterrors.Newf(agenterrors.InvalidParams, "unknown header template {{%s}} (one of %s)", name, strings.Join(templateVars, ", "))
+			}
+			b.WriteString(value)
+		}
+		tmpl = tmpl[start+end+2:]
+	}
+}
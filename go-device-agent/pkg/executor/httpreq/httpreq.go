@@ -0,0 +1,387 @@
+// Package httpreq answers http.request by calling local services (Home
+// Assistant, Node-RED, a NAS, a printer's web API) on an allowlist of
+// hosts, so simple integrations need no executor of their own
+package httpreq
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// methods are those http.request sends
+var methods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+
+// reservedHeaders are set by the transport and can't be given
+var reservedHeaders = []string{"Host", "Content-Length", "Transfer-Encoding", "Connection", "Upgrade", "Te", "Trailer", "Keep-Alive", "Proxy-Authorization", "Proxy-Connection"}
+
+// maxRedirects bounds redirects followed within a host
+const maxRedirects = 5
+
+// Config sets the hosts and limits
+type Config struct {
+	// Hosts are the only services requests may go to
+	Hosts []Host
+
+	// Timeout bounds a request unless the intent asks for less
+	// (default 15s)
+	Timeout time.Duration
+
+	// MaxResponse caps the response body read, in bytes (default 256 KiB);
+	// intents may ask for less
+	MaxResponse int64
+
+	// MaxBody caps the request body sent (default 64 KiB)
+	MaxBody int
+}
+
+// Executor handles http.request
+type Executor struct {
+	cfg   Config
+	hosts []*Host
+}
+
+// NewExecutor prepares the hosts, loading their CA bundles
+func NewExecutor(cfg Config) (*Executor, error) {
+	if len(cfg.Hosts) == 0 {
+		return nil, errors.New("no hosts configured for http.request")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 15 * time.Second
+	}
+	if cfg.MaxResponse <= 0 {
+		cfg.MaxResponse = 256 << 10
+	}
+	if cfg.MaxBody <= 0 {
+		cfg.MaxBody = 64 << 10
+	}
+	e := &Executor{cfg: cfg}
+	for n := range cfg.Hosts {
+		h := &cfg.Hosts[n]
+		if h.name == "" {
+			if err := h.prepare(); err != nil {
+				return nil, err
+			}
+		}
+		tlsConfig, err := h.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		// Redirects are followed only within the host, so its headers
+		// never reach a host they weren't configured for
+		h.client = connpool.Default.HTTP(0)
+		if tlsConfig != nil {
+			h.client = connpool.Default.TLSClient(0, tlsConfig)
+		}
+		h.client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects || req.URL.Host != via[0].URL.Host || req.URL.Scheme != via[0].URL.Scheme {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		}
+		e.hosts = append(e.hosts, h)
+	}
+	return e, nil
+}
+
+func (e *Executor) Name() string {
+	return "http"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"http.request"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{Network: true}
+}
+
+// Hosts returns the allowed hosts
+func (e *Executor) Hosts() []string {
+	hosts := make([]string, len(e.hosts))
+	for n, h := range e.hosts {
+		hosts[n] = h.Host
+	}
+	return hosts
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "http",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	if i.IntentType != "http.request" {
+		return fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+
+	var params struct {
+		URL            string            `param:"url,required"`
+		Method         string            `param:"method"`
+		Headers        map[string]string `param:"headers"`
+		Query          map[string]string `param:"query"`
+		Body           *string           `param:"body"`
+		JSON           interface{}       `param:"json"`
+		Extract        map[string]string `param:"extract"`
+		OmitBody       bool              `param:"omit_body"`
+		MaxBytes       int64             `param:"max_bytes"`
+		TimeoutSeconds float64           `param:"timeout_seconds"`
+	}
+	if err := i.DecodeParams(&params); err != nil {
+		return fail(err)
+	}
+	method := strings.ToUpper(params.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	target, err := url.Parse(params.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fail(agenterrors.Newf(agenterrors.InvalidParams, "url must be an absolute http or https URL: %s", params.URL))
+	}
+	if target.User != nil {
+		return fail(agenterrors.New(agenterrors.InvalidParams, "credentials in the url are not allowed; configure a header for the host instead"))
+	}
+	host := e.host(target)
+	if host == nil {
+		return fail(agenterrors.Newf(agenterrors.DeniedByPolicy, "%s is not an allowed host", target.Host))
+	}
+	if !host.allows(method) {
+		return fail(agenterrors.Newf(agenterrors.DeniedByPolicy, "%s is not allowed to %s", method, host.Host))
+	}
+	if len(params.Query) > 0 {
+		query := target.Query()
+		for k, v := range params.Query {
+			query.Set(k, v)
+		}
+		target.RawQuery = query.Encode()
+	}
+
+	var body []byte
+	contentType := ""
+	switch {
+	case params.Body != nil && params.JSON != nil:
+		return fail(agenterrors.New(agenterrors.InvalidParams, "give body or json, not both"))
+	case params.Body != nil:
+		body = []byte(*params.Body)
+	case params.JSON != nil:
+		if body, err = json.Marshal(params.JSON); err != nil {
+			return fail(agenterrors.Wrap(agenterrors.InvalidParams, fmt.Errorf("encoding json: %w", err)))
+		}
+		contentType = "application/json"
+	}
+	if len(body) > e.cfg.MaxBody {
+		return fail(agenterrors.Newf(agenterrors.InvalidParams, "body is %d bytes; the limit is %d", len(body), e.cfg.MaxBody))
+	}
+	if body != nil && (method == http.MethodGet || method == http.MethodHead) {
+		return fail(agenterrors.Newf(agenterrors.InvalidParams, "%s requests can't have a body", method))
+	}
+
+	extract := make(map[string][]string, len(params.Extract))
+	for name, path := range params.Extract {
+		if extract[name], err = parsePath(path); err != nil {
+			return fail(err)
+		}
+	}
+	limit := e.cfg.MaxResponse
+	if params.MaxBytes > 0 && params.MaxBytes < limit {
+		limit = params.MaxBytes
+	}
+	timeout := e.cfg.Timeout
+	if t := time.Duration(params.TimeoutSeconds * float64(time.Second)); t > 0 && t < timeout {
+		timeout = t
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return fail(agenterrors.Wrap(agenterrors.InvalidParams, fmt.Errorf("building request: %w", err)))
+	}
+	if body == nil {
+		req.Body, req.ContentLength = nil, 0
+	}
+	vars := map[string]string{
+		"intent_id":      i.ID,
+		"correlation_id": i.CorrelationID,
+		"session_id":     i.SessionID,
+		"host":           target.Hostname(),
+	}
+	for name, value := range params.Headers {
+		if isReserved(name) {
+			return fail(agenterrors.Newf(agenterrors.InvalidParams, "header %s can't be set", name))
+		}
+		if value, err = expand(value, vars, false); err != nil {
+			return fail(err)
+		}
+		req.Header.Set(name, value)
+	}
+	if contentType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for name, value := range host.Headers {
+		if value, err = expand(value, vars, true); err != nil {
+			return fail(err)
+		}
+		req.Header.Set(name, value)
+	}
+
+	start := time.Now()
+	resp, err := host.client.Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fail(agenterrors.Newf(agenterrors.Timeout, "%s %s took longer than %s", method, target.Redacted(), timeout))
+		}
+		if errors.Is(err, connpool.ErrEgressBlocked) {
+			return fail(agenterrors.Wrap(agenterrors.DeniedByPolicy, fmt.Errorf("request blocked: %w", err)))
+		}
+		return fail(agenterrors.Wrap(agenterrors.Unavailable, fmt.Errorf("request failed: %w", err)))
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return fail(agenterrors.Wrap(agenterrors.Unavailable, fmt.Errorf("reading response: %w", err)))
+	}
+	if int64(len(data)) > limit {
+		return fail(agenterrors.Newf(agenterrors.Unavailable, "response is larger than the %d-byte limit", limit))
+	}
+
+	result.Result = map[string]interface{}{
+		"status":       resp.StatusCode,
+		"url":          resp.Request.URL.Redacted(),
+		"bytes":        len(data),
+		"duration_ms":  time.Since(start).Milliseconds(),
+		"content_type": resp.Header.Get("Content-Type"),
+	}
+	if location := resp.Header.Get("Location"); location != "" {
+		result.Result["location"] = location
+	}
+
+	var decoded interface{}
+	isJSON := false
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") || len(extract) > 0 {
+		isJSON = json.Unmarshal(data, &decoded) == nil
+	}
+	if !params.OmitBody && len(data) > 0 {
+		switch {
+		case isJSON:
+			result.Result["json"] = decoded
+		case utf8.Valid(data):
+			result.Result["body"] = string(data)
+		default:
+			result.Result["body_base64"] = base64.StdEncoding.EncodeToString(data)
+		}
+	}
+	if len(extract) > 0 {
+		if !isJSON {
+			return fail(agenterrors.New(agenterrors.Unavailable, "response is not JSON, so nothing can be extracted"))
+		}
+		values := make(map[string]interface{}, len(extract))
+		var missing []string
+		for name, steps := range extract {
+			v, ok := lookup(decoded, steps)
+			if !ok {
+				missing = append(missing, name)
+			}
+			values[name] = v
+		}
+		result.Result["extracted"] = values
+		if len(missing) > 0 {
+			result.Result["missing"] = missing
+		}
+	}
+
+	if resp.StatusCode/100 != 2 {
+		code := agenterrors.Unavailable
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			code = agenterrors.NotFound
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			code = agenterrors.Unauthorized
+		case resp.StatusCode == http.StatusTooManyRequests:
+			code = agenterrors.RateLimited
+		case resp.StatusCode/100 == 4:
+			code = agenterrors.InvalidParams
+		}
+		result.Fail(agenterrors.Newf(code, "%s %s: %s", method, target.Redacted(), resp.Status))
+		return result, nil
+	}
+	result.Success = true
+	return result, nil
+}
+
+// host finds the first allowed host covering a URL
+func (e *Executor) host(u *url.URL) *Host {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	for _, h := range e.hosts {
+		if h.matches(u.Hostname(), port) {
+			return h
+		}
+	}
+	return nil
+}
+
+func isReserved(header string) bool {
+	for _, r := range reservedHeaders {
+		if strings.EqualFold(header, r) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Executor) IsAvailable() bool {
+	return true
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"http.request": schema.MustParse(fmt.Sprintf(`{
+			"type": "object",
+			"properties": {
+				"url": {"type": "string", "pattern": "^https?://"},
+				"method": {"type": "string", "enum": ["GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "get", "head", "post", "put", "patch", "delete"]},
+				"headers": {"type": "object"},
+				"query": {"type": "object"},
+				"body": {"type": "string", "maxLength": %d},
+				"json": {"description": "any JSON value, sent as application/json"},
+				"extract": {"type": "object", "description": "name to JSON path, e.g. {\"temp\": \"attributes.temperature\"}"},
+				"omit_body": {"type": "boolean"},
+				"max_bytes": {"type": "integer", "minimum": 1},
+				"timeout_seconds": {"type": "number", "minimum": 0}
+			},
+			"required": ["url"],
+			"additionalProperties": false
+		}`, e.cfg.MaxBody)),
+	}
+}
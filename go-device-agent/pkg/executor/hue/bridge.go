@@ -0,0 +1,212 @@
+package hue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/usage"
+)
+
+// Hue API error types, from the bridge's v1 API
+const (
+	errUnauthorizedUser    = 1
+	errResourceUnavailable = 3
+	errLinkButton          = 101
+	errDeviceOff           = 201
+)
+
+// maxResponse bounds bridge responses; a large install's full config is
+// well under this
+const maxResponse = 4 << 20
+
+// bridge talks to a paired bridge over the local v1 REST API
+type bridge struct {
+	addr     string
+	username string
+	client   *http.Client
+}
+
+type apiError struct {
+	Type        int    `json:"type"`
+	Address     string `json:"address"`
+	Description string `json:"description"`
+}
+
+// apiResult is one element of the array the bridge answers writes with
+type apiResult struct {
+	Error   *apiError              `json:"error"`
+	Success map[string]interface{} `json:"success"`
+}
+
+type lightState struct {
+	On        bool      `json:"on"`
+	Bri       int       `json:"bri"`
+	XY        []float64 `json:"xy,omitempty"`
+	CT        int       `json:"ct,omitempty"`
+	ColorMode string    `json:"colormode,omitempty"`
+	Reachable bool      `json:"reachable"`
+}
+
+type light struct {
+	Name  string     `json:"name"`
+	Type  string     `json:"type"`
+	State lightState `json:"state"`
+}
+
+type group struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	Lights []string `json:"lights"`
+}
+
+type scene struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	Group  string   `json:"group"`
+	Lights []string `json:"lights"`
+}
+
+func newBridge(addr, username string) *bridge {
+	return &bridge{addr: addr, username: username, client: connpool.Default.HTTP(10 * time.Second)}
+}
+
+// pair asks the bridge for an app key, which it grants for 30 seconds after
+// its link button is pressed
+func (b *bridge) pair(ctx context.Context, deviceType string) (string, error) {
+	var results []apiResult
+	body := map[string]interface{}{"devicetype": deviceType}
+	if err := b.request(ctx, http.MethodPost, "/api", body, &results); err != nil {
+		return "", err
+	}
+	if err := firstError(results); err != nil {
+		return "", err
+	}
+	for _, r := range results {
+		if username, ok := r.Success["username"].(string); ok {
+			return username, nil
+		}
+	}
+	return "", agenterrors.New(agenterrors.Unavailable, "bridge did not return an app key")
+}
+
+func (b *bridge) lights(ctx context.Context) (map[string]light, error) {
+	var lights map[string]light
+	return lights, b.get(ctx, "/lights", &lights)
+}
+
+func (b *bridge) groups(ctx context.Context) (map[string]group, error) {
+	var groups map[string]group
+	return groups, b.get(ctx, "/groups", &groups)
+}
+
+func (b *bridge) scenes(ctx context.Context) (map[string]scene, error) {
+	var scenes map[string]scene
+	return scenes, b.get(ctx, "/scenes", &scenes)
+}
+
+// setLight changes one light's state
+func (b *bridge) setLight(ctx context.Context, id string, state map[string]interface{}) error {
+	usage.DeviceCommand(ctx)
+	return b.put(ctx, "/lights/"+id+"/state", state)
+}
+
+// setGroup changes every light in a group; group "0" is all lights
+func (b *bridge) setGroup(ctx context.Context, id string, action map[string]interface{}) error {
+	usage.DeviceCommand(ctx)
+	return b.put(ctx, "/groups/"+id+"/action", action)
+}
+
+func (b *bridge) get(ctx context.Context, path string, out interface{}) error {
+	return b.request(ctx, http.MethodGet, "/api/"+b.username+path, nil, out)
+}
+
+func (b *bridge) put(ctx context.Context, path string, body interface{}) error {
+	var results []apiResult
+	if err := b.request(ctx, http.MethodPut, "/api/"+b.username+path, body, &results); err != nil {
+		return err
+	}
+	return firstError(results)
+}
+
+func (b *bridge) request(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, "http://"+b.addr+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return agenterrors.Newf(agenterrors.Unavailable, "cannot reach Hue bridge at %s: %w", b.addr, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponse))
+	if err != nil {
+		return agenterrors.Newf(agenterrors.Unavailable, "reading Hue bridge response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return agenterrors.Newf(agenterrors.Unavailable, "Hue bridge answered %s", resp.Status)
+	}
+
+	// Reads of collections answer with an object, but failures always come
+	// back as an array of errors
+	trimmed := bytes.TrimSpace(data)
+	if _, isList := out.(*[]apiResult); !isList && bytes.HasPrefix(trimmed, []byte("[")) {
+		var results []apiResult
+		if err := json.Unmarshal(trimmed, &results); err == nil {
+			if err := firstError(results); err != nil {
+				return err
+			}
+		}
+	}
+	if err := json.Unmarshal(trimmed, out); err != nil {
+		return fmt.Errorf("unexpected Hue bridge response: %w", err)
+	}
+	return nil
+}
+
+// firstError classifies the first error in a write response
+func firstError(results []apiResult) error {
+	for _, r := range results {
+		if r.Error == nil {
+			continue
+		}
+		switch r.Error.Type {
+		case errUnauthorizedUser:
+			return agenterrors.New(agenterrors.Unauthorized, "the Hue bridge no longer accepts our app key; pair again with light.pair")
+		case errLinkButton:
+			return agenterrors.New(agenterrors.Conflict, "press the link button on the Hue bridge, then pair again within 30 seconds")
+		case errResourceUnavailable:
+			return agenterrors.Newf(agenterrors.NotFound, "Hue bridge: %s", r.Error.Description)
+		case errDeviceOff:
+			return agenterrors.Newf(agenterrors.Conflict, "Hue bridge: %s", r.Error.Description)
+		default:
+			return agenterrors.Newf(agenterrors.InvalidParams, "Hue bridge: %s", r.Error.Description)
+		}
+	}
+	return nil
+}
+
+// normalizeAddr strips the scheme and path from a configured bridge address
+func normalizeAddr(addr string) string {
+	addr = strings.TrimPrefix(strings.TrimPrefix(addr, "http://"), "https://")
+	addr, _, _ = strings.Cut(addr, "/")
+	return addr
+}
@@ -0,0 +1,140 @@
+package hue
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/internal/mdns"
+)
+
+// Bridge is a Hue bridge found on the local network
+type Bridge struct {
+	ID      string `json:"id,omitempty"`
+	Address string `json:"address"`
+}
+
+var ssdpAddr = &net.UDPAddr{IP: net.IPv4(239, 255, 255, 250), Port: 1900}
+
+const hueService = "_hue._tcp.local."
+
+// Discover looks for bridges with mDNS and SSDP at once, listening until
+// ctx is done, and returns them deduplicated by ID
+func Discover(ctx context.Context) ([]Bridge, error) {
+	var (
+		mu    sync.Mutex
+		found = make(map[string]Bridge)
+		errs  []error
+		wg    sync.WaitGroup
+	)
+	add := func(b Bridge, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		key := strings.ToLower(b.ID)
+		if key == "" {
+			key = b.Address
+		}
+		if _, ok := found[key]; !ok {
+			found[key] = b
+		}
+	}
+	for _, search := range []func(context.Context, func(Bridge, error)){searchMDNS, searchSSDP} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			search(ctx, add)
+		}()
+	}
+	wg.Wait()
+
+	bridges := make([]Bridge, 0, len(found))
+	for _, b := range found {
+		bridges = append(bridges, b)
+	}
+	sort.Slice(bridges, func(a, b int) bool { return bridges[a].Address < bridges[b].Address })
+	if len(bridges) == 0 && len(errs) == 2 {
+		return nil, errors.Join(errs...)
+	}
+	return bridges, nil
+}
+
+// searchSSDP sends an M-SEARCH and keeps responses carrying hue-bridgeid
+func searchSSDP(ctx context.Context, add func(Bridge, error)) {
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		add(Bridge{}, err)
+		return
+	}
+	defer conn.Close()
+	stopOnDone(ctx, conn)
+
+	msg := "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: 239.255.255.250:1900\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 2\r\n" +
+		"ST: upnp:rootdevice\r\n\r\n"
+	if _, err := conn.WriteToUDP([]byte(msg), ssdpAddr); err != nil {
+		add(Bridge{}, err)
+		return
+	}
+
+	buf := make([]byte, 2048)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buf[:n])), nil)
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+		id := resp.Header.Get("hue-bridgeid")
+		if id == "" {
+			continue
+		}
+		addr := from.IP.String()
+		if loc, err := url.Parse(resp.Header.Get("Location")); err == nil && loc.Host != "" {
+			addr = loc.Host
+			if loc.Port() == "80" {
+				addr = loc.Hostname()
+			}
+		}
+		add(Bridge{ID: id, Address: addr}, nil)
+	}
+}
+
+// searchMDNS asks for _hue._tcp instances and reads the bridge ID from
+// their TXT records
+func searchMDNS(ctx context.Context, add func(Bridge, error)) {
+	err := mdns.Browse(ctx, hueService, func(s mdns.Service) {
+		add(Bridge{ID: s.TXT["bridgeid"], Address: s.Addr.String()}, nil)
+	})
+	if err != nil {
+		add(Bridge{}, err)
+	}
+}
+
+// stopOnDone unblocks reads on conn once ctx is done
+func stopOnDone(ctx context.Context, conn *net.UDPConn) {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetReadDeadline(deadline)
+		return
+	}
+	go func() {
+		<-ctx.Done()
+		conn.SetReadDeadline(time.Now())
+	}()
+}
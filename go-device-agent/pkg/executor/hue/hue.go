@@ -0,0 +1,523 @@
+// Package hue controls Philips Hue lights through a bridge on the local
+// network, found with mDNS or SSDP and paired with the link button
+package hue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/registry"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Config sets where the pairing is kept and how the bridge is found
+type Config struct {
+	// StatePath stores the paired bridge's address and app key
+	StatePath string
+
+	// Address of the bridge (host or host:port), skipping discovery
+	Address string
+
+	// DiscoveryTimeout bounds discovery (default 5s)
+	DiscoveryTimeout time.Duration
+}
+
+// pairing is the persisted bridge token
+type pairing struct {
+	BridgeID string    `json:"bridge_id,omitempty"`
+	Address  string    `json:"address"`
+	Username string    `json:"username"`
+	PairedAt time.Time `json:"paired_at"`
+}
+
+// deviceType identifies the agent in the bridge's list of apps
+const deviceType = "local-agent#device-agent"
+
+// Executor handles light.discover, light.pair, light.list, light.on,
+// light.off, light.brightness, light.color, and light.scene
+type Executor struct {
+	cfg      Config
+	registry *registry.Registry
+
+	mu      sync.Mutex
+	pairing *pairing
+}
+
+// NewExecutor creates a Hue executor, loading any saved pairing. Lights are
+// mirrored into reg when it is not nil.
+func NewExecutor(cfg Config, reg *registry.Registry) (*Executor, error) {
+	if cfg.DiscoveryTimeout <= 0 {
+		cfg.DiscoveryTimeout = 5 * time.Second
+	}
+	cfg.Address = normalizeAddr(cfg.Address)
+	e := &Executor{cfg: cfg, registry: reg}
+	if cfg.StatePath == "" {
+		return e, nil
+	}
+	data, err := os.ReadFile(cfg.StatePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return e, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p pairing
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	e.pairing = &p
+	return e, nil
+}
+
+func (e *Executor) Name() string {
+	return "hue"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"light.discover", "light.pair", "light.list", "light.on", "light.off", "light.brightness", "light.color", "light.scene"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{Network: true, Write: []string{filepath.Dir(e.cfg.StatePath)}}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "hue",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	switch i.IntentType {
+	case "light.discover":
+		bridges, err := e.discover(ctx)
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{"bridges": bridges}
+		return result, nil
+
+	case "light.pair":
+		address, err := i.StringParamOr("address", e.cfg.Address)
+		if err != nil {
+			return fail(err)
+		}
+		p, err := e.pair(ctx, normalizeAddr(address))
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{"bridge_id": p.BridgeID, "address": p.Address}
+		return result, nil
+	}
+
+	b, err := e.bridge()
+	if err != nil {
+		return fail(err)
+	}
+
+	switch i.IntentType {
+	case "light.list":
+		lights, err := e.list(ctx, b)
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{"lights": lights}
+		return result, nil
+
+	case "light.scene":
+		var params struct {
+			Scene string `param:"scene,required"`
+			Room  string `param:"room"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		s, groupID, err := e.findScene(ctx, b, params.Scene, params.Room)
+		if err != nil {
+			return fail(err)
+		}
+		if err := b.setGroup(ctx, groupID, map[string]interface{}{"scene": s.ID}); err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{"scene": s.Name, "group": groupID}
+		return result, nil
+	}
+
+	var params struct {
+		Light            string `param:"light"`
+		Room             string `param:"room"`
+		Brightness       *int   `param:"brightness"`
+		Color            string `param:"color"`
+		ColorTemperature int    `param:"color_temperature"`
+	}
+	if err := i.DecodeParams(&params); err != nil {
+		return fail(err)
+	}
+
+	state := map[string]interface{}{"on": true}
+	switch i.IntentType {
+	case "light.on":
+		if params.Brightness != nil {
+			state["bri"] = bri(*params.Brightness)
+		}
+	case "light.off":
+		state["on"] = false
+	case "light.brightness":
+		if params.Brightness == nil {
+			return fail(agenterrors.New(agenterrors.InvalidParams, "brightness is required"))
+		}
+		if *params.Brightness <= 0 {
+			state["on"] = false
+		} else {
+			state["bri"] = bri(*params.Brightness)
+		}
+	case "light.color":
+		if params.Brightness != nil {
+			state["bri"] = bri(*params.Brightness)
+		}
+	default:
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+		return result, nil
+	}
+	if i.IntentType != "light.off" {
+		switch {
+		case params.ColorTemperature > 0:
+			state["ct"] = mired(params.ColorTemperature)
+		case params.Color != "":
+			color, err := colorState(params.Color)
+			if err != nil {
+				return fail(agenterrors.Wrap(agenterrors.InvalidParams, err))
+			}
+			for k, v := range color {
+				state[k] = v
+			}
+		case i.IntentType == "light.color":
+			return fail(agenterrors.New(agenterrors.InvalidParams, "color or color_temperature is required"))
+		}
+	}
+
+	t, err := e.resolve(ctx, b, params.Light, params.Room)
+	if err != nil {
+		return fail(err)
+	}
+	if t.group {
+		err = b.setGroup(ctx, t.id, state)
+	} else {
+		err = b.setLight(ctx, t.id, state)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	e.record(t, state)
+
+	result.Success = true
+	result.Result = map[string]interface{}{"target": t.name, "on": state["on"]}
+	if v, ok := state["bri"].(int); ok {
+		result.Result["brightness"] = percent(v)
+	}
+	return result, nil
+}
+
+// bridge returns a client for the paired bridge
+func (e *Executor) bridge() (*bridge, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.pairing == nil {
+		return nil, agenterrors.New(agenterrors.Unavailable, "no Hue bridge paired; press its link button and send light.pair")
+	}
+	return newBridge(e.pairing.Address, e.pairing.Username), nil
+}
+
+func (e *Executor) discover(ctx context.Context) ([]Bridge, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.cfg.DiscoveryTimeout)
+	defer cancel()
+	bridges, err := Discover(ctx)
+	if err != nil {
+		return nil, agenterrors.Newf(agenterrors.Unavailable, "bridge discovery failed: %w", err)
+	}
+	return bridges, nil
+}
+
+// pair requests an app key from the bridge at address, or the only one
+// discovery finds, and saves it
+func (e *Executor) pair(ctx context.Context, address string) (*pairing, error) {
+	var id string
+	if address == "" {
+		bridges, err := e.discover(ctx)
+		if err != nil {
+			return nil, err
+		}
+		switch len(bridges) {
+		case 0:
+			return nil, agenterrors.New(agenterrors.NotFound, "no Hue bridge found on the network; pass its address")
+		case 1:
+			address, id = bridges[0].Address, bridges[0].ID
+		default:
+			return nil, agenterrors.Newf(agenterrors.InvalidParams, "found %d Hue bridges; pass the address of the one to pair", len(bridges))
+		}
+	}
+
+	username, err := newBridge(address, "").pair(ctx, deviceType)
+	if err != nil {
+		return nil, err
+	}
+	p := &pairing{BridgeID: id, Address: address, Username: username, PairedAt: time.Now().UTC()}
+	if err := e.save(p); err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	e.pairing = p
+	e.mu.Unlock()
+	return p, nil
+}
+
+// save writes the pairing atomically; the app key grants full control of
+// the lights, so the file is private
+func (e *Executor) save(p *pairing) error {
+	if e.cfg.StatePath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(e.cfg.StatePath), 0o700); err != nil {
+		return err
+	}
+	tmp := e.cfg.StatePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, e.cfg.StatePath)
+}
+
+// target is a light or a group of lights
+type target struct {
+	group  bool
+	id     string
+	name   string
+	lights []string
+}
+
+// resolve finds the light or room (room, zone, or other group) by name or
+// ID. "all" as the room addresses every light.
+func (e *Executor) resolve(ctx context.Context, b *bridge, lightName, room string) (target, error) {
+	switch {
+	case lightName != "":
+		lights, err := b.lights(ctx)
+		if err != nil {
+			return target{}, err
+		}
+		for id, l := range lights {
+			if id == lightName || strings.EqualFold(l.Name, lightName) {
+				return target{id: id, name: l.Name, lights: []string{id}}, nil
+			}
+		}
+		return target{}, agenterrors.Newf(agenterrors.NotFound, "no Hue light named '%s'", lightName)
+
+	case strings.EqualFold(room, "all"):
+		return target{group: true, id: "0", name: "all lights"}, nil
+
+	case room != "":
+		groups, err := b.groups(ctx)
+		if err != nil {
+			return target{}, err
+		}
+		for id, g := range groups {
+			if id == room || strings.EqualFold(g.Name, room) {
+				return target{group: true, id: id, name: g.Name, lights: g.Lights}, nil
+			}
+		}
+		return target{}, agenterrors.Newf(agenterrors.NotFound, "no Hue room or zone named '%s'", room)
+	}
+	return target{}, agenterrors.New(agenterrors.InvalidParams, "name a light or a room")
+}
+
+// sceneMatch is a scene found by name
+type sceneMatch struct {
+	ID   string
+	Name string
+}
+
+// findScene finds a scene by name, within the room if given, returning the
+// group to recall it on
+func (e *Executor) findScene(ctx context.Context, b *bridge, name, room string) (sceneMatch, string, error) {
+	scenes, err := b.scenes(ctx)
+	if err != nil {
+		return sceneMatch{}, "", err
+	}
+	groupID := ""
+	if room != "" {
+		t, err := e.resolve(ctx, b, "", room)
+		if err != nil {
+			return sceneMatch{}, "", err
+		}
+		groupID = t.id
+	}
+
+	var matches []string
+	for id, s := range scenes {
+		if strings.EqualFold(s.Name, name) && (groupID == "" || s.Group == groupID) {
+			matches = append(matches, id)
+		}
+	}
+	sort.Strings(matches)
+	switch len(matches) {
+	case 0:
+		return sceneMatch{}, "", agenterrors.Newf(agenterrors.NotFound, "no Hue scene named '%s'", name)
+	case 1:
+	default:
+		if groupID == "" {
+			return sceneMatch{}, "", agenterrors.Newf(agenterrors.InvalidParams, "several rooms have a scene named '%s'; name the room", name)
+		}
+	}
+	s := scenes[matches[0]]
+	group := s.Group
+	if group == "" {
+		group = "0" // light scenes are recalled on the all-lights group
+	}
+	return sceneMatch{ID: matches[0], Name: s.Name}, group, nil
+}
+
+// list reports every light with its room and mirrors them into the registry
+func (e *Executor) list(ctx context.Context, b *bridge) ([]map[string]interface{}, error) {
+	lights, err := b.lights(ctx)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := b.groups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rooms := make(map[string]string)
+	for _, g := range groups {
+		if g.Type != "Room" {
+			continue
+		}
+		for _, id := range g.Lights {
+			rooms[id] = g.Name
+		}
+	}
+
+	ids := make([]string, 0, len(lights))
+	for id := range lights {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	list := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		l := lights[id]
+		state := map[string]interface{}{
+			"on":         l.State.On,
+			"brightness": percent(l.State.Bri),
+			"reachable":  l.State.Reachable,
+		}
+		if e.registry != nil {
+			e.registry.Upsert(registry.Device{
+				ID:     "hue:" + id,
+				Name:   l.Name,
+				Kind:   registry.KindLight,
+				Room:   rooms[id],
+				Module: "hue",
+				State:  state,
+			})
+		}
+		entry := map[string]interface{}{"id": id, "name": l.Name, "room": rooms[id]}
+		for k, v := range state {
+			entry[k] = v
+		}
+		list = append(list, entry)
+	}
+	return list, nil
+}
+
+// record mirrors a state change into the registry for lights it knows
+func (e *Executor) record(t target, state map[string]interface{}) {
+	if e.registry == nil {
+		return
+	}
+	update := map[string]interface{}{"on": state["on"]}
+	if v, ok := state["bri"].(int); ok {
+		update["brightness"] = percent(v)
+	}
+	for _, id := range t.lights {
+		e.registry.UpdateState("hue:"+id, update) // unknown until light.list
+	}
+}
+
+func (e *Executor) IsAvailable() bool {
+	return true
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	target := `
+				"light": {"type": "string", "minLength": 1},
+				"room": {"type": "string", "minLength": 1},`
+	return map[string]*schema.Schema{
+		"light.discover": schema.MustParse(`{"type": "object", "properties": {}}`),
+		"light.pair": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"address": {"type": "string", "minLength": 1}
+			}
+		}`),
+		"light.list": schema.MustParse(`{"type": "object", "properties": {}}`),
+		"light.on": schema.MustParse(`{
+			"type": "object",
+			"properties": {` + target + `
+				"brightness": {"type": "integer", "minimum": 0, "maximum": 100},
+				"color": {"type": "string", "minLength": 1},
+				"color_temperature": {"type": "integer", "minimum": 2000, "maximum": 6500}
+			}
+		}`),
+		"light.off": schema.MustParse(`{
+			"type": "object",
+			"properties": {` + strings.TrimSuffix(target, ",") + `
+			}
+		}`),
+		"light.brightness": schema.MustParse(`{
+			"type": "object",
+			"properties": {` + target + `
+				"brightness": {"type": "integer", "minimum": 0, "maximum": 100}
+			},
+			"required": ["brightness"]
+		}`),
+		"light.color": schema.MustParse(`{
+			"type": "object",
+			"properties": {` + target + `
+				"brightness": {"type": "integer", "minimum": 0, "maximum": 100},
+				"color": {"type": "string", "minLength": 1},
+				"color_temperature": {"type": "integer", "minimum": 2000, "maximum": 6500}
+			}
+		}`),
+		"light.scene": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"scene": {"type": "string", "minLength": 1},
+				"room": {"type": "string", "minLength": 1}
+			},
+			"required": ["scene"]
+		}`),
+	}
+}
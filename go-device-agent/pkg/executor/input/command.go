@@ -0,0 +1,32 @@
+package input
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// run runs an input tool, feeding it stdin. Text goes through stdin rather
+// than arguments so other users can't read it from the process list.
+func run(ctx context.Context, stdin string, argv ...string) error {
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return agenterrors.Newf(agenterrors.Timeout, "%s did not finish", argv[0])
+		}
+		var exit *exec.ExitError
+		if msg := strings.TrimSpace(stderr.String()); errors.As(err, &exit) && msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return agenterrors.Wrap(agenterrors.Unavailable, fmt.Errorf("%s: %w", argv[0], err))
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+// Package input types text and clicks the mouse on the desktop for
+// automation: xdotool under X11, ydotool under Wayland, System Events and
+// Quartz events on macOS, and SendInput on Windows. Because it can do
+// anything the user at the keyboard can, it is off unless configured, every
+// action needs requires_permission, and what it typed or clicked is written
+// to the audit log.
+package input
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// timeout bounds each action, typing included
+const timeout = 30 * time.Second
+
+// buttons maps the buttons input.click takes to X11 button numbers, which
+// the backends translate from
+var buttons = map[string]int{"left": 1, "middle": 2, "right": 3}
+
+// backend injects input into the user's session
+type backend interface {
+	name() string
+	typeText(ctx context.Context, text string) error
+	pressEnter(ctx context.Context) error
+	click(ctx context.Context, x, y, button int, double bool) error
+}
+
+// Config limits input automation
+type Config struct {
+	// MaxText is the most characters input.type types at once (default 500)
+	MaxText int
+}
+
+// Executor handles input.type and input.click
+type Executor struct {
+	cfg     Config
+	backend backend
+}
+
+// NewExecutor finds a way to inject input into the desktop session,
+// failing when there is none (as on a headless machine)
+func NewExecutor(cfg Config) (*Executor, error) {
+	if cfg.MaxText <= 0 {
+		cfg.MaxText = 500
+	}
+	b, err := newBackend()
+	if err != nil {
+		return nil, err
+	}
+	return &Executor{cfg: cfg, backend: b}, nil
+}
+
+// Backend names the input tool in use, e.g. "xdotool"
+func (e *Executor) Backend() string {
+	return e.backend.name()
+}
+
+func (e *Executor) Name() string {
+	return "input"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"input.type", "input.click"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	// xdotool, ydotool, or cliclick; Windows uses SendInput
+	return sandbox.Needs{Subprocess: runtime.GOOS != "windows"}
+}
+
+// PermissionRequired makes every action need requires_permission
+func (e *Executor) PermissionRequired() []string {
+	return e.SupportedActions()
+}
+
+// Exclusions runs one action at a time, so typed text never interleaves
+// with a click
+func (e *Executor) Exclusions(i *intent.Intent) []gateway.Exclusion {
+	return []gateway.Exclusion{{Group: "input:desktop"}}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "input",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	var params struct {
+		Text      string `param:"text"`
+		Enter     bool   `param:"enter"`
+		Sensitive bool   `param:"sensitive"`
+		X         *int   `param:"x"`
+		Y         *int   `param:"y"`
+		Button    string `param:"button"`
+		Double    bool   `param:"double"`
+	}
+	if err := i.DecodeParams(&params); err != nil {
+		return fail(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	switch i.IntentType {
+	case "input.type":
+		if params.Text == "" && !params.Enter {
+			return fail(agenterrors.New(agenterrors.InvalidParams, "text or enter is required"))
+		}
+		chars := utf8.RuneCountInString(params.Text)
+		if chars > e.cfg.MaxText {
+			return fail(agenterrors.Newf(agenterrors.InvalidParams, "text is %d characters; the limit is %d", chars, e.cfg.MaxText))
+		}
+		if strings.ContainsFunc(params.Text, func(r rune) bool { return unicode.IsControl(r) && r != '\n' && r != '\t' }) {
+			return fail(agenterrors.New(agenterrors.InvalidParams, "text may not contain control characters other than newline and tab"))
+		}
+		result.Audit = map[string]interface{}{
+			"backend":   e.backend.name(),
+			"chars":     chars,
+			"enter":     params.Enter,
+			"sensitive": params.Sensitive,
+		}
+		// Passwords and the like are typed but kept out of the audit log
+		if !params.Sensitive {
+			result.Audit["text"] = params.Text
+		}
+		if params.Text != "" {
+			if err := e.backend.typeText(ctx, params.Text); err != nil {
+				return fail(err)
+			}
+		}
+		if params.Enter {
+			if err := e.backend.pressEnter(ctx); err != nil {
+				return fail(err)
+			}
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"chars": chars,
+			"enter": params.Enter,
+		}
+
+	case "input.click":
+		if params.X == nil || params.Y == nil {
+			return fail(agenterrors.New(agenterrors.InvalidParams, "x and y are required"))
+		}
+		if *params.X < 0 || *params.Y < 0 {
+			return fail(agenterrors.New(agenterrors.InvalidParams, "x and y must not be negative"))
+		}
+		if params.Button == "" {
+			params.Button = "left"
+		}
+		button, ok := buttons[params.Button]
+		if !ok {
+			return fail(agenterrors.Newf(agenterrors.InvalidParams, "unknown button %q: use left, middle, or right", params.Button))
+		}
+		result.Audit = map[string]interface{}{
+			"backend": e.backend.name(),
+			"x":       *params.X,
+			"y":       *params.Y,
+			"button":  params.Button,
+			"double":  params.Double,
+		}
+		if err := e.backend.click(ctx, *params.X, *params.Y, button, params.Double); err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"x":      *params.X,
+			"y":      *params.Y,
+			"button": params.Button,
+			"double": params.Double,
+		}
+
+	default:
+		return fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+	return result, nil
+}
+
+func (e *Executor) IsAvailable() bool {
+	return e.backend != nil
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"input.type": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"text": {"type": "string"},
+				"enter": {"type": "boolean"},
+				"sensitive": {"type": "boolean"}
+			}
+		}`),
+		"input.click": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"x": {"type": "integer", "minimum": 0},
+				"y": {"type": "integer", "minimum": 0},
+				"button": {"type": "string", "enum": ["left", "middle", "right"]},
+				"double": {"type": "boolean"}
+			},
+			"required": ["x", "y"]
+		}`),
+	}
+}
@@ -0,0 +1,91 @@
+package input
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// Scripts for JavaScript for Automation. Text comes in on stdin and
+// numbers as arguments to run, never spliced into the source.
+const (
+	typeScript = `
+ObjC.import("Foundation");
+function run() {
+	const data = $.NSFileHandle.fileHandleWithStandardInput.readDataToEndOfFile;
+	const text = $.NSString.alloc.initWithDataEncoding(data, $.NSUTF8StringEncoding).js;
+	Application("System Events").keystroke(text);
+}`
+
+	enterScript = `
+function run() {
+	Application("System Events").keyCode(36);
+}`
+
+	// clickScript posts Quartz mouse events: argv is x, y, the event
+	// types for the button going down and up, the button, and the number
+	// of clicks
+	clickScript = `
+ObjC.import("CoreGraphics");
+function run(argv) {
+	const [x, y, down, up, button, clicks] = argv.map(Number);
+	const at = $.CGPointMake(x, y);
+	$.CGEventPost($.kCGHIDEventTap, $.CGEventCreateMouseEvent(null, 5, at, 0));
+	for (let n = 1; n <= clicks; n++) {
+		for (const type of [down, up]) {
+			const e = $.CGEventCreateMouseEvent(null, type, at, button);
+			$.CGEventSetIntegerValueField(e, 1, n);
+			$.CGEventPost($.kCGHIDEventTap, e);
+		}
+	}
+}`
+)
+
+// quartzButtons maps X11 button numbers to the Quartz button and the event
+// types for it going down and up
+var quartzButtons = map[int][3]int{
+	1: {0, 1, 2},
+	2: {2, 25, 26},
+	3: {1, 3, 4},
+}
+
+// quartz types through System Events and clicks with Quartz events
+type quartz struct{}
+
+func newBackend() (backend, error) {
+	return quartz{}, nil
+}
+
+func (quartz) name() string {
+	return "quartz"
+}
+
+func (quartz) typeText(ctx context.Context, text string) error {
+	return osascript(ctx, text, typeScript)
+}
+
+func (quartz) pressEnter(ctx context.Context) error {
+	return osascript(ctx, "", enterScript)
+}
+
+func (quartz) click(ctx context.Context, x, y, button int, double bool) error {
+	b := quartzButtons[button]
+	clicks := 1
+	if double {
+		clicks = 2
+	}
+	return osascript(ctx, "", clickScript, strconv.Itoa(x), strconv.Itoa(y), strconv.Itoa(b[1]), strconv.Itoa(b[2]), strconv.Itoa(b[0]), strconv.Itoa(clicks))
+}
+
+// osascript runs a JavaScript for Automation script. Without the
+// Accessibility permission, System Events refuses with error -1719 or
+// -25211 (and Quartz drops the events silently).
+func osascript(ctx context.Context, stdin, script string, args ...string) error {
+	err := run(ctx, stdin, append([]string{"osascript", "-l", "JavaScript", "-e", script}, args...)...)
+	if err != nil && (strings.Contains(err.Error(), "-1719") || strings.Contains(err.Error(), "-25211") || strings.Contains(err.Error(), "assistive access")) {
+		return agenterrors.New(agenterrors.Unauthorized, "the agent needs the Accessibility permission (System Settings → Privacy & Security → Accessibility)")
+	}
+	return err
+}
@@ -0,0 +1,86 @@
+//go:build !windows && !darwin
+
+package input
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// newBackend uses ydotool under Wayland, which keeps clients from faking
+// each other's input, and xdotool under X11
+func newBackend() (backend, error) {
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		if _, err := exec.LookPath("ydotool"); err != nil {
+			return nil, errors.New("ydotool not found: under Wayland input needs ydotool and a running ydotoold")
+		}
+		return ydotool{}, nil
+	}
+	if os.Getenv("DISPLAY") == "" {
+		return nil, errors.New("no graphical session (neither WAYLAND_DISPLAY nor DISPLAY is set)")
+	}
+	if _, err := exec.LookPath("xdotool"); err != nil {
+		return nil, errors.New("xdotool not found: install xdotool")
+	}
+	return xdotool{}, nil
+}
+
+// xdotool fakes input through the XTEST extension
+type xdotool struct{}
+
+func (xdotool) name() string {
+	return "xdotool"
+}
+
+// typeText types into the focused window, releasing any modifier keys
+// held down so they don't turn letters into shortcuts
+func (xdotool) typeText(ctx context.Context, text string) error {
+	return run(ctx, text, "xdotool", "type", "--clearmodifiers", "--delay", "12", "--file", "-")
+}
+
+func (xdotool) pressEnter(ctx context.Context) error {
+	return run(ctx, "", "xdotool", "key", "--clearmodifiers", "Return")
+}
+
+func (xdotool) click(ctx context.Context, x, y, button int, double bool) error {
+	argv := []string{"xdotool", "mousemove", "--sync", strconv.Itoa(x), strconv.Itoa(y), "click"}
+	if double {
+		argv = append(argv, "--repeat", "2")
+	}
+	return run(ctx, "", append(argv, strconv.Itoa(button))...)
+}
+
+// ydotool fakes input through uinput, by way of the ydotoold daemon
+type ydotool struct{}
+
+// Linux input event codes ydotool takes: KEY_ENTER, and the mouse buttons'
+// down-and-up codes by X11 button number
+const ydotoolEnter = "28"
+
+var ydotoolButtons = map[int]string{1: "0xC0", 2: "0xC2", 3: "0xC1"}
+
+func (ydotool) name() string {
+	return "ydotool"
+}
+
+func (ydotool) typeText(ctx context.Context, text string) error {
+	return run(ctx, text, "ydotool", "type", "--key-delay", "12", "--file", "-")
+}
+
+func (ydotool) pressEnter(ctx context.Context) error {
+	return run(ctx, "", "ydotool", "key", ydotoolEnter+":1", ydotoolEnter+":0")
+}
+
+func (ydotool) click(ctx context.Context, x, y, button int, double bool) error {
+	if err := run(ctx, "", "ydotool", "mousemove", "--absolute", "-x", strconv.Itoa(x), "-y", strconv.Itoa(y)); err != nil {
+		return err
+	}
+	argv := []string{"ydotool", "click"}
+	if double {
+		argv = append(argv, "--repeat", "2")
+	}
+	return run(ctx, "", append(argv, ydotoolButtons[button])...)
+}
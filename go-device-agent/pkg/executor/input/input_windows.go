@@ -0,0 +1,127 @@
+package input
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf16"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+const (
+	inputMouse    = 0
+	inputKeyboard = 1
+
+	keyeventfKeyUp   = 0x0002
+	keyeventfUnicode = 0x0004
+
+	vkReturn = 0x0D
+)
+
+// mouseDown and mouseUp are the MOUSEEVENTF flags for each X11 button
+// number
+var (
+	mouseDown = map[int]uint32{1: 0x0002, 2: 0x0020, 3: 0x0008}
+	mouseUp   = map[int]uint32{1: 0x0004, 2: 0x0040, 3: 0x0010}
+)
+
+var (
+	user32 = windows.NewLazySystemDLL("user32.dll")
+
+	sendInput    = user32.NewProc("SendInput")
+	setCursorPos = user32.NewProc("SetCursorPos")
+)
+
+// mouseInput and keyboardInput are INPUT structures of each kind. The
+// union is a nested struct so it is aligned as in C, and the keyboard one
+// is padded to the size MOUSEINPUT gives the union.
+type (
+	mouseInput struct {
+		typ uint32
+		mi  struct {
+			dx, dy    int32
+			mouseData uint32
+			flags     uint32
+			time      uint32
+			extraInfo uintptr
+		}
+	}
+
+	keyboardInput struct {
+		typ uint32
+		ki  struct {
+			vk, scan  uint16
+			flags     uint32
+			time      uint32
+			extraInfo uintptr
+			_         [8]byte
+		}
+	}
+)
+
+// win32 injects input with SendInput. It reaches only the desktop the
+// agent runs on, so the agent must run in the user's session rather than
+// as a service.
+type win32 struct{}
+
+func newBackend() (backend, error) {
+	if err := user32.Load(); err != nil {
+		return nil, err
+	}
+	return win32{}, nil
+}
+
+func (win32) name() string {
+	return "win32"
+}
+
+func (win32) typeText(ctx context.Context, text string) error {
+	var inputs []keyboardInput
+	for _, unit := range utf16.Encode([]rune(text)) {
+		if unit == '\n' {
+			inputs = append(inputs, key(vkReturn, 0, 0), key(vkReturn, 0, keyeventfKeyUp))
+			continue
+		}
+		inputs = append(inputs, key(0, unit, keyeventfUnicode), key(0, unit, keyeventfUnicode|keyeventfKeyUp))
+	}
+	return send(unsafe.Pointer(&inputs[0]), len(inputs), unsafe.Sizeof(inputs[0]))
+}
+
+func (win32) pressEnter(ctx context.Context) error {
+	inputs := []keyboardInput{key(vkReturn, 0, 0), key(vkReturn, 0, keyeventfKeyUp)}
+	return send(unsafe.Pointer(&inputs[0]), len(inputs), unsafe.Sizeof(inputs[0]))
+}
+
+func (win32) click(ctx context.Context, x, y, button int, double bool) error {
+	if r, _, err := setCursorPos.Call(uintptr(x), uintptr(y)); r == 0 {
+		return agenterrors.Wrap(agenterrors.Unavailable, fmt.Errorf("SetCursorPos: %w", err))
+	}
+	inputs := make([]mouseInput, 2)
+	for n, flags := range []uint32{mouseDown[button], mouseUp[button]} {
+		inputs[n].typ = inputMouse
+		inputs[n].mi.flags = flags
+	}
+	if double {
+		inputs = append(inputs, inputs...)
+	}
+	return send(unsafe.Pointer(&inputs[0]), len(inputs), unsafe.Sizeof(inputs[0]))
+}
+
+func key(vk, scan uint16, flags uint32) keyboardInput {
+	in := keyboardInput{typ: inputKeyboard}
+	in.ki.vk, in.ki.scan, in.ki.flags = vk, scan, flags
+	return in
+}
+
+// send passes inputs to SendInput, which fails when another desktop, such
+// as the lock screen or a UAC prompt, has the input
+func send(inputs unsafe.Pointer, n int, size uintptr) error {
+	sent, _, err := sendInput.Call(uintptr(n), uintptr(inputs), size)
+	if int(sent) != n {
+		return agenterrors.Wrap(agenterrors.Unavailable, fmt.Errorf("SendInput sent %d of %d events: %w", sent, n, err))
+	}
+	return nil
+}
@@ -0,0 +1,565 @@
+// Package maintenance tracks battery levels, service dates, and consumable
+// wear (filters, brushes, bulbs) for registry devices, answering questions
+// about their upkeep and raising events when one needs attention
+package maintenance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/registry"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Config lists the schedules and where their progress is kept
+type Config struct {
+	Schedules []Schedule
+
+	// StateFile persists service dates and counters across restarts
+	StateFile string
+
+	// LowBattery is the percentage at or below which a battery is reported
+	// (default 20)
+	LowBattery float64
+
+	// CheckInterval is how often devices are checked (default 1m)
+	CheckInterval time.Duration
+}
+
+// batteryHysteresis is how far above LowBattery a battery must climb
+// before it can be reported low again, so a level wavering around the
+// threshold doesn't raise an event on every check
+const batteryHysteresis = 5
+
+// record is the persisted upkeep of one device
+type record struct {
+	// Since is when tracking began, the start of the first service interval
+	Since time.Time `json:"since"`
+
+	LastMaintained time.Time         `json:"last_maintained,omitzero"`
+	Consumables    map[string]*usage `json:"consumables,omitempty"`
+
+	// Alerted holds the items ("battery", "service", or a consumable)
+	// already reported, so each is reported once until it is dealt with
+	Alerted map[string]bool `json:"alerted,omitempty"`
+}
+
+type usage struct {
+	Used       float64   `json:"used"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+// Executor handles device.maintenance_query and device.maintenance_log and
+// checks devices in the background
+type Executor struct {
+	cfg       Config
+	schedules map[string]Schedule
+	devices   *registry.Registry
+	bus       *events.Bus
+	logger    *slog.Logger
+
+	mu        sync.Mutex
+	records   map[string]*record
+	lastCheck time.Time
+}
+
+// NewExecutor creates a maintenance executor for the registry's devices.
+// Battery levels are read from the "battery" state key, which every
+// device reporting one gets tracked by; schedules add service intervals
+// and consumables. Events go to bus.
+func NewExecutor(cfg Config, devices *registry.Registry, bus *events.Bus) (*Executor, error) {
+	if cfg.LowBattery <= 0 {
+		cfg.LowBattery = 20
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = time.Minute
+	}
+	e := &Executor{
+		cfg:       cfg,
+		schedules: make(map[string]Schedule, len(cfg.Schedules)),
+		devices:   devices,
+		bus:       bus,
+		logger:    slog.Default(),
+		records:   make(map[string]*record),
+	}
+	for _, s := range cfg.Schedules {
+		if err := s.check(); err != nil {
+			return nil, fmt.Errorf("device %s: %w", s.Device, err)
+		}
+		e.schedules[s.Device] = s
+	}
+
+	if cfg.StateFile != "" {
+		data, err := os.ReadFile(cfg.StateFile)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return nil, err
+		default:
+			if err := json.Unmarshal(data, &e.records); err != nil {
+				return nil, fmt.Errorf("invalid maintenance state %s: %w", cfg.StateFile, err)
+			}
+		}
+	}
+	if bus != nil {
+		bus.Subscribe("intent.completed", e.countUse)
+	}
+	return e, nil
+}
+
+// SetLogger sets where background save errors are reported
+func (e *Executor) SetLogger(logger *slog.Logger) {
+	e.logger = logger
+}
+
+// Start checks devices every CheckInterval until ctx is cancelled
+func (e *Executor) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(e.cfg.CheckInterval)
+		defer ticker.Stop()
+		e.check(time.Now())
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				e.check(now)
+			}
+		}
+	}()
+}
+
+// check accrues running hours and raises device.battery_low and
+// device.maintenance_due events for anything newly needing attention
+func (e *Executor) check(now time.Time) {
+	var raised []events.Event
+
+	e.mu.Lock()
+	// Time the agent wasn't running can't be attributed to anything
+	elapsed := min(now.Sub(e.lastCheck), 2*e.cfg.CheckInterval)
+	if e.lastCheck.IsZero() {
+		elapsed = 0
+	}
+	e.lastCheck = now
+
+	for _, d := range e.devices.List() {
+		if level, ok := battery(d); ok {
+			r := e.record(d.ID, now)
+			switch {
+			case level <= e.cfg.LowBattery && !r.Alerted["battery"]:
+				r.Alerted["battery"] = true
+				raised = append(raised, event("device.battery_low", d, now, map[string]interface{}{
+					"battery": level,
+					"message": fmt.Sprintf("%s battery is at %.0f%%", d.Name, level),
+				}))
+			case level > e.cfg.LowBattery+batteryHysteresis && r.Alerted["battery"]:
+				delete(r.Alerted, "battery")
+			}
+		}
+
+		s, ok := e.schedules[d.ID]
+		if !ok {
+			continue
+		}
+		r := e.record(d.ID, now)
+		if on, _ := d.State["on"].(bool); on && elapsed > 0 {
+			for _, c := range s.Consumables {
+				if c.Unit == "hours" {
+					r.usage(c.Name, now).Used += elapsed.Hours()
+				}
+			}
+		}
+		for _, item := range e.due(s, r, now) {
+			if r.Alerted[item] {
+				continue
+			}
+			r.Alerted[item] = true
+			message := fmt.Sprintf("%s is due for service", d.Name)
+			if item != "service" {
+				message = fmt.Sprintf("%s needs a new %s", d.Name, item)
+			}
+			raised = append(raised, event("device.maintenance_due", d, now, map[string]interface{}{
+				"item":    item,
+				"message": message,
+			}))
+		}
+	}
+	err := e.save()
+	e.mu.Unlock()
+
+	if err != nil {
+		e.logger.Error("Failed to save maintenance state", "error", err)
+	}
+	if e.bus != nil {
+		for _, ev := range raised {
+			e.bus.Publish(ev)
+		}
+	}
+}
+
+func event(eventType string, d registry.Device, now time.Time, data map[string]interface{}) events.Event {
+	data["device"] = d.ID
+	data["name"] = d.Name
+	if d.Room != "" {
+		data["room"] = d.Room
+	}
+	return events.Event{
+		ID:        events.NewID(),
+		Type:      eventType,
+		Source:    "maintenance",
+		Data:      data,
+		Timestamp: now,
+	}
+}
+
+// countUse counts a successful device.control intent against the "uses"
+// consumables of the device it named
+func (e *Executor) countUse(ev events.Event) {
+	if ev.Data["intent_type"] != "device.control" || ev.Data["success"] != true {
+		return
+	}
+	params, _ := ev.Data["parameters"].(map[string]interface{})
+	name, _ := params["device"].(string)
+	module, _ := ev.Data["module"].(string)
+	if name == "" {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := time.Now()
+	for id, s := range e.schedules {
+		d, ok := e.devices.Get(id)
+		if !ok || d.Module != module || !names(d, name) {
+			continue
+		}
+		for _, c := range s.Consumables {
+			if c.Unit == "uses" {
+				e.record(id, now).usage(c.Name, now).Used++
+			}
+		}
+	}
+}
+
+// names reports whether an intent's device parameter refers to d: its
+// registry ID, its ID within its module, or its name
+func names(d registry.Device, name string) bool {
+	return strings.EqualFold(name, d.ID) || strings.EqualFold(d.Module+":"+name, d.ID) || strings.EqualFold(name, d.Name)
+}
+
+// record returns a device's record, creating it; e.mu must be held
+func (e *Executor) record(id string, now time.Time) *record {
+	r, ok := e.records[id]
+	if !ok {
+		r = &record{Since: now}
+		e.records[id] = r
+	}
+	if r.Alerted == nil {
+		r.Alerted = make(map[string]bool)
+	}
+	if r.Consumables == nil {
+		r.Consumables = make(map[string]*usage)
+	}
+	return r
+}
+
+func (r *record) usage(name string, now time.Time) *usage {
+	u, ok := r.Consumables[name]
+	if !ok {
+		u = &usage{ReplacedAt: now}
+		r.Consumables[name] = u
+	}
+	return u
+}
+
+// used is how much of a consumable's limit has been used up
+func used(c Consumable, u *usage, now time.Time) float64 {
+	if c.Unit == "days" {
+		return now.Sub(u.ReplacedAt).Hours() / 24
+	}
+	return u.Used
+}
+
+// nextService is when a device is next due for service
+func nextService(s Schedule, r *record) time.Time {
+	if s.EveryDays == 0 {
+		return time.Time{}
+	}
+	from := r.Since
+	if !r.LastMaintained.IsZero() {
+		from = r.LastMaintained
+	}
+	return from.AddDate(0, 0, s.EveryDays)
+}
+
+// due lists what a device needs now: "service" and worn-out consumables
+func (e *Executor) due(s Schedule, r *record, now time.Time) []string {
+	var items []string
+	if next := nextService(s, r); !next.IsZero() && !now.Before(next) {
+		items = append(items, "service")
+	}
+	for _, c := range s.Consumables {
+		if used(c, r.usage(c.Name, now), now) >= c.Limit {
+			items = append(items, c.Name)
+		}
+	}
+	return items
+}
+
+// battery reads a device's battery percentage from its state
+func battery(d registry.Device) (float64, bool) {
+	switch v := d.State["battery"].(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func (e *Executor) Name() string {
+	return "maintenance"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"device.maintenance_query", "device.maintenance_log"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{Write: []string{filepath.Dir(e.cfg.StateFile)}}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "maintenance",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	switch i.IntentType {
+	case "device.maintenance_query":
+		var params struct {
+			Device    string `param:"device"`
+			Attention bool   `param:"attention"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		devices := e.devices.List()
+		if params.Device != "" {
+			d, err := e.find(params.Device)
+			if err != nil {
+				return fail(err)
+			}
+			devices = []registry.Device{d}
+		}
+		reports := e.report(devices, params.Attention, time.Now())
+		result.Success = true
+		result.Result = map[string]interface{}{"devices": reports, "count": len(reports)}
+		return result, nil
+
+	case "device.maintenance_log":
+		var params struct {
+			Device     string `param:"device,required"`
+			Consumable string `param:"consumable"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		d, err := e.find(params.Device)
+		if err != nil {
+			return fail(err)
+		}
+		report, err := e.logMaintenance(d, params.Consumable, time.Now())
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = report
+		return result, nil
+
+	default:
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+		return result, nil
+	}
+}
+
+// find looks a device up by ID or name
+func (e *Executor) find(name string) (registry.Device, error) {
+	if d, ok := e.devices.Get(name); ok {
+		return d, nil
+	}
+	for _, d := range e.devices.List() {
+		if strings.EqualFold(d.ID, name) || strings.EqualFold(d.Name, name) {
+			return d, nil
+		}
+	}
+	return registry.Device{}, agenterrors.Newf(agenterrors.NotFound, "no device named '%s'", name)
+}
+
+// logMaintenance records a service, or a consumable's replacement, done now
+func (e *Executor) logMaintenance(d registry.Device, consumable string, now time.Time) (map[string]interface{}, error) {
+	s, ok := e.schedules[d.ID]
+	if !ok {
+		return nil, agenterrors.Newf(agenterrors.NotFound, "%s has no maintenance schedule", d.Name)
+	}
+
+	e.mu.Lock()
+	r := e.record(d.ID, now)
+	if consumable == "" || consumable == "service" {
+		r.LastMaintained = now
+		delete(r.Alerted, "service")
+	} else {
+		known := false
+		for _, c := range s.Consumables {
+			known = known || c.Name == consumable
+		}
+		if !known {
+			e.mu.Unlock()
+			return nil, agenterrors.Newf(agenterrors.InvalidParams, "%s has no consumable '%s'", d.Name, consumable)
+		}
+		r.Consumables[consumable] = &usage{ReplacedAt: now}
+		delete(r.Alerted, consumable)
+	}
+	err := e.save()
+	e.mu.Unlock()
+	if err != nil {
+		return nil, agenterrors.Newf(agenterrors.Internal, "cannot save maintenance state: %w", err)
+	}
+	return e.report([]registry.Device{d}, false, now)[0], nil
+}
+
+// report describes the upkeep of the devices that have any, or only those
+// needing attention
+func (e *Executor) report(devices []registry.Device, attention bool, now time.Time) []map[string]interface{} {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	reports := []map[string]interface{}{}
+	for _, d := range devices {
+		level, hasBattery := battery(d)
+		s, scheduled := e.schedules[d.ID]
+		if !hasBattery && !scheduled {
+			continue
+		}
+		needs := false
+		entry := map[string]interface{}{"device": d.ID, "name": d.Name}
+		if d.Room != "" {
+			entry["room"] = d.Room
+		}
+		if hasBattery {
+			low := level <= e.cfg.LowBattery
+			entry["battery"] = level
+			entry["battery_low"] = low
+			needs = needs || low
+		}
+		if scheduled {
+			r := e.record(d.ID, now)
+			if !r.LastMaintained.IsZero() {
+				entry["last_maintained"] = r.LastMaintained.Format(time.RFC3339)
+			}
+			if next := nextService(s, r); !next.IsZero() {
+				overdue := !now.Before(next)
+				entry["next_service"] = next.Format(time.RFC3339)
+				entry["overdue"] = overdue
+				needs = needs || overdue
+			}
+			var consumables []map[string]interface{}
+			for _, c := range s.Consumables {
+				u := r.usage(c.Name, now)
+				spent := used(c, u, now)
+				consumables = append(consumables, map[string]interface{}{
+					"name":          c.Name,
+					"unit":          c.Unit,
+					"used":          round1(spent),
+					"limit":         c.Limit,
+					"remaining_pct": round1(max(0, 100*(1-spent/c.Limit))),
+					"replaced_at":   u.ReplacedAt.Format(time.RFC3339),
+					"due":           spent >= c.Limit,
+				})
+				needs = needs || spent >= c.Limit
+			}
+			if len(consumables) > 0 {
+				entry["consumables"] = consumables
+			}
+		}
+		entry["needs_attention"] = needs
+		if attention && !needs {
+			continue
+		}
+		reports = append(reports, entry)
+	}
+	sort.Slice(reports, func(a, b int) bool { return reports[a]["device"].(string) < reports[b]["device"].(string) })
+	return reports
+}
+
+func round1(f float64) float64 {
+	return math.Round(f*10) / 10
+}
+
+// save writes the records; e.mu must be held
+func (e *Executor) save() error {
+	if e.cfg.StateFile == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(e.records, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(e.cfg.StateFile), 0o700); err != nil {
+		return err
+	}
+	tmp := e.cfg.StateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, e.cfg.StateFile)
+}
+
+func (e *Executor) IsAvailable() bool {
+	return true
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"device.maintenance_query": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"device": {"type": "string"},
+				"attention": {"type": "boolean"}
+			}
+		}`),
+		"device.maintenance_log": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"device": {"type": "string", "minLength": 1},
+				"consumable": {"type": "string"}
+			},
+			"required": ["device"]
+		}`),
+	}
+}
@@ -0,0 +1,82 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Schedule is the upkeep configured for one registry device
+type Schedule struct {
+	// Device is the registry ID, e.g. "mqtt:purifier"
+	Device string `json:"device"`
+
+	// EveryDays is the service interval; 0 for none
+	EveryDays int `json:"every_days,omitempty"`
+
+	Consumables []Consumable `json:"consumables,omitempty"`
+}
+
+// Consumable is a part that wears out, such as a filter, counted from when
+// it was last replaced
+type Consumable struct {
+	Name string `json:"name"`
+
+	// Unit is what Limit counts: "hours" the device spends on (its state's
+	// "on" is true), "days" since replacement, or "uses", intents that
+	// successfully controlled the device
+	Unit string `json:"unit"`
+
+	Limit float64 `json:"limit"`
+}
+
+// LoadSchedules reads a JSON array of schedules
+func LoadSchedules(path string) ([]Schedule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var schedules []Schedule
+	if err := json.Unmarshal(data, &schedules); err != nil {
+		return nil, fmt.Errorf("invalid maintenance file %s: %w", path, err)
+	}
+	seen := make(map[string]bool, len(schedules))
+	for _, s := range schedules {
+		if err := s.check(); err != nil {
+			return nil, fmt.Errorf("invalid maintenance file %s: device %q: %w", path, s.Device, err)
+		}
+		if seen[s.Device] {
+			return nil, fmt.Errorf("invalid maintenance file %s: device %q is listed twice", path, s.Device)
+		}
+		seen[s.Device] = true
+	}
+	return schedules, nil
+}
+
+func (s Schedule) check() error {
+	if s.Device == "" {
+		return fmt.Errorf("missing device")
+	}
+	if s.EveryDays < 0 {
+		return fmt.Errorf("every_days can't be negative")
+	}
+	names := make(map[string]bool, len(s.Consumables))
+	for _, c := range s.Consumables {
+		if c.Name == "" || c.Name == "service" {
+			return fmt.Errorf("consumables need a name other than \"service\"")
+		}
+		if names[c.Name] {
+			return fmt.Errorf("consumable %s is listed twice", c.Name)
+		}
+		names[c.Name] = true
+		switch c.Unit {
+		case "hours", "days", "uses":
+		default:
+			return fmt.Errorf("consumable %s: unit must be hours, days, or uses", c.Name)
+		}
+		if c.Limit <= 0 {
+			return fmt.Errorf("consumable %s: limit must be positive", c.Name)
+		}
+	}
+	return nil
+}
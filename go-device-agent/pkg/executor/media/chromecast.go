@@ -0,0 +1,234 @@
+package media
+
+import (
+	"context"
+	"mime"
+	"net"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/internal/cast"
+	"github.com/vinod901/local-agent-core/go-device-agent/internal/mdns"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+const (
+	castService = "_googlecast._tcp.local."
+
+	// castDiscoveryAge is how long discovered devices are remembered
+	// before asking again
+	castDiscoveryAge = time.Minute
+
+	// castBrowse is how long discovery listens for answers
+	castBrowse = 1500 * time.Millisecond
+
+	castTimeout = 15 * time.Second
+)
+
+// Chromecast plays URLs on Cast devices with the default media receiver
+// and controls whatever app they are running
+type Chromecast struct {
+	// Devices lists Cast devices by name, as host or host:port
+	Devices map[string]string
+
+	// Discover also finds devices on the local network with mDNS
+	Discover bool
+
+	mu         sync.Mutex
+	discovered []Player
+	at         time.Time
+}
+
+func (c *Chromecast) Name() string {
+	return "chromecast"
+}
+
+func (c *Chromecast) Players(ctx context.Context) ([]Player, error) {
+	players := make([]Player, 0, len(c.Devices))
+	seen := make(map[string]bool)
+	for name, addr := range c.Devices {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, cast.Port)
+		}
+		players = append(players, Player{ID: addr, Name: name, Backend: "chromecast"})
+		seen[addr] = true
+	}
+	if c.Discover {
+		for _, p := range c.discover(ctx) {
+			if !seen[p.ID] {
+				players = append(players, p)
+			}
+		}
+	}
+	sort.Slice(players, func(a, b int) bool { return players[a].Name < players[b].Name })
+	return players, nil
+}
+
+// discover browses for Cast devices until ctx is done, reusing recent
+// results
+func (c *Chromecast) discover(ctx context.Context) []Player {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Since(c.at) < castDiscoveryAge {
+		return c.discovered
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, castBrowse)
+	defer cancel()
+	var found []Player
+	seen := make(map[string]bool)
+	mdns.Browse(ctx, castService, func(s mdns.Service) {
+		port := s.Port
+		if port == 0 {
+			port, _ = strconv.Atoi(cast.Port)
+		}
+		addr := net.JoinHostPort(s.Addr.String(), strconv.Itoa(port))
+		if seen[addr] {
+			return
+		}
+		seen[addr] = true
+		name := s.TXT["fn"]
+		if name == "" {
+			name = strings.TrimSuffix(s.Instance, "."+castService)
+		}
+		found = append(found, Player{ID: addr, Name: name, Backend: "chromecast"})
+	})
+	c.discovered, c.at = found, time.Now()
+	return found
+}
+
+// Play loads a URL into the default media receiver, or with no URL
+// resumes what the device is playing
+func (c *Chromecast) Play(ctx context.Context, player string, m Media) error {
+	if m.URI == "" {
+		return c.control(ctx, player, map[string]interface{}{"type": "PLAY"})
+	}
+	u, err := url.Parse(m.URI)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return agenterrors.Newf(agenterrors.InvalidParams, "Cast devices play http(s) URLs, not '%s'", m.URI)
+	}
+	contentType := m.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(u.Path))
+	}
+	if contentType == "" {
+		contentType = "video/mp4"
+	}
+
+	conn, cancel, err := c.dial(ctx, player)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	app, err := conn.Launch(cast.DefaultMediaReceiver)
+	if err != nil {
+		return agenterrors.Newf(agenterrors.Unavailable, "could not start the media receiver: %w", err)
+	}
+	if err := conn.Load(app.TransportID, m.URI, contentType, "BUFFERED"); err != nil {
+		return agenterrors.Newf(agenterrors.Unavailable, "could not play %s: %w", m.URI, err)
+	}
+	return nil
+}
+
+func (c *Chromecast) Pause(ctx context.Context, player string) error {
+	return c.control(ctx, player, map[string]interface{}{"type": "PAUSE"})
+}
+
+func (c *Chromecast) Next(ctx context.Context, player string) error {
+	return c.control(ctx, player, map[string]interface{}{"type": "QUEUE_UPDATE", "jump": 1})
+}
+
+func (c *Chromecast) Volume(ctx context.Context, player string) (float64, error) {
+	conn, cancel, err := c.dial(ctx, player)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+	status, err := conn.ReceiverStatus()
+	if err != nil {
+		return 0, agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	return status.Volume.Level * 100, nil
+}
+
+func (c *Chromecast) SetVolume(ctx context.Context, player string, level float64) (float64, error) {
+	conn, cancel, err := c.dial(ctx, player)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+	level = clamp(level)
+	msg, err := conn.Request(cast.Receiver, cast.NamespaceReceiver, map[string]interface{}{
+		"type":   "SET_VOLUME",
+		"volume": map[string]interface{}{"level": level / 100},
+	})
+	if err != nil {
+		return 0, agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	if msg.Type != "RECEIVER_STATUS" {
+		return 0, agenterrors.Newf(agenterrors.Unavailable, "volume not set (%s)", msg.Type)
+	}
+	return level, nil
+}
+
+// control sends a media command to the running app's current session
+func (c *Chromecast) control(ctx context.Context, player string, command map[string]interface{}) error {
+	conn, cancel, err := c.dial(ctx, player)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	status, err := conn.ReceiverStatus()
+	if err != nil {
+		return agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	var transportID string
+	for _, app := range status.Applications {
+		if !app.IsIdleScreen && app.TransportID != "" {
+			transportID = app.TransportID
+		}
+	}
+	if transportID == "" {
+		return agenterrors.New(agenterrors.Conflict, "nothing is playing")
+	}
+	if err := conn.Connect(transportID); err != nil {
+		return agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	msg, err := conn.Request(transportID, cast.NamespaceMedia, map[string]interface{}{"type": "GET_STATUS"})
+	if err != nil {
+		return agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	sessions, err := msg.Media()
+	if err != nil || len(sessions) == 0 {
+		return agenterrors.New(agenterrors.Conflict, "the app on the device has no media to control")
+	}
+
+	command["mediaSessionId"] = sessions[0].MediaSessionID
+	msg, err = conn.Request(transportID, cast.NamespaceMedia, command)
+	if err != nil {
+		return agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	if msg.Type != "MEDIA_STATUS" {
+		return agenterrors.Newf(agenterrors.Conflict, "the device refused %s (%s)", command["type"], msg.Type)
+	}
+	return nil
+}
+
+func (c *Chromecast) dial(ctx context.Context, player string) (*cast.Conn, func(), error) {
+	ctx, cancel := context.WithTimeout(ctx, castTimeout)
+	conn, err := cast.Dial(ctx, player)
+	if err != nil {
+		cancel()
+		return nil, nil, agenterrors.Newf(agenterrors.Unavailable, "cannot reach %s: %v", player, err)
+	}
+	return conn, func() {
+		conn.Close()
+		cancel()
+	}, nil
+}
@@ -0,0 +1,304 @@
+// Package media controls playback on local MPRIS players, Spotify Connect
+// devices, and Chromecasts through one set of media.* intents, with the
+// target player named in the parameters
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/usage"
+)
+
+// Backend controls the players of one kind
+type Backend interface {
+	// Name identifies the backend in parameters and results, e.g. "mpris"
+	Name() string
+
+	// Players lists the players reachable now
+	Players(ctx context.Context) ([]Player, error)
+
+	// Play starts the media at uri, or resumes when uri is empty
+	Play(ctx context.Context, player string, m Media) error
+
+	Pause(ctx context.Context, player string) error
+	Next(ctx context.Context, player string) error
+
+	// Volume returns the volume, 0 to 100
+	Volume(ctx context.Context, player string) (float64, error)
+
+	// SetVolume sets the volume, 0 to 100, returning the level the player
+	// reports or rounds it to
+	SetVolume(ctx context.Context, player string, level float64) (float64, error)
+}
+
+// Player is something that plays media
+type Player struct {
+	// ID is the backend's handle for it: an MPRIS bus name, a Spotify
+	// device ID, or a Cast device's address
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Backend string `json:"backend"`
+
+	// Active marks a player that is playing, or for Spotify the device
+	// the account is playing on
+	Active bool `json:"active,omitempty"`
+}
+
+// Media is what to play
+type Media struct {
+	URI string
+
+	// ContentType is the MIME type, which Cast devices need for URLs
+	ContentType string
+}
+
+// listTimeout bounds finding players, which may take mDNS discovery
+const listTimeout = 3 * time.Second
+
+// Executor handles media.play, media.pause, media.next, and media.volume
+type Executor struct {
+	backends []Backend
+}
+
+// NewExecutor creates a media executor over the backends, preferred in
+// the order given when no player is named
+func NewExecutor(backends ...Backend) (*Executor, error) {
+	if len(backends) == 0 {
+		return nil, errors.New("no media backends configured")
+	}
+	return &Executor{backends: backends}, nil
+}
+
+func (e *Executor) Name() string {
+	return "media"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"media.play", "media.pause", "media.next", "media.volume"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	var needs sandbox.Needs
+	for _, b := range e.backends {
+		if _, local := b.(*MPRIS); !local {
+			needs.Network = true
+		}
+	}
+	return needs
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "media",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	var params struct {
+		Device      string   `param:"device"`
+		Backend     string   `param:"backend"`
+		URI         string   `param:"uri"`
+		ContentType string   `param:"content_type"`
+		Level       *float64 `param:"level"`
+		Change      *float64 `param:"change"`
+	}
+	if err := i.DecodeParams(&params); err != nil {
+		return fail(err)
+	}
+	b, p, err := e.pick(ctx, params.Backend, params.Device)
+	if err != nil {
+		return fail(err)
+	}
+	result.Result = map[string]interface{}{"device": p.Name, "backend": b.Name()}
+
+	switch i.IntentType {
+	case "media.play":
+		err = b.Play(ctx, p.ID, Media{URI: params.URI, ContentType: params.ContentType})
+		if err == nil && params.URI != "" {
+			result.Result["uri"] = params.URI
+		}
+
+	case "media.pause":
+		err = b.Pause(ctx, p.ID)
+
+	case "media.next":
+		err = b.Next(ctx, p.ID)
+
+	case "media.volume":
+		var level float64
+		switch {
+		case params.Level != nil && params.Change != nil:
+			return fail(agenterrors.New(agenterrors.InvalidParams, "give a level or a change, not both"))
+		case params.Level != nil:
+			if *params.Level < 0 || *params.Level > 100 {
+				return fail(agenterrors.New(agenterrors.InvalidParams, "level must be between 0 and 100"))
+			}
+			level = *params.Level
+		case params.Change != nil:
+			current, err := b.Volume(ctx, p.ID)
+			if err != nil {
+				return fail(err)
+			}
+			level = clamp(current + *params.Change)
+		default:
+			return fail(agenterrors.New(agenterrors.InvalidParams, "give a level (0-100) or a change (e.g. -10)"))
+		}
+		// The gateway clamped a level given outright; a change is held to
+		// the same limit here, before the player gets louder
+		capped, adjustment := gateway.SafetyClamp(ctx, "level", level)
+		if adjustment != nil {
+			result.Adjusted = map[string]interface{}{"level": adjustment}
+		}
+		level, err = b.SetVolume(ctx, p.ID, capped)
+		if err == nil {
+			result.Result["volume"] = level
+		}
+
+	default:
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+		return result, nil
+	}
+	if err != nil {
+		return fail(err)
+	}
+	usage.DeviceCommand(ctx)
+	result.Success = true
+	return result, nil
+}
+
+// candidate is a player and the backend that has it
+type candidate struct {
+	b Backend
+	p Player
+}
+
+// pick finds the player to act on: the one named, from the named backend
+// or any, or else the first active player, or the only one there is
+func (e *Executor) pick(ctx context.Context, backend, device string) (Backend, Player, error) {
+	var backends []Backend
+	for _, b := range e.backends {
+		if backend == "" || strings.EqualFold(b.Name(), backend) {
+			backends = append(backends, b)
+		}
+	}
+	if len(backends) == 0 {
+		return nil, Player{}, agenterrors.Newf(agenterrors.NotFound, "no media backend named '%s' (have %s)", backend, e.names())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, listTimeout)
+	defer cancel()
+	var all []candidate
+	var errs []error
+	for _, b := range backends {
+		players, err := b.Players(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", b.Name(), err))
+			continue
+		}
+		for _, p := range players {
+			all = append(all, candidate{b, p})
+		}
+	}
+
+	if device != "" {
+		for _, c := range all {
+			if strings.EqualFold(c.p.ID, device) || strings.EqualFold(c.p.Name, device) {
+				return c.b, c.p, nil
+			}
+		}
+		// A partial name is fine when it picks out one player
+		var matches []candidate
+		for _, c := range all {
+			if strings.Contains(strings.ToLower(c.p.Name), strings.ToLower(device)) {
+				matches = append(matches, c)
+			}
+		}
+		if len(matches) == 1 {
+			return matches[0].b, matches[0].p, nil
+		}
+		return nil, Player{}, e.notFound(fmt.Sprintf("no media player named '%s'", device), all, errs)
+	}
+
+	for _, c := range all {
+		if c.p.Active {
+			return c.b, c.p, nil
+		}
+	}
+	if len(all) == 1 {
+		return all[0].b, all[0].p, nil
+	}
+	if len(all) == 0 {
+		return nil, Player{}, e.notFound("no media players found", nil, errs)
+	}
+	return nil, Player{}, e.notFound("nothing is playing, so name a device", all, errs)
+}
+
+// notFound lists the players there are, and backends that failed to list
+// theirs, so the core can ask the user
+func (e *Executor) notFound(message string, all []candidate, errs []error) error {
+	names := make([]string, 0, len(all))
+	for _, c := range all {
+		names = append(names, fmt.Sprintf("%s (%s)", c.p.Name, c.b.Name()))
+	}
+	if len(names) > 0 {
+		message += "; players: " + strings.Join(names, ", ")
+	}
+	if len(errs) > 0 {
+		message += "; " + errors.Join(errs...).Error()
+	}
+	return agenterrors.New(agenterrors.NotFound, strings.ReplaceAll(message, "\n", "; "))
+}
+
+func (e *Executor) names() string {
+	names := make([]string, len(e.backends))
+	for n, b := range e.backends {
+		names[n] = b.Name()
+	}
+	return strings.Join(names, ", ")
+}
+
+// clamp keeps a volume within 0 to 100
+func clamp(level float64) float64 {
+	return min(max(level, 0), 100)
+}
+
+func (e *Executor) IsAvailable() bool {
+	return true
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	target := `"device": {"type": "string"}, "backend": {"type": "string"}`
+	return map[string]*schema.Schema{
+		"media.play": schema.MustParse(`{
+			"type": "object",
+			"properties": {` + target + `,
+				"uri": {"type": "string"},
+				"content_type": {"type": "string"}
+			}
+		}`),
+		"media.pause": schema.MustParse(`{"type": "object", "properties": {` + target + `}}`),
+		"media.next":  schema.MustParse(`{"type": "object", "properties": {` + target + `}}`),
+		"media.volume": schema.MustParse(`{
+			"type": "object",
+			"properties": {` + target + `,
+				"level": {"type": "number", "minimum": 0, "maximum": 100},
+				"change": {"type": "number", "minimum": -100, "maximum": 100}
+			}
+		}`),
+	}
+}
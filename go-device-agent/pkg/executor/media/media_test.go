@@ -0,0 +1,82 @@
+package media_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/executor/media"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// fakeBackend has one player, remembering the loudest volume it was set to
+type fakeBackend struct {
+	volume, loudest float64
+}
+
+func (b *fakeBackend) Name() string { return "fake" }
+
+func (b *fakeBackend) Players(ctx context.Context) ([]media.Player, error) {
+	return []media.Player{{ID: "speaker", Name: "Kitchen Speaker", Backend: "fake", Active: true}}, nil
+}
+
+func (b *fakeBackend) Play(ctx context.Context, player string, m media.Media) error { return nil }
+func (b *fakeBackend) Pause(ctx context.Context, player string) error               { return nil }
+func (b *fakeBackend) Next(ctx context.Context, player string) error                { return nil }
+
+func (b *fakeBackend) Volume(ctx context.Context, player string) (float64, error) {
+	return b.volume, nil
+}
+
+func (b *fakeBackend) SetVolume(ctx context.Context, player string, level float64) (float64, error) {
+	b.volume = level
+	b.loudest = max(b.loudest, level)
+	return level, nil
+}
+
+func TestChildSafetyClampsMediaVolume(t *testing.T) {
+	tests := []struct {
+		name   string
+		start  float64
+		params map[string]interface{}
+		steps  int
+	}{
+		{name: "level", start: 20, params: map[string]interface{}{"level": 100}, steps: 1},
+		{name: "level as a string", start: 20, params: map[string]interface{}{"level": "90"}, steps: 1},
+		{name: "change", start: 30, params: map[string]interface{}{"change": 50}, steps: 1},
+		{name: "repeated changes", start: 10, params: map[string]interface{}{"change": 20}, steps: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &fakeBackend{volume: tt.start}
+			e, err := media.NewExecutor(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gw := gateway.NewGateway(nil)
+			gw.RegisterExecutor(e)
+			gw.SetSafetyPolicy(gateway.ChildSafetyPolicy())
+			gw.SetSafetyMode(true)
+
+			var result *gateway.ExecutionResult
+			for range tt.steps {
+				data, err := intent.New("media.volume").Params(tt.params).Confidence(0.9).Reasoning("test").JSON()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result, err = gw.ProcessIntent(context.Background(), data); err != nil {
+					t.Fatal(err)
+				}
+				if !result.Success {
+					t.Fatalf("failed: %s", result.Error)
+				}
+			}
+			if b.volume != 40 || b.loudest > 40 {
+				t.Errorf("volume is %v and reached %v, want it held to 40", b.volume, b.loudest)
+			}
+			if result.Adjusted["level"] == nil {
+				t.Errorf("result doesn't report the clamped level: %v", result.Adjusted)
+			}
+		})
+	}
+}
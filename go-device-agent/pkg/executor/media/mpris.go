@@ -0,0 +1,126 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+const (
+	mprisPrefix = "org.mpris.MediaPlayer2."
+	mprisPath   = dbus.ObjectPath("/org/mpris/MediaPlayer2")
+	mprisPlayer = "org.mpris.MediaPlayer2.Player"
+)
+
+// MPRIS controls local players (VLC, mpv, Spotify's desktop app, browsers)
+// over the D-Bus session bus
+type MPRIS struct {
+	conn *dbus.Conn
+}
+
+// NewMPRIS connects to the session bus
+func NewMPRIS() (*MPRIS, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("no D-Bus session bus: %w", err)
+	}
+	return &MPRIS{conn: conn}, nil
+}
+
+func (m *MPRIS) Name() string {
+	return "mpris"
+}
+
+func (m *MPRIS) Players(ctx context.Context) ([]Player, error) {
+	var names []string
+	if err := m.conn.BusObject().CallWithContext(ctx, "org.freedesktop.DBus.ListNames", 0).Store(&names); err != nil {
+		return nil, err
+	}
+	var players []Player
+	for _, name := range names {
+		if !strings.HasPrefix(name, mprisPrefix) {
+			continue
+		}
+		obj := m.conn.Object(name, mprisPath)
+		p := Player{ID: name, Name: strings.TrimPrefix(name, mprisPrefix), Backend: "mpris"}
+		if v, err := obj.GetProperty("org.mpris.MediaPlayer2.Identity"); err == nil {
+			if identity, ok := v.Value().(string); ok && identity != "" {
+				p.Name = identity
+			}
+		}
+		if v, err := obj.GetProperty(mprisPlayer + ".PlaybackStatus"); err == nil {
+			p.Active = v.Value() == "Playing"
+		}
+		players = append(players, p)
+	}
+	return players, nil
+}
+
+func (m *MPRIS) Play(ctx context.Context, player string, media Media) error {
+	if media.URI == "" {
+		return m.call(ctx, player, "Play")
+	}
+	uri := media.URI
+	// Players take URIs; a bare path is a local file
+	if u, err := url.Parse(uri); err != nil || u.Scheme == "" {
+		abs, err := filepath.Abs(uri)
+		if err != nil {
+			return agenterrors.Wrap(agenterrors.InvalidParams, err)
+		}
+		uri = (&url.URL{Scheme: "file", Path: abs}).String()
+	}
+	return m.call(ctx, player, "OpenUri", uri)
+}
+
+func (m *MPRIS) Pause(ctx context.Context, player string) error {
+	return m.call(ctx, player, "Pause")
+}
+
+func (m *MPRIS) Next(ctx context.Context, player string) error {
+	return m.call(ctx, player, "Next")
+}
+
+func (m *MPRIS) Volume(ctx context.Context, player string) (float64, error) {
+	v, err := m.conn.Object(player, mprisPath).GetProperty(mprisPlayer + ".Volume")
+	if err != nil {
+		return 0, m.fail(player, err)
+	}
+	current, _ := v.Value().(float64)
+	return current * 100, nil
+}
+
+func (m *MPRIS) SetVolume(ctx context.Context, player string, level float64) (float64, error) {
+	obj := m.conn.Object(player, mprisPath)
+	level = clamp(level)
+	if err := obj.SetProperty(mprisPlayer+".Volume", dbus.MakeVariant(level/100)); err != nil {
+		return 0, m.fail(player, err)
+	}
+	return level, nil
+}
+
+func (m *MPRIS) call(ctx context.Context, player, method string, args ...interface{}) error {
+	return m.fail(player, m.conn.Object(player, mprisPath).CallWithContext(ctx, mprisPlayer+"."+method, 0, args...).Err)
+}
+
+// fail turns D-Bus errors into agent errors: a player that refuses a
+// method (e.g. Next with nothing queued) conflicts with its state
+func (m *MPRIS) fail(player string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(dbus.Error); ok {
+		switch e.Name {
+		case "org.freedesktop.DBus.Error.ServiceUnknown":
+			return agenterrors.Newf(agenterrors.NotFound, "%s has closed", strings.TrimPrefix(player, mprisPrefix))
+		case "org.freedesktop.DBus.Error.NotSupported", "org.mpris.MediaPlayer2.Player.Error.NotSupported":
+			return agenterrors.Newf(agenterrors.Unsupported, "%s can't do that", strings.TrimPrefix(player, mprisPrefix))
+		}
+	}
+	return agenterrors.Newf(agenterrors.Conflict, "%s: %v", strings.TrimPrefix(player, mprisPrefix), err)
+}
@@ -0,0 +1,260 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+)
+
+// Spotify Web API endpoints
+const (
+	spotifyAPI   = "https://api.spotify.com/v1"
+	spotifyToken = "https://accounts.spotify.com/api/token"
+)
+
+// Spotify controls Spotify Connect devices through the Web API. It signs
+// in with a refresh token granted the user-read-playback-state and
+// user-modify-playback-state scopes; playback control needs Premium.
+type Spotify struct {
+	ClientID     string
+	ClientSecret string
+	RefreshToken string
+
+	// APIURL and TokenURL override the Spotify endpoints
+	APIURL   string
+	TokenURL string
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func (s *Spotify) Name() string {
+	return "spotify"
+}
+
+func (s *Spotify) Players(ctx context.Context) ([]Player, error) {
+	var resp struct {
+		Devices []struct {
+			ID       string `json:"id"`
+			Name     string `json:"name"`
+			IsActive bool   `json:"is_active"`
+		} `json:"devices"`
+	}
+	if err := s.do(ctx, http.MethodGet, "/me/player/devices", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	players := make([]Player, 0, len(resp.Devices))
+	for _, d := range resp.Devices {
+		if d.ID == "" {
+			continue
+		}
+		players = append(players, Player{ID: d.ID, Name: d.Name, Backend: "spotify", Active: d.IsActive})
+	}
+	return players, nil
+}
+
+// Play plays a track or episode URI, or a context such as an album,
+// playlist, or artist, moving playback to the device
+func (s *Spotify) Play(ctx context.Context, player string, m Media) error {
+	uri := spotifyURI(m.URI)
+	var body map[string]interface{}
+	switch kind := spotifyKind(uri); kind {
+	case "":
+	case "track", "episode":
+		body = map[string]interface{}{"uris": []string{uri}}
+	case "album", "playlist", "artist", "show":
+		body = map[string]interface{}{"context_uri": uri}
+	default:
+		return agenterrors.Newf(agenterrors.InvalidParams, "'%s' is not a Spotify URI (e.g. spotify:track:...)", m.URI)
+	}
+	return s.do(ctx, http.MethodPut, "/me/player/play", url.Values{"device_id": {player}}, body, nil)
+}
+
+func (s *Spotify) Pause(ctx context.Context, player string) error {
+	return s.do(ctx, http.MethodPut, "/me/player/pause", url.Values{"device_id": {player}}, nil, nil)
+}
+
+func (s *Spotify) Next(ctx context.Context, player string) error {
+	return s.do(ctx, http.MethodPost, "/me/player/next", url.Values{"device_id": {player}}, nil, nil)
+}
+
+func (s *Spotify) Volume(ctx context.Context, player string) (float64, error) {
+	var resp struct {
+		Devices []struct {
+			ID     string   `json:"id"`
+			Volume *float64 `json:"volume_percent"`
+		} `json:"devices"`
+	}
+	if err := s.do(ctx, http.MethodGet, "/me/player/devices", nil, nil, &resp); err != nil {
+		return 0, err
+	}
+	for _, d := range resp.Devices {
+		if d.ID == player && d.Volume != nil {
+			return *d.Volume, nil
+		}
+	}
+	return 0, agenterrors.New(agenterrors.Unsupported, "this Spotify device doesn't report its volume")
+}
+
+func (s *Spotify) SetVolume(ctx context.Context, player string, level float64) (float64, error) {
+	level = clamp(level)
+	query := url.Values{"device_id": {player}, "volume_percent": {strconv.Itoa(int(level + 0.5))}}
+	if err := s.do(ctx, http.MethodPut, "/me/player/volume", query, nil, nil); err != nil {
+		return 0, err
+	}
+	return float64(int(level + 0.5)), nil
+}
+
+// spotifyURI turns an open.spotify.com link into a spotify:<type>:<id> URI
+func spotifyURI(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Host != "open.spotify.com" {
+		return uri
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	// Links may carry a locale, e.g. /intl-de/track/...
+	if len(parts) == 3 && strings.HasPrefix(parts[0], "intl-") {
+		parts = parts[1:]
+	}
+	if len(parts) != 2 {
+		return uri
+	}
+	return "spotify:" + parts[0] + ":" + parts[1]
+}
+
+// spotifyKind returns the type in a spotify:<type>:<id> URI, "" for none
+func spotifyKind(uri string) string {
+	if uri == "" {
+		return ""
+	}
+	if rest, ok := strings.CutPrefix(uri, "spotify:"); ok {
+		kind, _, _ := strings.Cut(rest, ":")
+		return kind
+	}
+	return "invalid"
+}
+
+// do calls the Web API, translating its errors
+func (s *Spotify) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	token, err := s.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	base := s.APIURL
+	if base == "" {
+		base = spotifyAPI
+	}
+	target := base + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := connpool.Default.HTTP(10 * time.Second).Do(req)
+	if err != nil {
+		return agenterrors.Newf(agenterrors.Unavailable, "Spotify unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+				Reason  string `json:"reason"`
+			} `json:"error"`
+		}
+		json.Unmarshal(data, &e)
+		message := e.Error.Message
+		if message == "" {
+			message = resp.Status
+		}
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			return agenterrors.Newf(agenterrors.NotFound, "Spotify: %s", message)
+		case e.Error.Reason == "PREMIUM_REQUIRED":
+			return agenterrors.New(agenterrors.DeniedByPolicy, "Spotify playback control needs a Premium account")
+		case resp.StatusCode == http.StatusForbidden:
+			return agenterrors.Newf(agenterrors.Conflict, "Spotify: %s", message)
+		case resp.StatusCode == http.StatusUnauthorized:
+			s.mu.Lock()
+			s.token = ""
+			s.mu.Unlock()
+			return agenterrors.Newf(agenterrors.Unauthorized, "Spotify: %s", message)
+		}
+		return agenterrors.Newf(agenterrors.Unavailable, "Spotify: %s", message)
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("invalid Spotify response: %w", err)
+		}
+	}
+	return nil
+}
+
+// accessToken returns a current access token, refreshing it a minute
+// before it expires
+func (s *Spotify) accessToken(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && time.Now().Before(s.expires) {
+		return s.token, nil
+	}
+
+	endpoint := s.TokenURL
+	if endpoint == "" {
+		endpoint = spotifyToken
+	}
+	form := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {s.RefreshToken}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(s.ClientID, s.ClientSecret)
+
+	resp, err := connpool.Default.HTTP(10 * time.Second).Do(req)
+	if err != nil {
+		return "", agenterrors.Newf(agenterrors.Unavailable, "Spotify unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+		Error       string `json:"error_description"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token)
+	if resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		return "", agenterrors.Newf(agenterrors.Unauthorized, "Spotify sign-in failed: %s", strings.TrimSpace(resp.Status+" "+token.Error))
+	}
+	s.token = token.AccessToken
+	s.expires = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return s.token, nil
+}
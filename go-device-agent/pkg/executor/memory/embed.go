@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+)
+
+// Embedder turns text into vectors with a local embedding model
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Ollama embeds text with a local Ollama server's /api/embed endpoint
+type Ollama struct {
+	URL   string // e.g. http://127.0.0.1:11434
+	Model string // e.g. nomic-embed-text
+
+	client *http.Client
+}
+
+// NewOllama returns an embedder for the given server and model
+func NewOllama(url, model string) *Ollama {
+	return &Ollama{
+		URL:    strings.TrimRight(url, "/"),
+		Model:  model,
+		client: connpool.Default.HTTP(60 * time.Second),
+	}
+}
+
+func (o *Ollama) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"model": o.Model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.URL+"/api/embed", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, agenterrors.Newf(agenterrors.Unavailable, "embedding server unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		Embeddings [][]float32 `json:"embeddings"`
+		Error      string      `json:"error"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("invalid embedding response (%s): %w", resp.Status, err)
+	}
+	if out.Error != "" {
+		return nil, fmt.Errorf("embedding failed: %s", out.Error)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embedding server returned %s", resp.Status)
+	}
+	if len(out.Embeddings) != len(texts) {
+		return nil, errors.New("embedding server returned the wrong number of vectors")
+	}
+	return out.Embeddings, nil
+}
@@ -0,0 +1,262 @@
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// Fact is a value the agent core stored under a key, such as a user's
+// preferred wake-up time
+type Fact struct {
+	Namespace string      `json:"namespace"`
+	Key       string      `json:"key"`
+	Value     interface{} `json:"value"`
+	UpdatedAt time.Time   `json:"updated_at"`
+
+	// ExpiresAt is when a fact set with a TTL is forgotten
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// size is what a fact counts against its namespace's quota
+func (f Fact) size() int {
+	data, _ := json.Marshal(f.Value)
+	return len(f.Key) + len(data)
+}
+
+func (f Fact) expired(now time.Time) bool {
+	return f.ExpiresAt != nil && !now.Before(*f.ExpiresAt)
+}
+
+// facts is a key-value store split into namespaces, each with a size
+// quota, saved as JSON on every change
+type facts struct {
+	path    string
+	quota   int
+	maxKeys int
+
+	mu         sync.Mutex
+	namespaces map[string]map[string]Fact
+}
+
+func loadFacts(path string, quota, maxKeys int) (*facts, error) {
+	f := &facts{path: path, quota: quota, maxKeys: maxKeys, namespaces: make(map[string]map[string]Fact)}
+	if path == "" {
+		return f, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []Fact
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("invalid memory facts file %s: %w", path, err)
+	}
+	for _, fact := range list {
+		if f.namespaces[fact.Namespace] == nil {
+			f.namespaces[fact.Namespace] = make(map[string]Fact)
+		}
+		f.namespaces[fact.Namespace][fact.Key] = fact
+	}
+	return f, nil
+}
+
+// set stores a fact, replacing the key's old value, as long as the
+// namespace stays within its quota
+func (f *facts) set(namespace, key string, value interface{}, ttl time.Duration) (Fact, error) {
+	now := time.Now().UTC()
+	fact := Fact{Namespace: namespace, Key: key, Value: value, UpdatedAt: now}
+	if ttl > 0 {
+		expires := now.Add(ttl)
+		fact.ExpiresAt = &expires
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sweep(now)
+	ns := f.namespaces[namespace]
+	used, keys := fact.size(), 1
+	for k, old := range ns {
+		if k != key {
+			used += old.size()
+			keys++
+		}
+	}
+	if used > f.quota {
+		return Fact{}, agenterrors.Newf(agenterrors.Conflict,
+			"namespace %s would use %d of its %d bytes; delete keys or set shorter TTLs", namespace, used, f.quota)
+	}
+	if keys > f.maxKeys {
+		return Fact{}, agenterrors.Newf(agenterrors.Conflict,
+			"namespace %s is limited to %d keys; delete some first", namespace, f.maxKeys)
+	}
+	if ns == nil {
+		ns = make(map[string]Fact)
+		f.namespaces[namespace] = ns
+	}
+	ns[key] = fact
+	return fact, f.save()
+}
+
+func (f *facts) get(namespace, key string) (Fact, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fact, ok := f.namespaces[namespace][key]
+	if !ok || fact.expired(time.Now()) {
+		return Fact{}, false
+	}
+	return fact, true
+}
+
+func (f *facts) delete(namespace, key string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.namespaces[namespace][key]; !ok {
+		return false, nil
+	}
+	delete(f.namespaces[namespace], key)
+	if len(f.namespaces[namespace]) == 0 {
+		delete(f.namespaces, namespace)
+	}
+	return true, f.save()
+}
+
+// search finds the facts in namespace ("" for all) whose key or value
+// contains every word of query, keys matching first
+func (f *facts) search(namespace, query string, limit int) []Fact {
+	words := strings.Fields(strings.ToLower(query))
+	now := time.Now()
+
+	f.mu.Lock()
+	type scored struct {
+		fact  Fact
+		score int
+	}
+	var found []scored
+	for ns, keys := range f.namespaces {
+		if namespace != "" && ns != namespace {
+			continue
+		}
+		for _, fact := range keys {
+			if fact.expired(now) {
+				continue
+			}
+			key := strings.ToLower(fact.Key)
+			value, _ := json.Marshal(fact.Value)
+			text := strings.ToLower(string(value))
+			score := 0
+			for _, w := range words {
+				switch {
+				case strings.Contains(key, w):
+					score += 2
+				case strings.Contains(text, w):
+					score++
+				default:
+					score = -1
+				}
+				if score < 0 {
+					break
+				}
+			}
+			if score >= 0 {
+				found = append(found, scored{fact, score})
+			}
+		}
+	}
+	f.mu.Unlock()
+
+	sort.Slice(found, func(a, b int) bool {
+		if found[a].score != found[b].score {
+			return found[a].score > found[b].score
+		}
+		return found[a].fact.UpdatedAt.After(found[b].fact.UpdatedAt)
+	})
+	out := make([]Fact, 0, min(len(found), limit))
+	for _, s := range found[:min(len(found), limit)] {
+		out = append(out, s.fact)
+	}
+	return out
+}
+
+// usage reports a namespace's keys and bytes
+func (f *facts) usage(namespace string) (keys, bytes int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	for _, fact := range f.namespaces[namespace] {
+		if !fact.expired(now) {
+			keys++
+			bytes += fact.size()
+		}
+	}
+	return keys, bytes
+}
+
+// sweep forgets expired facts
+func (f *facts) sweep(now time.Time) bool {
+	swept := false
+	for ns, keys := range f.namespaces {
+		for k, fact := range keys {
+			if fact.expired(now) {
+				delete(keys, k)
+				swept = true
+			}
+		}
+		if len(keys) == 0 {
+			delete(f.namespaces, ns)
+		}
+	}
+	return swept
+}
+
+// expire forgets expired facts, saving if any were
+func (f *facts) expire() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.sweep(time.Now()) {
+		return nil
+	}
+	return f.save()
+}
+
+// save writes the facts atomically, sorted so the file diffs cleanly
+func (f *facts) save() error {
+	if f.path == "" {
+		return nil
+	}
+	list := []Fact{}
+	for _, keys := range f.namespaces {
+		for _, fact := range keys {
+			list = append(list, fact)
+		}
+	}
+	sort.Slice(list, func(a, b int) bool {
+		if list[a].Namespace != list[b].Namespace {
+			return list[a].Namespace < list[b].Namespace
+		}
+		return list[a].Key < list[b].Key
+	})
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o700); err != nil {
+		return err
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
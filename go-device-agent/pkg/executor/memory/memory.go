@@ -0,0 +1,572 @@
+// Package memory remembers device activity in a local vector index so the
+// agent core can recall it semantically ("when did the garage door last
+// open?"), and keeps facts it's told to remember, such as preferences,
+// under keys. Embeddings come from a local model; nothing leaves the
+// machine.
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Config controls what is remembered and how much
+type Config struct {
+	// StoreFile persists the index across restarts
+	StoreFile string
+
+	// Capture lists event patterns to remember (default "*"). Handled
+	// intents arrive as intent.completed events from the gateway.
+	Capture []string
+
+	// MaxRecords bounds the index; the oldest records go first (default 20000)
+	MaxRecords int
+
+	// MinScore is the cosine similarity below which matches are dropped (default 0.3)
+	MinScore float64
+
+	// FactsFile persists the facts set with memory.set
+	FactsFile string
+
+	// NamespaceQuota bounds the bytes of keys and values in one namespace
+	// (default 256 KiB)
+	NamespaceQuota int
+
+	// NamespaceKeys bounds the keys in one namespace (default 1000)
+	NamespaceKeys int
+}
+
+// Limits on what a single record and search can hold
+const (
+	maxTextChars   = 1000
+	maxResultsCap  = 20
+	queueSize      = 256
+	batchSize      = 16
+	saveInterval   = time.Minute
+	defaultResults = 5
+	maxValueBytes  = 16 << 10
+)
+
+// namespaces are lowercase, like user IDs or "preferences"
+var namespaces = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+// Executor handles memory.search, memory.remember, memory.set, memory.get,
+// and memory.delete, and records matching events in the background
+type Executor struct {
+	cfg      Config
+	embedder Embedder
+	store    *store
+	facts    *facts
+	queue    chan Record
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	dropped int
+}
+
+// NewExecutor creates a memory executor that remembers events from bus.
+// Without an embedder it only keeps facts: activity isn't recorded,
+// memory.remember is unavailable, and memory.search matches facts by
+// keyword.
+func NewExecutor(cfg Config, embedder Embedder, bus *events.Bus) (*Executor, error) {
+	if len(cfg.Capture) == 0 {
+		cfg.Capture = []string{"*"}
+	}
+	if cfg.MaxRecords <= 0 {
+		cfg.MaxRecords = 20000
+	}
+	if cfg.MinScore == 0 {
+		cfg.MinScore = 0.3
+	}
+	if cfg.NamespaceQuota <= 0 {
+		cfg.NamespaceQuota = 256 << 10
+	}
+	if cfg.NamespaceKeys <= 0 {
+		cfg.NamespaceKeys = 1000
+	}
+	s, err := loadStore(cfg.StoreFile, cfg.MaxRecords)
+	if err != nil {
+		return nil, err
+	}
+	f, err := loadFacts(cfg.FactsFile, cfg.NamespaceQuota, cfg.NamespaceKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Executor{
+		cfg:      cfg,
+		embedder: embedder,
+		store:    s,
+		facts:    f,
+		queue:    make(chan Record, queueSize),
+		logger:   slog.Default(),
+	}
+	if bus != nil && embedder != nil {
+		bus.Subscribe("*", e.observe)
+	}
+	return e, nil
+}
+
+// SetLogger sets where background embedding errors are reported
+func (e *Executor) SetLogger(logger *slog.Logger) {
+	e.logger = logger
+}
+
+// observe queues a captured event; it runs on the publisher's goroutine so
+// it never blocks, dropping records when the embedder falls behind
+func (e *Executor) observe(ev events.Event) {
+	captured := false
+	for _, pattern := range e.cfg.Capture {
+		if events.Match(pattern, ev.Type) {
+			captured = true
+			break
+		}
+	}
+	if !captured {
+		return
+	}
+	r, ok := describe(ev)
+	if !ok {
+		return
+	}
+	select {
+	case e.queue <- r:
+	default:
+		e.mu.Lock()
+		e.dropped++
+		e.mu.Unlock()
+	}
+}
+
+// Start embeds queued records in batches, saves the index, and forgets
+// expired facts periodically until ctx is cancelled
+func (e *Executor) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(saveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				if err := e.store.save(); err != nil {
+					e.logger.Error("Failed to save memory", "error", err)
+				}
+				return
+			case r := <-e.queue:
+				batch := []Record{r}
+			fill:
+				for len(batch) < batchSize {
+					select {
+					case r := <-e.queue:
+						batch = append(batch, r)
+					default:
+						break fill
+					}
+				}
+				if err := e.embed(ctx, batch); err != nil && ctx.Err() == nil {
+					e.logger.Error("Failed to remember records", "records", len(batch), "error", err)
+				}
+			case <-ticker.C:
+				if err := e.store.save(); err != nil {
+					e.logger.Error("Failed to save memory", "error", err)
+				}
+				if err := e.facts.expire(); err != nil {
+					e.logger.Error("Failed to save memory facts", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+func (e *Executor) embed(ctx context.Context, records []Record) error {
+	texts := make([]string, len(records))
+	for i, r := range records {
+		texts[i] = r.Text
+	}
+	vectors, err := e.embedder.Embed(ctx, texts)
+	if err != nil {
+		return err
+	}
+	for i := range records {
+		records[i].Vector = vectors[i]
+	}
+	e.store.add(records...)
+	return nil
+}
+
+func (e *Executor) Name() string {
+	return "memory"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"memory.search", "memory.remember", "memory.set", "memory.get", "memory.delete"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{
+		Network: e.embedder != nil,
+		Write:   []string{filepath.Dir(e.cfg.StoreFile), filepath.Dir(e.cfg.FactsFile)},
+	}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "memory",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	switch i.IntentType {
+	case "memory.search":
+		var params struct {
+			Query     string        `param:"query,required"`
+			Limit     int           `param:"limit"`
+			Kind      string        `param:"kind"`
+			Since     time.Duration `param:"since"`
+			Namespace string        `param:"namespace"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		limit := params.Limit
+		if limit <= 0 {
+			limit = defaultResults
+		}
+		if limit > maxResultsCap {
+			limit = maxResultsCap
+		}
+		var cutoff time.Time
+		if params.Since > 0 {
+			cutoff = time.Now().Add(-params.Since)
+		}
+
+		// Facts are matched by keyword, and only facts have namespaces
+		found := make([]map[string]interface{}, 0, limit)
+		if params.Kind == "" || params.Kind == "fact" {
+			for _, f := range e.facts.search(params.Namespace, params.Query, limit) {
+				if !f.UpdatedAt.Before(cutoff) {
+					found = append(found, factResult(f))
+				}
+			}
+		}
+		if e.embedder == nil || params.Kind == "fact" || params.Namespace != "" {
+			result.Success = true
+			result.Result = map[string]interface{}{
+				"query":   params.Query,
+				"matches": found,
+				"count":   len(found),
+			}
+			break
+		}
+
+		vectors, err := e.embedder.Embed(ctx, []string{params.Query})
+		if err != nil {
+			return fail(err)
+		}
+		matches := e.store.search(vectors[0], limit, e.cfg.MinScore, func(r Record) bool {
+			return (params.Kind == "" || r.Kind == params.Kind) && !r.Time.Before(cutoff)
+		})
+
+		for _, m := range matches {
+			entry := map[string]interface{}{
+				"id":    m.ID,
+				"kind":  m.Kind,
+				"text":  m.Text,
+				"time":  m.Time.Format(time.RFC3339),
+				"score": m.Score,
+			}
+			if m.Source != "" {
+				entry["source"] = m.Source
+			}
+			if len(m.Metadata) > 0 {
+				entry["metadata"] = m.Metadata
+			}
+			found = append(found, entry)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"query":   params.Query,
+			"matches": found,
+			"count":   len(found),
+			"records": e.store.size(),
+		}
+		e.mu.Lock()
+		if e.dropped > 0 {
+			result.Result["dropped"] = e.dropped
+		}
+		e.mu.Unlock()
+
+	case "memory.remember":
+		if e.embedder == nil {
+			return fail(agenterrors.New(agenterrors.Unavailable, "memory.remember needs an embedding model; use memory.set to keep a fact"))
+		}
+		var params struct {
+			Text   string `param:"text,required"`
+			Source string `param:"source"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		r := Record{
+			ID:     i.ID,
+			Kind:   "note",
+			Text:   truncate(params.Text),
+			Source: params.Source,
+			Time:   time.Now().UTC(),
+		}
+		if err := e.embed(ctx, []Record{r}); err != nil {
+			return fail(err)
+		}
+		if err := e.store.save(); err != nil {
+			return fail(fmt.Errorf("remembered but not saved: %w", err))
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{"id": r.ID, "records": e.store.size()}
+
+	case "memory.set":
+		var params struct {
+			Namespace string        `param:"namespace"`
+			Key       string        `param:"key,required"`
+			Value     interface{}   `param:"value,required"`
+			TTL       time.Duration `param:"ttl"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		namespace, err := namespaceOf(params.Namespace)
+		if err != nil {
+			return fail(err)
+		}
+		if data, _ := json.Marshal(params.Value); len(data) > maxValueBytes {
+			return fail(agenterrors.Newf(agenterrors.InvalidParams, "values are at most %d bytes of JSON", maxValueBytes))
+		}
+		if params.TTL < 0 {
+			return fail(agenterrors.New(agenterrors.InvalidParams, "ttl must be positive"))
+		}
+		f, err := e.facts.set(namespace, params.Key, params.Value, params.TTL)
+		if err != nil {
+			return fail(err)
+		}
+		keys, bytes := e.facts.usage(namespace)
+		out := factResult(f)
+		out["namespace_keys"] = keys
+		out["namespace_bytes"] = bytes
+		out["namespace_quota"] = e.cfg.NamespaceQuota
+		result.Success = true
+		result.Result = out
+
+	case "memory.get":
+		var params struct {
+			Namespace string `param:"namespace"`
+			Key       string `param:"key,required"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		namespace, err := namespaceOf(params.Namespace)
+		if err != nil {
+			return fail(err)
+		}
+		f, ok := e.facts.get(namespace, params.Key)
+		if !ok {
+			return fail(agenterrors.Newf(agenterrors.NotFound, "nothing remembered as %s in %s", params.Key, namespace))
+		}
+		result.Success = true
+		result.Result = factResult(f)
+
+	case "memory.delete":
+		var params struct {
+			Namespace string `param:"namespace"`
+			Key       string `param:"key,required"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		namespace, err := namespaceOf(params.Namespace)
+		if err != nil {
+			return fail(err)
+		}
+		deleted, err := e.facts.delete(namespace, params.Key)
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"namespace": namespace,
+			"key":       params.Key,
+			"deleted":   deleted,
+		}
+
+	default:
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+
+	return result, nil
+}
+
+// namespaceOf checks a namespace, defaulting to "default"
+func namespaceOf(ns string) (string, error) {
+	if ns == "" {
+		return "default", nil
+	}
+	if !namespaces.MatchString(ns) {
+		return "", agenterrors.Newf(agenterrors.InvalidParams, "namespace %q must be lowercase letters, digits, and . _ -", ns)
+	}
+	return ns, nil
+}
+
+func factResult(f Fact) map[string]interface{} {
+	out := map[string]interface{}{
+		"kind":       "fact",
+		"namespace":  f.Namespace,
+		"key":        f.Key,
+		"value":      f.Value,
+		"updated_at": f.UpdatedAt.Format(time.RFC3339),
+	}
+	if f.ExpiresAt != nil {
+		out["expires_at"] = f.ExpiresAt.Format(time.RFC3339)
+	}
+	return out
+}
+
+func (e *Executor) IsAvailable() bool {
+	return true
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"memory.search": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"query": {"type": "string", "minLength": 1, "maxLength": 500},
+				"limit": {"type": "integer", "minimum": 1, "maximum": 20},
+				"kind": {"type": "string", "enum": ["intent", "event", "note", "fact"]},
+				"namespace": {"type": "string", "minLength": 1}
+			},
+			"required": ["query"]
+		}`),
+		"memory.remember": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"text": {"type": "string", "minLength": 1, "maxLength": 1000},
+				"source": {"type": "string"}
+			},
+			"required": ["text"]
+		}`),
+		"memory.set": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"namespace": {"type": "string", "minLength": 1, "maxLength": 64},
+				"key": {"type": "string", "minLength": 1, "maxLength": 200},
+				"value": {},
+				"ttl": {"type": "string"}
+			},
+			"required": ["key", "value"]
+		}`),
+		"memory.get": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"namespace": {"type": "string", "minLength": 1, "maxLength": 64},
+				"key": {"type": "string", "minLength": 1, "maxLength": 200}
+			},
+			"required": ["key"]
+		}`),
+		"memory.delete": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"namespace": {"type": "string", "minLength": 1, "maxLength": 64},
+				"key": {"type": "string", "minLength": 1, "maxLength": 200}
+			},
+			"required": ["key"]
+		}`),
+	}
+}
+
+// describe renders an event as the sentence that gets embedded. Memory's
+// own activity is skipped so searches don't remember themselves.
+func describe(ev events.Event) (Record, bool) {
+	r := Record{
+		ID:     ev.ID,
+		Kind:   "event",
+		Source: ev.Source,
+		Time:   ev.Timestamp.UTC(),
+	}
+
+	if ev.Type == "intent.completed" {
+		intentType, _ := ev.Data["intent_type"].(string)
+		if strings.HasPrefix(intentType, "memory.") {
+			return Record{}, false
+		}
+		var text strings.Builder
+		text.WriteString(intentType)
+		if params, ok := ev.Data["parameters"].(map[string]interface{}); ok && len(params) > 0 {
+			text.WriteString(" (" + pairs(params) + ")")
+		}
+		if success, _ := ev.Data["success"].(bool); success {
+			text.WriteString(" succeeded")
+		} else {
+			text.WriteString(" failed")
+			if msg, _ := ev.Data["error"].(string); msg != "" {
+				text.WriteString(": " + msg)
+			}
+		}
+		r.Kind = "intent"
+		r.Text = truncate(text.String())
+		r.Metadata = map[string]string{"intent_type": intentType}
+		for _, key := range []string{"intent_id", "correlation_id", "module"} {
+			if v, _ := ev.Data[key].(string); v != "" {
+				r.Metadata[key] = v
+			}
+		}
+		return r, true
+	}
+
+	text := ev.Type + " from " + ev.Source
+	if len(ev.Data) > 0 {
+		text += ": " + pairs(ev.Data)
+	}
+	r.Text = truncate(text)
+	r.Metadata = map[string]string{"event_type": ev.Type}
+	return r, true
+}
+
+// pairs formats a map as "key=value" pairs in key order
+func pairs(m map[string]interface{}) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=%v", k, m[k])
+	}
+	return strings.Join(parts, ", ")
+}
+
+func truncate(s string) string {
+	if r := []rune(s); len(r) > maxTextChars {
+		return string(r[:maxTextChars])
+	}
+	return s
+}
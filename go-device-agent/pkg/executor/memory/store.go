@@ -0,0 +1,154 @@
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Record is one remembered item
+type Record struct {
+	ID       string            `json:"id"`
+	Kind     string            `json:"kind"` // "intent", "event", or "note"
+	Text     string            `json:"text"`
+	Source   string            `json:"source,omitempty"`
+	Time     time.Time         `json:"time"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Vector   []float32         `json:"vector"`
+}
+
+// Match is a search result
+type Match struct {
+	Record
+	Score float64 `json:"score"`
+}
+
+// store is a brute-force cosine index over unit-length vectors. Linear
+// scans stay fast at household scale (tens of thousands of records).
+type store struct {
+	path       string
+	maxRecords int
+
+	mu      sync.RWMutex
+	records []Record
+	dirty   bool
+}
+
+func loadStore(path string, maxRecords int) (*store, error) {
+	s := &store{path: path, maxRecords: maxRecords}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.records); err != nil {
+		return nil, fmt.Errorf("invalid memory store %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// add stores records, evicting the oldest beyond maxRecords
+func (s *store) add(records ...Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range records {
+		r.Vector = normalize(r.Vector)
+		s.records = append(s.records, r)
+	}
+	if over := len(s.records) - s.maxRecords; over > 0 {
+		s.records = append([]Record(nil), s.records[over:]...)
+	}
+	s.dirty = true
+}
+
+// search returns the records most similar to vector, newest first among
+// equal scores
+func (s *store) search(vector []float32, limit int, minScore float64, keep func(Record) bool) []Match {
+	vector = normalize(vector)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matches []Match
+	for _, r := range s.records {
+		if len(r.Vector) != len(vector) || (keep != nil && !keep(r)) {
+			continue
+		}
+		var dot float64
+		for i := range vector {
+			dot += float64(vector[i]) * float64(r.Vector[i])
+		}
+		if dot < minScore {
+			continue
+		}
+		matches = append(matches, Match{Record: r, Score: math.Round(dot*1000) / 1000})
+	}
+	sort.SliceStable(matches, func(a, b int) bool {
+		if matches[a].Score != matches[b].Score {
+			return matches[a].Score > matches[b].Score
+		}
+		return matches[a].Time.After(matches[b].Time)
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+func (s *store) size() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.records)
+}
+
+// save writes the store atomically if it changed since the last save
+func (s *store) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.path == "" || !s.dirty {
+		return nil
+	}
+	data, err := json.Marshal(s.records)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := float32(math.Sqrt(sum))
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = x / norm
+	}
+	return out
+}
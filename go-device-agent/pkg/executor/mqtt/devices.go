@@ -0,0 +1,228 @@
+package mqtt
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/registry"
+)
+
+// DeviceMap is the YAML file describing the devices behind the broker:
+//
+//	broker: tcp://192.168.1.10:1883
+//	devices:
+//	  kitchen_plug:
+//	    name: Kitchen Plug
+//	    room: kitchen
+//	    preset: tasmota
+//	    topic: tasmota_A1B2C3
+//	  garage_door:
+//	    state_topic: garage/door/state
+//	    actions:
+//	      open: {topic: garage/door/set, payload: OPEN}
+//	      position: {topic: garage/door/set, payload: '{"position": {{value}}}'}
+type DeviceMap struct {
+	// Broker URL (tcp://, ssl://, ws://, or wss://)
+	Broker string `yaml:"broker"`
+
+	Devices map[string]*Device `yaml:"devices"`
+}
+
+// Device is one device and the topics that drive it. Topics and payloads
+// are templates: {{id}} and {{topic}} expand to the device's ID and base
+// topic, and any other {{name}} to the intent parameter of that name
+// (usually {{value}}).
+type Device struct {
+	ID   string        `yaml:"-"`
+	Name string        `yaml:"name"`
+	Kind registry.Kind `yaml:"kind"`
+	Room string        `yaml:"room"`
+
+	// Preset fills in the topics and actions of a known firmware:
+	// "tasmota" or "zigbee2mqtt". Actions listed here add to or replace
+	// the preset's.
+	Preset string `yaml:"preset"`
+
+	// Topic is the device's base topic (the Tasmota topic or the
+	// Zigbee2MQTT friendly name)
+	Topic string `yaml:"topic"`
+
+	// StateTopic is subscribed to for state reports; it may use MQTT
+	// wildcards
+	StateTopic string `yaml:"state_topic"`
+
+	// Query asks the device to report its state
+	Query *Message `yaml:"query"`
+
+	// Actions maps device.control actions to the message they publish
+	Actions map[string]Message `yaml:"actions"`
+}
+
+// Message is a publish template
+type Message struct {
+	Topic   string `yaml:"topic"`
+	Payload string `yaml:"payload"`
+	QoS     byte   `yaml:"qos"`
+	Retain  bool   `yaml:"retain"`
+}
+
+// presets hold the conventions of common firmware
+var presets = map[string]Device{
+	"tasmota": {
+		Kind:       registry.KindSwitch,
+		StateTopic: "stat/{{topic}}/RESULT",
+		Query:      &Message{Topic: "cmnd/{{topic}}/STATE"},
+		Actions: map[string]Message{
+			"on":         {Topic: "cmnd/{{topic}}/POWER", Payload: "ON"},
+			"off":        {Topic: "cmnd/{{topic}}/POWER", Payload: "OFF"},
+			"toggle":     {Topic: "cmnd/{{topic}}/POWER", Payload: "TOGGLE"},
+			"brightness": {Topic: "cmnd/{{topic}}/Dimmer", Payload: "{{value}}"},
+			"color":      {Topic: "cmnd/{{topic}}/Color", Payload: "{{value}}"},
+		},
+	},
+	"zigbee2mqtt": {
+		Kind:       registry.KindLight,
+		StateTopic: "zigbee2mqtt/{{topic}}",
+		Query:      &Message{Topic: "zigbee2mqtt/{{topic}}/get", Payload: `{"state": ""}`},
+		Actions: map[string]Message{
+			"on":         {Topic: "zigbee2mqtt/{{topic}}/set", Payload: `{"state": "ON"}`},
+			"off":        {Topic: "zigbee2mqtt/{{topic}}/set", Payload: `{"state": "OFF"}`},
+			"toggle":     {Topic: "zigbee2mqtt/{{topic}}/set", Payload: `{"state": "TOGGLE"}`},
+			"brightness": {Topic: "zigbee2mqtt/{{topic}}/set", Payload: `{"brightness": {{value}}}`},
+			"color":      {Topic: "zigbee2mqtt/{{topic}}/set", Payload: `{"color": {"hex": "{{value}}"}}`},
+		},
+	},
+}
+
+// LoadDevices reads and checks a YAML device map
+func LoadDevices(path string) (*DeviceMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m DeviceMap
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid MQTT device map %s: %w", path, err)
+	}
+	for id, d := range m.Devices {
+		if d == nil {
+			return nil, fmt.Errorf("invalid MQTT device map %s: device %s is empty", path, id)
+		}
+		d.ID = id
+		if err := d.prepare(); err != nil {
+			return nil, fmt.Errorf("invalid MQTT device map %s: device %s: %w", path, id, err)
+		}
+	}
+	return &m, nil
+}
+
+// prepare applies the preset and defaults and checks the templates
+func (d *Device) prepare() error {
+	if d.Preset != "" {
+		p, ok := presets[strings.ToLower(d.Preset)]
+		if !ok {
+			return fmt.Errorf("unknown preset %q (want tasmota or zigbee2mqtt)", d.Preset)
+		}
+		if d.Topic == "" {
+			return fmt.Errorf("preset %s needs a topic", d.Preset)
+		}
+		if d.Kind == "" {
+			d.Kind = p.Kind
+		}
+		if d.StateTopic == "" {
+			d.StateTopic = p.StateTopic
+		}
+		if d.Query == nil {
+			d.Query = p.Query
+		}
+		actions := make(map[string]Message, len(p.Actions)+len(d.Actions))
+		for name, msg := range p.Actions {
+			actions[name] = msg
+		}
+		for name, msg := range d.Actions {
+			actions[name] = msg
+		}
+		d.Actions = actions
+	}
+	if d.Name == "" {
+		d.Name = d.ID
+	}
+	if d.Kind == "" {
+		d.Kind = registry.KindSwitch
+	}
+	if len(d.Actions) == 0 && d.StateTopic == "" {
+		return fmt.Errorf("needs actions or a state_topic")
+	}
+	for name, msg := range d.Actions {
+		if msg.Topic == "" {
+			return fmt.Errorf("action %s has no topic", name)
+		}
+		if msg.QoS > 2 {
+			return fmt.Errorf("action %s: qos must be 0, 1, or 2", name)
+		}
+	}
+	if d.Query != nil && d.Query.Topic == "" {
+		return fmt.Errorf("query has no topic")
+	}
+	return nil
+}
+
+// actionNames lists the device's actions in order
+func (d *Device) actionNames() []string {
+	names := make([]string, 0, len(d.Actions))
+	for name := range d.Actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// expand fills in a template from the device and the intent parameters
+func (d *Device) expand(tmpl string, params map[string]interface{}) (string, error) {
+	var b strings.Builder
+	for {
+		start := strings.Index(tmpl, "{{")
+		if start < 0 {
+			b.WriteString(tmpl)
+			return b.String(), nil
+		}
+		end := strings.Index(tmpl[start:], "}}")
+		if end < 0 {
+			b.WriteString(tmpl)
+			return b.String(), nil
+		}
+		b.WriteString(tmpl[:start])
+		name := strings.TrimSpace(tmpl[start+2 : start+end])
+		switch name {
+		case "id":
+			b.WriteString(d.ID)
+		case "topic":
+			b.WriteString(d.Topic)
+		default:
+			v, ok := params[name]
+			if !ok || v == nil {
+				return "", agenterrors.Newf(agenterrors.InvalidParams, "%s needs a '%s' parameter", d.ID, name)
+			}
+			b.WriteString(format(v))
+		}
+		tmpl = tmpl[start+end+2:]
+	}
+}
+
+func format(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,188 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/internal/uuid"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/usage"
+)
+
+// Zigbee2MQTT's bridge API for over-the-air updates
+const (
+	z2mOTACheck  = "zigbee2mqtt/bridge/request/device/ota_update/check"
+	z2mOTAUpdate = "zigbee2mqtt/bridge/request/device/ota_update/update"
+	z2mResponse  = "zigbee2mqtt/bridge/response/device/ota_update/"
+)
+
+// CheckFirmware reports the firmware a Tasmota or Zigbee2MQTT device runs.
+// For Zigbee2MQTT it also asks the coordinator's OTA index for a newer
+// version; available is the installed version when there is none, and
+// empty when the firmware can't tell.
+func (e *Executor) CheckFirmware(ctx context.Context, device string) (installed, available string, err error) {
+	d, err := e.find(strings.TrimPrefix(device, "mqtt:"))
+	if err != nil {
+		return "", "", err
+	}
+	switch strings.ToLower(d.Preset) {
+	case "tasmota":
+		reply, err := e.request(ctx, "cmnd/"+d.Topic+"/STATUS", "2", "stat/"+d.Topic+"/STATUS2", nil)
+		if err != nil {
+			return "", "", err
+		}
+		var status struct {
+			StatusFWR struct {
+				Version string
+			}
+		}
+		if err := json.Unmarshal(reply, &status); err != nil || status.StatusFWR.Version == "" {
+			return "", "", agenterrors.Newf(agenterrors.Unavailable, "%s sent an unreadable firmware status", d.Name)
+		}
+		return status.StatusFWR.Version, "", nil
+
+	case "zigbee2mqtt":
+		data, err := e.bridge(ctx, z2mOTACheck, d)
+		if err != nil {
+			return "", "", err
+		}
+		newer, _ := data["update_available"].(bool)
+
+		// The device's own reports carry the versions
+		e.mu.Lock()
+		update, _ := e.states[d.ID].values["update"].(map[string]interface{})
+		e.mu.Unlock()
+		installed = version(update["installed_version"])
+		if installed == "" {
+			return "", "", agenterrors.Newf(agenterrors.Unavailable, "%s has not reported its firmware version", d.Name)
+		}
+		if !newer {
+			return installed, installed, nil
+		}
+		if available = version(update["latest_version"]); available == "" || available == installed {
+			available = "newer than " + installed
+		}
+		return installed, available, nil
+	}
+	return "", "", agenterrors.Newf(agenterrors.Unsupported, "%s has no firmware preset, so it can't be updated", d.Name)
+}
+
+// InstallFirmware updates a device. Tasmota fetches the image URL given,
+// or its configured OtaUrl, and restarts, so this returns once the
+// upgrade is requested; Zigbee2MQTT installs from its OTA index and
+// answers when the transfer finishes, which can take most of an hour.
+func (e *Executor) InstallFirmware(ctx context.Context, device, image string) error {
+	d, err := e.find(strings.TrimPrefix(device, "mqtt:"))
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(d.Preset) {
+	case "tasmota":
+		usage.DeviceCommand(ctx)
+		if image != "" {
+			if err := e.send("cmnd/"+d.Topic+"/OtaUrl", 1, false, image); err != nil {
+				return err
+			}
+		}
+		return e.send("cmnd/"+d.Topic+"/Upgrade", 1, false, "1")
+
+	case "zigbee2mqtt":
+		if image != "" {
+			return agenterrors.New(agenterrors.Unsupported, "Zigbee2MQTT installs from its OTA index; an image can't be chosen")
+		}
+		usage.DeviceCommand(ctx)
+		_, err := e.bridge(ctx, z2mOTAUpdate, d)
+		return err
+	}
+	return agenterrors.Newf(agenterrors.Unsupported, "%s has no firmware preset, so it can't be updated", d.Name)
+}
+
+// bridge makes a Zigbee2MQTT bridge request about a device and returns the
+// response's data
+func (e *Executor) bridge(ctx context.Context, topic string, d *Device) (map[string]interface{}, error) {
+	transaction := uuid.New()
+	payload, _ := json.Marshal(map[string]string{"id": d.Topic, "transaction": transaction})
+	reply := z2mResponse + topic[strings.LastIndex(topic, "/")+1:]
+
+	type response struct {
+		Data        map[string]interface{} `json:"data"`
+		Status      string                 `json:"status"`
+		Error       string                 `json:"error"`
+		Transaction string                 `json:"transaction"`
+	}
+	msg, err := e.request(ctx, topic, string(payload), reply, func(msg []byte) bool {
+		var r response
+		return json.Unmarshal(msg, &r) == nil && r.Transaction == transaction
+	})
+	if err != nil {
+		return nil, err
+	}
+	var r response
+	json.Unmarshal(msg, &r)
+	if r.Status != "ok" {
+		return nil, agenterrors.Newf(agenterrors.Unavailable, "Zigbee2MQTT: %s", r.Error)
+	}
+	return r.Data, nil
+}
+
+// request publishes a payload and waits for a reply on another topic,
+// the first one match accepts (any, when match is nil). Without a deadline
+// on ctx it waits for the configured timeout.
+func (e *Executor) request(ctx context.Context, topic, payload, reply string, match func([]byte) bool) ([]byte, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.cfg.Timeout)
+		defer cancel()
+	}
+	if e.blocked != nil {
+		return nil, e.blocked
+	}
+	if !e.client.IsConnectionOpen() {
+		return nil, agenterrors.Newf(agenterrors.Unavailable, "not connected to MQTT broker %s", e.cfg.Broker)
+	}
+
+	replies := make(chan []byte, 1)
+	token := e.client.Subscribe(reply, 1, func(_ paho.Client, msg paho.Message) {
+		if match == nil || match(msg.Payload()) {
+			select {
+			case replies <- msg.Payload():
+			default:
+			}
+		}
+	})
+	if !token.WaitTimeout(e.cfg.Timeout) || token.Error() != nil {
+		return nil, agenterrors.Newf(agenterrors.Unavailable, "subscribing to %s failed", reply)
+	}
+	defer e.client.Unsubscribe(reply)
+
+	if err := e.send(topic, 1, false, payload); err != nil {
+		return nil, err
+	}
+	select {
+	case msg := <-replies:
+		return msg, nil
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, agenterrors.Newf(agenterrors.Timeout, "no reply on %s", reply)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// version formats a firmware version as Zigbee2MQTT reports it, a number
+// or a string
+func version(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	case string:
+		return v
+	}
+	return fmt.Sprint(v)
+}
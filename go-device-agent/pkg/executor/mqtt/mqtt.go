@@ -0,0 +1,466 @@
+// Package mqtt controls devices that speak MQTT, such as Tasmota plugs and
+// Zigbee2MQTT lights, by publishing to their command topics and following
+// their state topics as a YAML device map describes
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/registry"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/usage"
+)
+
+// Config sets the broker and the devices behind it
+type Config struct {
+	// Broker URL, overriding the device map's
+	Broker   string
+	Username string
+	Password string
+
+	// ClientID identifies the agent to the broker (default
+	// device-agent-<hostname>). Executors with the same broker, client ID,
+	// and login share a connection.
+	ClientID string
+
+	// Devices is the loaded device map
+	Devices *DeviceMap
+
+	// Timeout bounds publishes and waits for state reports (default 5s)
+	Timeout time.Duration
+}
+
+// state is the last report from a device, merged across messages since
+// firmware like Tasmota reports a field at a time
+type state struct {
+	values    map[string]interface{}
+	updatedAt time.Time
+	changed   chan struct{} // closed and replaced on every report
+}
+
+// Executor handles device.list, device.control, and device.query for the
+// devices in the map; intents reach it by targeting the mqtt module
+type Executor struct {
+	cfg      Config
+	devices  map[string]*Device
+	session  *connpool.MQTTSession
+	client   paho.Client
+	registry *registry.Registry
+	logger   *slog.Logger
+
+	// blocked is why the broker may not be reached in air-gapped mode
+	blocked error
+
+	mu     sync.Mutex
+	states map[string]*state
+}
+
+// NewExecutor creates an MQTT executor. Devices are mirrored into reg as
+// "mqtt:<id>" when it is not nil.
+func NewExecutor(cfg Config, reg *registry.Registry) (*Executor, error) {
+	if cfg.Devices == nil || len(cfg.Devices.Devices) == 0 {
+		return nil, agenterrors.New(agenterrors.InvalidParams, "the MQTT device map has no devices")
+	}
+	if cfg.Broker == "" {
+		cfg.Broker = cfg.Devices.Broker
+	}
+	if cfg.Broker == "" {
+		return nil, agenterrors.New(agenterrors.InvalidParams, "no MQTT broker configured")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.ClientID == "" {
+		host, _ := os.Hostname()
+		cfg.ClientID = "device-agent-" + host
+	}
+	e := &Executor{
+		cfg:      cfg,
+		devices:  cfg.Devices.Devices,
+		registry: reg,
+		logger:   slog.Default(),
+		states:   make(map[string]*state),
+	}
+	for id, d := range e.devices {
+		e.states[id] = &state{values: make(map[string]interface{}), changed: make(chan struct{})}
+		if reg != nil {
+			reg.Upsert(registry.Device{
+				ID:     "mqtt:" + id,
+				Name:   d.Name,
+				Kind:   d.Kind,
+				Room:   d.Room,
+				Module: "mqtt",
+			})
+		}
+	}
+
+	session, err := connpool.Default.MQTT(connpool.MQTTOptions{
+		Broker:         cfg.Broker,
+		ClientID:       cfg.ClientID,
+		Username:       cfg.Username,
+		Password:       cfg.Password,
+		ConnectTimeout: cfg.Timeout,
+	})
+	switch {
+	case errors.Is(err, connpool.ErrEgressBlocked):
+		e.blocked = agenterrors.Wrap(agenterrors.Unavailable, err)
+	case err != nil:
+		return nil, agenterrors.Wrap(agenterrors.InvalidParams, err)
+	default:
+		e.session, e.client = session, session.Client()
+	}
+	return e, nil
+}
+
+// SetLogger sets where connection problems are logged
+func (e *Executor) SetLogger(logger *slog.Logger) {
+	e.logger = logger
+}
+
+// Start subscribes to the devices' states whenever the shared broker
+// session connects, until ctx is done. A broker air-gapped mode blocks is
+// never dialed.
+func (e *Executor) Start(ctx context.Context) {
+	if e.blocked != nil {
+		e.logger.Warn("MQTT broker not allowed in air-gapped mode", "broker", e.cfg.Broker, "error", e.blocked)
+		return
+	}
+	e.session.OnConnectionLost(func(err error) {
+		e.logger.Warn("MQTT connection lost", "broker", e.cfg.Broker, "error", err)
+	})
+	e.session.OnConnect(e.connected)
+	go func() {
+		<-ctx.Done()
+		e.session.Release()
+	}()
+}
+
+// connected subscribes to every state topic and asks each device for its
+// state, on the first connection and after every reconnect
+func (e *Executor) connected(client paho.Client) {
+	for id, d := range e.devices {
+		if d.StateTopic == "" {
+			continue
+		}
+		topic, err := d.expand(d.StateTopic, nil)
+		if err != nil {
+			e.logger.Warn("Bad MQTT state topic", "device", id, "error", err)
+			continue
+		}
+		client.Subscribe(topic, 0, func(_ paho.Client, msg paho.Message) {
+			e.observe(id, msg.Payload())
+		})
+		if d.Query != nil {
+			e.publish(d, *d.Query, nil)
+		}
+	}
+}
+
+// observe merges a state report and mirrors it into the registry
+func (e *Executor) observe(id string, payload []byte) {
+	report := make(map[string]interface{})
+	if err := json.Unmarshal(payload, &report); err != nil {
+		report = map[string]interface{}{"state": string(payload)}
+	}
+
+	e.mu.Lock()
+	s := e.states[id]
+	for k, v := range report {
+		s.values[k] = v
+	}
+	s.updatedAt = time.Now()
+	close(s.changed)
+	s.changed = make(chan struct{})
+	e.mu.Unlock()
+
+	if e.registry != nil {
+		if on, ok := power(report); ok {
+			report["on"] = on
+		}
+		e.registry.UpdateState("mqtt:"+id, report)
+	}
+}
+
+// power reads the on/off state from Tasmota's POWER or Zigbee2MQTT's state
+func power(report map[string]interface{}) (bool, bool) {
+	for k, v := range report {
+		if !strings.EqualFold(k, "power") && !strings.EqualFold(k, "state") {
+			continue
+		}
+		switch s, _ := v.(string); strings.ToUpper(s) {
+		case "ON":
+			return true, true
+		case "OFF":
+			return false, true
+		}
+	}
+	return false, false
+}
+
+func (e *Executor) Name() string {
+	return "mqtt"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"device.list", "device.control", "device.query"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{Network: true}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "mqtt",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	switch i.IntentType {
+	case "device.list":
+		result.Success = true
+		result.Result = map[string]interface{}{"devices": e.list()}
+		return result, nil
+
+	case "device.control":
+		var params struct {
+			Device string `param:"device,required"`
+			Action string `param:"action,required"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		d, err := e.find(params.Device)
+		if err != nil {
+			return fail(err)
+		}
+		msg, ok := d.Actions[strings.ToLower(params.Action)]
+		if !ok {
+			return fail(agenterrors.Newf(agenterrors.InvalidParams, "%s does not support '%s' (supports %s)",
+				d.Name, params.Action, strings.Join(d.actionNames(), ", ")))
+		}
+		topic, err := e.publish(d, msg, i.Parameters)
+		if err != nil {
+			return fail(err)
+		}
+		usage.DeviceCommand(ctx)
+		result.Success = true
+		result.Result = map[string]interface{}{"device": d.ID, "action": params.Action, "topic": topic}
+		return result, nil
+
+	case "device.query":
+		var params struct {
+			Device  string `param:"device,required"`
+			Refresh bool   `param:"refresh"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		d, err := e.find(params.Device)
+		if err != nil {
+			return fail(err)
+		}
+		values, updatedAt, err := e.query(ctx, d, params.Refresh)
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"device":     d.ID,
+			"state":      values,
+			"updated_at": updatedAt.Format(time.RFC3339),
+		}
+		return result, nil
+
+	default:
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+		return result, nil
+	}
+}
+
+// Exclusions shares the device among everyday commands, so work that needs
+// it alone, such as a firmware update, holds it exclusively
+func (e *Executor) Exclusions(i *intent.Intent) []gateway.Exclusion {
+	if i.IntentType != "device.control" {
+		return nil
+	}
+	name, _ := i.StringParam("device")
+	d, err := e.find(name)
+	if err != nil {
+		return nil
+	}
+	return []gateway.Exclusion{{Group: "device:mqtt:" + d.ID, Shared: true}}
+}
+
+// find looks a device up by ID or name
+func (e *Executor) find(name string) (*Device, error) {
+	if d, ok := e.devices[name]; ok {
+		return d, nil
+	}
+	for _, d := range e.devices {
+		if strings.EqualFold(d.ID, name) || strings.EqualFold(d.Name, name) {
+			return d, nil
+		}
+	}
+	return nil, agenterrors.Newf(agenterrors.NotFound, "no MQTT device named '%s'", name)
+}
+
+// publish sends a message template to the broker, returning the topic. It
+// refuses rather than queues while disconnected, so a command is never
+// delivered late.
+func (e *Executor) publish(d *Device, msg Message, params map[string]interface{}) (string, error) {
+	topic, err := d.expand(msg.Topic, params)
+	if err != nil {
+		return "", err
+	}
+	if strings.ContainsAny(topic, "+#") {
+		return "", agenterrors.Newf(agenterrors.InvalidParams, "cannot publish to wildcard topic %s", topic)
+	}
+	payload, err := d.expand(msg.Payload, params)
+	if err != nil {
+		return "", err
+	}
+	if err := e.send(topic, msg.QoS, msg.Retain, payload); err != nil {
+		return "", err
+	}
+	return topic, nil
+}
+
+// send publishes a payload and waits for the broker to acknowledge it
+func (e *Executor) send(topic string, qos byte, retain bool, payload string) error {
+	if e.blocked != nil {
+		return e.blocked
+	}
+	if !e.client.IsConnectionOpen() {
+		return agenterrors.Newf(agenterrors.Unavailable, "not connected to MQTT broker %s", e.cfg.Broker)
+	}
+	token := e.client.Publish(topic, qos, retain, payload)
+	if !token.WaitTimeout(e.cfg.Timeout) {
+		return agenterrors.Newf(agenterrors.Timeout, "MQTT broker did not acknowledge %s", topic)
+	}
+	if err := token.Error(); err != nil {
+		return agenterrors.Newf(agenterrors.Unavailable, "publishing to %s: %w", topic, err)
+	}
+	return nil
+}
+
+// query returns the device's last reported state. With refresh, or when
+// nothing has been reported yet, it asks the device and waits for a report.
+func (e *Executor) query(ctx context.Context, d *Device, refresh bool) (map[string]interface{}, time.Time, error) {
+	if d.StateTopic == "" {
+		return nil, time.Time{}, agenterrors.Newf(agenterrors.InvalidParams, "%s does not report its state", d.Name)
+	}
+	e.mu.Lock()
+	s := e.states[d.ID]
+	reported, changed := !s.updatedAt.IsZero(), s.changed
+	e.mu.Unlock()
+
+	if refresh || !reported {
+		if d.Query != nil {
+			if _, err := e.publish(d, *d.Query, nil); err != nil {
+				return nil, time.Time{}, err
+			}
+		}
+		timer := time.NewTimer(e.cfg.Timeout)
+		defer timer.Stop()
+		select {
+		case <-changed:
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil, time.Time{}, ctx.Err()
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if s.updatedAt.IsZero() {
+		return nil, time.Time{}, agenterrors.Newf(agenterrors.Unavailable, "%s has not reported its state", d.Name)
+	}
+	values := make(map[string]interface{}, len(s.values))
+	for k, v := range s.values {
+		values[k] = v
+	}
+	return values, s.updatedAt, nil
+}
+
+// list describes every device with its last reported state
+func (e *Executor) list() []map[string]interface{} {
+	ids := make([]string, 0, len(e.devices))
+	for id := range e.devices {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	list := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		d := e.devices[id]
+		entry := map[string]interface{}{
+			"id":      id,
+			"name":    d.Name,
+			"kind":    d.Kind,
+			"actions": d.actionNames(),
+		}
+		if d.Room != "" {
+			entry["room"] = d.Room
+		}
+		if s := e.states[id]; !s.updatedAt.IsZero() {
+			values := make(map[string]interface{}, len(s.values))
+			for k, v := range s.values {
+				values[k] = v
+			}
+			entry["state"] = values
+			entry["updated_at"] = s.updatedAt.Format(time.RFC3339)
+		}
+		list = append(list, entry)
+	}
+	return list
+}
+
+func (e *Executor) IsAvailable() bool {
+	return e.blocked == nil
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"device.list": schema.MustParse(`{"type": "object", "properties": {}}`),
+		"device.control": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"device": {"type": "string", "minLength": 1},
+				"action": {"type": "string", "minLength": 1}
+			},
+			"required": ["device", "action"]
+		}`),
+		"device.query": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"device": {"type": "string", "minLength": 1},
+				"refresh": {"type": "boolean"}
+			},
+			"required": ["device"]
+		}`),
+	}
+}
@@ -0,0 +1,297 @@
+package news
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+)
+
+// maxItemsPerFeed bounds how many entries are kept from one feed
+const maxItemsPerFeed = 50
+
+type cachedFeed struct {
+	items        []Item
+	fetched      time.Time
+	etag         string
+	lastModified string
+}
+
+// fetcher downloads feeds, reusing them for ttl and revalidating with
+// ETag/Last-Modified afterwards
+type fetcher struct {
+	ttl      time.Duration
+	maxBytes int64
+	client   *http.Client
+
+	mu    sync.Mutex
+	cache map[string]*cachedFeed
+}
+
+func newFetcher(ttl time.Duration, maxBytes int64) *fetcher {
+	return &fetcher{
+		ttl:      ttl,
+		maxBytes: maxBytes,
+		client:   connpool.Default.HTTP(15 * time.Second),
+		cache:    make(map[string]*cachedFeed),
+	}
+}
+
+// fetch returns the feed's items and whether they came from the cache
+func (f *fetcher) fetch(ctx context.Context, feed Feed) ([]Item, bool, error) {
+	f.mu.Lock()
+	cached := f.cache[feed.URL]
+	f.mu.Unlock()
+	if cached != nil && time.Since(cached.fetched) < f.ttl {
+		return cached.items, true, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feed.URL, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.5")
+	if cached != nil {
+		if cached.etag != "" {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+		if cached.lastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		if cached != nil {
+			// Stale news beats no news
+			return cached.items, true, nil
+		}
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		f.store(feed.URL, &cachedFeed{items: cached.items, fetched: time.Now(), etag: cached.etag, lastModified: cached.lastModified})
+		return cached.items, true, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("feed returned %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(data)) > f.maxBytes {
+		return nil, false, fmt.Errorf("feed exceeds %d bytes", f.maxBytes)
+	}
+
+	items, err := parseFeed(data, feed.Name)
+	if err != nil {
+		return nil, false, err
+	}
+	f.store(feed.URL, &cachedFeed{
+		items:        items,
+		fetched:      time.Now(),
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	})
+	return items, false, nil
+}
+
+func (f *fetcher) store(url string, c *cachedFeed) {
+	f.mu.Lock()
+	f.cache[url] = c
+	f.mu.Unlock()
+}
+
+// document covers both RSS 2.0 (<rss><channel><item>) and Atom (<feed><entry>)
+type document struct {
+	XMLName xml.Name
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Title    string      `xml:"title"`
+	Entries  []atomEntry `xml:"entry"`
+	RDFItems []rssItem   `xml:"item"` // RSS 1.0 puts items beside the channel
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Description string `xml:"description"`
+}
+
+type atomEntry struct {
+	Title     string `xml:"title"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+	Summary   string `xml:"summary"`
+	Content   string `xml:"content"`
+	Links     []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+}
+
+func parseFeed(data []byte, source string) ([]Item, error) {
+	var doc document
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		// Feeds declaring Latin-1 and friends are almost always ASCII in practice
+		return input, nil
+	}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid feed: %w", err)
+	}
+
+	var items []Item
+	switch doc.XMLName.Local {
+	case "rss", "RDF":
+		if source == "" {
+			source = clean(doc.Channel.Title)
+		}
+		for _, it := range append(doc.Channel.Items, doc.RDFItems...) {
+			link := strings.TrimSpace(it.Link)
+			if link == "" {
+				link = strings.TrimSpace(it.GUID)
+			}
+			items = append(items, Item{
+				Title:     clean(it.Title),
+				Source:    source,
+				Link:      link,
+				Published: parseDate(it.PubDate, it.Date),
+				Summary:   clean(it.Description),
+			})
+		}
+	case "feed":
+		if source == "" {
+			source = clean(doc.Title)
+		}
+		for _, en := range doc.Entries {
+			summary := en.Summary
+			if summary == "" {
+				summary = en.Content
+			}
+			items = append(items, Item{
+				Title:     clean(en.Title),
+				Source:    source,
+				Link:      atomLink(en),
+				Published: parseDate(en.Published, en.Updated),
+				Summary:   clean(summary),
+			})
+		}
+	default:
+		return nil, errors.New("not an RSS or Atom feed")
+	}
+
+	if len(items) > maxItemsPerFeed {
+		items = items[:maxItemsPerFeed]
+	}
+	return items, nil
+}
+
+func atomLink(en atomEntry) string {
+	for _, l := range en.Links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			return l.Href
+		}
+	}
+	if len(en.Links) > 0 {
+		return en.Links[0].Href
+	}
+	return ""
+}
+
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02",
+}
+
+func parseDate(values ...string) time.Time {
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+var (
+	tagPattern   = regexp.MustCompile(`<[^>]*>`)
+	spacePattern = regexp.MustCompile(`\s+`)
+)
+
+// clean strips markup and collapses whitespace so text is ready to narrate
+func clean(s string) string {
+	s = tagPattern.ReplaceAllString(s, " ")
+	s = html.UnescapeString(s)
+	return strings.TrimSpace(spacePattern.ReplaceAllString(s, " "))
+}
+
+// dedupe drops stories seen in more than one feed, matching on the link
+// without tracking parameters or, failing that, on the title
+func dedupe(items []Item) []Item {
+	seen := make(map[string]bool, len(items))
+	kept := items[:0]
+	for _, item := range items {
+		keys := []string{"t:" + strings.ToLower(item.Title)}
+		if link := canonicalLink(item.Link); link != "" {
+			keys = append(keys, "l:"+link)
+		}
+		dup := false
+		for _, k := range keys {
+			if seen[k] {
+				dup = true
+			}
+			seen[k] = true
+		}
+		if !dup && item.Title != "" {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+func canonicalLink(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	q := u.Query()
+	for key := range q {
+		if strings.HasPrefix(key, "utm_") || key == "ref" || key == "fbclid" {
+			q.Del(key)
+		}
+	}
+	u.RawQuery = q.Encode()
+	u.Fragment = ""
+	u.Scheme = "https"
+	u.Host = strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	return strings.TrimSuffix(u.String(), "/")
+}
@@ -0,0 +1,259 @@
+// Package news fetches configured RSS and Atom feeds and answers
+// news.briefing with a bounded, deduplicated summary for the agent core's
+// language model to narrate
+package news
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Feed is a configured news source
+type Feed struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// Config controls fetching and the size of a briefing
+type Config struct {
+	Feeds           []Feed
+	CacheTTL        time.Duration // how long a fetched feed is reused (default 15m)
+	MaxFeedBytes    int64         // download cap per feed (default 2MB)
+	MaxItems        int           // items per briefing (default 10, hard cap 25)
+	MaxSummaryChars int           // characters per item summary (default 280)
+}
+
+// Item is one story in a briefing
+type Item struct {
+	Title     string    `json:"title"`
+	Source    string    `json:"source"`
+	Link      string    `json:"link,omitempty"`
+	Published time.Time `json:"published,omitzero"`
+	Summary   string    `json:"summary,omitempty"`
+}
+
+// maxItemsCap bounds briefings regardless of configuration
+const maxItemsCap = 25
+
+// Executor handles news.briefing
+type Executor struct {
+	cfg     Config
+	fetcher *fetcher
+}
+
+// NewExecutor creates a news executor for the configured feeds
+func NewExecutor(cfg Config) *Executor {
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = 15 * time.Minute
+	}
+	if cfg.MaxFeedBytes <= 0 {
+		cfg.MaxFeedBytes = 2 << 20
+	}
+	if cfg.MaxItems <= 0 {
+		cfg.MaxItems = 10
+	}
+	if cfg.MaxItems > maxItemsCap {
+		cfg.MaxItems = maxItemsCap
+	}
+	if cfg.MaxSummaryChars <= 0 {
+		cfg.MaxSummaryChars = 280
+	}
+	return &Executor{
+		cfg:     cfg,
+		fetcher: newFetcher(cfg.CacheTTL, cfg.MaxFeedBytes),
+	}
+}
+
+func (e *Executor) Name() string {
+	return "news"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"news.briefing"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{Network: true}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "news",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	if i.IntentType != "news.briefing" {
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+		return result, nil
+	}
+
+	var params struct {
+		Limit int           `param:"limit"`
+		Feed  string        `param:"feed"`
+		Topic string        `param:"topic"`
+		Since time.Duration `param:"since"`
+	}
+	if err := i.DecodeParams(&params); err != nil {
+		result.Fail(err)
+		return result, nil
+	}
+	limit := params.Limit
+	if limit <= 0 || limit > e.cfg.MaxItems {
+		limit = e.cfg.MaxItems
+	}
+
+	feeds := e.selectFeeds(params.Feed)
+	if len(feeds) == 0 {
+		result.Fail(agenterrors.Newf(agenterrors.NotFound, "no feed named '%s'", params.Feed))
+		return result, nil
+	}
+
+	items, statuses := e.collect(ctx, feeds)
+	items = dedupe(items)
+	items = filter(items, params.Topic, params.Since)
+	sort.SliceStable(items, func(a, b int) bool { return items[a].Published.After(items[b].Published) })
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	for idx := range items {
+		items[idx].Summary = truncate(items[idx].Summary, e.cfg.MaxSummaryChars)
+	}
+
+	failed := 0
+	for _, s := range statuses {
+		if !s.OK {
+			failed++
+		}
+	}
+	if failed == len(statuses) {
+		result.Fail(agenterrors.New(agenterrors.Unavailable, "no feed could be fetched"))
+		result.Result = map[string]interface{}{"feeds": statuses}
+		return result, nil
+	}
+
+	result.Success = true
+	result.Result = map[string]interface{}{
+		"items": items,
+		"count": len(items),
+		"feeds": statuses,
+	}
+	return result, nil
+}
+
+func (e *Executor) IsAvailable() bool {
+	for _, f := range e.cfg.Feeds {
+		if connpool.Default.Reachable(f.URL) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"news.briefing": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"limit": {"type": "integer", "minimum": 1, "maximum": 25},
+				"feed": {"type": "string"},
+				"topic": {"type": "string"},
+				"since": {"type": "string"}
+			}
+		}`),
+	}
+}
+
+// FeedStatus reports how fetching one feed went
+type FeedStatus struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Cached bool   `json:"cached,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (e *Executor) selectFeeds(name string) []Feed {
+	if name == "" {
+		return e.cfg.Feeds
+	}
+	var feeds []Feed
+	for _, f := range e.cfg.Feeds {
+		if strings.EqualFold(f.Name, name) {
+			feeds = append(feeds, f)
+		}
+	}
+	return feeds
+}
+
+// collect fetches the feeds concurrently
+func (e *Executor) collect(ctx context.Context, feeds []Feed) ([]Item, []FeedStatus) {
+	var wg sync.WaitGroup
+	results := make([][]Item, len(feeds))
+	statuses := make([]FeedStatus, len(feeds))
+	for idx, f := range feeds {
+		wg.Add(1)
+		go func(idx int, f Feed) {
+			defer wg.Done()
+			items, cached, err := e.fetcher.fetch(ctx, f)
+			statuses[idx] = FeedStatus{Name: f.Name, OK: err == nil, Cached: cached}
+			if f.Name == "" {
+				statuses[idx].Name = f.URL
+			}
+			if err != nil {
+				statuses[idx].Error = err.Error()
+				return
+			}
+			results[idx] = items
+		}(idx, f)
+	}
+	wg.Wait()
+
+	var all []Item
+	for _, items := range results {
+		all = append(all, items...)
+	}
+	return all, statuses
+}
+
+func filter(items []Item, topic string, since time.Duration) []Item {
+	topic = strings.ToLower(strings.TrimSpace(topic))
+	cutoff := time.Time{}
+	if since > 0 {
+		cutoff = time.Now().Add(-since)
+	}
+	kept := items[:0]
+	for _, item := range items {
+		if topic != "" && !strings.Contains(strings.ToLower(item.Title+" "+item.Summary), topic) {
+			continue
+		}
+		if !cutoff.IsZero() && !item.Published.IsZero() && item.Published.Before(cutoff) {
+			continue
+		}
+		kept = append(kept, item)
+	}
+	return kept
+}
+
+// truncate shortens s to at most n runes, cutting at a word boundary
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	cut := string(r[:n])
+	if idx := strings.LastIndexByte(cut, ' '); idx > n/2 {
+		cut = cut[:idx]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
@@ -0,0 +1,291 @@
+// Package notes captures and searches free-text notes, kept in a local file
+// or as Markdown files in a directory such as an Obsidian vault
+package notes
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Limits on notes and searches
+const (
+	maxTitle       = 80
+	snippetChars   = 160
+	defaultResults = 10
+	maxResults     = 50
+)
+
+// Note is one captured note
+type Note struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Text      string    `json:"text"`
+	Tags      []string  `json:"tags,omitempty"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Store keeps notes
+type Store interface {
+	Create(n Note) (Note, error)
+	All() ([]Note, error)
+}
+
+// Executor handles note.create and note.search
+type Executor struct {
+	store Store
+}
+
+// NewExecutor creates a notes executor over store, a FileStore or a
+// MarkdownDir
+func NewExecutor(store Store) *Executor {
+	return &Executor{store: store}
+}
+
+func (e *Executor) Name() string {
+	return "note"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"note.create", "note.search"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	switch s := e.store.(type) {
+	case *FileStore:
+		return sandbox.Needs{Write: []string{filepath.Dir(s.path)}}
+	case *MarkdownDir:
+		return sandbox.Needs{Write: []string{s.dir}}
+	}
+	return sandbox.Needs{}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "note",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	switch i.IntentType {
+	case "note.create":
+		var params struct {
+			Text  string   `param:"text,required"`
+			Title string   `param:"title"`
+			Tags  []string `param:"tags"`
+			User  string   `param:"user"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		text := strings.TrimSpace(params.Text)
+		if text == "" {
+			return fail(agenterrors.New(agenterrors.InvalidParams, "text is empty"))
+		}
+		title := strings.TrimSpace(params.Title)
+		if title == "" {
+			title = titleOf(text)
+		}
+		n, err := e.store.Create(Note{
+			ID:        events.NewID(),
+			Title:     title,
+			Text:      text,
+			Tags:      normalizeTags(params.Tags),
+			CreatedBy: params.User,
+			CreatedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"note": n,
+		}
+
+	case "note.search":
+		var params struct {
+			Query string `param:"query"`
+			Tag   string `param:"tag"`
+			Limit int    `param:"limit"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		limit := params.Limit
+		if limit <= 0 {
+			limit = defaultResults
+		}
+		limit = min(limit, maxResults)
+		all, err := e.store.All()
+		if err != nil {
+			return fail(err)
+		}
+		matches := search(all, params.Query, strings.ToLower(strings.TrimPrefix(params.Tag, "#")))
+		total := len(matches)
+		if len(matches) > limit {
+			matches = matches[:limit]
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"notes": matches,
+			"count": len(matches),
+			"total": total,
+		}
+
+	default:
+		return fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+	return result, nil
+}
+
+// match is a search result: the note without its full text
+type match struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Snippet   string    `json:"snippet"`
+	Tags      []string  `json:"tags,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	score     int
+}
+
+// search finds the notes containing every word of query (all notes for an
+// empty one) and carrying tag, if set: notes matching in the title first,
+// then newest first
+func search(notes []Note, query, tag string) []match {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	matches := []match{}
+	for _, n := range notes {
+		if tag != "" && !contains(n.Tags, tag) {
+			continue
+		}
+		title, text := strings.ToLower(n.Title), strings.ToLower(n.Text)
+		score, first := 0, -1
+		for _, w := range words {
+			inTitle := strings.Contains(title, w)
+			at := strings.Index(text, w)
+			if !inTitle && at < 0 && !contains(n.Tags, w) {
+				score = -1
+				break
+			}
+			if inTitle {
+				score += 2
+			}
+			if at >= 0 && (first < 0 || at < first) {
+				first = at
+			}
+			score++
+		}
+		if score < 0 {
+			continue
+		}
+		matches = append(matches, match{
+			ID:        n.ID,
+			Title:     n.Title,
+			Snippet:   snippet(n.Text, max(first, 0)),
+			Tags:      n.Tags,
+			CreatedAt: n.CreatedAt,
+			score:     score,
+		})
+	}
+	sort.SliceStable(matches, func(a, b int) bool {
+		if matches[a].score != matches[b].score {
+			return matches[a].score > matches[b].score
+		}
+		return matches[a].CreatedAt.After(matches[b].CreatedAt)
+	})
+	return matches
+}
+
+// snippet cuts about snippetChars of text around byte offset at, on rune
+// boundaries
+func snippet(text string, at int) string {
+	runes := []rune(text)
+	start := len([]rune(text[:at])) - snippetChars/4
+	start = max(start, 0)
+	end := min(start+snippetChars, len(runes))
+	s := strings.Join(strings.Fields(string(runes[start:end])), " ")
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(runes) {
+		s += "…"
+	}
+	return s
+}
+
+// titleOf makes a title from a note's first line
+func titleOf(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	line = strings.TrimSpace(strings.TrimLeft(line, "# "))
+	if runes := []rune(line); len(runes) > maxTitle {
+		line = strings.TrimSpace(string(runes[:maxTitle])) + "…"
+	}
+	return line
+}
+
+// normalizeTags lowercases tags and drops #s, blanks, and duplicates
+func normalizeTags(tags []string) []string {
+	var out []string
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(t), "#")))
+		if t != "" && !contains(out, t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Executor) IsAvailable() bool {
+	return e.store != nil
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"note.create": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"text": {"type": "string", "minLength": 1, "maxLength": 100000},
+				"title": {"type": "string", "maxLength": 200},
+				"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 20},
+				"user": {"type": "string"}
+			},
+			"required": ["text"]
+		}`),
+		"note.search": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"query": {"type": "string"},
+				"tag": {"type": "string"},
+				"limit": {"type": "integer", "minimum": 1, "maximum": 50}
+			}
+		}`),
+	}
+}
@@ -0,0 +1,246 @@
+package notes
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// FileStore keeps notes in one JSON file
+type FileStore struct {
+	path string
+
+	mu    sync.Mutex
+	notes []Note
+}
+
+// OpenFile loads the notes stored at path, starting empty if the file
+// doesn't exist yet
+func OpenFile(path string) (*FileStore, error) {
+	s := &FileStore{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.notes); err != nil {
+		return nil, fmt.Errorf("invalid notes file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Create implements Store
+func (s *FileStore) Create(n Note) (Note, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notes = append(s.notes, n)
+	data, err := json.MarshalIndent(s.notes, "", "  ")
+	if err != nil {
+		return Note{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return Note{}, err
+	}
+	// Written atomically so a crash never leaves a torn file
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return Note{}, err
+	}
+	return n, os.Rename(tmp, s.path)
+}
+
+// All implements Store
+func (s *FileStore) All() ([]Note, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Note(nil), s.notes...), nil
+}
+
+// Limits on what a Markdown directory scan reads
+const (
+	maxNoteFiles = 5000
+	maxNoteSize  = 256 << 10
+)
+
+// MarkdownDir keeps each note as a Markdown file with YAML front matter,
+// so notes show up in Obsidian or any editor and syncs with whatever syncs
+// the directory. Notes written there by other tools are searched too.
+type MarkdownDir struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// frontMatter is what a note file records besides its text
+type frontMatter struct {
+	ID        string    `yaml:"id,omitempty"`
+	Title     string    `yaml:"title,omitempty"`
+	Tags      []string  `yaml:"tags,omitempty"`
+	CreatedBy string    `yaml:"created_by,omitempty"`
+	Created   time.Time `yaml:"created,omitempty"`
+}
+
+// OpenMarkdownDir uses dir for notes, creating it if needed
+func OpenMarkdownDir(dir string) (*MarkdownDir, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &MarkdownDir{dir: dir}, nil
+}
+
+// Create implements Store, naming the file after the title
+func (m *MarkdownDir) Create(n Note) (Note, error) {
+	fm, err := yaml.Marshal(frontMatter{
+		ID:        n.ID,
+		Title:     n.Title,
+		Tags:      n.Tags,
+		CreatedBy: n.CreatedBy,
+		Created:   n.CreatedAt,
+	})
+	if err != nil {
+		return Note{}, err
+	}
+	content := "---\n" + string(fm) + "---\n\n" + n.Text + "\n"
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	base := slug(n.Title)
+	for suffix := 1; ; suffix++ {
+		name := base + ".md"
+		if suffix > 1 {
+			name = fmt.Sprintf("%s-%d.md", base, suffix)
+		}
+		f, err := os.OpenFile(filepath.Join(m.dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return Note{}, err
+		}
+		if _, err := f.WriteString(content); err != nil {
+			f.Close()
+			return Note{}, err
+		}
+		return n, f.Close()
+	}
+}
+
+// All implements Store, reading every Markdown file under the directory
+// except hidden ones such as .obsidian and .git
+func (m *MarkdownDir) All() ([]Note, error) {
+	var notes []Note
+	err := filepath.WalkDir(m.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != m.dir {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".md") {
+			return nil
+		}
+		if len(notes) >= maxNoteFiles {
+			return filepath.SkipAll
+		}
+		n, err := m.read(path, d)
+		if err != nil {
+			return nil
+		}
+		notes = append(notes, n)
+		return nil
+	})
+	return notes, err
+}
+
+// read parses a note file. Files without front matter are titled by their
+// first heading, or their name, and dated by their modification time.
+func (m *MarkdownDir) read(path string, d fs.DirEntry) (Note, error) {
+	info, err := d.Info()
+	if err != nil {
+		return Note{}, err
+	}
+	if info.Size() > maxNoteSize {
+		return Note{}, fmt.Errorf("%s is too big", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Note{}, err
+	}
+	rel, _ := filepath.Rel(m.dir, path)
+	text := string(data)
+	var fm frontMatter
+	if rest, ok := strings.CutPrefix(text, "---\n"); ok {
+		if header, body, ok := strings.Cut(rest, "\n---\n"); ok && yaml.Unmarshal([]byte(header), &fm) == nil {
+			text = body
+		}
+	}
+	n := Note{
+		ID:        fm.ID,
+		Title:     fm.Title,
+		Text:      strings.TrimSpace(text),
+		Tags:      normalizeTags(fm.Tags),
+		CreatedBy: fm.CreatedBy,
+		CreatedAt: fm.Created,
+	}
+	if n.ID == "" {
+		n.ID = "md:" + filepath.ToSlash(rel)
+	}
+	if n.Title == "" {
+		n.Title = heading(n.Text)
+	}
+	if n.Title == "" {
+		n.Title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = info.ModTime().UTC()
+	}
+	return n, nil
+}
+
+// heading returns a Markdown text's first heading
+func heading(text string) string {
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); strings.HasPrefix(line, "#") {
+			return strings.TrimSpace(strings.TrimLeft(line, "#"))
+		}
+	}
+	return ""
+}
+
+// slug makes a file name from a title
+func slug(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+			dash = false
+		case !dash && b.Len() > 0:
+			b.WriteByte('-')
+			dash = true
+		}
+		if b.Len() >= 50 {
+			break
+		}
+	}
+	s := strings.Trim(b.String(), "-")
+	if s == "" {
+		s = "note-" + time.Now().UTC().Format("20060102-150405")
+	}
+	return s
+}
@@ -0,0 +1,62 @@
+package executor
+
+import "context"
+
+// Notification is what notification.send shows
+type Notification struct {
+	ID      string               `json:"id"`
+	Title   string               `json:"title,omitempty"`
+	Message string               `json:"message"`
+	Urgency string               `json:"urgency"` // low, normal, or critical
+	Icon    string               `json:"icon,omitempty"`
+	Actions []NotificationAction `json:"actions,omitempty"`
+
+	// Timeout in seconds before the notification expires; 0 leaves it to
+	// the desktop
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// NotificationAction is a button on a notification. Clicking it publishes
+// a notification.action event carrying the ID.
+type NotificationAction struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+// Limits shared by every backend
+const (
+	// maxNotificationActions is the most buttons macOS alerts can show
+	maxNotificationActions = 3
+
+	// defaultActionWait is how long backends that can't see a notification
+	// being dismissed wait for a click
+	defaultActionWait = 300
+)
+
+// notifier shows notifications. send calls done exactly once, when the
+// notification is gone, with the ID of the clicked action or "" if it was
+// dismissed, expired, or can't be tracked. It reports whether the actions
+// were shown.
+type notifier interface {
+	name() string
+	send(ctx context.Context, n *Notification, done func(action string)) (bool, error)
+	close(id string) error
+}
+
+// waitSeconds is how long to wait for a click on n
+func waitSeconds(n *Notification) int {
+	if n.Timeout > 0 {
+		return n.Timeout
+	}
+	return defaultActionWait
+}
+
+// actionFor maps a clicked button's label back to its action ID
+func actionFor(n *Notification, label string) string {
+	for _, a := range n.Actions {
+		if a.Label == label {
+			return a.ID
+		}
+	}
+	return ""
+}
@@ -0,0 +1,102 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Notification Center shows plain notifications; ones with actions become
+// alerts, which are the only way osascript can offer buttons
+const (
+	notifyScript = `on run argv
+	display notification (item 2 of argv) with title (item 1 of argv)
+end run`
+
+	alertScript = `on run argv
+	set wait to (item 4 of argv) as integer
+	if item 3 of argv is "critical" then
+		set answer to display alert (item 1 of argv) message (item 2 of argv) as critical buttons (items 5 thru -1 of argv) giving up after wait
+	else
+		set answer to display alert (item 1 of argv) message (item 2 of argv) buttons (items 5 thru -1 of argv) giving up after wait
+	end if
+	if gave up of answer then return ""
+	return button returned of answer
+end run`
+)
+
+// osascriptNotifier shows notifications through osascript. Values are
+// passed as arguments, never spliced into the script.
+type osascriptNotifier struct {
+	mu     sync.Mutex
+	alerts map[string]context.CancelFunc
+}
+
+func newDesktopNotifier() (notifier, error) {
+	if _, err := exec.LookPath("osascript"); err != nil {
+		return nil, err
+	}
+	return &osascriptNotifier{alerts: make(map[string]context.CancelFunc)}, nil
+}
+
+func (o *osascriptNotifier) name() string {
+	return "osascript"
+}
+
+func (o *osascriptNotifier) send(ctx context.Context, n *Notification, done func(string)) (bool, error) {
+	title := n.Title
+	if title == "" {
+		title = "Device Agent"
+	}
+	if len(n.Actions) == 0 {
+		out, err := exec.CommandContext(ctx, "osascript", "-e", notifyScript, title, n.Message).CombinedOutput()
+		if err != nil {
+			return false, fmt.Errorf("osascript: %v: %s", err, strings.TrimSpace(string(out)))
+		}
+		done("")
+		return false, nil
+	}
+
+	args := []string{"-e", alertScript, title, n.Message, n.Urgency, fmt.Sprint(waitSeconds(n))}
+	for _, a := range n.Actions {
+		args = append(args, a.Label)
+	}
+	alertCtx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(alertCtx, "osascript", args...)
+	stdout := &strings.Builder{}
+	cmd.Stdout = stdout
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return false, err
+	}
+	o.mu.Lock()
+	o.alerts[n.ID] = cancel
+	o.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		var action string
+		if cmd.Wait() == nil {
+			action = actionFor(n, strings.TrimSpace(stdout.String()))
+		}
+		o.mu.Lock()
+		delete(o.alerts, n.ID)
+		o.mu.Unlock()
+		done(action)
+	}()
+	return true, nil
+}
+
+// close dismisses an alert; Notification Center notifications can't be
+// withdrawn from osascript
+func (o *osascriptNotifier) close(id string) error {
+	o.mu.Lock()
+	cancel, ok := o.alerts[id]
+	o.mu.Unlock()
+	if ok {
+		cancel()
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	dbusNotifications     = "org.freedesktop.Notifications"
+	dbusNotificationsPath = dbus.ObjectPath("/org/freedesktop/Notifications")
+)
+
+// dbusNotifier talks to the desktop's notification server over the session
+// bus (org.freedesktop.Notifications)
+type dbusNotifier struct {
+	conn    *dbus.Conn
+	obj     dbus.BusObject
+	actions bool // the server shows action buttons
+
+	mu       sync.Mutex
+	shown    map[uint32]*dbusNotification // by server ID
+	serverID map[string]uint32
+}
+
+type dbusNotification struct {
+	id   string
+	done func(string)
+}
+
+func newDesktopNotifier() (notifier, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("no D-Bus session bus: %w", err)
+	}
+	obj := conn.Object(dbusNotifications, dbusNotificationsPath)
+	var caps []string
+	if err := obj.Call(dbusNotifications+".GetCapabilities", 0).Store(&caps); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("no notification server: %w", err)
+	}
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(dbusNotificationsPath),
+		dbus.WithMatchInterface(dbusNotifications),
+	); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	d := &dbusNotifier{
+		conn:     conn,
+		obj:      obj,
+		actions:  slices.Contains(caps, "actions"),
+		shown:    make(map[uint32]*dbusNotification),
+		serverID: make(map[string]uint32),
+	}
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+	go d.listen(signals)
+	return d, nil
+}
+
+func (d *dbusNotifier) name() string {
+	return "dbus"
+}
+
+func (d *dbusNotifier) send(ctx context.Context, n *Notification, done func(string)) (bool, error) {
+	var actions []string
+	if d.actions {
+		for _, a := range n.Actions {
+			actions = append(actions, a.ID, a.Label)
+		}
+	}
+	urgency := map[string]byte{"low": 0, "normal": 1, "critical": 2}[n.Urgency]
+	hints := map[string]dbus.Variant{"urgency": dbus.MakeVariant(urgency)}
+	expire := int32(-1)
+	if n.Timeout > 0 {
+		expire = int32(n.Timeout * 1000)
+	}
+	title := n.Title
+	if title == "" {
+		title = "Device Agent"
+	}
+
+	var id uint32
+	call := d.obj.CallWithContext(ctx, dbusNotifications+".Notify", 0,
+		"device-agent", uint32(0), n.Icon, title, n.Message, actions, hints, expire)
+	if err := call.Store(&id); err != nil {
+		return false, err
+	}
+	d.mu.Lock()
+	d.shown[id] = &dbusNotification{id: n.ID, done: done}
+	d.serverID[n.ID] = id
+	d.mu.Unlock()
+	return len(actions) > 0, nil
+}
+
+func (d *dbusNotifier) close(id string) error {
+	d.mu.Lock()
+	serverID, ok := d.serverID[id]
+	d.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return d.obj.Call(dbusNotifications+".CloseNotification", 0, serverID).Err
+}
+
+// listen turns ActionInvoked and NotificationClosed signals into calls to
+// the done functions
+func (d *dbusNotifier) listen(signals <-chan *dbus.Signal) {
+	for sig := range signals {
+		if len(sig.Body) < 2 {
+			continue
+		}
+		serverID, ok := sig.Body[0].(uint32)
+		if !ok {
+			continue
+		}
+		var action string
+		switch sig.Name {
+		case dbusNotifications + ".ActionInvoked":
+			action, _ = sig.Body[1].(string)
+		case dbusNotifications + ".NotificationClosed":
+		default:
+			continue
+		}
+
+		d.mu.Lock()
+		shown, ok := d.shown[serverID]
+		if ok {
+			delete(d.shown, serverID)
+			delete(d.serverID, shown.id)
+		}
+		d.mu.Unlock()
+		if ok {
+			shown.done(action)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !windows
+
+package executor
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func newDesktopNotifier() (notifier, error) {
+	return nil, fmt.Errorf("desktop notifications are not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,160 @@
+package executor
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// toastApp is PowerShell's AppUserModelID; toasts must be raised on behalf
+// of an installed app and this one is always present
+const toastApp = `{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe`
+
+// toastScript shows the toast in $env:AGENT_TOAST_XML and, when asked,
+// waits for it to be clicked, printing the clicked button's arguments.
+// Values come through the environment, never spliced into the script.
+const toastScript = `
+$ErrorActionPreference = 'Stop'
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+[Windows.Data.Xml.Dom.XmlDocument, Windows.Data.Xml.Dom.XmlDocument, ContentType = WindowsRuntime] | Out-Null
+$xml = New-Object Windows.Data.Xml.Dom.XmlDocument
+$xml.LoadXml($env:AGENT_TOAST_XML)
+$toast = [Windows.UI.Notifications.ToastNotification]::new($xml)
+$toast.Tag = $env:AGENT_TOAST_TAG
+$toast.Group = 'device-agent'
+Register-ObjectEvent -InputObject $toast -EventName Activated -SourceIdentifier activated | Out-Null
+Register-ObjectEvent -InputObject $toast -EventName Dismissed -SourceIdentifier dismissed | Out-Null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($env:AGENT_TOAST_APP).Show($toast)
+if ($env:AGENT_TOAST_WAIT -eq '0') { exit 0 }
+$e = Wait-Event -Timeout ([int]$env:AGENT_TOAST_WAIT)
+if ($e -and $e.SourceIdentifier -eq 'activated') { [Console]::Out.Write($e.SourceArgs[1].Arguments) }
+`
+
+const toastRemoveScript = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+[Windows.UI.Notifications.ToastNotificationManager]::History.Remove($env:AGENT_TOAST_TAG, 'device-agent', $env:AGENT_TOAST_APP)
+`
+
+// toastNotifier raises Windows toast notifications through PowerShell
+type toastNotifier struct {
+	mu      sync.Mutex
+	waiting map[string]context.CancelFunc
+}
+
+func newDesktopNotifier() (notifier, error) {
+	if _, err := exec.LookPath("powershell.exe"); err != nil {
+		return nil, err
+	}
+	return &toastNotifier{waiting: make(map[string]context.CancelFunc)}, nil
+}
+
+func (t *toastNotifier) name() string {
+	return "toast"
+}
+
+func (t *toastNotifier) send(ctx context.Context, n *Notification, done func(string)) (bool, error) {
+	wait := 0
+	if len(n.Actions) > 0 {
+		wait = waitSeconds(n)
+	}
+	env := []string{
+		"AGENT_TOAST_XML=" + toastXML(n),
+		"AGENT_TOAST_TAG=" + n.ID,
+		"AGENT_TOAST_APP=" + toastApp,
+		fmt.Sprintf("AGENT_TOAST_WAIT=%d", wait),
+	}
+	if wait == 0 {
+		if out, err := powershell(ctx, toastScript, env).CombinedOutput(); err != nil {
+			return false, fmt.Errorf("powershell: %v: %s", err, strings.TrimSpace(string(out)))
+		}
+		done("")
+		return false, nil
+	}
+
+	waitCtx, cancel := context.WithCancel(context.Background())
+	cmd := powershell(waitCtx, toastScript, env)
+	stdout := &strings.Builder{}
+	cmd.Stdout = stdout
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return false, err
+	}
+	t.mu.Lock()
+	t.waiting[n.ID] = cancel
+	t.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		var action string
+		if cmd.Wait() == nil {
+			action = strings.TrimSpace(stdout.String())
+		}
+		t.mu.Lock()
+		delete(t.waiting, n.ID)
+		t.mu.Unlock()
+		done(action)
+	}()
+	return true, nil
+}
+
+func (t *toastNotifier) close(id string) error {
+	t.mu.Lock()
+	cancel, ok := t.waiting[id]
+	t.mu.Unlock()
+	if ok {
+		cancel()
+	}
+	env := []string{"AGENT_TOAST_TAG=" + id, "AGENT_TOAST_APP=" + toastApp}
+	if out, err := powershell(context.Background(), toastRemoveScript, env).CombinedOutput(); err != nil {
+		return fmt.Errorf("powershell: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func powershell(ctx context.Context, script string, env []string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "powershell.exe", "-NoProfile", "-NonInteractive", "-Command", script)
+	cmd.Env = append(os.Environ(), env...)
+	return cmd
+}
+
+// toastXML builds the toast's content. Critical toasts use the reminder
+// scenario, which stays on screen until dismissed; low ones are silent.
+func toastXML(n *Notification) string {
+	var b strings.Builder
+	if n.Urgency == "critical" {
+		b.WriteString(`<toast scenario="reminder">`)
+	} else {
+		b.WriteString(`<toast>`)
+	}
+	b.WriteString(`<visual><binding template="ToastGeneric">`)
+	if n.Title != "" {
+		fmt.Fprintf(&b, `<text>%s</text>`, xmlEscape(n.Title))
+	}
+	fmt.Fprintf(&b, `<text>%s</text>`, xmlEscape(n.Message))
+	if n.Icon != "" {
+		fmt.Fprintf(&b, `<image placement="appLogoOverride" src="%s"/>`, xmlEscape(n.Icon))
+	}
+	b.WriteString(`</binding></visual>`)
+	if len(n.Actions) > 0 {
+		b.WriteString(`<actions>`)
+		for _, a := range n.Actions {
+			fmt.Fprintf(&b, `<action content="%s" arguments="%s" activationType="foreground"/>`, xmlEscape(a.Label), xmlEscape(a.ID))
+		}
+		b.WriteString(`</actions>`)
+	}
+	if n.Urgency == "low" {
+		b.WriteString(`<audio silent="true"/>`)
+	}
+	b.WriteString(`</toast>`)
+	return b.String()
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
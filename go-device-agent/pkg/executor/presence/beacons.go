@@ -0,0 +1,169 @@
+package presence
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	bluezService = "org.bluez"
+	bluezAdapter = "org.bluez.Adapter1"
+	bluezDevice  = "org.bluez.Device1"
+
+	// appleID and iBeaconType prefix an iBeacon's manufacturer data
+	appleID     = 0x004C
+	iBeaconType = 0x0215
+)
+
+// scanner listens for Bluetooth LE advertisements through BlueZ, keeping
+// a discovery session open; BlueZ shares it with other clients, such as
+// the bluetooth executor's scans
+type scanner struct {
+	conn    *dbus.Conn
+	adapter dbus.ObjectPath
+}
+
+// newScanner connects to BlueZ on the named adapter (e.g. hci0), or the
+// first one
+func newScanner(adapter string) (*scanner, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, fmt.Errorf("no D-Bus system bus: %w", err)
+	}
+	var objects map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+	err = conn.Object(bluezService, "/").Call("org.freedesktop.DBus.ObjectManager.GetManagedObjects", 0).Store(&objects)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("BlueZ isn't running: %w", err)
+	}
+	var adapters []string
+	for p, ifaces := range objects {
+		if _, ok := ifaces[bluezAdapter]; ok {
+			adapters = append(adapters, string(p))
+		}
+	}
+	sort.Strings(adapters)
+	if len(adapters) == 0 {
+		conn.Close()
+		return nil, errors.New("no Bluetooth adapter found")
+	}
+	s := &scanner{conn: conn, adapter: dbus.ObjectPath(adapters[0])}
+	if adapter != "" {
+		s.adapter = dbus.ObjectPath("/org/bluez/" + adapter)
+		if _, ok := objects[s.adapter][bluezAdapter]; !ok {
+			conn.Close()
+			return nil, fmt.Errorf("no Bluetooth adapter %s", adapter)
+		}
+	}
+	return s, nil
+}
+
+// scan reports the address of every advertisement heard, and the UUID of
+// every iBeacon, until ctx is done
+func (s *scanner) scan(ctx context.Context, seen func(ids ...string)) error {
+	defer s.conn.Close()
+	err := s.conn.AddMatchSignal(
+		dbus.WithMatchInterface("org.freedesktop.DBus.Properties"),
+		dbus.WithMatchMember("PropertiesChanged"),
+		dbus.WithMatchPathNamespace(s.adapter),
+	)
+	if err != nil {
+		return err
+	}
+	err = s.conn.AddMatchSignal(
+		dbus.WithMatchInterface("org.freedesktop.DBus.ObjectManager"),
+		dbus.WithMatchMember("InterfacesAdded"),
+	)
+	if err != nil {
+		return err
+	}
+	signals := make(chan *dbus.Signal, 64)
+	s.conn.Signal(signals)
+
+	adapter := s.conn.Object(bluezService, s.adapter)
+	filter := map[string]dbus.Variant{
+		"Transport": dbus.MakeVariant("le"),
+		// Repeat advertisements from known devices, or a beacon would be
+		// heard only once
+		"DuplicateData": dbus.MakeVariant(true),
+	}
+	if err := adapter.CallWithContext(ctx, bluezAdapter+".SetDiscoveryFilter", 0, filter).Err; err != nil {
+		return err
+	}
+	if err := adapter.CallWithContext(ctx, bluezAdapter+".StartDiscovery", 0).Err; err != nil {
+		return err
+	}
+	defer adapter.Call(bluezAdapter+".StopDiscovery", 0)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case sig, ok := <-signals:
+			if !ok {
+				return errors.New("D-Bus connection closed")
+			}
+			if ids := advertised(sig); len(ids) > 0 {
+				seen(ids...)
+			}
+		}
+	}
+}
+
+// advertised reads what a device signal says was heard: its address when
+// it carries a signal strength or advertising data, plus any iBeacon UUID
+func advertised(sig *dbus.Signal) []string {
+	var props map[string]dbus.Variant
+	devicePath := sig.Path
+	switch sig.Name {
+	case "org.freedesktop.DBus.Properties.PropertiesChanged":
+		if len(sig.Body) < 2 {
+			return nil
+		}
+		if iface, _ := sig.Body[0].(string); iface != bluezDevice {
+			return nil
+		}
+		props, _ = sig.Body[1].(map[string]dbus.Variant)
+	case "org.freedesktop.DBus.ObjectManager.InterfacesAdded":
+		if len(sig.Body) < 2 {
+			return nil
+		}
+		devicePath, _ = sig.Body[0].(dbus.ObjectPath)
+		ifaces, _ := sig.Body[1].(map[string]map[string]dbus.Variant)
+		props = ifaces[bluezDevice]
+	}
+	_, rssi := props["RSSI"]
+	data, hasData := props["ManufacturerData"]
+	if !rssi && !hasData {
+		return nil
+	}
+	base := path.Base(string(devicePath))
+	if !strings.HasPrefix(base, "dev_") {
+		return nil
+	}
+	ids := []string{strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(base, "dev_"), "_", ":"))}
+	if hasData {
+		if uuid, ok := iBeacon(data); ok {
+			ids = append(ids, uuid)
+		}
+	}
+	return ids
+}
+
+// iBeacon reads the proximity UUID from Apple manufacturer data
+func iBeacon(data dbus.Variant) (string, bool) {
+	byVendor, _ := data.Value().(map[uint16]dbus.Variant)
+	b, _ := byVendor[appleID].Value().([]byte)
+	if len(b) < 18 || uint16(b[0])<<8|uint16(b[1]) != iBeaconType {
+		return "", false
+	}
+	u := hex.EncodeToString(b[2:18])
+	return u[:8] + "-" + u[8:12] + "-" + u[12:16] + "-" + u[16:20] + "-" + u[20:], true
+}
@@ -0,0 +1,43 @@
+package presence
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// atfCom marks a complete ARP entry, one the phone has answered
+const atfCom = 0x2
+
+func pingCommand(ip string) (string, []string) {
+	return "ping", []string{"-n", "-c", "1", "-W", "1", ip}
+}
+
+func replied(out []byte) bool {
+	return true
+}
+
+// neighbor looks an address up in the kernel's ARP table
+func neighbor(ctx context.Context, ip string) (string, bool) {
+	f, err := os.Open("/proc/net/arp")
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// IP address, HW type, Flags, HW address, Mask, Device
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[0] != ip {
+			continue
+		}
+		var flags int
+		if _, err := fmt.Sscanf(fields[2], "0x%x", &flags); err != nil || flags&atfCom == 0 {
+			return "", false
+		}
+		return findMAC(fields[3])
+	}
+	return "", false
+}
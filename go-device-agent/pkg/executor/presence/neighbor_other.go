@@ -0,0 +1,36 @@
+//go:build !linux && !windows
+
+package presence
+
+import (
+	"context"
+	"net"
+	"os/exec"
+	"strings"
+)
+
+func pingCommand(ip string) (string, []string) {
+	if net.ParseIP(ip).To4() == nil {
+		return "ping6", []string{"-n", "-c", "1", ip}
+	}
+	return "ping", []string{"-n", "-c", "1", "-t", "1", ip}
+}
+
+func replied(out []byte) bool {
+	return true
+}
+
+// neighbor looks an address up with arp -n, skipping "(incomplete)"
+// entries
+func neighbor(ctx context.Context, ip string) (string, bool) {
+	out, err := exec.CommandContext(ctx, "arp", "-n", ip).Output()
+	if err != nil {
+		return "", false
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.Contains(line, "("+ip+")") {
+			return findMAC(line)
+		}
+	}
+	return "", false
+}
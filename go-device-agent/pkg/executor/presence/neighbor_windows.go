@@ -0,0 +1,32 @@
+package presence
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+)
+
+func pingCommand(ip string) (string, []string) {
+	return "ping", []string{"-n", "1", "-w", "1000", ip}
+}
+
+// replied tells a reply from "Destination host unreachable", for which
+// Windows' ping also exits 0
+func replied(out []byte) bool {
+	return bytes.Contains(bytes.ToUpper(out), []byte("TTL="))
+}
+
+// neighbor looks an address up with arp -a
+func neighbor(ctx context.Context, ip string) (string, bool) {
+	out, err := exec.CommandContext(ctx, "arp", "-a", ip).Output()
+	if err != nil {
+		return "", false
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] == ip {
+			return findMAC(line)
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,169 @@
+package presence
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Person is someone whose presence is tracked, with the detectors that see
+// them; they're home while any detector says so
+type Person struct {
+	// Phones are pinged, and looked up in the ARP table for phones that
+	// sleep through pings
+	Phones []Phone `json:"phones,omitempty"`
+
+	// MQTT topics report the person's state, e.g. from OwnTracks, Home
+	// Assistant, or a door keypad
+	MQTT []Topic `json:"mqtt,omitempty"`
+
+	// Beacons are Bluetooth LE addresses or iBeacon UUIDs, such as a key
+	// fob's
+	Beacons []string `json:"beacons,omitempty"`
+}
+
+// Phone is a phone on the home network
+type Phone struct {
+	IP string `json:"ip"`
+
+	// MAC is the phone's Wi-Fi address; phones randomize it per network,
+	// so it's the one shown in the phone's settings for this network
+	MAC string `json:"mac,omitempty"`
+}
+
+// Topic is an MQTT topic whose messages report a person's state
+type Topic struct {
+	Topic string `json:"topic"`
+
+	// Field is the JSON field holding the state, e.g. "state" (default the
+	// whole payload)
+	Field string `json:"field,omitempty"`
+
+	// Home lists the states that mean home, case-insensitively (default
+	// home, on, true, and 1)
+	Home []string `json:"home,omitempty"`
+}
+
+// File is a presence configuration file:
+//
+//	{
+//	  "mqtt_broker": "tcp://localhost:1883",
+//	  "people": {
+//	    "alice": {
+//	      "phones": [{"ip": "192.168.1.20", "mac": "3c:22:fb:12:34:56"}],
+//	      "mqtt": [{"topic": "owntracks/alice/phone", "field": "inregions", "home": ["home"]}],
+//	      "beacons": ["f7826da6-4fa2-4e98-8024-bc5b71e0893e"]
+//	    }
+//	  }
+//	}
+type File struct {
+	People map[string]*Person `json:"people"`
+
+	// Broker is the MQTT broker the topics are on
+	Broker string `json:"mqtt_broker,omitempty"`
+
+	// Interval between phone checks, as a Go duration (default 30s)
+	Interval string `json:"interval,omitempty"`
+
+	// AwayAfter is how long a phone or beacon goes unseen before its
+	// person counts as away (default 10m)
+	AwayAfter string `json:"away_after,omitempty"`
+
+	interval  time.Duration
+	awayAfter time.Duration
+}
+
+var (
+	names   = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	uuidish = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+)
+
+// LoadFile reads a presence configuration file
+func LoadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid presence file %s: %w", path, err)
+	}
+	if err := f.check(); err != nil {
+		return nil, fmt.Errorf("invalid presence file %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+func (f *File) check() error {
+	if len(f.People) == 0 {
+		return fmt.Errorf("no people")
+	}
+	var err error
+	if f.interval, err = duration(f.Interval, 30*time.Second); err != nil {
+		return fmt.Errorf("interval %w", err)
+	}
+	if f.awayAfter, err = duration(f.AwayAfter, 10*time.Minute); err != nil {
+		return fmt.Errorf("away_after %w", err)
+	}
+	for name, p := range f.People {
+		if !names.MatchString(name) {
+			return fmt.Errorf("person %q: names are lowercase letters, digits, - and _", name)
+		}
+		if err := p.check(f); err != nil {
+			return fmt.Errorf("person %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (p *Person) check(f *File) error {
+	if len(p.Phones)+len(p.MQTT)+len(p.Beacons) == 0 {
+		return fmt.Errorf("no phones, mqtt topics, or beacons")
+	}
+	for i, ph := range p.Phones {
+		if net.ParseIP(ph.IP) == nil {
+			return fmt.Errorf("phone %q isn't an IP address", ph.IP)
+		}
+		if ph.MAC != "" {
+			mac, err := net.ParseMAC(ph.MAC)
+			if err != nil {
+				return fmt.Errorf("phone %s: %w", ph.IP, err)
+			}
+			p.Phones[i].MAC = mac.String()
+		}
+	}
+	if len(p.MQTT) > 0 && f.Broker == "" {
+		return fmt.Errorf("mqtt topics need mqtt_broker")
+	}
+	for i, t := range p.MQTT {
+		if t.Topic == "" || strings.ContainsAny(t.Topic, "+#") {
+			return fmt.Errorf("mqtt topics must be set and have no wildcards")
+		}
+		if len(t.Home) == 0 {
+			p.MQTT[i].Home = []string{"home", "on", "true", "1"}
+		}
+	}
+	for i, b := range p.Beacons {
+		b = strings.ToLower(b)
+		if _, err := net.ParseMAC(b); err != nil && !uuidish.MatchString(b) {
+			return fmt.Errorf("beacon %q is neither a Bluetooth address nor an iBeacon UUID", b)
+		}
+		p.Beacons[i] = b
+	}
+	return nil
+}
+
+func duration(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("must be a positive Go duration such as 30s")
+	}
+	return d, nil
+}
@@ -0,0 +1,76 @@
+package presence
+
+import (
+	"context"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// checkTimeout bounds a phone check
+	checkTimeout = 3 * time.Second
+
+	// resolveWait is how long the kernel gets to resolve a poked address
+	resolveWait = 500 * time.Millisecond
+)
+
+// seenPhone pings a phone and, when it doesn't answer, looks for it in the
+// ARP table: the ping makes the kernel resolve the address, and phones
+// whose Wi-Fi sleeps between pushes still answer ARP
+func seenPhone(ctx context.Context, ph Phone) bool {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	if ping(ctx, ph.IP) {
+		return true
+	}
+	if ph.MAC == "" {
+		return false
+	}
+	// Without ping, or with ICMP blocked, a datagram to the discard port
+	// still gets the address resolved
+	if conn, err := net.Dial("udp", net.JoinHostPort(ph.IP, "9")); err == nil {
+		conn.Write([]byte{0})
+		conn.Close()
+	}
+	select {
+	case <-time.After(resolveWait):
+	case <-ctx.Done():
+		return false
+	}
+	mac, ok := neighbor(ctx, ph.IP)
+	return ok && mac == ph.MAC
+}
+
+func ping(ctx context.Context, ip string) bool {
+	name, args := pingCommand(ip)
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	return err == nil && replied(out)
+}
+
+// findMAC returns the first MAC address in a line of arp output,
+// normalized the way net.HardwareAddr prints it. macOS drops leading
+// zeros ("3c:22:fb:2:34:56") and Windows separates with dashes.
+func findMAC(line string) (string, bool) {
+	for _, field := range strings.Fields(line) {
+		parts := strings.FieldsFunc(field, func(r rune) bool { return r == ':' || r == '-' })
+		if len(parts) != 6 {
+			continue
+		}
+		for i, p := range parts {
+			if len(p) == 1 {
+				parts[i] = "0" + p
+			}
+		}
+		if mac, err := net.ParseMAC(strings.Join(parts, ":")); err == nil {
+			return mac.String(), true
+		}
+	}
+	return "", false
+}
+
+func hasCommand(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
@@ -0,0 +1,520 @@
+// Package presence works out who is home from their phones on the network,
+// MQTT presence topics, and Bluetooth beacons, for presence.query and for
+// the gateway's presence policy
+package presence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// settle is how long the detectors run before presence counts as known,
+// so retained MQTT states and nearby beacons have been heard
+const settle = 5 * time.Second
+
+// Config sets the people tracked and how to reach the detectors
+type Config struct {
+	// File is the loaded presence file
+	File *File
+
+	// Username and Password log in to the MQTT broker
+	Username string
+	Password string
+
+	// BluetoothAdapter listens for beacons, e.g. hci1 (default the first)
+	BluetoothAdapter string
+}
+
+// detector is one way a person is seen, and what it last saw
+type detector struct {
+	kind string // phone, mqtt, or beacon
+	id   string // the phone's IP, the topic, or the beacon
+
+	// lastSeen is when a phone answered or a beacon was heard
+	lastSeen time.Time
+
+	// reported is an MQTT topic's last state, and when it arrived
+	reported   *bool
+	reportedAt time.Time
+}
+
+// person is a tracked person's detectors and current state
+type person struct {
+	detectors []*detector
+	home      bool
+	since     time.Time
+}
+
+// Executor handles presence.query, and is the gateway's PresenceSource
+type Executor struct {
+	cfg     Config
+	file    *File
+	names   []string
+	session *connpool.MQTTSession
+	bus     *events.Bus
+	logger  *slog.Logger
+
+	// blocked is why the broker may not be reached in air-gapped mode
+	blocked error
+
+	mu     sync.Mutex
+	people map[string]*person
+	known  bool
+}
+
+// NewExecutor creates a presence executor; detection starts with Start.
+// Arrivals and departures are published on bus as presence.arrived and
+// presence.left.
+func NewExecutor(cfg Config, bus *events.Bus) (*Executor, error) {
+	if cfg.File == nil || len(cfg.File.People) == 0 {
+		return nil, agenterrors.New(agenterrors.InvalidParams, "no people configured for presence")
+	}
+	e := &Executor{
+		cfg:    cfg,
+		file:   cfg.File,
+		bus:    bus,
+		logger: slog.Default(),
+		people: make(map[string]*person, len(cfg.File.People)),
+	}
+	topics := false
+	for name, p := range cfg.File.People {
+		e.names = append(e.names, name)
+		tracked := &person{since: time.Now()}
+		for _, ph := range p.Phones {
+			tracked.detectors = append(tracked.detectors, &detector{kind: "phone", id: ph.IP})
+		}
+		for _, t := range p.MQTT {
+			tracked.detectors = append(tracked.detectors, &detector{kind: "mqtt", id: t.Topic})
+			topics = true
+		}
+		for _, b := range p.Beacons {
+			tracked.detectors = append(tracked.detectors, &detector{kind: "beacon", id: b})
+		}
+		e.people[name] = tracked
+	}
+	sort.Strings(e.names)
+
+	if topics {
+		host, _ := os.Hostname()
+		session, err := connpool.Default.MQTT(connpool.MQTTOptions{
+			Broker:   cfg.File.Broker,
+			ClientID: "device-agent-" + host,
+			Username: cfg.Username,
+			Password: cfg.Password,
+		})
+		switch {
+		case errors.Is(err, connpool.ErrEgressBlocked):
+			e.blocked = agenterrors.Wrap(agenterrors.Unavailable, err)
+		case err != nil:
+			return nil, agenterrors.Wrap(agenterrors.InvalidParams, err)
+		default:
+			e.session = session
+		}
+	}
+	return e, nil
+}
+
+// SetLogger sets where detector problems are logged
+func (e *Executor) SetLogger(logger *slog.Logger) {
+	e.logger = logger
+}
+
+// Start runs the detectors until ctx is done: phones are checked every
+// interval, while MQTT states and beacons are taken as they arrive
+func (e *Executor) Start(ctx context.Context) {
+	if e.blocked != nil {
+		e.logger.Warn("Presence MQTT broker not allowed in air-gapped mode", "broker", e.file.Broker, "error", e.blocked)
+	} else if e.session != nil {
+		e.session.OnConnectionLost(func(err error) {
+			e.logger.Warn("Presence MQTT connection lost", "broker", e.file.Broker, "error", err)
+		})
+		e.session.OnConnect(e.connected)
+		go func() {
+			<-ctx.Done()
+			e.session.Release()
+		}()
+	}
+	if e.hasPhones() {
+		if name, _ := pingCommand("127.0.0.1"); !hasCommand(name) {
+			e.logger.Warn("Phones are only found by MAC address", "missing", name)
+		}
+	}
+	if e.hasBeacons() {
+		if s, err := newScanner(e.cfg.BluetoothAdapter); err != nil {
+			e.logger.Warn("Presence beacons unavailable", "error", err)
+		} else {
+			go func() {
+				if err := s.scan(ctx, e.heard); err != nil && ctx.Err() == nil {
+					e.logger.Warn("Presence beacon scan stopped", "error", err)
+				}
+			}()
+		}
+	}
+	go func() {
+		timer := time.NewTimer(settle)
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+			e.sweep(ctx)
+			timer.Reset(e.file.interval)
+		}
+	}()
+}
+
+func (e *Executor) hasPhones() bool {
+	for _, p := range e.file.People {
+		if len(p.Phones) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Executor) hasBeacons() bool {
+	for _, p := range e.file.People {
+		if len(p.Beacons) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// sweep checks every phone, then re-evaluates everyone, which is also how
+// people whose phones and beacons went quiet come to be away
+func (e *Executor) sweep(ctx context.Context) {
+	type check struct {
+		d  *detector
+		ph Phone
+	}
+	var checks []check
+	e.mu.Lock()
+	for _, name := range e.names {
+		for i, ph := range e.file.People[name].Phones {
+			checks = append(checks, check{e.people[name].detectors[i], ph})
+		}
+	}
+	e.mu.Unlock()
+
+	var wg sync.WaitGroup
+	seen := make([]bool, len(checks))
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			seen[i] = seenPhone(ctx, c.ph)
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return
+	}
+
+	now := time.Now()
+	e.mu.Lock()
+	for i, c := range checks {
+		if seen[i] {
+			c.d.lastSeen = now
+		}
+	}
+	e.known = true
+	e.mu.Unlock()
+	e.update(e.names...)
+}
+
+// connected subscribes to every person's topics, after every reconnect
+func (e *Executor) connected(client paho.Client) {
+	for _, name := range e.names {
+		for i, t := range e.file.People[name].MQTT {
+			client.Subscribe(t.Topic, 0, func(_ paho.Client, msg paho.Message) {
+				e.reported(name, i, msg.Payload())
+			})
+		}
+	}
+}
+
+// reported records the state an MQTT topic reported for a person
+func (e *Executor) reported(name string, index int, payload []byte) {
+	t := e.file.People[name].MQTT[index]
+	home, ok := parseState(payload, t)
+	if !ok {
+		e.logger.Warn("Presence payload isn't a JSON object", "topic", t.Topic, "payload", fmt.Sprintf("%.100q", payload))
+		return
+	}
+	e.mu.Lock()
+	n := 0
+	for _, d := range e.people[name].detectors {
+		if d.kind == "mqtt" {
+			if n == index {
+				d.reported = &home
+				d.reportedAt = time.Now()
+				break
+			}
+			n++
+		}
+	}
+	e.mu.Unlock()
+	e.update(name)
+}
+
+// parseState reads a topic's payload: the whole payload, or a field of a
+// JSON object, compared against the topic's home states. Array fields,
+// such as OwnTracks' inregions, count as home when any element does, and
+// a missing field counts as away.
+func parseState(payload []byte, t Topic) (bool, bool) {
+	var values []string
+	if t.Field == "" {
+		values = []string{strings.TrimSpace(string(payload))}
+	} else {
+		var obj map[string]interface{}
+		if err := json.Unmarshal(payload, &obj); err != nil {
+			return false, false
+		}
+		switch v := obj[t.Field].(type) {
+		case nil:
+			// OwnTracks leaves inregions out outside every region
+		case []interface{}:
+			for _, item := range v {
+				values = append(values, fmt.Sprint(item))
+			}
+		default:
+			values = []string{fmt.Sprint(v)}
+		}
+	}
+	for _, v := range values {
+		for _, h := range t.Home {
+			if strings.EqualFold(v, h) {
+				return true, true
+			}
+		}
+	}
+	return false, true
+}
+
+// heard records beacon advertisements, updating only the people they
+// belong to
+func (e *Executor) heard(ids ...string) {
+	now := time.Now()
+	var arrived []string
+	e.mu.Lock()
+	for _, name := range e.names {
+		p := e.people[name]
+		for _, d := range p.detectors {
+			if d.kind == "beacon" && slices.Contains(ids, d.id) {
+				d.lastSeen = now
+				if !p.home {
+					arrived = append(arrived, name)
+				}
+			}
+		}
+	}
+	e.mu.Unlock()
+	if len(arrived) > 0 {
+		e.update(arrived...)
+	}
+}
+
+// update re-evaluates people, publishing arrivals and departures
+func (e *Executor) update(names ...string) {
+	now := time.Now()
+	type change struct {
+		name string
+		home bool
+		by   *detector
+	}
+	var changes []change
+	e.mu.Lock()
+	for _, name := range names {
+		p := e.people[name]
+		home, by := e.evaluate(p, now)
+		if home != p.home {
+			p.home, p.since = home, now
+			changes = append(changes, change{name, home, by})
+		}
+	}
+	anyone := e.anyoneHome()
+	e.mu.Unlock()
+
+	for _, c := range changes {
+		eventType, verb := "presence.left", "left"
+		if c.home {
+			eventType, verb = "presence.arrived", "arrived"
+		}
+		e.logger.Info("Presence changed", "person", c.name, "change", verb)
+		if e.bus == nil {
+			continue
+		}
+		data := map[string]interface{}{"person": c.name, "anyone_home": anyone}
+		if c.by != nil {
+			data["detector"] = c.by.kind + ":" + c.by.id
+		}
+		e.bus.Publish(events.Event{Type: eventType, Source: "presence", Data: data})
+	}
+}
+
+// evaluate says whether a person is home, and which detector says so:
+// an MQTT topic reporting home, or a phone or beacon seen recently
+func (e *Executor) evaluate(p *person, now time.Time) (bool, *detector) {
+	for _, d := range p.detectors {
+		if d.reported != nil && *d.reported {
+			return true, d
+		}
+		if !d.lastSeen.IsZero() && now.Sub(d.lastSeen) < e.file.awayAfter {
+			return true, d
+		}
+	}
+	return false, nil
+}
+
+func (e *Executor) anyoneHome() bool {
+	for _, p := range e.people {
+		if p.home {
+			return true
+		}
+	}
+	return false
+}
+
+// Home returns the people home now. Presence is known once the phones
+// have been checked after the detectors settled.
+func (e *Executor) Home() ([]string, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	home := []string{}
+	for _, name := range e.names {
+		if e.people[name].home {
+			home = append(home, name)
+		}
+	}
+	return home, e.known
+}
+
+func (e *Executor) Name() string {
+	return "presence"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"presence.query"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	// The MQTT broker, and arp and ping for phones on the network
+	return sandbox.Needs{Network: true, Subprocess: true}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "presence",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	switch i.IntentType {
+	case "presence.query":
+		var params struct {
+			Person string `param:"person"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		names := e.names
+		if params.Person != "" {
+			if _, ok := e.people[params.Person]; !ok {
+				return fail(agenterrors.Newf(agenterrors.NotFound, "no person named %q (people: %s)",
+					params.Person, strings.Join(e.names, ", ")))
+			}
+			names = []string{params.Person}
+		}
+		home, known := e.Home()
+		out := map[string]interface{}{
+			"anyone_home": len(home) > 0,
+			"home":        home,
+			"known":       known,
+		}
+		e.mu.Lock()
+		people := make([]map[string]interface{}, 0, len(names))
+		for _, name := range names {
+			people = append(people, e.describe(name))
+		}
+		e.mu.Unlock()
+		if params.Person != "" {
+			out["person"] = people[0]
+		} else {
+			out["people"] = people
+		}
+		result.Success = true
+		result.Result = out
+
+	default:
+		return fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+	return result, nil
+}
+
+// describe reports a person's state and what each detector last saw
+func (e *Executor) describe(name string) map[string]interface{} {
+	p := e.people[name]
+	detectors := make([]map[string]interface{}, 0, len(p.detectors))
+	for _, d := range p.detectors {
+		entry := map[string]interface{}{"type": d.kind, "id": d.id}
+		if !d.lastSeen.IsZero() {
+			entry["last_seen"] = d.lastSeen.UTC().Format(time.RFC3339)
+		}
+		if d.reported != nil {
+			entry["reported_home"] = *d.reported
+			entry["reported_at"] = d.reportedAt.UTC().Format(time.RFC3339)
+		}
+		detectors = append(detectors, entry)
+	}
+	return map[string]interface{}{
+		"person":    name,
+		"home":      p.home,
+		"since":     p.since.UTC().Format(time.RFC3339),
+		"detectors": detectors,
+	}
+}
+
+// IsAvailable reports whether any detector can run: MQTT topics can't when
+// air-gapped mode blocks their broker
+func (e *Executor) IsAvailable() bool {
+	return e.blocked == nil || e.hasPhones() || e.hasBeacons()
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"presence.query": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"person": {"type": "string", "minLength": 1}
+			}
+		}`),
+	}
+}
@@ -0,0 +1,39 @@
+package presence
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+)
+
+func TestBrokerChecksEgress(t *testing.T) {
+	saved := connpool.Default
+	defer func() { connpool.Default = saved }()
+	connpool.Default = connpool.New(connpool.Config{Egress: &connpool.Egress{}})
+
+	// 203.0.113.0/24 is reserved for documentation, so never local
+	file := &File{
+		Broker: "tcp://203.0.113.5:1883",
+		People: map[string]*Person{"sam": {MQTT: []Topic{{Topic: "presence/sam"}}}},
+	}
+	e, err := NewExecutor(Config{File: file}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(e.blocked, connpool.ErrEgressBlocked) {
+		t.Fatalf("got %v, want the broker refused in air-gapped mode", e.blocked)
+	}
+	if e.IsAvailable() {
+		t.Error("available with only MQTT detectors and their broker blocked")
+	}
+
+	// Phones on the local network still work without the broker
+	file.People["alex"] = &Person{Phones: []Phone{{IP: "192.168.1.20"}}}
+	if e, err = NewExecutor(Config{File: file}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !e.IsAvailable() {
+		t.Error("unavailable although phones can be checked")
+	}
+}
@@ -0,0 +1,318 @@
+package printer
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// IPP operations used here
+const (
+	opPrintJob             = 0x0002
+	opGetJobAttributes     = 0x0009
+	opGetJobs              = 0x000A
+	opGetPrinterAttributes = 0x000B
+)
+
+// Attribute group and value tags
+const (
+	tagOperation = 0x01
+	tagJob       = 0x02
+	tagEnd       = 0x03
+
+	tagInteger    = 0x21
+	tagBoolean    = 0x22
+	tagEnum       = 0x23
+	tagRange      = 0x33
+	tagBegCollect = 0x34
+	tagEndCollect = 0x37
+	tagText       = 0x41
+	tagName       = 0x42
+	tagKeyword    = 0x44
+	tagURI        = 0x45
+	tagCharset    = 0x47
+	tagLanguage   = 0x48
+	tagMimeType   = 0x49
+)
+
+// attribute is one IPP attribute to send
+type attribute struct {
+	tag    byte
+	name   string
+	values []interface{} // string, int, bool, or [2]int for ranges
+}
+
+func attr(tag byte, name string, values ...interface{}) attribute {
+	return attribute{tag: tag, name: name, values: values}
+}
+
+// attributes are the decoded attributes of a response, by name. Every
+// group is merged; the operations used here return one job or printer
+// group, or one group per job for Get-Jobs.
+type attributes map[string][]interface{}
+
+func (a attributes) str(name string) string {
+	if v, ok := a[name]; ok && len(v) > 0 {
+		s, _ := v[0].(string)
+		return s
+	}
+	return ""
+}
+
+func (a attributes) int(name string) (int, bool) {
+	if v, ok := a[name]; ok && len(v) > 0 {
+		n, ok := v[0].(int)
+		return n, ok
+	}
+	return 0, false
+}
+
+func (a attributes) strs(name string) []string {
+	var out []string
+	for _, v := range a[name] {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+var requestID atomic.Int32
+
+// encode writes an IPP/1.1 request header with its operation and job
+// attributes
+func encode(op uint16, operation, job []attribute) []byte {
+	var b bytes.Buffer
+	b.Write([]byte{1, 1})
+	binary.Write(&b, binary.BigEndian, op)
+	binary.Write(&b, binary.BigEndian, requestID.Add(1))
+	groups := []struct {
+		tag   byte
+		attrs []attribute
+	}{{tagOperation, operation}, {tagJob, job}}
+	for _, g := range groups {
+		if len(g.attrs) == 0 {
+			continue
+		}
+		b.WriteByte(g.tag)
+		for _, a := range g.attrs {
+			for n, v := range a.values {
+				b.WriteByte(a.tag)
+				name := a.name
+				if n > 0 {
+					name = "" // additional values of the same attribute
+				}
+				binary.Write(&b, binary.BigEndian, uint16(len(name)))
+				b.WriteString(name)
+				switch v := v.(type) {
+				case string:
+					binary.Write(&b, binary.BigEndian, uint16(len(v)))
+					b.WriteString(v)
+				case int:
+					binary.Write(&b, binary.BigEndian, uint16(4))
+					binary.Write(&b, binary.BigEndian, int32(v))
+				case bool:
+					binary.Write(&b, binary.BigEndian, uint16(1))
+					if v {
+						b.WriteByte(1)
+					} else {
+						b.WriteByte(0)
+					}
+				case [2]int:
+					binary.Write(&b, binary.BigEndian, uint16(8))
+					binary.Write(&b, binary.BigEndian, int32(v[0]))
+					binary.Write(&b, binary.BigEndian, int32(v[1]))
+				}
+			}
+		}
+	}
+	b.WriteByte(tagEnd)
+	return b.Bytes()
+}
+
+// decode reads a response's status and attributes. Groups are returned
+// in order; collections are skipped, as nothing read here is one.
+func decode(data []byte) (uint16, []attributes, error) {
+	r := bytes.NewReader(data)
+	var header struct {
+		Version   [2]byte
+		Status    uint16
+		RequestID int32
+	}
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return 0, nil, errors.New("response is not IPP")
+	}
+	var (
+		groups []attributes
+		group  attributes
+		last   string
+		depth  int
+	)
+	for {
+		tag, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, errors.New("truncated IPP response")
+		}
+		if tag == tagEnd {
+			break
+		}
+		if tag < 0x10 {
+			group = make(attributes)
+			groups = append(groups, group)
+			continue
+		}
+		name, err := readField(r)
+		if err != nil {
+			return 0, nil, err
+		}
+		value, err := readField(r)
+		if err != nil {
+			return 0, nil, err
+		}
+		switch tag {
+		case tagBegCollect:
+			depth++
+			continue
+		case tagEndCollect:
+			depth--
+			continue
+		}
+		if depth > 0 || group == nil {
+			continue
+		}
+		if len(name) > 0 {
+			last = string(name)
+		}
+		var v interface{}
+		switch {
+		case (tag == tagInteger || tag == tagEnum) && len(value) == 4:
+			v = int(int32(binary.BigEndian.Uint32(value)))
+		case tag == tagBoolean && len(value) == 1:
+			v = value[0] != 0
+		case tag == tagRange && len(value) == 8:
+			v = [2]int{int(int32(binary.BigEndian.Uint32(value))), int(int32(binary.BigEndian.Uint32(value[4:])))}
+		case tag >= 0x40 && tag <= 0x4F:
+			v = string(value)
+		case tag == 0x35 || tag == 0x36:
+			// text or name with language: a length-prefixed language, then
+			// the length-prefixed text
+			vr := bytes.NewReader(value)
+			if _, err := readField(vr); err == nil {
+				if text, err := readField(vr); err == nil {
+					v = string(text)
+				}
+			}
+		}
+		if v != nil {
+			group[last] = append(group[last], v)
+		}
+	}
+	return header.Status, groups, nil
+}
+
+func readField(r *bytes.Reader) ([]byte, error) {
+	var n uint16
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return nil, errors.New("truncated IPP response")
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, errors.New("truncated IPP response")
+	}
+	return b, nil
+}
+
+// post sends a request, followed by document when not nil, to a printer.
+// ipp:// and ipps:// printer URIs are reached over HTTP and HTTPS on port
+// 631 unless they name another.
+func post(ctx context.Context, client *http.Client, printerURI string, request []byte, document io.Reader) ([]attributes, error) {
+	body := io.Reader(bytes.NewReader(request))
+	if document != nil {
+		body = io.MultiReader(body, document)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, httpURL(printerURI), body)
+	if err != nil {
+		return nil, agenterrors.Wrap(agenterrors.InvalidParams, err)
+	}
+	req.Header.Set("Content-Type", "application/ipp")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, agenterrors.New(agenterrors.Unauthorized, "the printer wants authentication the agent doesn't have")
+	case resp.StatusCode != http.StatusOK:
+		return nil, agenterrors.Newf(agenterrors.Unavailable, "the printer answered with HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	status, groups, err := decode(data)
+	if err != nil {
+		return nil, agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	if status >= 0x0400 {
+		return nil, statusError(status, groups)
+	}
+	return groups, nil
+}
+
+// httpURL is where an ipp:// or ipps:// printer URI is posted to
+func httpURL(printerURI string) string {
+	scheme, rest, ok := strings.Cut(printerURI, "://")
+	if !ok {
+		return printerURI
+	}
+	switch scheme {
+	case "ipp":
+		scheme = "http"
+	case "ipps":
+		scheme = "https"
+	default:
+		return printerURI
+	}
+	host, path, _ := strings.Cut(rest, "/")
+	if !strings.Contains(host, ":") || strings.HasSuffix(host, "]") {
+		host += ":631"
+	}
+	return scheme + "://" + host + "/" + path
+}
+
+// statusError gives an IPP error status an agent error code
+func statusError(status uint16, groups []attributes) error {
+	text := fmt.Sprintf("the printer refused the request (IPP status 0x%04x)", status)
+	for _, g := range groups {
+		if msg := g.str("status-message"); msg != "" {
+			text = "the printer refused the request: " + msg
+		}
+	}
+	switch status {
+	case 0x0401, 0x0402, 0x0403: // forbidden, not authenticated, not authorized
+		return agenterrors.New(agenterrors.Unauthorized, text)
+	case 0x0406, 0x0407: // not found, gone
+		return agenterrors.New(agenterrors.NotFound, text)
+	case 0x0408: // request entity too large
+		return agenterrors.New(agenterrors.InvalidParams, text)
+	case 0x040A: // document format not supported
+		return agenterrors.New(agenterrors.Unsupported, text)
+	case 0x0501: // operation not supported
+		return agenterrors.New(agenterrors.Unsupported, text)
+	case 0x0505, 0x0506, 0x0507: // temporary error, not accepting jobs, busy
+		return agenterrors.New(agenterrors.Unavailable, text)
+	}
+	if status < 0x0500 {
+		return agenterrors.New(agenterrors.InvalidParams, text)
+	}
+	return agenterrors.New(agenterrors.Unavailable, text)
+}
@@ -0,0 +1,620 @@
+// Package printer prints documents and reports printer and job status over
+// IPP, for CUPS queues and network printers alike
+package printer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Printer is an IPP print queue, e.g. ipp://localhost:631/printers/office
+// for CUPS or ipp://192.168.1.30/ipp/print for a network printer
+type Printer struct {
+	Name string
+	URI  string
+}
+
+// Config sets the printers and what may be printed
+type Config struct {
+	// Printers are the queues, the first being the default
+	Printers []Printer
+
+	// Dirs are the directories files may be printed from
+	Dirs []string
+
+	// MaxSize caps the size of a printed file (default 50 MiB)
+	MaxSize int64
+
+	// Timeout bounds each request to a printer (default 30s)
+	Timeout time.Duration
+}
+
+// job is a job this executor submitted
+type job struct {
+	printer string
+	file    string
+}
+
+// Executor handles print.document and print.status
+type Executor struct {
+	cfg  Config
+	user string
+
+	mu   sync.Mutex
+	jobs map[int]job // submitted jobs by ID, so status needs only the ID
+}
+
+// maxTracked bounds how many submitted jobs are remembered
+const maxTracked = 200
+
+var pagesPattern = regexp.MustCompile(`^\d+(-\d+)?(,\d+(-\d+)?)*$`)
+
+// Formats printers commonly take, by extension. Anything else is sent as
+// application/octet-stream for the printer to detect.
+var formats = map[string]string{
+	".pdf":  "application/pdf",
+	".ps":   "application/postscript",
+	".txt":  "text/plain",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".pwg":  "image/pwg-raster",
+	".urf":  "image/urf",
+}
+
+// NewExecutor checks the printers and directories
+func NewExecutor(cfg Config) (*Executor, error) {
+	if len(cfg.Printers) == 0 {
+		return nil, errors.New("no printers configured")
+	}
+	seen := make(map[string]bool)
+	for _, p := range cfg.Printers {
+		if p.Name == "" || seen[p.Name] {
+			return nil, fmt.Errorf("printer names must be unique and non-empty: %q", p.Name)
+		}
+		seen[p.Name] = true
+		scheme, _, _ := strings.Cut(p.URI, "://")
+		switch scheme {
+		case "ipp", "ipps", "http", "https":
+		default:
+			return nil, fmt.Errorf("printer %s: %q is not an ipp:// or ipps:// URI", p.Name, p.URI)
+		}
+	}
+	for n, dir := range cfg.Dirs {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("print directory %s is not a directory", dir)
+		}
+		cfg.Dirs[n] = abs
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 50 << 20
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	user := os.Getenv("USER")
+	if user == "" {
+		user = "device-agent"
+	}
+	return &Executor{cfg: cfg, user: user, jobs: make(map[int]job)}, nil
+}
+
+func (e *Executor) Name() string {
+	return "print"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"print.document", "print.status"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{Network: true, Read: e.cfg.Dirs}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "print",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+	defer cancel()
+
+	switch i.IntentType {
+	case "print.document":
+		var params struct {
+			Path    string `param:"path,required"`
+			Printer string `param:"printer"`
+			Copies  int    `param:"copies"`
+			Duplex  bool   `param:"duplex"`
+			Pages   string `param:"pages"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		p, err := e.printer(params.Printer)
+		if err != nil {
+			return fail(err)
+		}
+		out, err := e.print(ctx, p, params.Path, params.Copies, params.Duplex, params.Pages)
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = out
+
+	case "print.status":
+		var params struct {
+			Printer string `param:"printer"`
+			JobID   int    `param:"job_id"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		if params.Printer == "" && params.JobID > 0 {
+			e.mu.Lock()
+			params.Printer = e.jobs[params.JobID].printer
+			e.mu.Unlock()
+		}
+		p, err := e.printer(params.Printer)
+		if err != nil {
+			return fail(err)
+		}
+		var out map[string]interface{}
+		if params.JobID > 0 {
+			out, err = e.jobStatus(ctx, p, params.JobID)
+		} else {
+			out, err = e.printerStatus(ctx, p)
+		}
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = out
+
+	default:
+		return fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+	return result, nil
+}
+
+// printer finds a printer by name, the default when name is empty
+func (e *Executor) printer(name string) (Printer, error) {
+	if name == "" {
+		return e.cfg.Printers[0], nil
+	}
+	var names []string
+	for _, p := range e.cfg.Printers {
+		if strings.EqualFold(p.Name, name) {
+			return p, nil
+		}
+		names = append(names, p.Name)
+	}
+	return Printer{}, agenterrors.Newf(agenterrors.NotFound, "no printer named %q (printers: %s)", name, strings.Join(names, ", "))
+}
+
+// open opens a file to print, which must be inside one of the print
+// directories. Relative paths are taken from the first. The file is opened
+// through os.Root, so symlinks can't lead out of the directory either.
+func (e *Executor) open(path string) (*os.File, error) {
+	if len(e.cfg.Dirs) == 0 {
+		return nil, agenterrors.New(agenterrors.Unsupported, "no print directories are configured")
+	}
+	dir, rel := e.cfg.Dirs[0], path
+	if filepath.IsAbs(path) {
+		dir = ""
+		for _, d := range e.cfg.Dirs {
+			if r, err := filepath.Rel(d, filepath.Clean(path)); err == nil && filepath.IsLocal(r) {
+				dir, rel = d, r
+				break
+			}
+		}
+		if dir == "" {
+			return nil, agenterrors.Newf(agenterrors.DeniedByPolicy, "%s is outside the print directories", path)
+		}
+	} else if !filepath.IsLocal(path) {
+		return nil, agenterrors.Newf(agenterrors.DeniedByPolicy, "%s is outside the print directories", path)
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	defer root.Close()
+	f, err := root.Open(rel)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, agenterrors.Newf(agenterrors.NotFound, "%s does not exist", path)
+	case err != nil && strings.Contains(err.Error(), "path escapes from parent"):
+		return nil, agenterrors.Newf(agenterrors.DeniedByPolicy, "%s is outside the print directories", path)
+	case err != nil:
+		return nil, agenterrors.Newf(agenterrors.Unavailable, "%s: %v", path, err)
+	}
+	return f, nil
+}
+
+func (e *Executor) print(ctx context.Context, p Printer, path string, copies int, duplex bool, pages string) (map[string]interface{}, error) {
+	if pages != "" && !pagesPattern.MatchString(pages) {
+		return nil, agenterrors.New(agenterrors.InvalidParams, "pages must look like 1-3,5")
+	}
+	f, err := e.open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	switch {
+	case err != nil:
+		return nil, agenterrors.Wrap(agenterrors.Unavailable, err)
+	case info.IsDir():
+		return nil, agenterrors.Newf(agenterrors.InvalidParams, "%s is a directory", path)
+	case info.Size() > e.cfg.MaxSize:
+		return nil, agenterrors.Newf(agenterrors.InvalidParams, "%s is larger than %d bytes", path, e.cfg.MaxSize)
+	}
+
+	format := formats[strings.ToLower(filepath.Ext(path))]
+	if format == "" {
+		format = "application/octet-stream"
+	}
+	supported, err := e.attributes(ctx, p, "document-format-supported")
+	if err != nil {
+		return nil, err
+	}
+	if formats := supported.strs("document-format-supported"); len(formats) > 0 && !contains(formats, format) {
+		if !contains(formats, "application/octet-stream") {
+			return nil, agenterrors.Newf(agenterrors.Unsupported, "%s can't print %s files (takes %s)",
+				p.Name, typeName(format), strings.Join(formats, ", "))
+		}
+		format = "application/octet-stream"
+	}
+
+	operation := []attribute{
+		attr(tagCharset, "attributes-charset", "utf-8"),
+		attr(tagLanguage, "attributes-natural-language", "en"),
+		attr(tagURI, "printer-uri", p.URI),
+		attr(tagName, "requesting-user-name", e.user),
+		attr(tagName, "job-name", filepath.Base(path)),
+		attr(tagMimeType, "document-format", format),
+	}
+	var jobAttrs []attribute
+	if copies > 1 {
+		jobAttrs = append(jobAttrs, attr(tagInteger, "copies", copies))
+	}
+	if duplex {
+		jobAttrs = append(jobAttrs, attr(tagKeyword, "sides", "two-sided-long-edge"))
+	}
+	if pages != "" {
+		var ranges []interface{}
+		for _, r := range strings.Split(pages, ",") {
+			from, to, ok := strings.Cut(r, "-")
+			lo, _ := strconv.Atoi(from)
+			hi := lo
+			if ok {
+				hi, _ = strconv.Atoi(to)
+			}
+			if lo < 1 || hi < lo {
+				return nil, agenterrors.Newf(agenterrors.InvalidParams, "invalid page range %s", r)
+			}
+			ranges = append(ranges, [2]int{lo, hi})
+		}
+		jobAttrs = append(jobAttrs, attribute{tag: tagRange, name: "page-ranges", values: ranges})
+	}
+
+	groups, err := post(ctx, e.client(), p.URI, encode(opPrintJob, operation, jobAttrs), f)
+	if err != nil {
+		return nil, err
+	}
+	attrs := merge(groups)
+	id, ok := attrs.int("job-id")
+	if !ok {
+		return nil, agenterrors.New(agenterrors.Unavailable, "the printer accepted the job without a job ID")
+	}
+	e.track(id, job{printer: p.Name, file: path})
+
+	out := map[string]interface{}{
+		"job_id":  id,
+		"printer": p.Name,
+		"file":    path,
+		"format":  format,
+		"bytes":   info.Size(),
+	}
+	if uri := attrs.str("job-uri"); uri != "" {
+		out["job_uri"] = uri
+	}
+	if state, ok := attrs.int("job-state"); ok {
+		out["state"] = jobState(state)
+	}
+	return out, nil
+}
+
+// track remembers a submitted job, forgetting the oldest beyond maxTracked
+func (e *Executor) track(id int, j job) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.jobs[id] = j
+	if len(e.jobs) > maxTracked {
+		oldest := id
+		for jid := range e.jobs {
+			oldest = min(oldest, jid)
+		}
+		delete(e.jobs, oldest)
+	}
+}
+
+func (e *Executor) printerStatus(ctx context.Context, p Printer) (map[string]interface{}, error) {
+	attrs, err := e.attributes(ctx, p,
+		"printer-state", "printer-state-reasons", "printer-state-message", "printer-is-accepting-jobs",
+		"printer-make-and-model", "queued-job-count", "marker-names", "marker-levels")
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]interface{}{
+		"printer": p.Name,
+		"state":   printerState(attrs),
+	}
+	if reasons := stateReasons(attrs.strs("printer-state-reasons")); len(reasons) > 0 {
+		out["reasons"] = reasons
+	}
+	if msg := attrs.str("printer-state-message"); msg != "" {
+		out["message"] = msg
+	}
+	if accepting, ok := attrs["printer-is-accepting-jobs"]; ok && len(accepting) > 0 {
+		out["accepting_jobs"] = accepting[0]
+	}
+	if model := attrs.str("printer-make-and-model"); model != "" {
+		out["model"] = model
+	}
+	if n, ok := attrs.int("queued-job-count"); ok {
+		out["queued_jobs"] = n
+	}
+	// Ink and toner levels in percent; negative levels mean unknown
+	names, levels := attrs.strs("marker-names"), attrs["marker-levels"]
+	if len(names) == len(levels) && len(names) > 0 {
+		markers := make(map[string]int, len(names))
+		for n, name := range names {
+			if level, ok := levels[n].(int); ok && level >= 0 {
+				markers[name] = level
+			}
+		}
+		if len(markers) > 0 {
+			out["supplies"] = markers
+		}
+	}
+
+	jobs, err := e.queue(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	out["jobs"] = jobs
+	return out, nil
+}
+
+// queue lists the printer's jobs that haven't finished
+func (e *Executor) queue(ctx context.Context, p Printer) ([]map[string]interface{}, error) {
+	operation := e.operation(p,
+		attr(tagKeyword, "which-jobs", "not-completed"),
+		attr(tagKeyword, "requested-attributes", "job-id", "job-name", "job-state", "job-originating-user-name"))
+	groups, err := post(ctx, e.client(), p.URI, encode(opGetJobs, operation, nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	jobs := []map[string]interface{}{}
+	for _, g := range groups {
+		id, ok := g.int("job-id")
+		if !ok {
+			continue
+		}
+		j := map[string]interface{}{"job_id": id}
+		if name := g.str("job-name"); name != "" {
+			j["name"] = name
+		}
+		if state, ok := g.int("job-state"); ok {
+			j["state"] = jobState(state)
+		}
+		if user := g.str("job-originating-user-name"); user != "" {
+			j["user"] = user
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, nil
+}
+
+func (e *Executor) jobStatus(ctx context.Context, p Printer, id int) (map[string]interface{}, error) {
+	operation := e.operation(p,
+		attr(tagInteger, "job-id", id),
+		attr(tagKeyword, "requested-attributes", "job-id", "job-name", "job-state", "job-state-reasons",
+			"job-state-message", "job-impressions-completed", "job-media-sheets-completed"))
+	groups, err := post(ctx, e.client(), p.URI, encode(opGetJobAttributes, operation, nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	attrs := merge(groups)
+	state, _ := attrs.int("job-state")
+	out := map[string]interface{}{
+		"job_id":  id,
+		"printer": p.Name,
+		"state":   jobState(state),
+		"done":    state >= 7,
+	}
+	if name := attrs.str("job-name"); name != "" {
+		out["name"] = name
+	}
+	if reasons := stateReasons(attrs.strs("job-state-reasons")); len(reasons) > 0 {
+		out["reasons"] = reasons
+	}
+	if msg := attrs.str("job-state-message"); msg != "" {
+		out["message"] = msg
+	}
+	if n, ok := attrs.int("job-impressions-completed"); ok {
+		out["pages_printed"] = n
+	}
+	if n, ok := attrs.int("job-media-sheets-completed"); ok {
+		out["sheets_printed"] = n
+	}
+	e.mu.Lock()
+	if j, ok := e.jobs[id]; ok && j.printer == p.Name {
+		out["file"] = j.file
+	}
+	e.mu.Unlock()
+	return out, nil
+}
+
+// attributes gets printer attributes
+func (e *Executor) attributes(ctx context.Context, p Printer, names ...string) (attributes, error) {
+	requested := make([]interface{}, len(names))
+	for n, name := range names {
+		requested[n] = name
+	}
+	operation := e.operation(p, attribute{tag: tagKeyword, name: "requested-attributes", values: requested})
+	groups, err := post(ctx, e.client(), p.URI, encode(opGetPrinterAttributes, operation, nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	return merge(groups), nil
+}
+
+// operation is the operation group every request starts with, plus extra
+func (e *Executor) operation(p Printer, extra ...attribute) []attribute {
+	return append([]attribute{
+		attr(tagCharset, "attributes-charset", "utf-8"),
+		attr(tagLanguage, "attributes-natural-language", "en"),
+		attr(tagURI, "printer-uri", p.URI),
+		attr(tagName, "requesting-user-name", e.user),
+	}, extra...)
+}
+
+func (e *Executor) client() *http.Client {
+	return connpool.Default.HTTP(e.cfg.Timeout)
+}
+
+func (e *Executor) IsAvailable() bool {
+	return true
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"print.document": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"path": {"type": "string", "minLength": 1},
+				"printer": {"type": "string", "minLength": 1},
+				"copies": {"type": "integer", "minimum": 1, "maximum": 99},
+				"duplex": {"type": "boolean"},
+				"pages": {"type": "string", "pattern": "^[0-9]+(-[0-9]+)?(,[0-9]+(-[0-9]+)?)*$"}
+			},
+			"required": ["path"]
+		}`),
+		"print.status": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"printer": {"type": "string", "minLength": 1},
+				"job_id": {"type": "integer", "minimum": 1}
+			}
+		}`),
+	}
+}
+
+func merge(groups []attributes) attributes {
+	all := make(attributes)
+	for _, g := range groups {
+		for k, v := range g {
+			all[k] = v
+		}
+	}
+	return all
+}
+
+func printerState(attrs attributes) string {
+	state, _ := attrs.int("printer-state")
+	switch state {
+	case 3:
+		return "idle"
+	case 4:
+		return "printing"
+	case 5:
+		return "stopped"
+	}
+	return "unknown"
+}
+
+func jobState(state int) string {
+	switch state {
+	case 3:
+		return "pending"
+	case 4:
+		return "held"
+	case 5:
+		return "printing"
+	case 6:
+		return "stopped"
+	case 7:
+		return "canceled"
+	case 8:
+		return "aborted"
+	case 9:
+		return "completed"
+	}
+	return "unknown"
+}
+
+// stateReasons drops "none" and the -report, -warning, and -error
+// suffixes, leaving e.g. media-empty or toner-low
+func stateReasons(list []string) []string {
+	var out []string
+	for _, r := range list {
+		if r == "none" {
+			continue
+		}
+		for _, suffix := range []string{"-report", "-warning", "-error"} {
+			r = strings.TrimSuffix(r, suffix)
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// typeName is a document format's usual extension, e.g. PDF
+func typeName(format string) string {
+	for ext, f := range formats {
+		if f == format && ext != ".jpeg" {
+			return strings.ToUpper(ext[1:])
+		}
+	}
+	return format
+}
@@ -0,0 +1,137 @@
+// Package profile lets the agent core switch between the named profiles
+// of the agent's config file, such as home, work, and travel, each with
+// its own policies, executors, and device maps
+package profile
+
+import (
+	"context"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/config"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Switcher is what the executor switches profiles with, a
+// *config.Reloader in the agent
+type Switcher interface {
+	Profile() string
+	Profiles() []config.Profile
+	SetProfile(name string) (*config.Report, error)
+}
+
+// Executor handles profile.switch and profile.get. Switching needs an
+// intent with requires_permission set, and is never allowed to guests.
+type Executor struct {
+	profiles Switcher
+	bus      *events.Bus
+}
+
+// NewExecutor creates a profile executor; switches are published to bus
+// as "profile.changed" events
+func NewExecutor(profiles Switcher, bus *events.Bus) *Executor {
+	return &Executor{profiles: profiles, bus: bus}
+}
+
+func (e *Executor) Name() string {
+	return "profile"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"profile.switch", "profile.get"}
+}
+
+func (e *Executor) PermissionRequired() []string {
+	return []string{"profile.switch"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "profile",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	switch i.IntentType {
+	case "profile.switch":
+		// A profile can lift a guest's restrictions along with everyone's
+		if i.GuestToken != "" {
+			return fail(agenterrors.New(agenterrors.DeniedByPolicy, "guests cannot switch profiles"))
+		}
+		var params struct {
+			Profile string `param:"profile,required"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		previous := e.profiles.Profile()
+		report, err := e.profiles.SetProfile(params.Profile)
+		if err != nil {
+			return fail(agenterrors.Wrap(agenterrors.NotFound, err))
+		}
+		if report.Error != "" {
+			return fail(agenterrors.Newf(agenterrors.Unavailable, "profile %s not applied: %s", params.Profile, report.Error))
+		}
+		if previous != report.Profile && e.bus != nil {
+			e.bus.Publish(events.Event{Type: "profile.changed", Source: "profile", Data: map[string]interface{}{
+				"profile":  report.Profile,
+				"previous": previous,
+			}})
+		}
+		result.Result = map[string]interface{}{
+			"profile":          report.Profile,
+			"previous":         previous,
+			"applied":          report.Applied,
+			"restart_required": report.Restart,
+			"overridden":       report.Overridden,
+			"failed":           report.Failed,
+		}
+		if err := report.Err(); err != nil {
+			return fail(agenterrors.Wrap(agenterrors.PartialFailure, err))
+		}
+		result.Success = true
+
+	case "profile.get":
+		profiles := []map[string]interface{}{}
+		for _, p := range e.profiles.Profiles() {
+			profiles = append(profiles, map[string]interface{}{"name": p.Name, "description": p.Description})
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{"profile": e.profiles.Profile(), "profiles": profiles}
+
+	default:
+		return fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+	return result, nil
+}
+
+func (e *Executor) IsAvailable() bool {
+	return true
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"profile.switch": schema.MustParse(`{
+			"type": "object",
+			"required": ["profile"],
+			"properties": {
+				"profile": {"type": "string"}
+			}
+		}`),
+		"profile.get": schema.MustParse(`{"type": "object", "properties": {}}`),
+	}
+}
@@ -0,0 +1,225 @@
+// Package security answers "is the house secure?" by aggregating contact
+// sensors, locks, and cameras from the device registry
+package security
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/registry"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Opening is the security state of a single door, window, lock, or camera
+type Opening struct {
+	ID     string        `json:"id"`
+	Name   string        `json:"name"`
+	Kind   registry.Kind `json:"kind"`
+	Room   string        `json:"room,omitempty"`
+	Status string        `json:"status"`
+	Secure bool          `json:"secure"`
+}
+
+// Status is the aggregated security summary
+type Status struct {
+	Secure   bool      `json:"secure"`
+	Openings []Opening `json:"openings"`
+	Issues   []string  `json:"issues"`
+}
+
+// ArmHook runs the user's arming routine once the house has been checked
+type ArmHook func(ctx context.Context, status Status) error
+
+// Executor handles security.status and security.arm
+type Executor struct {
+	registry *registry.Registry
+	armHook  ArmHook
+}
+
+// NewExecutor creates a security executor reading from reg
+func NewExecutor(reg *registry.Registry) *Executor {
+	return &Executor{
+		registry: reg,
+	}
+}
+
+// SetArmHook sets the routine run by security.arm
+func (e *Executor) SetArmHook(hook ArmHook) {
+	e.armHook = hook
+}
+
+func (e *Executor) Name() string {
+	return "security"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"security.status", "security.arm"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "security",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	room, err := i.StringParamOr("room", "")
+	if err != nil {
+		result.Fail(err)
+		return result, nil
+	}
+	status := e.Status(room)
+
+	switch i.IntentType {
+	case "security.status":
+		result.Success = true
+		result.Result = statusResult(status)
+
+	case "security.arm":
+		if e.armHook == nil {
+			result.Fail(agenterrors.New(agenterrors.Unavailable, "no arm routine configured"))
+			return result, nil
+		}
+
+		force, err := i.BoolParamOr("force", false)
+		if err != nil {
+			result.Fail(err)
+			return result, nil
+		}
+		if !status.Secure && !force {
+			result.Fail(agenterrors.Newf(agenterrors.Conflict, "house is not secure: %d issue(s)", len(status.Issues)))
+			result.Result = statusResult(status)
+			return result, nil
+		}
+
+		if err := e.armHook(ctx, status); err != nil {
+			return nil, fmt.Errorf("arm routine failed: %w", err)
+		}
+
+		result.Success = true
+		result.Result = statusResult(status)
+		result.Result["armed"] = true
+
+	default:
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+
+	return result, nil
+}
+
+func (e *Executor) IsAvailable() bool {
+	return e.registry != nil
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"security.status": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"room": {"type": "string"}
+			}
+		}`),
+		"security.arm": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"room": {"type": "string"},
+				"force": {"type": "boolean"}
+			}
+		}`),
+	}
+}
+
+// Status builds the security summary, optionally limited to one room
+func (e *Executor) Status(room string) Status {
+	status := Status{
+		Secure:   true,
+		Openings: []Opening{},
+		Issues:   []string{},
+	}
+
+	devices := e.registry.ByKind(registry.KindContactSensor, registry.KindLock, registry.KindCamera)
+	for _, d := range devices {
+		if room != "" && d.Room != room {
+			continue
+		}
+
+		opening := classify(d)
+		status.Openings = append(status.Openings, opening)
+		if !opening.Secure {
+			status.Secure = false
+			status.Issues = append(status.Issues, describeIssue(d, opening))
+		}
+	}
+
+	return status
+}
+
+// classify derives the status of one device from its registry state.
+// Devices that have not reported state are treated as insecure.
+func classify(d registry.Device) Opening {
+	opening := Opening{
+		ID:     d.ID,
+		Name:   d.Name,
+		Kind:   d.Kind,
+		Room:   d.Room,
+		Status: "unknown",
+	}
+
+	switch d.Kind {
+	case registry.KindContactSensor:
+		if open, ok := d.State["open"].(bool); ok {
+			opening.Status = "closed"
+			if open {
+				opening.Status = "open"
+			}
+			opening.Secure = !open
+		}
+	case registry.KindLock:
+		if locked, ok := d.State["locked"].(bool); ok {
+			opening.Status = "unlocked"
+			if locked {
+				opening.Status = "locked"
+			}
+			opening.Secure = locked
+		}
+	case registry.KindCamera:
+		if online, ok := d.State["online"].(bool); ok {
+			opening.Status = "offline"
+			if online {
+				opening.Status = "online"
+			}
+			opening.Secure = online
+		}
+	}
+
+	return opening
+}
+
+func describeIssue(d registry.Device, opening Opening) string {
+	name := d.Name
+	if name == "" {
+		name = d.ID
+	}
+	if opening.Status == "unknown" {
+		return fmt.Sprintf("%s has not reported its state", name)
+	}
+	return fmt.Sprintf("%s is %s", name, opening.Status)
+}
+
+func statusResult(status Status) map[string]interface{} {
+	return map[string]interface{}{
+		"secure":   status.Secure,
+		"openings": status.Openings,
+		"issues":   status.Issues,
+	}
+}
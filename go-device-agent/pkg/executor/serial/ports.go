@@ -0,0 +1,86 @@
+package serial
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+// Port is a serial port, such as a microcontroller's USB serial
+type Port struct {
+	// Device is the port's path; /dev/serial/by-id paths survive
+	// replugging
+	Device string `json:"device"`
+
+	// Baud is the line speed (default 9600)
+	Baud int `json:"baud,omitempty"`
+
+	// LineEnding ends text written and a reply read (default "\n")
+	LineEnding *string `json:"line_ending,omitempty"`
+
+	// ReplyTimeout is how long serial.write waits for a reply when asked
+	// to, as a Go duration (default 1s)
+	ReplyTimeout string `json:"reply_timeout,omitempty"`
+
+	// Confirm makes writes to this port need requires_permission
+	Confirm bool `json:"confirm,omitempty"`
+
+	Description string `json:"description,omitempty"`
+
+	lineEnding   string
+	replyTimeout time.Duration
+}
+
+// bauds are the standard line speeds
+var bauds = []int{1200, 2400, 4800, 9600, 19200, 38400, 57600, 115200, 230400, 460800, 921600}
+
+// LoadPorts reads the "ports" object of a hardware file, keyed by name:
+//
+//	{"ports": {"arduino": {"device": "/dev/ttyACM0", "baud": 115200}}}
+//
+// Other keys, such as the GPIO pins, are ignored.
+func LoadPorts(path string) (map[string]*Port, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Ports map[string]*Port `json:"ports"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid hardware file %s: %w", path, err)
+	}
+	for name, p := range file.Ports {
+		if err := p.check(); err != nil {
+			return nil, fmt.Errorf("invalid hardware file %s: port %q: %w", path, name, err)
+		}
+	}
+	return file.Ports, nil
+}
+
+func (p *Port) check() error {
+	if p.Device == "" {
+		return fmt.Errorf("missing device")
+	}
+	if p.Baud == 0 {
+		p.Baud = 9600
+	}
+	if !slices.Contains(bauds, p.Baud) {
+		return fmt.Errorf("unsupported baud rate %d", p.Baud)
+	}
+	p.lineEnding = "\n"
+	if p.LineEnding != nil {
+		p.lineEnding = *p.LineEnding
+	}
+	p.replyTimeout = time.Second
+	if p.ReplyTimeout != "" {
+		d, err := time.ParseDuration(p.ReplyTimeout)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("reply_timeout must be a positive Go duration such as 500ms")
+		}
+		p.replyTimeout = d
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+// Package serial writes commands to serial ports, such as a
+// microcontroller's USB serial, and reads their replies
+package serial
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/usage"
+)
+
+// maxWrite bounds the data a serial.write sends
+const maxWrite = 4096
+
+// Executor handles serial.write
+type Executor struct {
+	ports map[string]*Port
+	names []string
+	open  map[string]*openPort
+}
+
+// openPort is a port opened on first use and kept open, since opening
+// resets many boards
+type openPort struct {
+	mu   sync.Mutex
+	conn *conn
+}
+
+// NewExecutor creates a serial executor; ports open when first written
+func NewExecutor(ports map[string]*Port) (*Executor, error) {
+	if len(ports) == 0 {
+		return nil, errors.New("no serial ports configured")
+	}
+	e := &Executor{ports: ports, open: make(map[string]*openPort, len(ports))}
+	for name := range ports {
+		e.names = append(e.names, name)
+		e.open[name] = &openPort{}
+	}
+	sort.Strings(e.names)
+	return e, nil
+}
+
+// Close closes the open ports
+func (e *Executor) Close() error {
+	for _, op := range e.open {
+		op.mu.Lock()
+		if op.conn != nil {
+			op.conn.close()
+			op.conn = nil
+		}
+		op.mu.Unlock()
+	}
+	return nil
+}
+
+func (e *Executor) Name() string {
+	return "serial"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"serial.write"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	var needs sandbox.Needs
+	for _, name := range e.names {
+		needs.Write = append(needs.Write, e.ports[name].Device)
+	}
+	return needs
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "serial",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	switch i.IntentType {
+	case "serial.write":
+		var params struct {
+			Port      string `param:"port,required"`
+			Data      string `param:"data,required"`
+			Encoding  string `param:"encoding"`
+			ReadReply bool   `param:"read_reply"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		p, ok := e.ports[params.Port]
+		if !ok {
+			return fail(agenterrors.Newf(agenterrors.NotFound, "no port named %q (ports: %s)",
+				params.Port, strings.Join(e.names, ", ")))
+		}
+		if p.Confirm && !i.RequiresPermission {
+			return fail(agenterrors.Newf(agenterrors.ConfirmationRequired,
+				"writing to %s needs requires_permission", params.Port))
+		}
+		data, err := decode(params.Data, params.Encoding, p.lineEnding)
+		if err != nil {
+			return fail(err)
+		}
+		if len(data) > maxWrite {
+			return fail(agenterrors.Newf(agenterrors.InvalidParams, "writes are at most %d bytes", maxWrite))
+		}
+		reply, err := e.write(ctx, params.Port, data, params.ReadReply)
+		if err != nil {
+			return fail(err)
+		}
+		out := map[string]interface{}{
+			"port":    params.Port,
+			"written": len(data),
+		}
+		if params.ReadReply {
+			if utf8.Valid(reply) {
+				out["reply"] = strings.TrimRight(string(reply), "\r\n")
+			} else {
+				out["reply_base64"] = base64.StdEncoding.EncodeToString(reply)
+			}
+		}
+		result.Success = true
+		result.Result = out
+
+	default:
+		return fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+	return result, nil
+}
+
+// decode turns data into bytes; text gets the port's line ending
+func decode(data, encoding, ending string) ([]byte, error) {
+	switch encoding {
+	case "", "text":
+		return []byte(data + ending), nil
+	case "hex":
+		b, err := hex.DecodeString(strings.ReplaceAll(data, " ", ""))
+		if err != nil {
+			return nil, agenterrors.Newf(agenterrors.InvalidParams, "invalid hex data: %v", err)
+		}
+		return b, nil
+	case "base64":
+		b, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, agenterrors.Newf(agenterrors.InvalidParams, "invalid base64 data: %v", err)
+		}
+		return b, nil
+	}
+	return nil, agenterrors.Newf(agenterrors.InvalidParams, "encoding must be text, hex, or base64")
+}
+
+// write sends data on a port, opening it if needed, and reads the reply
+// when asked; a failed port is closed so the next write reopens it, as
+// after the board is replugged
+func (e *Executor) write(ctx context.Context, name string, data []byte, readReply bool) ([]byte, error) {
+	p, op := e.ports[name], e.open[name]
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return nil, agenterrors.Wrap(agenterrors.Cancelled, err)
+	}
+	if op.conn == nil {
+		c, err := open(p)
+		if err != nil {
+			return nil, err
+		}
+		op.conn = c
+	}
+	fail := func(err error) ([]byte, error) {
+		op.conn.close()
+		op.conn = nil
+		return nil, err
+	}
+	if err := op.conn.write(data); err != nil {
+		return fail(err)
+	}
+	usage.DeviceCommand(ctx)
+	if !readReply {
+		return nil, nil
+	}
+	timeout := p.replyTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = min(timeout, time.Until(deadline))
+	}
+	reply, err := op.conn.readUntil(p.lineEnding, timeout)
+	if err != nil {
+		return fail(err)
+	}
+	return reply, nil
+}
+
+func (e *Executor) IsAvailable() bool {
+	return len(e.ports) > 0
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"serial.write": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"port": {"type": "string", "minLength": 1},
+				"data": {"type": "string", "minLength": 1},
+				"encoding": {"type": "string", "enum": ["text", "hex", "base64"]},
+				"read_reply": {"type": "boolean"}
+			},
+			"required": ["port", "data"]
+		}`),
+	}
+}
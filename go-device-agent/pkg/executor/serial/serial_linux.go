@@ -0,0 +1,130 @@
+package serial
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// maxReply bounds a reply read, for devices that stream without line
+// endings
+const maxReply = 4096
+
+var speeds = map[int]uint32{
+	1200:   unix.B1200,
+	2400:   unix.B2400,
+	4800:   unix.B4800,
+	9600:   unix.B9600,
+	19200:  unix.B19200,
+	38400:  unix.B38400,
+	57600:  unix.B57600,
+	115200: unix.B115200,
+	230400: unix.B230400,
+	460800: unix.B460800,
+	921600: unix.B921600,
+}
+
+// conn is an open port in raw mode, 8N1 without flow control
+type conn struct {
+	f *os.File
+}
+
+func open(p *Port) (*conn, error) {
+	// O_NONBLOCK keeps the open from waiting for carrier detect
+	fd, err := unix.Open(p.Device, unix.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, portError(p, err)
+	}
+	if err := configure(fd, p.Baud); err != nil {
+		unix.Close(fd)
+		return nil, portError(p, err)
+	}
+	return &conn{f: os.NewFile(uintptr(fd), p.Device)}, nil
+}
+
+func configure(fd, baud int) error {
+	if err := unix.SetNonblock(fd, false); err != nil {
+		return err
+	}
+	// Exclusive, so another program's reads don't steal replies
+	if err := unix.IoctlSetInt(fd, unix.TIOCEXCL, 0); err != nil {
+		return err
+	}
+	t, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return err
+	}
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB | unix.CSTOPB | unix.CRTSCTS | unix.CBAUD
+	t.Cflag |= unix.CS8 | unix.CLOCAL | unix.CREAD | speeds[baud]
+	t.Ispeed = speeds[baud]
+	t.Ospeed = speeds[baud]
+	// Reads return after a tenth of a second without data, so readUntil
+	// can watch its deadline
+	t.Cc[unix.VMIN] = 0
+	t.Cc[unix.VTIME] = 1
+	return unix.IoctlSetTermios(fd, unix.TCSETS, t)
+}
+
+// write sends data after discarding anything unread, so a reply belongs
+// to this write
+func (c *conn) write(data []byte) error {
+	fd := int(c.f.Fd())
+	if err := unix.IoctlSetInt(fd, unix.TCFLSH, unix.TCIFLUSH); err != nil {
+		return agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	if _, err := c.f.Write(data); err != nil {
+		return agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	return nil
+}
+
+// readUntil reads until ending, the timeout, or maxReply bytes, returning
+// what arrived; an empty ending reads until the line goes quiet
+func (c *conn) readUntil(ending string, timeout time.Duration) ([]byte, error) {
+	deadline := time.Now().Add(timeout)
+	var reply []byte
+	buf := make([]byte, 256)
+	for len(reply) < maxReply && time.Now().Before(deadline) {
+		n, err := c.f.Read(buf)
+		// A read that times out under VTIME comes back as end of file
+		if err != nil && !errors.Is(err, io.EOF) {
+			return reply, agenterrors.Wrap(agenterrors.Unavailable, err)
+		}
+		reply = append(reply, buf[:n]...)
+		if ending != "" && bytes.HasSuffix(reply, []byte(ending)) {
+			break
+		}
+		if ending == "" && n == 0 && len(reply) > 0 {
+			break
+		}
+	}
+	return reply[:min(len(reply), maxReply)], nil
+}
+
+func (c *conn) close() error {
+	return c.f.Close()
+}
+
+// portError explains why a port couldn't be opened
+func portError(p *Port, err error) error {
+	switch {
+	case errors.Is(err, unix.ENOENT), errors.Is(err, unix.ENXIO), errors.Is(err, unix.ENODEV):
+		return agenterrors.Newf(agenterrors.NotFound, "%s isn't connected", p.Device)
+	case errors.Is(err, unix.EBUSY):
+		return agenterrors.Newf(agenterrors.Conflict, "%s is in use by another program", p.Device)
+	case errors.Is(err, unix.EACCES), errors.Is(err, unix.EPERM):
+		return agenterrors.Newf(agenterrors.Unauthorized, "%s: permission denied (add the agent's user to the dialout group)", p.Device)
+	case errors.Is(err, unix.ENOTTY):
+		return agenterrors.Newf(agenterrors.InvalidParams, "%s isn't a serial port", p.Device)
+	}
+	return agenterrors.Newf(agenterrors.Unavailable, "%s: %v", p.Device, err)
+}
@@ -0,0 +1,28 @@
+//go:build !linux
+
+package serial
+
+import (
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// conn is never opened here; ports are configured through Linux's termios
+type conn struct{}
+
+func open(p *Port) (*conn, error) {
+	return nil, agenterrors.New(agenterrors.Unsupported, "serial ports need Linux")
+}
+
+func (c *conn) write(data []byte) error {
+	return agenterrors.New(agenterrors.Unsupported, "serial ports need Linux")
+}
+
+func (c *conn) readUntil(ending string, timeout time.Duration) ([]byte, error) {
+	return nil, agenterrors.New(agenterrors.Unsupported, "serial ports need Linux")
+}
+
+func (c *conn) close() error {
+	return nil
+}
@@ -0,0 +1,226 @@
+// Package service reports on and starts, stops, or restarts systemd units,
+// limited to an allowlist
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Config sets the units intents may touch
+type Config struct {
+	// Units are unit names or globs such as backup-*.service. Names
+	// without a type suffix are services.
+	Units []string
+
+	// User manages the agent user's own units instead of the system's
+	User bool
+
+	// Timeout bounds waiting for a unit to change state (default 90s, as
+	// systemd's default start timeout)
+	Timeout time.Duration
+}
+
+// Executor handles service.status, service.start, service.stop, and
+// service.restart
+type Executor struct {
+	cfg     Config
+	systemd *systemd
+}
+
+// NewExecutor connects to systemd, failing when it isn't running
+func NewExecutor(cfg Config) (*Executor, error) {
+	if len(cfg.Units) == 0 {
+		return nil, errors.New("no units allowed")
+	}
+	for n, u := range cfg.Units {
+		if _, err := path.Match(u, ""); err != nil || strings.Contains(u, "/") {
+			return nil, fmt.Errorf("invalid unit pattern %q", u)
+		}
+		cfg.Units[n] = unitName(u)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 90 * time.Second
+	}
+	s, err := newSystemd(cfg.User)
+	if err != nil {
+		return nil, err
+	}
+	return &Executor{cfg: cfg, systemd: s}, nil
+}
+
+func (e *Executor) Name() string {
+	return "service"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"service.status", "service.start", "service.stop", "service.restart"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{} // systemd over D-Bus
+}
+
+// PermissionRequired makes every change of a unit's state need
+// requires_permission
+func (e *Executor) PermissionRequired() []string {
+	return []string{"service.start", "service.stop", "service.restart"}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "service",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	switch i.IntentType {
+	case "service.status":
+		var params struct {
+			Unit string `param:"unit"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		if params.Unit == "" {
+			// Every unit named outright; globs can't be listed
+			var units []Status
+			for _, u := range e.cfg.Units {
+				if strings.ContainsAny(u, "*?[") {
+					continue
+				}
+				st, err := e.systemd.status(ctx, u)
+				if agenterrors.CodeOf(err) == agenterrors.NotFound {
+					st, err = Status{Unit: u, LoadState: "not-found", ActiveState: "inactive"}, nil
+				}
+				if err != nil {
+					return fail(err)
+				}
+				units = append(units, st)
+			}
+			result.Success = true
+			result.Result = map[string]interface{}{
+				"units": units,
+			}
+			break
+		}
+		unit, err := e.allowed(params.Unit)
+		if err != nil {
+			return fail(err)
+		}
+		st, err := e.systemd.status(ctx, unit)
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"status": st,
+		}
+
+	case "service.start", "service.stop", "service.restart":
+		var params struct {
+			Unit string `param:"unit,required"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		unit, err := e.allowed(params.Unit)
+		if err != nil {
+			return fail(err)
+		}
+		before, err := e.systemd.status(ctx, unit)
+		if err != nil {
+			return fail(err)
+		}
+		if before.LoadState == "not-found" {
+			return fail(agenterrors.Newf(agenterrors.NotFound, "no unit %s", unit))
+		}
+		method := map[string]string{
+			"service.start":   "StartUnit",
+			"service.stop":    "StopUnit",
+			"service.restart": "RestartUnit",
+		}[i.IntentType]
+		wait, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+		err = e.systemd.run(wait, method, unit)
+		cancel()
+		if err != nil {
+			return fail(err)
+		}
+		after, err := e.systemd.status(ctx, unit)
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"unit":     unit,
+			"previous": before.ActiveState,
+			"status":   after,
+		}
+
+	default:
+		return fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+	return result, nil
+}
+
+// allowed checks a unit against the allowlist
+func (e *Executor) allowed(unit string) (string, error) {
+	unit = unitName(unit)
+	for _, pattern := range e.cfg.Units {
+		if ok, _ := path.Match(pattern, unit); ok {
+			return unit, nil
+		}
+	}
+	return "", agenterrors.Newf(agenterrors.DeniedByPolicy, "%s is not an allowed unit", unit)
+}
+
+// unitName adds .service to a name without a unit type
+func unitName(name string) string {
+	for _, suffix := range []string{".service", ".socket", ".timer", ".target", ".path", ".mount", ".automount", ".swap", ".slice", ".scope", ".device"} {
+		if strings.HasSuffix(name, suffix) {
+			return name
+		}
+	}
+	return name + ".service"
+}
+
+func (e *Executor) IsAvailable() bool {
+	return e.systemd.conn.Connected()
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	schemas := map[string]*schema.Schema{
+		"service.status": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"unit": {"type": "string", "minLength": 1, "pattern": "^[-A-Za-z0-9:_.@]+$"}
+			}
+		}`),
+	}
+	for _, action := range []string{"service.start", "service.stop", "service.restart"} {
+		schemas[action] = schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"unit": {"type": "string", "minLength": 1, "pattern": "^[-A-Za-z0-9:_.@]+$"}
+			},
+			"required": ["unit"]
+		}`)
+	}
+	return schemas
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+const (
+	systemdService = "org.freedesktop.systemd1"
+	systemdPath    = dbus.ObjectPath("/org/freedesktop/systemd1")
+	managerIface   = "org.freedesktop.systemd1.Manager"
+	unitIface      = "org.freedesktop.systemd1.Unit"
+	serviceIface   = "org.freedesktop.systemd1.Service"
+)
+
+// Status is a unit's state as systemctl status shows it
+type Status struct {
+	Unit          string `json:"unit"`
+	Description   string `json:"description,omitempty"`
+	LoadState     string `json:"load_state"`                // loaded, not-found, masked, ...
+	ActiveState   string `json:"active_state"`              // active, inactive, failed, activating, ...
+	SubState      string `json:"sub_state"`                 // running, exited, dead, ...
+	UnitFileState string `json:"unit_file_state,omitempty"` // enabled, disabled, static, ...
+	Since         string `json:"since,omitempty"`           // when it entered its active state
+	MainPID       uint32 `json:"main_pid,omitempty"`
+	Restarts      uint32 `json:"restarts,omitempty"`
+	Result        string `json:"result,omitempty"` // success, or why it last failed
+	MemoryBytes   uint64 `json:"memory_bytes,omitempty"`
+}
+
+// systemd talks to the service manager over D-Bus
+type systemd struct {
+	conn *dbus.Conn
+}
+
+func newSystemd(user bool) (*systemd, error) {
+	connect, bus := dbus.ConnectSystemBus, "system"
+	if user {
+		connect, bus = dbus.ConnectSessionBus, "session"
+	}
+	conn, err := connect()
+	if err != nil {
+		return nil, fmt.Errorf("no D-Bus %s bus: %w", bus, err)
+	}
+	// Without a subscriber systemd doesn't send the JobRemoved signals
+	// waited on for each change
+	if err := conn.Object(systemdService, systemdPath).Call(managerIface+".Subscribe", 0).Err; err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("systemd isn't running: %w", err)
+	}
+	err = conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(systemdPath),
+		dbus.WithMatchInterface(managerIface),
+		dbus.WithMatchMember("JobRemoved"),
+	)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &systemd{conn: conn}, nil
+}
+
+func (s *systemd) status(ctx context.Context, unit string) (Status, error) {
+	var p dbus.ObjectPath
+	// LoadUnit, unlike GetUnit, also finds units that aren't loaded
+	// because they are stopped
+	if err := s.conn.Object(systemdService, systemdPath).CallWithContext(ctx, managerIface+".LoadUnit", 0, unit).Store(&p); err != nil {
+		return Status{}, systemdError(err)
+	}
+	obj := s.conn.Object(systemdService, p)
+	var props map[string]dbus.Variant
+	if err := obj.CallWithContext(ctx, "org.freedesktop.DBus.Properties.GetAll", 0, unitIface).Store(&props); err != nil {
+		return Status{}, systemdError(err)
+	}
+	st := Status{Unit: unit}
+	st.Description, _ = props["Description"].Value().(string)
+	st.LoadState, _ = props["LoadState"].Value().(string)
+	st.ActiveState, _ = props["ActiveState"].Value().(string)
+	st.SubState, _ = props["SubState"].Value().(string)
+	st.UnitFileState, _ = props["UnitFileState"].Value().(string)
+	if usec, _ := props["ActiveEnterTimestamp"].Value().(uint64); usec > 0 && st.ActiveState == "active" {
+		st.Since = time.UnixMicro(int64(usec)).Format(time.RFC3339)
+	}
+	if !strings.HasSuffix(unit, ".service") || st.LoadState != "loaded" {
+		return st, nil
+	}
+	if err := obj.CallWithContext(ctx, "org.freedesktop.DBus.Properties.GetAll", 0, serviceIface).Store(&props); err != nil {
+		return st, nil
+	}
+	st.MainPID, _ = props["MainPID"].Value().(uint32)
+	st.Restarts, _ = props["NRestarts"].Value().(uint32)
+	st.Result, _ = props["Result"].Value().(string)
+	// The maximum means memory isn't accounted
+	if mem, _ := props["MemoryCurrent"].Value().(uint64); mem != math.MaxUint64 {
+		st.MemoryBytes = mem
+	}
+	return st, nil
+}
+
+// run starts, stops, or restarts a unit and waits for the job to finish
+func (s *systemd) run(ctx context.Context, method, unit string) error {
+	// Listen before queuing the job so its end isn't missed
+	signals := make(chan *dbus.Signal, 64)
+	s.conn.Signal(signals)
+	defer s.conn.RemoveSignal(signals)
+
+	var job dbus.ObjectPath
+	if err := s.conn.Object(systemdService, systemdPath).CallWithContext(ctx, managerIface+"."+method, 0, unit, "replace").Store(&job); err != nil {
+		return systemdError(err)
+	}
+	for {
+		select {
+		case sig := <-signals:
+			// JobRemoved(id, job, unit, result)
+			if sig.Name != managerIface+".JobRemoved" || len(sig.Body) < 4 || sig.Body[1] != job {
+				continue
+			}
+			result, _ := sig.Body[3].(string)
+			return jobError(unit, result)
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return agenterrors.Newf(agenterrors.Timeout, "%s is still changing state; check service.status later", unit)
+			}
+			return agenterrors.Wrap(agenterrors.Cancelled, ctx.Err())
+		}
+	}
+}
+
+// jobError explains a job that didn't finish with "done"
+func jobError(unit, result string) error {
+	switch result {
+	case "done", "skipped":
+		return nil
+	case "canceled":
+		return agenterrors.Newf(agenterrors.Conflict, "the job for %s was replaced by another", unit)
+	case "timeout":
+		return agenterrors.Newf(agenterrors.Timeout, "%s timed out changing state", unit)
+	case "dependency":
+		return agenterrors.Newf(agenterrors.Unavailable, "a unit %s depends on failed", unit)
+	case "unsupported":
+		return agenterrors.Newf(agenterrors.Unsupported, "%s doesn't support that", unit)
+	}
+	return agenterrors.Newf(agenterrors.Unavailable, "%s failed (%s); see journalctl -u %s", unit, result, unit)
+}
+
+// systemdError gives a D-Bus error from systemd its code
+func systemdError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return agenterrors.Wrap(agenterrors.Timeout, err)
+	}
+	var e dbus.Error
+	if !errors.As(err, &e) {
+		return agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	msg := e.Name
+	if len(e.Body) > 0 {
+		if detail, ok := e.Body[0].(string); ok && detail != "" {
+			msg = detail
+		}
+	}
+	switch e.Name {
+	case "org.freedesktop.systemd1.NoSuchUnit", "org.freedesktop.systemd1.LoadFailed":
+		return agenterrors.New(agenterrors.NotFound, msg)
+	case "org.freedesktop.DBus.Error.AccessDenied", "org.freedesktop.DBus.Error.InteractiveAuthorizationRequired":
+		return agenterrors.New(agenterrors.Unauthorized, msg+" (the agent's user needs a polkit rule to manage units)")
+	case "org.freedesktop.systemd1.UnitMasked", "org.freedesktop.systemd1.OnlyByDependency":
+		return agenterrors.New(agenterrors.DeniedByPolicy, msg)
+	case "org.freedesktop.DBus.Error.InvalidArgs":
+		return agenterrors.New(agenterrors.InvalidParams, msg)
+	}
+	return agenterrors.New(agenterrors.Unavailable, msg)
+}
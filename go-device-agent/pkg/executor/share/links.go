@@ -0,0 +1,138 @@
+package share
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxLinks bounds how many links are live at once
+const maxLinks = 256
+
+// link is one short-lived payload
+type link struct {
+	data        []byte
+	contentType string
+	expires     time.Time
+	uses        int // remaining; the link is deleted at zero
+}
+
+// LinkServer serves short-lived, limited-use links under unguessable URLs.
+// Expired and used-up links answer 404 like ones that never existed.
+type LinkServer struct {
+	addr    string
+	baseURL string
+
+	mu    sync.Mutex
+	links map[string]*link
+}
+
+// NewLinkServer creates a server listening on addr (e.g. ":8098") once
+// started. baseURL is how others reach it, e.g. "http://192.168.1.10:8098".
+func NewLinkServer(addr, baseURL string) *LinkServer {
+	return &LinkServer{
+		addr:    addr,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		links:   make(map[string]*link),
+	}
+}
+
+// Start serves until ctx is cancelled
+func (s *LinkServer) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /s/{token}", s.handle)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	go server.Serve(listener)
+	return nil
+}
+
+func (s *LinkServer) handle(w http.ResponseWriter, r *http.Request) {
+	token := r.PathValue("token")
+	now := time.Now()
+
+	s.mu.Lock()
+	l, ok := s.links[token]
+	if ok && now.After(l.expires) {
+		delete(s.links, token)
+		ok = false
+	}
+	if ok {
+		l.uses--
+		if l.uses <= 0 {
+			delete(s.links, token)
+		}
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", l.contentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Write(l.data)
+}
+
+// create publishes data and returns its URL
+func (s *LinkServer) create(data []byte, contentType string, expires time.Time, uses int) (string, error) {
+	var raw [16]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw[:])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(time.Now())
+	if len(s.links) >= maxLinks {
+		s.evictSoonest()
+	}
+	s.links[token] = &link{data: data, contentType: contentType, expires: expires, uses: uses}
+	return s.baseURL + "/s/" + token, nil
+}
+
+// revoke deletes the link with the given URL, reporting whether it was live
+func (s *LinkServer) revoke(url string) bool {
+	token := strings.TrimPrefix(url, s.baseURL+"/s/")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.links[token]
+	delete(s.links, token)
+	return ok && time.Now().Before(l.expires)
+}
+
+// prune drops expired links. Callers hold s.mu.
+func (s *LinkServer) prune(now time.Time) {
+	for token, l := range s.links {
+		if now.After(l.expires) {
+			delete(s.links, token)
+		}
+	}
+}
+
+// evictSoonest drops the link closest to expiry. Callers hold s.mu.
+func (s *LinkServer) evictSoonest() {
+	var soonest string
+	for token, l := range s.links {
+		if soonest == "" || l.expires.Before(s.links[soonest].expires) {
+			soonest = token
+		}
+	}
+	delete(s.links, soonest)
+}
@@ -0,0 +1,331 @@
+// Package share generates QR codes and short-lived links for pairing and
+// sharing, e.g. guest Wi-Fi credentials shown on a photo frame
+package share
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image/png"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Screen shows generated images; the frame executor implements it
+type Screen interface {
+	// ShowFile shows the image at path on the named display (empty for the
+	// default) and returns the display's name
+	ShowFile(ctx context.Context, display, path string) (string, error)
+}
+
+// Config sets where generated images go and how long links live
+type Config struct {
+	// Dir holds QR images while they are on screen
+	Dir string
+
+	// LinkTTL is the default link lifetime (default 15m)
+	LinkTTL time.Duration
+
+	// MaxLinkTTL caps requested lifetimes (default 24h)
+	MaxLinkTTL time.Duration
+}
+
+// Limits on generated output
+const (
+	maxUses       = 100
+	maxShownFiles = 16
+	defaultScale  = 8
+	maxScale      = 32
+)
+
+// Executor handles share.qr, share.wifi, share.link, and share.revoke
+type Executor struct {
+	cfg    Config
+	links  *LinkServer
+	screen Screen
+
+	mu    sync.Mutex
+	shown []string
+}
+
+// NewExecutor creates a share executor. Without a link server only QR
+// codes are available.
+func NewExecutor(cfg Config, links *LinkServer) *Executor {
+	if cfg.LinkTTL <= 0 {
+		cfg.LinkTTL = 15 * time.Minute
+	}
+	if cfg.MaxLinkTTL <= 0 {
+		cfg.MaxLinkTTL = 24 * time.Hour
+	}
+	if cfg.LinkTTL > cfg.MaxLinkTTL {
+		cfg.LinkTTL = cfg.MaxLinkTTL
+	}
+	return &Executor{cfg: cfg, links: links}
+}
+
+// SetScreen lets codes be shown with the display parameter
+func (e *Executor) SetScreen(s Screen) {
+	e.screen = s
+}
+
+func (e *Executor) Name() string {
+	return "share"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"share.qr", "share.wifi", "share.link", "share.revoke"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{Network: e.links != nil, Write: []string{e.cfg.Dir}}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "share",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	var out struct {
+		Display string `param:"display"`
+		Scale   int    `param:"scale"`
+	}
+	if err := i.DecodeParams(&out); err != nil {
+		return fail(err)
+	}
+
+	var payload string
+	switch i.IntentType {
+	case "share.qr":
+		text, err := i.StringParam("text")
+		if err != nil {
+			return fail(err)
+		}
+		payload = text
+		result.Result = map[string]interface{}{}
+
+	case "share.wifi":
+		var params struct {
+			SSID     string `param:"ssid,required"`
+			Password string `param:"password"`
+			Security string `param:"security"`
+			Hidden   bool   `param:"hidden"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		payload = wifiPayload(params.SSID, params.Password, params.Security, params.Hidden)
+		result.Result = map[string]interface{}{"ssid": params.SSID}
+
+	case "share.link":
+		if e.links == nil {
+			return fail(agenterrors.New(agenterrors.Unavailable, "no link server configured"))
+		}
+		var params struct {
+			Text string        `param:"text,required"`
+			TTL  time.Duration `param:"ttl"`
+			Uses int           `param:"uses"`
+			QR   bool          `param:"qr"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		ttl := params.TTL
+		if ttl <= 0 {
+			ttl = e.cfg.LinkTTL
+		}
+		ttl = min(ttl, e.cfg.MaxLinkTTL)
+		// A link never outlives the intent that asked for it
+		expires := time.Now().Add(ttl)
+		if deadline, ok := i.Deadline(); ok && deadline.Before(expires) {
+			expires = deadline
+		}
+		uses := min(max(params.Uses, 1), maxUses)
+
+		url, err := e.links.create([]byte(params.Text), "text/plain; charset=utf-8", expires, uses)
+		if err != nil {
+			return fail(err)
+		}
+		result.Result = map[string]interface{}{
+			"url":        url,
+			"expires_at": expires.Format(time.RFC3339),
+			"uses":       uses,
+		}
+		if !params.QR && out.Display == "" {
+			result.Success = true
+			return result, nil
+		}
+		payload = url
+
+	case "share.revoke":
+		if e.links == nil {
+			return fail(agenterrors.New(agenterrors.Unavailable, "no link server configured"))
+		}
+		url, err := i.StringParam("url")
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{"url": url, "revoked": e.links.revoke(url)}
+		return result, nil
+
+	default:
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+		return result, nil
+	}
+
+	code, err := encodeQR([]byte(payload))
+	if err != nil {
+		return fail(agenterrors.Wrap(agenterrors.InvalidParams, err))
+	}
+	scale := out.Scale
+	if scale <= 0 {
+		scale = defaultScale
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, code.image(min(scale, maxScale))); err != nil {
+		return fail(err)
+	}
+	result.Result["format"] = "png"
+	result.Result["image"] = base64.StdEncoding.EncodeToString(buf.Bytes())
+	result.Result["modules"] = code.size
+
+	if out.Display != "" {
+		name, err := e.show(ctx, out.Display, buf.Bytes())
+		if err != nil {
+			return fail(err)
+		}
+		result.Result["display"] = name
+	}
+	result.Success = true
+	return result, nil
+}
+
+// show writes the image under Dir and puts it on screen, keeping only the
+// most recent files
+func (e *Executor) show(ctx context.Context, display string, image []byte) (string, error) {
+	if e.screen == nil {
+		return "", agenterrors.New(agenterrors.Unavailable, "no display configured for QR codes")
+	}
+	if e.cfg.Dir == "" {
+		return "", errors.New("share image directory not configured")
+	}
+	if err := os.MkdirAll(e.cfg.Dir, 0o700); err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(e.cfg.Dir, "qr-*.png")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(image)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	e.mu.Lock()
+	e.shown = append(e.shown, f.Name())
+	if len(e.shown) > maxShownFiles {
+		os.Remove(e.shown[0])
+		e.shown = e.shown[1:]
+	}
+	e.mu.Unlock()
+
+	return e.screen.ShowFile(ctx, display, f.Name())
+}
+
+// wifiPayload formats credentials the way phone cameras recognize
+func wifiPayload(ssid, password, security string, hidden bool) string {
+	escape := strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, `:`, `\:`, `"`, `\"`).Replace
+	switch strings.ToUpper(security) {
+	case "":
+		security = "WPA"
+		if password == "" {
+			security = "nopass"
+		}
+	case "NOPASS", "NONE", "OPEN":
+		security = "nopass"
+	default:
+		security = strings.ToUpper(security)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "WIFI:T:%s;S:%s;", security, escape(ssid))
+	if security != "nopass" {
+		fmt.Fprintf(&b, "P:%s;", escape(password))
+	}
+	if hidden {
+		b.WriteString("H:true;")
+	}
+	b.WriteString(";")
+	return b.String()
+}
+
+func (e *Executor) IsAvailable() bool {
+	return true
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"share.qr": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"text": {"type": "string", "minLength": 1, "maxLength": 213},
+				"display": {"type": "string"},
+				"scale": {"type": "integer", "minimum": 1, "maximum": 32}
+			},
+			"required": ["text"]
+		}`),
+		"share.wifi": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"ssid": {"type": "string", "minLength": 1, "maxLength": 32},
+				"password": {"type": "string", "maxLength": 63},
+				"security": {"type": "string", "enum": ["WPA", "WEP", "nopass"]},
+				"hidden": {"type": "boolean"},
+				"display": {"type": "string"},
+				"scale": {"type": "integer", "minimum": 1, "maximum": 32}
+			},
+			"required": ["ssid"]
+		}`),
+		"share.link": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"text": {"type": "string", "minLength": 1, "maxLength": 65536},
+				"ttl": {"type": "string"},
+				"uses": {"type": "integer", "minimum": 1, "maximum": 100},
+				"qr": {"type": "boolean"},
+				"display": {"type": "string"},
+				"scale": {"type": "integer", "minimum": 1, "maximum": 32}
+			},
+			"required": ["text"]
+		}`),
+		"share.revoke": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"url": {"type": "string", "minLength": 1}
+			},
+			"required": ["url"]
+		}`),
+	}
+}
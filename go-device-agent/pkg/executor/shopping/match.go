@@ -0,0 +1,96 @@
+package shopping
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	// matchThreshold is the similarity above which two names are the same item
+	matchThreshold = 0.8
+	// suggestThreshold is the similarity above which a name is worth suggesting
+	suggestThreshold = 0.5
+)
+
+// fillers are words speech recognition leaves around item names
+var fillers = map[string]bool{
+	"a": true, "an": true, "the": true, "some": true, "more": true, "of": true,
+}
+
+// bestMatch returns the index of the item that best matches name, or -1
+func bestMatch(items []Item, name string) int {
+	target := normalize(name)
+	best, bestScore := -1, 0.0
+	for idx, item := range items {
+		score := similarity(normalize(item.Name), target)
+		if score >= matchThreshold && score > bestScore {
+			best, bestScore = idx, score
+		}
+	}
+	return best
+}
+
+// normalize lowercases a name, drops filler words and punctuation, and
+// reduces each word to a rough singular form
+func normalize(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	kept := words[:0]
+	for _, w := range words {
+		if !fillers[w] {
+			kept = append(kept, singular(w))
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// singular strips common English plural endings
+func singular(w string) string {
+	switch {
+	case len(w) > 4 && strings.HasSuffix(w, "ies"):
+		return w[:len(w)-3] + "y"
+	case len(w) > 4 && (strings.HasSuffix(w, "oes") || strings.HasSuffix(w, "ches") ||
+		strings.HasSuffix(w, "shes") || strings.HasSuffix(w, "xes")):
+		return w[:len(w)-2]
+	case len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss"):
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+// similarity scores two normalized names from 0 to 1 by edit distance
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,307 @@
+// Package shopping keeps shopping lists on disk and matches spoken item
+// names loosely, so "add two apples" and "remove apple" hit the same entry
+package shopping
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// DefaultList is used when an intent names neither a list nor a user
+const DefaultList = "shared"
+
+// Item is one entry on a shopping list
+type Item struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Quantity float64   `json:"quantity"`
+	Unit     string    `json:"unit,omitempty"`
+	AddedBy  string    `json:"added_by,omitempty"`
+	AddedAt  time.Time `json:"added_at"`
+}
+
+// Executor handles shopping.add, shopping.list, and shopping.remove
+type Executor struct {
+	path    string
+	adapter SyncAdapter
+
+	mu    sync.Mutex
+	lists map[string][]Item
+}
+
+// NewExecutor loads the lists stored at path, starting empty if the file
+// doesn't exist yet
+func NewExecutor(path string) (*Executor, error) {
+	e := &Executor{
+		path:  path,
+		lists: make(map[string][]Item),
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return e, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &e.lists); err != nil {
+		return nil, fmt.Errorf("invalid shopping list file %s: %w", path, err)
+	}
+	return e, nil
+}
+
+// SetSyncAdapter mirrors changes to an external list service. The local
+// file stays the source of truth; sync failures are reported as warnings.
+func (e *Executor) SetSyncAdapter(adapter SyncAdapter) {
+	e.adapter = adapter
+}
+
+func (e *Executor) Name() string {
+	return "shopping"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"shopping.add", "shopping.list", "shopping.remove"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{Network: e.adapter != nil, Write: []string{filepath.Dir(e.path)}}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "shopping",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	var params struct {
+		Item     string  `param:"item"`
+		Quantity float64 `param:"quantity"`
+		Unit     string  `param:"unit"`
+		List     string  `param:"list"`
+		User     string  `param:"user"`
+	}
+	if err := i.DecodeParams(&params); err != nil {
+		result.Fail(err)
+		return result, nil
+	}
+
+	// Each user gets their own list unless a list is named explicitly
+	list := params.List
+	if list == "" {
+		list = params.User
+	}
+	if list == "" {
+		list = DefaultList
+	}
+	list = strings.ToLower(strings.TrimSpace(list))
+
+	var change func(context.Context, SyncAdapter) error
+	switch i.IntentType {
+	case "shopping.add":
+		quantity := params.Quantity
+		if quantity <= 0 {
+			quantity = 1
+		}
+		item, merged, err := e.add(list, params.Item, quantity, params.Unit, params.User)
+		if err != nil {
+			return nil, err
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"list":   list,
+			"item":   item,
+			"merged": merged,
+		}
+		change = func(ctx context.Context, a SyncAdapter) error { return a.Put(ctx, list, item) }
+
+	case "shopping.list":
+		items := e.items(list)
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"list":  list,
+			"items": items,
+			"count": len(items),
+		}
+
+	case "shopping.remove":
+		item, remaining, err := e.remove(list, params.Item, params.Quantity)
+		if errors.Is(err, errNoMatch) {
+			result.Fail(agenterrors.Newf(agenterrors.NotFound, "'%s' is not on the %s list", params.Item, list))
+			if suggestions := e.suggest(list, params.Item); len(suggestions) > 0 {
+				result.Result = map[string]interface{}{"suggestions": suggestions}
+			}
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"list":    list,
+			"removed": item,
+		}
+		if remaining != nil {
+			result.Result["remaining"] = remaining
+			change = func(ctx context.Context, a SyncAdapter) error { return a.Put(ctx, list, *remaining) }
+		} else {
+			change = func(ctx context.Context, a SyncAdapter) error { return a.Delete(ctx, list, item) }
+		}
+
+	default:
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+		return result, nil
+	}
+
+	if change != nil && e.adapter != nil {
+		if err := change(ctx, e.adapter); err != nil {
+			result.Result["warnings"] = []string{fmt.Sprintf("%s sync failed: %v", e.adapter.Name(), err)}
+		}
+	}
+	return result, nil
+}
+
+func (e *Executor) IsAvailable() bool {
+	return e.path != ""
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"shopping.add": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"item": {"type": "string", "minLength": 1},
+				"quantity": {"type": "number", "minimum": 0},
+				"unit": {"type": "string"},
+				"list": {"type": "string"},
+				"user": {"type": "string"}
+			},
+			"required": ["item"]
+		}`),
+		"shopping.list": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"list": {"type": "string"},
+				"user": {"type": "string"}
+			}
+		}`),
+		"shopping.remove": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"item": {"type": "string", "minLength": 1},
+				"quantity": {"type": "number", "minimum": 0},
+				"list": {"type": "string"},
+				"user": {"type": "string"}
+			},
+			"required": ["item"]
+		}`),
+	}
+}
+
+var errNoMatch = errors.New("no matching item")
+
+// add puts an item on the list, merging it into an existing entry for the
+// same thing in the same unit
+func (e *Executor) add(list, name string, quantity float64, unit, user string) (Item, bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	items := e.lists[list]
+	if idx := bestMatch(items, name); idx >= 0 && strings.EqualFold(items[idx].Unit, unit) {
+		items[idx].Quantity += quantity
+		return items[idx], true, e.save()
+	}
+
+	item := Item{
+		ID:       events.NewID(),
+		Name:     strings.TrimSpace(name),
+		Quantity: quantity,
+		Unit:     unit,
+		AddedBy:  user,
+		AddedAt:  time.Now(),
+	}
+	e.lists[list] = append(items, item)
+	return item, false, e.save()
+}
+
+// remove takes an item off the list. A quantity smaller than what's on the
+// list only reduces it, in which case the remaining entry is returned.
+func (e *Executor) remove(list, name string, quantity float64) (Item, *Item, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	items := e.lists[list]
+	idx := bestMatch(items, name)
+	if idx < 0 {
+		return Item{}, nil, errNoMatch
+	}
+
+	item := items[idx]
+	if quantity > 0 && quantity < item.Quantity {
+		items[idx].Quantity -= quantity
+		item.Quantity = quantity
+		remaining := items[idx]
+		return item, &remaining, e.save()
+	}
+
+	e.lists[list] = append(items[:idx], items[idx+1:]...)
+	if len(e.lists[list]) == 0 {
+		delete(e.lists, list)
+	}
+	return item, nil, e.save()
+}
+
+func (e *Executor) items(list string) []Item {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	items := append([]Item(nil), e.lists[list]...)
+	sort.Slice(items, func(a, b int) bool { return items[a].AddedAt.Before(items[b].AddedAt) })
+	return items
+}
+
+// suggest lists item names that loosely resemble name, for "did you mean"
+func (e *Executor) suggest(list, name string) []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var names []string
+	for _, item := range e.lists[list] {
+		if similarity(normalize(item.Name), normalize(name)) >= suggestThreshold {
+			names = append(names, item.Name)
+		}
+	}
+	return names
+}
+
+// save writes the lists atomically so a crash never leaves a torn file
+func (e *Executor) save() error {
+	data, err := json.MarshalIndent(e.lists, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(e.path), 0o700); err != nil {
+		return err
+	}
+	tmp := e.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, e.path)
+}
@@ -0,0 +1,104 @@
+package shopping
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+)
+
+// SyncAdapter mirrors list changes to an external service such as a CalDAV
+// task list, Bring, or Todoist. Put is called for new and updated items.
+type SyncAdapter interface {
+	Name() string
+	Put(ctx context.Context, list string, item Item) error
+	Delete(ctx context.Context, list string, item Item) error
+}
+
+// CalDAV stores each item as a VTODO in a CalDAV task collection, one
+// collection per list under BaseURL (e.g. https://dav.example.com/tasks/)
+type CalDAV struct {
+	BaseURL  string
+	Username string
+	Password string
+	Client   *http.Client
+}
+
+// Name implements SyncAdapter
+func (c *CalDAV) Name() string {
+	return "caldav"
+}
+
+// Put implements SyncAdapter
+func (c *CalDAV) Put(ctx context.Context, list string, item Item) error {
+	return c.do(ctx, http.MethodPut, list, item, []byte(vtodo(item)))
+}
+
+// Delete implements SyncAdapter
+func (c *CalDAV) Delete(ctx context.Context, list string, item Item) error {
+	return c.do(ctx, http.MethodDelete, list, item, nil)
+}
+
+func (c *CalDAV) do(ctx context.Context, method, list string, item Item, body []byte) error {
+	target := strings.TrimSuffix(c.BaseURL, "/") + "/" + url.PathEscape(list) + "/" + item.ID + ".ics"
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "text/calendar; charset=utf-8")
+	}
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	client := c.Client
+	if client == nil {
+		client = connpool.Default.HTTP(10 * time.Second)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	// A task that is already gone is as good as deleted
+	if method == http.MethodDelete && resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s returned %s", method, target, resp.Status)
+	}
+	return nil
+}
+
+// vtodo renders an item as an iCalendar VTODO
+func vtodo(item Item) string {
+	summary := item.Name
+	if item.Quantity != 1 || item.Unit != "" {
+		summary = strings.TrimSpace(strconv.FormatFloat(item.Quantity, 'f', -1, 64)+" "+item.Unit) + " " + item.Name
+	}
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	var b strings.Builder
+	b.WriteString("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//local-agent-core//shopping//EN\r\n")
+	b.WriteString("BEGIN:VTODO\r\n")
+	b.WriteString("UID:" + item.ID + "\r\n")
+	b.WriteString("DTSTAMP:" + stamp + "\r\n")
+	b.WriteString("CREATED:" + item.AddedAt.UTC().Format("20060102T150405Z") + "\r\n")
+	b.WriteString("SUMMARY:" + escapeText(summary) + "\r\n")
+	b.WriteString("STATUS:NEEDS-ACTION\r\n")
+	b.WriteString("END:VTODO\r\nEND:VCALENDAR\r\n")
+	return b.String()
+}
+
+// escapeText escapes an iCalendar TEXT value
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,101 @@
+package sound
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+)
+
+// Source provides raw microphone audio as signed 16-bit little-endian mono PCM
+type Source interface {
+	Open(ctx context.Context, sampleRate int) (io.ReadCloser, error)
+	Available() bool
+}
+
+// Detection is a sound class recognised in a frame of audio
+type Detection struct {
+	Label      string  `json:"label"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Classifier recognises sound classes (e.g. "cry", "glass_break") in audio.
+// Implementations wrap a lightweight local model; audio never leaves the device.
+type Classifier interface {
+	Classify(samples []int16, sampleRate int) []Detection
+}
+
+// commandSource captures audio with ALSA's arecord
+type commandSource struct {
+	device string
+}
+
+// NewALSASource returns a source that records from an ALSA device via arecord
+func NewALSASource(device string) Source {
+	if device == "" {
+		device = "default"
+	}
+	return &commandSource{device: device}
+}
+
+func (s *commandSource) Open(ctx context.Context, sampleRate int) (io.ReadCloser, error) {
+	cmd := exec.CommandContext(ctx, "arecord",
+		"-q", "-D", s.device, "-f", "S16_LE", "-c", "1", "-r", strconv.Itoa(sampleRate), "-t", "raw")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start arecord: %w", err)
+	}
+	return &commandReader{ReadCloser: stdout, cmd: cmd}, nil
+}
+
+func (s *commandSource) Available() bool {
+	_, err := exec.LookPath("arecord")
+	return err == nil
+}
+
+// commandReader reaps the recording process when closed
+type commandReader struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+func (r *commandReader) Close() error {
+	r.ReadCloser.Close()
+	r.cmd.Process.Kill()
+	r.cmd.Wait()
+	return nil
+}
+
+// readFrame reads one frame of samples from r
+func readFrame(r io.Reader, buf []byte, samples []int16) error {
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return err
+	}
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(buf[2*i:]))
+	}
+	return nil
+}
+
+// levelDBFS returns the RMS level of a frame in dBFS (0 is full scale)
+func levelDBFS(samples []int16) float64 {
+	if len(samples) == 0 {
+		return math.Inf(-1)
+	}
+	var sum float64
+	for _, s := range samples {
+		v := float64(s) / math.MaxInt16
+		sum += v * v
+	}
+	rms := math.Sqrt(sum / float64(len(samples)))
+	if rms == 0 {
+		return math.Inf(-1)
+	}
+	return 20 * math.Log10(rms)
+}
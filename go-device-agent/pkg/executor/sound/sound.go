@@ -0,0 +1,336 @@
+// Package sound monitors a microphone for loud noises and classified sound
+// events (a crying baby, breaking glass) and reports them as events.
+// Raw audio is never kept or returned unless explicitly allowed.
+package sound
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+)
+
+// maxRecentEvents bounds the events kept for sound.events
+const maxRecentEvents = 20
+
+// Config configures the sound monitor
+type Config struct {
+	// Device is the ALSA capture device (default "default")
+	Device string
+
+	// SampleRate in Hz (default 16000)
+	SampleRate int
+
+	// Threshold is the loudness in dBFS that triggers a sound.loud event (default -20)
+	Threshold float64
+
+	// Cooldown is the minimum time between events with the same label (default 30s)
+	Cooldown time.Duration
+
+	// AllowRawAudio permits sound.clip to return recorded audio
+	AllowRawAudio bool
+
+	// ClipSeconds is how much audio sound.clip can return (default 5)
+	ClipSeconds int
+}
+
+// Executor handles sound.* actions and runs the background monitor
+type Executor struct {
+	cfg        Config
+	bus        *events.Bus
+	source     Source
+	classifier Classifier
+	privacy    func() bool
+
+	mu        sync.Mutex
+	running   bool
+	level     float64
+	lastError string
+	lastFired map[string]time.Time
+	recent    []events.Event
+	clip      []int16
+	clipPos   int
+	clipFull  bool
+}
+
+// NewExecutor creates a sound executor publishing events to bus
+func NewExecutor(cfg Config, bus *events.Bus) *Executor {
+	if cfg.SampleRate <= 0 {
+		cfg.SampleRate = 16000
+	}
+	if cfg.Threshold == 0 {
+		cfg.Threshold = -20
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 30 * time.Second
+	}
+	if cfg.ClipSeconds <= 0 {
+		cfg.ClipSeconds = 5
+	}
+
+	e := &Executor{
+		cfg:       cfg,
+		bus:       bus,
+		source:    NewALSASource(cfg.Device),
+		level:     math.Inf(-1),
+		lastFired: make(map[string]time.Time),
+	}
+	if cfg.AllowRawAudio {
+		e.clip = make([]int16, cfg.SampleRate*cfg.ClipSeconds)
+	}
+	return e
+}
+
+// SetSource replaces the audio source
+func (e *Executor) SetSource(source Source) {
+	e.source = source
+}
+
+// SetClassifier sets the local model used to classify sounds
+func (e *Executor) SetClassifier(classifier Classifier) {
+	e.classifier = classifier
+}
+
+// SetPrivacyCheck sets a function reporting whether privacy mode is on.
+// While it returns true audio is discarded unread and clips are refused.
+func (e *Executor) SetPrivacyCheck(check func() bool) {
+	e.privacy = check
+}
+
+// Start begins monitoring in the background until ctx is cancelled
+func (e *Executor) Start(ctx context.Context) error {
+	e.mu.Lock()
+	if e.running {
+		e.mu.Unlock()
+		return errors.New("sound monitor already running")
+	}
+	e.running = true
+	e.lastError = ""
+	e.mu.Unlock()
+
+	stream, err := e.source.Open(ctx, e.cfg.SampleRate)
+	if err != nil {
+		e.stopped(err)
+		return err
+	}
+
+	go func() {
+		defer stream.Close()
+
+		samples := make([]int16, e.cfg.SampleRate/10) // 100ms frames
+		buf := make([]byte, 2*len(samples))
+		for {
+			if err := readFrame(stream, buf, samples); err != nil {
+				if ctx.Err() != nil {
+					err = nil
+				} else if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+					err = errors.New("audio stream ended")
+				}
+				e.stopped(err)
+				return
+			}
+			e.analyze(samples)
+		}
+	}()
+	return nil
+}
+
+func (e *Executor) stopped(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.running = false
+	e.level = math.Inf(-1)
+	if err != nil {
+		e.lastError = err.Error()
+	}
+}
+
+func (e *Executor) inPrivacyMode() bool {
+	return e.privacy != nil && e.privacy()
+}
+
+// analyze processes one frame of audio
+func (e *Executor) analyze(samples []int16) {
+	if e.inPrivacyMode() {
+		e.mu.Lock()
+		e.level = math.Inf(-1)
+		e.clipPos, e.clipFull = 0, false
+		e.mu.Unlock()
+		return
+	}
+
+	level := levelDBFS(samples)
+
+	e.mu.Lock()
+	e.level = level
+	if e.clip != nil {
+		for _, s := range samples {
+			e.clip[e.clipPos] = s
+			e.clipPos = (e.clipPos + 1) % len(e.clip)
+			if e.clipPos == 0 {
+				e.clipFull = true
+			}
+		}
+	}
+	e.mu.Unlock()
+
+	if level >= e.cfg.Threshold {
+		e.emit("sound.loud", "loud", map[string]interface{}{
+			"level_db": roundLevel(level),
+		})
+	}
+
+	if e.classifier != nil {
+		for _, d := range e.classifier.Classify(samples, e.cfg.SampleRate) {
+			e.emit("sound.detected", d.Label, map[string]interface{}{
+				"label":      d.Label,
+				"confidence": d.Confidence,
+				"level_db":   roundLevel(level),
+			})
+		}
+	}
+}
+
+// emit publishes an event unless one with the same label fired recently
+func (e *Executor) emit(eventType, label string, data map[string]interface{}) {
+	now := time.Now()
+
+	e.mu.Lock()
+	if last, ok := e.lastFired[label]; ok && now.Sub(last) < e.cfg.Cooldown {
+		e.mu.Unlock()
+		return
+	}
+	e.lastFired[label] = now
+
+	event := events.Event{
+		ID:        events.NewID(),
+		Type:      eventType,
+		Source:    "sound",
+		Data:      data,
+		Timestamp: now,
+	}
+	e.recent = append(e.recent, event)
+	if len(e.recent) > maxRecentEvents {
+		e.recent = e.recent[len(e.recent)-maxRecentEvents:]
+	}
+	e.mu.Unlock()
+
+	if e.bus != nil {
+		e.bus.Publish(event)
+	}
+}
+
+func (e *Executor) Name() string {
+	return "sound"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"sound.status", "sound.events", "sound.clip"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{Subprocess: true} // arecord
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "sound",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	switch i.IntentType {
+	case "sound.status":
+		status := map[string]interface{}{
+			"monitoring":   e.running,
+			"privacy":      e.inPrivacyMode(),
+			"threshold_db": e.cfg.Threshold,
+			"level_db":     nil,
+		}
+		if !math.IsInf(e.level, -1) {
+			status["level_db"] = roundLevel(e.level)
+		}
+		if e.lastError != "" {
+			status["last_error"] = e.lastError
+		}
+		result.Success = true
+		result.Result = status
+
+	case "sound.events":
+		recent := make([]events.Event, len(e.recent))
+		copy(recent, e.recent)
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"events": recent,
+		}
+
+	case "sound.clip":
+		switch {
+		case !e.cfg.AllowRawAudio:
+			result.Fail(agenterrors.New(agenterrors.DeniedByPolicy, "raw audio access is disabled"))
+		case !i.RequiresPermission:
+			result.Fail(agenterrors.New(agenterrors.Unauthorized, "raw audio requires an intent with requires_permission set"))
+		case e.inPrivacyMode():
+			result.Fail(agenterrors.New(agenterrors.DeniedByPolicy, "raw audio is unavailable in privacy mode"))
+		default:
+			audio := e.clipBytes()
+			result.Success = true
+			result.Result = map[string]interface{}{
+				"format":      "s16le",
+				"channels":    1,
+				"sample_rate": e.cfg.SampleRate,
+				"seconds":     float64(len(audio)/2) / float64(e.cfg.SampleRate),
+				"audio":       base64.StdEncoding.EncodeToString(audio),
+			}
+		}
+
+	default:
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+
+	return result, nil
+}
+
+// PermissionRequired marks raw audio as needing requires_permission
+func (e *Executor) PermissionRequired() []string {
+	return []string{"sound.clip"}
+}
+
+func (e *Executor) IsAvailable() bool {
+	return e.source != nil && e.source.Available()
+}
+
+// clipBytes returns the buffered audio oldest-first. Callers hold e.mu.
+func (e *Executor) clipBytes() []byte {
+	var ordered []int16
+	if e.clipFull {
+		ordered = append(append(ordered, e.clip[e.clipPos:]...), e.clip[:e.clipPos]...)
+	} else {
+		ordered = e.clip[:e.clipPos]
+	}
+
+	out := make([]byte, 2*len(ordered))
+	for i, s := range ordered {
+		binary.LittleEndian.PutUint16(out[2*i:], uint16(s))
+	}
+	return out
+}
+
+func roundLevel(level float64) float64 {
+	return math.Round(level*10) / 10
+}
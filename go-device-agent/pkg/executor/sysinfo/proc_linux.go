@@ -0,0 +1,320 @@
+package sysinfo
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// readCPU sums the first line of /proc/stat; iowait counts as idle
+func readCPU() (cpuTimes, error) {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return cpuTimes{}, agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	line, _, _ := strings.Cut(string(data), "\n")
+	fields := strings.Fields(line)
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return cpuTimes{}, agenterrors.New(agenterrors.Internal, "unexpected /proc/stat format")
+	}
+	var t cpuTimes
+	// user nice system idle iowait irq softirq steal; guest time is
+	// already counted in user
+	for n, f := range fields[1:min(len(fields), 9)] {
+		v, _ := strconv.ParseUint(f, 10, 64)
+		t.total += v
+		if n == 3 || n == 4 {
+			t.idle += v
+		}
+	}
+	return t, nil
+}
+
+// readLoad returns the 1, 5, and 15 minute load averages
+func readLoad() ([]float64, error) {
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return nil, agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 3 {
+		return nil, agenterrors.New(agenterrors.Internal, "unexpected /proc/loadavg format")
+	}
+	load := make([]float64, 3)
+	for n := range load {
+		load[n], _ = strconv.ParseFloat(fields[n], 64)
+	}
+	return load, nil
+}
+
+func readMemory() (*Memory, error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return nil, agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	defer f.Close()
+	kb := make(map[string]float64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) > 0 {
+			kb[name], _ = strconv.ParseFloat(fields[0], 64)
+		}
+	}
+	total := kb["MemTotal"]
+	if total == 0 {
+		return nil, agenterrors.New(agenterrors.Internal, "unexpected /proc/meminfo format")
+	}
+	available, ok := kb["MemAvailable"]
+	if !ok {
+		// Kernels before 3.14
+		available = kb["MemFree"] + kb["Buffers"] + kb["Cached"]
+	}
+	swapUsed := kb["SwapTotal"] - kb["SwapFree"]
+	m := &Memory{
+		TotalMB:     round1(total / 1024),
+		AvailableMB: round1(available / 1024),
+		UsedMB:      round1((total - available) / 1024),
+		UsedPercent: round1(100 * (total - available) / total),
+		SwapTotalMB: round1(kb["SwapTotal"] / 1024),
+		SwapUsedMB:  round1(swapUsed / 1024),
+	}
+	if kb["SwapTotal"] > 0 {
+		m.SwapUsedPercent = round1(100 * swapUsed / kb["SwapTotal"])
+	}
+	return m, nil
+}
+
+// readDisks reports the given paths' filesystems and, with all, every
+// mounted block device (loop devices, like snaps, aside)
+func readDisks(paths []string, all bool) ([]Disk, error) {
+	type mount struct{ path, device, fs string }
+	var mounts []mount
+	seen := make(map[string]bool)
+	if all {
+		data, err := os.ReadFile("/proc/self/mounts")
+		if err != nil {
+			return nil, agenterrors.Wrap(agenterrors.Unavailable, err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 3 || !strings.HasPrefix(fields[0], "/dev/") || strings.HasPrefix(fields[0], "/dev/loop") {
+				continue
+			}
+			// A device mounted twice (bind mounts, btrfs subvolumes) is
+			// reported once
+			if seen[fields[0]] {
+				continue
+			}
+			seen[fields[0]] = true
+			path := strings.ReplaceAll(fields[1], `\040`, " ")
+			mounts = append(mounts, mount{path: path, device: fields[0], fs: fields[2]})
+		}
+	}
+	for _, p := range paths {
+		if !slices.ContainsFunc(mounts, func(m mount) bool { return m.path == p }) {
+			mounts = append(mounts, mount{path: p})
+		}
+	}
+
+	disks := make([]Disk, 0, len(mounts))
+	for _, m := range mounts {
+		var st unix.Statfs_t
+		if err := unix.Statfs(m.path, &st); err != nil {
+			if !all {
+				return nil, agenterrors.Newf(agenterrors.NotFound, "can't read the disk at %s: %v", m.path, err)
+			}
+			continue
+		}
+		size := float64(st.Bsize)
+		total := float64(st.Blocks) * size
+		if total == 0 {
+			continue
+		}
+		// As df: space reserved for root counts as neither free nor used
+		free := float64(st.Bavail) * size
+		used := total - float64(st.Bfree)*size
+		d := Disk{
+			Path:       m.path,
+			Device:     m.device,
+			Filesystem: m.fs,
+			TotalGB:    round1(total / (1 << 30)),
+			FreeGB:     round1(free / (1 << 30)),
+			UsedGB:     round1(used / (1 << 30)),
+		}
+		if used+free > 0 {
+			d.UsedPercent = round1(100 * used / (used + free))
+		}
+		disks = append(disks, d)
+	}
+	return disks, nil
+}
+
+func readBatteries() ([]Battery, error) {
+	dirs, _ := filepath.Glob("/sys/class/power_supply/*")
+	var batteries []Battery
+	for _, dir := range dirs {
+		if sysString(dir, "type") != "Battery" || sysString(dir, "present") == "0" {
+			continue
+		}
+		percent, ok := sysFloat(dir, "capacity")
+		if !ok {
+			continue
+		}
+		b := Battery{Name: filepath.Base(dir), Percent: percent, Status: sysString(dir, "status")}
+		// Energy in µWh with power in µW, or charge in µAh with current in µA
+		now, full, rate := "energy_now", "energy_full", "power_now"
+		if _, ok := sysFloat(dir, now); !ok {
+			now, full, rate = "charge_now", "charge_full", "current_now"
+		}
+		level, _ := sysFloat(dir, now)
+		capacity, _ := sysFloat(dir, full)
+		draw, _ := sysFloat(dir, rate)
+		if draw > 0 {
+			switch b.Status {
+			case "Discharging":
+				b.MinutesLeft = int(60 * level / draw)
+			case "Charging":
+				b.MinutesLeft = int(60 * (capacity - level) / draw)
+			}
+		}
+		batteries = append(batteries, b)
+	}
+	if len(batteries) == 0 {
+		return nil, agenterrors.New(agenterrors.NotFound, "this machine has no battery")
+	}
+	return batteries, nil
+}
+
+// readTemperatures reads hwmon sensors, which name what they measure,
+// falling back to ACPI thermal zones
+func readTemperatures() ([]Temperature, error) {
+	var temps []Temperature
+	inputs, _ := filepath.Glob("/sys/class/hwmon/hwmon*/temp*_input")
+	for _, input := range inputs {
+		dir := filepath.Dir(input)
+		millis, ok := sysFloat(dir, filepath.Base(input))
+		if !ok {
+			continue
+		}
+		sensor := sysString(dir, "name")
+		if label := sysString(dir, strings.TrimSuffix(filepath.Base(input), "_input")+"_label"); label != "" {
+			sensor += " " + label
+		}
+		temps = append(temps, Temperature{Sensor: sensor, Celsius: round1(millis / 1000)})
+	}
+	if len(temps) == 0 {
+		zones, _ := filepath.Glob("/sys/class/thermal/thermal_zone*")
+		for _, dir := range zones {
+			if millis, ok := sysFloat(dir, "temp"); ok {
+				temps = append(temps, Temperature{Sensor: sysString(dir, "type"), Celsius: round1(millis / 1000)})
+			}
+		}
+	}
+	if len(temps) == 0 {
+		return nil, agenterrors.New(agenterrors.NotFound, "no temperature sensors found")
+	}
+	return temps, nil
+}
+
+func readUptime() (time.Duration, error) {
+	data, err := os.ReadFile("/proc/uptime")
+	if err != nil {
+		return 0, agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, agenterrors.New(agenterrors.Internal, "unexpected /proc/uptime format")
+	}
+	secs, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, agenterrors.New(agenterrors.Internal, "unexpected /proc/uptime format")
+	}
+	return time.Duration(secs * float64(time.Second)), nil
+}
+
+// readNetCounters returns each interface's received and sent bytes
+func readNetCounters() map[string][2]uint64 {
+	data, err := os.ReadFile("/proc/net/dev")
+	if err != nil {
+		return nil
+	}
+	counters := make(map[string][2]uint64)
+	for _, line := range strings.Split(string(data), "\n") {
+		name, stats, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(stats)
+		if len(fields) < 9 {
+			continue
+		}
+		rx, _ := strconv.ParseUint(fields[0], 10, 64)
+		tx, _ := strconv.ParseUint(fields[8], 10, 64)
+		counters[strings.TrimSpace(name)] = [2]uint64{rx, tx}
+	}
+	return counters
+}
+
+func kernelVersion() string {
+	data, _ := os.ReadFile("/proc/sys/kernel/osrelease")
+	return strings.TrimSpace(string(data))
+}
+
+// distribution is os-release's PRETTY_NAME, e.g. "Debian GNU/Linux 12"
+func distribution() string {
+	data, err := os.ReadFile("/etc/os-release")
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, "PRETTY_NAME="); ok {
+			return strings.Trim(value, `"'`)
+		}
+	}
+	return ""
+}
+
+// cpuModel is the processor's name; ARM boards often give only the board
+// ("Model") or SoC ("Hardware")
+func cpuModel() string {
+	data, err := os.ReadFile("/proc/cpuinfo")
+	if err != nil {
+		return ""
+	}
+	found := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if key = strings.TrimSpace(key); ok && found[key] == "" {
+			found[key] = strings.TrimSpace(value)
+		}
+	}
+	for _, key := range []string{"model name", "Model", "Hardware", "cpu model"} {
+		if found[key] != "" {
+			return found[key]
+		}
+	}
+	return ""
+}
+
+func sysString(dir, name string) string {
+	data, _ := os.ReadFile(filepath.Join(dir, name))
+	return strings.TrimSpace(string(data))
+}
+
+func sysFloat(dir, name string) (float64, bool) {
+	v, err := strconv.ParseFloat(sysString(dir, name), 64)
+	return v, err == nil
+}
@@ -0,0 +1,58 @@
+//go:build !linux
+
+package sysinfo
+
+import (
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// Metrics come from /proc and /sys, so elsewhere only system.info's
+// portable fields and network addresses are reported
+
+var errUnsupported = agenterrors.New(agenterrors.Unsupported, "system metrics are only read on Linux")
+
+func readCPU() (cpuTimes, error) {
+	return cpuTimes{}, errUnsupported
+}
+
+func readLoad() ([]float64, error) {
+	return nil, errUnsupported
+}
+
+func readMemory() (*Memory, error) {
+	return nil, errUnsupported
+}
+
+func readDisks(paths []string, all bool) ([]Disk, error) {
+	return nil, errUnsupported
+}
+
+func readBatteries() ([]Battery, error) {
+	return nil, errUnsupported
+}
+
+func readTemperatures() ([]Temperature, error) {
+	return nil, errUnsupported
+}
+
+func readUptime() (time.Duration, error) {
+	return 0, errUnsupported
+}
+
+func readNetCounters() map[string][2]uint64 {
+	return nil
+}
+
+func kernelVersion() string {
+	return ""
+}
+
+func distribution() string {
+	return ""
+}
+
+func cpuModel() string {
+	return ""
+}
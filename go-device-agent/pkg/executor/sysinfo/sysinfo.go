@@ -0,0 +1,392 @@
+// Package sysinfo answers system.info and system.metrics with the host's
+// CPU, memory, disk, battery, temperature, uptime, and network figures,
+// read from /proc and /sys, and raises events when a metric crosses a
+// configured threshold
+package sysinfo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Config lists the disks to report and the thresholds to watch
+type Config struct {
+	// Disks are mount points always reported, in addition to the mounted
+	// block devices found
+	Disks []string
+
+	// Thresholds raise system.threshold events; see LoadThresholds
+	Thresholds []Threshold
+
+	// Interval is how often thresholds are checked (default 1m)
+	Interval time.Duration
+}
+
+// sections are what system.metrics can report
+var sections = []string{"cpu", "memory", "disk", "battery", "temperature", "network", "uptime"}
+
+// cpuSampleTime is how long system.metrics watches the CPU to measure its
+// load
+const cpuSampleTime = 250 * time.Millisecond
+
+// Memory is RAM and swap in megabytes
+type Memory struct {
+	TotalMB         float64 `json:"total_mb"`
+	AvailableMB     float64 `json:"available_mb"`
+	UsedMB          float64 `json:"used_mb"`
+	UsedPercent     float64 `json:"used_percent"`
+	SwapTotalMB     float64 `json:"swap_total_mb"`
+	SwapUsedMB      float64 `json:"swap_used_mb"`
+	SwapUsedPercent float64 `json:"swap_used_percent"`
+}
+
+// Disk is one mounted filesystem, sizes in gigabytes
+type Disk struct {
+	Path        string  `json:"path"`
+	Device      string  `json:"device,omitempty"`
+	Filesystem  string  `json:"filesystem,omitempty"`
+	TotalGB     float64 `json:"total_gb"`
+	FreeGB      float64 `json:"free_gb"`
+	UsedGB      float64 `json:"used_gb"`
+	UsedPercent float64 `json:"used_percent"`
+}
+
+// Battery is one battery's charge
+type Battery struct {
+	Name    string  `json:"name"`
+	Percent float64 `json:"percent"`
+	// Status is the kernel's: Charging, Discharging, Full, or Not charging
+	Status string `json:"status,omitempty"`
+	// MinutesLeft estimates time to empty, or to full while charging
+	MinutesLeft int `json:"minutes_left,omitempty"`
+}
+
+// Temperature is one sensor's reading
+type Temperature struct {
+	Sensor  string  `json:"sensor"`
+	Celsius float64 `json:"celsius"`
+}
+
+// Interface is one network interface
+type Interface struct {
+	Name      string   `json:"name"`
+	Up        bool     `json:"up"`
+	MAC       string   `json:"mac,omitempty"`
+	Addresses []string `json:"addresses,omitempty"`
+	RxBytes   uint64   `json:"rx_bytes,omitempty"`
+	TxBytes   uint64   `json:"tx_bytes,omitempty"`
+}
+
+// Metrics is a snapshot of the host; sections that weren't asked for or
+// can't be read on this platform are left out
+type Metrics struct {
+	CPUPercent   *float64      `json:"cpu_percent,omitempty"`
+	Load         []float64     `json:"load,omitempty"`
+	Memory       *Memory       `json:"memory,omitempty"`
+	Disks        []Disk        `json:"disks,omitempty"`
+	Batteries    []Battery     `json:"batteries,omitempty"`
+	Temperatures []Temperature `json:"temperatures,omitempty"`
+	Network      []Interface   `json:"network,omitempty"`
+	Uptime       string        `json:"uptime,omitempty"`
+	UptimeSecs   int64         `json:"uptime_seconds,omitempty"`
+}
+
+// Executor handles system.info and system.metrics
+type Executor struct {
+	cfg    Config
+	bus    *events.Bus
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	crossed map[string]bool
+	lastCPU cpuTimes
+}
+
+// NewExecutor creates a system information executor; bus may be nil when
+// no thresholds are watched
+func NewExecutor(cfg Config, bus *events.Bus) (*Executor, error) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	for _, t := range cfg.Thresholds {
+		if err := t.check(); err != nil {
+			return nil, err
+		}
+	}
+	return &Executor{cfg: cfg, bus: bus, logger: slog.Default(), crossed: make(map[string]bool)}, nil
+}
+
+// SetLogger sets where threshold check failures are logged
+func (e *Executor) SetLogger(l *slog.Logger) {
+	e.logger = l
+}
+
+func (e *Executor) Name() string {
+	return "sysinfo"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"system.info", "system.metrics"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "sysinfo",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	switch i.IntentType {
+	case "system.info":
+		result.Success = true
+		result.Result = info()
+		return result, nil
+
+	case "system.metrics":
+		var params struct {
+			Metrics []string `param:"metrics"`
+			Path    string   `param:"path"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		want := params.Metrics
+		if len(want) == 0 || slices.Contains(want, "all") {
+			want = sections
+		}
+		for _, s := range want {
+			if !slices.Contains(sections, s) {
+				return fail(agenterrors.Newf(agenterrors.InvalidParams, "unknown metric '%s' (one of %s)", s, strings.Join(sections, ", ")))
+			}
+		}
+		disks := e.cfg.Disks
+		if params.Path != "" {
+			disks = []string{params.Path}
+		}
+		m, err := collect(ctx, want, disks, params.Path == "")
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{"metrics": m}
+		return result, nil
+
+	default:
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+		return result, nil
+	}
+}
+
+// info describes the host: what it is rather than how busy it is
+func info() map[string]interface{} {
+	out := map[string]interface{}{
+		"os":   runtime.GOOS,
+		"arch": runtime.GOARCH,
+		"cpus": runtime.NumCPU(),
+	}
+	if name, err := os.Hostname(); err == nil {
+		out["hostname"] = name
+	}
+	if v := kernelVersion(); v != "" {
+		out["kernel"] = v
+	}
+	if v := distribution(); v != "" {
+		out["distribution"] = v
+	}
+	if v := cpuModel(); v != "" {
+		out["cpu_model"] = v
+	}
+	if mem, err := readMemory(); err == nil {
+		out["memory_total_mb"] = mem.TotalMB
+	}
+	if up, err := readUptime(); err == nil {
+		out["uptime"] = durationText(up)
+		out["booted_at"] = time.Now().Add(-up).Truncate(time.Second).Format(time.RFC3339)
+	}
+	if ifaces := interfaces(); len(ifaces) > 0 {
+		out["network"] = ifaces
+	}
+	return out
+}
+
+// collect reads the sections asked for. Disks are the paths given, plus
+// the mounted block devices with all. A section this platform can't read
+// is an error only when it was the one asked for.
+func collect(ctx context.Context, want []string, disks []string, all bool) (*Metrics, error) {
+	m := &Metrics{}
+	var firstErr error
+	note := func(err error) {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	for _, section := range want {
+		switch section {
+		case "cpu":
+			before, err := readCPU()
+			if err != nil {
+				note(err)
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return nil, agenterrors.Wrap(agenterrors.Cancelled, ctx.Err())
+			case <-time.After(cpuSampleTime):
+			}
+			after, err := readCPU()
+			if err != nil {
+				note(err)
+				continue
+			}
+			percent := round1(after.busySince(before))
+			m.CPUPercent = &percent
+			if load, err := readLoad(); err == nil {
+				m.Load = load
+			}
+		case "memory":
+			mem, err := readMemory()
+			note(err)
+			m.Memory = mem
+		case "disk":
+			list, err := readDisks(disks, all)
+			note(err)
+			m.Disks = list
+		case "battery":
+			list, err := readBatteries()
+			note(err)
+			m.Batteries = list
+		case "temperature":
+			list, err := readTemperatures()
+			note(err)
+			m.Temperatures = list
+		case "network":
+			m.Network = interfaces()
+		case "uptime":
+			up, err := readUptime()
+			if err != nil {
+				note(err)
+				continue
+			}
+			m.Uptime = durationText(up)
+			m.UptimeSecs = int64(up / time.Second)
+		}
+	}
+	if len(want) == 1 && firstErr != nil {
+		return nil, firstErr
+	}
+	return m, nil
+}
+
+// interfaces lists the network interfaces with their addresses and, where
+// the kernel reports them, traffic counters
+func interfaces() []Interface {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	counters := readNetCounters()
+	list := make([]Interface, 0, len(ifaces))
+	for _, ifc := range ifaces {
+		item := Interface{
+			Name: ifc.Name,
+			Up:   ifc.Flags&net.FlagUp != 0,
+			MAC:  ifc.HardwareAddr.String(),
+		}
+		if addrs, err := ifc.Addrs(); err == nil {
+			for _, a := range addrs {
+				item.Addresses = append(item.Addresses, a.String())
+			}
+		}
+		if c, ok := counters[ifc.Name]; ok {
+			item.RxBytes, item.TxBytes = c[0], c[1]
+		}
+		list = append(list, item)
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].Name < list[b].Name })
+	return list
+}
+
+// cpuTimes are the CPU's idle and total time since boot, in clock ticks
+type cpuTimes struct {
+	idle, total uint64
+}
+
+// busySince is the percentage of time the CPU was busy since an earlier
+// reading
+func (c cpuTimes) busySince(before cpuTimes) float64 {
+	total := c.total - before.total
+	if total == 0 || c.total < before.total {
+		return 0
+	}
+	return 100 * (1 - float64(c.idle-before.idle)/float64(total))
+}
+
+func round1(v float64) float64 {
+	return float64(int64(v*10+0.5)) / 10
+}
+
+// durationText writes an uptime like "3 days 4 hours 12 minutes"
+func durationText(d time.Duration) string {
+	days := int64(d / (24 * time.Hour))
+	hours := int64(d % (24 * time.Hour) / time.Hour)
+	minutes := int64(d % time.Hour / time.Minute)
+	var parts []string
+	for _, p := range []struct {
+		n    int64
+		unit string
+	}{{days, "day"}, {hours, "hour"}, {minutes, "minute"}} {
+		switch {
+		case p.n == 1:
+			parts = append(parts, "1 "+p.unit)
+		case p.n > 1:
+			parts = append(parts, fmt.Sprintf("%d %ss", p.n, p.unit))
+		}
+	}
+	if len(parts) == 0 {
+		return "less than a minute"
+	}
+	return strings.Join(parts, " ")
+}
+
+func (e *Executor) IsAvailable() bool {
+	return true
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"system.info": schema.MustParse(`{"type": "object", "properties": {}}`),
+		"system.metrics": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"metrics": {"type": "array", "items": {"type": "string", "enum": ["all", "cpu", "memory", "disk", "battery", "temperature", "network", "uptime"]}},
+				"path": {"type": "string"}
+			}
+		}`),
+	}
+}
@@ -0,0 +1,303 @@
+package sysinfo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+)
+
+// thresholdMetrics are the values a threshold can watch
+var thresholdMetrics = []string{
+	"cpu.percent", "load.1m",
+	"memory.used_percent", "memory.available_mb", "swap.used_percent",
+	"disk.used_percent", "disk.free_gb",
+	"battery.percent", "temperature.celsius",
+}
+
+// Threshold raises system.threshold when a metric goes above or below a
+// limit, and system.threshold_cleared when it comes back
+type Threshold struct {
+	Name   string `json:"name"`
+	Metric string `json:"metric"`
+
+	// Target picks the disk's mount point (default "/"), the battery, or
+	// the temperature sensor (default the hottest)
+	Target string `json:"target,omitempty"`
+
+	Above *float64 `json:"above,omitempty"`
+	Below *float64 `json:"below,omitempty"`
+
+	// Hysteresis is how far back past the limit the metric must go to
+	// clear, so a value hovering at the limit doesn't raise it repeatedly
+	Hysteresis float64 `json:"hysteresis,omitempty"`
+
+	// Intents are run when the threshold is crossed, e.g. to clear a cache
+	// when the disk fills; the agent's main wires them up
+	Intents []Action `json:"intents,omitempty"`
+}
+
+// Action is an intent to run, without the envelope
+type Action struct {
+	IntentType string                 `json:"intent_type"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// LoadThresholds reads a JSON list of thresholds
+func LoadThresholds(path string) ([]Threshold, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var thresholds []Threshold
+	if err := json.Unmarshal(data, &thresholds); err != nil {
+		return nil, fmt.Errorf("invalid thresholds file %s: %w", path, err)
+	}
+	seen := make(map[string]bool, len(thresholds))
+	for _, t := range thresholds {
+		if err := t.check(); err != nil {
+			return nil, fmt.Errorf("invalid thresholds file %s: %w", path, err)
+		}
+		if seen[t.Name] {
+			return nil, fmt.Errorf("invalid thresholds file %s: threshold %q is listed twice", path, t.Name)
+		}
+		seen[t.Name] = true
+	}
+	return thresholds, nil
+}
+
+func (t Threshold) check() error {
+	if t.Name == "" {
+		return fmt.Errorf("threshold missing a name")
+	}
+	if !slices.Contains(thresholdMetrics, t.Metric) {
+		return fmt.Errorf("threshold %q: unknown metric %q (one of %s)", t.Name, t.Metric, strings.Join(thresholdMetrics, ", "))
+	}
+	if (t.Above == nil) == (t.Below == nil) {
+		return fmt.Errorf("threshold %q: give above or below", t.Name)
+	}
+	if t.Hysteresis < 0 {
+		return fmt.Errorf("threshold %q: hysteresis can't be negative", t.Name)
+	}
+	return nil
+}
+
+// Thresholds returns the thresholds being watched
+func (e *Executor) Thresholds() []Threshold {
+	return e.cfg.Thresholds
+}
+
+// Start checks the thresholds every Interval until ctx is done
+func (e *Executor) Start(ctx context.Context) {
+	if len(e.cfg.Thresholds) == 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(e.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			e.CheckThresholds(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// CheckThresholds reads the watched metrics once, publishing
+// system.threshold for thresholds newly crossed and
+// system.threshold_cleared for those no longer crossed
+func (e *Executor) CheckThresholds(ctx context.Context) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s := &sample{}
+	for _, t := range e.cfg.Thresholds {
+		value, err := e.read(ctx, s, t)
+		if err != nil {
+			e.logger.Warn("Threshold check failed", "threshold", t.Name, "error", err)
+			continue
+		}
+		limit, above := 0.0, t.Above != nil
+		if above {
+			limit = *t.Above
+		} else {
+			limit = *t.Below
+		}
+		beyond := value > limit
+		clear := value <= limit-t.Hysteresis
+		if !above {
+			beyond = value < limit
+			clear = value >= limit+t.Hysteresis
+		}
+
+		data := map[string]interface{}{
+			"name":   t.Name,
+			"metric": t.Metric,
+			"value":  value,
+			"limit":  limit,
+		}
+		if t.Target != "" {
+			data["target"] = t.Target
+		}
+		switch {
+		case beyond && !e.crossed[t.Name]:
+			e.crossed[t.Name] = true
+			direction := "below"
+			if above {
+				direction = "above"
+			}
+			data["direction"] = direction
+			data["message"] = fmt.Sprintf("%s: %s is %g, %s %g", t.Name, t.label(), value, direction, limit)
+			e.publish("system.threshold", data)
+		case clear && e.crossed[t.Name]:
+			delete(e.crossed, t.Name)
+			data["message"] = fmt.Sprintf("%s cleared: %s is back to %g", t.Name, t.label(), value)
+			e.publish("system.threshold_cleared", data)
+		}
+	}
+}
+
+// label names the metric and its target, e.g. "disk.free_gb on /home"
+func (t Threshold) label() string {
+	if t.Target == "" {
+		return t.Metric
+	}
+	return t.Metric + " on " + t.Target
+}
+
+func (e *Executor) publish(eventType string, data map[string]interface{}) {
+	if e.bus == nil {
+		return
+	}
+	e.bus.Publish(events.Event{Type: eventType, Source: "sysinfo", Data: data})
+}
+
+// sample caches what one check has read, so thresholds sharing a section
+// read it once
+type sample struct {
+	cpu          *float64
+	memory       *Memory
+	load         []float64
+	batteries    []Battery
+	temperatures []Temperature
+	disks        map[string]Disk
+}
+
+// read returns a threshold's current value. CPU load is averaged since the
+// previous check.
+func (e *Executor) read(ctx context.Context, s *sample, t Threshold) (float64, error) {
+	var err error
+	section, field, _ := strings.Cut(t.Metric, ".")
+	switch section {
+	case "cpu":
+		if s.cpu != nil {
+			return *s.cpu, nil
+		}
+		now, err := readCPU()
+		if err != nil {
+			return 0, err
+		}
+		if e.lastCPU == (cpuTimes{}) {
+			e.lastCPU = now
+			select {
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			case <-time.After(cpuSampleTime):
+			}
+			if now, err = readCPU(); err != nil {
+				return 0, err
+			}
+		}
+		busy := round1(now.busySince(e.lastCPU))
+		e.lastCPU = now
+		s.cpu = &busy
+		return busy, nil
+
+	case "load":
+		if s.load == nil {
+			if s.load, err = readLoad(); err != nil {
+				return 0, err
+			}
+		}
+		return s.load[0], nil
+
+	case "memory", "swap":
+		if s.memory == nil {
+			if s.memory, err = readMemory(); err != nil {
+				return 0, err
+			}
+		}
+		switch t.Metric {
+		case "memory.used_percent":
+			return s.memory.UsedPercent, nil
+		case "memory.available_mb":
+			return s.memory.AvailableMB, nil
+		}
+		return s.memory.SwapUsedPercent, nil
+
+	case "disk":
+		path := t.Target
+		if path == "" {
+			path = "/"
+		}
+		d, ok := s.disks[path]
+		if !ok {
+			disks, err := readDisks([]string{path}, false)
+			if err != nil {
+				return 0, err
+			}
+			if len(disks) == 0 {
+				return 0, fmt.Errorf("no filesystem at %s", path)
+			}
+			d = disks[0]
+			if s.disks == nil {
+				s.disks = make(map[string]Disk)
+			}
+			s.disks[path] = d
+		}
+		if field == "free_gb" {
+			return d.FreeGB, nil
+		}
+		return d.UsedPercent, nil
+
+	case "battery":
+		if s.batteries == nil {
+			if s.batteries, err = readBatteries(); err != nil {
+				return 0, err
+			}
+		}
+		for _, b := range s.batteries {
+			if t.Target == "" || strings.EqualFold(b.Name, t.Target) {
+				return b.Percent, nil
+			}
+		}
+		return 0, fmt.Errorf("no battery named %s", t.Target)
+
+	default: // temperature
+		if s.temperatures == nil {
+			if s.temperatures, err = readTemperatures(); err != nil {
+				return 0, err
+			}
+		}
+		hottest, found := 0.0, false
+		for _, temp := range s.temperatures {
+			if t.Target != "" && !strings.EqualFold(temp.Sensor, t.Target) {
+				continue
+			}
+			if !found || temp.Celsius > hottest {
+				hottest, found = temp.Celsius, true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("no temperature sensor named %s", t.Target)
+		}
+		return hottest, nil
+	}
+}
@@ -0,0 +1,196 @@
+package system
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Command is one allowlisted command. Argv is run directly, never through a
+// shell; arguments may contain {{name}} placeholders, each filled from the
+// intent's args and checked against its Param.
+type Command struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+
+	// Argv is the program and its arguments; the program is resolved
+	// against PATH when the commands are loaded
+	Argv []string `json:"argv"`
+
+	// Params declares every placeholder used in Argv
+	Params map[string]Param `json:"params,omitempty"`
+
+	// Dir is the working directory, relative to the executor's root (or
+	// absolute but inside it); the root itself when empty
+	Dir string `json:"dir,omitempty"`
+
+	// Env adds variables to the minimal environment commands get
+	Env map[string]string `json:"env,omitempty"`
+
+	// Timeout kills the command's process group, as a Go duration such as
+	// "10m" (default 30s)
+	Timeout string `json:"timeout,omitempty"`
+
+	// MaxOutput caps the captured bytes of stdout and of stderr
+	// (default 64 KiB)
+	MaxOutput int `json:"max_output,omitempty"`
+
+	Limits Limits `json:"limits,omitzero"`
+
+	timeout time.Duration
+}
+
+// Param constrains a placeholder. Values must match Pattern in full or be
+// one of Enum; a placeholder without either is refused when loading.
+type Param struct {
+	Pattern string   `json:"pattern,omitempty"`
+	Enum    []string `json:"enum,omitempty"`
+
+	// Default fills the placeholder when the arg is missing; without one
+	// the arg is required
+	Default string `json:"default,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// Limits are resource limits applied to the command's process (Linux only)
+type Limits struct {
+	CPUSeconds uint64 `json:"cpu_seconds,omitempty"`
+	MemoryMB   uint64 `json:"memory_mb,omitempty"`
+	OpenFiles  uint64 `json:"open_files,omitempty"`
+	FileSizeMB uint64 `json:"file_size_mb,omitempty"`
+}
+
+// Defaults for commands that don't set them
+const (
+	defaultTimeout   = 30 * time.Second
+	defaultMaxOutput = 64 << 10
+)
+
+var placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// LoadCommands reads a JSON array of commands and checks them
+func LoadCommands(path string) ([]Command, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var commands []Command
+	if err := json.Unmarshal(data, &commands); err != nil {
+		return nil, fmt.Errorf("invalid commands file %s: %w", path, err)
+	}
+	seen := make(map[string]bool, len(commands))
+	for n := range commands {
+		c := &commands[n]
+		if err := c.prepare(); err != nil {
+			return nil, fmt.Errorf("invalid commands file %s: command %q: %w", path, c.Name, err)
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("invalid commands file %s: command %q is defined twice", path, c.Name)
+		}
+		seen[c.Name] = true
+	}
+	return commands, nil
+}
+
+// prepare resolves the program and compiles the parameter patterns
+func (c *Command) prepare() error {
+	if c.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+	if len(c.Argv) == 0 || c.Argv[0] == "" {
+		return fmt.Errorf("missing argv")
+	}
+	if placeholder.MatchString(c.Argv[0]) {
+		return fmt.Errorf("the program can't be a placeholder")
+	}
+	program, err := exec.LookPath(c.Argv[0])
+	if err != nil {
+		return err
+	}
+	c.Argv[0] = program
+
+	c.timeout = defaultTimeout
+	if c.Timeout != "" {
+		d, err := time.ParseDuration(c.Timeout)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("timeout must be a positive Go duration such as 10m")
+		}
+		c.timeout = d
+	}
+	if c.MaxOutput <= 0 {
+		c.MaxOutput = defaultMaxOutput
+	}
+
+	for name, p := range c.Params {
+		if p.Pattern == "" && len(p.Enum) == 0 {
+			return fmt.Errorf("param %s needs a pattern or enum", name)
+		}
+		if p.Pattern != "" {
+			re, err := regexp.Compile(`^(?:` + p.Pattern + `)$`)
+			if err != nil {
+				return fmt.Errorf("param %s: %w", name, err)
+			}
+			p.re = re
+		}
+		c.Params[name] = p
+	}
+	for _, arg := range c.Argv[1:] {
+		for _, m := range placeholder.FindAllStringSubmatch(arg, -1) {
+			if _, ok := c.Params[m[1]]; !ok {
+				return fmt.Errorf("placeholder {{%s}} has no param", m[1])
+			}
+		}
+	}
+	return nil
+}
+
+// argv fills in the placeholders, refusing values the params don't allow
+func (c *Command) argv(args map[string]string) ([]string, error) {
+	values := make(map[string]string, len(c.Params))
+	for name, p := range c.Params {
+		v, ok := args[name]
+		if !ok {
+			if p.Default == "" {
+				return nil, fmt.Errorf("%s needs the '%s' arg", c.Name, name)
+			}
+			v = p.Default
+		}
+		if !p.allows(v) {
+			return nil, fmt.Errorf("'%s' is not an allowed value for %s", v, name)
+		}
+		values[name] = v
+	}
+	for name := range args {
+		if _, ok := c.Params[name]; !ok {
+			return nil, fmt.Errorf("%s takes no '%s' arg", c.Name, name)
+		}
+	}
+
+	argv := make([]string, len(c.Argv))
+	argv[0] = c.Argv[0]
+	for n, arg := range c.Argv[1:] {
+		argv[n+1] = placeholder.ReplaceAllStringFunc(arg, func(m string) string {
+			return values[strings.TrimSpace(m[2:len(m)-2])]
+		})
+	}
+	return argv, nil
+}
+
+func (p Param) allows(v string) bool {
+	if len(p.Enum) > 0 {
+		for _, e := range p.Enum {
+			if v == e {
+				return true
+			}
+		}
+		if p.re == nil {
+			return false
+		}
+	}
+	return p.re.MatchString(v)
+}
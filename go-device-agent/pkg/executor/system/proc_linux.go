@@ -0,0 +1,45 @@
+package system
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const limitsSupported = true
+
+// isolate puts the command in its own process group, so a timeout kills
+// everything it started
+func isolate(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// applyLimits sets resource limits on the process as soon as it has
+// started; anything it starts inherits them
+func applyLimits(pid int, l Limits) error {
+	set := func(resource int, v uint64) error {
+		if v == 0 {
+			return nil
+		}
+		err := unix.Prlimit(pid, resource, &unix.Rlimit{Cur: v, Max: v}, nil)
+		if errors.Is(err, unix.ESRCH) {
+			return nil // already exited
+		}
+		return err
+	}
+	if err := set(unix.RLIMIT_CPU, l.CPUSeconds); err != nil {
+		return err
+	}
+	if err := set(unix.RLIMIT_AS, l.MemoryMB<<20); err != nil {
+		return err
+	}
+	if err := set(unix.RLIMIT_NOFILE, l.OpenFiles); err != nil {
+		return err
+	}
+	return set(unix.RLIMIT_FSIZE, l.FileSizeMB<<20)
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package system
+
+import "os/exec"
+
+const limitsSupported = false
+
+// isolate is a no-op; on timeout only the command itself is killed
+func isolate(cmd *exec.Cmd) {}
+
+// applyLimits is never asked for limits here; NewExecutor refuses them
+func applyLimits(pid int, l Limits) error {
+	return nil
+}
@@ -0,0 +1,322 @@
+// Package system runs allowlisted commands ("restart plex", "run the backup
+// script") without a shell, confined to a working directory and bounded by
+// timeouts and resource limits
+package system
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Config lists the commands and where they may run
+type Config struct {
+	Commands []Command
+
+	// Root confines working directories; it is created if missing
+	Root string
+}
+
+// path is the only PATH commands see; the agent's environment, which may
+// hold credentials, is not passed on
+const path = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// Executor handles system.commands and system.run
+type Executor struct {
+	root     string
+	commands map[string]*Command
+}
+
+// NewExecutor creates a system executor for commands loaded with
+// LoadCommands
+func NewExecutor(cfg Config) (*Executor, error) {
+	if len(cfg.Commands) == 0 {
+		return nil, errors.New("no system commands configured")
+	}
+	if cfg.Root == "" {
+		return nil, errors.New("system commands need a root directory")
+	}
+	if err := os.MkdirAll(cfg.Root, 0o700); err != nil {
+		return nil, err
+	}
+	root, err := filepath.EvalSymlinks(cfg.Root)
+	if err != nil {
+		return nil, err
+	}
+	e := &Executor{root: root, commands: make(map[string]*Command)}
+	for n := range cfg.Commands {
+		c := &cfg.Commands[n]
+		if _, err := e.workDir(c); err != nil {
+			return nil, fmt.Errorf("command %s: %w", c.Name, err)
+		}
+		if !limitsSupported && c.Limits != (Limits{}) {
+			return nil, fmt.Errorf("command %s: resource limits are only supported on Linux", c.Name)
+		}
+		e.commands[c.Name] = c
+	}
+	return e, nil
+}
+
+func (e *Executor) Name() string {
+	return "system"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"system.commands", "system.run"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{Subprocess: true, Write: []string{e.root}}
+}
+
+// PermissionRequired makes every command run need requires_permission
+func (e *Executor) PermissionRequired() []string {
+	return []string{"system.run"}
+}
+
+// Exclusions runs one instance of each command at a time
+func (e *Executor) Exclusions(i *intent.Intent) []gateway.Exclusion {
+	if i.IntentType != "system.run" {
+		return nil
+	}
+	name, _ := i.StringParam("command")
+	return []gateway.Exclusion{{Group: "system:" + name}}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "system",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	switch i.IntentType {
+	case "system.commands":
+		result.Success = true
+		result.Result = map[string]interface{}{"commands": e.list()}
+		return result, nil
+
+	case "system.run":
+		name, err := i.StringParam("command")
+		if err != nil {
+			return fail(err)
+		}
+		c, ok := e.commands[name]
+		if !ok {
+			return fail(agenterrors.Newf(agenterrors.NotFound, "no command named '%s'; see system.commands", name))
+		}
+		args, err := stringArgs(i.Parameters["args"])
+		if err != nil {
+			return fail(err)
+		}
+		out, err := e.run(ctx, c, args)
+		result.Result = out
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		return result, nil
+
+	default:
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+		return result, nil
+	}
+}
+
+// stringArgs reads the args object, formatting numbers and booleans
+func stringArgs(v interface{}) (map[string]string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, agenterrors.New(agenterrors.InvalidParams, "args must be an object")
+	}
+	args := make(map[string]string, len(m))
+	for k, v := range m {
+		switch v := v.(type) {
+		case string:
+			args[k] = v
+		case float64, bool, int, int64:
+			args[k] = fmt.Sprint(v)
+		default:
+			return nil, agenterrors.Newf(agenterrors.InvalidParams, "arg %s must be a string, number, or boolean", k)
+		}
+	}
+	return args, nil
+}
+
+// run executes a command, returning its exit status and captured output
+// even when it fails
+func (e *Executor) run(ctx context.Context, c *Command, args map[string]string) (map[string]interface{}, error) {
+	argv, err := c.argv(args)
+	if err != nil {
+		return nil, agenterrors.Wrap(agenterrors.InvalidParams, err)
+	}
+	dir, err := e.workDir(c)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	stdout, stderr := &capped{max: c.MaxOutput}, &capped{max: c.MaxOutput}
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = dir
+	cmd.Env = e.env(c)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = 2 * time.Second
+	isolate(cmd)
+
+	started := time.Now()
+	if err := cmd.Start(); err != nil {
+		return nil, agenterrors.Newf(agenterrors.Unavailable, "cannot start %s: %w", c.Name, err)
+	}
+	if err := applyLimits(cmd.Process.Pid, c.Limits); err != nil {
+		cancel()
+		cmd.Wait()
+		return nil, agenterrors.Newf(agenterrors.Internal, "cannot limit %s: %w", c.Name, err)
+	}
+	err = cmd.Wait()
+
+	out := map[string]interface{}{
+		"command":     c.Name,
+		"exit_code":   cmd.ProcessState.ExitCode(),
+		"stdout":      stdout.String(),
+		"stderr":      stderr.String(),
+		"duration_ms": time.Since(started).Milliseconds(),
+	}
+	if stdout.dropped > 0 || stderr.dropped > 0 {
+		out["truncated"] = true
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return out, agenterrors.Newf(agenterrors.Timeout, "%s timed out after %s", c.Name, c.timeout)
+	case ctx.Err() != nil:
+		return out, agenterrors.Newf(agenterrors.Cancelled, "%s was cancelled", c.Name)
+	case errors.As(err, &exitErr):
+		return out, agenterrors.Newf(agenterrors.Internal, "%s exited with status %d", c.Name, exitErr.ExitCode())
+	case err != nil:
+		return out, agenterrors.Newf(agenterrors.Internal, "%s failed: %w", c.Name, err)
+	}
+	return out, nil
+}
+
+// workDir resolves the command's directory, refusing any that leaves the
+// root, including through symlinks
+func (e *Executor) workDir(c *Command) (string, error) {
+	dir := c.Dir
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(e.root, dir)
+	}
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", agenterrors.Newf(agenterrors.Unavailable, "working directory of %s: %w", c.Name, err)
+	}
+	rel, err := filepath.Rel(e.root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", agenterrors.Newf(agenterrors.DeniedByPolicy, "working directory of %s is outside %s", c.Name, e.root)
+	}
+	return resolved, nil
+}
+
+// env is the minimal environment plus the command's own variables
+func (e *Executor) env(c *Command) []string {
+	env := []string{"PATH=" + path, "HOME=" + e.root, "LANG=C.UTF-8"}
+	keys := make([]string, 0, len(c.Env))
+	for k := range c.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+c.Env[k])
+	}
+	return env
+}
+
+// list describes the commands and the args they take
+func (e *Executor) list() []map[string]interface{} {
+	names := make([]string, 0, len(e.commands))
+	for name := range e.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		c := e.commands[name]
+		entry := map[string]interface{}{"name": name, "timeout": c.timeout.String()}
+		if c.Description != "" {
+			entry["description"] = c.Description
+		}
+		if len(c.Params) > 0 {
+			entry["args"] = c.Params
+		}
+		list = append(list, entry)
+	}
+	return list
+}
+
+// capped keeps the first max bytes written and counts the rest
+type capped struct {
+	buf     bytes.Buffer
+	max     int
+	dropped int
+}
+
+func (c *capped) Write(p []byte) (int, error) {
+	keep := min(len(p), c.max-c.buf.Len())
+	c.buf.Write(p[:keep])
+	c.dropped += len(p) - keep
+	return len(p), nil
+}
+
+func (c *capped) String() string {
+	if c.dropped == 0 {
+		return c.buf.String()
+	}
+	return fmt.Sprintf("%s…[%d bytes truncated]", strings.ToValidUTF8(c.buf.String(), ""), c.dropped)
+}
+
+func (e *Executor) IsAvailable() bool {
+	return true
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	names := make([]string, 0, len(e.commands))
+	for name := range e.commands {
+		names = append(names, fmt.Sprintf("%q", name))
+	}
+	sort.Strings(names)
+	return map[string]*schema.Schema{
+		"system.commands": schema.MustParse(`{"type": "object", "properties": {}}`),
+		"system.run": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"command": {"type": "string", "enum": [` + strings.Join(names, ", ") + `]},
+				"args": {"type": "object"}
+			},
+			"required": ["command"]
+		}`),
+	}
+}
@@ -0,0 +1,99 @@
+package todo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+)
+
+// SyncAdapter mirrors tasks to an external service such as a CalDAV task
+// list. Put is called for new and completed tasks.
+type SyncAdapter interface {
+	Name() string
+	Put(ctx context.Context, task Task) error
+}
+
+// CalDAV stores each task as a VTODO in a CalDAV task collection, one
+// collection per list under BaseURL (e.g. https://dav.example.com/tasks/)
+type CalDAV struct {
+	BaseURL  string
+	Username string
+	Password string
+	Client   *http.Client
+}
+
+// Name implements SyncAdapter
+func (c *CalDAV) Name() string {
+	return "caldav"
+}
+
+// Put implements SyncAdapter
+func (c *CalDAV) Put(ctx context.Context, task Task) error {
+	target := strings.TrimSuffix(c.BaseURL, "/") + "/" + url.PathEscape(task.List) + "/" + task.ID + ".ics"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, bytes.NewReader([]byte(vtodo(task))))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/calendar; charset=utf-8")
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	client := c.Client
+	if client == nil {
+		client = connpool.Default.HTTP(10 * time.Second)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("PUT %s returned %s", target, resp.Status)
+	}
+	return nil
+}
+
+// vtodo renders a task as an iCalendar VTODO
+func vtodo(task Task) string {
+	const stampLayout = "20060102T150405Z"
+	var b strings.Builder
+	b.WriteString("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//local-agent-core//todo//EN\r\n")
+	b.WriteString("BEGIN:VTODO\r\n")
+	b.WriteString("UID:" + task.ID + "\r\n")
+	b.WriteString("DTSTAMP:" + time.Now().UTC().Format(stampLayout) + "\r\n")
+	b.WriteString("CREATED:" + task.AddedAt.UTC().Format(stampLayout) + "\r\n")
+	b.WriteString("SUMMARY:" + escapeText(task.Title) + "\r\n")
+	if d, err := time.Parse(time.DateOnly, task.Due); err == nil {
+		b.WriteString("DUE;VALUE=DATE:" + d.Format("20060102") + "\r\n")
+	} else if d, err := time.Parse(time.RFC3339, task.Due); err == nil {
+		b.WriteString("DUE:" + d.UTC().Format(stampLayout) + "\r\n")
+	}
+	// iCalendar priorities run from 1 (highest) to 9, 0 being undefined
+	switch task.Priority {
+	case "high":
+		b.WriteString("PRIORITY:1\r\n")
+	case "low":
+		b.WriteString("PRIORITY:9\r\n")
+	}
+	if task.CompletedAt != nil {
+		b.WriteString("STATUS:COMPLETED\r\n")
+		b.WriteString("COMPLETED:" + task.CompletedAt.UTC().Format(stampLayout) + "\r\n")
+		b.WriteString("PERCENT-COMPLETE:100\r\n")
+	} else {
+		b.WriteString("STATUS:NEEDS-ACTION\r\n")
+	}
+	b.WriteString("END:VTODO\r\nEND:VCALENDAR\r\n")
+	return b.String()
+}
+
+// escapeText escapes an iCalendar TEXT value
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,407 @@
+// Package todo keeps task lists on disk, optionally mirrored to CalDAV.
+//
+// Tasks are kept in a JSON file rather than SQLite: the usual SQLite driver
+// needs cgo, and the Landlock sandbox needs a build without it. A household's
+// tasks fit in memory, so the file is read once and rewritten on each change,
+// as the shopping lists are.
+package todo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// DefaultList is used when an intent names neither a list nor a user
+const DefaultList = "inbox"
+
+// keepCompleted is how long completed tasks stay on file
+const keepCompleted = 30 * 24 * time.Hour
+
+// Task is one entry on a task list
+type Task struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	List  string `json:"list"`
+
+	// Due is a date (2006-01-02) or an RFC 3339 time
+	Due string `json:"due,omitempty"`
+
+	// Priority is high, normal, or low
+	Priority string `json:"priority"`
+
+	AddedBy     string     `json:"added_by,omitempty"`
+	AddedAt     time.Time  `json:"added_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Executor handles todo.add, todo.list, and todo.complete
+type Executor struct {
+	path    string
+	adapter SyncAdapter
+
+	mu    sync.Mutex
+	tasks []Task
+}
+
+// NewExecutor loads the tasks stored at path, starting empty if the file
+// doesn't exist yet
+func NewExecutor(path string) (*Executor, error) {
+	e := &Executor{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return e, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &e.tasks); err != nil {
+		return nil, fmt.Errorf("invalid task file %s: %w", path, err)
+	}
+	return e, nil
+}
+
+// SetSyncAdapter mirrors new and completed tasks to an external service.
+// The local file stays the source of truth; sync failures are reported as
+// warnings.
+func (e *Executor) SetSyncAdapter(adapter SyncAdapter) {
+	e.adapter = adapter
+}
+
+func (e *Executor) Name() string {
+	return "todo"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"todo.add", "todo.list", "todo.complete"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{Network: e.adapter != nil, Write: []string{filepath.Dir(e.path)}}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "todo",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	var params struct {
+		Title            string `param:"title"`
+		ID               string `param:"id"`
+		Due              string `param:"due"`
+		Priority         string `param:"priority"`
+		List             string `param:"list"`
+		User             string `param:"user"`
+		All              bool   `param:"all"`
+		IncludeCompleted bool   `param:"include_completed"`
+	}
+	if err := i.DecodeParams(&params); err != nil {
+		return fail(err)
+	}
+
+	// Each user gets their own list unless a list is named explicitly
+	list := params.List
+	if list == "" {
+		list = params.User
+	}
+	if list == "" {
+		list = DefaultList
+	}
+	list = strings.ToLower(strings.TrimSpace(list))
+
+	var synced *Task
+	switch i.IntentType {
+	case "todo.add":
+		if strings.TrimSpace(params.Title) == "" {
+			return fail(agenterrors.New(agenterrors.InvalidParams, "title is required"))
+		}
+		due, err := parseDue(params.Due)
+		if err != nil {
+			return fail(err)
+		}
+		priority := params.Priority
+		if priority == "" {
+			priority = "normal"
+		}
+		task := Task{
+			ID:       events.NewID(),
+			Title:    strings.TrimSpace(params.Title),
+			List:     list,
+			Due:      due,
+			Priority: priority,
+			AddedBy:  params.User,
+			AddedAt:  time.Now().UTC(),
+		}
+		if err := e.add(task); err != nil {
+			return nil, err
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"list": list,
+			"task": task,
+		}
+		synced = &task
+
+	case "todo.list":
+		if params.All {
+			list = ""
+		}
+		tasks := e.list(list, params.IncludeCompleted)
+		overdue := 0
+		now := time.Now()
+		for _, t := range tasks {
+			if t.CompletedAt == nil && t.overdue(now) {
+				overdue++
+			}
+		}
+		out := map[string]interface{}{
+			"tasks":   tasks,
+			"count":   len(tasks),
+			"overdue": overdue,
+		}
+		if list != "" {
+			out["list"] = list
+		}
+		result.Success = true
+		result.Result = out
+
+	case "todo.complete":
+		if params.ID == "" && strings.TrimSpace(params.Title) == "" {
+			return fail(agenterrors.New(agenterrors.InvalidParams, "id or title is required"))
+		}
+		if params.All || (params.List == "" && params.User == "") {
+			list = ""
+		}
+		task, matches, err := e.complete(params.ID, params.Title, list)
+		if err != nil {
+			result.Fail(err)
+			if len(matches) > 0 {
+				result.Result = map[string]interface{}{"matches": matches}
+			}
+			return result, nil
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"task": task,
+		}
+		synced = &task
+
+	default:
+		return fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+
+	if synced != nil && e.adapter != nil {
+		if err := e.adapter.Put(ctx, *synced); err != nil {
+			result.Result["warnings"] = []string{fmt.Sprintf("%s sync failed: %v", e.adapter.Name(), err)}
+		}
+	}
+	return result, nil
+}
+
+// parseDue checks a due date or time, normalizing times to UTC
+func parseDue(due string) (string, error) {
+	if due == "" {
+		return "", nil
+	}
+	if _, err := time.Parse(time.DateOnly, due); err == nil {
+		return due, nil
+	}
+	if t, err := time.Parse(time.RFC3339, due); err == nil {
+		return t.UTC().Format(time.RFC3339), nil
+	}
+	return "", agenterrors.Newf(agenterrors.InvalidParams, "due must be a date (2006-01-02) or an RFC 3339 time, not %q", due)
+}
+
+// dueTime is when the task falls due: a date-only task at the end of its
+// day, local time
+func (t Task) dueTime() (time.Time, bool) {
+	if d, err := time.ParseInLocation(time.DateOnly, t.Due, time.Local); err == nil {
+		return d.AddDate(0, 0, 1), true
+	}
+	if d, err := time.Parse(time.RFC3339, t.Due); err == nil {
+		return d, true
+	}
+	return time.Time{}, false
+}
+
+func (t Task) overdue(now time.Time) bool {
+	due, ok := t.dueTime()
+	return ok && now.After(due)
+}
+
+func (e *Executor) add(task Task) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.tasks = append(e.tasks, task)
+	return e.save()
+}
+
+// list returns a list's tasks, or every list's for "": open ones first,
+// by due date, then priority, then age
+func (e *Executor) list(list string, includeCompleted bool) []Task {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	tasks := []Task{}
+	for _, t := range e.tasks {
+		if (list == "" || t.List == list) && (includeCompleted || t.CompletedAt == nil) {
+			tasks = append(tasks, t)
+		}
+	}
+	rank := map[string]int{"high": 0, "normal": 1, "low": 2}
+	sort.SliceStable(tasks, func(a, b int) bool {
+		ta, tb := tasks[a], tasks[b]
+		if (ta.CompletedAt == nil) != (tb.CompletedAt == nil) {
+			return ta.CompletedAt == nil
+		}
+		da, okA := ta.dueTime()
+		db, okB := tb.dueTime()
+		if okA != okB {
+			return okA
+		}
+		if okA && !da.Equal(db) {
+			return da.Before(db)
+		}
+		if rank[ta.Priority] != rank[tb.Priority] {
+			return rank[ta.Priority] < rank[tb.Priority]
+		}
+		return ta.AddedAt.Before(tb.AddedAt)
+	})
+	return tasks
+}
+
+// complete marks an open task done, found by ID or by title within list
+// ("" for any): an exact title first, then a unique partial one. When the
+// title is ambiguous, the candidates are returned with the error.
+func (e *Executor) complete(id, title, list string) (Task, []Task, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var exact, partial []int
+	title = strings.ToLower(strings.TrimSpace(title))
+	for idx, t := range e.tasks {
+		if t.CompletedAt != nil || (list != "" && t.List != list) {
+			continue
+		}
+		switch {
+		case id != "":
+			if t.ID == id {
+				exact = append(exact, idx)
+			}
+		case strings.ToLower(t.Title) == title:
+			exact = append(exact, idx)
+		case strings.Contains(strings.ToLower(t.Title), title):
+			partial = append(partial, idx)
+		}
+	}
+	found := exact
+	if len(found) == 0 {
+		found = partial
+	}
+	switch len(found) {
+	case 0:
+		if id != "" {
+			return Task{}, nil, agenterrors.Newf(agenterrors.NotFound, "no open task with id %s", id)
+		}
+		return Task{}, nil, agenterrors.Newf(agenterrors.NotFound, "no open task matching '%s'", title)
+	case 1:
+	default:
+		matches := make([]Task, len(found))
+		for n, idx := range found {
+			matches[n] = e.tasks[idx]
+		}
+		return Task{}, matches, agenterrors.Newf(agenterrors.Conflict, "%d open tasks match '%s'; name one by id", len(found), title)
+	}
+
+	now := time.Now().UTC()
+	e.tasks[found[0]].CompletedAt = &now
+	task := e.tasks[found[0]]
+	return task, nil, e.save()
+}
+
+// save drops long-completed tasks and writes the rest atomically so a
+// crash never leaves a torn file
+func (e *Executor) save() error {
+	cutoff := time.Now().Add(-keepCompleted)
+	kept := e.tasks[:0]
+	for _, t := range e.tasks {
+		if t.CompletedAt == nil || t.CompletedAt.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	e.tasks = kept
+
+	data, err := json.MarshalIndent(e.tasks, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(e.path), 0o700); err != nil {
+		return err
+	}
+	tmp := e.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, e.path)
+}
+
+func (e *Executor) IsAvailable() bool {
+	return e.path != ""
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"todo.add": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"title": {"type": "string", "minLength": 1, "maxLength": 500},
+				"due": {"type": "string"},
+				"priority": {"type": "string", "enum": ["high", "normal", "low"]},
+				"list": {"type": "string"},
+				"user": {"type": "string"}
+			},
+			"required": ["title"]
+		}`),
+		"todo.list": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"list": {"type": "string"},
+				"user": {"type": "string"},
+				"all": {"type": "boolean"},
+				"include_completed": {"type": "boolean"}
+			}
+		}`),
+		"todo.complete": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"id": {"type": "string", "minLength": 1},
+				"title": {"type": "string", "minLength": 1},
+				"list": {"type": "string"},
+				"user": {"type": "string"},
+				"all": {"type": "boolean"}
+			}
+		}`),
+	}
+}
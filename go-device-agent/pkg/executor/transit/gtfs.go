@@ -0,0 +1,326 @@
+package transit
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stopTime is one scheduled departure of a trip from a stop
+type stopTime struct {
+	tripID    string
+	sequence  int
+	departure int // seconds after midnight of the service day, may exceed 24h
+}
+
+type trip struct {
+	routeID   string
+	serviceID string
+	headsign  string
+}
+
+type service struct {
+	weekdays  [7]bool // indexed by time.Weekday
+	start     string  // YYYYMMDD
+	end       string
+	added     map[string]bool
+	removed   map[string]bool
+	hasPeriod bool
+}
+
+// Static answers departures from a GTFS static feed loaded into memory
+type Static struct {
+	location  *time.Location
+	stops     map[string]Stop
+	routes    map[string]string // route_id -> short or long name
+	trips     map[string]trip
+	services  map[string]*service
+	stopTimes map[string][]stopTime // stop_id -> departures sorted by time
+}
+
+// LoadGTFS loads a GTFS static feed from a .zip file or an extracted directory
+func LoadGTFS(path string) (*Static, error) {
+	open, closeFn, err := feedOpener(path)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFn()
+
+	s := &Static{
+		location:  time.Local,
+		stops:     make(map[string]Stop),
+		routes:    make(map[string]string),
+		trips:     make(map[string]trip),
+		services:  make(map[string]*service),
+		stopTimes: make(map[string][]stopTime),
+	}
+
+	loaders := []struct {
+		file     string
+		required bool
+		load     func(row map[string]string)
+	}{
+		{"agency.txt", false, s.loadAgency},
+		{"stops.txt", true, s.loadStop},
+		{"routes.txt", true, s.loadRoute},
+		{"trips.txt", true, s.loadTrip},
+		{"stop_times.txt", true, s.loadStopTime},
+		{"calendar.txt", false, s.loadCalendar},
+		{"calendar_dates.txt", false, s.loadCalendarDate},
+	}
+	for _, l := range loaders {
+		f, err := open(l.file)
+		if err != nil {
+			if l.required {
+				return nil, fmt.Errorf("GTFS feed is missing %s", l.file)
+			}
+			continue
+		}
+		err = readCSV(f, l.load)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", l.file, err)
+		}
+	}
+
+	for id := range s.stopTimes {
+		times := s.stopTimes[id]
+		sort.Slice(times, func(a, b int) bool { return times[a].departure < times[b].departure })
+	}
+	return s, nil
+}
+
+func feedOpener(path string) (func(string) (io.ReadCloser, error), func(), error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		return func(name string) (io.ReadCloser, error) {
+			return os.Open(filepath.Join(path, name))
+		}, func() {}, nil
+	}
+
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return func(name string) (io.ReadCloser, error) {
+		return z.Open(name)
+	}, func() { z.Close() }, nil
+}
+
+// readCSV calls fn with each row keyed by header name
+func readCSV(r io.Reader, fn func(map[string]string)) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return err
+	}
+	names := make([]string, len(header))
+	for i, h := range header {
+		names[i] = strings.TrimPrefix(strings.TrimSpace(h), "\ufeff")
+	}
+
+	row := make(map[string]string, len(names))
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for i, name := range names {
+			if i < len(record) {
+				row[name] = record[i]
+			} else {
+				row[name] = ""
+			}
+		}
+		fn(row)
+	}
+}
+
+func (s *Static) loadAgency(row map[string]string) {
+	if loc, err := time.LoadLocation(row["agency_timezone"]); err == nil {
+		s.location = loc
+	}
+}
+
+func (s *Static) loadStop(row map[string]string) {
+	s.stops[row["stop_id"]] = Stop{ID: row["stop_id"], Name: row["stop_name"]}
+}
+
+func (s *Static) loadRoute(row map[string]string) {
+	name := row["route_short_name"]
+	if name == "" {
+		name = row["route_long_name"]
+	}
+	s.routes[row["route_id"]] = name
+}
+
+func (s *Static) loadTrip(row map[string]string) {
+	s.trips[row["trip_id"]] = trip{
+		routeID:   row["route_id"],
+		serviceID: row["service_id"],
+		headsign:  row["trip_headsign"],
+	}
+}
+
+func (s *Static) loadStopTime(row map[string]string) {
+	value := row["departure_time"]
+	if value == "" {
+		value = row["arrival_time"]
+	}
+	secs, ok := parseGTFSTime(value)
+	if !ok {
+		return
+	}
+	seq, _ := strconv.Atoi(row["stop_sequence"])
+	stopID := row["stop_id"]
+	s.stopTimes[stopID] = append(s.stopTimes[stopID], stopTime{
+		tripID:    row["trip_id"],
+		sequence:  seq,
+		departure: secs,
+	})
+}
+
+func (s *Static) loadCalendar(row map[string]string) {
+	svc := s.service(row["service_id"])
+	days := []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+	for i, day := range days {
+		svc.weekdays[i] = row[day] == "1"
+	}
+	svc.start = row["start_date"]
+	svc.end = row["end_date"]
+	svc.hasPeriod = true
+}
+
+func (s *Static) loadCalendarDate(row map[string]string) {
+	svc := s.service(row["service_id"])
+	switch row["exception_type"] {
+	case "1":
+		svc.added[row["date"]] = true
+	case "2":
+		svc.removed[row["date"]] = true
+	}
+}
+
+func (s *Static) service(id string) *service {
+	svc, ok := s.services[id]
+	if !ok {
+		svc = &service{added: make(map[string]bool), removed: make(map[string]bool)}
+		s.services[id] = svc
+	}
+	return svc
+}
+
+// runsOn reports whether a service operates on the given service day
+func (s *Static) runsOn(serviceID string, day time.Time) bool {
+	svc, ok := s.services[serviceID]
+	if !ok {
+		return false
+	}
+	date := day.Format("20060102")
+	if svc.removed[date] {
+		return false
+	}
+	if svc.added[date] {
+		return true
+	}
+	return svc.hasPeriod && date >= svc.start && date <= svc.end && svc.weekdays[day.Weekday()]
+}
+
+// Name implements Backend
+func (s *Static) Name() string {
+	return "gtfs-static"
+}
+
+// FindStop resolves a stop by ID or, failing that, by case-insensitive name match
+func (s *Static) FindStop(query string) (Stop, bool) {
+	if stop, ok := s.stops[query]; ok {
+		return stop, true
+	}
+	q := strings.ToLower(strings.TrimSpace(query))
+	var best Stop
+	found := false
+	for _, stop := range s.stops {
+		name := strings.ToLower(stop.Name)
+		if name == q {
+			return stop, true
+		}
+		// Prefer the shortest partial match, e.g. "Main St" over "Main St & 5th Ave"
+		if strings.Contains(name, q) && (!found || len(stop.Name) < len(best.Name)) {
+			best, found = stop, true
+		}
+	}
+	return best, found
+}
+
+// Departures implements Backend using the timetable only
+func (s *Static) Departures(ctx context.Context, stopID string, after time.Time, limit int) ([]Departure, error) {
+	times, ok := s.stopTimes[stopID]
+	if !ok {
+		return nil, fmt.Errorf("unknown stop: %s", stopID)
+	}
+
+	after = after.In(s.location)
+	var departures []Departure
+	// Trips from yesterday's service day can still be running after midnight
+	for _, offset := range []int{-1, 0, 1} {
+		day := time.Date(after.Year(), after.Month(), after.Day()+offset, 0, 0, 0, 0, s.location)
+		for _, st := range times {
+			t, ok := s.trips[st.tripID]
+			if !ok || !s.runsOn(t.serviceID, day) {
+				continue
+			}
+			at := day.Add(time.Duration(st.departure) * time.Second)
+			if at.Before(after) {
+				continue
+			}
+			departures = append(departures, Departure{
+				Route:     s.routes[t.routeID],
+				Headsign:  t.headsign,
+				TripID:    st.tripID,
+				Scheduled: at,
+				Expected:  at,
+				sequence:  st.sequence,
+			})
+		}
+	}
+
+	sortDepartures(departures)
+	if limit > 0 && len(departures) > limit {
+		departures = departures[:limit]
+	}
+	return departures, nil
+}
+
+// parseGTFSTime parses HH:MM:SS, where hours may exceed 23
+func parseGTFSTime(value string) (int, bool) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	var total int
+	for _, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return 0, false
+		}
+		total = total*60 + n
+	}
+	return total, true
+}
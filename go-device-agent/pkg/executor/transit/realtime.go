@@ -0,0 +1,251 @@
+package transit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/internal/wire"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+)
+
+// maxFeedSize bounds a GTFS-realtime download
+const maxFeedSize = 16 << 20
+
+// stopUpdate is a GTFS-realtime StopTimeUpdate
+type stopUpdate struct {
+	sequence int
+	stopID   string
+	delay    *int64 // seconds
+	time     *int64 // unix seconds
+	skipped  bool
+}
+
+// tripUpdate is a GTFS-realtime TripUpdate
+type tripUpdate struct {
+	delay *int64
+	stops []stopUpdate
+}
+
+// Realtime applies a GTFS-realtime TripUpdates feed on top of the static
+// timetable. When the feed can't be fetched it fails, letting the executor
+// fall back to the next backend (normally the static timetable).
+type Realtime struct {
+	static *Static
+	url    string
+	ttl    time.Duration
+	client *http.Client
+
+	mu      sync.Mutex
+	fetched time.Time
+	updates map[string]tripUpdate
+}
+
+// NewRealtime creates a realtime backend for feedURL, caching the feed for ttl
+func NewRealtime(static *Static, feedURL string, ttl time.Duration) *Realtime {
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+	return &Realtime{
+		static: static,
+		url:    feedURL,
+		ttl:    ttl,
+		client: connpool.Default.HTTP(10 * time.Second),
+	}
+}
+
+// Name implements Backend
+func (r *Realtime) Name() string {
+	return "gtfs-realtime"
+}
+
+// Departures implements Backend
+func (r *Realtime) Departures(ctx context.Context, stopID string, after time.Time, limit int) ([]Departure, error) {
+	updates, err := r.feed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Look a little further back so delayed trips aren't missed
+	scheduled, err := r.static.Departures(ctx, stopID, after.Add(-30*time.Minute), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	departures := make([]Departure, 0, len(scheduled))
+	for _, d := range scheduled {
+		if u, ok := updates[d.TripID]; ok {
+			if !applyUpdate(&d, stopID, u) {
+				continue // stop skipped
+			}
+		}
+		if d.Expected.Before(after) {
+			continue
+		}
+		departures = append(departures, d)
+	}
+
+	sortDepartures(departures)
+	if limit > 0 && len(departures) > limit {
+		departures = departures[:limit]
+	}
+	return departures, nil
+}
+
+// applyUpdate adjusts a departure with its trip's update, returning false if
+// the stop is skipped. Per the GTFS-realtime spec a delay propagates to later
+// stops until the next update.
+func applyUpdate(d *Departure, stopID string, u tripUpdate) bool {
+	var exact, preceding *stopUpdate
+	for i := range u.stops {
+		s := &u.stops[i]
+		if s.stopID == stopID || (s.sequence != 0 && s.sequence == d.sequence) {
+			exact = s
+			break
+		}
+		if s.sequence != 0 && s.sequence < d.sequence && (preceding == nil || s.sequence > preceding.sequence) {
+			preceding = s
+		}
+	}
+
+	d.Realtime = true
+	switch {
+	case exact != nil && exact.skipped:
+		return false
+	case exact != nil && exact.time != nil:
+		d.Expected = time.Unix(*exact.time, 0).In(d.Scheduled.Location())
+	case exact != nil && exact.delay != nil:
+		d.Expected = d.Scheduled.Add(time.Duration(*exact.delay) * time.Second)
+	case preceding != nil && preceding.delay != nil:
+		d.Expected = d.Scheduled.Add(time.Duration(*preceding.delay) * time.Second)
+	case u.delay != nil:
+		d.Expected = d.Scheduled.Add(time.Duration(*u.delay) * time.Second)
+	}
+	d.DelaySeconds = int(d.Expected.Sub(d.Scheduled).Seconds())
+	return true
+}
+
+// feed returns the cached trip updates, refreshing them when stale
+func (r *Realtime) feed(ctx context.Context) (map[string]tripUpdate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.updates != nil && time.Since(r.fetched) < r.ttl {
+		return r.updates, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("realtime feed unavailable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("realtime feed returned %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize))
+	if err != nil {
+		return nil, err
+	}
+
+	updates, err := parseFeed(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid realtime feed: %w", err)
+	}
+	r.updates = updates
+	r.fetched = time.Now()
+	return updates, nil
+}
+
+// parseFeed decodes the TripUpdate entities of a GTFS-realtime FeedMessage
+func parseFeed(data []byte) (map[string]tripUpdate, error) {
+	updates := make(map[string]tripUpdate)
+	err := wire.Walk(data, func(entity wire.Field) error {
+		if entity.Num != 2 { // FeedMessage.entity
+			return nil
+		}
+		return wire.Walk(entity.Bytes(), func(f wire.Field) error {
+			if f.Num != 3 { // FeedEntity.trip_update
+				return nil
+			}
+			tripID, u, err := parseTripUpdate(f.Bytes())
+			if err == nil && tripID != "" {
+				updates[tripID] = u
+			}
+			return err
+		})
+	})
+	return updates, err
+}
+
+func parseTripUpdate(data []byte) (string, tripUpdate, error) {
+	var tripID string
+	var u tripUpdate
+	err := wire.Walk(data, func(f wire.Field) error {
+		switch f.Num {
+		case 1: // trip
+			return wire.Walk(f.Bytes(), func(t wire.Field) error {
+				if t.Num == 1 { // TripDescriptor.trip_id
+					tripID = t.String()
+				}
+				return nil
+			})
+		case 2: // stop_time_update
+			s, err := parseStopUpdate(f.Bytes())
+			u.stops = append(u.stops, s)
+			return err
+		case 5: // delay
+			delay := int64(int32(f.Int64()))
+			u.delay = &delay
+		}
+		return nil
+	})
+	return tripID, u, err
+}
+
+func parseStopUpdate(data []byte) (stopUpdate, error) {
+	var s stopUpdate
+	var arrival, departure stopUpdate
+	err := wire.Walk(data, func(f wire.Field) error {
+		switch f.Num {
+		case 1:
+			s.sequence = int(f.Int64())
+		case 2:
+			return parseStopEvent(f.Bytes(), &arrival)
+		case 3:
+			return parseStopEvent(f.Bytes(), &departure)
+		case 4:
+			s.stopID = f.String()
+		case 5:
+			s.skipped = f.Int64() == 1 // SKIPPED
+		}
+		return nil
+	})
+
+	// Departure predictions take precedence over arrival predictions
+	s.delay, s.time = arrival.delay, arrival.time
+	if departure.delay != nil || departure.time != nil {
+		s.delay, s.time = departure.delay, departure.time
+	}
+	return s, err
+}
+
+func parseStopEvent(data []byte, into *stopUpdate) error {
+	return wire.Walk(data, func(f wire.Field) error {
+		switch f.Num {
+		case 1:
+			delay := int64(int32(f.Int64()))
+			into.delay = &delay
+		case 2:
+			t := f.Int64()
+			into.time = &t
+		}
+		return nil
+	})
+}
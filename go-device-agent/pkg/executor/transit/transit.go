@@ -0,0 +1,212 @@
+// Package transit answers "when is the next bus from stop X" with pluggable
+// schedule backends, degrading from realtime predictions to the static
+// timetable when live data is unavailable
+package transit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// maxDepartures caps how many departures one query returns
+const maxDepartures = 10
+
+// Stop is a transit stop or station
+type Stop struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Departure is one upcoming departure from a stop
+type Departure struct {
+	Route        string    `json:"route"`
+	Headsign     string    `json:"headsign,omitempty"`
+	TripID       string    `json:"trip_id,omitempty"`
+	Scheduled    time.Time `json:"scheduled"`
+	Expected     time.Time `json:"expected"`
+	DelaySeconds int       `json:"delay_seconds"`
+	Realtime     bool      `json:"realtime"`
+
+	sequence int
+}
+
+// Backend provides departures for a stop. Backends for local country APIs
+// implement this alongside the built-in GTFS ones.
+type Backend interface {
+	Name() string
+	Departures(ctx context.Context, stopID string, after time.Time, limit int) ([]Departure, error)
+}
+
+// StopFinder resolves what the user said ("Main Street") to a stop
+type StopFinder interface {
+	FindStop(query string) (Stop, bool)
+}
+
+// Executor handles transit.departures
+type Executor struct {
+	stops    StopFinder
+	backends []Backend
+}
+
+// NewExecutor creates a transit executor. Backends are tried in order, so
+// list the most accurate first, e.g. NewExecutor(static, realtime, static).
+func NewExecutor(stops StopFinder, backends ...Backend) *Executor {
+	return &Executor{
+		stops:    stops,
+		backends: backends,
+	}
+}
+
+func (e *Executor) Name() string {
+	return "transit"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"transit.departures"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	var needs sandbox.Needs
+	for _, b := range e.backends {
+		if _, ok := b.(*Realtime); ok {
+			needs.Network = true
+		}
+	}
+	return needs
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "transit",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	if i.IntentType != "transit.departures" {
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+		return result, nil
+	}
+
+	var params struct {
+		Stop  string `param:"stop,required"`
+		Route string `param:"route"`
+		Limit int    `param:"limit"`
+	}
+	params.Limit = 3
+	if err := i.DecodeParams(&params); err != nil {
+		result.Fail(err)
+		return result, nil
+	}
+
+	stop, ok := e.stops.FindStop(params.Stop)
+	if !ok {
+		result.Fail(agenterrors.Newf(agenterrors.NotFound, "no stop matching '%s'", params.Stop))
+		return result, nil
+	}
+
+	route, limit := params.Route, params.Limit
+	if limit <= 0 {
+		limit = 3
+	}
+	if limit > maxDepartures {
+		limit = maxDepartures
+	}
+
+	now := time.Now()
+	var failures []string
+	for idx, backend := range e.backends {
+		fetchLimit := limit
+		if route != "" {
+			fetchLimit = 0 // filter first, then cut
+		}
+		departures, err := backend.Departures(ctx, stop.ID, now, fetchLimit)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", backend.Name(), err))
+			continue
+		}
+
+		departures = filterRoute(departures, route)
+		if len(departures) > limit {
+			departures = departures[:limit]
+		}
+
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"stop":       stop,
+			"source":     backend.Name(),
+			"degraded":   idx > 0,
+			"departures": describe(departures, now),
+		}
+		if len(failures) > 0 {
+			result.Result["warnings"] = failures
+		}
+		return result, nil
+	}
+
+	result.Fail(agenterrors.New(agenterrors.Unavailable, "no transit backend available: "+strings.Join(failures, "; ")))
+	return result, nil
+}
+
+func (e *Executor) IsAvailable() bool {
+	return e.stops != nil && len(e.backends) > 0
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"transit.departures": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"stop": {"type": "string", "minLength": 1},
+				"route": {"type": "string"},
+				"limit": {"type": "integer", "minimum": 1, "maximum": 10}
+			},
+			"required": ["stop"]
+		}`),
+	}
+}
+
+func filterRoute(departures []Departure, route string) []Departure {
+	if route == "" {
+		return departures
+	}
+	filtered := departures[:0]
+	for _, d := range departures {
+		if strings.EqualFold(d.Route, route) {
+			filtered = append(filtered, d)
+		}
+	}
+	return filtered
+}
+
+// describe adds a voice-friendly minutes-until field to each departure
+func describe(departures []Departure, now time.Time) []map[string]interface{} {
+	out := make([]map[string]interface{}, len(departures))
+	for i, d := range departures {
+		out[i] = map[string]interface{}{
+			"route":         d.Route,
+			"headsign":      d.Headsign,
+			"scheduled":     d.Scheduled.Format(time.RFC3339),
+			"expected":      d.Expected.Format(time.RFC3339),
+			"delay_seconds": d.DelaySeconds,
+			"realtime":      d.Realtime,
+			"in_minutes":    int(d.Expected.Sub(now).Minutes()),
+		}
+	}
+	return out
+}
+
+func sortDepartures(departures []Departure) {
+	sort.Slice(departures, func(a, b int) bool {
+		return departures[a].Expected.Before(departures[b].Expected)
+	})
+}
@@ -0,0 +1,197 @@
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// languagesTTL is how long the server's language list is reused
+const languagesTTL = time.Hour
+
+// client talks to the LibreTranslate HTTP API
+type client struct {
+	url    string
+	apiKey string
+	http   *http.Client
+
+	mu      sync.Mutex
+	langs   languageSet
+	fetched time.Time
+}
+
+func newClient(cfg Config) *client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !cfg.AllowRemote {
+		// Checked on the dialed address rather than the hostname so a name
+		// that later resolves elsewhere still can't carry text off the network
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isLocal(ip) {
+				return agenterrors.Newf(agenterrors.DeniedByPolicy, "translation server %s is not on the local network (set AllowRemote to permit it)", host)
+			}
+			return nil
+		}
+	}
+	return &client{
+		url:    cfg.URL,
+		apiKey: cfg.APIKey,
+		http: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext},
+		},
+	}
+}
+
+func isLocal(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast()
+}
+
+// language is one entry of GET /languages
+type language struct {
+	Code    string   `json:"code"`
+	Name    string   `json:"name"`
+	Targets []string `json:"targets"`
+}
+
+type languageSet []language
+
+// resolve accepts a language code or English name ("es", "Spanish")
+func (s languageSet) resolve(v string) (string, error) {
+	v = strings.TrimSpace(v)
+	for _, l := range s {
+		if strings.EqualFold(l.Code, v) || strings.EqualFold(l.Name, v) {
+			return l.Code, nil
+		}
+	}
+	return "", agenterrors.Newf(agenterrors.InvalidParams, "unsupported language: %s", v)
+}
+
+func (s languageSet) name(code string) string {
+	for _, l := range s {
+		if l.Code == code {
+			return l.Name
+		}
+	}
+	return code
+}
+
+// supports reports whether the server can translate source to target.
+// Older servers omit targets, in which case every pair is assumed to work.
+func (s languageSet) supports(source, target string) bool {
+	for _, l := range s {
+		if l.Code != source {
+			continue
+		}
+		if l.Targets == nil {
+			return true
+		}
+		for _, t := range l.Targets {
+			if t == target {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+func (c *client) languages(ctx context.Context) (languageSet, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.langs != nil && time.Since(c.fetched) < languagesTTL {
+		return c.langs, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+"/languages", nil)
+	if err != nil {
+		return nil, err
+	}
+	var langs languageSet
+	if err := c.do(req, &langs); err != nil {
+		if c.langs != nil {
+			return c.langs, nil
+		}
+		return nil, err
+	}
+	if len(langs) == 0 {
+		return nil, errors.New("translation server reports no languages")
+	}
+	c.langs, c.fetched = langs, time.Now()
+	return langs, nil
+}
+
+// translation is the response of POST /translate
+type translation struct {
+	Text     string `json:"translatedText"`
+	Detected struct {
+		Language   string  `json:"language"`
+		Confidence float64 `json:"confidence"`
+	} `json:"detectedLanguage"`
+}
+
+func (c *client) translate(ctx context.Context, text, source, target string) (*translation, error) {
+	body, err := json.Marshal(map[string]string{
+		"q":       text,
+		"source":  source,
+		"target":  target,
+		"format":  "text",
+		"api_key": c.apiKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+"/translate", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var t translation
+	if err := c.do(req, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// do sends a request and decodes a JSON response, surfacing the server's
+// {"error": "..."} message on failure
+func (c *client) do(req *http.Request, v interface{}) error {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return agenterrors.Newf(agenterrors.Unavailable, "translation server unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read translation response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &e) == nil && e.Error != "" {
+			return fmt.Errorf("translation failed: %s", e.Error)
+		}
+		return fmt.Errorf("translation server returned %s", resp.Status)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid translation response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+// Package translate answers text.translate with a self-hosted
+// LibreTranslate (Argos) server so the text being translated never leaves
+// the local network
+package translate
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// DefaultURL is where LibreTranslate listens when run locally
+const DefaultURL = "http://127.0.0.1:5000"
+
+// Config points the executor at a translation server
+type Config struct {
+	URL     string        // defaults to DefaultURL
+	APIKey  string        // only needed if the server requires keys
+	Timeout time.Duration // per request (default 30s; local models can be slow)
+	// MaxChars bounds the text per request (default 5000)
+	MaxChars int
+	// AllowRemote permits servers outside loopback and private networks.
+	// It is off by default because the point is keeping user text on-device.
+	AllowRemote bool
+}
+
+// Executor handles text.translate
+type Executor struct {
+	cfg    Config
+	client *client
+}
+
+// NewExecutor creates a translate executor
+func NewExecutor(cfg Config) *Executor {
+	if cfg.URL == "" {
+		cfg.URL = DefaultURL
+	}
+	cfg.URL = strings.TrimRight(cfg.URL, "/")
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.MaxChars <= 0 {
+		cfg.MaxChars = 5000
+	}
+	return &Executor{
+		cfg:    cfg,
+		client: newClient(cfg),
+	}
+}
+
+func (e *Executor) Name() string {
+	return "translate"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"text.translate"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{Network: true}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "translate",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	if i.IntentType != "text.translate" {
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+		return result, nil
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	var params struct {
+		Text   string `param:"text,required"`
+		Target string `param:"target,required"`
+		Source string `param:"source"`
+	}
+	if err := i.DecodeParams(&params); err != nil {
+		return fail(err)
+	}
+	if n := utf8.RuneCountInString(params.Text); n > e.cfg.MaxChars {
+		return fail(agenterrors.Newf(agenterrors.InvalidParams, "text is %d characters; the limit is %d", n, e.cfg.MaxChars))
+	}
+
+	languages, err := e.client.languages(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	target, err := languages.resolve(params.Target)
+	if err != nil {
+		return fail(err)
+	}
+	source := "auto"
+	if params.Source != "" && !strings.EqualFold(params.Source, "auto") {
+		if source, err = languages.resolve(params.Source); err != nil {
+			return fail(err)
+		}
+		if !languages.supports(source, target) {
+			return fail(agenterrors.Newf(agenterrors.InvalidParams, "the server cannot translate %s to %s", languages.name(source), languages.name(target)))
+		}
+	}
+
+	translated, err := e.client.translate(ctx, params.Text, source, target)
+	if err != nil {
+		return fail(err)
+	}
+	if source == "auto" {
+		source = translated.Detected.Language
+	}
+
+	result.Success = true
+	result.Result = map[string]interface{}{
+		"text":        translated.Text,
+		"source":      source,
+		"source_name": languages.name(source),
+		"target":      target,
+		"target_name": languages.name(target),
+	}
+	if params.Source == "" || strings.EqualFold(params.Source, "auto") {
+		result.Result["detected"] = true
+		result.Result["detection_confidence"] = translated.Detected.Confidence
+	}
+	return result, nil
+}
+
+func (e *Executor) IsAvailable() bool {
+	return true
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"text.translate": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"text": {"type": "string", "minLength": 1},
+				"target": {"type": "string", "minLength": 2},
+				"source": {"type": "string"}
+			},
+			"required": ["text", "target"]
+		}`),
+	}
+}
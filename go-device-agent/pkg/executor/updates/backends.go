@@ -0,0 +1,192 @@
+package updates
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Update is a package with a newer version available
+type Update struct {
+	Name      string `json:"name"`
+	Current   string `json:"current,omitempty"`
+	Available string `json:"available"`
+
+	// Source is the repository or origin offering the update
+	Source string `json:"source,omitempty"`
+
+	// Security is set for updates from a security repository
+	Security bool `json:"security,omitempty"`
+}
+
+// backend drives a package manager. Commands are argument lists run
+// without a shell; privileged ones go through sudo when configured.
+type backend struct {
+	name string
+
+	// refresh updates the package lists, when checking doesn't already
+	refresh []string
+
+	// check lists pending updates; okExit are exit codes besides 0 that
+	// still mean it worked
+	check  []string
+	okExit []int
+	parse  func(out string) ([]Update, error)
+
+	// upgrade applies every update, and upgradeSome the named packages'
+	// (nil if the package manager can't upgrade only some)
+	upgrade     []string
+	upgradeSome []string
+
+	// privileged backends need root to refresh and upgrade
+	privileged bool
+
+	env []string
+}
+
+// backends in the order they are looked for
+var backends = []*backend{
+	{
+		name:    "apt",
+		refresh: []string{"apt-get", "update", "-q"},
+		// A simulated upgrade works without root and, unlike apt list,
+		// shows which origin each update comes from
+		check:       []string{"apt-get", "-s", "-q", "upgrade"},
+		parse:       parseApt,
+		upgrade:     []string{"apt-get", "-y", "-q", "upgrade"},
+		upgradeSome: []string{"apt-get", "-y", "-q", "install", "--only-upgrade"},
+		privileged:  true,
+		env:         []string{"DEBIAN_FRONTEND=noninteractive"},
+	},
+	{
+		name: "dnf",
+		// check-update refreshes expired metadata itself, and exits 100
+		// when there are updates
+		check:       []string{"dnf", "check-update", "-q"},
+		okExit:      []int{100},
+		parse:       parseDnf,
+		upgrade:     []string{"dnf", "-y", "-q", "upgrade"},
+		upgradeSome: []string{"dnf", "-y", "-q", "upgrade"},
+		privileged:  true,
+	},
+	{
+		name: "pacman",
+		// checkupdates (from pacman-contrib) syncs a copy of the databases
+		// without root, and exits 2 when there are no updates. Arch doesn't
+		// support upgrading only some packages.
+		check:      []string{"checkupdates"},
+		okExit:     []int{2},
+		parse:      parsePacman,
+		upgrade:    []string{"pacman", "-Syu", "--noconfirm"},
+		privileged: true,
+	},
+	{
+		name:        "brew",
+		refresh:     []string{"brew", "update", "--quiet"},
+		check:       []string{"brew", "outdated", "--json=v2"},
+		parse:       parseBrew,
+		upgrade:     []string{"brew", "upgrade"},
+		upgradeSome: []string{"brew", "upgrade"},
+		// Homebrew refuses to run as root
+		env: []string{"HOMEBREW_NO_AUTO_UPDATE=1", "HOMEBREW_NO_ENV_HINTS=1"},
+	},
+}
+
+// aptInst matches a simulated install: "Inst name [current] (available
+// origin, origin [arch])"
+var aptInst = regexp.MustCompile(`^Inst (\S+) (?:\[([^\]]*)\] )?\((\S+) ([^\[)]*)`)
+
+func parseApt(out string) ([]Update, error) {
+	var updates []Update
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		m := aptInst.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		origin := strings.TrimSpace(m[4])
+		updates = append(updates, Update{
+			Name:      m[1],
+			Current:   m[2],
+			Available: m[3],
+			Source:    origin,
+			Security:  strings.Contains(origin, "-security"),
+		})
+	}
+	return updates, scanner.Err()
+}
+
+func parseDnf(out string) ([]Update, error) {
+	var updates []Update
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Packages obsoleted by others are listed after the updates
+		if strings.HasPrefix(line, "Obsoleting") {
+			break
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		name := fields[0]
+		if dot := strings.LastIndexByte(name, '.'); dot > 0 {
+			name = name[:dot]
+		}
+		updates = append(updates, Update{
+			Name:      name,
+			Available: fields[1],
+			Source:    fields[2],
+			Security:  strings.Contains(fields[2], "security"),
+		})
+	}
+	return updates, scanner.Err()
+}
+
+// parsePacman reads "name current -> available" lines
+func parsePacman(out string) ([]Update, error) {
+	var updates []Update
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[2] != "->" {
+			continue
+		}
+		updates = append(updates, Update{Name: fields[0], Current: fields[1], Available: fields[3]})
+	}
+	return updates, scanner.Err()
+}
+
+func parseBrew(out string) ([]Update, error) {
+	type outdated struct {
+		Name              string      `json:"name"`
+		InstalledVersions interface{} `json:"installed_versions"`
+		CurrentVersion    string      `json:"current_version"`
+	}
+	var parsed struct {
+		Formulae []outdated `json:"formulae"`
+		Casks    []outdated `json:"casks"`
+	}
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		return nil, fmt.Errorf("unexpected brew output: %w", err)
+	}
+	var updates []Update
+	for source, list := range map[string][]outdated{"formula": parsed.Formulae, "cask": parsed.Casks} {
+		for _, o := range list {
+			// Formulae list every installed version, casks just one
+			current := ""
+			switch v := o.InstalledVersions.(type) {
+			case string:
+				current = v
+			case []interface{}:
+				if len(v) > 0 {
+					current = fmt.Sprint(v[len(v)-1])
+				}
+			}
+			updates = append(updates, Update{Name: o.Name, Current: current, Available: o.CurrentVersion, Source: source})
+		}
+	}
+	return updates, nil
+}
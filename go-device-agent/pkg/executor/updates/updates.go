@@ -0,0 +1,360 @@
+// Package updates answers system.check_updates and system.apply_updates
+// through the host's package manager: apt, dnf, pacman, or Homebrew
+package updates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// rebootFlag is where Debian and Ubuntu note that an update needs a reboot
+const rebootFlag = "/var/run/reboot-required"
+
+// maxOutput is how much of an upgrade's output is returned, from the end
+const maxOutput = 8 << 10
+
+// packageName is what packages named in system.apply_updates must look
+// like, so none can pass for an option
+var packageName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9+._@:/-]*$`)
+
+// Config picks the package manager and how upgrades get root
+type Config struct {
+	// Backend is apt, dnf, pacman, or brew; the first installed one is
+	// used if empty
+	Backend string
+
+	// Sudo runs refreshes and upgrades through non-interactive sudo when
+	// the agent isn't root. The agent's user needs a sudoers rule allowing
+	// the package manager without a password.
+	Sudo bool
+
+	// CheckTimeout bounds a check, including a refresh (default 5m)
+	CheckTimeout time.Duration
+
+	// ApplyTimeout bounds an upgrade (default 1h)
+	ApplyTimeout time.Duration
+}
+
+// Executor handles system.check_updates and system.apply_updates
+type Executor struct {
+	cfg     Config
+	backend *backend
+}
+
+// NewExecutor finds the package manager, failing when there is none
+func NewExecutor(cfg Config) (*Executor, error) {
+	if cfg.CheckTimeout <= 0 {
+		cfg.CheckTimeout = 5 * time.Minute
+	}
+	if cfg.ApplyTimeout <= 0 {
+		cfg.ApplyTimeout = time.Hour
+	}
+	for _, b := range backends {
+		if cfg.Backend != "" && b.name != cfg.Backend {
+			continue
+		}
+		if _, err := exec.LookPath(b.check[0]); err != nil {
+			if cfg.Backend != "" {
+				return nil, fmt.Errorf("%s not found", b.check[0])
+			}
+			continue
+		}
+		return &Executor{cfg: cfg, backend: b}, nil
+	}
+	if cfg.Backend != "" {
+		return nil, fmt.Errorf("unknown package manager %q: use apt, dnf, pacman, or brew", cfg.Backend)
+	}
+	return nil, errors.New("no supported package manager found (apt, dnf, pacman with pacman-contrib, or brew)")
+}
+
+func (e *Executor) Name() string {
+	return "updates"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"system.check_updates", "system.apply_updates"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{Network: true, Subprocess: true}
+}
+
+// PermissionRequired makes upgrading need requires_permission
+func (e *Executor) PermissionRequired() []string {
+	return []string{"system.apply_updates"}
+}
+
+// Exclusions lets checks share the package manager but never while an
+// upgrade or refresh holds it, since package managers lock their
+// databases
+func (e *Executor) Exclusions(i *intent.Intent) []gateway.Exclusion {
+	refresh, _ := i.Parameters["refresh"].(bool)
+	dryRun, _ := i.Parameters["dry_run"].(bool)
+	shared := (i.IntentType == "system.check_updates" && !refresh) || (i.IntentType == "system.apply_updates" && dryRun)
+	return []gateway.Exclusion{{Group: "system:updates", Shared: shared}}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "updates",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	var params struct {
+		Refresh      bool     `param:"refresh"`
+		SecurityOnly bool     `param:"security_only"`
+		Packages     []string `param:"packages"`
+		DryRun       bool     `param:"dry_run"`
+	}
+	if err := i.DecodeParams(&params); err != nil {
+		return fail(err)
+	}
+
+	switch i.IntentType {
+	case "system.check_updates":
+		if params.SecurityOnly && e.backend.name != "apt" && e.backend.name != "dnf" {
+			return fail(agenterrors.Newf(agenterrors.Unsupported, "%s doesn't mark security updates", e.backend.name))
+		}
+		checkCtx, cancel := context.WithTimeout(ctx, e.cfg.CheckTimeout)
+		defer cancel()
+		if params.Refresh && e.backend.refresh != nil {
+			if _, err := e.run(checkCtx, e.backend.refresh, e.backend.privileged, nil, nil); err != nil {
+				return fail(err)
+			}
+		}
+		pending, err := e.pending(checkCtx)
+		if err != nil {
+			return fail(err)
+		}
+		security := 0
+		for _, u := range pending {
+			if u.Security {
+				security++
+			}
+		}
+		if params.SecurityOnly {
+			pending = slices.DeleteFunc(pending, func(u Update) bool { return !u.Security })
+		}
+		out := map[string]interface{}{
+			"backend":    e.backend.name,
+			"updates":    pending,
+			"count":      len(pending),
+			"up_to_date": len(pending) == 0,
+			"checked_at": time.Now().UTC().Format(time.RFC3339),
+		}
+		if e.backend.name == "apt" || e.backend.name == "dnf" {
+			out["security_count"] = security
+		}
+		if rebootRequired() {
+			out["reboot_required"] = true
+		}
+		result.Success = true
+		result.Result = out
+
+	case "system.apply_updates":
+		for _, p := range params.Packages {
+			if !packageName.MatchString(p) {
+				return fail(agenterrors.Newf(agenterrors.InvalidParams, "invalid package name %q", p))
+			}
+		}
+		if len(params.Packages) > 0 && e.backend.upgradeSome == nil {
+			return fail(agenterrors.Newf(agenterrors.Unsupported, "%s can't upgrade only some packages; leave out packages to upgrade everything", e.backend.name))
+		}
+		checkCtx, cancel := context.WithTimeout(ctx, e.cfg.CheckTimeout)
+		defer cancel()
+		pending, err := e.pending(checkCtx)
+		if err != nil {
+			return fail(err)
+		}
+		if len(params.Packages) > 0 {
+			var missing []string
+			for _, p := range params.Packages {
+				if !slices.ContainsFunc(pending, func(u Update) bool { return u.Name == p }) {
+					missing = append(missing, p)
+				}
+			}
+			if len(missing) > 0 {
+				return fail(agenterrors.Newf(agenterrors.NotFound, "no updates pending for %s", strings.Join(missing, ", ")))
+			}
+			pending = slices.DeleteFunc(pending, func(u Update) bool { return !slices.Contains(params.Packages, u.Name) })
+		}
+
+		out := map[string]interface{}{
+			"backend": e.backend.name,
+			"updates": pending,
+			"count":   len(pending),
+		}
+		if params.DryRun || len(pending) == 0 {
+			out["dry_run"] = params.DryRun
+			result.Success = true
+			result.Result = out
+			break
+		}
+
+		argv := e.backend.upgrade
+		if len(params.Packages) > 0 {
+			argv = append(slices.Clone(e.backend.upgradeSome), params.Packages...)
+		}
+		applyCtx, cancel := context.WithTimeout(ctx, e.cfg.ApplyTimeout)
+		defer cancel()
+		started := time.Now()
+		output := &tail{}
+		_, err = e.run(applyCtx, argv, e.backend.privileged, nil, output)
+		out["output"] = output.String()
+		out["duration_ms"] = time.Since(started).Milliseconds()
+		if rebootRequired() {
+			out["reboot_required"] = true
+		}
+		result.Result = out
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+
+	default:
+		return fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+	return result, nil
+}
+
+// pending lists the updates available, by name
+func (e *Executor) pending(ctx context.Context) ([]Update, error) {
+	out, err := e.run(ctx, e.backend.check, false, e.backend.okExit, nil)
+	if err != nil {
+		return nil, err
+	}
+	updates, err := e.backend.parse(out)
+	if err != nil {
+		return nil, agenterrors.Wrap(agenterrors.Internal, err)
+	}
+	if updates == nil {
+		updates = []Update{}
+	}
+	sort.Slice(updates, func(a, b int) bool { return updates[a].Name < updates[b].Name })
+	return updates, nil
+}
+
+// run runs a package manager command with a stable locale, through sudo
+// when it needs root and is allowed to, and returns its output. Exit codes
+// in okExit count as success. With w, output goes there instead.
+func (e *Executor) run(ctx context.Context, argv []string, privileged bool, okExit []int, w *tail) (string, error) {
+	if privileged && os.Geteuid() != 0 {
+		if !e.cfg.Sudo {
+			return "", agenterrors.Newf(agenterrors.Unauthorized, "%s needs root; run the agent as root or allow sudo (-updates-sudo)", argv[0])
+		}
+		argv = append([]string{"sudo", "-n", "--"}, argv...)
+	}
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	cmd.Env = append(cmd.Env, e.backend.env...)
+	var stdout, stderr strings.Builder
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if w != nil {
+		cmd.Stdout, cmd.Stderr = w, w
+	}
+	cmd.WaitDelay = 5 * time.Second
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return "", agenterrors.Newf(agenterrors.Timeout, "%s timed out", argv[0])
+	case ctx.Err() != nil:
+		return "", agenterrors.Newf(agenterrors.Cancelled, "%s was cancelled", argv[0])
+	case errors.As(err, &exitErr):
+		if slices.Contains(okExit, exitErr.ExitCode()) {
+			return stdout.String(), nil
+		}
+		msg := stderr.String()
+		if w != nil {
+			msg = w.String()
+		}
+		if argv[0] == "sudo" && strings.Contains(msg, "password is required") {
+			return "", agenterrors.Newf(agenterrors.Unauthorized, "sudo needs a password for %s; add a NOPASSWD sudoers rule", argv[3])
+		}
+		return "", agenterrors.Newf(agenterrors.Unavailable, "%s exited with status %d: %s", strings.Join(argv, " "), exitErr.ExitCode(), lastLine(msg))
+	case err != nil:
+		return "", agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	return stdout.String(), nil
+}
+
+func rebootRequired() bool {
+	_, err := os.Stat(rebootFlag)
+	return err == nil
+}
+
+func lastLine(s string) string {
+	s = strings.TrimSpace(s)
+	if n := strings.LastIndexByte(s, '\n'); n >= 0 {
+		return s[n+1:]
+	}
+	return s
+}
+
+// tail keeps the last maxOutput bytes written to it
+type tail struct {
+	buf     []byte
+	dropped bool
+}
+
+func (t *tail) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if over := len(t.buf) - maxOutput; over > 0 {
+		t.buf = t.buf[over:]
+		t.dropped = true
+	}
+	return len(p), nil
+}
+
+func (t *tail) String() string {
+	s := strings.ToValidUTF8(string(t.buf), "")
+	if t.dropped {
+		return "…" + s
+	}
+	return s
+}
+
+func (e *Executor) IsAvailable() bool {
+	return e.backend != nil
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"system.check_updates": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"refresh": {"type": "boolean"},
+				"security_only": {"type": "boolean"}
+			}
+		}`),
+		"system.apply_updates": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"packages": {"type": "array", "items": {"type": "string", "minLength": 1}, "maxItems": 100},
+				"dry_run": {"type": "boolean"}
+			}
+		}`),
+	}
+}
@@ -0,0 +1,264 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+)
+
+// AlertSource is a government weather warning feed
+type AlertSource interface {
+	Name() string
+
+	// Alerts returns the warnings in force for a place
+	Alerts(ctx context.Context, loc Location) ([]Alert, error)
+}
+
+// Alert is a weather warning
+type Alert struct {
+	ID     string `json:"id"`
+	Source string `json:"source"`
+
+	// Event is the warning's kind, e.g. "Tornado Warning" or "Wind"
+	Event string `json:"event"`
+
+	// Severity is minor, moderate, severe, extreme, or unknown (CAP's
+	// scale); Urgency and Certainty are CAP's too
+	Severity  string `json:"severity"`
+	Urgency   string `json:"urgency,omitempty"`
+	Certainty string `json:"certainty,omitempty"`
+
+	Headline    string `json:"headline,omitempty"`
+	Description string `json:"description,omitempty"`
+	Instruction string `json:"instruction,omitempty"`
+	Areas       string `json:"areas,omitempty"`
+	Onset       string `json:"onset,omitempty"`
+	Expires     string `json:"expires,omitempty"`
+	URL         string `json:"url,omitempty"`
+
+	// replaces lists earlier alerts this one updates
+	replaces []string
+}
+
+// Severe reports whether an alert is severe or extreme, the levels worth
+// acting on unprompted
+func (a Alert) Severe() bool {
+	return severityRank(a.Severity) >= severityRank("severe")
+}
+
+func severityRank(severity string) int {
+	switch severity {
+	case "minor":
+		return 1
+	case "moderate":
+		return 2
+	case "severe":
+		return 3
+	case "extreme":
+		return 4
+	}
+	return 0
+}
+
+// Start polls the alert sources for the default location immediately and
+// then every AlertInterval until ctx is cancelled. Without alert sources it
+// does nothing.
+func (e *Executor) Start(ctx context.Context) {
+	if len(e.cfg.Alerts) == 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(e.cfg.AlertInterval)
+		defer ticker.Stop()
+		for {
+			e.PollAlerts(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// PollAlerts checks every alert source once for the default location,
+// publishing weather.alert for warnings that start and weather.alert_ended
+// for those that end. A source that fails keeps its last warnings.
+func (e *Executor) PollAlerts(ctx context.Context) error {
+	loc, err := e.locate(ctx, "")
+	if err != nil {
+		e.mu.Lock()
+		e.alertErrors = map[string]string{"location": err.Error()}
+		e.polled = time.Now()
+		e.mu.Unlock()
+		return err
+	}
+
+	current := make(map[string][]Alert, len(e.cfg.Alerts))
+	failures := make(map[string]string)
+	for _, source := range e.cfg.Alerts {
+		alerts, err := source.Alerts(ctx, loc)
+		if err != nil {
+			failures[source.Name()] = err.Error()
+			continue
+		}
+		current[source.Name()] = alerts
+	}
+
+	var started, ended []Alert
+	e.mu.Lock()
+	for name, alerts := range current {
+		previous := make(map[string]Alert, len(e.alerts[name]))
+		for _, a := range e.alerts[name] {
+			previous[a.ID] = a
+		}
+		for _, a := range alerts {
+			_, known := previous[a.ID]
+			for _, id := range a.replaces {
+				if _, ok := previous[id]; ok {
+					known = true
+					delete(previous, id)
+				}
+			}
+			delete(previous, a.ID)
+			// Warnings in force when the agent starts are announced too
+			if !known {
+				started = append(started, a)
+			}
+		}
+		for _, a := range previous {
+			ended = append(ended, a)
+		}
+		e.alerts[name] = alerts
+	}
+	e.alertErrors = failures
+	e.polled = time.Now()
+	e.mu.Unlock()
+
+	if e.bus != nil {
+		for _, a := range started {
+			e.bus.Publish(events.Event{
+				Type:   "weather.alert",
+				Source: "weather",
+				Data: map[string]interface{}{
+					"id":       a.ID,
+					"source":   a.Source,
+					"event":    a.Event,
+					"severity": a.Severity,
+					"severe":   a.Severe(),
+					"headline": a.Headline,
+					"areas":    a.Areas,
+					"onset":    a.Onset,
+					"expires":  a.Expires,
+					"message":  alertMessage(a),
+				},
+			})
+		}
+		for _, a := range ended {
+			e.bus.Publish(events.Event{
+				Type:   "weather.alert_ended",
+				Source: "weather",
+				Data: map[string]interface{}{
+					"id":       a.ID,
+					"source":   a.Source,
+					"event":    a.Event,
+					"severity": a.Severity,
+				},
+			})
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("alert sources failed: %v", failures)
+	}
+	return nil
+}
+
+// alertMessage is a one-line summary for notifications
+func alertMessage(a Alert) string {
+	if a.Headline != "" {
+		return a.Headline
+	}
+	message := fmt.Sprintf("%s warning (%s)", a.Event, a.Severity)
+	if a.Areas != "" {
+		message += " for " + a.Areas
+	}
+	return message
+}
+
+// queryAlerts answers weather.alerts: the polled warnings for the default
+// location, or a fresh check for any other
+func (e *Executor) queryAlerts(ctx context.Context, location, severity string) (map[string]interface{}, error) {
+	if len(e.cfg.Alerts) == 0 {
+		return nil, agenterrors.New(agenterrors.Unavailable, "no weather alert feeds configured")
+	}
+	minimum := 0
+	if severity != "" {
+		severity = strings.ToLower(severity)
+		if minimum = severityRank(severity); minimum == 0 {
+			return nil, agenterrors.Newf(agenterrors.InvalidParams, "unknown severity '%s' (want minor, moderate, severe, or extreme)", severity)
+		}
+	}
+
+	var alerts []Alert
+	failures := make(map[string]string)
+	var checked time.Time
+	e.mu.Lock()
+	polled := !e.polled.IsZero() && (location == "" || strings.EqualFold(location, e.cfg.Location))
+	if polled {
+		for _, a := range e.alerts {
+			alerts = append(alerts, a...)
+		}
+		for name, err := range e.alertErrors {
+			failures[name] = err
+		}
+		checked = e.polled
+	}
+	e.mu.Unlock()
+
+	if !polled {
+		loc, err := e.locate(ctx, location)
+		if err != nil {
+			return nil, err
+		}
+		for _, source := range e.cfg.Alerts {
+			found, err := source.Alerts(ctx, loc)
+			if err != nil {
+				failures[source.Name()] = err.Error()
+				continue
+			}
+			alerts = append(alerts, found...)
+		}
+		if len(failures) == len(e.cfg.Alerts) {
+			return nil, agenterrors.Newf(agenterrors.Unavailable, "no alert feed answered: %v", failures)
+		}
+		checked = time.Now()
+	} else if location == "" {
+		location = e.cfg.Location
+	}
+
+	matched := make([]Alert, 0, len(alerts))
+	for _, a := range alerts {
+		if severityRank(a.Severity) >= minimum {
+			matched = append(matched, a)
+		}
+	}
+	sort.SliceStable(matched, func(a, b int) bool {
+		return severityRank(matched[a].Severity) > severityRank(matched[b].Severity)
+	})
+
+	result := map[string]interface{}{
+		"location":     location,
+		"alerts":       matched,
+		"count":        len(matched),
+		"last_checked": checked.Format(time.RFC3339),
+	}
+	if len(failures) > 0 {
+		result["errors"] = failures
+	}
+	return result, nil
+}
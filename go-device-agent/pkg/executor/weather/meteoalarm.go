@@ -0,0 +1,123 @@
+package weather
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+)
+
+// meteoAlarmFeeds is where MeteoAlarm publishes one Atom feed per country
+const meteoAlarmFeeds = "https://feeds.meteoalarm.org/feeds/meteoalarm-legacy-atom-"
+
+// MeteoAlarm is the European national weather services' warnings, read
+// from a country's MeteoAlarm feed
+type MeteoAlarm struct {
+	// Country is the feed name, e.g. "germany" or "united-kingdom"
+	Country string
+
+	// Areas keeps warnings whose area names contain one of these; without
+	// any, the location's region and name are matched, and a location with
+	// neither (given as coordinates) keeps the whole country's warnings
+	Areas []string
+
+	// FeedURL overrides the country's feed
+	FeedURL string
+}
+
+func (m *MeteoAlarm) Name() string {
+	return "meteoalarm"
+}
+
+func (m *MeteoAlarm) Alerts(ctx context.Context, loc Location) ([]Alert, error) {
+	feed := m.FeedURL
+	if feed == "" {
+		feed = meteoAlarmFeeds + m.Country
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feed, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := connpool.Default.HTTP(20 * time.Second).Do(req)
+	if err != nil {
+		return nil, agenterrors.Newf(agenterrors.Unavailable, "MeteoAlarm unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, agenterrors.Newf(agenterrors.NotFound, "MeteoAlarm has no feed for '%s'", m.Country)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, agenterrors.Newf(agenterrors.Unavailable, "MeteoAlarm: %s", resp.Status)
+	}
+
+	// Entries carry CAP fields; tags without a namespace match any
+	var atom struct {
+		Entries []struct {
+			Title string `xml:"title"`
+			Link  struct {
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+			Identifier  string `xml:"identifier"`
+			Event       string `xml:"event"`
+			Severity    string `xml:"severity"`
+			Urgency     string `xml:"urgency"`
+			Certainty   string `xml:"certainty"`
+			AreaDesc    string `xml:"areaDesc"`
+			Onset       string `xml:"onset"`
+			Effective   string `xml:"effective"`
+			Expires     string `xml:"expires"`
+			MessageType string `xml:"message_type"`
+		} `xml:"entry"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 8<<20)).Decode(&atom); err != nil {
+		return nil, fmt.Errorf("invalid MeteoAlarm feed: %w", err)
+	}
+
+	areas := m.Areas
+	if len(areas) == 0 {
+		for _, name := range []string{loc.Region, loc.Name} {
+			if name != "" {
+				areas = append(areas, name)
+			}
+		}
+	}
+	var all, matched []Alert
+	for _, e := range atom.Entries {
+		if strings.EqualFold(e.MessageType, "cancel") {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, e.Expires); err == nil && t.Before(time.Now()) {
+			continue
+		}
+		a := Alert{
+			ID:        firstOf(e.Identifier, e.Link.Href),
+			Source:    "meteoalarm",
+			Event:     e.Event,
+			Severity:  strings.ToLower(e.Severity),
+			Urgency:   strings.ToLower(e.Urgency),
+			Certainty: strings.ToLower(e.Certainty),
+			Headline:  e.Title,
+			Areas:     e.AreaDesc,
+			Onset:     firstOf(e.Onset, e.Effective),
+			Expires:   e.Expires,
+			URL:       e.Link.Href,
+		}
+		all = append(all, a)
+		for _, area := range areas {
+			if strings.Contains(strings.ToLower(a.Areas), strings.ToLower(area)) {
+				matched = append(matched, a)
+				break
+			}
+		}
+	}
+	if len(areas) == 0 {
+		return all, nil
+	}
+	return matched, nil
+}
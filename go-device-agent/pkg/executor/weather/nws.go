@@ -0,0 +1,116 @@
+package weather
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+const (
+	nwsAPI = "https://api.weather.gov"
+
+	// nwsUserAgent identifies the agent, which the NWS API requires
+	nwsUserAgent = "local-agent-core device agent"
+)
+
+// NWS is the US National Weather Service's active alerts, which only
+// cover US locations
+type NWS struct {
+	// UserAgent overrides the default; NWS asks for contact details in it
+	UserAgent string
+
+	// APIURL overrides the NWS API base URL
+	APIURL string
+}
+
+func (n *NWS) Name() string {
+	return "nws"
+}
+
+func (n *NWS) Alerts(ctx context.Context, loc Location) ([]Alert, error) {
+	base := n.APIURL
+	if base == "" {
+		base = nwsAPI
+	}
+	point := strconv.FormatFloat(loc.Latitude, 'f', 4, 64) + "," + strconv.FormatFloat(loc.Longitude, 'f', 4, 64)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/alerts/active?"+url.Values{"point": {point}}.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	agent := n.UserAgent
+	if agent == "" {
+		agent = nwsUserAgent
+	}
+	req.Header.Set("User-Agent", agent)
+	req.Header.Set("Accept", "application/geo+json")
+
+	var resp struct {
+		Features []struct {
+			Properties struct {
+				ID          string `json:"id"`
+				Event       string `json:"event"`
+				Severity    string `json:"severity"`
+				Urgency     string `json:"urgency"`
+				Certainty   string `json:"certainty"`
+				Headline    string `json:"headline"`
+				Description string `json:"description"`
+				Instruction string `json:"instruction"`
+				AreaDesc    string `json:"areaDesc"`
+				Onset       string `json:"onset"`
+				Effective   string `json:"effective"`
+				Expires     string `json:"expires"`
+				Ends        string `json:"ends"`
+				MessageType string `json:"messageType"`
+				References  []struct {
+					Identifier string `json:"identifier"`
+				} `json:"references"`
+			} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := doJSON("NWS", req, &resp); err != nil {
+		if agenterrors.CodeOf(err) == agenterrors.InvalidParams {
+			return nil, agenterrors.New(agenterrors.Unsupported, "NWS alerts only cover the United States")
+		}
+		return nil, err
+	}
+
+	alerts := make([]Alert, 0, len(resp.Features))
+	for _, f := range resp.Features {
+		p := f.Properties
+		if p.MessageType == "Cancel" {
+			continue
+		}
+		a := Alert{
+			ID:          p.ID,
+			Source:      "nws",
+			Event:       p.Event,
+			Severity:    strings.ToLower(p.Severity),
+			Urgency:     strings.ToLower(p.Urgency),
+			Certainty:   strings.ToLower(p.Certainty),
+			Headline:    p.Headline,
+			Description: p.Description,
+			Instruction: p.Instruction,
+			Areas:       p.AreaDesc,
+			Onset:       firstOf(p.Onset, p.Effective),
+			Expires:     firstOf(p.Ends, p.Expires),
+		}
+		for _, r := range p.References {
+			a.replaces = append(a.replaces, r.Identifier)
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, nil
+}
+
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
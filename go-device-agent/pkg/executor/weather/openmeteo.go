@@ -0,0 +1,211 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+)
+
+// Open-Meteo endpoints
+const (
+	openMeteoForecast  = "https://api.open-meteo.com/v1/forecast"
+	openMeteoGeocoding = "https://geocoding-api.open-meteo.com/v1/search"
+)
+
+// OpenMeteo is the Open-Meteo API, which needs no key
+type OpenMeteo struct {
+	// ForecastURL and GeocodingURL override the Open-Meteo endpoints
+	ForecastURL  string
+	GeocodingURL string
+}
+
+func (o *OpenMeteo) Name() string {
+	return "open-meteo"
+}
+
+func (o *OpenMeteo) Endpoint() string {
+	if o.ForecastURL != "" {
+		return o.ForecastURL
+	}
+	return openMeteoForecast
+}
+
+func (o *OpenMeteo) Geocode(ctx context.Context, query string) (Location, error) {
+	endpoint := o.GeocodingURL
+	if endpoint == "" {
+		endpoint = openMeteoGeocoding
+	}
+	var resp struct {
+		Results []struct {
+			Name      string  `json:"name"`
+			Admin1    string  `json:"admin1"`
+			Country   string  `json:"country"`
+			Latitude  float64 `json:"latitude"`
+			Longitude float64 `json:"longitude"`
+		} `json:"results"`
+	}
+	q := url.Values{"name": {query}, "count": {"1"}, "format": {"json"}}
+	if err := getJSON(ctx, "Open-Meteo", endpoint+"?"+q.Encode(), &resp); err != nil {
+		return Location{}, err
+	}
+	if len(resp.Results) == 0 {
+		return Location{}, agenterrors.Newf(agenterrors.NotFound, "no place called '%s'", query)
+	}
+	r := resp.Results[0]
+	return Location{Name: r.Name, Region: r.Admin1, Country: r.Country, Latitude: r.Latitude, Longitude: r.Longitude}, nil
+}
+
+func (o *OpenMeteo) Forecast(ctx context.Context, loc Location, days int, units Units) (*Report, error) {
+	endpoint := o.ForecastURL
+	if endpoint == "" {
+		endpoint = openMeteoForecast
+	}
+	q := url.Values{
+		"latitude":      {strconv.FormatFloat(loc.Latitude, 'f', 4, 64)},
+		"longitude":     {strconv.FormatFloat(loc.Longitude, 'f', 4, 64)},
+		"current":       {"temperature_2m,apparent_temperature,relative_humidity_2m,wind_speed_10m,weather_code"},
+		"daily":         {"weather_code,temperature_2m_max,temperature_2m_min,precipitation_sum,precipitation_probability_max"},
+		"forecast_days": {strconv.Itoa(days)},
+		"timezone":      {"auto"},
+	}
+	if units == Imperial {
+		q.Set("temperature_unit", "fahrenheit")
+		q.Set("wind_speed_unit", "mph")
+		q.Set("precipitation_unit", "inch")
+	}
+	var resp struct {
+		Current struct {
+			Temperature float64 `json:"temperature_2m"`
+			FeelsLike   float64 `json:"apparent_temperature"`
+			Humidity    float64 `json:"relative_humidity_2m"`
+			WindSpeed   float64 `json:"wind_speed_10m"`
+			Code        int     `json:"weather_code"`
+		} `json:"current"`
+		Daily struct {
+			Time                []string   `json:"time"`
+			Code                []int      `json:"weather_code"`
+			High                []float64  `json:"temperature_2m_max"`
+			Low                 []float64  `json:"temperature_2m_min"`
+			Precipitation       []float64  `json:"precipitation_sum"`
+			PrecipitationChance []*float64 `json:"precipitation_probability_max"`
+		} `json:"daily"`
+	}
+	if err := getJSON(ctx, "Open-Meteo", endpoint+"?"+q.Encode(), &resp); err != nil {
+		return nil, err
+	}
+
+	c := resp.Current
+	condition, description := wmoCondition(c.Code)
+	report := &Report{Current: Current{
+		Temperature: round1(c.Temperature),
+		FeelsLike:   round1(c.FeelsLike),
+		Humidity:    c.Humidity,
+		WindSpeed:   round1(c.WindSpeed),
+		Condition:   condition,
+		Description: description,
+	}}
+	d := resp.Daily
+	for n, date := range d.Time {
+		if n >= len(d.Code) || n >= len(d.High) || n >= len(d.Low) {
+			break
+		}
+		day := Day{Date: date, High: round1(d.High[n]), Low: round1(d.Low[n])}
+		day.Condition, day.Description = wmoCondition(d.Code[n])
+		if n < len(d.Precipitation) {
+			day.Precipitation = round1(d.Precipitation[n])
+		}
+		if n < len(d.PrecipitationChance) && d.PrecipitationChance[n] != nil {
+			day.PrecipitationChance = *d.PrecipitationChance[n]
+		}
+		report.Forecast = append(report.Forecast, day)
+	}
+	return report, nil
+}
+
+// wmoCondition maps a WMO weather interpretation code to a condition and
+// its description
+func wmoCondition(code int) (string, string) {
+	switch code {
+	case 0:
+		return Clear, "clear sky"
+	case 1:
+		return Clear, "mainly clear"
+	case 2:
+		return PartlyCloudy, "partly cloudy"
+	case 3:
+		return Cloudy, "overcast"
+	case 45, 48:
+		return Fog, "fog"
+	case 51, 53, 55:
+		return Drizzle, "drizzle"
+	case 56, 57:
+		return Sleet, "freezing drizzle"
+	case 61, 63, 65:
+		return Rain, "rain"
+	case 66, 67:
+		return Sleet, "freezing rain"
+	case 71, 73, 75, 77:
+		return Snow, "snow"
+	case 80, 81, 82:
+		return Rain, "rain showers"
+	case 85, 86:
+		return Snow, "snow showers"
+	case 95, 96, 99:
+		return Thunderstorm, "thunderstorm"
+	}
+	return Cloudy, ""
+}
+
+// getJSON fetches a provider URL
+func getJSON(ctx context.Context, service, target string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	return doJSON(service, req, out)
+}
+
+// doJSON sends a request and decodes the JSON reply, translating HTTP
+// failures
+func doJSON(service string, req *http.Request, out interface{}) error {
+	resp, err := connpool.Default.HTTP(10 * time.Second).Do(req)
+	if err != nil {
+		return agenterrors.Newf(agenterrors.Unavailable, "%s unreachable: %w", service, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+
+	if resp.StatusCode != http.StatusOK {
+		// Services explain errors in a JSON message, reason, or (NWS's
+		// problem details) detail
+		var e struct {
+			Message string `json:"message"`
+			Reason  string `json:"reason"`
+			Detail  string `json:"detail"`
+		}
+		json.Unmarshal(data, &e)
+		message := e.Message + e.Reason + e.Detail
+		if message == "" {
+			message = resp.Status
+		}
+		switch resp.StatusCode {
+		case http.StatusUnauthorized:
+			return agenterrors.Newf(agenterrors.Unauthorized, "%s: %s", service, message)
+		case http.StatusBadRequest:
+			return agenterrors.Newf(agenterrors.InvalidParams, "%s: %s", service, message)
+		}
+		return agenterrors.Newf(agenterrors.Unavailable, "%s: %s", service, message)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid %s response: %w", service, err)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package weather
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// openWeatherMapAPI is the OpenWeatherMap base URL
+const openWeatherMapAPI = "https://api.openweathermap.org"
+
+// OpenWeatherMap is the OpenWeatherMap API, using the current weather and
+// 5 day / 3 hour forecast endpoints a free key allows
+type OpenWeatherMap struct {
+	APIKey string
+
+	// APIURL overrides the OpenWeatherMap base URL
+	APIURL string
+}
+
+type owmCondition struct {
+	ID          int    `json:"id"`
+	Description string `json:"description"`
+}
+
+func (o *OpenWeatherMap) Name() string {
+	return "openweathermap"
+}
+
+func (o *OpenWeatherMap) Endpoint() string {
+	if o.APIURL != "" {
+		return o.APIURL
+	}
+	return openWeatherMapAPI
+}
+
+func (o *OpenWeatherMap) Geocode(ctx context.Context, query string) (Location, error) {
+	var resp []struct {
+		Name    string  `json:"name"`
+		State   string  `json:"state"`
+		Country string  `json:"country"`
+		Lat     float64 `json:"lat"`
+		Lon     float64 `json:"lon"`
+	}
+	if err := o.get(ctx, "/geo/1.0/direct", url.Values{"q": {query}, "limit": {"1"}}, &resp); err != nil {
+		return Location{}, err
+	}
+	if len(resp) == 0 {
+		return Location{}, agenterrors.Newf(agenterrors.NotFound, "no place called '%s'", query)
+	}
+	r := resp[0]
+	return Location{Name: r.Name, Region: r.State, Country: r.Country, Latitude: r.Lat, Longitude: r.Lon}, nil
+}
+
+func (o *OpenWeatherMap) Forecast(ctx context.Context, loc Location, days int, units Units) (*Report, error) {
+	q := url.Values{
+		"lat":   {strconv.FormatFloat(loc.Latitude, 'f', 4, 64)},
+		"lon":   {strconv.FormatFloat(loc.Longitude, 'f', 4, 64)},
+		"units": {string(units)},
+	}
+	var now struct {
+		Main struct {
+			Temp      float64 `json:"temp"`
+			FeelsLike float64 `json:"feels_like"`
+			Humidity  float64 `json:"humidity"`
+		} `json:"main"`
+		Wind struct {
+			Speed float64 `json:"speed"`
+		} `json:"wind"`
+		Weather []owmCondition `json:"weather"`
+	}
+	if err := o.get(ctx, "/data/2.5/weather", q, &now); err != nil {
+		return nil, err
+	}
+	var forecast struct {
+		List []struct {
+			Dt   int64 `json:"dt"`
+			Main struct {
+				TempMin float64 `json:"temp_min"`
+				TempMax float64 `json:"temp_max"`
+			} `json:"main"`
+			Weather []owmCondition `json:"weather"`
+			Pop     float64        `json:"pop"`
+			Rain    struct {
+				ThreeHours float64 `json:"3h"`
+			} `json:"rain"`
+			Snow struct {
+				ThreeHours float64 `json:"3h"`
+			} `json:"snow"`
+		} `json:"list"`
+		City struct {
+			Timezone int `json:"timezone"`
+		} `json:"city"`
+	}
+	if err := o.get(ctx, "/data/2.5/forecast", q, &forecast); err != nil {
+		return nil, err
+	}
+
+	// Wind comes in m/s for metric; precipitation is always in mm
+	wind := now.Wind.Speed
+	precipitation := 1.0
+	if units == Metric {
+		wind *= 3.6
+	} else {
+		precipitation = 1 / 25.4
+	}
+	report := &Report{Current: Current{
+		Temperature: round1(now.Main.Temp),
+		FeelsLike:   round1(now.Main.FeelsLike),
+		Humidity:    now.Main.Humidity,
+		WindSpeed:   round1(wind),
+	}}
+	report.Current.Condition, report.Current.Description = owmConditionOf(now.Weather)
+
+	// Fold the 3-hour steps into days in the place's time zone, taking the
+	// condition nearest midday as the day's
+	zone := time.FixedZone("", forecast.City.Timezone)
+	var day *Day
+	var fromNoon int
+	for _, step := range forecast.List {
+		at := time.Unix(step.Dt, 0).In(zone)
+		date := at.Format(time.DateOnly)
+		if day == nil || day.Date != date {
+			if len(report.Forecast) == days {
+				break
+			}
+			report.Forecast = append(report.Forecast, Day{Date: date, High: step.Main.TempMax, Low: step.Main.TempMin})
+			day, fromNoon = &report.Forecast[len(report.Forecast)-1], 24
+		}
+		day.High = max(day.High, step.Main.TempMax)
+		day.Low = min(day.Low, step.Main.TempMin)
+		day.Precipitation += (step.Rain.ThreeHours + step.Snow.ThreeHours) * precipitation
+		day.PrecipitationChance = max(day.PrecipitationChance, step.Pop*100)
+		if d := max(at.Hour()-12, 12-at.Hour()); d < fromNoon {
+			day.Condition, day.Description = owmConditionOf(step.Weather)
+			fromNoon = d
+		}
+	}
+	for n := range report.Forecast {
+		d := &report.Forecast[n]
+		d.High, d.Low, d.Precipitation = round1(d.High), round1(d.Low), round1(d.Precipitation)
+		d.PrecipitationChance = round1(d.PrecipitationChance)
+	}
+	return report, nil
+}
+
+// owmConditionOf maps OpenWeatherMap condition codes to a condition
+func owmConditionOf(weather []owmCondition) (string, string) {
+	if len(weather) == 0 {
+		return Cloudy, ""
+	}
+	w := weather[0]
+	switch {
+	case w.ID >= 200 && w.ID < 300:
+		return Thunderstorm, w.Description
+	case w.ID >= 300 && w.ID < 400:
+		return Drizzle, w.Description
+	case w.ID == 511 || (w.ID >= 611 && w.ID <= 616):
+		return Sleet, w.Description
+	case w.ID >= 500 && w.ID < 600:
+		return Rain, w.Description
+	case w.ID >= 600 && w.ID < 700:
+		return Snow, w.Description
+	case w.ID >= 700 && w.ID < 800:
+		return Fog, w.Description
+	case w.ID == 800 || w.ID == 801:
+		return Clear, w.Description
+	case w.ID == 802:
+		return PartlyCloudy, w.Description
+	}
+	return Cloudy, w.Description
+}
+
+func (o *OpenWeatherMap) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	base := o.APIURL
+	if base == "" {
+		base = openWeatherMapAPI
+	}
+	query.Set("appid", o.APIKey)
+	return getJSON(ctx, "OpenWeatherMap", base+path+"?"+query.Encode(), out)
+}
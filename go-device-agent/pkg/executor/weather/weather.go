@@ -0,0 +1,380 @@
+// Package weather answers weather.query from Open-Meteo or OpenWeatherMap,
+// geocoding place names and caching responses, with results in one
+// structure whatever the provider. It also polls government warning feeds
+// (NWS, MeteoAlarm) for the default location, publishing weather.alert
+// events, and answers weather.alerts.
+package weather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Provider is a weather service
+type Provider interface {
+	Name() string
+
+	// Endpoint is the service's URL, checked to be reachable
+	Endpoint() string
+
+	// Geocode finds a place by name
+	Geocode(ctx context.Context, query string) (Location, error)
+
+	// Forecast returns the current conditions and the next days' forecast
+	Forecast(ctx context.Context, loc Location, days int, units Units) (*Report, error)
+}
+
+// Location is a place weather is reported for
+type Location struct {
+	Name      string  `json:"name,omitempty"`
+	Region    string  `json:"region,omitempty"`
+	Country   string  `json:"country,omitempty"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Units selects metric (°C, km/h, mm) or imperial (°F, mph, in) results
+type Units string
+
+// Supported units
+const (
+	Metric   Units = "metric"
+	Imperial Units = "imperial"
+)
+
+// Report is a place's current weather and forecast
+type Report struct {
+	Location Location `json:"location"`
+	Current  Current  `json:"current"`
+	Forecast []Day    `json:"forecast"`
+	Units    struct {
+		Temperature   string `json:"temperature"`
+		WindSpeed     string `json:"wind_speed"`
+		Precipitation string `json:"precipitation"`
+	} `json:"units"`
+	Provider  string `json:"provider"`
+	FetchedAt string `json:"fetched_at"`
+}
+
+// Current is the weather now
+type Current struct {
+	Temperature float64 `json:"temperature"`
+	FeelsLike   float64 `json:"feels_like"`
+	Humidity    float64 `json:"humidity"`
+	WindSpeed   float64 `json:"wind_speed"`
+
+	// Condition is one of the condition constants; Description is the
+	// provider's wording
+	Condition   string `json:"condition"`
+	Description string `json:"description,omitempty"`
+}
+
+// Day is one day's forecast
+type Day struct {
+	Date          string  `json:"date"`
+	High          float64 `json:"high"`
+	Low           float64 `json:"low"`
+	Condition     string  `json:"condition"`
+	Description   string  `json:"description,omitempty"`
+	Precipitation float64 `json:"precipitation"`
+
+	// PrecipitationChance is a percentage
+	PrecipitationChance float64 `json:"precipitation_chance"`
+}
+
+// Conditions, coarse enough for the agent core to phrase and pick icons by
+const (
+	Clear        = "clear"
+	PartlyCloudy = "partly_cloudy"
+	Cloudy       = "cloudy"
+	Fog          = "fog"
+	Drizzle      = "drizzle"
+	Rain         = "rain"
+	Sleet        = "sleet"
+	Snow         = "snow"
+	Thunderstorm = "thunderstorm"
+)
+
+// Config sets the provider and defaults
+type Config struct {
+	Provider Provider
+
+	// Location is used when an intent names none: a place name or
+	// "latitude,longitude"
+	Location string
+
+	// Units are the default units (default metric)
+	Units Units
+
+	// CacheTTL is how long a forecast is reused (default 10m)
+	CacheTTL time.Duration
+
+	// Alerts are the warning feeds polled for Location
+	Alerts []AlertSource
+
+	// AlertInterval between warning checks (default 5m)
+	AlertInterval time.Duration
+}
+
+// maxDays is the longest forecast both providers give
+const maxDays = 5
+
+type cachedReport struct {
+	report  *Report
+	fetched time.Time
+}
+
+// Executor handles weather.query and weather.alerts
+type Executor struct {
+	cfg Config
+	bus *events.Bus
+
+	mu       sync.Mutex
+	places   map[string]Location
+	forecast map[string]cachedReport
+
+	alerts      map[string][]Alert // by source
+	alertErrors map[string]string
+	polled      time.Time
+}
+
+// NewExecutor creates a weather executor publishing alert events to bus
+func NewExecutor(cfg Config, bus *events.Bus) (*Executor, error) {
+	if cfg.Provider == nil {
+		return nil, errors.New("no weather provider configured")
+	}
+	if cfg.Units == "" {
+		cfg.Units = Metric
+	}
+	if cfg.Units != Metric && cfg.Units != Imperial {
+		return nil, fmt.Errorf("unknown units %q (want metric or imperial)", cfg.Units)
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = 10 * time.Minute
+	}
+	if cfg.AlertInterval <= 0 {
+		cfg.AlertInterval = 5 * time.Minute
+	}
+	return &Executor{
+		cfg:      cfg,
+		bus:      bus,
+		places:   make(map[string]Location),
+		forecast: make(map[string]cachedReport),
+		alerts:   make(map[string][]Alert),
+	}, nil
+}
+
+func (e *Executor) Name() string {
+	return "weather"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"weather.query", "weather.alerts"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{Network: true}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "weather",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	switch i.IntentType {
+	case "weather.query":
+		var params struct {
+			Location string `param:"location"`
+			Days     int    `param:"days"`
+			Units    string `param:"units"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		if params.Days == 0 {
+			params.Days = 3
+		}
+		if params.Days < 1 || params.Days > maxDays {
+			return fail(agenterrors.Newf(agenterrors.InvalidParams, "days must be between 1 and %d", maxDays))
+		}
+		units := e.cfg.Units
+		if params.Units != "" {
+			units = Units(strings.ToLower(params.Units))
+			if units != Metric && units != Imperial {
+				return fail(agenterrors.Newf(agenterrors.InvalidParams, "unknown units '%s' (want metric or imperial)", params.Units))
+			}
+		}
+
+		loc, err := e.locate(ctx, params.Location)
+		if err != nil {
+			return fail(err)
+		}
+		report, cached, err := e.report(ctx, loc, params.Days, units)
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"location": report.Location,
+			"current":  report.Current,
+			"forecast": report.Forecast,
+			"units":    report.Units,
+			"provider": report.Provider,
+			"cached":   cached,
+		}
+		return result, nil
+
+	case "weather.alerts":
+		var params struct {
+			Location string `param:"location"`
+			Severity string `param:"severity"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		alerts, err := e.queryAlerts(ctx, params.Location, params.Severity)
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = alerts
+		return result, nil
+
+	default:
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+		return result, nil
+	}
+}
+
+// locate resolves a location parameter: coordinates as given, a place name
+// through the provider's geocoder (remembered), or the default location
+func (e *Executor) locate(ctx context.Context, query string) (Location, error) {
+	if query == "" {
+		query = e.cfg.Location
+	}
+	if query == "" {
+		return Location{}, agenterrors.New(agenterrors.InvalidParams, "name a location; no default is configured")
+	}
+	if loc, ok := parseCoordinates(query); ok {
+		return loc, nil
+	}
+
+	key := strings.ToLower(strings.TrimSpace(query))
+	e.mu.Lock()
+	loc, ok := e.places[key]
+	e.mu.Unlock()
+	if ok {
+		return loc, nil
+	}
+	loc, err := e.cfg.Provider.Geocode(ctx, query)
+	if err != nil {
+		return Location{}, err
+	}
+	e.mu.Lock()
+	e.places[key] = loc
+	e.mu.Unlock()
+	return loc, nil
+}
+
+// parseCoordinates reads "latitude,longitude"
+func parseCoordinates(s string) (Location, bool) {
+	latText, lonText, ok := strings.Cut(s, ",")
+	if !ok {
+		return Location{}, false
+	}
+	lat, err1 := strconv.ParseFloat(strings.TrimSpace(latText), 64)
+	lon, err2 := strconv.ParseFloat(strings.TrimSpace(lonText), 64)
+	if err1 != nil || err2 != nil || math.Abs(lat) > 90 || math.Abs(lon) > 180 {
+		return Location{}, false
+	}
+	return Location{Latitude: lat, Longitude: lon}, true
+}
+
+// report returns a forecast, from the cache while fresh, and a stale one
+// when the provider can't be reached
+func (e *Executor) report(ctx context.Context, loc Location, days int, units Units) (*Report, bool, error) {
+	// Nearby points share a forecast cell anyway
+	key := fmt.Sprintf("%.2f,%.2f,%d,%s", loc.Latitude, loc.Longitude, days, units)
+	e.mu.Lock()
+	cached, ok := e.forecast[key]
+	e.mu.Unlock()
+	if ok && time.Since(cached.fetched) < e.cfg.CacheTTL {
+		return cached.report, true, nil
+	}
+
+	report, err := e.cfg.Provider.Forecast(ctx, loc, days, units)
+	if err != nil {
+		if ok {
+			return cached.report, true, nil
+		}
+		return nil, false, err
+	}
+	report.Location = loc
+	report.Provider = e.cfg.Provider.Name()
+	report.FetchedAt = time.Now().Format(time.RFC3339)
+	report.Units.Temperature, report.Units.WindSpeed, report.Units.Precipitation = "°C", "km/h", "mm"
+	if units == Imperial {
+		report.Units.Temperature, report.Units.WindSpeed, report.Units.Precipitation = "°F", "mph", "in"
+	}
+
+	e.mu.Lock()
+	e.forecast[key] = cachedReport{report: report, fetched: time.Now()}
+	for k, c := range e.forecast {
+		if time.Since(c.fetched) > e.cfg.CacheTTL {
+			delete(e.forecast, k)
+		}
+	}
+	e.mu.Unlock()
+	return report, false, nil
+}
+
+// round1 rounds to one decimal place
+func round1(f float64) float64 {
+	return math.Round(f*10) / 10
+}
+
+func (e *Executor) IsAvailable() bool {
+	return connpool.Default.Reachable(e.cfg.Provider.Endpoint())
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"weather.query": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"location": {"type": "string"},
+				"days": {"type": "integer", "minimum": 1, "maximum": 5},
+				"units": {"type": "string", "enum": ["metric", "imperial"]}
+			}
+		}`),
+		"weather.alerts": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"location": {"type": "string"},
+				"severity": {"type": "string", "enum": ["minor", "moderate", "severe", "extreme"]}
+			}
+		}`),
+	}
+}
@@ -0,0 +1,30 @@
+package window
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// run runs a window manager tool and returns its output
+func run(ctx context.Context, argv ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", agenterrors.Newf(agenterrors.Timeout, "%s did not respond", argv[0])
+		}
+		var exit *exec.ExitError
+		if msg := strings.TrimSpace(stderr.String()); errors.As(err, &exit) && msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", agenterrors.Wrap(agenterrors.Unavailable, fmt.Errorf("%s: %w", argv[0], err))
+	}
+	return stdout.String(), nil
+}
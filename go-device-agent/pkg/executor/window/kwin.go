@@ -0,0 +1,196 @@
+//go:build !windows && !darwin
+
+package window
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+const (
+	kwinService = "org.kde.KWin"
+	reportPath  = dbus.ObjectPath("/org/localagent/Windows")
+	reportIface = "org.localagent.Windows"
+)
+
+// kwinScript lists, focuses, or moves windows for one request, then
+// reports back over D-Bus. It runs inside KWin, the only way Wayland lets
+// a client see other clients' windows. It handles both KWin 6
+// (windowList, activeWindow) and KWin 5 (clientList, activeClient).
+const kwinScript = `
+const req = %s;
+const all = workspace.windowList ? workspace.windowList() : workspace.clientList();
+const stacking = workspace.stackingOrder || all;
+const active = workspace.activeWindow !== undefined ? workspace.activeWindow : workspace.activeClient;
+const out = {windows: [], found: false};
+for (let n = stacking.length - 1; n >= 0; n--) {
+	const w = stacking[n];
+	if (!w || !w.normalWindow) continue;
+	const id = String(w.internalId);
+	const g = w.frameGeometry;
+	if (req.op === "list") {
+		out.windows.push({id: id, title: String(w.caption), app: String(w.resourceClass), pid: w.pid,
+			x: Math.round(g.x), y: Math.round(g.y), width: Math.round(g.width), height: Math.round(g.height),
+			active: w === active});
+	} else if (id === req.id) {
+		out.found = true;
+		if (req.op === "focus") {
+			w.minimized = false;
+			if (workspace.activeWindow !== undefined) workspace.activeWindow = w; else workspace.activeClient = w;
+		} else if (req.op === "move") {
+			if (w.setMaximize) w.setMaximize(false, false);
+			w.frameGeometry = {x: req.x, y: req.y, width: req.width, height: req.height};
+		}
+	}
+}
+callDBus(%q, %q, %q, "Report", %q, JSON.stringify(out));
+`
+
+// kwin drives KWin over D-Bus by loading a script for each request
+type kwin struct {
+	conn *dbus.Conn
+
+	mu      sync.Mutex
+	pending map[string]chan string
+}
+
+// kwinReply is what the script reports
+type kwinReply struct {
+	Windows []Window `json:"windows"`
+	Found   bool     `json:"found"`
+}
+
+func newKWin() (*kwin, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("no D-Bus session bus: %w", err)
+	}
+	var running bool
+	if err := conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, kwinService).Store(&running); err != nil || !running {
+		conn.Close()
+		return nil, fmt.Errorf("%s is not on the session bus", kwinService)
+	}
+	k := &kwin{conn: conn, pending: make(map[string]chan string)}
+	if err := conn.Export(k, reportPath, reportIface); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return k, nil
+}
+
+func (k *kwin) name() string {
+	return "kwin"
+}
+
+// Report is called over D-Bus by the scripts
+func (k *kwin) Report(token, data string) *dbus.Error {
+	k.mu.Lock()
+	ch := k.pending[token]
+	delete(k.pending, token)
+	k.mu.Unlock()
+	if ch != nil {
+		ch <- data
+	}
+	return nil
+}
+
+func (k *kwin) list(ctx context.Context) ([]Window, error) {
+	reply, err := k.script(ctx, map[string]interface{}{"op": "list"})
+	if err != nil {
+		return nil, err
+	}
+	return reply.Windows, nil
+}
+
+func (k *kwin) focus(ctx context.Context, w Window) error {
+	return k.act(ctx, w, map[string]interface{}{"op": "focus", "id": w.ID})
+}
+
+func (k *kwin) move(ctx context.Context, w Window, x, y, width, height int) error {
+	return k.act(ctx, w, map[string]interface{}{"op": "move", "id": w.ID, "x": x, "y": y, "width": width, "height": height})
+}
+
+func (k *kwin) act(ctx context.Context, w Window, req map[string]interface{}) error {
+	reply, err := k.script(ctx, req)
+	if err != nil {
+		return err
+	}
+	if !reply.Found {
+		return agenterrors.Newf(agenterrors.NotFound, "window %s closed", w.ID)
+	}
+	return nil
+}
+
+// script loads the script for a request into KWin, runs it, and waits for
+// its report
+func (k *kwin) script(ctx context.Context, req map[string]interface{}) (*kwinReply, error) {
+	b := make([]byte, 8)
+	rand.Read(b)
+	token := hex.EncodeToString(b)
+	reqJSON, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp("", "device-agent-*.js")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	_, err = fmt.Fprintf(f, kwinScript, reqJSON, k.conn.Names()[0], reportPath, reportIface, token)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan string, 1)
+	k.mu.Lock()
+	k.pending[token] = ch
+	k.mu.Unlock()
+	defer func() {
+		k.mu.Lock()
+		delete(k.pending, token)
+		k.mu.Unlock()
+	}()
+
+	plugin := "device-agent-" + token
+	scripting := k.conn.Object(kwinService, "/Scripting")
+	var id int32
+	if err := scripting.CallWithContext(ctx, "org.kde.kwin.Scripting.loadScript", 0, f.Name(), plugin).Store(&id); err != nil {
+		return nil, agenterrors.Wrap(agenterrors.Unavailable, fmt.Errorf("loading KWin script: %w", err))
+	}
+	if id < 0 {
+		return nil, agenterrors.New(agenterrors.Unavailable, "KWin refused the script")
+	}
+	defer scripting.Call("org.kde.kwin.Scripting.unloadScript", 0, plugin)
+
+	// Scripts live under /Scripting since KWin 5.23, and at the root before
+	err = k.conn.Object(kwinService, dbus.ObjectPath(fmt.Sprintf("/Scripting/Script%d", id))).CallWithContext(ctx, "org.kde.kwin.Script.run", 0).Err
+	if err != nil {
+		err = k.conn.Object(kwinService, dbus.ObjectPath(fmt.Sprintf("/%d", id))).CallWithContext(ctx, "org.kde.kwin.Script.run", 0).Err
+	}
+	if err != nil {
+		return nil, agenterrors.Wrap(agenterrors.Unavailable, fmt.Errorf("running KWin script: %w", err))
+	}
+
+	select {
+	case data := <-ch:
+		var reply kwinReply
+		if err := json.Unmarshal([]byte(data), &reply); err != nil {
+			return nil, agenterrors.Wrap(agenterrors.Internal, fmt.Errorf("unexpected KWin script reply: %w", err))
+		}
+		return &reply, nil
+	case <-ctx.Done():
+		return nil, agenterrors.New(agenterrors.Timeout, "KWin did not run the script")
+	}
+}
@@ -0,0 +1,244 @@
+// Package window lists, focuses, and moves desktop windows so the agent
+// core can bring the right application forward: wmctrl under X11, KWin
+// scripting over D-Bus under Wayland, and the Accessibility API (through
+// System Events) on macOS
+package window
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// timeout bounds each call to the window manager
+const timeout = 5 * time.Second
+
+// Window is a top-level application window
+type Window struct {
+	// ID is the window manager's ID for the window; it's only good until
+	// the window closes
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	App    string `json:"app"`
+	PID    int    `json:"pid,omitempty"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Active bool   `json:"active,omitempty"`
+}
+
+// backend is a platform's window manager. Windows are listed topmost
+// first where the window manager says.
+type backend interface {
+	name() string
+	list(ctx context.Context) ([]Window, error)
+	focus(ctx context.Context, w Window) error
+	move(ctx context.Context, w Window, x, y, width, height int) error
+}
+
+// Executor handles window.list, window.focus, and window.move
+type Executor struct {
+	backend backend
+}
+
+// NewExecutor finds the platform's window manager, failing when there is
+// none it can drive (as on a headless machine)
+func NewExecutor() (*Executor, error) {
+	b, err := newBackend()
+	if err != nil {
+		return nil, err
+	}
+	return &Executor{backend: b}, nil
+}
+
+// Backend names the window manager in use, e.g. "wmctrl"
+func (e *Executor) Backend() string {
+	return e.backend.name()
+}
+
+func (e *Executor) Name() string {
+	return "window"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"window.list", "window.focus", "window.move"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	// wmctrl or osascript; KWin is scripted over D-Bus, and Windows
+	// through the Win32 API
+	return sandbox.Needs{Subprocess: e.backend.name() != "kwin" && runtime.GOOS != "windows"}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "window",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	var params struct {
+		ID     string `param:"id"`
+		App    string `param:"app"`
+		Title  string `param:"title"`
+		X      *int   `param:"x"`
+		Y      *int   `param:"y"`
+		Width  *int   `param:"width"`
+		Height *int   `param:"height"`
+	}
+	if err := i.DecodeParams(&params); err != nil {
+		return fail(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	windows, err := e.backend.list(ctx)
+	if err != nil {
+		return fail(err)
+	}
+
+	switch i.IntentType {
+	case "window.list":
+		matches := filter(windows, "", params.App, params.Title)
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"windows": matches,
+			"count":   len(matches),
+			"backend": e.backend.name(),
+		}
+
+	case "window.focus", "window.move":
+		if params.ID == "" && params.App == "" && params.Title == "" {
+			return fail(agenterrors.New(agenterrors.InvalidParams, "id, app, or title is required"))
+		}
+		matches := filter(windows, params.ID, params.App, params.Title)
+		// "Focus firefox" takes the app's active or topmost window, but a
+		// title is meant to pick one
+		sort.SliceStable(matches, func(a, b int) bool { return matches[a].Active && !matches[b].Active })
+		switch {
+		case len(matches) == 0:
+			return fail(agenterrors.Newf(agenterrors.NotFound, "no window matches %s", describe(params.ID, params.App, params.Title)))
+		case len(matches) > 1 && params.Title != "":
+			result.Fail(agenterrors.Newf(agenterrors.Conflict, "%d windows match %s; name one by id", len(matches), describe(params.ID, params.App, params.Title)))
+			result.Result = map[string]interface{}{"matches": matches}
+			return result, nil
+		}
+		w := matches[0]
+
+		if i.IntentType == "window.focus" {
+			if err := e.backend.focus(ctx, w); err != nil {
+				return fail(err)
+			}
+			w.Active = true
+		} else {
+			if params.X == nil && params.Y == nil && params.Width == nil && params.Height == nil {
+				return fail(agenterrors.New(agenterrors.InvalidParams, "x, y, width, or height is required"))
+			}
+			x, y, width, height := or(params.X, w.X), or(params.Y, w.Y), or(params.Width, w.Width), or(params.Height, w.Height)
+			if width <= 0 || height <= 0 {
+				return fail(agenterrors.New(agenterrors.InvalidParams, "width and height must be positive"))
+			}
+			if err := e.backend.move(ctx, w, x, y, width, height); err != nil {
+				return fail(err)
+			}
+			w.X, w.Y, w.Width, w.Height = x, y, width, height
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"window": w,
+		}
+
+	default:
+		return fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+	return result, nil
+}
+
+// filter keeps the windows with the ID, or whose app and title contain
+// the ones given, ignoring case
+func filter(windows []Window, id, app, title string) []Window {
+	app, title = strings.ToLower(app), strings.ToLower(title)
+	matches := []Window{}
+	for _, w := range windows {
+		switch {
+		case id != "" && w.ID != id:
+		case app != "" && !strings.Contains(strings.ToLower(w.App), app):
+		case title != "" && !strings.Contains(strings.ToLower(w.Title), title):
+		default:
+			matches = append(matches, w)
+		}
+	}
+	return matches
+}
+
+func describe(id, app, title string) string {
+	var parts []string
+	if id != "" {
+		parts = append(parts, "id "+id)
+	}
+	if app != "" {
+		parts = append(parts, "app '"+app+"'")
+	}
+	if title != "" {
+		parts = append(parts, "title '"+title+"'")
+	}
+	return strings.Join(parts, " and ")
+}
+
+func or(v *int, fallback int) int {
+	if v == nil {
+		return fallback
+	}
+	return *v
+}
+
+func (e *Executor) IsAvailable() bool {
+	return e.backend != nil
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"window.list": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"app": {"type": "string"},
+				"title": {"type": "string"}
+			}
+		}`),
+		"window.focus": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"id": {"type": "string", "minLength": 1},
+				"app": {"type": "string", "minLength": 1},
+				"title": {"type": "string", "minLength": 1}
+			}
+		}`),
+		"window.move": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"id": {"type": "string", "minLength": 1},
+				"app": {"type": "string", "minLength": 1},
+				"title": {"type": "string", "minLength": 1},
+				"x": {"type": "integer"},
+				"y": {"type": "integer"},
+				"width": {"type": "integer", "minimum": 1},
+				"height": {"type": "integer", "minimum": 1}
+			}
+		}`),
+	}
+}
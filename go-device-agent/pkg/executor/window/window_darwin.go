@@ -0,0 +1,110 @@
+package window
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// Scripts for System Events, which reaches windows through the
+// Accessibility API. Arguments are passed to run rather than spliced into
+// the source. Windows have no lasting IDs there, so a window's ID is its
+// app's PID and its place in the app's window list, frontmost first.
+const (
+	listScript = `
+function run() {
+	const se = Application("System Events");
+	const out = [];
+	for (const p of se.processes.whose({backgroundOnly: false})()) {
+		const pid = p.unixId(), front = p.frontmost();
+		p.windows().forEach((w, n) => {
+			let pos = [0, 0], size = [0, 0];
+			try { pos = w.position(); size = w.size(); } catch (e) {}
+			out.push({id: pid + ":" + (n + 1), title: w.name() || "", app: p.name(), pid: pid,
+				x: pos[0], y: pos[1], width: size[0], height: size[1], active: front && n === 0});
+		});
+	}
+	out.sort((a, b) => (b.active ? 1 : 0) - (a.active ? 1 : 0));
+	return JSON.stringify(out);
+}`
+
+	focusScript = `
+function run(argv) {
+	const p = Application("System Events").processes.whose({unixId: parseInt(argv[0])})[0];
+	const w = p.windows[parseInt(argv[1]) - 1];
+	p.frontmost = true;
+	w.actions.byName("AXRaise").perform();
+}`
+
+	moveScript = `
+function run(argv) {
+	const p = Application("System Events").processes.whose({unixId: parseInt(argv[0])})[0];
+	const w = p.windows[parseInt(argv[1]) - 1];
+	w.position = [parseInt(argv[2]), parseInt(argv[3])];
+	w.size = [parseInt(argv[4]), parseInt(argv[5])];
+}`
+)
+
+// accessibility drives windows through osascript
+type accessibility struct{}
+
+func newBackend() (backend, error) {
+	return accessibility{}, nil
+}
+
+func (accessibility) name() string {
+	return "accessibility"
+}
+
+func (accessibility) list(ctx context.Context) ([]Window, error) {
+	out, err := osascript(ctx, listScript)
+	if err != nil {
+		return nil, err
+	}
+	var windows []Window
+	if err := json.Unmarshal([]byte(out), &windows); err != nil {
+		return nil, agenterrors.Wrap(agenterrors.Internal, fmt.Errorf("unexpected window list: %w", err))
+	}
+	return windows, nil
+}
+
+func (accessibility) focus(ctx context.Context, w Window) error {
+	pid, index, err := splitID(w.ID)
+	if err != nil {
+		return err
+	}
+	_, err = osascript(ctx, focusScript, pid, index)
+	return err
+}
+
+func (accessibility) move(ctx context.Context, w Window, x, y, width, height int) error {
+	pid, index, err := splitID(w.ID)
+	if err != nil {
+		return err
+	}
+	_, err = osascript(ctx, moveScript, pid, index, strconv.Itoa(x), strconv.Itoa(y), strconv.Itoa(width), strconv.Itoa(height))
+	return err
+}
+
+func splitID(id string) (string, string, error) {
+	pid, index, ok := strings.Cut(id, ":")
+	if !ok {
+		return "", "", agenterrors.Newf(agenterrors.InvalidParams, "invalid window id %s", id)
+	}
+	return pid, index, nil
+}
+
+// osascript runs a JavaScript for Automation script. Without the
+// Accessibility permission, System Events refuses with error -1719 or
+// -25211.
+func osascript(ctx context.Context, script string, args ...string) (string, error) {
+	out, err := run(ctx, append([]string{"osascript", "-l", "JavaScript", "-e", script}, args...)...)
+	if err != nil && (strings.Contains(err.Error(), "-1719") || strings.Contains(err.Error(), "-25211") || strings.Contains(err.Error(), "assistive access")) {
+		return "", agenterrors.New(agenterrors.Unauthorized, "the agent needs the Accessibility permission (System Settings → Privacy & Security → Accessibility)")
+	}
+	return strings.TrimSpace(out), err
+}
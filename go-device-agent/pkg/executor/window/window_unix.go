@@ -0,0 +1,32 @@
+//go:build !windows && !darwin
+
+package window
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// newBackend uses KWin under Wayland, where wmctrl would only see XWayland
+// windows, and wmctrl under X11, falling back to KWin there too
+func newBackend() (backend, error) {
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		k, err := newKWin()
+		if err != nil {
+			return nil, fmt.Errorf("under Wayland only KWin's windows can be managed: %w", err)
+		}
+		return k, nil
+	}
+	if os.Getenv("DISPLAY") == "" {
+		return nil, errors.New("no graphical session (neither WAYLAND_DISPLAY nor DISPLAY is set)")
+	}
+	if _, err := exec.LookPath("wmctrl"); err == nil {
+		return wmctrl{}, nil
+	}
+	if k, err := newKWin(); err == nil {
+		return k, nil
+	}
+	return nil, errors.New("wmctrl not found: install wmctrl")
+}
@@ -0,0 +1,7 @@
+package window
+
+import "errors"
+
+func newBackend() (backend, error) {
+	return nil, errors.New("window management is not supported on Windows")
+}
@@ -0,0 +1,107 @@
+//go:build !windows && !darwin
+
+package window
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// wmctrl drives an X11 window manager through EWMH, which nearly all of
+// them support
+type wmctrl struct{}
+
+func (wmctrl) name() string {
+	return "wmctrl"
+}
+
+// list parses wmctrl -lpGx: "0x03a00003  0 1234  10 20 800 600
+// firefox.Firefox host Title". Windows on every desktop (-1), which are
+// mostly panels and docks, are left out.
+func (wmctrl) list(ctx context.Context) ([]Window, error) {
+	out, err := run(ctx, "wmctrl", "-lpGx")
+	if err != nil {
+		return nil, err
+	}
+	active, order := stacking(ctx)
+	rank := make(map[string]int, len(order))
+	for n, id := range order {
+		rank[fmt.Sprintf("0x%08x", id)] = n
+	}
+	var windows []Window
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 9 || fields[1] == "-1" {
+			continue
+		}
+		var n [5]int
+		for k, f := range fields[2:7] {
+			n[k], _ = strconv.Atoi(f)
+		}
+		// The class is instance.Class; the class names the app
+		app := fields[7]
+		if _, class, ok := strings.Cut(app, "."); ok {
+			app = class
+		}
+		id, _ := strconv.ParseUint(fields[0], 0, 64)
+		fields[0] = fmt.Sprintf("0x%08x", id)
+		windows = append(windows, Window{
+			ID:     fields[0],
+			Title:  strings.Join(fields[9:], " "),
+			App:    app,
+			PID:    n[0],
+			X:      n[1],
+			Y:      n[2],
+			Width:  n[3],
+			Height: n[4],
+			Active: id != 0 && id == active,
+		})
+	}
+	sort.SliceStable(windows, func(a, b int) bool { return rank[windows[a].ID] > rank[windows[b].ID] })
+	return windows, scanner.Err()
+}
+
+// stacking asks the root window which window is active and how windows
+// are stacked, bottom first. Both are empty when xprop isn't installed.
+func stacking(ctx context.Context) (active uint64, order []uint64) {
+	out, err := run(ctx, "xprop", "-root", "_NET_ACTIVE_WINDOW", "_NET_CLIENT_LIST_STACKING")
+	if err != nil {
+		return 0, nil
+	}
+	// "_NET_ACTIVE_WINDOW(WINDOW): window id # 0x3a00003"
+	for _, line := range strings.Split(out, "\n") {
+		name, ids, ok := strings.Cut(line, "# ")
+		if !ok {
+			continue
+		}
+		for _, id := range strings.Split(ids, ",") {
+			n, _ := strconv.ParseUint(strings.TrimSpace(id), 0, 64)
+			if strings.HasPrefix(name, "_NET_ACTIVE_WINDOW") {
+				active = n
+			} else {
+				order = append(order, n)
+			}
+		}
+	}
+	return active, order
+}
+
+func (wmctrl) focus(ctx context.Context, w Window) error {
+	_, err := run(ctx, "wmctrl", "-i", "-a", w.ID)
+	return err
+}
+
+// move unmaximizes the window first, since window managers ignore
+// geometry for maximized windows
+func (wmctrl) move(ctx context.Context, w Window, x, y, width, height int) error {
+	if _, err := run(ctx, "wmctrl", "-i", "-r", w.ID, "-b", "remove,maximized_vert,maximized_horz"); err != nil {
+		return err
+	}
+	_, err := run(ctx, "wmctrl", "-i", "-r", w.ID, "-e", fmt.Sprintf("0,%d,%d,%d,%d", x, y, width, height))
+	return err
+}
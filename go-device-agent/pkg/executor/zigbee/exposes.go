@@ -0,0 +1,308 @@
+package zigbee
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/registry"
+)
+
+// Access bits of an exposed feature
+const (
+	accessState = 1 // reported in state messages
+	accessSet   = 2 // settable through /set
+	accessGet   = 4 // can be asked for through /get
+)
+
+// feature is one value a device exposes, from the "exposes" list in
+// bridge/devices. Features of a light, switch, lock, or climate group are
+// listed with the group's type; composites such as color are kept whole.
+type feature struct {
+	Type        string          `json:"type"`
+	Name        string          `json:"name"`
+	Property    string          `json:"property"`
+	Access      int             `json:"access"`
+	Unit        string          `json:"unit,omitempty"`
+	ValueMin    *float64        `json:"value_min,omitempty"`
+	ValueMax    *float64        `json:"value_max,omitempty"`
+	ValueOn     interface{}     `json:"value_on,omitempty"`
+	ValueOff    interface{}     `json:"value_off,omitempty"`
+	ValueToggle interface{}     `json:"value_toggle,omitempty"`
+	Values      []interface{}   `json:"values,omitempty"`
+	Features    json.RawMessage `json:"features,omitempty"`
+
+	group string
+}
+
+// flatten lists the features of an exposes list
+func flatten(exposes json.RawMessage, group string) []feature {
+	var list []feature
+	if err := json.Unmarshal(exposes, &list); err != nil {
+		return nil
+	}
+	var features []feature
+	for _, f := range list {
+		switch {
+		case f.Type == "composite" || len(f.Features) == 0:
+			f.group = group
+			f.Features = nil
+			features = append(features, f)
+		default:
+			features = append(features, flatten(f.Features, f.Type)...)
+		}
+	}
+	return features
+}
+
+// kind classifies a device by what it exposes
+func kind(features []feature) registry.Kind {
+	groups := make(map[string]bool)
+	for _, f := range features {
+		groups[f.group] = true
+	}
+	switch {
+	case groups["light"]:
+		return registry.KindLight
+	case groups["lock"]:
+		return registry.KindLock
+	case groups["climate"]:
+		return registry.KindThermostat
+	case groups["switch"], groups["cover"], groups["fan"]:
+		return registry.KindSwitch
+	}
+	for _, f := range features {
+		if f.Property == "contact" {
+			return registry.KindContactSensor
+		}
+	}
+	return registry.KindSensor
+}
+
+// settable finds a feature /set accepts by property
+func settable(features []feature, property string) (feature, bool) {
+	for _, f := range features {
+		if f.Property == property && f.Access&accessSet != 0 {
+			return f, true
+		}
+	}
+	return feature{}, false
+}
+
+// setpoint finds a thermostat's heating setpoint
+func setpoint(features []feature) (feature, bool) {
+	for _, p := range []string{"occupied_heating_setpoint", "current_heating_setpoint"} {
+		if f, ok := settable(features, p); ok {
+			return f, true
+		}
+	}
+	return feature{}, false
+}
+
+// actionNames lists the device.control actions a device supports
+func actionNames(features []feature) []string {
+	var names []string
+	if state, ok := settable(features, "state"); ok {
+		if state.group == "lock" {
+			names = append(names, "lock", "unlock")
+		} else {
+			names = append(names, "on", "off")
+			if state.ValueToggle != nil {
+				names = append(names, "toggle")
+			}
+		}
+	}
+	if _, ok := settable(features, "brightness"); ok {
+		names = append(names, "brightness")
+	}
+	if _, ok := settable(features, "color"); ok {
+		names = append(names, "color")
+	}
+	if _, ok := settable(features, "color_temp"); ok {
+		names = append(names, "color_temperature")
+	}
+	if _, ok := setpoint(features); ok {
+		names = append(names, "temperature")
+	}
+	for _, f := range features {
+		if f.Access&accessSet != 0 {
+			names = append(names, "set")
+			break
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// command builds the /set payload for a device.control action. Brightness
+// is a percentage, color a hex string, color temperature in kelvin, and
+// temperature in the device's unit (normally °C); set takes any settable
+// property and a value Zigbee2MQTT accepts for it.
+func command(features []feature, action string, params map[string]interface{}) (map[string]interface{}, error) {
+	value := params["value"]
+	switch action {
+	case "on", "off", "toggle", "lock", "unlock":
+		state, ok := settable(features, "state")
+		if !ok || (state.group == "lock") != (action == "lock" || action == "unlock") {
+			break
+		}
+		switch action {
+		case "off", "unlock":
+			return map[string]interface{}{state.Property: state.ValueOff}, nil
+		case "toggle":
+			if state.ValueToggle == nil {
+				return nil, agenterrors.New(agenterrors.InvalidParams, "the device can't toggle; use on or off")
+			}
+			return map[string]interface{}{state.Property: state.ValueToggle}, nil
+		}
+		return map[string]interface{}{state.Property: state.ValueOn}, nil
+
+	case "brightness":
+		f, ok := settable(features, "brightness")
+		if !ok {
+			break
+		}
+		percent, ok := number(params, "brightness", "value")
+		if !ok || percent < 0 || percent > 100 {
+			return nil, agenterrors.New(agenterrors.InvalidParams, "brightness needs a value from 0 to 100")
+		}
+		lo, hi := bounds(f, 0, 254)
+		return map[string]interface{}{f.Property: math.Round(lo + percent/100*(hi-lo))}, nil
+
+	case "color":
+		if _, ok := settable(features, "color"); !ok {
+			break
+		}
+		hex, _ := params["color"].(string)
+		if hex == "" {
+			hex, _ = value.(string)
+		}
+		if !strings.HasPrefix(hex, "#") || len(hex) != 7 {
+			return nil, agenterrors.New(agenterrors.InvalidParams, "color needs a hex value such as #ff8800")
+		}
+		return map[string]interface{}{"color": map[string]interface{}{"hex": hex}}, nil
+
+	case "color_temperature":
+		f, ok := settable(features, "color_temp")
+		if !ok {
+			break
+		}
+		kelvin, ok := number(params, "color_temperature", "value")
+		if !ok || kelvin <= 0 {
+			return nil, agenterrors.New(agenterrors.InvalidParams, "color_temperature needs a value in kelvin")
+		}
+		lo, hi := bounds(f, 153, 500)
+		mired := math.Max(lo, math.Min(hi, math.Round(1e6/kelvin)))
+		return map[string]interface{}{f.Property: mired}, nil
+
+	case "temperature":
+		f, ok := setpoint(features)
+		if !ok {
+			break
+		}
+		t, ok := number(params, "temperature", "value")
+		if !ok {
+			return nil, agenterrors.New(agenterrors.InvalidParams, "temperature needs a value")
+		}
+		if err := check(f, t); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{f.Property: t}, nil
+
+	case "set":
+		property, _ := params["property"].(string)
+		f, ok := settable(features, property)
+		if !ok {
+			return nil, agenterrors.Newf(agenterrors.InvalidParams, "'%s' is not a settable property (settable: %s)",
+				property, strings.Join(settableNames(features), ", "))
+		}
+		if value == nil {
+			return nil, agenterrors.New(agenterrors.InvalidParams, "set needs a value")
+		}
+		if err := check(f, value); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{f.Property: value}, nil
+	}
+	names := actionNames(features)
+	if len(names) == 0 {
+		return nil, agenterrors.New(agenterrors.InvalidParams, "the device only reports its state")
+	}
+	return nil, agenterrors.Newf(agenterrors.InvalidParams, "unsupported action '%s' (supports %s)", action, strings.Join(names, ", "))
+}
+
+// number reads the first of the named parameters that is a number
+func number(params map[string]interface{}, names ...string) (float64, bool) {
+	for _, name := range names {
+		if v, ok := toFloat(params[name]); ok {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func bounds(f feature, lo, hi float64) (float64, float64) {
+	if f.ValueMin != nil {
+		lo = *f.ValueMin
+	}
+	if f.ValueMax != nil {
+		hi = *f.ValueMax
+	}
+	return lo, hi
+}
+
+// check validates a value against a feature's type
+func check(f feature, value interface{}) error {
+	switch f.Type {
+	case "numeric":
+		v, ok := toFloat(value)
+		if !ok {
+			return agenterrors.Newf(agenterrors.InvalidParams, "%s takes a number", f.Property)
+		}
+		if (f.ValueMin != nil && v < *f.ValueMin) || (f.ValueMax != nil && v > *f.ValueMax) {
+			lo, hi := bounds(f, math.Inf(-1), math.Inf(1))
+			return agenterrors.Newf(agenterrors.InvalidParams, "%s must be from %g to %g", f.Property, lo, hi)
+		}
+	case "binary":
+		if value != f.ValueOn && value != f.ValueOff && value != f.ValueToggle {
+			return agenterrors.Newf(agenterrors.InvalidParams, "%s takes %v or %v", f.Property, f.ValueOn, f.ValueOff)
+		}
+	case "enum":
+		for _, allowed := range f.Values {
+			if value == allowed {
+				return nil
+			}
+		}
+		return agenterrors.Newf(agenterrors.InvalidParams, "%s takes one of %s", f.Property, strings.Trim(fmt.Sprint(f.Values), "[]"))
+	}
+	return nil
+}
+
+func settableNames(features []feature) []string {
+	var names []string
+	for _, f := range features {
+		if f.Access&accessSet != 0 {
+			names = append(names, f.Property)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,577 @@
+// Package zigbee controls the devices paired with Zigbee2MQTT. Unlike the
+// mqtt executor, which needs a device map, it learns the devices and what
+// each can do from the bridge's own device list.
+package zigbee
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/registry"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/usage"
+)
+
+// Config sets the broker Zigbee2MQTT publishes to
+type Config struct {
+	// Broker URL (tcp://, ssl://, ws://, or wss://)
+	Broker   string
+	Username string
+	Password string
+
+	// ClientID identifies the agent to the broker (default
+	// device-agent-<hostname>). Executors with the same broker, client ID,
+	// and login share a connection.
+	ClientID string
+
+	// BaseTopic is Zigbee2MQTT's base_topic (default zigbee2mqtt)
+	BaseTopic string
+
+	// Timeout bounds publishes and waits for state reports (default 5s)
+	Timeout time.Duration
+}
+
+// device is a device from bridge/devices and its last reported state
+type device struct {
+	IEEE        string
+	Name        string // the friendly name, which is also its topic
+	Model       string
+	Vendor      string
+	Description string
+	features    []feature
+
+	state     map[string]interface{}
+	online    *bool
+	updatedAt time.Time
+	changed   chan struct{} // closed and replaced on every report
+}
+
+// Executor handles device.list, device.control, and device.query for
+// Zigbee2MQTT's devices; intents reach it by targeting the zigbee module
+type Executor struct {
+	cfg      Config
+	session  *connpool.MQTTSession
+	client   paho.Client
+	registry *registry.Registry
+	logger   *slog.Logger
+
+	// blocked is why the broker may not be reached in air-gapped mode
+	blocked error
+
+	mu      sync.Mutex
+	devices map[string]*device // by IEEE address
+
+	// pending holds retained state that arrived before the device list
+	pending map[string]map[string]interface{}
+}
+
+// NewExecutor creates a Zigbee2MQTT executor. Devices are mirrored into reg
+// as "zigbee:<ieee address>" when it is not nil.
+func NewExecutor(cfg Config, reg *registry.Registry) (*Executor, error) {
+	if cfg.Broker == "" {
+		return nil, agenterrors.New(agenterrors.InvalidParams, "no MQTT broker configured for Zigbee2MQTT")
+	}
+	if cfg.BaseTopic == "" {
+		cfg.BaseTopic = "zigbee2mqtt"
+	}
+	cfg.BaseTopic = strings.TrimSuffix(cfg.BaseTopic, "/")
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.ClientID == "" {
+		host, _ := os.Hostname()
+		cfg.ClientID = "device-agent-" + host
+	}
+
+	e := &Executor{
+		cfg:      cfg,
+		registry: reg,
+		logger:   slog.Default(),
+		devices:  make(map[string]*device),
+		pending:  make(map[string]map[string]interface{}),
+	}
+	session, err := connpool.Default.MQTT(connpool.MQTTOptions{
+		Broker:         cfg.Broker,
+		ClientID:       cfg.ClientID,
+		Username:       cfg.Username,
+		Password:       cfg.Password,
+		ConnectTimeout: cfg.Timeout,
+	})
+	switch {
+	case errors.Is(err, connpool.ErrEgressBlocked):
+		e.blocked = agenterrors.Wrap(agenterrors.Unavailable, err)
+	case err != nil:
+		return nil, agenterrors.Wrap(agenterrors.InvalidParams, err)
+	default:
+		e.session, e.client = session, session.Client()
+	}
+	return e, nil
+}
+
+// SetLogger sets where connection problems are logged
+func (e *Executor) SetLogger(logger *slog.Logger) {
+	e.logger = logger
+}
+
+// Start follows Zigbee2MQTT whenever the shared broker session connects,
+// until ctx is done. A broker air-gapped mode blocks is never dialed.
+func (e *Executor) Start(ctx context.Context) {
+	if e.blocked != nil {
+		e.logger.Warn("Zigbee2MQTT broker not allowed in air-gapped mode", "broker", e.cfg.Broker, "error", e.blocked)
+		return
+	}
+	e.session.OnConnectionLost(func(err error) {
+		e.logger.Warn("Zigbee2MQTT broker connection lost", "broker", e.cfg.Broker, "error", err)
+	})
+	e.session.OnConnect(e.connected)
+	go func() {
+		<-ctx.Done()
+		e.session.Release()
+	}()
+}
+
+// connected subscribes to everything under the base topic. The retained
+// bridge/devices message arrives first and names the devices; friendly
+// names may contain slashes, so device topics are matched against it
+// rather than subscribed one by one.
+func (e *Executor) connected(client paho.Client) {
+	client.Subscribe(e.cfg.BaseTopic+"/#", 0, func(_ paho.Client, msg paho.Message) {
+		e.receive(msg.Topic(), msg.Payload())
+	})
+}
+
+func (e *Executor) receive(topic string, payload []byte) {
+	name, ok := strings.CutPrefix(topic, e.cfg.BaseTopic+"/")
+	if !ok {
+		return
+	}
+	switch {
+	case name == "bridge/devices":
+		e.discovered(payload)
+	case strings.HasPrefix(name, "bridge/"), strings.HasSuffix(name, "/set"), strings.HasSuffix(name, "/get"):
+	case strings.HasSuffix(name, "/availability"):
+		e.availability(strings.TrimSuffix(name, "/availability"), payload)
+	default:
+		e.observe(name, payload)
+	}
+}
+
+// discovered replaces the device list from a bridge/devices message
+func (e *Executor) discovered(payload []byte) {
+	var list []struct {
+		IEEE         string `json:"ieee_address"`
+		FriendlyName string `json:"friendly_name"`
+		Type         string `json:"type"`
+		Disabled     bool   `json:"disabled"`
+		Definition   *struct {
+			Model       string          `json:"model"`
+			Vendor      string          `json:"vendor"`
+			Description string          `json:"description"`
+			Exposes     json.RawMessage `json:"exposes"`
+		} `json:"definition"`
+	}
+	if err := json.Unmarshal(payload, &list); err != nil {
+		e.logger.Warn("Zigbee2MQTT sent an unreadable device list", "error", err)
+		return
+	}
+
+	e.mu.Lock()
+	seen := make(map[string]bool)
+	var upserts []registry.Device
+	for _, entry := range list {
+		// The coordinator and unsupported devices have nothing to control
+		if entry.Type == "Coordinator" || entry.Disabled || entry.Definition == nil {
+			continue
+		}
+		seen[entry.IEEE] = true
+		d, ok := e.devices[entry.IEEE]
+		if !ok {
+			d = &device{IEEE: entry.IEEE, state: make(map[string]interface{}), changed: make(chan struct{})}
+			e.devices[entry.IEEE] = d
+		}
+		d.Name = entry.FriendlyName
+		d.Model = entry.Definition.Model
+		d.Vendor = entry.Definition.Vendor
+		d.Description = entry.Definition.Description
+		d.features = flatten(entry.Definition.Exposes, "")
+		if report, ok := e.pending[d.Name]; ok {
+			for k, v := range report {
+				d.state[k] = v
+			}
+			d.updatedAt = time.Now()
+		}
+		upserts = append(upserts, registry.Device{
+			ID:     "zigbee:" + d.IEEE,
+			Name:   d.Name,
+			Kind:   kind(d.features),
+			Module: "zigbee",
+			State:  registryState(d.state),
+		})
+	}
+	clear(e.pending)
+	var removed []string
+	for ieee := range e.devices {
+		if !seen[ieee] {
+			delete(e.devices, ieee)
+			removed = append(removed, ieee)
+		}
+	}
+	e.mu.Unlock()
+
+	if e.registry == nil {
+		return
+	}
+	for _, d := range upserts {
+		e.registry.Upsert(d)
+	}
+	for _, ieee := range removed {
+		e.registry.Remove("zigbee:" + ieee)
+	}
+}
+
+// byName finds a device by its friendly name; the caller holds e.mu
+func (e *Executor) byName(name string) *device {
+	for _, d := range e.devices {
+		if d.Name == name {
+			return d
+		}
+	}
+	return nil
+}
+
+// observe merges a state report and mirrors it into the registry
+func (e *Executor) observe(name string, payload []byte) {
+	report := make(map[string]interface{})
+	if err := json.Unmarshal(payload, &report); err != nil {
+		return
+	}
+	e.mu.Lock()
+	d := e.byName(name)
+	if d == nil {
+		if len(e.devices) == 0 {
+			e.pending[name] = report
+		}
+		e.mu.Unlock()
+		return
+	}
+	for k, v := range report {
+		d.state[k] = v
+	}
+	d.updatedAt = time.Now()
+	close(d.changed)
+	d.changed = make(chan struct{})
+	ieee := d.IEEE
+	e.mu.Unlock()
+
+	if e.registry != nil {
+		e.registry.UpdateState("zigbee:"+ieee, registryState(report))
+	}
+}
+
+// registryState adds the registry's keys for a kind to a state report:
+// "on" for lights and switches, "locked" for locks, and "open" for
+// contact sensors
+func registryState(report map[string]interface{}) map[string]interface{} {
+	state := make(map[string]interface{}, len(report)+1)
+	for k, v := range report {
+		state[k] = v
+	}
+	if s, ok := report["state"].(string); ok {
+		switch strings.ToUpper(s) {
+		case "ON":
+			state["on"] = true
+		case "OFF":
+			state["on"] = false
+		case "LOCK":
+			state["locked"] = true
+		case "UNLOCK":
+			state["locked"] = false
+		}
+	}
+	if contact, ok := report["contact"].(bool); ok {
+		// Zigbee2MQTT reports contact: true when closed
+		state["open"] = !contact
+	}
+	return state
+}
+
+// availability records an availability report, which is {"state":
+// "online"} or, in older versions, a bare "online"
+func (e *Executor) availability(name string, payload []byte) {
+	var report struct {
+		State string `json:"state"`
+	}
+	if json.Unmarshal(payload, &report) != nil {
+		report.State = string(payload)
+	}
+	online := report.State == "online"
+
+	e.mu.Lock()
+	d := e.byName(name)
+	if d != nil {
+		d.online = &online
+	}
+	e.mu.Unlock()
+	if d != nil && e.registry != nil {
+		e.registry.UpdateState("zigbee:"+d.IEEE, map[string]interface{}{"online": online})
+	}
+}
+
+func (e *Executor) Name() string {
+	return "zigbee"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"device.list", "device.control", "device.query"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{Network: true}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "zigbee",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	switch i.IntentType {
+	case "device.list":
+		result.Success = true
+		result.Result = map[string]interface{}{"devices": e.list()}
+
+	case "device.control":
+		var params struct {
+			Device string `param:"device,required"`
+			Action string `param:"action,required"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		d, err := e.find(params.Device)
+		if err != nil {
+			return fail(err)
+		}
+		payload, err := command(d.features, strings.ToLower(params.Action), i.Parameters)
+		if err != nil {
+			return fail(err)
+		}
+		if err := e.send(d.Name+"/set", payload); err != nil {
+			return fail(err)
+		}
+		usage.DeviceCommand(ctx)
+		result.Success = true
+		result.Result = map[string]interface{}{"device": d.IEEE, "name": d.Name, "action": params.Action, "set": payload}
+
+	case "device.query":
+		var params struct {
+			Device  string `param:"device,required"`
+			Refresh bool   `param:"refresh"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		d, err := e.find(params.Device)
+		if err != nil {
+			return fail(err)
+		}
+		values, updatedAt, err := e.query(ctx, d, params.Refresh)
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"device":     d.IEEE,
+			"name":       d.Name,
+			"state":      values,
+			"updated_at": updatedAt.Format(time.RFC3339),
+		}
+
+	default:
+		return fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+	return result, nil
+}
+
+// Exclusions shares the device among everyday commands, as the mqtt
+// executor does
+func (e *Executor) Exclusions(i *intent.Intent) []gateway.Exclusion {
+	if i.IntentType != "device.control" {
+		return nil
+	}
+	name, _ := i.StringParam("device")
+	d, err := e.find(name)
+	if err != nil {
+		return nil
+	}
+	return []gateway.Exclusion{{Group: "device:zigbee:" + d.IEEE, Shared: true}}
+}
+
+// find looks a device up by registry ID, IEEE address, or friendly name,
+// returning a copy of its description
+func (e *Executor) find(name string) (device, error) {
+	name = strings.TrimPrefix(name, "zigbee:")
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.devices) == 0 {
+		return device{}, agenterrors.New(agenterrors.Unavailable, "Zigbee2MQTT hasn't sent its device list yet")
+	}
+	for _, d := range e.devices {
+		if strings.EqualFold(d.IEEE, name) || strings.EqualFold(d.Name, name) {
+			return device{IEEE: d.IEEE, Name: d.Name, features: d.features}, nil
+		}
+	}
+	return device{}, agenterrors.Newf(agenterrors.NotFound, "no Zigbee device named '%s'", name)
+}
+
+// send publishes a JSON payload under the base topic and waits for the
+// broker to acknowledge it. It refuses rather than queues while
+// disconnected, so a command is never delivered late.
+func (e *Executor) send(topic string, payload map[string]interface{}) error {
+	if e.blocked != nil {
+		return e.blocked
+	}
+	if !e.client.IsConnectionOpen() {
+		return agenterrors.Newf(agenterrors.Unavailable, "not connected to MQTT broker %s", e.cfg.Broker)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return agenterrors.Wrap(agenterrors.InvalidParams, err)
+	}
+	topic = e.cfg.BaseTopic + "/" + topic
+	token := e.client.Publish(topic, 0, false, data)
+	if !token.WaitTimeout(e.cfg.Timeout) {
+		return agenterrors.Newf(agenterrors.Timeout, "MQTT broker did not acknowledge %s", topic)
+	}
+	if err := token.Error(); err != nil {
+		return agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	return nil
+}
+
+// query returns the device's last reported state. With refresh, or when
+// nothing has been reported yet, it asks the device for every value it
+// can be asked for and waits for a report.
+func (e *Executor) query(ctx context.Context, d device, refresh bool) (map[string]interface{}, time.Time, error) {
+	e.mu.Lock()
+	current := e.devices[d.IEEE]
+	if current == nil {
+		e.mu.Unlock()
+		return nil, time.Time{}, agenterrors.Newf(agenterrors.NotFound, "%s is no longer paired", d.Name)
+	}
+	reported, changed := !current.updatedAt.IsZero(), current.changed
+	e.mu.Unlock()
+
+	if refresh || !reported {
+		get := make(map[string]interface{})
+		for _, f := range d.features {
+			if f.Access&accessGet != 0 {
+				get[f.Property] = ""
+			}
+		}
+		if len(get) > 0 {
+			if err := e.send(d.Name+"/get", get); err != nil {
+				return nil, time.Time{}, err
+			}
+		}
+		timer := time.NewTimer(e.cfg.Timeout)
+		defer timer.Stop()
+		select {
+		case <-changed:
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil, time.Time{}, ctx.Err()
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if current.updatedAt.IsZero() {
+		return nil, time.Time{}, agenterrors.Newf(agenterrors.Unavailable, "%s has not reported its state", d.Name)
+	}
+	values := make(map[string]interface{}, len(current.state))
+	for k, v := range current.state {
+		values[k] = v
+	}
+	return values, current.updatedAt, nil
+}
+
+// list describes every device with its actions and last reported state
+func (e *Executor) list() []map[string]interface{} {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	list := make([]map[string]interface{}, 0, len(e.devices))
+	for _, d := range e.devices {
+		entry := map[string]interface{}{
+			"id":          "zigbee:" + d.IEEE,
+			"name":        d.Name,
+			"kind":        kind(d.features),
+			"model":       d.Model,
+			"vendor":      d.Vendor,
+			"description": d.Description,
+			"actions":     actionNames(d.features),
+			"settable":    settableNames(d.features),
+		}
+		if d.online != nil {
+			entry["online"] = *d.online
+		}
+		if !d.updatedAt.IsZero() {
+			values := make(map[string]interface{}, len(d.state))
+			for k, v := range d.state {
+				values[k] = v
+			}
+			entry["state"] = values
+			entry["updated_at"] = d.updatedAt.Format(time.RFC3339)
+		}
+		list = append(list, entry)
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a]["name"].(string) < list[b]["name"].(string) })
+	return list
+}
+
+func (e *Executor) IsAvailable() bool {
+	return e.blocked == nil && e.client.IsConnectionOpen()
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"device.list": schema.MustParse(`{"type": "object", "properties": {}}`),
+		"device.control": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"device": {"type": "string", "minLength": 1},
+				"action": {"type": "string", "minLength": 1}
+			},
+			"required": ["device", "action"]
+		}`),
+		"device.query": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"device": {"type": "string", "minLength": 1},
+				"refresh": {"type": "boolean"}
+			},
+			"required": ["device"]
+		}`),
+	}
+}
@@ -0,0 +1,33 @@
+package zigbee
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+)
+
+func TestBrokerChecksEgress(t *testing.T) {
+	saved := connpool.Default
+	defer func() { connpool.Default = saved }()
+	connpool.Default = connpool.New(connpool.Config{Egress: &connpool.Egress{}})
+
+	// 203.0.113.0/24 is reserved for documentation, so never local
+	e, err := NewExecutor(Config{Broker: "tcp://203.0.113.5:1883"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Start(context.Background())
+	if e.IsAvailable() {
+		t.Error("available with its broker blocked")
+	}
+	err = e.send("bridge/request/devices", map[string]interface{}{})
+	if !errors.Is(err, connpool.ErrEgressBlocked) {
+		t.Fatalf("got %v, want the broker refused in air-gapped mode", err)
+	}
+	if code := agenterrors.CodeOf(err); code != agenterrors.Unavailable {
+		t.Errorf("got code %s, want %s", code, agenterrors.Unavailable)
+	}
+}
@@ -0,0 +1,180 @@
+package zwave
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+)
+
+// schemaVersion is the zwave-js-server API schema the client speaks. A
+// server that only offers older or newer schemas is used at the nearest
+// one it has; the messages used here haven't changed in a breaking way.
+const schemaVersion = 35
+
+// message is anything zwave-js-server sends: the version banner on
+// connecting, command results, and events
+type message struct {
+	Type string `json:"type"`
+
+	// version
+	ServerVersion    string `json:"serverVersion"`
+	DriverVersion    string `json:"driverVersion"`
+	MinSchemaVersion int    `json:"minSchemaVersion"`
+	MaxSchemaVersion int    `json:"maxSchemaVersion"`
+
+	// result
+	MessageID string          `json:"messageId"`
+	Success   bool            `json:"success"`
+	Result    json.RawMessage `json:"result"`
+	ErrorCode string          `json:"errorCode"`
+	Message   string          `json:"message"`
+
+	// event
+	Event json.RawMessage `json:"event"`
+}
+
+// conn is one connection to zwave-js-server
+type conn struct {
+	ws      *websocket.Conn
+	writeMu sync.Mutex
+	nextID  atomic.Uint64
+
+	mu      sync.Mutex
+	pending map[string]chan message
+	err     error // why the connection closed
+	done    chan struct{}
+}
+
+// dial connects and agrees on a schema version. Events are handed to
+// onEvent from the reading goroutine until the connection closes.
+func dial(ctx context.Context, server string, onEvent func(json.RawMessage)) (*conn, message, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, message{}, err
+	}
+	if err := connpool.Default.CheckEgress(u.Host); err != nil {
+		return nil, message{}, agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+	ws, _, err := websocket.DefaultDialer.DialContext(ctx, server, nil)
+	if err != nil {
+		return nil, message{}, err
+	}
+	var version message
+	if err := ws.ReadJSON(&version); err != nil || version.Type != "version" {
+		ws.Close()
+		return nil, message{}, fmt.Errorf("%s did not introduce itself as zwave-js-server", server)
+	}
+	c := &conn{ws: ws, pending: make(map[string]chan message), done: make(chan struct{})}
+	go c.read(onEvent)
+
+	want := min(max(schemaVersion, version.MinSchemaVersion), version.MaxSchemaVersion)
+	if _, err := c.call(ctx, "set_api_schema", map[string]interface{}{"schemaVersion": want}); err != nil {
+		c.close()
+		return nil, message{}, err
+	}
+	return c, version, nil
+}
+
+func (c *conn) read(onEvent func(json.RawMessage)) {
+	var err error
+	for {
+		var msg message
+		if err = c.ws.ReadJSON(&msg); err != nil {
+			break
+		}
+		switch msg.Type {
+		case "result":
+			c.mu.Lock()
+			ch, ok := c.pending[msg.MessageID]
+			delete(c.pending, msg.MessageID)
+			c.mu.Unlock()
+			if ok {
+				ch <- msg
+			}
+		case "event":
+			onEvent(msg.Event)
+		}
+	}
+	c.mu.Lock()
+	c.err = err
+	c.pending = nil
+	c.mu.Unlock()
+	close(c.done)
+}
+
+// call sends a command and waits for its result
+func (c *conn) call(ctx context.Context, command string, args map[string]interface{}) (json.RawMessage, error) {
+	id := strconv.FormatUint(c.nextID.Add(1), 10)
+	req := map[string]interface{}{"messageId": id, "command": command}
+	for k, v := range args {
+		req[k] = v
+	}
+	ch := make(chan message, 1)
+	c.mu.Lock()
+	if c.pending == nil {
+		c.mu.Unlock()
+		return nil, agenterrors.New(agenterrors.Unavailable, "the connection to zwave-js-server closed")
+	}
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	c.writeMu.Lock()
+	err := c.ws.WriteJSON(req)
+	c.writeMu.Unlock()
+	if err != nil {
+		c.forget(id)
+		return nil, agenterrors.Wrap(agenterrors.Unavailable, err)
+	}
+
+	select {
+	case msg := <-ch:
+		if !msg.Success {
+			return nil, resultError(command, msg)
+		}
+		return msg.Result, nil
+	case <-c.done:
+		return nil, agenterrors.New(agenterrors.Unavailable, "the connection to zwave-js-server closed")
+	case <-ctx.Done():
+		c.forget(id)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, agenterrors.Newf(agenterrors.Timeout, "zwave-js-server did not answer %s", command)
+		}
+		return nil, agenterrors.Wrap(agenterrors.Cancelled, ctx.Err())
+	}
+}
+
+func (c *conn) forget(id string) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+func (c *conn) close() {
+	c.ws.Close()
+	<-c.done
+}
+
+// resultError gives a failed command's error code an agent error code
+func resultError(command string, msg message) error {
+	text := fmt.Sprintf("%s failed: %s", command, msg.ErrorCode)
+	if msg.Message != "" {
+		text += ": " + msg.Message
+	}
+	switch msg.ErrorCode {
+	case "node_not_found", "endpoint_not_found":
+		return agenterrors.New(agenterrors.NotFound, text)
+	case "invalid_argument", "unknown_command", "schema_incompatible":
+		return agenterrors.New(agenterrors.InvalidParams, text)
+	}
+	return agenterrors.New(agenterrors.Unavailable, text)
+}
@@ -0,0 +1,26 @@
+package zwave
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+)
+
+func TestDialChecksEgress(t *testing.T) {
+	saved := connpool.Default
+	defer func() { connpool.Default = saved }()
+	connpool.Default = connpool.New(connpool.Config{Egress: &connpool.Egress{}})
+
+	// 203.0.113.0/24 is reserved for documentation, so never local
+	_, _, err := dial(context.Background(), "ws://203.0.113.5:3000", func(json.RawMessage) {})
+	if !errors.Is(err, connpool.ErrEgressBlocked) {
+		t.Fatalf("got %v, want the dial refused in air-gapped mode", err)
+	}
+	if code := agenterrors.CodeOf(err); code != agenterrors.Unavailable {
+		t.Errorf("got code %s, want %s", code, agenterrors.Unavailable)
+	}
+}
@@ -0,0 +1,500 @@
+package zwave
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/registry"
+)
+
+// Command classes the executor understands
+const (
+	ccBinarySwitch       = 37
+	ccMultilevelSwitch   = 38
+	ccBinarySensor       = 48
+	ccMultilevelSensor   = 49
+	ccThermostatSetpoint = 67
+	ccDoorLock           = 98
+	ccNotification       = 113
+)
+
+// Door Lock modes
+const (
+	lockUnsecured = 0
+	lockSecured   = 255
+)
+
+// Node statuses as zwave-js numbers them
+var statuses = map[int]string{0: "unknown", 1: "asleep", 2: "awake", 3: "dead", 4: "alive"}
+
+// metadata describes a value
+type metadata struct {
+	Type      string            `json:"type"`
+	Readable  bool              `json:"readable"`
+	Writeable bool              `json:"writeable"`
+	Label     string            `json:"label,omitempty"`
+	Unit      string            `json:"unit,omitempty"`
+	Min       *float64          `json:"min,omitempty"`
+	Max       *float64          `json:"max,omitempty"`
+	States    map[string]string `json:"states,omitempty"`
+
+	// AllowManualEntry is set on configuration parameters whose states
+	// are only suggestions
+	AllowManualEntry *bool `json:"allowManualEntry,omitempty"`
+}
+
+// value is one of a node's values, identified by command class, endpoint,
+// property, and property key
+type value struct {
+	CommandClass     int         `json:"commandClass"`
+	CommandClassName string      `json:"commandClassName,omitempty"`
+	Endpoint         int         `json:"endpoint"`
+	Property         interface{} `json:"property"`
+	PropertyKey      interface{} `json:"propertyKey,omitempty"`
+	PropertyName     string      `json:"propertyName,omitempty"`
+	PropertyKeyName  string      `json:"propertyKeyName,omitempty"`
+	Metadata         *metadata   `json:"metadata,omitempty"`
+	Value            interface{} `json:"value,omitempty"`
+}
+
+// id is the value's ID as zwave-js-ui shows it, e.g. 37-0-targetValue or
+// 67-0-setpoint-1
+func (v *value) id() string {
+	id := fmt.Sprintf("%d-%d-%v", v.CommandClass, v.Endpoint, v.Property)
+	if v.PropertyKey != nil {
+		id += fmt.Sprintf("-%v", v.PropertyKey)
+	}
+	return id
+}
+
+// valueID is the value's address in commands
+func (v *value) valueID() map[string]interface{} {
+	id := map[string]interface{}{"commandClass": v.CommandClass, "endpoint": v.Endpoint, "property": v.Property}
+	if v.PropertyKey != nil {
+		id["propertyKey"] = v.PropertyKey
+	}
+	return id
+}
+
+func (v *value) writeable() bool {
+	return v.Metadata != nil && v.Metadata.Writeable
+}
+
+// label names the value for people
+func (v *value) label() string {
+	if v.Metadata != nil && v.Metadata.Label != "" {
+		return v.Metadata.Label
+	}
+	if v.PropertyName != "" {
+		return v.PropertyName
+	}
+	return fmt.Sprint(v.Property)
+}
+
+// nodeState is a node as zwave-js-server describes it
+type nodeState struct {
+	NodeID           int    `json:"nodeId"`
+	Name             string `json:"name"`
+	Location         string `json:"location"`
+	Label            string `json:"label"`
+	Status           int    `json:"status"`
+	Ready            bool   `json:"ready"`
+	IsControllerNode bool   `json:"isControllerNode"`
+	DeviceConfig     *struct {
+		Manufacturer string `json:"manufacturer"`
+		Label        string `json:"label"`
+		Description  string `json:"description"`
+	} `json:"deviceConfig"`
+	Values []*value `json:"values"`
+}
+
+// node is a node and its values, kept current by events
+type node struct {
+	ID           int
+	Name         string
+	Location     string
+	Manufacturer string
+	Product      string
+	Status       int
+	Ready        bool
+	values       map[string]*value // by id()
+
+	updatedAt time.Time
+	changed   chan struct{} // closed and replaced on every value update
+}
+
+func newNode(s nodeState) *node {
+	n := &node{
+		ID:       s.NodeID,
+		Name:     s.Name,
+		Location: s.Location,
+		Status:   s.Status,
+		Ready:    s.Ready,
+		values:   make(map[string]*value, len(s.Values)),
+		changed:  make(chan struct{}),
+	}
+	n.Product = s.Label
+	if s.DeviceConfig != nil {
+		n.Manufacturer = s.DeviceConfig.Manufacturer
+		if s.DeviceConfig.Description != "" {
+			n.Product = s.DeviceConfig.Description
+		}
+	}
+	if n.Name == "" {
+		n.Name = fmt.Sprintf("Node %d", n.ID)
+		if n.Product != "" {
+			n.Name = fmt.Sprintf("%s (node %d)", n.Product, n.ID)
+		}
+	}
+	for _, v := range s.Values {
+		n.values[v.id()] = v
+	}
+	return n
+}
+
+// find returns the node's value of a command class and property on the
+// lowest endpoint that has it
+func (n *node) find(cc int, property string, key interface{}) *value {
+	var found *value
+	for _, v := range n.values {
+		if v.CommandClass != cc || fmt.Sprint(v.Property) != property {
+			continue
+		}
+		if key != nil && fmt.Sprint(v.PropertyKey) != fmt.Sprint(key) {
+			continue
+		}
+		if found == nil || v.Endpoint < found.Endpoint {
+			found = v
+		}
+	}
+	return found
+}
+
+func (n *node) has(cc int) bool {
+	for _, v := range n.values {
+		if v.CommandClass == cc {
+			return true
+		}
+	}
+	return false
+}
+
+// kind classifies a node by its command classes
+func (n *node) kind() registry.Kind {
+	switch {
+	case n.has(ccDoorLock):
+		return registry.KindLock
+	case n.has(ccThermostatSetpoint):
+		return registry.KindThermostat
+	case n.has(ccMultilevelSwitch):
+		return registry.KindLight
+	case n.has(ccBinarySwitch):
+		return registry.KindSwitch
+	case n.find(ccNotification, "Access Control", "Door state") != nil, n.find(ccBinarySensor, "Door/Window", nil) != nil:
+		return registry.KindContactSensor
+	}
+	return registry.KindSensor
+}
+
+// setpoint is the heating setpoint, or failing that the first writeable one
+func (n *node) setpoint() *value {
+	if v := n.find(ccThermostatSetpoint, "setpoint", 1); v != nil {
+		return v
+	}
+	var found *value
+	for _, v := range n.values {
+		if v.CommandClass == ccThermostatSetpoint && v.writeable() && (found == nil || v.id() < found.id()) {
+			found = v
+		}
+	}
+	return found
+}
+
+// state summarizes the node in the registry's terms: "on" for lights and
+// switches, "locked" for locks, "open" for contact sensors, plus
+// brightness, setpoint, and sensor readings
+func (n *node) state() map[string]interface{} {
+	state := map[string]interface{}{"status": statuses[n.Status]}
+	if v := n.find(ccBinarySwitch, "currentValue", nil); v != nil {
+		if on, ok := v.Value.(bool); ok {
+			state["on"] = on
+		}
+	}
+	if v := n.find(ccMultilevelSwitch, "currentValue", nil); v != nil {
+		if level, ok := v.Value.(float64); ok {
+			state["on"] = level > 0
+			state["brightness"] = math.Round(math.Min(level, 99) / 99 * 100)
+		}
+	}
+	if v := n.find(ccDoorLock, "currentMode", nil); v != nil {
+		if mode, ok := v.Value.(float64); ok {
+			state["locked"] = mode == lockSecured
+		}
+	}
+	if v := n.find(ccNotification, "Access Control", "Door state"); v != nil {
+		// 22 is "Window/door is open", 23 "closed"
+		if s, ok := v.Value.(float64); ok {
+			state["open"] = s == 22
+		}
+	}
+	if v := n.find(ccBinarySensor, "Door/Window", nil); v != nil {
+		if open, ok := v.Value.(bool); ok {
+			state["open"] = open
+		}
+	}
+	if v := n.setpoint(); v != nil && v.Value != nil {
+		state["setpoint"] = v.Value
+	}
+	for _, v := range n.values {
+		if v.CommandClass == ccMultilevelSensor && v.Value != nil {
+			state[strings.ToLower(strings.ReplaceAll(v.label(), " ", "_"))] = v.Value
+		}
+	}
+	return state
+}
+
+// actionNames lists the device.control actions a node supports
+func (n *node) actionNames() []string {
+	var names []string
+	if n.find(ccDoorLock, "targetMode", nil) != nil {
+		names = append(names, "lock", "unlock")
+	}
+	if n.find(ccBinarySwitch, "targetValue", nil) != nil || n.find(ccMultilevelSwitch, "targetValue", nil) != nil {
+		names = append(names, "on", "off", "toggle")
+	}
+	if n.find(ccMultilevelSwitch, "targetValue", nil) != nil {
+		names = append(names, "brightness")
+	}
+	if n.setpoint() != nil {
+		names = append(names, "temperature")
+	}
+	for _, v := range n.values {
+		if v.writeable() {
+			names = append(names, "set")
+			break
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// settable lists the IDs of the node's writeable values
+func (n *node) settable() []string {
+	var ids []string
+	for id, v := range n.values {
+		if v.writeable() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// command picks the value a device.control action sets, and the value to
+// set it to. Brightness is a percentage and temperature in the setpoint's
+// unit; set takes a value ID (as zwave-js-ui shows them) or a property
+// name, and a value.
+func (n *node) command(action string, params map[string]interface{}) (*value, interface{}, error) {
+	switch action {
+	case "lock", "unlock":
+		v := n.find(ccDoorLock, "targetMode", nil)
+		if v == nil {
+			break
+		}
+		if action == "lock" {
+			return v, lockSecured, nil
+		}
+		return v, lockUnsecured, nil
+
+	case "on", "off", "toggle":
+		if v := n.find(ccBinarySwitch, "targetValue", nil); v != nil {
+			on := action == "on"
+			if action == "toggle" {
+				current, _ := n.find(ccBinarySwitch, "currentValue", nil).valueOr(false).(bool)
+				on = !current
+			}
+			return v, on, nil
+		}
+		v := n.find(ccMultilevelSwitch, "targetValue", nil)
+		if v == nil {
+			break
+		}
+		on := action == "on"
+		if action == "toggle" {
+			current, _ := n.find(ccMultilevelSwitch, "currentValue", nil).valueOr(0.0).(float64)
+			on = current == 0
+		}
+		if on {
+			// 255 restores the last level
+			return v, 255, nil
+		}
+		return v, 0, nil
+
+	case "brightness":
+		v := n.find(ccMultilevelSwitch, "targetValue", nil)
+		if v == nil {
+			break
+		}
+		percent, ok := number(params, "brightness", "value")
+		if !ok || percent < 0 || percent > 100 {
+			return nil, nil, agenterrors.New(agenterrors.InvalidParams, "brightness needs a value from 0 to 100")
+		}
+		return v, math.Round(percent / 100 * 99), nil
+
+	case "temperature":
+		v := n.setpoint()
+		if v == nil {
+			break
+		}
+		t, ok := number(params, "temperature", "value")
+		if !ok {
+			return nil, nil, agenterrors.New(agenterrors.InvalidParams, "temperature needs a value")
+		}
+		if err := check(v, t); err != nil {
+			return nil, nil, err
+		}
+		return v, t, nil
+
+	case "set":
+		property, _ := params["property"].(string)
+		v, err := n.writeableValue(property)
+		if err != nil {
+			return nil, nil, err
+		}
+		target, ok := params["value"]
+		if !ok {
+			return nil, nil, agenterrors.New(agenterrors.InvalidParams, "set needs a value")
+		}
+		if err := check(v, target); err != nil {
+			return nil, nil, err
+		}
+		return v, target, nil
+	}
+	names := n.actionNames()
+	if len(names) == 0 {
+		return nil, nil, agenterrors.Newf(agenterrors.InvalidParams, "%s only reports its state", n.Name)
+	}
+	return nil, nil, agenterrors.Newf(agenterrors.InvalidParams, "unsupported action '%s' (supports %s)", action, strings.Join(names, ", "))
+}
+
+func (v *value) valueOr(fallback interface{}) interface{} {
+	if v == nil || v.Value == nil {
+		return fallback
+	}
+	return v.Value
+}
+
+// writeableValue finds a writeable value by ID or by a property name only
+// one of the node's writeable values has
+func (n *node) writeableValue(property string) (*value, error) {
+	if v, ok := n.values[property]; ok && v.writeable() {
+		return v, nil
+	}
+	var matches []*value
+	for _, v := range n.values {
+		if v.writeable() && (strings.EqualFold(v.PropertyName, property) || strings.EqualFold(v.label(), property)) {
+			matches = append(matches, v)
+		}
+	}
+	switch len(matches) {
+	case 1:
+		return matches[0], nil
+	case 0:
+		return nil, agenterrors.Newf(agenterrors.InvalidParams, "'%s' is not a settable value of %s (settable: %s)",
+			property, n.Name, strings.Join(n.settable(), ", "))
+	}
+	ids := make([]string, len(matches))
+	for i, v := range matches {
+		ids[i] = v.id()
+	}
+	sort.Strings(ids)
+	return nil, agenterrors.Newf(agenterrors.InvalidParams, "'%s' matches more than one value; use an ID: %s", property, strings.Join(ids, ", "))
+}
+
+// check validates a value against its metadata
+func check(v *value, target interface{}) error {
+	if v.Metadata == nil {
+		return nil
+	}
+	m := v.Metadata
+	switch m.Type {
+	case "number":
+		f, ok := toFloat(target)
+		if !ok {
+			return agenterrors.Newf(agenterrors.InvalidParams, "%s takes a number", v.label())
+		}
+		if len(m.States) > 0 && !m.allowsManualEntry() {
+			if _, ok := m.States[strconv.FormatFloat(f, 'f', -1, 64)]; !ok {
+				return agenterrors.Newf(agenterrors.InvalidParams, "%s takes one of %s", v.label(), m.describeStates())
+			}
+		}
+		if (m.Min != nil && f < *m.Min) || (m.Max != nil && f > *m.Max) {
+			lo, hi := math.Inf(-1), math.Inf(1)
+			if m.Min != nil {
+				lo = *m.Min
+			}
+			if m.Max != nil {
+				hi = *m.Max
+			}
+			return agenterrors.Newf(agenterrors.InvalidParams, "%s must be from %g to %g", v.label(), lo, hi)
+		}
+	case "boolean":
+		if _, ok := target.(bool); !ok {
+			return agenterrors.Newf(agenterrors.InvalidParams, "%s takes true or false", v.label())
+		}
+	case "string":
+		if _, ok := target.(string); !ok {
+			return agenterrors.Newf(agenterrors.InvalidParams, "%s takes a string", v.label())
+		}
+	}
+	return nil
+}
+
+// allowsManualEntry reports whether a number with named states also takes
+// other values. Without a range, the states are all there is.
+func (m *metadata) allowsManualEntry() bool {
+	if m.AllowManualEntry != nil {
+		return *m.AllowManualEntry
+	}
+	return m.Min != nil || m.Max != nil
+}
+
+func (m *metadata) describeStates() string {
+	keys := make([]string, 0, len(m.States))
+	for k := range m.States {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	described := make([]string, len(keys))
+	for i, k := range keys {
+		described[i] = k + " (" + m.States[k] + ")"
+	}
+	return strings.Join(described, ", ")
+}
+
+// number reads the first of the named parameters that is a number
+func number(params map[string]interface{}, names ...string) (float64, bool) {
+	for _, name := range names {
+		if v, ok := toFloat(params[name]); ok {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,563 @@
+// Package zwave controls the nodes of a Z-Wave network through
+// zwave-js-server's WebSocket API, learning the nodes and their values from
+// the server rather than from configuration
+package zwave
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/registry"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/usage"
+)
+
+// Config sets the server
+type Config struct {
+	// URL of zwave-js-server, e.g. ws://192.168.1.10:3000
+	URL string
+
+	// Timeout bounds commands; battery devices can take a while to
+	// acknowledge (default 10s)
+	Timeout time.Duration
+}
+
+// Executor handles device.list, device.control, and device.query for the
+// Z-Wave network; intents reach it by targeting the zwave module
+type Executor struct {
+	cfg      Config
+	registry *registry.Registry
+	logger   *slog.Logger
+
+	mu    sync.Mutex
+	conn  *conn
+	nodes map[int]*node
+}
+
+// NewExecutor creates a zwave-js-server executor. Nodes are mirrored into
+// reg as "zwave:<node id>" when it is not nil.
+func NewExecutor(cfg Config, reg *registry.Registry) (*Executor, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "ws" && u.Scheme != "wss") || u.Host == "" {
+		return nil, agenterrors.Newf(agenterrors.InvalidParams, "zwave-js-server URL must be ws://host:port, not %q", cfg.URL)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	return &Executor{
+		cfg:      cfg,
+		registry: reg,
+		logger:   slog.Default(),
+		nodes:    make(map[int]*node),
+	}, nil
+}
+
+// SetLogger sets where connection problems are logged
+func (e *Executor) SetLogger(logger *slog.Logger) {
+	e.logger = logger
+}
+
+// Start connects to the server, reconnecting in the background whenever
+// the connection drops, until ctx is done
+func (e *Executor) Start(ctx context.Context) {
+	go func() {
+		backoff := 10 * time.Second
+		for {
+			if err := e.session(ctx); err != nil && ctx.Err() == nil {
+				e.logger.Warn("zwave-js-server connection failed", "url", e.cfg.URL, "error", err)
+			} else {
+				backoff = 10 * time.Second
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, time.Minute)
+		}
+	}()
+}
+
+// session connects, loads the network's state, and follows its events
+// until the connection drops
+func (e *Executor) session(ctx context.Context) error {
+	dialCtx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+	defer cancel()
+	c, version, err := dial(dialCtx, e.cfg.URL, e.event)
+	if err != nil {
+		return err
+	}
+	result, err := c.call(dialCtx, "start_listening", nil)
+	if err != nil {
+		c.close()
+		return err
+	}
+	var start struct {
+		State struct {
+			Nodes []nodeState `json:"nodes"`
+		} `json:"state"`
+	}
+	if err := json.Unmarshal(result, &start); err != nil {
+		c.close()
+		return fmt.Errorf("unreadable network state: %w", err)
+	}
+	e.logger.Info("Connected to zwave-js-server", "server", version.ServerVersion, "driver", version.DriverVersion, "nodes", len(start.State.Nodes))
+
+	e.mu.Lock()
+	e.conn = c
+	old := e.nodes
+	e.nodes = make(map[int]*node)
+	e.mu.Unlock()
+	for _, s := range start.State.Nodes {
+		e.upsert(s)
+	}
+	for id := range old {
+		e.mu.Lock()
+		_, ok := e.nodes[id]
+		e.mu.Unlock()
+		if !ok && e.registry != nil {
+			e.registry.Remove("zwave:" + strconv.Itoa(id))
+		}
+	}
+
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		c.close()
+	}
+	e.mu.Lock()
+	e.conn = nil
+	e.mu.Unlock()
+	if c.err != nil && ctx.Err() == nil {
+		return fmt.Errorf("connection lost: %w", c.err)
+	}
+	return nil
+}
+
+// upsert adds or replaces a node from its full state
+func (e *Executor) upsert(s nodeState) {
+	// The controller is a node too, but has nothing to control
+	if s.IsControllerNode {
+		return
+	}
+	n := newNode(s)
+	e.mu.Lock()
+	if old, ok := e.nodes[n.ID]; ok {
+		n.changed = old.changed
+	}
+	e.nodes[n.ID] = n
+	d := registry.Device{
+		ID:     "zwave:" + strconv.Itoa(n.ID),
+		Name:   n.Name,
+		Kind:   n.kind(),
+		Room:   n.Location,
+		Module: "zwave",
+		State:  n.state(),
+	}
+	e.mu.Unlock()
+	if e.registry != nil {
+		e.registry.Upsert(d)
+	}
+}
+
+// event applies a zwave-js event to the node it concerns
+func (e *Executor) event(raw json.RawMessage) {
+	var ev struct {
+		Source    string          `json:"source"`
+		Event     string          `json:"event"`
+		NodeID    int             `json:"nodeId"`
+		Args      json.RawMessage `json:"args"`
+		Node      *nodeState      `json:"node"`
+		NodeState *nodeState      `json:"nodeState"`
+	}
+	if err := json.Unmarshal(raw, &ev); err != nil {
+		return
+	}
+	switch {
+	case ev.Source == "controller" && ev.Event == "node added" && ev.Node != nil:
+		e.upsert(*ev.Node)
+		return
+	case ev.Source == "controller" && ev.Event == "node removed" && ev.Node != nil:
+		e.mu.Lock()
+		delete(e.nodes, ev.Node.NodeID)
+		e.mu.Unlock()
+		if e.registry != nil {
+			e.registry.Remove("zwave:" + strconv.Itoa(ev.Node.NodeID))
+		}
+		return
+	case ev.Source != "node":
+		return
+	case ev.Event == "ready" && ev.NodeState != nil:
+		e.upsert(*ev.NodeState)
+		return
+	}
+
+	e.mu.Lock()
+	n, ok := e.nodes[ev.NodeID]
+	if !ok {
+		e.mu.Unlock()
+		return
+	}
+	switch ev.Event {
+	case "value added", "value updated", "value notification", "metadata updated":
+		var args struct {
+			value
+			NewValue interface{} `json:"newValue"`
+		}
+		if json.Unmarshal(ev.Args, &args) != nil {
+			break
+		}
+		v, ok := n.values[args.id()]
+		if !ok {
+			v = &args.value
+			n.values[v.id()] = v
+		}
+		if args.Metadata != nil {
+			v.Metadata = args.Metadata
+		}
+		if ev.Event != "metadata updated" {
+			v.Value = args.NewValue
+		}
+	case "value removed":
+		var args value
+		if json.Unmarshal(ev.Args, &args) == nil {
+			delete(n.values, args.id())
+		}
+	case "sleep":
+		n.Status = 1
+	case "wake up":
+		n.Status = 2
+	case "dead":
+		n.Status = 3
+	case "alive":
+		n.Status = 4
+	default:
+		e.mu.Unlock()
+		return
+	}
+	n.updatedAt = time.Now()
+	close(n.changed)
+	n.changed = make(chan struct{})
+	id, state := n.ID, n.state()
+	e.mu.Unlock()
+	if e.registry != nil {
+		e.registry.UpdateState("zwave:"+strconv.Itoa(id), state)
+	}
+}
+
+func (e *Executor) Name() string {
+	return "zwave"
+}
+
+func (e *Executor) SupportedActions() []string {
+	return []string{"device.list", "device.control", "device.query"}
+}
+
+func (e *Executor) Needs() sandbox.Needs {
+	return sandbox.Needs{Network: true}
+}
+
+func (e *Executor) Execute(ctx context.Context, i *intent.Intent) (*gateway.ExecutionResult, error) {
+	result := &gateway.ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "zwave",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*gateway.ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	switch i.IntentType {
+	case "device.list":
+		result.Success = true
+		result.Result = map[string]interface{}{"devices": e.list()}
+
+	case "device.control":
+		var params struct {
+			Device string `param:"device,required"`
+			Action string `param:"action,required"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		c, err := e.connection()
+		if err != nil {
+			return fail(err)
+		}
+		var nodeID int
+		var name, valueID string
+		var target, address interface{}
+		err = e.withNode(params.Device, func(n *node) error {
+			v, t, err := n.command(strings.ToLower(params.Action), i.Parameters)
+			if err != nil {
+				return err
+			}
+			nodeID, name, valueID, target, address = n.ID, n.Name, v.id(), t, v.valueID()
+			return nil
+		})
+		if err != nil {
+			return fail(err)
+		}
+		if err := e.setValue(ctx, c, nodeID, address, target); err != nil {
+			return fail(err)
+		}
+		usage.DeviceCommand(ctx)
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"device": "zwave:" + strconv.Itoa(nodeID),
+			"name":   name,
+			"action": params.Action,
+			"value":  valueID,
+			"set":    target,
+		}
+
+	case "device.query":
+		var params struct {
+			Device  string `param:"device,required"`
+			Refresh bool   `param:"refresh"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		if params.Refresh {
+			if err := e.refresh(ctx, params.Device); err != nil {
+				return fail(err)
+			}
+		}
+		var entry map[string]interface{}
+		err := e.withNode(params.Device, func(n *node) error {
+			entry = describe(n, true)
+			return nil
+		})
+		if err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = entry
+
+	default:
+		return fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+	return result, nil
+}
+
+// Exclusions shares the node among everyday commands, as the mqtt
+// executor does
+func (e *Executor) Exclusions(i *intent.Intent) []gateway.Exclusion {
+	if i.IntentType != "device.control" {
+		return nil
+	}
+	name, _ := i.StringParam("device")
+	var group string
+	e.withNode(name, func(n *node) error {
+		group = "device:zwave:" + strconv.Itoa(n.ID)
+		return nil
+	})
+	if group == "" {
+		return nil
+	}
+	return []gateway.Exclusion{{Group: group, Shared: true}}
+}
+
+func (e *Executor) connection() (*conn, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.conn == nil {
+		return nil, agenterrors.Newf(agenterrors.Unavailable, "not connected to zwave-js-server %s", e.cfg.URL)
+	}
+	return e.conn, nil
+}
+
+// withNode runs fn with the node named by registry ID, node ID, or name,
+// holding the lock that guards it
+func (e *Executor) withNode(name string, fn func(n *node) error) error {
+	name = strings.TrimPrefix(name, "zwave:")
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if id, err := strconv.Atoi(name); err == nil {
+		if n, ok := e.nodes[id]; ok {
+			return fn(n)
+		}
+	}
+	for _, n := range e.nodes {
+		if strings.EqualFold(n.Name, name) {
+			return fn(n)
+		}
+	}
+	if e.conn == nil && len(e.nodes) == 0 {
+		return agenterrors.Newf(agenterrors.Unavailable, "not connected to zwave-js-server %s", e.cfg.URL)
+	}
+	return agenterrors.Newf(agenterrors.NotFound, "no Z-Wave device named '%s'", name)
+}
+
+// setValue sends node.set_value and checks its outcome. Since schema 29
+// the result carries a status; older servers only report success.
+func (e *Executor) setValue(ctx context.Context, c *conn, nodeID int, address, target interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+	defer cancel()
+	raw, err := c.call(ctx, "node.set_value", map[string]interface{}{
+		"nodeId":  nodeID,
+		"valueId": address,
+		"value":   target,
+	})
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Success *bool `json:"success"`
+		Result  *struct {
+			Status  int    `json:"status"`
+			Message string `json:"message"`
+		} `json:"result"`
+	}
+	if json.Unmarshal(raw, &result) != nil {
+		return nil
+	}
+	if result.Success != nil && !*result.Success {
+		return agenterrors.Newf(agenterrors.Unavailable, "node %d did not accept the value", nodeID)
+	}
+	if result.Result == nil {
+		return nil
+	}
+	// SetValueStatus: 1 is working, 254 and 255 success; the rest failures
+	switch status := result.Result.Status; status {
+	case 1, 254, 255:
+		return nil
+	case 0, 3, 4:
+		return agenterrors.Newf(agenterrors.Unsupported, "node %d can't set that value: %s", nodeID, result.Result.Message)
+	case 5:
+		return agenterrors.Newf(agenterrors.InvalidParams, "node %d rejected the value: %s", nodeID, result.Result.Message)
+	default:
+		return agenterrors.Newf(agenterrors.Unavailable, "node %d failed to set the value (status %d): %s", nodeID, status, result.Result.Message)
+	}
+}
+
+// refresh polls the values a node's state is summarized from. Sleeping
+// nodes only answer when they wake, so their cached values stand.
+func (e *Executor) refresh(ctx context.Context, name string) error {
+	c, err := e.connection()
+	if err != nil {
+		return err
+	}
+	var nodeID int
+	var asleep bool
+	var addresses []map[string]interface{}
+	err = e.withNode(name, func(n *node) error {
+		nodeID, asleep = n.ID, n.Status == 1
+		for _, v := range []*value{
+			n.find(ccBinarySwitch, "currentValue", nil),
+			n.find(ccMultilevelSwitch, "currentValue", nil),
+			n.find(ccDoorLock, "currentMode", nil),
+			n.setpoint(),
+		} {
+			if v != nil {
+				addresses = append(addresses, v.valueID())
+			}
+		}
+		return nil
+	})
+	if err != nil || asleep {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+	defer cancel()
+	for _, address := range addresses {
+		// The polled value also arrives as a value updated event
+		if _, err := c.call(ctx, "node.poll_value", map[string]interface{}{"nodeId": nodeID, "valueId": address}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// describe summarizes a node; with values, it includes every value by ID
+func describe(n *node, values bool) map[string]interface{} {
+	entry := map[string]interface{}{
+		"id":           "zwave:" + strconv.Itoa(n.ID),
+		"name":         n.Name,
+		"kind":         n.kind(),
+		"manufacturer": n.Manufacturer,
+		"product":      n.Product,
+		"status":       statuses[n.Status],
+		"ready":        n.Ready,
+		"actions":      n.actionNames(),
+		"state":        n.state(),
+	}
+	if n.Location != "" {
+		entry["room"] = n.Location
+	}
+	if !n.updatedAt.IsZero() {
+		entry["updated_at"] = n.updatedAt.Format(time.RFC3339)
+	}
+	if values {
+		all := make(map[string]interface{}, len(n.values))
+		for id, v := range n.values {
+			entry := map[string]interface{}{"label": v.label(), "value": v.Value, "writeable": v.writeable()}
+			if v.Metadata != nil && v.Metadata.Unit != "" {
+				entry["unit"] = v.Metadata.Unit
+			}
+			all[id] = entry
+		}
+		entry["values"] = all
+	}
+	return entry
+}
+
+// list describes every node
+func (e *Executor) list() []map[string]interface{} {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ids := make([]int, 0, len(e.nodes))
+	for id := range e.nodes {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	list := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		list = append(list, describe(e.nodes[id], false))
+	}
+	return list
+}
+
+func (e *Executor) IsAvailable() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.conn != nil
+}
+
+func (e *Executor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"device.list": schema.MustParse(`{"type": "object", "properties": {}}`),
+		"device.control": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"device": {"type": "string", "minLength": 1},
+				"action": {"type": "string", "minLength": 1}
+			},
+			"required": ["device", "action"]
+		}`),
+		"device.query": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"device": {"type": "string", "minLength": 1},
+				"refresh": {"type": "boolean"}
+			},
+			"required": ["device"]
+		}`),
+	}
+}
@@ -0,0 +1,73 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/anomaly"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/approval"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// SetAnomalyDetector counts intents with d, publishing an "intent.anomaly"
+// event when a type arrives in an unusual burst. Bursts of types d holds
+// wait for approval at its hold level, and are refused if no approvals
+// are set.
+func (g *Gateway) SetAnomalyDetector(d *anomaly.Detector) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.anomalies = d
+}
+
+// AnomalyDetector returns the anomaly detector, or nil if none is set
+func (g *Gateway) AnomalyDetector() *anomaly.Detector {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.anomalies
+}
+
+// checkAnomaly counts the intent, returning the burst it is part of, if
+// any, and how it was approved if the burst was held. Intents already
+// approved, such as the steps of an approved plan, are counted but not
+// held again.
+func (g *Gateway) checkAnomaly(ctx context.Context, i *intent.Intent) (context.Context, *anomaly.Anomaly, *approval.Record, error) {
+	d := g.AnomalyDetector()
+	if d == nil {
+		return ctx, nil, nil, nil
+	}
+	a := d.Observe(i.IntentType, time.Now())
+	if a == nil {
+		return ctx, nil, nil, nil
+	}
+	if a.First {
+		g.logger.WarnContext(ctx, "Unusual burst", "intent_type", a.IntentType, "count", a.Count, "expected", a.Expected, "held", a.Held)
+		g.mu.RLock()
+		bus := g.bus
+		g.mu.RUnlock()
+		if bus != nil {
+			bus.Publish(events.Event{Type: "intent.anomaly", Source: "gateway", Data: map[string]interface{}{
+				"intent_type": a.IntentType,
+				"count":       a.Count,
+				"expected":    a.Expected,
+				"window":      a.Window,
+				"held":        a.Held,
+				"message":     a.Message(),
+			}})
+		}
+	}
+	if !a.Held || ctx.Value(approvedKey{}) != nil {
+		return ctx, a, nil, nil
+	}
+	approvals := g.Approvals()
+	if approvals == nil {
+		return ctx, a, nil, agenterrors.Newf(agenterrors.DeniedByPolicy, "%s is held as part of an unusual burst (%s)", i.IntentType, a.Message())
+	}
+	record, err := approvals.Require(ctx, i, d.HoldLevel())
+	if err != nil {
+		return ctx, a, nil, err
+	}
+	g.logger.InfoContext(ctx, "Intent held in an unusual burst was approved", "approvers", record.Approvers)
+	return context.WithValue(ctx, approvedKey{}, true), a, record, nil
+}
@@ -0,0 +1,44 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/approval"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// approvedKey marks the context of an approved intent, so the plan steps
+// and broadcast copies it runs aren't held for approval again
+type approvedKey struct{}
+
+// SetApprovals holds intents the approvals' risk table classifies until
+// their approvers confirm them. Confirmed intents record how in the audit
+// log.
+func (g *Gateway) SetApprovals(a *approval.Approvals) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.approvals = a
+}
+
+// Approvals returns the approval check, or nil if none is set
+func (g *Gateway) Approvals() *approval.Approvals {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.approvals
+}
+
+// checkApproval waits for the approval the intent's risk level needs,
+// returning the context to run it in and how it was approved, or nil for
+// intents needing none
+func (g *Gateway) checkApproval(ctx context.Context, i *intent.Intent) (context.Context, *approval.Record, error) {
+	a := g.Approvals()
+	if a == nil || ctx.Value(approvedKey{}) != nil {
+		return ctx, nil, nil
+	}
+	record, err := a.Check(ctx, i)
+	if err != nil || record == nil {
+		return ctx, nil, err
+	}
+	g.logger.InfoContext(ctx, "Intent approved", "risk", record.Risk, "approvers", record.Approvers)
+	return context.WithValue(ctx, approvedKey{}, true), record, nil
+}
@@ -0,0 +1,221 @@
+package gateway
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/crypto"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/registry"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// PermissionProvider is implemented by executors with actions that may only
+// run for intents with requires_permission set. The gateway refuses such
+// intents without it and lists the requirement in the capability manifest.
+type PermissionProvider interface {
+	// PermissionRequired returns the intent types that need permission
+	PermissionRequired() []string
+}
+
+// Manifest describes everything the device agent can do, so the agent core
+// can plan intents without hard-coding executors
+type Manifest struct {
+	GeneratedAt string                 `json:"generated_at"`
+	Executors   []ExecutorCapabilities `json:"executors"`
+	Groups      map[string][]string    `json:"groups,omitempty"`
+	Devices     []DeviceCapability     `json:"devices,omitempty"`
+
+	// ReceiptKey is the public key results are signed with, when they are
+	ReceiptKey *crypto.Key `json:"receipt_key,omitempty"`
+
+	// PayloadKey is the key parameters may be sealed to, when encrypted
+	// parameters are enabled
+	PayloadKey *PayloadKeyInfo `json:"payload_key,omitempty"`
+
+	// Mode is the gateway's current mode; actions it suppresses outright
+	// are marked denied
+	Mode string `json:"mode"`
+
+	// Revision counts capability changes; diffs carry the revision they
+	// bring the manifest to
+	Revision uint64 `json:"revision"`
+}
+
+// PayloadKeyInfo is the public half of the agent's payload key
+type PayloadKeyInfo struct {
+	ID        string `json:"id"`
+	PublicKey []byte `json:"public_key"`
+}
+
+// ExecutorCapabilities describes one registered executor
+type ExecutorCapabilities struct {
+	Name      string             `json:"name"`
+	Available bool               `json:"available"`
+	Disabled  bool               `json:"disabled,omitempty"`
+	Degraded  bool               `json:"degraded,omitempty"`
+	Actions   []ActionCapability `json:"actions"`
+}
+
+// ActionCapability describes one intent type an executor handles
+type ActionCapability struct {
+	IntentType         string         `json:"intent_type"`
+	Parameters         *schema.Schema `json:"parameters,omitempty"`
+	RequiresPermission bool           `json:"requires_permission,omitempty"`
+
+	// Denied is set while safety mode or the current mode refuses the
+	// intent type
+	Denied bool `json:"denied,omitempty"`
+}
+
+// DeviceCapability describes a device in the registry set with
+// SetDeviceRegistry
+type DeviceCapability struct {
+	ID     string        `json:"id"`
+	Name   string        `json:"name"`
+	Kind   registry.Kind `json:"kind"`
+	Room   string        `json:"room,omitempty"`
+	Module string        `json:"module,omitempty"`
+}
+
+// Capabilities builds the manifest of registered executors, sorted by name.
+// Availability is checked as the manifest is built.
+func (g *Gateway) Capabilities() *Manifest {
+	g.capMu.Lock()
+	defer g.capMu.Unlock()
+	m := g.capabilities()
+	m.Revision = g.capRevision
+	return m
+}
+
+func (g *Gateway) capabilities() *Manifest {
+	executors := g.GetExecutors()
+	sort.Slice(executors, func(a, b int) bool { return executors[a].Name() < executors[b].Name() })
+
+	m := &Manifest{
+		GeneratedAt: time.Now().Format(time.RFC3339),
+		Executors:   make([]ExecutorCapabilities, 0, len(executors)),
+		Groups:      g.ModuleGroups(),
+		ReceiptKey:  g.ReceiptKey(),
+		Mode:        g.Mode(),
+	}
+	mode := g.currentMode()
+	g.mu.RLock()
+	devices, safety, safetyOn := g.devices, g.safety, g.safetyOn
+	if g.payloadKey != nil {
+		m.PayloadKey = &PayloadKeyInfo{ID: g.payloadKey.KeyID(), PublicKey: g.payloadKey.PublicKey()}
+	}
+	g.mu.RUnlock()
+	if devices != nil {
+		m.Devices = []DeviceCapability{}
+		for _, d := range devices.List() {
+			m.Devices = append(m.Devices, DeviceCapability{ID: d.ID, Name: d.Name, Kind: d.Kind, Room: d.Room, Module: d.Module})
+		}
+	}
+	for _, e := range executors {
+		guarded := permissionRequired(e)
+		caps := ExecutorCapabilities{
+			Name:      e.Name(),
+			Available: e.IsAvailable(),
+			Disabled:  g.IsExecutorDisabled(e.Name()),
+			Degraded:  g.IsExecutorDegraded(e.Name()),
+			Actions:   []ActionCapability{},
+		}
+		for _, action := range e.SupportedActions() {
+			s, _ := g.schemas.Lookup(action)
+			caps.Actions = append(caps.Actions, ActionCapability{
+				IntentType:         action,
+				Parameters:         s,
+				RequiresPermission: guarded[action],
+				Denied:             (safetyOn && safety.denies(action)) || (mode != nil && mode.suppressesAll(action)),
+			})
+		}
+		m.Executors = append(m.Executors, caps)
+	}
+	return m
+}
+
+// permissionRequired returns the set of e's actions that need permission
+func permissionRequired(e Executor) map[string]bool {
+	provider, ok := e.(PermissionProvider)
+	if !ok {
+		return nil
+	}
+	guarded := make(map[string]bool)
+	for _, action := range provider.PermissionRequired() {
+		guarded[action] = true
+	}
+	return guarded
+}
+
+// CapabilitiesResult wraps the manifest in an ExecutionResult, the form
+// transports push to the agent core when it connects
+func (g *Gateway) CapabilitiesResult() *ExecutionResult {
+	m := g.Capabilities()
+	result := &ExecutionResult{
+		Success:   true,
+		Module:    "capabilities",
+		Action:    "capabilities.list",
+		Result:    map[string]interface{}{"executors": m.Executors, "groups": m.Groups, "devices": m.Devices, "mode": m.Mode, "revision": m.Revision},
+		Timestamp: m.GeneratedAt,
+	}
+	if m.ReceiptKey != nil {
+		result.Result["receipt_key"] = m.ReceiptKey
+	}
+	if m.PayloadKey != nil {
+		result.Result["payload_key"] = m.PayloadKey
+	}
+	return result
+}
+
+// CapabilitiesExecutor answers capabilities.list intents with the manifest,
+// so transports without a side channel can query it
+type CapabilitiesExecutor struct {
+	gw *Gateway
+}
+
+// NewCapabilitiesExecutor creates a capabilities executor for the gateway
+func NewCapabilitiesExecutor(gw *Gateway) *CapabilitiesExecutor {
+	return &CapabilitiesExecutor{gw: gw}
+}
+
+func (c *CapabilitiesExecutor) Name() string {
+	return "capabilities"
+}
+
+func (c *CapabilitiesExecutor) SupportedActions() []string {
+	return []string{"capabilities.list"}
+}
+
+func (c *CapabilitiesExecutor) Needs() sandbox.Needs {
+	return sandbox.Needs{}
+}
+
+func (c *CapabilitiesExecutor) Execute(ctx context.Context, i *intent.Intent) (*ExecutionResult, error) {
+	if i.IntentType != "capabilities.list" {
+		result := &ExecutionResult{
+			IntentID:  i.ID,
+			Module:    "capabilities",
+			Action:    i.IntentType,
+			Timestamp: time.Now().Format(time.RFC3339),
+		}
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+		return result, nil
+	}
+	result := c.gw.CapabilitiesResult()
+	result.IntentID = i.ID
+	return result, nil
+}
+
+func (c *CapabilitiesExecutor) IsAvailable() bool {
+	return true
+}
+
+func (c *CapabilitiesExecutor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"capabilities.list": schema.MustParse(`{"type": "object", "properties": {}}`),
+	}
+}
@@ -0,0 +1,143 @@
+package gateway
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/registry"
+)
+
+// CapabilityDiff is what changed in the manifest, so the agent core can
+// update its tool schemas without asking for the whole manifest again
+type CapabilityDiff struct {
+	// Revision is the manifest revision after the change; a gap means a
+	// diff was missed and the manifest should be fetched again
+	Revision uint64 `json:"revision"`
+
+	// Reason names the change that triggered the diff (e.g.
+	// "executor.registered", "executor.disabled", "safety.enabled",
+	// "devices")
+	Reason string `json:"reason"`
+
+	Added   []ExecutorCapabilities `json:"added,omitempty"`
+	Changed []ExecutorCapabilities `json:"changed,omitempty"`
+	Removed []string               `json:"removed,omitempty"`
+
+	// DevicesAdded holds new devices and devices whose name, kind, room,
+	// or module changed
+	DevicesAdded   []DeviceCapability `json:"devices_added,omitempty"`
+	DevicesRemoved []string           `json:"devices_removed,omitempty"`
+
+	// Groups holds every module group when any of them changed
+	Groups map[string][]string `json:"groups,omitzero"`
+}
+
+// SetDeviceRegistry lists reg's devices in the manifest and sends a diff
+// whenever devices appear or disappear
+func (g *Gateway) SetDeviceRegistry(reg *registry.Registry) {
+	g.mu.Lock()
+	g.devices = reg
+	g.mu.Unlock()
+	reg.Watch(func() { g.capabilitiesChanged("devices") })
+	g.capabilitiesChanged("devices")
+}
+
+// WatchCapabilities calls fn with every capability diff, in revision order,
+// and returns a function that stops the calls. fn runs while the diff is
+// being made, so it must not block or call back into the gateway's
+// capability methods.
+func (g *Gateway) WatchCapabilities(fn func(*CapabilityDiff)) func() {
+	g.capMu.Lock()
+	defer g.capMu.Unlock()
+	if g.capWatchers == nil {
+		g.capWatchers = make(map[int]func(*CapabilityDiff))
+	}
+	id := g.capNextID
+	g.capNextID++
+	g.capWatchers[id] = fn
+	return func() {
+		g.capMu.Lock()
+		defer g.capMu.Unlock()
+		delete(g.capWatchers, id)
+	}
+}
+
+// capabilitiesChanged rebuilds the manifest after a change and, when it
+// differs from the last one, sends the diff to watchers and publishes a
+// "capabilities.changed" event
+func (g *Gateway) capabilitiesChanged(reason string) {
+	g.capMu.Lock()
+	defer g.capMu.Unlock()
+
+	m := g.capabilities()
+	diff := diffManifests(g.capLast, m)
+	g.capLast = m
+	if diff == nil {
+		return
+	}
+	g.capRevision++
+	diff.Revision = g.capRevision
+	diff.Reason = reason
+
+	for _, fn := range g.capWatchers {
+		fn(diff)
+	}
+	g.mu.RLock()
+	bus := g.bus
+	g.mu.RUnlock()
+	if bus != nil {
+		bus.Publish(events.Event{Type: "capabilities.changed", Source: "gateway", Data: map[string]interface{}{
+			"revision": diff.Revision,
+			"reason":   reason,
+			"diff":     diff,
+		}})
+	}
+}
+
+// diffManifests compares two manifests, returning nil when nothing a
+// client would act on changed
+func diffManifests(old, m *Manifest) *CapabilityDiff {
+	if old == nil {
+		old = &Manifest{}
+	}
+	diff := &CapabilityDiff{}
+
+	before := make(map[string]ExecutorCapabilities, len(old.Executors))
+	for _, e := range old.Executors {
+		before[e.Name] = e
+	}
+	for _, e := range m.Executors {
+		prev, ok := before[e.Name]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, e)
+		case !reflect.DeepEqual(prev, e):
+			diff.Changed = append(diff.Changed, e)
+		}
+		delete(before, e.Name)
+	}
+	diff.Removed = slices.Sorted(maps.Keys(before))
+
+	devices := make(map[string]DeviceCapability, len(old.Devices))
+	for _, d := range old.Devices {
+		devices[d.ID] = d
+	}
+	for _, d := range m.Devices {
+		if prev, ok := devices[d.ID]; !ok || prev != d {
+			diff.DevicesAdded = append(diff.DevicesAdded, d)
+		}
+		delete(devices, d.ID)
+	}
+	diff.DevicesRemoved = slices.Sorted(maps.Keys(devices))
+
+	if !reflect.DeepEqual(old.Groups, m.Groups) && (len(old.Groups) > 0 || len(m.Groups) > 0) {
+		diff.Groups = m.Groups
+	}
+
+	if len(diff.Added)+len(diff.Changed)+len(diff.Removed)+len(diff.DevicesAdded)+len(diff.DevicesRemoved) == 0 && diff.Groups == nil {
+		return nil
+	}
+	return diff
+}
@@ -0,0 +1,68 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/challenge"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// challengeKey carries an intent's challenge response, taken out of its
+// parameters so the PIN never reaches executors, events, or the audit log
+type challengeKey struct{}
+
+// challengedKey marks the context of an intent whose PIN was verified, so
+// the plan steps and broadcast copies it runs aren't challenged again
+type challengedKey struct{}
+
+// SetChallenges makes the intents v requires a PIN for carry a right one
+// in challenge_response. When wrong PINs lock challenges a
+// "challenge.locked" event is published.
+func (g *Gateway) SetChallenges(v *challenge.Verifier) {
+	v.OnLockout = func(until time.Time) {
+		g.logger.Warn("Too many wrong PINs; challenges locked", "until", until.Format(time.RFC3339))
+		g.mu.RLock()
+		bus := g.bus
+		g.mu.RUnlock()
+		if bus != nil {
+			bus.Publish(events.Event{Type: "challenge.locked", Source: "gateway", Data: map[string]interface{}{
+				"until": until.Format(time.RFC3339),
+			}})
+		}
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.challenges = v
+}
+
+// withChallengeResponse moves the intent's challenge_response parameter,
+// if it has one, into the context
+func withChallengeResponse(ctx context.Context, i *intent.Intent) context.Context {
+	response, ok := challenge.Response(i)
+	if !ok {
+		return ctx
+	}
+	delete(i.Parameters, challenge.Param)
+	return context.WithValue(ctx, challengeKey{}, response)
+}
+
+// checkChallenge verifies the PIN of intents that need one, returning the
+// context to run them in and the name of the PIN that matched, or "" for
+// intents needing none
+func (g *Gateway) checkChallenge(ctx context.Context, i *intent.Intent) (context.Context, string, error) {
+	g.mu.RLock()
+	v := g.challenges
+	g.mu.RUnlock()
+	if v == nil || ctx.Value(challengedKey{}) != nil || !v.Required(i) {
+		return ctx, "", nil
+	}
+	response, _ := ctx.Value(challengeKey{}).(string)
+	name, err := v.Verify(i.IntentType, response)
+	if err != nil {
+		g.logger.WarnContext(ctx, "Rejected intent", "error", err)
+		return ctx, "", err
+	}
+	return context.WithValue(ctx, challengedKey{}, name), name, nil
+}
@@ -0,0 +1,109 @@
+package gateway
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// Exclusion is a group an intent holds while it executes. Groups name
+// whatever can't be driven two ways at once: "cover:garage" for a motor,
+// "device:hue:3" for a device, "system:backup" for a command.
+type Exclusion struct {
+	Group string `json:"group"`
+
+	// Shared holders run alongside each other but never alongside an
+	// exclusive holder, so everyday commands to a device can share it
+	// while a firmware update holds it alone
+	Shared bool `json:"shared,omitempty"`
+}
+
+// ExclusionProvider is implemented by executors whose actions conflict
+// physically. The dispatcher holds an intent's exclusions while it
+// executes and refuses, with a Conflict error, intents needing a group
+// that is already held, so conflicting commands are never issued.
+type ExclusionProvider interface {
+	// Exclusions returns the groups the intent needs; none for intents
+	// that conflict with nothing
+	Exclusions(i *intent.Intent) []Exclusion
+}
+
+type exclusionHolder struct {
+	intentID   string
+	intentType string
+	shared     bool
+}
+
+// Exclusions tracks which groups are held. The gateway's is available
+// from Gateway.Exclusions for executors whose work outlives Execute, such
+// as background jobs.
+type Exclusions struct {
+	mu     sync.Mutex
+	held   map[string]map[int]exclusionHolder // by group, then claim
+	nextID int
+}
+
+// NewExclusions creates an empty set of groups
+func NewExclusions() *Exclusions {
+	return &Exclusions{held: make(map[string]map[int]exclusionHolder)}
+}
+
+// Acquire holds every group for the intent, or none of them if any is
+// taken. The returned function releases them.
+func (x *Exclusions) Acquire(i *intent.Intent, exclusions []Exclusion) (func(), error) {
+	if len(exclusions) == 0 {
+		return func() {}, nil
+	}
+
+	// A group listed twice is held exclusively if either asks for that
+	wanted := make(map[string]bool, len(exclusions))
+	for _, e := range exclusions {
+		shared, seen := wanted[e.Group]
+		wanted[e.Group] = e.Shared && (!seen || shared)
+	}
+	groups := make([]string, 0, len(wanted))
+	for group := range wanted {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, group := range groups {
+		for _, h := range x.held[group] {
+			if !wanted[group] || !h.shared {
+				return nil, agenterrors.Newf(agenterrors.Conflict, "%s is busy with intent %s (%s)", group, h.intentID, h.intentType)
+			}
+		}
+	}
+
+	id := x.nextID
+	x.nextID++
+	for _, group := range groups {
+		if x.held[group] == nil {
+			x.held[group] = make(map[int]exclusionHolder)
+		}
+		x.held[group][id] = exclusionHolder{intentID: i.ID, intentType: i.IntentType, shared: wanted[group]}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			x.mu.Lock()
+			defer x.mu.Unlock()
+			for _, group := range groups {
+				delete(x.held[group], id)
+				if len(x.held[group]) == 0 {
+					delete(x.held, group)
+				}
+			}
+		})
+	}, nil
+}
+
+// Exclusions returns the gateway's exclusion groups
+func (g *Gateway) Exclusions() *Exclusions {
+	return g.exclusions
+}
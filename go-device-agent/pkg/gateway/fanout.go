@@ -0,0 +1,140 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// Broadcast is the target_module that addresses every executor supporting
+// the intent type
+const Broadcast = "*"
+
+// SetModuleGroup names a set of modules (e.g. "all-lights") that intents
+// can target together. An empty modules list removes the group.
+func (g *Gateway) SetModuleGroup(name string, modules []string) {
+	defer g.capabilitiesChanged("groups")
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.groups == nil {
+		g.groups = make(map[string][]string)
+	}
+	if len(modules) == 0 {
+		delete(g.groups, name)
+		return
+	}
+	g.groups[name] = append([]string(nil), modules...)
+}
+
+// ModuleGroups returns a copy of the groups set with SetModuleGroup
+func (g *Gateway) ModuleGroups() map[string][]string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	groups := make(map[string][]string, len(g.groups))
+	for name, modules := range g.groups {
+		groups[name] = append([]string(nil), modules...)
+	}
+	return groups
+}
+
+// fanOutTargets resolves a broadcast or group target to the modules that
+// support the intent type. ok is false for ordinary module targets.
+func (g *Gateway) fanOutTargets(target, intentType string) (modules []string, ok bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var candidates []string
+	if target == Broadcast {
+		for name := range g.executors {
+			candidates = append(candidates, name)
+		}
+		sort.Strings(candidates)
+	} else if _, isModule := g.executors[target]; isModule {
+		return nil, false
+	} else if candidates, ok = g.groups[target]; !ok {
+		return nil, false
+	}
+
+	for _, name := range candidates {
+		e, found := g.executors[name]
+		if found && contains(e.SupportedActions(), intentType) {
+			modules = append(modules, name)
+		}
+	}
+	return modules, true
+}
+
+// fanOut runs a copy of the intent on each module concurrently, each routed
+// like a standalone intent, and aggregates the outcomes in module order
+func (g *Gateway) fanOut(ctx context.Context, i *intent.Intent, modules []string) *ExecutionResult {
+	result := &ExecutionResult{
+		IntentID:  i.ID,
+		Module:    *i.TargetModule,
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+	if len(modules) == 0 {
+		result.Fail(agenterrors.Newf(agenterrors.NotFound, "no executor in %s supports %s", *i.TargetModule, i.IntentType))
+		return result
+	}
+
+	// The intent was counted against its quotas once for all its copies
+	ctx = context.WithValue(ctx, quotaTakenKey{}, true)
+	results := make([]*ExecutionResult, len(modules))
+	var wg sync.WaitGroup
+	for n, module := range modules {
+		sub := *i
+		sub.ID = i.ID + "/" + module
+		sub.TargetModule = &module
+		if sub.CorrelationID == "" {
+			sub.CorrelationID = i.ID
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[n] = g.route(ctx, &sub)
+		}()
+	}
+	wg.Wait()
+
+	failed := 0
+	code := agenterrors.Code("")
+	for _, r := range results {
+		if r.Success {
+			continue
+		}
+		failed++
+		if code == "" {
+			code = r.ErrorCode
+		} else if code != r.ErrorCode {
+			code = agenterrors.PartialFailure
+		}
+	}
+	result.Success = failed == 0
+	if failed > 0 {
+		// When every executor failed the same way, say how
+		if failed < len(modules) || code == "" {
+			code = agenterrors.PartialFailure
+		}
+		result.Fail(agenterrors.Newf(code, "%d of %d executors failed", failed, len(modules)))
+	}
+	result.Result = map[string]interface{}{
+		"results":   results,
+		"succeeded": len(modules) - failed,
+		"failed":    failed,
+	}
+	return result
+}
+
+// describeTarget names a fan-out target for logs
+func describeTarget(target string, modules []string) string {
+	if target == Broadcast {
+		return fmt.Sprintf("all executors %v", modules)
+	}
+	return fmt.Sprintf("group %s %v", target, modules)
+}
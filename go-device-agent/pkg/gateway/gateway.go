@@ -5,18 +5,91 @@ package gateway
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"maps"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/access"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/anomaly"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/approval"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/audit"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/challenge"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/crypto"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/history"
 	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/logging"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/quota"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/registry"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/tracing"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/usage"
 )
 
 // Gateway is the secure boundary between thinking and acting
 type Gateway struct {
-	executors map[string]Executor
-	mu        sync.RWMutex
-	logger    *log.Logger
+	executors      map[string]Executor
+	disabled       map[string]bool
+	groups         map[string][]string
+	schemas        *schema.Registry
+	maxAge         time.Duration
+	verifier       *crypto.Verifier
+	payloadKey     *crypto.PayloadKey
+	strict         bool
+	signer         *crypto.Signer
+	parse          parseMode
+	audit          *audit.Log
+	history        *history.Store
+	guests         *access.Grants
+	guestLog       *audit.Log
+	roles          *access.Policy
+	approvals      *approval.Approvals
+	anomalies      *anomaly.Detector
+	challenges     *challenge.Verifier
+	quotas         *quota.Tracker
+	tracer         *tracing.Tracer
+	sandbox        *sandbox.Policy
+	needs          map[string]sandbox.Needs
+	speakers       SpeakerIdentifier
+	speakerPolicy  SpeakerPolicy
+	presence       PresenceSource
+	presencePolicy PresencePolicy
+	safety         SafetyPolicy
+	safetyOn       bool
+	modes          map[string]Mode
+	mode           string
+	transformers   []ParamTransformer
+	redactions     []redaction
+	maintenance    map[string]Maintenance
+	devices        *registry.Registry
+	bus            *events.Bus
+	exclusions     *Exclusions
+	usage          *usage.Stats
+	mu             sync.RWMutex
+	logger         *slog.Logger
+
+	// Guards the last manifest diffs are computed against
+	capMu       sync.Mutex
+	capLast     *Manifest
+	capRevision uint64
+	capWatchers map[int]func(*CapabilityDiff)
+	capNextID   int
+
+	// Guards the intents in flight
+	flightMu   sync.Mutex
+	flights    map[uint64]InFlight
+	flightNext uint64
+
+	// Guards the executors' latency and outcome stats
+	statsMu sync.Mutex
+	stats   map[string]*executorStats
+	latency LatencyPolicy
 }
 
 // Executor interface for action executors
@@ -34,6 +107,23 @@ type Executor interface {
 	IsAvailable() bool
 }
 
+// SchemaProvider is implemented by executors that declare a JSON Schema for
+// the parameters of their actions. Schemas are registered automatically
+// when the executor is registered.
+type SchemaProvider interface {
+	// ParameterSchemas returns the parameter schema for each intent type
+	ParameterSchemas() map[string]*schema.Schema
+}
+
+// NeedsProvider is implemented by executors that declare what they need
+// from the system outside the agent. A sandbox policy refuses to register
+// executors needing more than it allows, and the agent process can be
+// confined to what its executors declared.
+type NeedsProvider interface {
+	// Needs returns the network, paths, and programs the executor uses
+	Needs() sandbox.Needs
+}
+
 // ExecutionResult represents the result of executing an intent
 type ExecutionResult struct {
 	Success   bool                   `json:"success"`
@@ -43,95 +133,864 @@ type ExecutionResult struct {
 	Result    map[string]interface{} `json:"result,omitempty"`
 	Error     string                 `json:"error,omitempty"`
 	Timestamp string                 `json:"timestamp"`
+
+	// ErrorCode classifies a failure; see agenterrors for the codes
+	ErrorCode agenterrors.Code `json:"error_code,omitempty"`
+
+	// Adjusted reports parameters the safety policy clamped, keyed by name,
+	// with the requested and applied values
+	Adjusted map[string]interface{} `json:"adjusted,omitempty"`
+
+	// Copied from the intent by the gateway; executors needn't set them
+	CorrelationID string          `json:"correlation_id,omitempty"`
+	SessionID     string          `json:"session_id,omitempty"`
+	Provenance    []intent.Origin `json:"provenance,omitempty"`
+
+	// TraceID is the trace of the intent's spans, when tracing is on
+	TraceID string `json:"trace_id,omitempty"`
+
+	// Usage is what handling the intent cost, measured by the gateway
+	Usage *usage.Usage `json:"usage,omitempty"`
+
+	// Audit is what the executor did, for executors whose actions need a
+	// fuller trail than the intent type and outcome. It is recorded in the
+	// audit log but never returned.
+	Audit map[string]interface{} `json:"-"`
+
+	// Receipt is added last, by the gateway, when results are signed
+	Receipt *Receipt `json:"receipt,omitempty"`
+}
+
+// parseMode selects how ProcessEncodedIntent decodes intents
+type parseMode struct {
+	strict  bool
+	maxSkew time.Duration
+}
+
+// Fail marks the result failed with err's message and code
+func (r *ExecutionResult) Fail(err error) {
+	r.Success = false
+	r.Error = err.Error()
+	r.ErrorCode = agenterrors.CodeOf(err)
 }
 
 // NewGateway creates a new intent gateway
-func NewGateway(logger *log.Logger) *Gateway {
+func NewGateway(logger *slog.Logger) *Gateway {
 	if logger == nil {
-		logger = log.Default()
+		logger = slog.Default()
 	}
-	return &Gateway{
-		executors: make(map[string]Executor),
-		logger:    logger,
+	g := &Gateway{
+		executors:  make(map[string]Executor),
+		disabled:   make(map[string]bool),
+		needs:      make(map[string]sandbox.Needs),
+		modes:      make(map[string]Mode),
+		mode:       NormalMode,
+		schemas:    schema.NewRegistry(),
+		exclusions: NewExclusions(),
+		usage:      usage.NewStats(),
+		flights:    make(map[uint64]InFlight),
+		stats:      make(map[string]*executorStats),
+		latency:    LatencyPolicy{Window: DefaultLatencyWindow, MinSamples: DefaultLatencyMinSamples},
+		logger:     logger,
 	}
+	for _, m := range DefaultModes() {
+		g.modes[m.Name] = m
+	}
+	return g
 }
 
-// RegisterExecutor registers an action executor
+// RegisterExecutor registers an action executor, unless the sandbox
+// policy refuses what it declares it needs
 func (g *Gateway) RegisterExecutor(executor Executor) {
+	name := executor.Name()
+	needs, err := g.checkSandbox(executor)
+	if err != nil {
+		g.logger.Warn("Refused executor", "executor", name, "error", err)
+		return
+	}
+
+	defer g.capabilitiesChanged("executor.registered")
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
-	name := executor.Name()
 	g.executors[name] = executor
-	g.logger.Printf("Registered executor: %s (actions: %v)", name, executor.SupportedActions())
+	g.needs[name] = needs
+	g.logger.Info("Registered executor", "executor", name, "actions", executor.SupportedActions())
+
+	if provider, ok := executor.(SchemaProvider); ok {
+		for intentType, s := range provider.ParameterSchemas() {
+			if err := g.schemas.Register(intentType, s); err != nil {
+				g.logger.Warn("Ignoring invalid schema", "intent_type", intentType, "error", err)
+			}
+		}
+	}
+}
+
+// Schemas returns the parameter schema registry, so schemas can also be
+// declared outside executors (e.g. from configuration)
+func (g *Gateway) Schemas() *schema.Registry {
+	return g.schemas
+}
+
+// SetMaxIntentAge refuses intents created more than maxAge ago even when
+// they carry no expiry of their own. Zero disables the check.
+func (g *Gateway) SetMaxIntentAge(maxAge time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.maxAge = maxAge
+}
+
+// SetVerifier checks intent signatures against the verifier's keys.
+// Badly signed intents are always rejected; with strict set, unsigned ones
+// are too. In strict mode without a verifier every intent is rejected.
+func (g *Gateway) SetVerifier(v *crypto.Verifier, strict bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.verifier = v
+	g.strict = strict
+}
+
+// SetPayloadKey opens intents' encrypted_parameters with k. Without a key,
+// intents carrying them are refused.
+func (g *Gateway) SetPayloadKey(k *crypto.PayloadKey) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.payloadKey = k
+}
+
+// SetStrictParsing decodes intents with intent.DecodeStrict: unknown fields,
+// IDs that aren't UUIDs or ULIDs, missing target modules, and created_at
+// more than maxSkew in the future are rejected
+func (g *Gateway) SetStrictParsing(strict bool, maxSkew time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.parse = parseMode{strict: strict, maxSkew: maxSkew}
+}
+
+// SetAuditLog records every handled intent, including plan steps and
+// rejected intents, in l
+func (g *Gateway) SetAuditLog(l *audit.Log) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.audit = l
+}
+
+// AuditLog returns the audit log set with SetAuditLog, or nil
+func (g *Gateway) AuditLog() *audit.Log {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.audit
+}
+
+// SetTracer records a trace of spans for every intent: parsing,
+// validation, policy checks, and execution, with executors' own spans
+// beneath. Results carry the trace ID. Intents arriving with a remote
+// parent in their context (see tracing.Extract) continue its trace.
+func (g *Gateway) SetTracer(t *tracing.Tracer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.tracer = t
+}
+
+// UsageStats returns the resource usage of the intents handled since the
+// gateway was created, per executor and per intent type
+func (g *Gateway) UsageStats() usage.Snapshot {
+	return g.usage.Snapshot()
+}
+
+// SetGuestAccess limits intents carrying a guest token or a granted
+// speaker ID to their grant's scope, and records them in log as well as the
+// main audit log
+func (g *Gateway) SetGuestAccess(grants *access.Grants, log *audit.Log) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.guests = grants
+	g.guestLog = log
+}
+
+// SetRolePolicy limits intents that transports attach an identity to (see
+// access.WithIdentity) to what the identity's roles allow. Intents the
+// agent raises itself, such as automations', carry no identity and are not
+// limited.
+func (g *Gateway) SetRolePolicy(p *access.Policy) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.roles = p
+}
+
+// SetEventBus publishes an intent.completed event for every handled intent
+func (g *Gateway) SetEventBus(bus *events.Bus) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.bus = bus
 }
 
 // UnregisterExecutor removes an executor
 func (g *Gateway) UnregisterExecutor(name string) {
+	defer g.capabilitiesChanged("executor.unregistered")
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
 	delete(g.executors, name)
-	g.logger.Printf("Unregistered executor: %s", name)
+	delete(g.disabled, name)
+	delete(g.needs, name)
+	g.logger.Info("Unregistered executor", "executor", name)
+}
+
+// DisableExecutor quarantines a registered executor: its intents, including
+// plan steps, fail with EXECUTOR_DISABLED until EnableExecutor is called
+func (g *Gateway) DisableExecutor(name string) error {
+	return g.setDisabled(name, true)
+}
+
+// EnableExecutor reverses DisableExecutor
+func (g *Gateway) EnableExecutor(name string) error {
+	return g.setDisabled(name, false)
+}
+
+func (g *Gateway) setDisabled(name string, disabled bool) error {
+	g.mu.Lock()
+	if _, ok := g.executors[name]; !ok {
+		g.mu.Unlock()
+		return agenterrors.Newf(agenterrors.NotFound, "no executor named %s", name)
+	}
+	changed := g.disabled[name] != disabled
+	if disabled {
+		g.disabled[name] = true
+	} else {
+		delete(g.disabled, name)
+	}
+	bus := g.bus
+	g.mu.Unlock()
+
+	if !changed {
+		return nil
+	}
+	state := "enabled"
+	if disabled {
+		state = "disabled"
+	}
+	g.logger.Info("Executor "+state, "executor", name)
+	if bus != nil {
+		bus.Publish(events.Event{Type: "executor." + state, Source: "gateway", Data: map[string]interface{}{"executor": name}})
+	}
+	g.capabilitiesChanged("executor." + state)
+	return nil
+}
+
+// IsExecutorDisabled reports whether name was disabled with DisableExecutor
+func (g *Gateway) IsExecutorDisabled(name string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.disabled[name]
 }
 
-// ProcessIntent processes an intent through the gateway
+// ProcessIntent processes a JSON intent through the gateway
 func (g *Gateway) ProcessIntent(ctx context.Context, intentData []byte) (*ExecutionResult, error) {
+	return g.ProcessEncodedIntent(ctx, intentData, intent.JSON)
+}
+
+// ProcessEncodedIntent processes an intent encoded with the given codec
+func (g *Gateway) ProcessEncodedIntent(ctx context.Context, intentData []byte, codec intent.Codec) (*ExecutionResult, error) {
+	g.mu.RLock()
+	parse, tracer := g.parse, g.tracer
+	g.mu.RUnlock()
+	ctx, span := tracer.Start(ctx, "process intent")
+	defer span.End()
+	span.SetKind(tracing.KindServer)
+
 	// Parse intent
-	i, err := intent.ParseIntent(intentData)
+	_, parseSpan := tracing.Start(ctx, "parse")
+	var i *intent.Intent
+	var err error
+	if parse.strict {
+		i, err = intent.DecodeStrict(intentData, codec, parse.maxSkew)
+	} else {
+		i, err = intent.Decode(intentData, codec)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse intent: %w", err)
+		err = agenterrors.Newf(agenterrors.InvalidIntent, "failed to parse intent: %w", err)
+		parseSpan.Fail(err.Error())
+		span.Fail(err.Error())
+		parseSpan.End()
+		return nil, err
 	}
+	parseSpan.End()
 
-	// Validate intent
+	// Validate intent; the span also covers the signature, encrypted
+	// parameters, and expiry
+	_, validateSpan := tracing.Start(ctx, "validate")
+	defer validateSpan.End()
+	span.SetAttribute("intent.id", i.ID)
+	span.SetAttribute("intent.type", i.IntentType)
 	if err := i.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid intent: %w", err)
+		err = agenterrors.Newf(agenterrors.InvalidIntent, "invalid intent: %w", err)
+		validateSpan.Fail(err.Error())
+		span.Fail(err.Error())
+		return nil, err
 	}
 
-	g.logger.Printf("Processing intent: %s (type: %s, confidence: %.2f)",
-		i.ID, i.IntentType, i.Confidence)
+	ctx = withIntent(ctx, i)
+	g.logger.InfoContext(ctx, "Processing intent", "intent_type", i.IntentType, "confidence", fmt.Sprintf("%.2f", i.Confidence))
 
-	// Find executor
-	g.mu.RLock()
-	executor, ok := g.executors[*i.TargetModule]
-	g.mu.RUnlock()
+	// The PIN is signed with the other parameters, then kept out of
+	// everything after the signature check
+	sigErr := g.checkSignature(ctx, i)
+	ctx = withChallengeResponse(ctx, i)
+	if err := sigErr; err != nil {
+		g.logger.WarnContext(ctx, "Rejected intent", "error", err)
+		return g.finish(ctx, i, &ExecutionResult{
+			Success:   false,
+			IntentID:  i.ID,
+			Action:    i.IntentType,
+			Error:     fmt.Sprintf("signature rejected: %v", err),
+			ErrorCode: agenterrors.Unauthorized,
+		}), nil
+	}
 
-	if !ok {
+	if err := g.openParameters(i); err != nil {
+		g.logger.WarnContext(ctx, "Rejected intent", "error", err)
+		return g.finish(ctx, i, &ExecutionResult{
+			Success:   false,
+			IntentID:  i.ID,
+			Action:    i.IntentType,
+			Error:     err.Error(),
+			ErrorCode: agenterrors.CodeOf(err),
+		}), nil
+	}
+	ctx = withChallengeResponse(ctx, i)
+
+	g.identifySpeaker(ctx, i)
+
+	// Refuse stale intents, e.g. ones queued or replayed after an outage
+	deadline, hasDeadline := g.deadline(i)
+	if hasDeadline && !time.Now().Before(deadline) {
+		g.logger.WarnContext(ctx, "Rejected expired intent", "expired_at", deadline.Format(time.RFC3339))
+		module := ""
+		if i.TargetModule != nil {
+			module = *i.TargetModule
+		}
+		return g.finish(ctx, i, &ExecutionResult{
+			Success:   false,
+			IntentID:  i.ID,
+			Module:    module,
+			Action:    i.IntentType,
+			Result:    map[string]interface{}{"expired_at": deadline.Format(time.RFC3339)},
+			Error:     fmt.Sprintf("intent expired at %s", deadline.Format(time.RFC3339)),
+			ErrorCode: agenterrors.Expired,
+		}), nil
+	}
+
+	// Executors that wait on devices stop once the intent expires
+	if hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+	validateSpan.End()
+
+	return g.route(ctx, i), nil
+}
+
+// route finds the intent's executor, validates its parameters, and runs it,
+// metering what it costs. Plan steps enter here too, having been vetted as
+// part of their plan.
+func (g *Gateway) route(ctx context.Context, i *intent.Intent) *ExecutionResult {
+	ctx = withIntent(ctx, i)
+	defer g.track(ctx, i)()
+	ctx, span := tracing.Start(ctx, i.IntentType)
+	defer span.End()
+	span.SetAttribute("intent.id", i.ID)
+	if i.TargetModule != nil {
+		span.SetAttribute("intent.target_module", *i.TargetModule)
+	}
+	ctx, meter := usage.Start(ctx)
+	result := g.dispatch(ctx, i)
+	if result != nil {
+		u := meter.Usage()
+		result.Usage = &u
+	}
+	return g.finish(ctx, i, result)
+}
+
+// withIntent tags the records logged with ctx with the intent's ID and
+// correlation ID
+func withIntent(ctx context.Context, i *intent.Intent) context.Context {
+	attrs := []slog.Attr{slog.String("intent_id", i.ID)}
+	if i.CorrelationID != "" {
+		attrs = append(attrs, slog.String("correlation_id", i.CorrelationID))
+	}
+	return logging.With(ctx, attrs...)
+}
+
+func (g *Gateway) dispatch(ctx context.Context, i *intent.Intent) *ExecutionResult {
+	// The policy span ends where execution starts, or when a check
+	// refuses the intent
+	_, policySpan := tracing.Start(ctx, "policy")
+	defer policySpan.End()
+
+	if i.TargetModule == nil {
+		return &ExecutionResult{
+			Success:   false,
+			IntentID:  i.ID,
+			Action:    i.IntentType,
+			Error:     "intent has no target_module",
+			ErrorCode: agenterrors.InvalidIntent,
+		}
+	}
+
+	if err := g.checkRole(ctx, i); err != nil {
+		return &ExecutionResult{
+			Success:   false,
+			IntentID:  i.ID,
+			Module:    *i.TargetModule,
+			Action:    i.IntentType,
+			Error:     err.Error(),
+			ErrorCode: agenterrors.CodeOf(err),
+		}
+	}
+
+	if err := g.transform(ctx, i); err != nil {
+		return &ExecutionResult{
+			Success:   false,
+			IntentID:  i.ID,
+			Module:    *i.TargetModule,
+			Action:    i.IntentType,
+			Error:     err.Error(),
+			ErrorCode: agenterrors.CodeOf(err),
+		}
+	}
+
+	if grant, err := g.guestGrant(i); err != nil {
+		return &ExecutionResult{
+			Success:   false,
+			IntentID:  i.ID,
+			Module:    *i.TargetModule,
+			Action:    i.IntentType,
+			Error:     err.Error(),
+			ErrorCode: agenterrors.CodeOf(err),
+		}
+	} else if grant != nil && !grant.Allows(i) {
+		return &ExecutionResult{
+			Success:   false,
+			IntentID:  i.ID,
+			Module:    *i.TargetModule,
+			Action:    i.IntentType,
+			Error:     fmt.Sprintf("guest %s is not allowed %s", grant.Name, i.IntentType),
+			ErrorCode: agenterrors.DeniedByPolicy,
+		}
+	}
+
+	if err := g.checkSpeaker(i); err != nil {
+		return &ExecutionResult{
+			Success:   false,
+			IntentID:  i.ID,
+			Module:    *i.TargetModule,
+			Action:    i.IntentType,
+			Error:     err.Error(),
+			ErrorCode: agenterrors.CodeOf(err),
+		}
+	}
+
+	if err := g.checkPresence(i); err != nil {
+		return &ExecutionResult{
+			Success:   false,
+			IntentID:  i.ID,
+			Module:    *i.TargetModule,
+			Action:    i.IntentType,
+			Error:     err.Error(),
+			ErrorCode: agenterrors.CodeOf(err),
+		}
+	}
+
+	if err := g.checkMode(i); err != nil {
+		return &ExecutionResult{
+			Success:   false,
+			IntentID:  i.ID,
+			Module:    *i.TargetModule,
+			Action:    i.IntentType,
+			Error:     err.Error(),
+			ErrorCode: agenterrors.CodeOf(err),
+		}
+	}
+
+	ctx, adjusted, err := g.applySafety(ctx, i)
+	if err != nil {
+		return &ExecutionResult{
+			Success:   false,
+			IntentID:  i.ID,
+			Module:    *i.TargetModule,
+			Action:    i.IntentType,
+			Error:     err.Error(),
+			ErrorCode: agenterrors.CodeOf(err),
+		}
+	}
+	ctx, pin, err := g.checkChallenge(ctx, i)
+	if err != nil {
 		return &ExecutionResult{
-			Success:  false,
-			IntentID: i.ID,
-			Module:   *i.TargetModule,
-			Action:   i.IntentType,
-			Error:    fmt.Sprintf("no executor found for module: %s", *i.TargetModule),
-		}, nil
+			Success:   false,
+			IntentID:  i.ID,
+			Module:    *i.TargetModule,
+			Action:    i.IntentType,
+			Error:     err.Error(),
+			ErrorCode: agenterrors.CodeOf(err),
+		}
+	}
+	ctx, unusual, approved, err := g.checkAnomaly(ctx, i)
+	if err == nil && approved == nil {
+		ctx, approved, err = g.checkApproval(ctx, i)
+	}
+	if err == nil {
+		err = g.checkQuota(ctx, i)
+	}
+	if err != nil {
+		return &ExecutionResult{
+			Success:   false,
+			IntentID:  i.ID,
+			Module:    *i.TargetModule,
+			Action:    i.IntentType,
+			Error:     err.Error(),
+			ErrorCode: agenterrors.CodeOf(err),
+		}
+	}
+	policySpan.End()
+	result := g.execute(ctx, i)
+	// Executors may have clamped values of their own with SafetyClamp
+	if result != nil && adjusted != nil {
+		if result.Adjusted == nil {
+			result.Adjusted = make(map[string]interface{})
+		}
+		maps.Copy(result.Adjusted, adjusted)
+	}
+	if result != nil && approved != nil {
+		if result.Audit == nil {
+			result.Audit = make(map[string]interface{})
+		}
+		result.Audit["approval"] = approved
+	}
+	if result != nil && pin != "" {
+		if result.Audit == nil {
+			result.Audit = make(map[string]interface{})
+		}
+		result.Audit["challenge"] = pin
+	}
+	if result != nil && unusual != nil {
+		if result.Audit == nil {
+			result.Audit = make(map[string]interface{})
+		}
+		result.Audit["anomaly"] = unusual
+	}
+	return result
+}
+
+// execute runs an intent that passed the gateway's policies on its target
+func (g *Gateway) execute(ctx context.Context, i *intent.Intent) *ExecutionResult {
+	ctx, span := tracing.Start(ctx, "execute")
+	defer span.End()
+	span.SetAttribute("executor", *i.TargetModule)
+
+	if modules, ok := g.fanOutTargets(*i.TargetModule, i.IntentType); ok {
+		g.logger.InfoContext(ctx, "Fanning out intent", "target", describeTarget(*i.TargetModule, modules))
+		return g.fanOut(ctx, i, modules)
+	}
+
+	if err := g.checkMaintenance(ctx, i); err != nil {
+		return &ExecutionResult{
+			Success:   false,
+			IntentID:  i.ID,
+			Module:    *i.TargetModule,
+			Action:    i.IntentType,
+			Error:     err.Error(),
+			ErrorCode: agenterrors.CodeOf(err),
+		}
 	}
 
-	// Check if executor is available
-	if !executor.IsAvailable() {
+	executor, err := g.readyExecutor(ctx, i, *i.TargetModule)
+	if err != nil {
 		return &ExecutionResult{
-			Success:  false,
-			IntentID: i.ID,
-			Module:   *i.TargetModule,
-			Action:   i.IntentType,
-			Error:    fmt.Sprintf("executor '%s' is not available", executor.Name()),
-		}, nil
+			Success:   false,
+			IntentID:  i.ID,
+			Module:    *i.TargetModule,
+			Action:    i.IntentType,
+			Error:     err.Error(),
+			ErrorCode: agenterrors.CodeOf(err),
+		}
+	}
+
+	// Validate parameters against the intent type's schema
+	if err := g.schemas.Validate(i.IntentType, i.Parameters); err != nil {
+		g.logger.WarnContext(ctx, "Rejected intent", "error", err)
+		result := &ExecutionResult{
+			Success:   false,
+			IntentID:  i.ID,
+			Module:    executor.Name(),
+			Action:    i.IntentType,
+			Error:     err.Error(),
+			ErrorCode: agenterrors.InvalidParams,
+		}
+		var verr *schema.ValidationError
+		if errors.As(err, &verr) {
+			result.Result = map[string]interface{}{"field_errors": verr.Fields}
+		}
+		return result
+	}
+
+	// Hold the intent's exclusion groups while it runs
+	if provider, ok := executor.(ExclusionProvider); ok {
+		release, err := g.exclusions.Acquire(i, provider.Exclusions(i))
+		if err != nil {
+			g.logger.WarnContext(ctx, "Rejected intent", "error", err)
+			return &ExecutionResult{
+				Success:   false,
+				IntentID:  i.ID,
+				Module:    executor.Name(),
+				Action:    i.IntentType,
+				Error:     err.Error(),
+				ErrorCode: agenterrors.CodeOf(err),
+			}
+		}
+		defer release()
 	}
 
 	// Execute intent
+	started := time.Now()
 	result, err := executor.Execute(ctx, i)
+	g.recordExecution(ctx, executor.Name(), time.Since(started), result, err)
 	if err != nil {
-		g.logger.Printf("Execution error for intent %s: %v", i.ID, err)
+		span.Fail(err.Error())
+		g.logger.ErrorContext(ctx, "Execution error", "executor", executor.Name(), "error", err)
 		return &ExecutionResult{
-			Success:  false,
-			IntentID: i.ID,
-			Module:   executor.Name(),
-			Action:   i.IntentType,
-			Error:    err.Error(),
-		}, nil
+			Success:   false,
+			IntentID:  i.ID,
+			Module:    executor.Name(),
+			Action:    i.IntentType,
+			Error:     err.Error(),
+			ErrorCode: agenterrors.CodeOf(err),
+		}
 	}
 
-	g.logger.Printf("Intent %s executed successfully", i.ID)
-	return result, nil
+	if result != nil && !result.Success {
+		span.Fail(result.Error)
+	}
+	g.logger.InfoContext(ctx, "Intent executed", "executor", executor.Name(), "success", result != nil && result.Success)
+	return result
+}
+
+// readyExecutor returns the executor of module if it can run the intent:
+// registered, enabled, available, not degraded for the intent's origin,
+// and given permission if its action needs it
+func (g *Gateway) readyExecutor(ctx context.Context, i *intent.Intent, module string) (Executor, error) {
+	g.mu.RLock()
+	executor, ok := g.executors[module]
+	disabled := g.disabled[module]
+	g.mu.RUnlock()
+
+	switch {
+	case !ok:
+		return nil, agenterrors.Newf(agenterrors.NotFound, "no executor found for module: %s", module)
+	case disabled:
+		return nil, agenterrors.Newf(agenterrors.ExecutorDisabled, "executor '%s' is disabled", executor.Name())
+	case !executor.IsAvailable():
+		return nil, agenterrors.Newf(agenterrors.Unavailable, "executor '%s' is not available", executor.Name())
+	}
+	if err := g.checkDegraded(ctx, i, executor.Name()); err != nil {
+		return nil, err
+	}
+	if permissionRequired(executor)[i.IntentType] && !i.RequiresPermission {
+		return nil, agenterrors.Newf(agenterrors.Unauthorized, "%s requires an intent with requires_permission set", i.IntentType)
+	}
+	return executor, nil
+}
+
+// finish copies the intent's trace IDs onto its result, redacts it, signs
+// it, publishes it, audits it, and keeps it in the history
+func (g *Gateway) finish(ctx context.Context, i *intent.Intent, result *ExecutionResult) *ExecutionResult {
+	span := tracing.SpanFromContext(ctx)
+	_, finishSpan := tracing.Start(ctx, "finish")
+	defer finishSpan.End()
+	if result == nil {
+		result = &ExecutionResult{
+			Success:   false,
+			IntentID:  i.ID,
+			Action:    i.IntentType,
+			Error:     "executor returned no result",
+			ErrorCode: agenterrors.Internal,
+		}
+	}
+	if result.Timestamp == "" {
+		result.Timestamp = time.Now().Format(time.RFC3339)
+	}
+	result.CorrelationID = i.CorrelationID
+	result.SessionID = i.SessionID
+	result.Provenance = i.Provenance
+	result.TraceID = span.TraceID()
+	span.SetAttribute("intent.success", result.Success)
+	if !result.Success {
+		span.SetAttribute("intent.error_code", string(result.ErrorCode))
+		span.Fail(result.Error)
+	}
+	g.redact(i, result)
+	g.sign(ctx, i, result)
+
+	g.mu.RLock()
+	bus := g.bus
+	g.mu.RUnlock()
+	if bus != nil {
+		bus.Publish(events.Event{
+			Type:   "intent.completed",
+			Source: "gateway",
+			Data: map[string]interface{}{
+				"intent_id":      i.ID,
+				"intent_type":    i.IntentType,
+				"parameters":     i.Parameters,
+				"module":         result.Module,
+				"success":        result.Success,
+				"error":          result.Error,
+				"error_code":     string(result.ErrorCode),
+				"result":         result.Result,
+				"correlation_id": i.CorrelationID,
+				"session_id":     i.SessionID,
+				"provenance":     i.Provenance,
+				"usage":          result.Usage,
+			},
+		})
+	}
+
+	entry := audit.Entry{
+		IntentID:      i.ID,
+		IntentType:    i.IntentType,
+		Module:        result.Module,
+		CorrelationID: i.CorrelationID,
+		SessionID:     i.SessionID,
+		SpeakerID:     i.SpeakerID,
+		Provenance:    i.Provenance,
+		Success:       result.Success,
+		Error:         result.Error,
+		Usage:         result.Usage,
+		Details:       result.Audit,
+	}
+	if id := access.IdentityFrom(ctx); id != nil {
+		entry.Identity = id.Name
+		entry.Roles = id.Roles
+	}
+	if result.Usage != nil {
+		g.usage.Record(result.Module, i.IntentType, *result.Usage, !result.Success)
+	}
+	grant, err := g.guestGrant(i)
+	if grant != nil {
+		entry.Guest = grant.Name
+	} else if err != nil {
+		entry.Guest = "(unknown)"
+	}
+	if l := g.AuditLog(); l != nil {
+		if err := l.Record(entry); err != nil {
+			g.logger.ErrorContext(ctx, "Failed to audit intent", "error", err)
+		}
+	}
+	g.mu.RLock()
+	guestLog := g.guestLog
+	g.mu.RUnlock()
+	if guestLog != nil && entry.Guest != "" {
+		if err := guestLog.Record(entry); err != nil {
+			g.logger.ErrorContext(ctx, "Failed to audit guest intent", "error", err)
+		}
+	}
+	g.recordHistory(ctx, i, result)
+	return result
+}
+
+// checkRole refuses intents the roles of the identity that sent them don't
+// allow. The policy is applied to every plan step and broadcast copy as
+// well, so a plan or scene can't do more than its sender could.
+func (g *Gateway) checkRole(ctx context.Context, i *intent.Intent) error {
+	id := access.IdentityFrom(ctx)
+	if id == nil {
+		return nil
+	}
+	g.mu.RLock()
+	roles := g.roles
+	g.mu.RUnlock()
+	if roles == nil {
+		return nil
+	}
+	// The identity's roles are looked up again, so a reloaded policy
+	// applies to clients that authenticated under the old one
+	current, ok := roles.Identity(id.Name)
+	if !ok {
+		return agenterrors.Newf(agenterrors.Unauthorized, "identity %s has been removed", id.Name)
+	}
+	if id = current; roles.Allows(id, i) {
+		return nil
+	}
+	return agenterrors.Newf(agenterrors.DeniedByPolicy, "%s (roles %s) is not allowed %s", id.Name, strings.Join(id.Roles, ", "), i.IntentType)
+}
+
+// guestGrant returns the grant a guest intent acts under, nil for intents
+// not from a guest
+func (g *Gateway) guestGrant(i *intent.Intent) (*access.Grant, error) {
+	g.mu.RLock()
+	guests := g.guests
+	g.mu.RUnlock()
+	if guests == nil {
+		if i.GuestToken != "" {
+			return nil, agenterrors.New(agenterrors.Unauthorized, "guest access is not enabled")
+		}
+		return nil, nil
+	}
+	return guests.Lookup(i)
+}
+
+// checkSignature applies the signature policy set with SetVerifier
+func (g *Gateway) checkSignature(ctx context.Context, i *intent.Intent) error {
+	g.mu.RLock()
+	verifier, strict := g.verifier, g.strict
+	g.mu.RUnlock()
+
+	if verifier == nil {
+		if strict {
+			return errors.New("no trusted keys configured")
+		}
+		return nil
+	}
+
+	err := verifier.VerifyIntent(i)
+	if errors.Is(err, crypto.ErrUnsigned) && !strict {
+		g.logger.DebugContext(ctx, "Accepting unsigned intent (strict mode off)")
+		return nil
+	}
+	return err
+}
+
+// openParameters decrypts the intent's sealed parameters, if any, with the
+// key set by SetPayloadKey
+func (g *Gateway) openParameters(i *intent.Intent) error {
+	if i.EncryptedParameters == nil {
+		return nil
+	}
+	g.mu.RLock()
+	key := g.payloadKey
+	g.mu.RUnlock()
+	if key == nil {
+		return agenterrors.New(agenterrors.Unsupported, "encrypted parameters aren't enabled on this agent")
+	}
+	return agenterrors.Wrap(agenterrors.InvalidIntent, key.Open(i))
+}
+
+// deadline combines the intent's own expiry with the gateway's max age
+func (g *Gateway) deadline(i *intent.Intent) (time.Time, bool) {
+	deadline, ok := i.Deadline()
+
+	g.mu.RLock()
+	maxAge := g.maxAge
+	g.mu.RUnlock()
+
+	if maxAge > 0 && !i.CreatedAt.IsZero() {
+		aged := i.CreatedAt.Add(maxAge)
+		if !ok || aged.Before(deadline) {
+			deadline, ok = aged, true
+		}
+	}
+	return deadline, ok
 }
 
 // GetExecutors returns all registered executors
@@ -0,0 +1,198 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/access"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/history"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// SetHistory keeps every result, including rejections and plan steps, in
+// s, after redaction
+func (g *Gateway) SetHistory(s *history.Store) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.history = s
+}
+
+// History returns the history set with SetHistory, or nil
+func (g *Gateway) History() *history.Store {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.history
+}
+
+// recordHistory keeps a result in the history. history.* intents aren't
+// kept, so asking what the agent did doesn't become something it did.
+func (g *Gateway) recordHistory(ctx context.Context, i *intent.Intent, result *ExecutionResult) {
+	s := g.History()
+	if s == nil || access.MatchIntentType("history.*", i.IntentType) {
+		return
+	}
+	r := history.Record{
+		IntentID:      i.ID,
+		IntentType:    i.IntentType,
+		Executor:      result.Module,
+		Parameters:    i.Parameters,
+		Success:       result.Success,
+		Result:        result.Result,
+		Error:         result.Error,
+		ErrorCode:     string(result.ErrorCode),
+		CorrelationID: i.CorrelationID,
+		SessionID:     i.SessionID,
+	}
+	if result.Usage != nil {
+		r.WallTimeMs = result.Usage.WallTimeMs
+	}
+	if err := s.Record(r); err != nil {
+		g.logger.ErrorContext(ctx, "Failed to record history", "error", err)
+	}
+}
+
+// HistoryExecutor lets the agent core look up what the agent did, such as
+// everything since midnight or the lights that failed to turn on
+type HistoryExecutor struct {
+	gw *Gateway
+}
+
+// NewHistoryExecutor creates a history executor for the gateway
+func NewHistoryExecutor(gw *Gateway) *HistoryExecutor {
+	return &HistoryExecutor{gw: gw}
+}
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
+
+func (e *HistoryExecutor) Name() string {
+	return "history"
+}
+
+func (e *HistoryExecutor) SupportedActions() []string {
+	return []string{"history.query"}
+}
+
+func (e *HistoryExecutor) Needs() sandbox.Needs {
+	return sandbox.Needs{}
+}
+
+func (e *HistoryExecutor) Execute(ctx context.Context, i *intent.Intent) (*ExecutionResult, error) {
+	result := &ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "history",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	if i.IntentType != "history.query" {
+		return fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+	s := e.gw.History()
+	if s == nil {
+		return fail(agenterrors.New(agenterrors.Unavailable, "no history is kept"))
+	}
+	var params struct {
+		Since      string    `param:"since"`
+		Until      time.Time `param:"until"`
+		IntentType string    `param:"intent_type"`
+		Executor   string    `param:"executor"`
+		Success    *bool     `param:"success"`
+		Limit      int       `param:"limit"`
+	}
+	if err := i.DecodeParams(&params); err != nil {
+		return fail(err)
+	}
+	q := history.Query{
+		Until:      params.Until,
+		IntentType: params.IntentType,
+		Executor:   params.Executor,
+		Success:    params.Success,
+		Limit:      params.Limit,
+	}
+	if params.Since != "" {
+		since, until, err := parseSince(params.Since, time.Now())
+		if err != nil {
+			return fail(err)
+		}
+		q.Since = since
+		if q.Until.IsZero() {
+			q.Until = until
+		}
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultHistoryLimit
+	}
+	if q.Limit > maxHistoryLimit {
+		q.Limit = maxHistoryLimit
+	}
+
+	records, err := s.Query(q)
+	if err != nil {
+		return fail(agenterrors.Newf(agenterrors.Internal, "failed to read history: %w", err))
+	}
+	failed := 0
+	for _, r := range records {
+		if !r.Success {
+			failed++
+		}
+	}
+	result.Success = true
+	result.Result = map[string]interface{}{
+		"records": records,
+		"count":   len(records),
+		"failed":  failed,
+	}
+	return result, nil
+}
+
+// parseSince reads history.query's since: "today" or "yesterday" in local
+// time, an RFC 3339 time, or a duration ago. Yesterday also sets the
+// period's end.
+func parseSince(s string, now time.Time) (since, until time.Time, err error) {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch s {
+	case "today":
+		return midnight, time.Time{}, nil
+	case "yesterday":
+		return midnight.AddDate(0, 0, -1), midnight, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return now.Add(-d), time.Time{}, nil
+	}
+	return since, until, agenterrors.Newf(agenterrors.InvalidParams,
+		"since must be today, yesterday, an RFC 3339 time, or a duration like 3h, got %q", s)
+}
+
+func (e *HistoryExecutor) IsAvailable() bool {
+	return e.gw.History() != nil
+}
+
+func (e *HistoryExecutor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"history.query": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"since": {"type": "string", "minLength": 1},
+				"until": {"type": "string", "minLength": 1},
+				"intent_type": {"type": "string", "minLength": 1},
+				"executor": {"type": "string", "minLength": 1},
+				"success": {"type": "boolean"},
+				"limit": {"type": "integer", "minimum": 1, "maximum": 500}
+			}
+		}`),
+	}
+}
@@ -0,0 +1,159 @@
+package gateway
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/access"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/quota"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+)
+
+// InFlight is an intent being handled: checked against the policies,
+// waiting for approval, or running
+type InFlight struct {
+	IntentID      string    `json:"intent_id"`
+	IntentType    string    `json:"intent_type"`
+	Module        string    `json:"module,omitempty"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	Identity      string    `json:"identity,omitempty"`
+	Started       time.Time `json:"started"`
+	ElapsedMs     int64     `json:"elapsed_ms"`
+}
+
+// track counts an intent as in flight until the returned function is
+// called. Plan steps and broadcast copies are tracked as well as the
+// intent that spawned them.
+func (g *Gateway) track(ctx context.Context, i *intent.Intent) func() {
+	f := InFlight{
+		IntentID:      i.ID,
+		IntentType:    i.IntentType,
+		CorrelationID: i.CorrelationID,
+		Started:       time.Now(),
+	}
+	if i.TargetModule != nil {
+		f.Module = *i.TargetModule
+	}
+	if id := access.IdentityFrom(ctx); id != nil {
+		f.Identity = id.Name
+	}
+	g.flightMu.Lock()
+	g.flightNext++
+	key := g.flightNext
+	g.flights[key] = f
+	g.flightMu.Unlock()
+	return func() {
+		g.flightMu.Lock()
+		delete(g.flights, key)
+		g.flightMu.Unlock()
+	}
+}
+
+// InFlight lists the intents being handled, longest running first
+func (g *Gateway) InFlight() []InFlight {
+	g.flightMu.Lock()
+	flights := slices.Collect(maps.Values(g.flights))
+	g.flightMu.Unlock()
+	slices.SortFunc(flights, func(a, b InFlight) int {
+		return a.Started.Compare(b.Started)
+	})
+	now := time.Now()
+	for n := range flights {
+		flights[n].ElapsedMs = now.Sub(flights[n].Started).Milliseconds()
+	}
+	if flights == nil {
+		flights = []InFlight{}
+	}
+	return flights
+}
+
+// Policies is what the gateway currently enforces, for inspection. API
+// key hashes, PINs, and keys are left out.
+type Policies struct {
+	Mode              string `json:"mode"`
+	SafetyMode        bool   `json:"safety_mode"`
+	RequireSignatures bool   `json:"require_signatures"`
+	TrustedKeys       bool   `json:"trusted_keys"`
+	EncryptedParams   bool   `json:"encrypted_params"`
+	SignResults       bool   `json:"sign_results"`
+	StrictParsing     bool   `json:"strict_parsing"`
+	MaxIntentAge      string `json:"max_intent_age,omitempty"`
+
+	// Roles and the roles of each identity
+	Roles      map[string]access.Role `json:"roles,omitempty"`
+	Identities map[string][]string    `json:"identities,omitempty"`
+
+	Sandbox       *sandbox.Policy     `json:"sandbox,omitempty"`
+	Sensitive     []string            `json:"sensitive_intents,omitempty"`
+	MinConfidence float32             `json:"speaker_min_confidence,omitempty"`
+	KnownSpeakers []string            `json:"known_speakers,omitempty"`
+	RequireHome   []string            `json:"require_home,omitempty"`
+	ModuleGroups  map[string][]string `json:"module_groups,omitempty"`
+	Disabled      []string            `json:"disabled_executors,omitempty"`
+	Degraded      []string            `json:"degraded_executors,omitempty"`
+	Maintenance   []Maintenance       `json:"maintenance,omitempty"`
+	Quotas        []quota.Status      `json:"quotas,omitempty"`
+	Approvals     bool                `json:"approvals"`
+	Challenges    bool                `json:"pin_challenges"`
+	Anomalies     bool                `json:"anomaly_detection"`
+	Redactions    int                 `json:"redactions"`
+	Transformers  int                 `json:"param_transformers"`
+}
+
+// Policies returns what the gateway currently enforces
+func (g *Gateway) Policies() Policies {
+	g.mu.RLock()
+	p := Policies{
+		Mode:              g.mode,
+		SafetyMode:        g.safetyOn,
+		RequireSignatures: g.strict,
+		TrustedKeys:       g.verifier != nil,
+		EncryptedParams:   g.payloadKey != nil,
+		SignResults:       g.signer != nil,
+		StrictParsing:     g.parse.strict,
+		Sandbox:           g.sandbox,
+		Sensitive:         g.speakerPolicy.Sensitive,
+		MinConfidence:     g.speakerPolicy.MinConfidence,
+		KnownSpeakers:     g.speakerPolicy.Known,
+		RequireHome:       g.presencePolicy.RequireHome,
+		Approvals:         g.approvals != nil,
+		Challenges:        g.challenges != nil,
+		Anomalies:         g.anomalies != nil,
+		Redactions:        len(g.redactions),
+		Transformers:      len(g.transformers),
+	}
+	if g.maxAge > 0 {
+		p.MaxIntentAge = g.maxAge.String()
+	}
+	if g.roles != nil {
+		p.Roles = g.roles.Roles
+		p.Identities = make(map[string][]string, len(g.roles.Identities))
+		for _, id := range g.roles.Identities {
+			p.Identities[id.Name] = id.Roles
+		}
+	}
+	for name := range g.disabled {
+		p.Disabled = append(p.Disabled, name)
+	}
+	slices.Sort(p.Disabled)
+	quotas := g.quotas
+	g.mu.RUnlock()
+
+	if groups := g.ModuleGroups(); len(groups) > 0 {
+		p.ModuleGroups = groups
+	}
+	p.Maintenance = g.MaintenanceWindows()
+	for name, s := range g.Stats() {
+		if s.Degraded {
+			p.Degraded = append(p.Degraded, name)
+		}
+	}
+	slices.Sort(p.Degraded)
+	if quotas != nil {
+		p.Quotas = quotas.Status("", time.Now())
+	}
+	return p
+}
@@ -0,0 +1,167 @@
+package gateway
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// Maintenance is a window during which an executor or a device only takes
+// intents the user asked for directly. Intents generated by automations,
+// schedules, routines, or suggestions fail with UNDER_MAINTENANCE, so
+// firmware updates and repairs aren't interrupted.
+type Maintenance struct {
+	// Executor or Device (matched against the intent's "device" parameter)
+	// being maintained; exactly one is set
+	Executor string `json:"executor,omitempty"`
+	Device   string `json:"device,omitempty"`
+
+	Reason  string    `json:"reason,omitempty"`
+	Started time.Time `json:"started"`
+
+	// Until ends the window on its own; zero until EndMaintenance
+	Until time.Time `json:"until,omitzero"`
+}
+
+func (m Maintenance) key() string {
+	if m.Executor != "" {
+		return "executor:" + m.Executor
+	}
+	return "device:" + m.Device
+}
+
+func (m Maintenance) target() string {
+	if m.Executor != "" {
+		return "executor " + m.Executor
+	}
+	return "device " + m.Device
+}
+
+func (m Maintenance) active(now time.Time) bool {
+	return m.Until.IsZero() || now.Before(m.Until)
+}
+
+// StartMaintenance opens a window, replacing any for the same target
+func (g *Gateway) StartMaintenance(m Maintenance) error {
+	if (m.Executor == "") == (m.Device == "") {
+		return agenterrors.New(agenterrors.InvalidParams, "maintenance needs either an executor or a device")
+	}
+	if m.Started.IsZero() {
+		m.Started = time.Now().UTC()
+	}
+
+	g.mu.Lock()
+	if m.Executor != "" {
+		if _, ok := g.executors[m.Executor]; !ok {
+			g.mu.Unlock()
+			return agenterrors.Newf(agenterrors.NotFound, "no executor named %s", m.Executor)
+		}
+	}
+	if g.maintenance == nil {
+		g.maintenance = make(map[string]Maintenance)
+	}
+	g.maintenance[m.key()] = m
+	g.mu.Unlock()
+
+	g.logger.Info("Maintenance started", "target", m.target(), "reason", m.Reason)
+	g.publishMaintenance("maintenance.started", m)
+	return nil
+}
+
+// EndMaintenance closes the window for an executor or a device
+func (g *Gateway) EndMaintenance(executor, device string) error {
+	if (executor == "") == (device == "") {
+		return agenterrors.New(agenterrors.InvalidParams, "maintenance needs either an executor or a device")
+	}
+	key := Maintenance{Executor: executor, Device: device}.key()
+	g.mu.Lock()
+	m, ok := g.maintenance[key]
+	delete(g.maintenance, key)
+	g.mu.Unlock()
+
+	if !ok {
+		return agenterrors.Newf(agenterrors.NotFound, "%s is not under maintenance", Maintenance{Executor: executor, Device: device}.target())
+	}
+	g.logger.Info("Maintenance ended", "target", m.target())
+	g.publishMaintenance("maintenance.ended", m)
+	return nil
+}
+
+// MaintenanceWindows lists the open windows, executors first
+func (g *Gateway) MaintenanceWindows() []Maintenance {
+	now := time.Now()
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	list := []Maintenance{}
+	for _, m := range g.maintenance {
+		if m.active(now) {
+			list = append(list, m)
+		}
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].key() < list[b].key() })
+	return list
+}
+
+// checkMaintenance refuses intents not from the user that target an
+// executor or device under maintenance
+func (g *Gateway) checkMaintenance(ctx context.Context, i *intent.Intent) error {
+	origin := i.OriginKind()
+	if origin == intent.OriginUser {
+		return nil
+	}
+
+	g.mu.Lock()
+	if len(g.maintenance) == 0 {
+		g.mu.Unlock()
+		return nil
+	}
+	keys := []string{Maintenance{Executor: *i.TargetModule}.key()}
+	if device, ok := i.Parameters["device"].(string); ok && device != "" {
+		keys = append(keys, Maintenance{Device: device}.key())
+	}
+	var window *Maintenance
+	now := time.Now()
+	for _, key := range keys {
+		m, ok := g.maintenance[key]
+		if !ok {
+			continue
+		}
+		if !m.active(now) {
+			delete(g.maintenance, key)
+			continue
+		}
+		window = &m
+		break
+	}
+	g.mu.Unlock()
+	if window == nil {
+		return nil
+	}
+
+	g.logger.InfoContext(ctx, "Suppressed intent under maintenance", "origin", origin, "target", window.target())
+	g.publishMaintenance("maintenance.suppressed", *window)
+	return agenterrors.Newf(agenterrors.UnderMaintenance, "%s is under maintenance; %s intents are suppressed", window.target(), origin)
+}
+
+func (g *Gateway) publishMaintenance(eventType string, m Maintenance) {
+	g.mu.RLock()
+	bus := g.bus
+	g.mu.RUnlock()
+	if bus == nil {
+		return
+	}
+	data := map[string]interface{}{"reason": m.Reason}
+	if m.Executor != "" {
+		data["executor"] = m.Executor
+	} else {
+		data["device"] = m.Device
+	}
+	if !m.Until.IsZero() {
+		data["until"] = m.Until.Format(time.RFC3339)
+	}
+	bus.Publish(events.Event{Type: eventType, Source: "gateway", Data: data})
+}
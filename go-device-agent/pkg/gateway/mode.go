@@ -0,0 +1,280 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/access"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// NormalMode is the mode in which nothing is suppressed
+const NormalMode = "normal"
+
+// Mode declares classes of intents suppressed while it is on, such as
+// everything touching the camera and microphone in privacy mode. mode.*
+// intents are never suppressed, so a mode can always be left.
+type Mode struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+
+	// Suppress lists the intents refused with DENIED_BY_POLICY
+	Suppress []access.Rule `json:"suppress"`
+
+	// Allow lists exceptions to Suppress, such as critical notifications
+	// in do-not-disturb
+	Allow []access.Rule `json:"allow,omitempty"`
+}
+
+// DefaultModes are "privacy", suppressing the camera, microphone, screen,
+// and clipboard, and "dnd" (do not disturb), suppressing all but critical
+// notifications, sounds, and media
+func DefaultModes() []Mode {
+	return []Mode{
+		{
+			Name:        "privacy",
+			Description: "No camera, microphone, screen, or clipboard access",
+			Suppress: []access.Rule{
+				{IntentTypes: []string{"camera.*", "sound.*", "window.*", "input.*", "clipboard.get"}},
+			},
+		},
+		{
+			Name:        "dnd",
+			Description: "Do not disturb: only critical notifications",
+			Suppress: []access.Rule{
+				{IntentTypes: []string{"notification.send", "media.play", "sound.clip", "audio.volume", "media.volume"}},
+			},
+			Allow: []access.Rule{
+				{IntentTypes: []string{"notification.send"}, Params: map[string][]string{"urgency": {"critical"}}},
+			},
+		},
+	}
+}
+
+// LoadModes reads a JSON array of modes
+func LoadModes(path string) ([]Mode, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var modes []Mode
+	if err := json.Unmarshal(data, &modes); err != nil {
+		return nil, fmt.Errorf("invalid modes file %s: %w", path, err)
+	}
+	for n, m := range modes {
+		if m.Name == "" || m.Name == NormalMode {
+			return nil, fmt.Errorf("mode %d in %s needs a name other than %q", n, path, NormalMode)
+		}
+	}
+	return modes, nil
+}
+
+// suppresses reports whether the mode refuses the intent
+func (m *Mode) suppresses(i *intent.Intent) bool {
+	if access.MatchIntentType("mode.*", i.IntentType) {
+		return false
+	}
+	for _, r := range m.Allow {
+		if r.Matches(i) {
+			return false
+		}
+	}
+	for _, r := range m.Suppress {
+		if r.Matches(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// suppressesAll reports whether the mode refuses every intent of a type,
+// whatever its parameters
+func (m *Mode) suppressesAll(intentType string) bool {
+	probe := &intent.Intent{IntentType: intentType}
+	for _, r := range m.Allow {
+		for _, pattern := range r.IntentTypes {
+			if access.MatchIntentType(pattern, intentType) {
+				return false
+			}
+		}
+	}
+	for _, r := range m.Suppress {
+		if len(r.Params) == 0 && r.Matches(probe) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetModes sets the modes SetMode can switch to, replacing those of the
+// same name; the defaults are DefaultModes
+func (g *Gateway) SetModes(modes []Mode) {
+	g.mu.Lock()
+	for _, m := range modes {
+		g.modes[m.Name] = m
+	}
+	g.mu.Unlock()
+	g.capabilitiesChanged("mode.modes")
+}
+
+// Modes lists the modes SetMode can switch to, by name
+func (g *Gateway) Modes() []Mode {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	modes := make([]Mode, 0, len(g.modes))
+	for _, m := range g.modes {
+		modes = append(modes, m)
+	}
+	sort.Slice(modes, func(a, b int) bool { return modes[a].Name < modes[b].Name })
+	return modes
+}
+
+// SetMode switches to the named mode, or back to NormalMode, publishing a
+// "mode.changed" event when it changes
+func (g *Gateway) SetMode(name string) error {
+	if name == "" {
+		name = NormalMode
+	}
+	g.mu.Lock()
+	if _, ok := g.modes[name]; !ok && name != NormalMode {
+		g.mu.Unlock()
+		return agenterrors.Newf(agenterrors.NotFound, "no mode named %q", name)
+	}
+	previous := g.mode
+	g.mode = name
+	bus := g.bus
+	g.mu.Unlock()
+
+	if previous == name {
+		return nil
+	}
+	g.logger.Info("Mode changed", "from", previous, "to", name)
+	if bus != nil {
+		bus.Publish(events.Event{Type: "mode.changed", Source: "gateway", Data: map[string]interface{}{
+			"mode":     name,
+			"previous": previous,
+		}})
+	}
+	g.capabilitiesChanged("mode.changed")
+	return nil
+}
+
+// Mode returns the current mode's name
+func (g *Gateway) Mode() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.mode
+}
+
+// currentMode returns the mode in force, or nil in NormalMode
+func (g *Gateway) currentMode() *Mode {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	m, ok := g.modes[g.mode]
+	if !ok {
+		return nil
+	}
+	return &m
+}
+
+// checkMode refuses intents the current mode suppresses
+func (g *Gateway) checkMode(i *intent.Intent) error {
+	if m := g.currentMode(); m != nil && m.suppresses(i) {
+		return agenterrors.Newf(agenterrors.DeniedByPolicy, "%s is suppressed in %s mode", i.IntentType, m.Name)
+	}
+	return nil
+}
+
+// ModeExecutor lets the agent core read and switch the gateway's mode.
+// Switching needs an intent with requires_permission set.
+type ModeExecutor struct {
+	gw *Gateway
+}
+
+// NewModeExecutor creates a mode executor for the gateway
+func NewModeExecutor(gw *Gateway) *ModeExecutor {
+	return &ModeExecutor{gw: gw}
+}
+
+func (e *ModeExecutor) Name() string {
+	return "mode"
+}
+
+func (e *ModeExecutor) SupportedActions() []string {
+	return []string{"mode.set", "mode.get"}
+}
+
+func (e *ModeExecutor) PermissionRequired() []string {
+	return []string{"mode.set"}
+}
+
+func (e *ModeExecutor) Needs() sandbox.Needs {
+	return sandbox.Needs{}
+}
+
+func (e *ModeExecutor) Execute(ctx context.Context, i *intent.Intent) (*ExecutionResult, error) {
+	result := &ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "mode",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	switch i.IntentType {
+	case "mode.set":
+		var params struct {
+			Mode string `param:"mode,required"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		previous := e.gw.Mode()
+		if err := e.gw.SetMode(params.Mode); err != nil {
+			return fail(err)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{"mode": e.gw.Mode(), "previous": previous}
+
+	case "mode.get":
+		modes := []map[string]interface{}{}
+		for _, m := range e.gw.Modes() {
+			modes = append(modes, map[string]interface{}{"name": m.Name, "description": m.Description})
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{"mode": e.gw.Mode(), "modes": modes}
+
+	default:
+		return fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+	return result, nil
+}
+
+func (e *ModeExecutor) IsAvailable() bool {
+	return true
+}
+
+func (e *ModeExecutor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"mode.set": schema.MustParse(`{
+			"type": "object",
+			"required": ["mode"],
+			"properties": {
+				"mode": {"type": "string", "minLength": 1}
+			}
+		}`),
+		"mode.get": schema.MustParse(`{"type": "object", "properties": {}}`),
+	}
+}
@@ -0,0 +1,459 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// maxPlanSteps bounds the size of one plan
+const maxPlanSteps = 32
+
+// PlanStep is one sub-intent of a plan. Steps run in list order unless any
+// step declares DependsOn, in which case the plan is a dependency graph and
+// steps whose dependencies are done run concurrently. List order only
+// sequences steps; a step is skipped only when a step it names in DependsOn
+// or When did not succeed.
+type PlanStep struct {
+	ID                 string                 `json:"id"`
+	IntentType         string                 `json:"intent_type"`
+	TargetModule       string                 `json:"target_module,omitempty"`
+	Parameters         map[string]interface{} `json:"parameters,omitempty"`
+	RequiresPermission bool                   `json:"requires_permission,omitempty"`
+	DependsOn          []string               `json:"depends_on,omitempty"`
+	When               *StepCondition         `json:"when,omitempty"`
+}
+
+// StepCondition makes a step conditional on an earlier step's outcome.
+// Success defaults to true; set it to false for a failure branch. Field is
+// a dotted path into the earlier step's result compared against Equals.
+type StepCondition struct {
+	Step    string      `json:"step"`
+	Success *bool       `json:"success,omitempty"`
+	Field   string      `json:"field,omitempty"`
+	Equals  interface{} `json:"equals,omitempty"`
+}
+
+// StepStatus is the outcome of one plan step
+type StepStatus string
+
+// Step outcomes
+const (
+	StepSucceeded StepStatus = "succeeded"
+	StepFailed    StepStatus = "failed"
+	StepSkipped   StepStatus = "skipped"
+)
+
+// StepResult reports one step of a plan
+type StepResult struct {
+	ID     string           `json:"id"`
+	Status StepStatus       `json:"status"`
+	Reason string           `json:"reason,omitempty"`
+	Result *ExecutionResult `json:"result,omitempty"`
+}
+
+// PlanResult aggregates the steps of a plan in plan order
+type PlanResult struct {
+	Steps     []StepResult `json:"steps"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Skipped   int          `json:"skipped"`
+}
+
+// PlanExecutor runs plan.execute intents, routing each step through the
+// gateway like a standalone intent
+type PlanExecutor struct {
+	gw *Gateway
+}
+
+// NewPlanExecutor creates a plan executor for the gateway
+func NewPlanExecutor(gw *Gateway) *PlanExecutor {
+	return &PlanExecutor{gw: gw}
+}
+
+func (p *PlanExecutor) Name() string {
+	return "plan"
+}
+
+func (p *PlanExecutor) SupportedActions() []string {
+	return []string{"plan.execute"}
+}
+
+func (p *PlanExecutor) Needs() sandbox.Needs {
+	return sandbox.Needs{} // steps run through their own executors
+}
+
+func (p *PlanExecutor) Execute(ctx context.Context, i *intent.Intent) (*ExecutionResult, error) {
+	result := &ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "plan",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	if i.IntentType != "plan.execute" {
+		result.Fail(agenterrors.UnsupportedAction(i.IntentType))
+		return result, nil
+	}
+
+	var params struct {
+		Steps         []PlanStep `param:"steps,required"`
+		StopOnFailure bool       `param:"stop_on_failure"`
+	}
+	if err := i.DecodeParams(&params); err != nil {
+		result.Fail(err)
+		return result, nil
+	}
+
+	steps, deps, gates, err := resolvePlan(params.Steps)
+	if err != nil {
+		result.Fail(agenterrors.Newf(agenterrors.InvalidParams, "invalid plan: %v", err))
+		return result, nil
+	}
+
+	plan := p.run(ctx, i, intent.Origin{Kind: intent.OriginPlan, ID: i.ID}, steps, deps, gates, params.StopOnFailure)
+	result.Success = plan.Failed == 0
+	if !result.Success {
+		result.Fail(agenterrors.Newf(agenterrors.PartialFailure, "%d of %d steps failed", plan.Failed, len(steps)))
+	}
+	result.Result = map[string]interface{}{
+		"steps":     plan.Steps,
+		"succeeded": plan.Succeeded,
+		"failed":    plan.Failed,
+		"skipped":   plan.Skipped,
+	}
+	return result, nil
+}
+
+func (p *PlanExecutor) IsAvailable() bool {
+	return p.gw != nil
+}
+
+func (p *PlanExecutor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"plan.execute": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"steps": {
+					"type": "array",
+					"items": {
+						"type": "object",
+						"properties": {
+							"id": {"type": "string"},
+							"intent_type": {"type": "string", "minLength": 1},
+							"target_module": {"type": "string"},
+							"parameters": {"type": "object"},
+							"requires_permission": {"type": "boolean"},
+							"depends_on": {"type": "array", "items": {"type": "string"}},
+							"when": {
+								"type": "object",
+								"properties": {
+									"step": {"type": "string", "minLength": 1},
+									"success": {"type": "boolean"},
+									"field": {"type": "string"}
+								},
+								"required": ["step"]
+							}
+						},
+						"required": ["intent_type"]
+					}
+				},
+				"stop_on_failure": {"type": "boolean"}
+			},
+			"required": ["steps"]
+		}`),
+	}
+}
+
+// resolvePlan assigns missing step IDs and returns, for each step, the steps
+// that must finish before it (deps) and the ones that must also have
+// succeeded (gates). It rejects unknown references, nested plans, and cycles.
+func resolvePlan(steps []PlanStep) ([]PlanStep, map[string][]string, map[string][]string, error) {
+	if len(steps) == 0 {
+		return nil, nil, nil, fmt.Errorf("plan has no steps")
+	}
+	if len(steps) > maxPlanSteps {
+		return nil, nil, nil, fmt.Errorf("plan has %d steps, more than %d", len(steps), maxPlanSteps)
+	}
+
+	graph := false
+	index := make(map[string]int, len(steps))
+	for n := range steps {
+		if steps[n].ID == "" {
+			steps[n].ID = fmt.Sprintf("step%d", n+1)
+		}
+		if _, dup := index[steps[n].ID]; dup {
+			return nil, nil, nil, fmt.Errorf("duplicate step id: %s", steps[n].ID)
+		}
+		if steps[n].IntentType == "plan.execute" {
+			return nil, nil, nil, fmt.Errorf("step %s: plans cannot be nested", steps[n].ID)
+		}
+		index[steps[n].ID] = n
+		if len(steps[n].DependsOn) > 0 {
+			graph = true
+		}
+	}
+
+	deps := make(map[string][]string, len(steps))
+	gates := make(map[string][]string, len(steps))
+	for n, s := range steps {
+		g := append([]string(nil), s.DependsOn...)
+		if s.When != nil && !contains(g, s.When.Step) {
+			g = append(g, s.When.Step)
+		}
+		d := append([]string(nil), g...)
+		if !graph && n > 0 && !contains(d, steps[n-1].ID) {
+			d = append(d, steps[n-1].ID)
+		}
+		for _, dep := range d {
+			if _, ok := index[dep]; !ok {
+				return nil, nil, nil, fmt.Errorf("step %s depends on unknown step %s", s.ID, dep)
+			}
+		}
+		deps[s.ID] = d
+		gates[s.ID] = g
+	}
+
+	// Depth-first search for cycles
+	state := make(map[string]int, len(steps)) // 0 unvisited, 1 visiting, 2 done
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch state[id] {
+		case 1:
+			return fmt.Errorf("dependency cycle through step %s", id)
+		case 2:
+			return nil
+		}
+		state[id] = 1
+		for _, dep := range deps[id] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[id] = 2
+		return nil
+	}
+	for _, s := range steps {
+		if err := visit(s.ID); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return steps, deps, gates, nil
+}
+
+// run executes the plan in waves of steps whose dependencies are finished.
+// Steps extend the parent's provenance with origin.
+func (p *PlanExecutor) run(ctx context.Context, parent *intent.Intent, origin intent.Origin, steps []PlanStep, deps, gates map[string][]string, stopOnFailure bool) PlanResult {
+	results := make(map[string]*StepResult, len(steps))
+	failed := false
+
+	for len(results) < len(steps) {
+		var ready []PlanStep
+		for _, s := range steps {
+			if results[s.ID] != nil {
+				continue
+			}
+			done := true
+			for _, dep := range deps[s.ID] {
+				if results[dep] == nil {
+					done = false
+					break
+				}
+			}
+			if done {
+				ready = append(ready, s)
+			}
+		}
+
+		if len(ready) == 0 {
+			break // unreachable after the cycle check, but never spin
+		}
+
+		// Decide skips before starting anything, since running steps write results
+		var runnable []PlanStep
+		for _, s := range ready {
+			if reason := skipReason(ctx, s, gates[s.ID], results, failed && stopOnFailure); reason != "" {
+				results[s.ID] = &StepResult{ID: s.ID, Status: StepSkipped, Reason: reason}
+			} else {
+				runnable = append(runnable, s)
+			}
+		}
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		for _, s := range runnable {
+			wg.Add(1)
+			go func(s PlanStep) {
+				defer wg.Done()
+				r := p.gw.route(ctx, stepIntent(parent, origin, s))
+				status := StepSucceeded
+				if !r.Success {
+					status = StepFailed
+				}
+				p.gw.logger.InfoContext(ctx, "Plan step finished", "plan_id", parent.ID, "step", s.ID, "status", status)
+
+				mu.Lock()
+				results[s.ID] = &StepResult{ID: s.ID, Status: status, Result: r}
+				mu.Unlock()
+			}(s)
+		}
+		wg.Wait()
+
+		for _, s := range ready {
+			if results[s.ID].Status == StepFailed {
+				failed = true
+			}
+		}
+	}
+
+	var plan PlanResult
+	for _, s := range steps {
+		r := results[s.ID]
+		plan.Steps = append(plan.Steps, *r)
+		switch r.Status {
+		case StepSucceeded:
+			plan.Succeeded++
+		case StepFailed:
+			plan.Failed++
+		case StepSkipped:
+			plan.Skipped++
+		}
+	}
+	return plan
+}
+
+// skipReason explains why a step should not run, or returns ""
+func skipReason(ctx context.Context, s PlanStep, gates []string, results map[string]*StepResult, stopped bool) string {
+	if ctx.Err() != nil {
+		return "plan cancelled"
+	}
+	if stopped {
+		return "an earlier step failed"
+	}
+
+	for _, dep := range gates {
+		r := results[dep]
+		// A failure branch explicitly runs after its step fails
+		if s.When != nil && s.When.Step == dep && s.When.Success != nil && !*s.When.Success {
+			continue
+		}
+		if r.Status != StepSucceeded {
+			return fmt.Sprintf("step %s %s", dep, r.Status)
+		}
+	}
+
+	if s.When != nil && !conditionMet(s.When, results[s.When.Step]) {
+		return fmt.Sprintf("condition on step %s not met", s.When.Step)
+	}
+	return ""
+}
+
+func conditionMet(c *StepCondition, r *StepResult) bool {
+	wantSuccess := c.Success == nil || *c.Success
+	if (r.Status == StepSucceeded) != wantSuccess {
+		return false
+	}
+	if c.Field == "" {
+		return true
+	}
+	if r.Result == nil {
+		return false
+	}
+	value, ok := lookupField(r.Result.Result, c.Field)
+	return ok && sameValue(value, c.Equals)
+}
+
+// lookupField follows a dotted path through nested result maps
+func lookupField(m map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = m
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = obj[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// sameValue compares a result value with a condition value, treating all
+// numeric types alike since results are built in Go but conditions arrive
+// through a codec
+func sameValue(a, b interface{}) bool {
+	if fa, ok := number(a); ok {
+		fb, ok := number(b)
+		return ok && fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// stepIntent derives a sub-intent that inherits the plan's identity
+func stepIntent(parent *intent.Intent, origin intent.Origin, s PlanStep) *intent.Intent {
+	module := s.TargetModule
+	if module == "" {
+		module, _, _ = strings.Cut(s.IntentType, ".")
+	}
+	params := s.Parameters
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+	// Steps stay traceable to their plan even when it carries no correlation ID
+	correlation := parent.CorrelationID
+	if correlation == "" {
+		correlation = parent.ID
+	}
+	return &intent.Intent{
+		ID:                 parent.ID + "/" + s.ID,
+		IntentType:         s.IntentType,
+		Confidence:         parent.Confidence,
+		Parameters:         params,
+		Reasoning:          parent.Reasoning,
+		RequiresPermission: parent.RequiresPermission || s.RequiresPermission,
+		TargetModule:       &module,
+		CreatedAt:          parent.CreatedAt,
+		CorrelationID:      correlation,
+		SessionID:          parent.SessionID,
+		GuestToken:         parent.GuestToken,
+		SpeakerID:          parent.SpeakerID,
+		SpeakerConfidence:  parent.SpeakerConfidence,
+		ApprovalCode:       parent.ApprovalCode,
+		Provenance:         append(slices.Clip(parent.Provenance), origin),
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+package gateway
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// stepRecorder runs step.ok and step.fail intents, recording the order
+// steps started in
+type stepRecorder struct {
+	mu      sync.Mutex
+	started []string
+}
+
+func (r *stepRecorder) Name() string               { return "step" }
+func (r *stepRecorder) SupportedActions() []string { return []string{"step.ok", "step.fail"} }
+func (r *stepRecorder) IsAvailable() bool          { return true }
+
+func (r *stepRecorder) Execute(ctx context.Context, i *intent.Intent) (*ExecutionResult, error) {
+	_, step, _ := strings.Cut(i.ID, "/")
+	r.mu.Lock()
+	r.started = append(r.started, step)
+	r.mu.Unlock()
+	result := &ExecutionResult{IntentID: i.ID, Module: "step", Action: i.IntentType, Success: true}
+	if i.IntentType == "step.fail" {
+		result.Fail(agenterrors.New(agenterrors.Unavailable, "step failed"))
+	}
+	return result, nil
+}
+
+func planGateway(t *testing.T) (*Gateway, *stepRecorder) {
+	t.Helper()
+	rec := &stepRecorder{}
+	gw := NewGateway(nil)
+	gw.RegisterExecutor(rec)
+	gw.RegisterExecutor(NewPlanExecutor(gw))
+	return gw, rec
+}
+
+// step is a plan step of intent type step.<kind>
+func step(id, kind string, dependsOn ...string) map[string]interface{} {
+	s := map[string]interface{}{"id": id, "intent_type": "step." + kind}
+	if len(dependsOn) > 0 {
+		s["depends_on"] = dependsOn
+	}
+	return s
+}
+
+// runPlan executes a plan, returning its result and step outcomes by ID
+func runPlan(t *testing.T, gw *Gateway, steps []interface{}, stopOnFailure bool) (*ExecutionResult, map[string]StepResult) {
+	t.Helper()
+	result := process(t, gw, intent.New("plan.execute").ID("plan").Param("steps", steps).Param("stop_on_failure", stopOnFailure))
+	outcomes := make(map[string]StepResult)
+	if planSteps, ok := result.Result["steps"].([]StepResult); ok {
+		for _, s := range planSteps {
+			outcomes[s.ID] = s
+		}
+	}
+	return result, outcomes
+}
+
+func TestPlanRejectsInvalidGraphs(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []interface{}
+		want  string
+	}{
+		{"cycle", []interface{}{step("a", "ok", "c"), step("b", "ok", "a"), step("c", "ok", "b")}, "dependency cycle"},
+		{"self dependency", []interface{}{step("a", "ok", "a")}, "dependency cycle"},
+		{"cycle through a condition", []interface{}{
+			step("a", "ok", "b"),
+			map[string]interface{}{"id": "b", "intent_type": "step.ok", "when": map[string]interface{}{"step": "a"}},
+		}, "dependency cycle"},
+		{"unknown step", []interface{}{step("a", "ok", "missing")}, "unknown step missing"},
+		{"duplicate id", []interface{}{step("a", "ok"), step("a", "ok")}, "duplicate step id"},
+		{"nested plan", []interface{}{map[string]interface{}{"intent_type": "plan.execute"}}, "cannot be nested"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gw, rec := planGateway(t)
+			result, _ := runPlan(t, gw, tt.steps, false)
+			if result.Success || result.ErrorCode != agenterrors.InvalidParams || !strings.Contains(result.Error, tt.want) {
+				t.Errorf("got success %v, %s %q; want INVALID_PARAMS mentioning %q", result.Success, result.ErrorCode, result.Error, tt.want)
+			}
+			if len(rec.started) > 0 {
+				t.Errorf("steps %v ran from a rejected plan", rec.started)
+			}
+		})
+	}
+}
+
+func TestPlanRunsStepsAfterTheirDependencies(t *testing.T) {
+	gw, rec := planGateway(t)
+	// Listed out of order: d needs b and c, which both need a
+	steps := []interface{}{step("d", "ok", "b", "c"), step("c", "ok", "a"), step("b", "ok", "a"), step("a", "ok")}
+	result, outcomes := runPlan(t, gw, steps, false)
+	if !result.Success {
+		t.Fatalf("failed: %s", result.Error)
+	}
+	if len(rec.started) != 4 {
+		t.Fatalf("started %v, want all four steps", rec.started)
+	}
+	for _, s := range steps {
+		s := s.(map[string]interface{})
+		deps, _ := s["depends_on"].([]string)
+		for _, dep := range deps {
+			if slices.Index(rec.started, dep) > slices.Index(rec.started, s["id"].(string)) {
+				t.Errorf("%s started before its dependency %s: %v", s["id"], dep, rec.started)
+			}
+		}
+		if outcomes[s["id"].(string)].Status != StepSucceeded {
+			t.Errorf("step %s: %+v", s["id"], outcomes[s["id"].(string)])
+		}
+	}
+}
+
+func TestPlanRunsListedStepsInOrder(t *testing.T) {
+	gw, rec := planGateway(t)
+	result, _ := runPlan(t, gw, []interface{}{step("a", "ok"), step("b", "ok"), step("c", "ok")}, false)
+	if !result.Success || !slices.Equal(rec.started, []string{"a", "b", "c"}) {
+		t.Errorf("got success %v, order %v; want a, b, c", result.Success, rec.started)
+	}
+}
+
+func TestPlanSkipsDependentsOfFailedSteps(t *testing.T) {
+	gw, rec := planGateway(t)
+	// b fails: c depends on it directly and d through c, while e only
+	// needs a and the failure branch f runs because b failed
+	steps := []interface{}{
+		step("a", "ok"),
+		step("b", "fail", "a"),
+		step("c", "ok", "b"),
+		step("d", "ok", "c"),
+		step("e", "ok", "a"),
+		map[string]interface{}{"id": "f", "intent_type": "step.ok", "when": map[string]interface{}{"step": "b", "success": false}},
+		map[string]interface{}{"id": "g", "intent_type": "step.ok", "when": map[string]interface{}{"step": "b"}},
+	}
+	result, outcomes := runPlan(t, gw, steps, false)
+	if result.Success || result.ErrorCode != agenterrors.PartialFailure {
+		t.Errorf("got success %v, %s; want PARTIAL_FAILURE", result.Success, result.ErrorCode)
+	}
+
+	want := map[string]StepStatus{
+		"a": StepSucceeded,
+		"b": StepFailed,
+		"c": StepSkipped,
+		"d": StepSkipped,
+		"e": StepSucceeded,
+		"f": StepSucceeded,
+		"g": StepSkipped,
+	}
+	for id, status := range want {
+		if got := outcomes[id]; got.Status != status {
+			t.Errorf("step %s: %s (%s), want %s", id, got.Status, got.Reason, status)
+		}
+	}
+	if reason := outcomes["c"].Reason; reason != "step b failed" {
+		t.Errorf("step c skipped for %q, want the failure of b", reason)
+	}
+	if reason := outcomes["d"].Reason; reason != "step c skipped" {
+		t.Errorf("step d skipped for %q, want the skip of c", reason)
+	}
+	for _, id := range []string{"c", "d", "g"} {
+		if slices.Contains(rec.started, id) {
+			t.Errorf("skipped step %s ran", id)
+		}
+	}
+	if result.Result["failed"] != 1 || result.Result["skipped"] != 3 || result.Result["succeeded"] != 3 {
+		t.Errorf("counts: %v", result.Result)
+	}
+}
+
+func TestPlanStopOnFailure(t *testing.T) {
+	gw, rec := planGateway(t)
+	// Stopping skips even the failure branch e
+	steps := []interface{}{
+		step("a", "ok"),
+		step("b", "fail", "a"),
+		step("c", "ok", "b"),
+		map[string]interface{}{"id": "e", "intent_type": "step.ok", "when": map[string]interface{}{"step": "b", "success": false}},
+	}
+	_, outcomes := runPlan(t, gw, steps, true)
+	for _, id := range []string{"c", "e"} {
+		if outcomes[id].Status != StepSkipped || outcomes[id].Reason != "an earlier step failed" {
+			t.Errorf("step %s: %+v, want skipped after the failure", id, outcomes[id])
+		}
+	}
+	if !slices.Equal(rec.started, []string{"a", "b"}) {
+		t.Errorf("started %v, want a and b only", rec.started)
+	}
+}
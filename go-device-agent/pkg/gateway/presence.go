@@ -0,0 +1,61 @@
+package gateway
+
+import (
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/access"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// PresenceSource says who is home, e.g. the presence executor
+type PresenceSource interface {
+	// Home returns the people home now; known is false until the
+	// detectors have reported
+	Home() (people []string, known bool)
+}
+
+// PresencePolicy refuses intents that only make sense with someone home,
+// such as unlocking the front door, while nobody is
+type PresencePolicy struct {
+	// RequireHome lists intent types; ".*" suffixes match whole modules
+	RequireHome []string
+}
+
+// SetPresence sets who-is-home source and the policy checked against it
+func (g *Gateway) SetPresence(source PresenceSource, p PresencePolicy) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.presence = source
+	g.presencePolicy = p
+}
+
+// checkPresence applies the presence policy. An intent that needs someone
+// home fails with DENIED_BY_POLICY while nobody is, or while presence isn't
+// known yet; requires_permission doesn't override it.
+func (g *Gateway) checkPresence(i *intent.Intent) error {
+	g.mu.RLock()
+	source, policy := g.presence, g.presencePolicy
+	g.mu.RUnlock()
+	if !policy.requiresHome(i.IntentType) {
+		return nil
+	}
+	if source == nil {
+		return agenterrors.Newf(agenterrors.DeniedByPolicy, "%s needs someone home, and presence isn't tracked", i.IntentType)
+	}
+	people, known := source.Home()
+	switch {
+	case !known:
+		return agenterrors.Newf(agenterrors.DeniedByPolicy, "%s needs someone home, and presence isn't known yet", i.IntentType)
+	case len(people) == 0:
+		return agenterrors.Newf(agenterrors.DeniedByPolicy, "%s needs someone home, and nobody is", i.IntentType)
+	}
+	return nil
+}
+
+func (p PresencePolicy) requiresHome(intentType string) bool {
+	for _, pattern := range p.RequireHome {
+		if access.MatchIntentType(pattern, intentType) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package gateway
+
+import (
+	"github.com/vinod901/local-agent-core/go-device-agent/internal/wire"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// MarshalProto encodes the result as an agent.v1.ExecutionResult message
+func (r *ExecutionResult) MarshalProto() ([]byte, error) {
+	var b []byte
+	b = wire.AppendBool(b, 1, r.Success)
+	b = wire.AppendString(b, 2, r.IntentID)
+	b = wire.AppendString(b, 3, r.Module)
+	b = wire.AppendString(b, 4, r.Action)
+	b, err := wire.AppendStruct(b, 5, r.Result)
+	if err != nil {
+		return nil, err
+	}
+	b = wire.AppendString(b, 6, r.Error)
+	b = wire.AppendString(b, 7, r.Timestamp)
+	b = wire.AppendString(b, 8, r.CorrelationID)
+	b = wire.AppendString(b, 9, r.SessionID)
+	b = wire.AppendString(b, 10, string(r.ErrorCode))
+	b, err = wire.AppendStruct(b, 11, r.Adjusted)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range r.Provenance {
+		b = wire.AppendMessage(b, 12, o.MarshalProto())
+	}
+	if r.Receipt != nil {
+		b = wire.AppendMessage(b, 13, r.Receipt.MarshalProto())
+	}
+	b = wire.AppendString(b, 14, r.TraceID)
+	return b, nil
+}
+
+// MarshalProto encodes the receipt as an agent.v1.Receipt message
+func (r Receipt) MarshalProto() []byte {
+	var b []byte
+	b = wire.AppendString(b, 1, r.KeyID)
+	b = wire.AppendString(b, 2, r.IntentDigest)
+	b = wire.AppendString(b, 3, r.SignedAt)
+	b = wire.AppendString(b, 4, r.Signature)
+	return b
+}
+
+// UnmarshalProto decodes an agent.v1.Receipt message into the receipt
+func (r *Receipt) UnmarshalProto(data []byte) error {
+	*r = Receipt{}
+	return wire.Walk(data, func(f wire.Field) error {
+		switch f.Num {
+		case 1:
+			r.KeyID = f.String()
+		case 2:
+			r.IntentDigest = f.String()
+		case 3:
+			r.SignedAt = f.String()
+		case 4:
+			r.Signature = f.String()
+		}
+		return nil
+	})
+}
+
+// UnmarshalProto decodes an agent.v1.ExecutionResult message into the result
+func (r *ExecutionResult) UnmarshalProto(data []byte) error {
+	*r = ExecutionResult{}
+	return wire.Walk(data, func(f wire.Field) error {
+		var err error
+		switch f.Num {
+		case 1:
+			r.Success = f.Bool()
+		case 2:
+			r.IntentID = f.String()
+		case 3:
+			r.Module = f.String()
+		case 4:
+			r.Action = f.String()
+		case 5:
+			r.Result, err = f.Struct()
+		case 6:
+			r.Error = f.String()
+		case 7:
+			r.Timestamp = f.String()
+		case 8:
+			r.CorrelationID = f.String()
+		case 9:
+			r.SessionID = f.String()
+		case 10:
+			r.ErrorCode = agenterrors.Code(f.String())
+		case 11:
+			r.Adjusted, err = f.Struct()
+		case 12:
+			var o intent.Origin
+			err = o.UnmarshalProto(f.Bytes())
+			r.Provenance = append(r.Provenance, o)
+		case 13:
+			r.Receipt = &Receipt{}
+			err = r.Receipt.UnmarshalProto(f.Bytes())
+		case 14:
+			r.TraceID = f.String()
+		}
+		return err
+	})
+}
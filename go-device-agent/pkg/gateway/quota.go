@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/access"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/quota"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// SetQuotas counts intents that pass every other check against t's
+// quotas, refusing them with QUOTA_EXCEEDED once one is used up. A
+// "quota.exceeded" event is published the first time each quota refuses
+// an intent in an hour or day.
+func (g *Gateway) SetQuotas(t *quota.Tracker) {
+	t.OnExceeded = func(name, intentType string, resets time.Time) {
+		g.logger.Warn("Quota used up", "quota", name, "intent_type", intentType, "resets", resets.Format(time.RFC3339))
+		g.mu.RLock()
+		bus := g.bus
+		g.mu.RUnlock()
+		if bus != nil {
+			bus.Publish(events.Event{Type: "quota.exceeded", Source: "gateway", Data: map[string]interface{}{
+				"quota":       name,
+				"intent_type": intentType,
+				"resets":      resets.Format(time.RFC3339),
+			}})
+		}
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.quotas = t
+}
+
+// Quotas returns the quota tracker, or nil if none is set
+func (g *Gateway) Quotas() *quota.Tracker {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.quotas
+}
+
+// quotaTakenKey marks the context of the copies a broadcast or group
+// intent fans out to, which the intent itself was counted for
+type quotaTakenKey struct{}
+
+// checkQuota counts the intent against its quotas. quota.* intents are
+// never counted, so quotas can always be checked.
+func (g *Gateway) checkQuota(ctx context.Context, i *intent.Intent) error {
+	t := g.Quotas()
+	if t == nil || ctx.Value(quotaTakenKey{}) != nil || access.MatchIntentType("quota.*", i.IntentType) {
+		return nil
+	}
+	return t.Take(i.IntentType, time.Now())
+}
+
+// QuotaExecutor lets the agent core see how much of each quota is used,
+// so it can slow down before intents are refused
+type QuotaExecutor struct {
+	gw *Gateway
+}
+
+// NewQuotaExecutor creates a quota executor for the gateway
+func NewQuotaExecutor(gw *Gateway) *QuotaExecutor {
+	return &QuotaExecutor{gw: gw}
+}
+
+func (e *QuotaExecutor) Name() string {
+	return "quota"
+}
+
+func (e *QuotaExecutor) SupportedActions() []string {
+	return []string{"quota.status"}
+}
+
+func (e *QuotaExecutor) Needs() sandbox.Needs {
+	return sandbox.Needs{}
+}
+
+func (e *QuotaExecutor) Execute(ctx context.Context, i *intent.Intent) (*ExecutionResult, error) {
+	result := &ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "quota",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	if i.IntentType != "quota.status" {
+		return fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+	var params struct {
+		IntentType string `param:"intent_type"`
+	}
+	if err := i.DecodeParams(&params); err != nil {
+		return fail(err)
+	}
+	statuses := []quota.Status{}
+	if t := e.gw.Quotas(); t != nil {
+		statuses = t.Status(params.IntentType, time.Now())
+	}
+	result.Success = true
+	result.Result = map[string]interface{}{"quotas": statuses}
+	return result, nil
+}
+
+func (e *QuotaExecutor) IsAvailable() bool {
+	return e.gw.Quotas() != nil
+}
+
+func (e *QuotaExecutor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"quota.status": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"intent_type": {"type": "string", "minLength": 1}
+			}
+		}`),
+	}
+}
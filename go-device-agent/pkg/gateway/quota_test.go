@@ -0,0 +1,39 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/quota"
+)
+
+func TestQuotaCountsBroadcastOnce(t *testing.T) {
+	gw := NewGateway(nil)
+	gw.RegisterExecutor(&recorder{name: "kitchen", actions: []string{"light.off"}})
+	gw.RegisterExecutor(&recorder{name: "hall", actions: []string{"light.off"}})
+	tracker, err := quota.New(quota.Config{Quotas: []quota.Limit{{Name: "lights", IntentTypes: []string{"light.off"}, PerHour: 3}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw.SetQuotas(tracker)
+
+	result := process(t, gw, intent.New("light.off").Target(Broadcast))
+	if !result.Success {
+		t.Fatalf("broadcast failed: %s", result.Error)
+	}
+	if got := result.Result["succeeded"]; got != 2 {
+		t.Fatalf("broadcast reached %v executors, want 2", got)
+	}
+	status := tracker.Status("light.off", time.Now())
+	if len(status) != 1 || status[0].UsedHour != 1 {
+		t.Fatalf("quota status %+v, want 1 used", status)
+	}
+
+	// The two intents left in the hour still run
+	for range 2 {
+		if result := process(t, gw, intent.New("light.off").Target("kitchen")); !result.Success {
+			t.Fatalf("intent within the quota refused: %s", result.Error)
+		}
+	}
+}
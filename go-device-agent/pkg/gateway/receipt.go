@@ -0,0 +1,139 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/crypto"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// Receipt verification errors
+var (
+	ErrNoReceipt        = errors.New("result has no receipt")
+	ErrReceiptForIntent = errors.New("receipt is for a different intent")
+)
+
+// Receipt is the agent's signature over a result, proving which agent
+// performed which action and when. It is checked with VerifyReceipt.
+type Receipt struct {
+	// KeyID names the agent's identity key
+	KeyID string `json:"key_id"`
+
+	// IntentDigest is "sha256:" and the hex SHA-256 of the intent's
+	// signing payload, binding the result to the exact intent it answers
+	IntentDigest string `json:"intent_digest"`
+
+	// SignedAt is when the result was signed, in RFC 3339 with nanoseconds
+	SignedAt string `json:"signed_at"`
+
+	// Signature is the base64 Ed25519 signature over the result's
+	// ReceiptPayload
+	Signature string `json:"signature"`
+}
+
+// SetReceiptSigner signs every result with the agent's identity key. Nil
+// stops signing.
+func (g *Gateway) SetReceiptSigner(s *crypto.Signer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.signer = s
+}
+
+// ReceiptKey returns the public key receipts are signed with, or nil when
+// results aren't signed
+func (g *Gateway) ReceiptKey() *crypto.Key {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.signer == nil {
+		return nil
+	}
+	key := g.signer.PublicKey()
+	return &key
+}
+
+// ReceiptPayload returns the bytes a receipt's signature covers: the result,
+// receipt included, as canonical JSON with the signature removed. Redacted
+// fields are covered as redacted, since that is what was delivered.
+func (r *ExecutionResult) ReceiptPayload() ([]byte, error) {
+	unsigned := *r
+	if r.Receipt != nil {
+		receipt := *r.Receipt
+		receipt.Signature = ""
+		unsigned.Receipt = &receipt
+	}
+	return intent.CanonicalJSON(&unsigned)
+}
+
+// sign attaches a receipt when a signer is set
+func (g *Gateway) sign(ctx context.Context, i *intent.Intent, result *ExecutionResult) {
+	g.mu.RLock()
+	signer := g.signer
+	g.mu.RUnlock()
+	if signer == nil {
+		return
+	}
+
+	digest, err := intentDigest(i)
+	if err != nil {
+		g.logger.ErrorContext(ctx, "Failed to sign result", "error", err)
+		return
+	}
+	result.Receipt = &Receipt{
+		KeyID:        signer.KeyID(),
+		IntentDigest: digest,
+		SignedAt:     time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	payload, err := result.ReceiptPayload()
+	if err != nil {
+		result.Receipt = nil
+		g.logger.ErrorContext(ctx, "Failed to sign result", "error", err)
+		return
+	}
+	result.Receipt.Signature = base64.StdEncoding.EncodeToString(signer.Sign(payload))
+}
+
+func intentDigest(i *intent.Intent) (string, error) {
+	payload, err := i.SigningPayload()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(payload)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// VerifyReceipt checks a result's receipt against the verifier's keys as of
+// when it was signed, so receipts stay checkable after a key is retired.
+// When i is not nil the receipt must also be for that intent.
+func VerifyReceipt(v *crypto.Verifier, r *ExecutionResult, i *intent.Intent) error {
+	if r.Receipt == nil {
+		return ErrNoReceipt
+	}
+	signedAt, err := time.Parse(time.RFC3339Nano, r.Receipt.SignedAt)
+	if err != nil {
+		return fmt.Errorf("%w: invalid signed_at", crypto.ErrBadSignature)
+	}
+	sig, err := base64.StdEncoding.DecodeString(r.Receipt.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: signature is not base64", crypto.ErrBadSignature)
+	}
+	if i != nil {
+		digest, err := intentDigest(i)
+		if err != nil {
+			return err
+		}
+		if digest != r.Receipt.IntentDigest || i.ID != r.IntentID {
+			return ErrReceiptForIntent
+		}
+	}
+	payload, err := r.ReceiptPayload()
+	if err != nil {
+		return err
+	}
+	return v.VerifyAt(r.Receipt.KeyID, payload, sig, signedAt)
+}
@@ -0,0 +1,194 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/access"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// Redacted replaces values removed by a Redaction
+const Redacted = "[REDACTED]"
+
+// Redaction rewrites result fields before results leave the gateway or
+// reach the event bus. Keys and patterns apply at any depth of the result's
+// maps and lists, and Pattern also covers the error message.
+type Redaction struct {
+	// IntentTypes the rule applies to; empty for every intent. Types may
+	// end in ".*" to match a whole module.
+	IntentTypes []string `json:"intent_types,omitempty"`
+
+	// Keys whose values are replaced entirely, compared case-insensitively
+	// (e.g. "token", "path")
+	Keys []string `json:"keys,omitempty"`
+
+	// Pattern is a regular expression; matches within string values are
+	// replaced (e.g. credentials in camera URLs)
+	Pattern string `json:"pattern,omitempty"`
+
+	// MaxLength truncates longer string values (0 for no limit)
+	MaxLength int `json:"max_length,omitempty"`
+}
+
+type redaction struct {
+	Redaction
+	re *regexp.Regexp
+}
+
+// LoadRedactions reads a JSON array of Redaction rules
+func LoadRedactions(path string) ([]Redaction, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Redaction
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid redactions file %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// AddRedaction appends a rule; rules apply in the order they were added
+func (g *Gateway) AddRedaction(r Redaction) error {
+	compiled, err := compileRedaction(r)
+	if err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.redactions = append(g.redactions, compiled)
+	return nil
+}
+
+// SetRedactions replaces every rule with rules. If any rule is invalid the
+// rules in use are kept.
+func (g *Gateway) SetRedactions(rules []Redaction) error {
+	compiled := make([]redaction, 0, len(rules))
+	for _, r := range rules {
+		c, err := compileRedaction(r)
+		if err != nil {
+			return err
+		}
+		compiled = append(compiled, c)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.redactions = compiled
+	return nil
+}
+
+func compileRedaction(r Redaction) (redaction, error) {
+	compiled := redaction{Redaction: r}
+	if r.Pattern != "" {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return redaction{}, fmt.Errorf("invalid redaction pattern: %w", err)
+		}
+		compiled.re = re
+	}
+	if len(r.Keys) == 0 && compiled.re == nil && r.MaxLength <= 0 {
+		return redaction{}, errors.New("redaction needs keys, a pattern, or max_length")
+	}
+	return compiled, nil
+}
+
+// redact applies the matching rules to the result. Maps and lists are
+// copied rather than edited, since executors may hand out their own state.
+func (g *Gateway) redact(i *intent.Intent, result *ExecutionResult) {
+	g.mu.RLock()
+	rules := g.redactions
+	g.mu.RUnlock()
+
+	for _, r := range rules {
+		if !r.matches(i.IntentType) {
+			continue
+		}
+		if result.Result != nil {
+			result.Result = r.object(result.Result)
+		}
+		if r.re != nil && result.Error != "" {
+			result.Error = r.re.ReplaceAllLiteralString(result.Error, Redacted)
+		}
+	}
+}
+
+func (r redaction) matches(intentType string) bool {
+	if len(r.IntentTypes) == 0 {
+		return true
+	}
+	for _, pattern := range r.IntentTypes {
+		if access.MatchIntentType(pattern, intentType) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r redaction) object(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if r.redactsKey(k) {
+			out[k] = Redacted
+			continue
+		}
+		out[k] = r.value(v)
+	}
+	return out
+}
+
+func (r redaction) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return r.object(v)
+	case []map[string]interface{}:
+		out := make([]map[string]interface{}, len(v))
+		for n, m := range v {
+			out[n] = r.object(m)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for n, e := range v {
+			out[n] = r.value(e)
+		}
+		return out
+	case []string:
+		out := make([]string, len(v))
+		for n, s := range v {
+			out[n] = r.text(s)
+		}
+		return out
+	case string:
+		return r.text(v)
+	}
+	return v
+}
+
+func (r redaction) text(s string) string {
+	if r.re != nil {
+		s = r.re.ReplaceAllLiteralString(s, Redacted)
+	}
+	if r.MaxLength > 0 && len(s) > r.MaxLength {
+		cut := r.MaxLength
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		s = fmt.Sprintf("%s…[%d bytes truncated]", s[:cut], len(s)-cut)
+	}
+	return s
+}
+
+func (r redaction) redactsKey(k string) bool {
+	for _, key := range r.Keys {
+		if strings.EqualFold(key, k) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+package gateway
+
+import (
+	"context"
+	"maps"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/access"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// Limit clamps a numeric parameter of matching intents into [Min, Max]
+// rather than refusing them. Intent types may end in ".*" to match a whole
+// module, or be "*" for every intent. Executors hold values they derive
+// for the parameter, such as the level a relative volume change reaches,
+// to the same limit with SafetyClamp.
+type Limit struct {
+	IntentTypes []string `json:"intent_types"`
+	Param       string   `json:"param"`
+	Min         *float64 `json:"min,omitempty"`
+	Max         *float64 `json:"max,omitempty"`
+}
+
+// SafetyPolicy restricts what intents may do while safety mode is on
+type SafetyPolicy struct {
+	// Name is reported with every adjustment (e.g. "child-safety")
+	Name string `json:"name"`
+
+	// Limits clamp parameters; the result's adjusted field tells the agent
+	// core what was actually applied
+	Limits []Limit `json:"limits,omitempty"`
+
+	// Deny lists intent types refused outright with DENIED_BY_POLICY
+	Deny []string `json:"deny,omitempty"`
+}
+
+// ChildSafetyPolicy caps volume at 40%, thermostats at 23°C, and refuses
+// purchases. The audio and media executors set volume with a level, or
+// change it relative to the current one, so theirs is capped by level.
+func ChildSafetyPolicy() SafetyPolicy {
+	maxVolume, maxTemperature := 40.0, 23.0
+	return SafetyPolicy{
+		Name: "child-safety",
+		Limits: []Limit{
+			{IntentTypes: []string{"*"}, Param: "volume", Max: &maxVolume},
+			{IntentTypes: []string{"audio.volume", "media.volume"}, Param: "level", Max: &maxVolume},
+			{IntentTypes: []string{"device.control", "thermostat.*", "climate.*"}, Param: "temperature", Max: &maxTemperature},
+		},
+		Deny: []string{"purchase.*", "payment.*", "shopping.order", "shopping.checkout"},
+	}
+}
+
+// SetSafetyPolicy sets the policy applied while safety mode is on
+func (g *Gateway) SetSafetyPolicy(p SafetyPolicy) {
+	g.mu.Lock()
+	g.safety = p
+	g.mu.Unlock()
+	g.capabilitiesChanged("safety.policy")
+}
+
+// SafetyPolicy returns the policy set with SetSafetyPolicy
+func (g *Gateway) SafetyPolicy() SafetyPolicy {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.safety
+}
+
+// SetSafetyMode turns the safety policy on or off, publishing a
+// "safety.enabled" or "safety.disabled" event when it changes
+func (g *Gateway) SetSafetyMode(on bool) {
+	g.mu.Lock()
+	changed := g.safetyOn != on
+	g.safetyOn = on
+	name := g.safety.Name
+	bus := g.bus
+	g.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	state := "disabled"
+	if on {
+		state = "enabled"
+	}
+	g.logger.Info("Safety mode "+state, "policy", name)
+	if bus != nil {
+		bus.Publish(events.Event{Type: "safety." + state, Source: "gateway", Data: map[string]interface{}{"policy": name}})
+	}
+	g.capabilitiesChanged("safety." + state)
+}
+
+// SafetyMode reports whether the safety policy is on
+func (g *Gateway) SafetyMode() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.safetyOn
+}
+
+// denies reports whether the policy refuses intentType outright
+func (p SafetyPolicy) denies(intentType string) bool {
+	for _, pattern := range p.Deny {
+		if access.MatchIntentType(pattern, intentType) {
+			return true
+		}
+	}
+	return false
+}
+
+// safetyKey carries the limits the safety policy puts on the intent being
+// run, for SafetyClamp
+type safetyKey struct{}
+
+// safetyLimits are the limits of a policy matching one intent
+type safetyLimits struct {
+	policy string
+	limits []Limit
+}
+
+// applySafety refuses denied intents and clamps limited parameters in
+// place, returning the context to run the intent in, which carries the
+// limits for SafetyClamp, and the adjustments keyed by parameter name
+func (g *Gateway) applySafety(ctx context.Context, i *intent.Intent) (context.Context, map[string]interface{}, error) {
+	g.mu.RLock()
+	policy, on := g.safety, g.safetyOn
+	g.mu.RUnlock()
+	// Always set, so a plan step isn't held to the limits of its plan
+	matched := &safetyLimits{policy: policy.Name}
+	ctx = context.WithValue(ctx, safetyKey{}, matched)
+	if !on {
+		return ctx, nil, nil
+	}
+
+	if policy.denies(i.IntentType) {
+		return ctx, nil, agenterrors.Newf(agenterrors.DeniedByPolicy, "%s is not allowed in %s mode", i.IntentType, policy.Name)
+	}
+
+	var adjusted map[string]interface{}
+	for _, limit := range policy.Limits {
+		if !limit.matches(i.IntentType) {
+			continue
+		}
+		matched.limits = append(matched.limits, limit)
+		if _, ok := i.Parameters[limit.Param]; !ok {
+			continue
+		}
+		// Coerced as executors read it, so "80" can't slip past as a string
+		requested, err := i.FloatParam(limit.Param)
+		if err != nil {
+			return ctx, nil, err
+		}
+		applied, adjustment := limit.clamp(requested, policy.Name)
+		if adjustment == nil {
+			continue
+		}
+
+		if adjusted == nil {
+			adjusted = make(map[string]interface{})
+			// Don't write through to a map the caller may still hold
+			i.Parameters = maps.Clone(i.Parameters)
+		}
+		i.Parameters[limit.Param] = applied
+		adjusted[limit.Param] = adjustment
+		g.logger.InfoContext(ctx, "Clamped parameter", "param", limit.Param, "requested", requested, "applied", applied, "policy", policy.Name)
+	}
+	return ctx, adjusted, nil
+}
+
+// SafetyClamp holds a value an executor derived for param, such as the
+// level a relative volume change reaches, to the limits the safety policy
+// puts on param for the intent running in ctx. It returns the value to
+// apply and, when that isn't v, the adjustment to report in the result's
+// Adjusted field under param.
+func SafetyClamp(ctx context.Context, param string, v float64) (float64, map[string]interface{}) {
+	matched, _ := ctx.Value(safetyKey{}).(*safetyLimits)
+	if matched == nil {
+		return v, nil
+	}
+	applied := v
+	for _, limit := range matched.limits {
+		if limit.Param == param {
+			applied, _ = limit.clamp(applied, matched.policy)
+		}
+	}
+	if applied == v {
+		return v, nil
+	}
+	return applied, map[string]interface{}{
+		"requested": v,
+		"applied":   applied,
+		"policy":    matched.policy,
+	}
+}
+
+// clamp holds v within the limit, returning the adjustment made, or nil
+// if v is within it
+func (l Limit) clamp(v float64, policy string) (float64, map[string]interface{}) {
+	applied := v
+	if l.Max != nil {
+		applied = min(applied, *l.Max)
+	}
+	if l.Min != nil {
+		applied = max(applied, *l.Min)
+	}
+	if applied == v {
+		return v, nil
+	}
+	return applied, map[string]interface{}{
+		"requested": v,
+		"applied":   applied,
+		"policy":    policy,
+	}
+}
+
+func (l Limit) matches(intentType string) bool {
+	for _, pattern := range l.IntentTypes {
+		if access.MatchIntentType(pattern, intentType) {
+			return true
+		}
+	}
+	return false
+}
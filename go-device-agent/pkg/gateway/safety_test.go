@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// recorder is an executor that succeeds, keeping the parameters it ran with
+type recorder struct {
+	name    string
+	actions []string
+	params  map[string]interface{}
+}
+
+func (r *recorder) Name() string               { return r.name }
+func (r *recorder) SupportedActions() []string { return r.actions }
+func (r *recorder) IsAvailable() bool          { return true }
+
+func (r *recorder) Execute(ctx context.Context, i *intent.Intent) (*ExecutionResult, error) {
+	r.params = i.Parameters
+	return &ExecutionResult{Success: true, IntentID: i.ID, Module: r.name, Action: i.IntentType}, nil
+}
+
+// childSafeGateway returns a gateway in child-safety mode with rec
+// registered
+func childSafeGateway(t *testing.T, rec Executor) *Gateway {
+	t.Helper()
+	gw := NewGateway(nil)
+	gw.RegisterExecutor(rec)
+	gw.SetSafetyPolicy(ChildSafetyPolicy())
+	gw.SetSafetyMode(true)
+	return gw
+}
+
+func process(t *testing.T, gw *Gateway, b *intent.Builder) *ExecutionResult {
+	t.Helper()
+	data, err := b.Confidence(0.9).Reasoning("test").JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := gw.ProcessIntent(context.Background(), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestSafetyClampsNumericStrings(t *testing.T) {
+	rec := &recorder{name: "speaker", actions: []string{"speaker.play"}}
+	gw := childSafeGateway(t, rec)
+
+	result := process(t, gw, intent.New("speaker.play").Param("volume", "80"))
+	if !result.Success {
+		t.Fatalf("failed: %s", result.Error)
+	}
+	if got := rec.params["volume"]; got != 40.0 {
+		t.Errorf("executor got volume %v, want 40", got)
+	}
+	if result.Adjusted["volume"] == nil {
+		t.Error("result doesn't report the clamped volume")
+	}
+
+	rec.params = nil
+	result = process(t, gw, intent.New("speaker.play").Param("volume", "loud"))
+	if result.Success || result.ErrorCode != agenterrors.InvalidParams {
+		t.Errorf("non-numeric volume: got success %v, code %s; want INVALID_PARAMS", result.Success, result.ErrorCode)
+	}
+	if rec.params != nil {
+		t.Error("the executor ran with a non-numeric limited parameter")
+	}
+}
@@ -0,0 +1,43 @@
+package gateway
+
+import (
+	"maps"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+)
+
+// SetSandboxPolicy checks the needs executors declare against p when they
+// are registered, refusing those it doesn't allow. Executors registered
+// before it is set aren't checked.
+func (g *Gateway) SetSandboxPolicy(p *sandbox.Policy) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.sandbox = p
+}
+
+// ExecutorNeeds returns what each registered executor declared it needs;
+// executors that declare nothing are listed with no needs
+func (g *Gateway) ExecutorNeeds() map[string]sandbox.Needs {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return maps.Clone(g.needs)
+}
+
+// checkSandbox returns what an executor declares it needs, or why the
+// sandbox policy refuses it
+func (g *Gateway) checkSandbox(executor Executor) (sandbox.Needs, error) {
+	var needs sandbox.Needs
+	provider, declared := executor.(NeedsProvider)
+	if declared {
+		needs = provider.Needs()
+	}
+	g.mu.RLock()
+	policy := g.sandbox
+	g.mu.RUnlock()
+	if policy != nil {
+		if err := policy.Check(executor.Name(), needs, declared); err != nil {
+			return needs, err
+		}
+	}
+	return needs, nil
+}
@@ -0,0 +1,204 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Scene is a named set of intents activated together, such as "movie
+// night" dimming the lights, closing the blinds, and starting the
+// projector. Its steps are a plan, so they may use depends_on and when,
+// and may be any executor's actions except plans and other scenes.
+type Scene struct {
+	ID            string     `json:"id"`
+	Name          string     `json:"name,omitempty"`
+	Aliases       []string   `json:"aliases,omitempty"`
+	Description   string     `json:"description,omitempty"`
+	Steps         []PlanStep `json:"steps"`
+	StopOnFailure bool       `json:"stop_on_failure,omitempty"`
+}
+
+// matches reports whether the scene goes by name, ignoring case
+func (s *Scene) matches(name string) bool {
+	name = strings.TrimSpace(name)
+	return strings.EqualFold(s.ID, name) || strings.EqualFold(s.Name, name) ||
+		slices.ContainsFunc(s.Aliases, func(a string) bool { return strings.EqualFold(a, name) })
+}
+
+// LoadScenes reads a JSON array of scenes, checking each one's steps the
+// way plan.execute would
+func LoadScenes(path string) ([]Scene, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var scenes []Scene
+	if err := json.Unmarshal(data, &scenes); err != nil {
+		return nil, fmt.Errorf("invalid scenes file %s: %w", path, err)
+	}
+	owners := make(map[string]string)
+	for n := range scenes {
+		s := &scenes[n]
+		if s.ID == "" {
+			return nil, fmt.Errorf("scene %d in %s needs an id", n, path)
+		}
+		if s.Name == "" {
+			s.Name = s.ID
+		}
+		for _, name := range append([]string{s.ID, s.Name}, s.Aliases...) {
+			key := strings.ToLower(strings.TrimSpace(name))
+			if owner, ok := owners[key]; ok && owner != s.ID {
+				return nil, fmt.Errorf("scene %s in %s: %q also names scene %s", s.ID, path, name, owner)
+			}
+			owners[key] = s.ID
+		}
+		for _, step := range s.Steps {
+			if step.IntentType == "scene.activate" {
+				return nil, fmt.Errorf("scene %s in %s: scenes cannot activate other scenes", s.ID, path)
+			}
+		}
+		steps, _, _, err := resolvePlan(s.Steps)
+		if err != nil {
+			return nil, fmt.Errorf("scene %s in %s: %w", s.ID, path, err)
+		}
+		s.Steps = steps
+	}
+	return scenes, nil
+}
+
+// SceneExecutor activates configured scenes, running each scene's steps
+// through the gateway as a plan
+type SceneExecutor struct {
+	plans  *PlanExecutor
+	scenes []Scene
+}
+
+// NewSceneExecutor creates a scene executor for the gateway
+func NewSceneExecutor(gw *Gateway, scenes []Scene) *SceneExecutor {
+	return &SceneExecutor{plans: NewPlanExecutor(gw), scenes: scenes}
+}
+
+func (e *SceneExecutor) Name() string {
+	return "scene"
+}
+
+func (e *SceneExecutor) SupportedActions() []string {
+	return []string{"scene.activate", "scene.list"}
+}
+
+func (e *SceneExecutor) Needs() sandbox.Needs {
+	return sandbox.Needs{}
+}
+
+func (e *SceneExecutor) Execute(ctx context.Context, i *intent.Intent) (*ExecutionResult, error) {
+	result := &ExecutionResult{
+		IntentID:  i.ID,
+		Module:    "scene",
+		Action:    i.IntentType,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	fail := func(err error) (*ExecutionResult, error) {
+		result.Fail(err)
+		return result, nil
+	}
+
+	switch i.IntentType {
+	case "scene.activate":
+		var params struct {
+			Scene string `param:"scene,required"`
+		}
+		if err := i.DecodeParams(&params); err != nil {
+			return fail(err)
+		}
+		idx := slices.IndexFunc(e.scenes, func(s Scene) bool { return s.matches(params.Scene) })
+		if idx < 0 {
+			return fail(agenterrors.Newf(agenterrors.NotFound, "no scene named %q", params.Scene))
+		}
+		scene := e.scenes[idx]
+
+		// Each activation resolves its own copy, since resolving assigns
+		// step IDs in place
+		steps, deps, gates, err := resolvePlan(slices.Clone(scene.Steps))
+		if err != nil {
+			return fail(agenterrors.Newf(agenterrors.Internal, "scene %s: %v", scene.ID, err))
+		}
+		origin := intent.Origin{Kind: intent.OriginScene, ID: scene.ID, Name: scene.Name}
+		plan := e.plans.run(ctx, i, origin, steps, deps, gates, scene.StopOnFailure)
+		result.Success = plan.Failed == 0
+		if !result.Success {
+			result.Fail(agenterrors.Newf(agenterrors.PartialFailure, "%d of %d steps of scene %s failed", plan.Failed, len(steps), scene.Name))
+		}
+		result.Result = map[string]interface{}{
+			"scene":     scene.ID,
+			"name":      scene.Name,
+			"steps":     plan.Steps,
+			"succeeded": plan.Succeeded,
+			"failed":    plan.Failed,
+			"skipped":   plan.Skipped,
+		}
+
+	case "scene.list":
+		scenes := make([]map[string]interface{}, 0, len(e.scenes))
+		for _, s := range e.scenes {
+			var types []string
+			for _, step := range s.Steps {
+				if !slices.Contains(types, step.IntentType) {
+					types = append(types, step.IntentType)
+				}
+			}
+			entry := map[string]interface{}{
+				"id":           s.ID,
+				"name":         s.Name,
+				"steps":        len(s.Steps),
+				"intent_types": types,
+			}
+			if len(s.Aliases) > 0 {
+				entry["aliases"] = s.Aliases
+			}
+			if s.Description != "" {
+				entry["description"] = s.Description
+			}
+			scenes = append(scenes, entry)
+		}
+		result.Success = true
+		result.Result = map[string]interface{}{
+			"scenes": scenes,
+			"count":  len(scenes),
+		}
+
+	default:
+		return fail(agenterrors.UnsupportedAction(i.IntentType))
+	}
+	return result, nil
+}
+
+func (e *SceneExecutor) IsAvailable() bool {
+	return e.plans.gw != nil
+}
+
+func (e *SceneExecutor) ParameterSchemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"scene.activate": schema.MustParse(`{
+			"type": "object",
+			"properties": {
+				"scene": {"type": "string", "minLength": 1}
+			},
+			"required": ["scene"]
+		}`),
+		"scene.list": schema.MustParse(`{
+			"type": "object",
+			"properties": {}
+		}`),
+	}
+}
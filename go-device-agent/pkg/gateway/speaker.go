@@ -0,0 +1,95 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/access"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// SpeakerIdentifier attaches a voice profile to intents that arrive without
+// one, e.g. a local speaker-ID model matching the utterance the core heard
+type SpeakerIdentifier interface {
+	// Identify returns the speaker's profile ID and confidence, or an empty
+	// ID when the voice is not recognized
+	Identify(ctx context.Context, i *intent.Intent) (speakerID string, confidence float32, err error)
+}
+
+// SpeakerPolicy makes sensitive intents from unrecognized voices ask for
+// confirmation: unless requires_permission is set they fail with
+// CONFIRMATION_REQUIRED when the speaker is unknown or identified with less
+// than MinConfidence
+type SpeakerPolicy struct {
+	// Sensitive intent types; ".*" suffixes match whole modules
+	Sensitive []string
+
+	// MinConfidence a speaker needs to be recognized (e.g. 0.8)
+	MinConfidence float32
+
+	// Known profiles; when set, other speaker IDs count as unrecognized
+	Known []string
+}
+
+// SetSpeakerIdentifier identifies the speaker of intents without a speaker_id
+func (g *Gateway) SetSpeakerIdentifier(id SpeakerIdentifier) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.speakers = id
+}
+
+// SetSpeakerPolicy sets the confirmation policy for unrecognized voices
+func (g *Gateway) SetSpeakerPolicy(p SpeakerPolicy) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.speakerPolicy = p
+}
+
+// identifySpeaker fills in the speaker of an intent that names none
+func (g *Gateway) identifySpeaker(ctx context.Context, i *intent.Intent) {
+	g.mu.RLock()
+	identifier := g.speakers
+	g.mu.RUnlock()
+	if identifier == nil || i.SpeakerID != "" {
+		return
+	}
+
+	id, confidence, err := identifier.Identify(ctx, i)
+	if err != nil {
+		g.logger.WarnContext(ctx, "Speaker identification failed", "error", err)
+		return
+	}
+	i.SpeakerID = id
+	i.SpeakerConfidence = min(max(confidence, 0), 1)
+}
+
+// checkSpeaker applies the speaker policy
+func (g *Gateway) checkSpeaker(i *intent.Intent) error {
+	g.mu.RLock()
+	policy := g.speakerPolicy
+	g.mu.RUnlock()
+	if i.RequiresPermission || !policy.sensitive(i.IntentType) {
+		return nil
+	}
+
+	switch {
+	case i.SpeakerID == "":
+		return agenterrors.Newf(agenterrors.ConfirmationRequired, "%s from an unrecognized voice needs confirmation", i.IntentType)
+	case len(policy.Known) > 0 && !contains(policy.Known, i.SpeakerID):
+		return agenterrors.Newf(agenterrors.ConfirmationRequired, "%s from unknown speaker %s needs confirmation", i.IntentType, i.SpeakerID)
+	case i.SpeakerConfidence < policy.MinConfidence:
+		return agenterrors.Newf(agenterrors.ConfirmationRequired,
+			"%s needs confirmation: speaker %s identified with confidence %.2f, below %.2f",
+			i.IntentType, i.SpeakerID, i.SpeakerConfidence, policy.MinConfidence)
+	}
+	return nil
+}
+
+func (p SpeakerPolicy) sensitive(intentType string) bool {
+	for _, pattern := range p.Sensitive {
+		if access.MatchIntentType(pattern, intentType) {
+			return true
+		}
+	}
+	return false
+}
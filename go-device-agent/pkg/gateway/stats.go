@@ -0,0 +1,236 @@
+package gateway
+
+import (
+	"context"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// Defaults of LatencyPolicy
+const (
+	DefaultLatencyWindow     = 100
+	DefaultLatencyMinSamples = 20
+)
+
+// LatencyPolicy marks executors degraded while the p95 of their recent
+// executions is over a limit. A degraded executor only takes intents the
+// user asked for directly, so automations don't pile onto a slow device
+// while the user's own requests still measure it recovering.
+type LatencyPolicy struct {
+	// P95 is the limit of every executor without one in Executors; zero
+	// leaves them unlimited
+	P95       time.Duration
+	Executors map[string]time.Duration
+
+	// Window is how many of each executor's latest executions the
+	// percentiles are taken over, and MinSamples how many it needs before
+	// it can be marked degraded
+	Window     int
+	MinSamples int
+}
+
+func (p LatencyPolicy) limit(executor string) time.Duration {
+	if limit, ok := p.Executors[executor]; ok {
+		return limit
+	}
+	return p.P95
+}
+
+// ExecutorStats is how an executor has been doing: outcomes since the
+// agent started, and latency percentiles over its latest executions
+type ExecutorStats struct {
+	Executions  int64   `json:"executions"`
+	Failures    int64   `json:"failures"`
+	SuccessRate float64 `json:"success_rate"`
+
+	Samples int     `json:"samples"`
+	P50Ms   float64 `json:"p50_ms"`
+	P95Ms   float64 `json:"p95_ms"`
+	P99Ms   float64 `json:"p99_ms"`
+
+	LastError     string           `json:"last_error,omitempty"`
+	LastErrorCode agenterrors.Code `json:"last_error_code,omitempty"`
+	LastErrorAt   time.Time        `json:"last_error_at,omitzero"`
+
+	// Degraded is set while p95 is over the executor's limit
+	Degraded      bool      `json:"degraded,omitempty"`
+	DegradedSince time.Time `json:"degraded_since,omitzero"`
+	P95LimitMs    float64   `json:"p95_limit_ms,omitempty"`
+}
+
+// executorStats keeps an executor's latest latencies in a ring
+type executorStats struct {
+	ExecutorStats
+	latencies []time.Duration
+	next      int
+}
+
+// SetLatencyPolicy sets when executors count as degraded, rechecking each
+// executor against it
+func (g *Gateway) SetLatencyPolicy(p LatencyPolicy) {
+	if p.Window <= 0 {
+		p.Window = DefaultLatencyWindow
+	}
+	if p.MinSamples <= 0 {
+		p.MinSamples = DefaultLatencyMinSamples
+	}
+	g.statsMu.Lock()
+	g.latency = p
+	var changed []string
+	for name, s := range g.stats {
+		if len(s.latencies) > p.Window {
+			s.latencies = s.latencies[len(s.latencies)-p.Window:]
+			s.next = 0
+		}
+		if g.updateDegraded(name, s) {
+			changed = append(changed, name)
+		}
+	}
+	g.statsMu.Unlock()
+	for _, name := range changed {
+		g.degradedChanged(name)
+	}
+}
+
+// Stats returns each executor's stats, for executors that have run
+func (g *Gateway) Stats() map[string]ExecutorStats {
+	g.statsMu.Lock()
+	defer g.statsMu.Unlock()
+	stats := make(map[string]ExecutorStats, len(g.stats))
+	for name, s := range g.stats {
+		stats[name] = s.snapshot()
+	}
+	return stats
+}
+
+// IsExecutorDegraded reports whether an executor's p95 is over its limit
+func (g *Gateway) IsExecutorDegraded(name string) bool {
+	g.statsMu.Lock()
+	defer g.statsMu.Unlock()
+	s, ok := g.stats[name]
+	return ok && s.Degraded
+}
+
+// recordExecution counts one run of an executor, marking it degraded or
+// recovered when that changes its p95
+func (g *Gateway) recordExecution(ctx context.Context, executor string, elapsed time.Duration, result *ExecutionResult, err error) {
+	g.statsMu.Lock()
+	s, ok := g.stats[executor]
+	if !ok {
+		s = &executorStats{}
+		g.stats[executor] = s
+	}
+	s.Executions++
+	switch {
+	case err != nil:
+		s.fail(err.Error(), agenterrors.CodeOf(err))
+	case result == nil:
+		s.fail("executor returned no result", agenterrors.Internal)
+	case !result.Success:
+		s.fail(result.Error, result.ErrorCode)
+	}
+	if len(s.latencies) < g.latency.Window {
+		s.latencies = append(s.latencies, elapsed)
+	} else {
+		s.latencies[s.next] = elapsed
+		s.next = (s.next + 1) % len(s.latencies)
+	}
+	changed := g.updateDegraded(executor, s)
+	degraded, p95 := s.Degraded, s.P95Ms
+	g.statsMu.Unlock()
+
+	if changed {
+		if degraded {
+			g.logger.WarnContext(ctx, "Executor degraded", "executor", executor, "p95_ms", p95)
+		} else {
+			g.logger.InfoContext(ctx, "Executor recovered", "executor", executor, "p95_ms", p95)
+		}
+		g.degradedChanged(executor)
+	}
+}
+
+func (s *executorStats) fail(msg string, code agenterrors.Code) {
+	s.Failures++
+	s.LastError = msg
+	s.LastErrorCode = code
+	s.LastErrorAt = time.Now().UTC()
+}
+
+// updateDegraded recomputes the percentiles and reports whether the
+// executor became or stopped being degraded. The caller holds statsMu.
+func (g *Gateway) updateDegraded(executor string, s *executorStats) bool {
+	sorted := slices.Sorted(slices.Values(s.latencies))
+	p95 := percentile(sorted, 0.95)
+	s.P50Ms = ms(percentile(sorted, 0.50))
+	s.P95Ms = ms(p95)
+	s.P99Ms = ms(percentile(sorted, 0.99))
+
+	limit := g.latency.limit(executor)
+	s.P95LimitMs = ms(limit)
+	degraded := limit > 0 && len(sorted) >= g.latency.MinSamples && p95 > limit
+	if degraded == s.Degraded {
+		return false
+	}
+	s.Degraded = degraded
+	s.DegradedSince = time.Time{}
+	if degraded {
+		s.DegradedSince = time.Now().UTC()
+	}
+	return true
+}
+
+func (s *executorStats) snapshot() ExecutorStats {
+	snap := s.ExecutorStats
+	snap.Samples = len(s.latencies)
+	if s.Executions > 0 {
+		snap.SuccessRate = math.Round(float64(s.Executions-s.Failures)/float64(s.Executions)*1000) / 1000
+	}
+	return snap
+}
+
+// percentile is the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// ms converts a duration to milliseconds, keeping microseconds
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// degradedChanged announces that an executor became or stopped being
+// degraded, with an "executor.degraded" or "executor.recovered" event and
+// a capability diff
+func (g *Gateway) degradedChanged(name string) {
+	state := "recovered"
+	if g.IsExecutorDegraded(name) {
+		state = "degraded"
+	}
+	g.mu.RLock()
+	bus := g.bus
+	g.mu.RUnlock()
+	if bus != nil {
+		bus.Publish(events.Event{Type: "executor." + state, Source: "gateway", Data: map[string]interface{}{"executor": name}})
+	}
+	g.capabilitiesChanged("executor." + state)
+}
+
+// checkDegraded refuses intents not from the user that target a degraded
+// executor
+func (g *Gateway) checkDegraded(ctx context.Context, i *intent.Intent, executor string) error {
+	origin := i.OriginKind()
+	if origin == intent.OriginUser || !g.IsExecutorDegraded(executor) {
+		return nil
+	}
+	g.logger.InfoContext(ctx, "Suppressed intent to degraded executor", "executor", executor, "origin", origin)
+	return agenterrors.Newf(agenterrors.Unavailable, "executor '%s' is degraded (p95 latency over its limit); %s intents are suppressed", executor, origin)
+}
@@ -0,0 +1,148 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/access"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// ParamTransformer rewrites an intent's parameters before it is checked
+// against policy and dispatched, so executors receive canonical values
+type ParamTransformer interface {
+	// Transform returns the parameters to use, or nil to leave them alone.
+	// It must not modify i.Parameters in place.
+	Transform(i *intent.Intent) (map[string]interface{}, error)
+}
+
+// Transform is a ParamTransformer rule for matching intent types. Intent
+// types may end in ".*" to match a whole module, or be "*" for every intent.
+//
+// With Values, a string parameter found in the map (compared
+// case-insensitively) is replaced by the mapped value and written to To, if
+// set, instead of Param; other values are left as they are. Without Values,
+// the parameter is just renamed to To.
+type Transform struct {
+	IntentTypes []string               `json:"intent_types"`
+	Param       string                 `json:"param"`
+	Values      map[string]interface{} `json:"values,omitempty"`
+	To          string                 `json:"to,omitempty"`
+}
+
+// Transform applies the rule
+func (t Transform) Transform(i *intent.Intent) (map[string]interface{}, error) {
+	if !t.matches(i.IntentType) {
+		return nil, nil
+	}
+	v, ok := i.Parameters[t.Param]
+	if !ok {
+		return nil, nil
+	}
+	to := t.To
+	if to == "" {
+		to = t.Param
+	}
+
+	if len(t.Values) > 0 {
+		s, ok := v.(string)
+		if !ok {
+			return nil, nil
+		}
+		mapped, ok := t.lookup(s)
+		if !ok {
+			return nil, nil
+		}
+		v = mapped
+	} else if to == t.Param {
+		return nil, nil
+	}
+
+	params := maps.Clone(i.Parameters)
+	delete(params, t.Param)
+	params[to] = v
+	return params, nil
+}
+
+func (t Transform) lookup(s string) (interface{}, bool) {
+	s = strings.TrimSpace(s)
+	for alias, value := range t.Values {
+		if strings.EqualFold(alias, s) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+func (t Transform) matches(intentType string) bool {
+	for _, pattern := range t.IntentTypes {
+		if access.MatchIntentType(pattern, intentType) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadTransforms reads a JSON array of Transform rules
+func LoadTransforms(path string) ([]Transform, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Transform
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid transforms file %s: %w", path, err)
+	}
+	for n, r := range rules {
+		if r.Param == "" || len(r.IntentTypes) == 0 {
+			return nil, fmt.Errorf("transform %d in %s needs intent_types and param", n, path)
+		}
+	}
+	return rules, nil
+}
+
+// AddTransformer appends a transformer; transformers run in the order they
+// were added, each seeing the previous one's output
+func (g *Gateway) AddTransformer(t ParamTransformer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.transformers = append(g.transformers, t)
+}
+
+// SetTransformers replaces every transformer, including those added
+// with AddTransformer, with transformers
+func (g *Gateway) SetTransformers(transformers []ParamTransformer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.transformers = slices.Clone(transformers)
+}
+
+// transform runs the transformers over the intent's parameters
+func (g *Gateway) transform(ctx context.Context, i *intent.Intent) error {
+	g.mu.RLock()
+	transformers := g.transformers
+	g.mu.RUnlock()
+
+	for _, t := range transformers {
+		params, err := t.Transform(i)
+		if err != nil {
+			var coded *agenterrors.Error
+			if !errors.As(err, &coded) {
+				err = agenterrors.Wrap(agenterrors.InvalidParams, err)
+			}
+			return err
+		}
+		if params != nil {
+			g.logger.DebugContext(ctx, "Transformed parameters", "from", i.Parameters, "to", params)
+			i.Parameters = params
+		}
+	}
+	return nil
+}
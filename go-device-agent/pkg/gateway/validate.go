@@ -0,0 +1,151 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/access"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// Check is the outcome of one check of ValidateIntent
+type Check struct {
+	Name      string           `json:"check"`
+	OK        bool             `json:"ok"`
+	Error     string           `json:"error,omitempty"`
+	ErrorCode agenterrors.Code `json:"error_code,omitempty"`
+	Detail    interface{}      `json:"detail,omitempty"`
+}
+
+// Validation is what ValidateIntent found
+type Validation struct {
+	Valid      bool    `json:"valid"`
+	IntentID   string  `json:"intent_id,omitempty"`
+	IntentType string  `json:"intent_type,omitempty"`
+	Module     string  `json:"module,omitempty"`
+	Checks     []Check `json:"checks"`
+
+	// NeedsApproval is the risk level the intent would wait for approval
+	// at, and NeedsChallenge is set when it would need a PIN. Neither is
+	// asked for.
+	NeedsApproval  string `json:"needs_approval,omitempty"`
+	NeedsChallenge bool   `json:"needs_challenge,omitempty"`
+}
+
+func (v *Validation) check(name string, err error) bool {
+	c := Check{Name: name, OK: err == nil}
+	if err != nil {
+		v.Valid = false
+		c.Error, c.ErrorCode = err.Error(), agenterrors.CodeOf(err)
+		var verr *schema.ValidationError
+		if errors.As(err, &verr) {
+			c.Detail = map[string]interface{}{"field_errors": verr.Fields}
+		}
+	}
+	v.Checks = append(v.Checks, c)
+	return c.OK
+}
+
+// ValidateIntent runs an intent through the checks it would meet on its
+// way to its executor — parsing, signature, expiry, roles, speaker,
+// presence, mode, safety, quotas, the executor's state, and its parameter
+// schema — without executing it. Nothing is counted against quotas and no
+// approval or PIN is asked for, so an intent that validates may still be
+// refused by one of those, or by a device.
+func (g *Gateway) ValidateIntent(ctx context.Context, data []byte, codec intent.Codec) *Validation {
+	v := &Validation{Valid: true}
+	g.mu.RLock()
+	parse := g.parse
+	g.mu.RUnlock()
+
+	var i *intent.Intent
+	var err error
+	if parse.strict {
+		i, err = intent.DecodeStrict(data, codec, parse.maxSkew)
+	} else {
+		i, err = intent.Decode(data, codec)
+	}
+	if !v.check("parse", agenterrors.Wrap(agenterrors.InvalidIntent, err)) {
+		return v
+	}
+	v.IntentID, v.IntentType = i.ID, i.IntentType
+	if !v.check("intent", agenterrors.Wrap(agenterrors.InvalidIntent, i.Validate())) {
+		return v
+	}
+
+	v.check("signature", agenterrors.Wrap(agenterrors.Unauthorized, g.checkSignature(ctx, i)))
+	ctx = withChallengeResponse(ctx, i)
+	if !v.check("encrypted_parameters", g.openParameters(i)) {
+		return v
+	}
+	ctx = withChallengeResponse(ctx, i)
+	if deadline, ok := g.deadline(i); ok && !time.Now().Before(deadline) {
+		err = agenterrors.Newf(agenterrors.Expired, "intent expired at %s", deadline.Format(time.RFC3339))
+	} else {
+		err = nil
+	}
+	v.check("expiry", err)
+
+	if i.TargetModule == nil {
+		v.check("target", agenterrors.New(agenterrors.InvalidIntent, "intent has no target_module"))
+		return v
+	}
+	v.Module = *i.TargetModule
+
+	v.check("role", g.checkRole(ctx, i))
+	v.check("transform", g.transform(ctx, i))
+	grant, err := g.guestGrant(i)
+	if err == nil && grant != nil && !grant.Allows(i) {
+		err = agenterrors.Newf(agenterrors.DeniedByPolicy, "guest %s is not allowed %s", grant.Name, i.IntentType)
+	}
+	v.check("guest", err)
+	v.check("speaker", g.checkSpeaker(i))
+	v.check("presence", g.checkPresence(i))
+	v.check("mode", g.checkMode(i))
+	_, _, err = g.applySafety(ctx, i)
+	v.check("safety", err)
+	v.check("quota", g.quotaLeft(i))
+
+	g.mu.RLock()
+	challenges := g.challenges
+	g.mu.RUnlock()
+	v.NeedsChallenge = challenges != nil && challenges.Required(i)
+	if a := g.Approvals(); a != nil {
+		v.NeedsApproval = a.Classify(i)
+	}
+
+	modules, fanOut := g.fanOutTargets(*i.TargetModule, i.IntentType)
+	if !fanOut {
+		modules = []string{*i.TargetModule}
+	}
+	var errs []error
+	for _, module := range modules {
+		if _, err := g.readyExecutor(ctx, i, module); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	v.check("executor", errors.Join(errs...))
+	v.check("parameters", g.schemas.Validate(i.IntentType, i.Parameters))
+	return v
+}
+
+// quotaLeft fails with QUOTA_EXCEEDED if a quota counting the intent is
+// used up, without counting it
+func (g *Gateway) quotaLeft(i *intent.Intent) error {
+	t := g.Quotas()
+	if t == nil || access.MatchIntentType("quota.*", i.IntentType) {
+		return nil
+	}
+	for _, s := range t.Status(i.IntentType, time.Now()) {
+		switch {
+		case s.PerHour > 0 && s.UsedHour >= s.PerHour:
+			return agenterrors.Newf(agenterrors.QuotaExceeded, "quota %s (%d per hour) is used up; it resets at %s", s.Name, s.PerHour, s.HourResets.Format("Jan 2 15:04"))
+		case s.PerDay > 0 && s.UsedDay >= s.PerDay:
+			return agenterrors.Newf(agenterrors.QuotaExceeded, "quota %s (%d per day) is used up; it resets at %s", s.Name, s.PerDay, s.DayResets.Format("Jan 2 15:04"))
+		}
+	}
+	return nil
+}
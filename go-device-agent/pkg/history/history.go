@@ -0,0 +1,236 @@
+// Package history keeps every result the gateway produced, so the agent
+// core can answer "what did you do today?" from the agent's own records
+// rather than its memory of the conversation. Records are appended to a
+// JSON Lines file and, with a retention age, pruned once a day.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/access"
+)
+
+// Record is one handled intent and its result
+type Record struct {
+	Time          time.Time              `json:"time"`
+	IntentID      string                 `json:"intent_id"`
+	IntentType    string                 `json:"intent_type"`
+	Executor      string                 `json:"executor,omitempty"`
+	Parameters    map[string]interface{} `json:"parameters,omitempty"`
+	Success       bool                   `json:"success"`
+	Result        interface{}            `json:"result,omitempty"`
+	Error         string                 `json:"error,omitempty"`
+	ErrorCode     string                 `json:"error_code,omitempty"`
+	CorrelationID string                 `json:"correlation_id,omitempty"`
+	SessionID     string                 `json:"session_id,omitempty"`
+	WallTimeMs    int64                  `json:"wall_time_ms,omitempty"`
+}
+
+// Query selects records. Empty fields match everything.
+type Query struct {
+	Since time.Time
+	Until time.Time // exclusive
+
+	// IntentType is an intent type, or module.* for all of a module's
+	IntentType string
+	Executor   string
+	Success    *bool
+	Limit      int // most recent records to return (0 for all)
+}
+
+// Match reports whether r passes the query
+func (q Query) Match(r Record) bool {
+	if !q.Since.IsZero() && r.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !r.Time.Before(q.Until) {
+		return false
+	}
+	if q.IntentType != "" && !access.MatchIntentType(q.IntentType, r.IntentType) {
+		return false
+	}
+	if q.Executor != "" && r.Executor != q.Executor {
+		return false
+	}
+	if q.Success != nil && r.Success != *q.Success {
+		return false
+	}
+	return true
+}
+
+// pruneEvery is how often records past the retention age are removed
+const pruneEvery = 24 * time.Hour
+
+// Store is a JSON Lines history file
+type Store struct {
+	path      string
+	retention time.Duration
+
+	mu     sync.Mutex
+	file   *os.File
+	pruned time.Time
+}
+
+// Open opens the history at path for appending, creating it if needed.
+// With a retention age, records older than it are removed now and daily
+// after; zero keeps them all.
+func Open(path string, retention time.Duration) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{path: path, retention: retention, file: file}
+	if retention > 0 {
+		if err := s.prune(time.Now().Add(-retention)); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Record appends a record, stamping the time if it is not set
+func (s *Store) Record(r Record) error {
+	if r.Time.IsZero() {
+		r.Time = time.Now().UTC()
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return errors.New("history is closed")
+	}
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if s.retention > 0 && time.Since(s.pruned) >= pruneEvery {
+		return s.prune(time.Now().Add(-s.retention))
+	}
+	return nil
+}
+
+// Query returns matching records, oldest first
+func (s *Store) Query(q Query) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records := []Record{}
+	err := s.scan(func(r Record) {
+		if !q.Match(r) {
+			return
+		}
+		records = append(records, r)
+		if q.Limit > 0 && len(records) > q.Limit {
+			records = records[1:]
+		}
+	})
+	return records, err
+}
+
+// Prune removes records from before cutoff, returning how many it removed
+func (s *Store) Prune(cutoff time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	before := 0
+	if err := s.scan(func(r Record) {
+		if r.Time.Before(cutoff) {
+			before++
+		}
+	}); err != nil {
+		return 0, err
+	}
+	if before == 0 {
+		return 0, nil
+	}
+	return before, s.prune(cutoff)
+}
+
+// prune rewrites the file without the records from before cutoff
+func (s *Store) prune(cutoff time.Time) error {
+	s.pruned = time.Now()
+	var kept []Record
+	if err := s.scan(func(r Record) {
+		if !r.Time.Before(cutoff) {
+			kept = append(kept, r)
+		}
+	}); err != nil {
+		return err
+	}
+
+	// Written atomically so a crash never leaves a torn file
+	tmp := s.path + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(out)
+	enc := json.NewEncoder(w)
+	for _, r := range kept {
+		if err := enc.Encode(r); err != nil {
+			out.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	// Windows can't replace a file that is open, so appends stop while it
+	// is, then continue in the new file
+	if s.file != nil {
+		s.file.Close()
+	}
+	renameErr := os.Rename(tmp, s.path)
+	s.file, err = os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if renameErr != nil {
+		return renameErr
+	}
+	return err
+}
+
+// scan calls fn with each record in the file, skipping lines that don't
+// parse, such as one torn by a crash mid-write
+func (s *Store) scan(fn func(Record)) error {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for scanner.Scan() {
+		var r Record
+		if json.Unmarshal(scanner.Bytes(), &r) == nil {
+			fn(r)
+		}
+	}
+	return scanner.Err()
+}
+
+// Close closes the history file
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
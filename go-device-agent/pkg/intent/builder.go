@@ -0,0 +1,181 @@
+package intent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/internal/uuid"
+)
+
+// Builder assembles intents for Go programs and tests that emit intents
+// directly instead of through the agent core:
+//
+//	i, err := intent.New("device.control").
+//		Param("device", "lamp").
+//		Param("action", "on").
+//		Confidence(0.9).
+//		RequirePermission().
+//		Build()
+type Builder struct {
+	i Intent
+}
+
+// New starts an intent of the given type with a fresh ID, the current
+// time, full confidence, and the target module taken from the type prefix
+// ("device.control" targets "device")
+func New(intentType string) *Builder {
+	b := &Builder{i: Intent{
+		ID:         uuid.NewID(),
+		IntentType: intentType,
+		Confidence: 1.0,
+		Parameters: make(map[string]interface{}),
+		Reasoning:  fmt.Sprintf("%s requested programmatically", intentType),
+		CreatedAt:  time.Now().UTC(),
+	}}
+	if module, _, ok := strings.Cut(intentType, "."); ok && module != "" {
+		b.i.TargetModule = &module
+	}
+	return b
+}
+
+// ID overrides the generated intent ID
+func (b *Builder) ID(id string) *Builder {
+	b.i.ID = id
+	return b
+}
+
+// Param sets one parameter
+func (b *Builder) Param(name string, value interface{}) *Builder {
+	b.i.Parameters[name] = value
+	return b
+}
+
+// Params sets several parameters
+func (b *Builder) Params(params map[string]interface{}) *Builder {
+	for name, value := range params {
+		b.i.Parameters[name] = value
+	}
+	return b
+}
+
+// Confidence sets the confidence score
+func (b *Builder) Confidence(c float32) *Builder {
+	b.i.Confidence = c
+	return b
+}
+
+// Reasoning sets the human-readable reason for the intent
+func (b *Builder) Reasoning(reasoning string) *Builder {
+	b.i.Reasoning = reasoning
+	return b
+}
+
+// RequirePermission marks the intent as needing permission
+func (b *Builder) RequirePermission() *Builder {
+	b.i.RequiresPermission = true
+	return b
+}
+
+// Target overrides the target module
+func (b *Builder) Target(module string) *Builder {
+	b.i.TargetModule = &module
+	return b
+}
+
+// Correlation sets the correlation ID shared by intents from one utterance
+func (b *Builder) Correlation(id string) *Builder {
+	b.i.CorrelationID = id
+	return b
+}
+
+// Guest acts under the guest grant identified by token
+func (b *Builder) Guest(token string) *Builder {
+	b.i.GuestToken = token
+	return b
+}
+
+// Speaker sets the identified speaker's voice profile ID and the
+// identification confidence
+func (b *Builder) Speaker(id string, confidence float32) *Builder {
+	b.i.SpeakerID = id
+	b.i.SpeakerConfidence = confidence
+	return b
+}
+
+// Approval sets the authenticator app code for intents held for
+// approval
+func (b *Builder) Approval(code string) *Builder {
+	b.i.ApprovalCode = code
+	return b
+}
+
+// Origin appends a link to the provenance chain, e.g. the automation that
+// generated the intent
+func (b *Builder) Origin(kind, id, name string) *Builder {
+	b.i.Provenance = append(b.i.Provenance, Origin{Kind: kind, ID: id, Name: name})
+	return b
+}
+
+// Session sets the conversation session ID
+func (b *Builder) Session(id string) *Builder {
+	b.i.SessionID = id
+	return b
+}
+
+// CreatedAt overrides the creation time
+func (b *Builder) CreatedAt(t time.Time) *Builder {
+	b.i.CreatedAt = t
+	return b
+}
+
+// ExpiresAt sets an absolute expiry
+func (b *Builder) ExpiresAt(t time.Time) *Builder {
+	b.i.ExpiresAt = &t
+	return b
+}
+
+// TTL sets an expiry relative to the creation time, rounded up to a second
+func (b *Builder) TTL(d time.Duration) *Builder {
+	secs := int64((d + time.Second - 1) / time.Second)
+	b.i.TTLSeconds = &secs
+	return b
+}
+
+// Build validates and returns the intent. The builder can be reused; each
+// call returns an independent copy.
+func (b *Builder) Build() (*Intent, error) {
+	i := b.i
+	i.Parameters = make(map[string]interface{}, len(b.i.Parameters))
+	for name, value := range b.i.Parameters {
+		i.Parameters[name] = value
+	}
+	i.Provenance = append([]Origin(nil), b.i.Provenance...)
+	if err := i.Validate(); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// MustBuild is Build for intents known to be valid, such as in tests
+func (b *Builder) MustBuild() *Intent {
+	i, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+	return i
+}
+
+// JSON builds the intent and marshals it for the gateway
+func (b *Builder) JSON() ([]byte, error) {
+	return b.Encode(JSON)
+}
+
+// Encode builds the intent and encodes it with the given codec
+func (b *Builder) Encode(codec Codec) ([]byte, error) {
+	i, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	return Encode(i, codec)
+}
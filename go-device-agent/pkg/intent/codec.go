@@ -0,0 +1,191 @@
+package intent
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"reflect"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Content types understood by the built-in codecs
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeCBOR     = "application/cbor"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// Codec encodes and decodes intents and results on the wire
+type Codec interface {
+	// Name returns a short codec name (e.g. "json")
+	Name() string
+
+	// ContentType returns the MIME type used for negotiation
+	ContentType() string
+
+	// Marshal encodes v
+	Marshal(v interface{}) ([]byte, error)
+
+	// Unmarshal decodes data into v
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// ProtoMessage is implemented by types that can be carried by the protobuf
+// codec. Field numbers are documented in proto/agent.proto.
+type ProtoMessage interface {
+	MarshalProto() ([]byte, error)
+	UnmarshalProto(data []byte) error
+}
+
+// Built-in codecs
+var (
+	JSON     Codec = jsonCodec{}
+	CBOR     Codec = newCBORCodec()
+	Protobuf Codec = protobufCodec{}
+)
+
+var codecs = []Codec{JSON, CBOR, Protobuf}
+
+// Decode decodes an intent using the given codec
+func Decode(data []byte, codec Codec) (*Intent, error) {
+	if codec == nil {
+		codec = JSON
+	}
+	var intent Intent
+	if err := codec.Unmarshal(data, &intent); err != nil {
+		return nil, err
+	}
+	return &intent, nil
+}
+
+// Encode encodes an intent using the given codec
+func Encode(i *Intent, codec Codec) ([]byte, error) {
+	if codec == nil {
+		codec = JSON
+	}
+	return codec.Marshal(i)
+}
+
+// CodecForName returns the codec with the given short name
+func CodecForName(name string) (Codec, bool) {
+	for _, c := range codecs {
+		if c.Name() == strings.ToLower(name) {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// CodecForContentType returns the codec for a Content-Type header value.
+// An empty content type selects JSON.
+func CodecForContentType(contentType string) (Codec, bool) {
+	if contentType == "" {
+		return JSON, true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	for _, c := range codecs {
+		if c.ContentType() == mediaType {
+			return c, true
+		}
+	}
+	// Common aliases
+	switch mediaType {
+	case "application/protobuf", "application/vnd.google.protobuf":
+		return Protobuf, true
+	}
+	return nil, false
+}
+
+// NegotiateCodec picks a response codec from an Accept header value,
+// falling back when nothing acceptable is offered
+func NegotiateCodec(accept string, fallback Codec) Codec {
+	for _, part := range strings.Split(accept, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" || strings.HasPrefix(part, "*/*") {
+			continue
+		}
+		if c, ok := CodecForContentType(part); ok {
+			return c
+		}
+	}
+	return fallback
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string        { return "json" }
+func (jsonCodec) ContentType() string { return ContentTypeJSON }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// cborCodec uses the json struct tags so both encodings share field names
+type cborCodec struct {
+	enc    cbor.EncMode
+	dec    cbor.DecMode
+	strict cbor.DecMode
+}
+
+func newCBORCodec() cborCodec {
+	enc, err := cbor.EncOptions{Time: cbor.TimeRFC3339Nano}.EncMode()
+	if err != nil {
+		panic(err)
+	}
+	// Nested maps decode as map[string]interface{} like encoding/json
+	opts := cbor.DecOptions{
+		DefaultMapType: reflect.TypeOf(map[string]interface{}(nil)),
+	}
+	dec, err := opts.DecMode()
+	if err != nil {
+		panic(err)
+	}
+	opts.ExtraReturnErrors = cbor.ExtraDecErrorUnknownField
+	opts.DupMapKey = cbor.DupMapKeyEnforcedAPF
+	strict, err := opts.DecMode()
+	if err != nil {
+		panic(err)
+	}
+	return cborCodec{enc: enc, dec: dec, strict: strict}
+}
+
+func (cborCodec) Name() string        { return "cbor" }
+func (cborCodec) ContentType() string { return ContentTypeCBOR }
+
+func (c cborCodec) Marshal(v interface{}) ([]byte, error) {
+	return c.enc.Marshal(v)
+}
+
+func (c cborCodec) Unmarshal(data []byte, v interface{}) error {
+	return c.dec.Unmarshal(data, v)
+}
+
+type protobufCodec struct{}
+
+func (protobufCodec) Name() string        { return "protobuf" }
+func (protobufCodec) ContentType() string { return ContentTypeProtobuf }
+
+func (protobufCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(ProtoMessage)
+	if !ok {
+		return nil, fmt.Errorf("protobuf codec: unsupported type %T", v)
+	}
+	return m.MarshalProto()
+}
+
+func (protobufCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(ProtoMessage)
+	if !ok {
+		return fmt.Errorf("protobuf codec: unsupported type %T", v)
+	}
+	return m.UnmarshalProto(data)
+}
@@ -0,0 +1,37 @@
+package intent
+
+import (
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/internal/uuid"
+)
+
+// ID strategies for generated intent, event, and job IDs
+const (
+	// IDUUIDv4 is random and reveals nothing about when an ID was made
+	IDUUIDv4 = uuid.V4
+	// IDUUIDv7 leads with a millisecond timestamp, so IDs sort by time
+	IDUUIDv7 = uuid.V7
+	// IDULID is time-ordered like UUIDv7, as 26 characters that also sort
+	// as text
+	IDULID = uuid.ULID
+)
+
+// SetIDStrategy chooses how the agent generates intent, event, and job
+// IDs: IDUUIDv4 (the default), IDUUIDv7, or IDULID. Time-ordered IDs from
+// one process strictly increase, so logs, audit entries, and results sort
+// in the order they happened.
+func SetIDStrategy(name string) error {
+	return uuid.SetStrategy(name)
+}
+
+// NewID returns an ID in the chosen strategy
+func NewID() string {
+	return uuid.NewID()
+}
+
+// IDTime returns when a UUIDv7 or ULID was generated; false for IDs that
+// carry no time
+func IDTime(id string) (time.Time, bool) {
+	return uuid.Time(id)
+}
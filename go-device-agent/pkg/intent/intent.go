@@ -2,6 +2,7 @@
 package intent
 
 import (
+	"bytes"
 	"encoding/json"
 	"time"
 )
@@ -9,23 +10,90 @@ import (
 // Intent represents a structured intent emitted by the agent core
 // This is the security boundary - agent emits intents, device agents execute
 type Intent struct {
-	ID                string                 `json:"id"`
-	IntentType        string                 `json:"intent_type"`
-	Confidence        float32                `json:"confidence"`
-	Parameters        map[string]interface{} `json:"parameters"`
-	Reasoning         string                 `json:"reasoning"`
+	ID                 string                 `json:"id"`
+	IntentType         string                 `json:"intent_type"`
+	Confidence         float32                `json:"confidence"`
+	Parameters         map[string]interface{} `json:"parameters"`
+	Reasoning          string                 `json:"reasoning"`
 	RequiresPermission bool                   `json:"requires_permission"`
-	TargetModule      *string                `json:"target_module,omitempty"`
-	CreatedAt         time.Time              `json:"created_at"`
+	TargetModule       *string                `json:"target_module,omitempty"`
+	CreatedAt          time.Time              `json:"created_at"`
+
+	// CorrelationID ties together every intent stemming from one user
+	// utterance; SessionID groups utterances into a conversation. Both are
+	// copied onto results and audit entries.
+	CorrelationID string `json:"correlation_id,omitempty"`
+	SessionID     string `json:"session_id,omitempty"`
+
+	// GuestToken or SpeakerID identify a guest acting under an access
+	// grant; such intents are limited to the grant's scope
+	GuestToken string `json:"guest_token,omitempty"`
+
+	// SpeakerID is the voice profile that spoke the request, identified
+	// with SpeakerConfidence (0-1) by the core or a local model
+	SpeakerID         string  `json:"speaker_id,omitempty"`
+	SpeakerConfidence float32 `json:"speaker_confidence,omitempty"`
+
+	// ApprovalCode is a code from the user's authenticator app, for
+	// intents the agent holds for second-factor approval
+	ApprovalCode string `json:"approval_code,omitempty"`
+
+	// EncryptedParameters carries parameters sealed to the device agent's
+	// payload key, so transports in between can't read them. The gateway
+	// opens them into Parameters after checking the signature.
+	EncryptedParameters *Sealed `json:"encrypted_parameters,omitempty"`
+
+	// Provenance traces an intent that an automation, routine, schedule,
+	// plan, or follow-up suggestion generated, root origin first. It is
+	// empty for intents the user asked for directly.
+	Provenance []Origin `json:"provenance,omitempty"`
+
+	// ExpiresAt and TTLSeconds (relative to CreatedAt) bound how long the
+	// intent may wait before execution. When both are set the earlier wins.
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	TTLSeconds *int64     `json:"ttl_seconds,omitempty"`
+
+	// Signature is a base64 Ed25519 signature over SigningPayload, made with
+	// the agent core key named by KeyID
+	Signature string `json:"signature,omitempty"`
+	KeyID     string `json:"key_id,omitempty"`
+}
+
+// Sealed is a JSON parameters object encrypted with AES-256-GCM under a
+// key agreed by X25519 between a one-time sender key and the device
+// agent's payload key. Byte fields are base64 in JSON.
+type Sealed struct {
+	// KeyID names the payload key it was sealed to
+	KeyID string `json:"key_id,omitempty"`
+
+	// EphemeralKey is the sender's one-time X25519 public key
+	EphemeralKey []byte `json:"ephemeral_key"`
+	Nonce        []byte `json:"nonce"`
+	Ciphertext   []byte `json:"ciphertext"`
+}
+
+// Origin kinds
+const (
+	OriginUser       = "user"
+	OriginAutomation = "automation"
+	OriginRoutine    = "routine"
+	OriginSchedule   = "schedule"
+	OriginSuggestion = "suggestion"
+	OriginPlan       = "plan"
+	OriginScene      = "scene"
+)
+
+// Origin is one link in an intent's provenance chain: the rule, routine,
+// or intent that gave rise to the next link
+type Origin struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
 }
 
 // ParseIntent parses a JSON intent from the agent core
 func ParseIntent(data []byte) (*Intent, error) {
-	var intent Intent
-	if err := json.Unmarshal(data, &intent); err != nil {
-		return nil, err
-	}
-	return &intent, nil
+	return Decode(data, JSON)
 }
 
 // ToJSON converts the intent to JSON
@@ -44,9 +112,56 @@ func (i *Intent) Validate() error {
 	if i.Reasoning == "" {
 		return &ValidationError{Field: "reasoning", Message: "cannot be empty"}
 	}
+	if i.SpeakerConfidence < 0.0 || i.SpeakerConfidence > 1.0 {
+		return &ValidationError{Field: "speaker_confidence", Message: "must be between 0.0 and 1.0"}
+	}
+	for _, o := range i.Provenance {
+		if o.Kind == "" || o.ID == "" {
+			return &ValidationError{Field: "provenance", Message: "every origin needs a kind and an id"}
+		}
+	}
+	if i.TTLSeconds != nil {
+		if *i.TTLSeconds <= 0 {
+			return &ValidationError{Field: "ttl_seconds", Message: "must be positive"}
+		}
+		if i.CreatedAt.IsZero() {
+			return &ValidationError{Field: "ttl_seconds", Message: "requires created_at"}
+		}
+	}
 	return nil
 }
 
+// Deadline returns when the intent expires, if it has an expiry
+func (i *Intent) Deadline() (time.Time, bool) {
+	var deadline time.Time
+	if i.TTLSeconds != nil && !i.CreatedAt.IsZero() {
+		deadline = i.CreatedAt.Add(time.Duration(*i.TTLSeconds) * time.Second)
+	}
+	if i.ExpiresAt != nil && (deadline.IsZero() || i.ExpiresAt.Before(deadline)) {
+		deadline = *i.ExpiresAt
+	}
+	return deadline, !deadline.IsZero()
+}
+
+// OriginKind returns the kind of the intent's root origin, or OriginUser
+// for intents the user asked for directly. Plans and scenes only relay
+// their own origin, so steps of a plan or scene the user asked for are
+// OriginUser too.
+func (i *Intent) OriginKind() string {
+	for _, o := range i.Provenance {
+		if o.Kind != OriginPlan && o.Kind != OriginScene {
+			return o.Kind
+		}
+	}
+	return OriginUser
+}
+
+// Expired reports whether the intent's deadline has passed at now
+func (i *Intent) Expired(now time.Time) bool {
+	deadline, ok := i.Deadline()
+	return ok && !now.Before(deadline)
+}
+
 // ValidationError represents a validation error
 type ValidationError struct {
 	Field   string
@@ -56,3 +171,38 @@ type ValidationError struct {
 func (e *ValidationError) Error() string {
 	return "validation error for field '" + e.Field + "': " + e.Message
 }
+
+// SigningPayload returns the bytes covered by Signature: the intent as
+// compact JSON with object keys sorted and the signature field removed.
+// It is the same whichever codec carried the intent.
+func (i *Intent) SigningPayload() ([]byte, error) {
+	unsigned := *i
+	unsigned.Signature = ""
+	return CanonicalJSON(&unsigned)
+}
+
+// CanonicalJSON encodes v as compact JSON with object keys sorted at every
+// level and no HTML escaping, so signers in other languages produce the
+// same bytes
+func CanonicalJSON(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// Round-trip through a map so nested keys are sorted too
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var canonical interface{}
+	if err := decoder.Decode(&canonical); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(canonical); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,135 @@
+package intent
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []string // JSON texts that must canonicalize alike
+		want string
+	}{
+		{
+			name: "key order",
+			in: []string{
+				`{"b": 1, "a": {"d": [3, {"z": 1, "y": 2}], "c": true}}`,
+				`{"a": {"c": true, "d": [3, {"y": 2, "z": 1}]}, "b": 1}`,
+			},
+			want: `{"a":{"c":true,"d":[3,{"y":2,"z":1}]},"b":1}`,
+		},
+		{
+			name: "numbers",
+			in: []string{
+				`{"n": 1.5, "i": 80, "big": 12345678901234567890, "neg": -0.25}`,
+				`{"neg": -0.25, "big": 12345678901234567890, "i": 80, "n": 1.5}`,
+			},
+			want: `{"big":12345678901234567890,"i":80,"n":1.5,"neg":-0.25}`,
+		},
+		{
+			name: "unicode and HTML characters",
+			in: []string{
+				`{"s": "café <b>&</b> ☕"}`,
+				`{"s": "caf\u00e9 \u003cb\u003e\u0026\u003c/b\u003e \u2615"}`,
+			},
+			want: `{"s":"café <b>&</b> ☕"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, text := range tt.in {
+				// json.Number keeps big integers exact
+				decoder := json.NewDecoder(bytes.NewReader([]byte(text)))
+				decoder.UseNumber()
+				var v interface{}
+				if err := decoder.Decode(&v); err != nil {
+					t.Fatal(err)
+				}
+				got, err := CanonicalJSON(v)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != tt.want {
+					t.Errorf("CanonicalJSON(%s)\n got %s\nwant %s", text, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCanonicalJSONIsStable(t *testing.T) {
+	v := map[string]interface{}{"z": []interface{}{1.0, "x"}, "a": map[string]interface{}{"k": nil}}
+	first, err := CanonicalJSON(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Re-encoding the canonical form must not change it
+	var again interface{}
+	if err := json.Unmarshal(first, &again); err != nil {
+		t.Fatal(err)
+	}
+	second, err := CanonicalJSON(again)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(first) != string(second) {
+		t.Errorf("canonical form changed on re-encoding:\n%s\n%s", first, second)
+	}
+}
+
+func TestSigningPayloadIsCodecIndependent(t *testing.T) {
+	i := New("media.volume").
+		ID("intent-1").
+		Param("level", 30).
+		Param("device", "Küche").
+		Param("nested", map[string]interface{}{"b": 2, "a": []interface{}{"x", 1.5}}).
+		Confidence(0.9).
+		Reasoning("User asked to turn it down").
+		CreatedAt(time.Date(2026, 1, 3, 15, 0, 0, 0, time.UTC)).
+		MustBuild()
+	i.Signature = "ignored"
+	i.KeyID = "core-1"
+
+	want, err := i.SigningPayload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, codec := range codecs {
+		t.Run(codec.Name(), func(t *testing.T) {
+			data, err := Encode(i, codec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := Decode(data, codec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := decoded.SigningPayload()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("payload after %s round trip\n got %s\nwant %s", codec.Name(), got, want)
+			}
+		})
+	}
+}
+
+func TestSigningPayloadOmitsSignature(t *testing.T) {
+	i := New("time.query").ID("intent-1").Confidence(0.9).Reasoning("test").MustBuild()
+	unsigned, err := i.SigningPayload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.Signature = "c2lnbmF0dXJl"
+	signed, err := i.SigningPayload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(signed) != string(unsigned) {
+		t.Errorf("the signature changed the payload it signs:\n%s\n%s", unsigned, signed)
+	}
+}
@@ -0,0 +1,410 @@
+package intent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParamError reports a missing or malformed intent parameter
+type ParamError struct {
+	Name    string
+	Message string
+	Missing bool
+}
+
+func (e *ParamError) Error() string {
+	return "parameter '" + e.Name + "': " + e.Message
+}
+
+// IsMissing reports whether err is a ParamError for an absent parameter
+func IsMissing(err error) bool {
+	var pe *ParamError
+	return errors.As(err, &pe) && pe.Missing
+}
+
+func missing(name string) error {
+	return &ParamError{Name: name, Message: "is required", Missing: true}
+}
+
+func invalid(name string, format string, args ...interface{}) error {
+	return &ParamError{Name: name, Message: fmt.Sprintf(format, args...)}
+}
+
+// Param returns a raw parameter value. A null value counts as absent.
+func (i *Intent) Param(name string) (interface{}, bool) {
+	v, ok := i.Parameters[name]
+	return v, ok && v != nil
+}
+
+// StringParam returns a string parameter. Numbers are formatted as strings.
+func (i *Intent) StringParam(name string) (string, error) {
+	v, ok := i.Param(name)
+	if !ok {
+		return "", missing(name)
+	}
+	s, err := toString(v)
+	if err != nil {
+		return "", invalid(name, "%v", err)
+	}
+	return s, nil
+}
+
+// IntParam returns an integer parameter. Integral numbers and numeric
+// strings are accepted; fractional values are an error.
+func (i *Intent) IntParam(name string) (int, error) {
+	v, ok := i.Param(name)
+	if !ok {
+		return 0, missing(name)
+	}
+	n, err := toInt(v)
+	if err != nil {
+		return 0, invalid(name, "%v", err)
+	}
+	return n, nil
+}
+
+// FloatParam returns a numeric parameter. Numeric strings are accepted.
+func (i *Intent) FloatParam(name string) (float64, error) {
+	v, ok := i.Param(name)
+	if !ok {
+		return 0, missing(name)
+	}
+	f, err := toFloat(v)
+	if err != nil {
+		return 0, invalid(name, "%v", err)
+	}
+	return f, nil
+}
+
+// BoolParam returns a boolean parameter. Besides true/false it accepts
+// "yes"/"no", "on"/"off", "1"/"0", and the numbers 1 and 0.
+func (i *Intent) BoolParam(name string) (bool, error) {
+	v, ok := i.Param(name)
+	if !ok {
+		return false, missing(name)
+	}
+	b, err := toBool(v)
+	if err != nil {
+		return false, invalid(name, "%v", err)
+	}
+	return b, nil
+}
+
+// DurationParam returns a duration parameter. Strings use Go duration
+// syntax ("90s", "1h30m"); bare numbers and numeric strings are seconds.
+func (i *Intent) DurationParam(name string) (time.Duration, error) {
+	v, ok := i.Param(name)
+	if !ok {
+		return 0, missing(name)
+	}
+	d, err := toDuration(v)
+	if err != nil {
+		return 0, invalid(name, "%v", err)
+	}
+	return d, nil
+}
+
+// StringParamOr is StringParam with a default for an absent parameter
+func (i *Intent) StringParamOr(name, def string) (string, error) {
+	s, err := i.StringParam(name)
+	if IsMissing(err) {
+		return def, nil
+	}
+	return s, err
+}
+
+// IntParamOr is IntParam with a default for an absent parameter
+func (i *Intent) IntParamOr(name string, def int) (int, error) {
+	n, err := i.IntParam(name)
+	if IsMissing(err) {
+		return def, nil
+	}
+	return n, err
+}
+
+// FloatParamOr is FloatParam with a default for an absent parameter
+func (i *Intent) FloatParamOr(name string, def float64) (float64, error) {
+	f, err := i.FloatParam(name)
+	if IsMissing(err) {
+		return def, nil
+	}
+	return f, err
+}
+
+// BoolParamOr is BoolParam with a default for an absent parameter
+func (i *Intent) BoolParamOr(name string, def bool) (bool, error) {
+	b, err := i.BoolParam(name)
+	if IsMissing(err) {
+		return def, nil
+	}
+	return b, err
+}
+
+// DurationParamOr is DurationParam with a default for an absent parameter
+func (i *Intent) DurationParamOr(name string, def time.Duration) (time.Duration, error) {
+	d, err := i.DurationParam(name)
+	if IsMissing(err) {
+		return def, nil
+	}
+	return d, err
+}
+
+// DecodeParams fills the struct pointed to by v from the parameters using
+// the same coercion rules as the typed accessors. Fields are matched by
+// their `param` tag, then their `json` tag, then their lowercased name.
+// A ",required" tag option makes an absent parameter an error:
+//
+//	var p struct {
+//		Device     string        `param:"device,required"`
+//		Brightness int           `param:"brightness"`
+//		For        time.Duration `param:"for"`
+//	}
+//	if err := i.DecodeParams(&p); err != nil { ... }
+func (i *Intent) DecodeParams(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("DecodeParams requires a pointer to a struct, got %T", v)
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	for f := 0; f < rt.NumField(); f++ {
+		field := rt.Field(f)
+		if !field.IsExported() {
+			continue
+		}
+		name, required := paramName(field)
+		if name == "-" {
+			continue
+		}
+
+		raw, ok := i.Param(name)
+		if !ok {
+			if required {
+				return missing(name)
+			}
+			continue
+		}
+		if err := assign(rv.Field(f), raw); err != nil {
+			return invalid(name, "%v", err)
+		}
+	}
+	return nil
+}
+
+func paramName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("param")
+	if tag == "" {
+		tag = field.Tag.Get("json")
+	}
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	required := false
+	for _, opt := range parts[1:] {
+		if opt == "required" {
+			required = true
+		}
+	}
+	return name, required
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// assign coerces raw into the field
+func assign(field reflect.Value, raw interface{}) error {
+	switch field.Type() {
+	case durationType:
+		d, err := toDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	case timeType:
+		s, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("expected an RFC3339 timestamp, got %T", raw)
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return fmt.Errorf("expected an RFC3339 timestamp: %v", err)
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		s, err := toString(raw)
+		if err != nil {
+			return err
+		}
+		field.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := toInt(raw)
+		if err != nil {
+			return err
+		}
+		if field.OverflowInt(int64(n)) {
+			return fmt.Errorf("%d is out of range", n)
+		}
+		field.SetInt(int64(n))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := toInt(raw)
+		if err != nil {
+			return err
+		}
+		if n < 0 || field.OverflowUint(uint64(n)) {
+			return fmt.Errorf("%d is out of range", n)
+		}
+		field.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat(raw)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := toBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Ptr:
+		elem := reflect.New(field.Type().Elem())
+		if err := assign(elem.Elem(), raw); err != nil {
+			return err
+		}
+		field.Set(elem)
+	default:
+		// Slices, maps, and nested structs go through their JSON form
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, field.Addr().Interface()); err != nil {
+			return fmt.Errorf("expected %s: %v", field.Type(), err)
+		}
+	}
+	return nil
+}
+
+func toString(v interface{}) (string, error) {
+	switch s := v.(type) {
+	case string:
+		return s, nil
+	case json.Number:
+		return s.String(), nil
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64), nil
+	case int, int64, uint64:
+		return fmt.Sprint(s), nil
+	}
+	return "", fmt.Errorf("expected a string, got %s", kindOf(v))
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case float32:
+		return float64(n), nil
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case uint64:
+		return float64(n), nil
+	case json.Number:
+		return n.Float64()
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		if err != nil {
+			return 0, fmt.Errorf("expected a number, got %q", n)
+		}
+		return f, nil
+	}
+	return 0, fmt.Errorf("expected a number, got %s", kindOf(v))
+}
+
+func toInt(v interface{}) (int, error) {
+	f, err := toFloat(v)
+	if err != nil {
+		return 0, err
+	}
+	if f != math.Trunc(f) {
+		return 0, fmt.Errorf("expected an integer, got %v", f)
+	}
+	if f > math.MaxInt32 || f < math.MinInt32 {
+		return 0, fmt.Errorf("%v is out of range", f)
+	}
+	return int(f), nil
+}
+
+func toBool(v interface{}) (bool, error) {
+	switch b := v.(type) {
+	case bool:
+		return b, nil
+	case string:
+		switch strings.ToLower(strings.TrimSpace(b)) {
+		case "true", "yes", "on", "1":
+			return true, nil
+		case "false", "no", "off", "0":
+			return false, nil
+		}
+		return false, fmt.Errorf("expected a boolean, got %q", b)
+	}
+	if f, err := toFloat(v); err == nil && (f == 0 || f == 1) {
+		return f == 1, nil
+	}
+	return false, fmt.Errorf("expected a boolean, got %s", kindOf(v))
+}
+
+func toDuration(v interface{}) (time.Duration, error) {
+	if s, ok := v.(string); ok {
+		s = strings.TrimSpace(s)
+		if d, err := time.ParseDuration(s); err == nil {
+			return d, nil
+		}
+		if secs, err := strconv.ParseFloat(s, 64); err == nil {
+			return time.Duration(secs * float64(time.Second)), nil
+		}
+		return 0, fmt.Errorf("expected a duration like \"90s\" or \"1h30m\", got %q", s)
+	}
+	secs, err := toFloat(v)
+	if err != nil {
+		return 0, fmt.Errorf("expected a duration, got %s", kindOf(v))
+	}
+	return time.Duration(secs * float64(time.Second)), nil
+}
+
+func kindOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	}
+	if _, err := toFloat(v); err == nil {
+		return "a number"
+	}
+	return fmt.Sprintf("%T", v)
+}
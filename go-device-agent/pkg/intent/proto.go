@@ -0,0 +1,157 @@
+package intent
+
+import (
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/internal/wire"
+)
+
+// MarshalProto encodes the intent as an agent.v1.Intent message
+func (i *Intent) MarshalProto() ([]byte, error) {
+	var b []byte
+	b = wire.AppendString(b, 1, i.ID)
+	b = wire.AppendString(b, 2, i.IntentType)
+	b = wire.AppendFloat(b, 3, i.Confidence)
+	b, err := wire.AppendStruct(b, 4, i.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	b = wire.AppendString(b, 5, i.Reasoning)
+	b = wire.AppendBool(b, 6, i.RequiresPermission)
+	if i.TargetModule != nil {
+		b = wire.AppendString(b, 7, *i.TargetModule)
+	}
+	b = wire.AppendTime(b, 8, i.CreatedAt)
+	if i.ExpiresAt != nil {
+		b = wire.AppendTime(b, 9, *i.ExpiresAt)
+	}
+	if i.TTLSeconds != nil {
+		b = wire.AppendInt64(b, 10, *i.TTLSeconds)
+	}
+	b = wire.AppendString(b, 11, i.Signature)
+	b = wire.AppendString(b, 12, i.KeyID)
+	b = wire.AppendString(b, 13, i.CorrelationID)
+	b = wire.AppendString(b, 14, i.SessionID)
+	b = wire.AppendString(b, 15, i.GuestToken)
+	b = wire.AppendString(b, 16, i.SpeakerID)
+	b = wire.AppendFloat(b, 17, i.SpeakerConfidence)
+	for _, o := range i.Provenance {
+		b = wire.AppendMessage(b, 18, o.MarshalProto())
+	}
+	b = wire.AppendString(b, 19, i.ApprovalCode)
+	if i.EncryptedParameters != nil {
+		b = wire.AppendMessage(b, 20, i.EncryptedParameters.MarshalProto())
+	}
+	return b, nil
+}
+
+// MarshalProto encodes the sealed parameters as an agent.v1.Sealed message
+func (s *Sealed) MarshalProto() []byte {
+	var b []byte
+	b = wire.AppendString(b, 1, s.KeyID)
+	b = wire.AppendBytes(b, 2, s.EphemeralKey)
+	b = wire.AppendBytes(b, 3, s.Nonce)
+	b = wire.AppendBytes(b, 4, s.Ciphertext)
+	return b
+}
+
+// UnmarshalProto decodes an agent.v1.Sealed message into s
+func (s *Sealed) UnmarshalProto(data []byte) error {
+	*s = Sealed{}
+	return wire.Walk(data, func(f wire.Field) error {
+		switch f.Num {
+		case 1:
+			s.KeyID = f.String()
+		case 2:
+			s.EphemeralKey = append([]byte(nil), f.Bytes()...)
+		case 3:
+			s.Nonce = append([]byte(nil), f.Bytes()...)
+		case 4:
+			s.Ciphertext = append([]byte(nil), f.Bytes()...)
+		}
+		return nil
+	})
+}
+
+// MarshalProto encodes the origin as an agent.v1.Origin message
+func (o Origin) MarshalProto() []byte {
+	var b []byte
+	b = wire.AppendString(b, 1, o.Kind)
+	b = wire.AppendString(b, 2, o.ID)
+	b = wire.AppendString(b, 3, o.Name)
+	return b
+}
+
+// UnmarshalProto decodes an agent.v1.Origin message into the origin
+func (o *Origin) UnmarshalProto(data []byte) error {
+	*o = Origin{}
+	return wire.Walk(data, func(f wire.Field) error {
+		switch f.Num {
+		case 1:
+			o.Kind = f.String()
+		case 2:
+			o.ID = f.String()
+		case 3:
+			o.Name = f.String()
+		}
+		return nil
+	})
+}
+
+// UnmarshalProto decodes an agent.v1.Intent message into the intent
+func (i *Intent) UnmarshalProto(data []byte) error {
+	*i = Intent{}
+	return wire.Walk(data, func(f wire.Field) error {
+		var err error
+		switch f.Num {
+		case 1:
+			i.ID = f.String()
+		case 2:
+			i.IntentType = f.String()
+		case 3:
+			i.Confidence = f.Float()
+		case 4:
+			i.Parameters, err = f.Struct()
+		case 5:
+			i.Reasoning = f.String()
+		case 6:
+			i.RequiresPermission = f.Bool()
+		case 7:
+			module := f.String()
+			i.TargetModule = &module
+		case 8:
+			i.CreatedAt, err = f.Time()
+		case 9:
+			var expires time.Time
+			expires, err = f.Time()
+			i.ExpiresAt = &expires
+		case 10:
+			ttl := f.Int64()
+			i.TTLSeconds = &ttl
+		case 11:
+			i.Signature = f.String()
+		case 12:
+			i.KeyID = f.String()
+		case 13:
+			i.CorrelationID = f.String()
+		case 14:
+			i.SessionID = f.String()
+		case 15:
+			i.GuestToken = f.String()
+		case 16:
+			i.SpeakerID = f.String()
+		case 17:
+			i.SpeakerConfidence = f.Float()
+		case 18:
+			var o Origin
+			err = o.UnmarshalProto(f.Bytes())
+			i.Provenance = append(i.Provenance, o)
+		case 19:
+			i.ApprovalCode = f.String()
+		case 20:
+			i.EncryptedParameters = &Sealed{}
+			err = i.EncryptedParameters.UnmarshalProto(f.Bytes())
+		}
+		return err
+	})
+}
@@ -0,0 +1,78 @@
+package intent
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/internal/uuid"
+)
+
+// DefaultClockSkew is how far in the future created_at may be before a
+// strictly parsed intent is rejected
+const DefaultClockSkew = 5 * time.Minute
+
+// ParseStrict parses a JSON intent, rejecting unknown fields and trailing
+// data, then applies ValidateStrict with DefaultClockSkew
+func ParseStrict(data []byte) (*Intent, error) {
+	return DecodeStrict(data, JSON, DefaultClockSkew)
+}
+
+// DecodeStrict is the strict counterpart of Decode. JSON and CBOR reject
+// unknown fields (and CBOR duplicate keys); protobuf skips unknown fields
+// by design, so it only gets the ValidateStrict checks.
+func DecodeStrict(data []byte, codec Codec, maxSkew time.Duration) (*Intent, error) {
+	if codec == nil {
+		codec = JSON
+	}
+	var i Intent
+	var err error
+	switch c := codec.(type) {
+	case jsonCodec:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err = decoder.Decode(&i); err == nil {
+			if _, extra := decoder.Token(); !errors.Is(extra, io.EOF) {
+				err = errors.New("unexpected data after intent")
+			}
+		}
+	case cborCodec:
+		err = c.strict.Unmarshal(data, &i)
+	default:
+		err = codec.Unmarshal(data, &i)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := i.ValidateStrict(time.Now(), maxSkew); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// ValidateStrict runs Validate and additionally requires a UUID or ULID id, a
+// target_module, and a created_at no later than now plus maxSkew
+func (i *Intent) ValidateStrict(now time.Time, maxSkew time.Duration) error {
+	if err := i.Validate(); err != nil {
+		return err
+	}
+	if !uuid.ValidID(i.ID) {
+		return &ValidationError{Field: "id", Message: "must be a UUID or ULID"}
+	}
+	if i.TargetModule == nil || *i.TargetModule == "" {
+		return &ValidationError{Field: "target_module", Message: "is required"}
+	}
+	if i.CreatedAt.IsZero() {
+		return &ValidationError{Field: "created_at", Message: "is required"}
+	}
+	if i.CreatedAt.After(now.Add(maxSkew)) {
+		return &ValidationError{
+			Field:   "created_at",
+			Message: fmt.Sprintf("is %s in the future (allowed skew %s)", i.CreatedAt.Sub(now).Round(time.Second), maxSkew),
+		}
+	}
+	return nil
+}
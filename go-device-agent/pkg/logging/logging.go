@@ -0,0 +1,193 @@
+// Package logging sets up the agent's structured logs. Records are written
+// by log/slog as text or JSON, each component (the gateway, the transports,
+// every executor) has its own minimum level, and records logged with an
+// intent's context carry its intent and correlation IDs, so one intent's
+// records can be picked out of a busy log.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Levels holds each component's minimum level. A component without a level
+// of its own takes its family's, so "executor" covers every
+// "executor:<name>" not listed, and otherwise Default.
+type Levels struct {
+	Default    slog.Level
+	Components map[string]slog.Level
+}
+
+// ParseLevels parses a comma-separated list of levels, such as
+// "info,gateway=debug,executor:mqtt=warn". A bare level sets the default;
+// component=level sets a component's. Levels are debug, info, warn, or
+// error, optionally with an offset (e.g. info+2).
+func ParseLevels(s string) (Levels, error) {
+	levels := Levels{Default: slog.LevelInfo, Components: make(map[string]slog.Level)}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		component, name, found := strings.Cut(item, "=")
+		if !found {
+			component, name = "", item
+		}
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+			return Levels{}, fmt.Errorf("invalid log level %q", item)
+		}
+		if component = strings.TrimSpace(component); component == "" {
+			levels.Default = level
+		} else {
+			levels.Components[component] = level
+		}
+	}
+	return levels, nil
+}
+
+// Level returns the minimum level of a component
+func (l Levels) Level(component string) slog.Level {
+	for {
+		if level, ok := l.Components[component]; ok {
+			return level
+		}
+		i := strings.LastIndexByte(component, ':')
+		if i < 0 {
+			return l.Default
+		}
+		component = component[:i]
+	}
+}
+
+// Logs hands out component loggers writing to one output
+type Logs struct {
+	handler slog.Handler
+
+	mu     sync.RWMutex
+	levels Levels
+}
+
+// New creates logs writing records in format (text or JSON) to w
+func New(w io.Writer, format string, levels Levels) (*Logs, error) {
+	// Filtering is per component, so the handler itself passes everything
+	opts := &slog.HandlerOptions{Level: slog.Level(-1 << 10)}
+	var h slog.Handler
+	switch format {
+	case FormatText, "":
+		h = slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q (want %s or %s)", format, FormatText, FormatJSON)
+	}
+	return &Logs{handler: h, levels: levels}, nil
+}
+
+// NewHandler creates logs writing records to h, such as a handler of a
+// system log
+func NewHandler(h slog.Handler, levels Levels) *Logs {
+	return &Logs{handler: h, levels: levels}
+}
+
+// SetLevels changes the component levels, including those of loggers
+// already handed out
+func (l *Logs) SetLevels(levels Levels) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.levels = levels
+}
+
+// Levels returns the component levels
+func (l *Logs) Levels() Levels {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.levels
+}
+
+// Logger returns the logger of a component, whose records carry it as
+// their component attribute
+func (l *Logs) Logger(component string) *slog.Logger {
+	return slog.New(&handler{
+		logs:      l,
+		component: component,
+		next:      l.handler.WithAttrs([]slog.Attr{slog.String("component", component)}),
+	})
+}
+
+func (l *Logs) enabled(component string, level slog.Level) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return level >= l.levels.Level(component)
+}
+
+// handler filters a component's records by its level and adds the
+// attributes of their context
+type handler struct {
+	logs      *Logs
+	component string
+	next      slog.Handler
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.logs.enabled(h.component, level)
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs := attrsFrom(ctx); len(attrs) > 0 {
+		r = r.Clone()
+		r.AddAttrs(attrs...)
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &handler{logs: h.logs, component: h.component, next: h.next.WithAttrs(attrs)}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{logs: h.logs, component: h.component, next: h.next.WithGroup(name)}
+}
+
+type attrsKey struct{}
+
+// With returns a context whose records carry attrs, after any ctx already
+// carries. An attribute with the key of an earlier one replaces it.
+func With(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing := attrsFrom(ctx)
+	merged := make([]slog.Attr, 0, len(existing)+len(attrs))
+	for _, a := range existing {
+		if !hasKey(attrs, a.Key) {
+			merged = append(merged, a)
+		}
+	}
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, attrsKey{}, merged)
+}
+
+func attrsFrom(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+func hasKey(attrs []slog.Attr, key string) bool {
+	for _, a := range attrs {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
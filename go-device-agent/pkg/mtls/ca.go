@@ -0,0 +1,271 @@
+// Package mtls secures the network transports with mutual TLS. A small
+// certificate authority, kept in a directory on the agent, issues the
+// agent's server certificate and its clients' certificates; the server then
+// accepts only clients holding one, optionally pinned to specific keys.
+package mtls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Files in a CA directory. Issued certificates are written beside them as
+// <name>.pem and <name>-key.pem.
+const (
+	CACertFile = "ca.pem"
+	caKeyFile  = "ca-key.pem"
+)
+
+// Validity of what the CA issues, unless a Request says otherwise
+const (
+	caValidity   = 10 * 365 * 24 * time.Hour
+	certValidity = 90 * 24 * time.Hour
+)
+
+// CA issues certificates for the agent and its clients
+type CA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// LoadOrCreateCA reads the CA in dir, generating one named name on first
+// use. The private key is only ever written to dir, readable by its owner.
+func LoadOrCreateCA(dir, name string) (*CA, error) {
+	ca, err := LoadCA(dir)
+	if err == nil || !errors.Is(err, os.ErrNotExist) {
+		return ca, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFile(filepath.Join(dir, caKeyFile), keyPEM, 0600); err != nil {
+		return nil, err
+	}
+	if err := writeFile(filepath.Join(dir, CACertFile), encodeCert(der), 0644); err != nil {
+		return nil, err
+	}
+	return LoadCA(dir)
+}
+
+// LoadCA reads the CA certificate and key in dir
+func LoadCA(dir string) (*CA, error) {
+	cert, err := LoadCertificate(filepath.Join(dir, CACertFile))
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, caKeyFile))
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM key", caKeyFile)
+	}
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", caKeyFile, err)
+	}
+	if !key.PublicKey.Equal(cert.PublicKey) {
+		return nil, fmt.Errorf("%s does not match %s", caKeyFile, CACertFile)
+	}
+	return &CA{cert: cert, key: key}, nil
+}
+
+// Certificate returns the CA's certificate, which clients trust to verify
+// the agent and the agent trusts to verify clients
+func (ca *CA) Certificate() *x509.Certificate {
+	return ca.cert
+}
+
+// Request describes a certificate to issue
+type Request struct {
+	// Name is the certificate's common name. For client certificates it
+	// is the identity the client authenticates as (see access.Policy).
+	Name string
+
+	// Hosts are the DNS names and IP addresses a server certificate is
+	// valid for; a certificate without hosts is for clients only
+	Hosts []string
+
+	// Validity defaults to 90 days
+	Validity time.Duration
+}
+
+// Issue creates a key pair and certificate signed by the CA, returning both
+// PEM encoded
+func (ca *CA) Issue(req Request) (certPEM, keyPEM []byte, err error) {
+	if req.Name == "" {
+		return nil, nil, errors.New("certificate needs a name")
+	}
+	if req.Validity <= 0 {
+		req.Validity = certValidity
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: req.Name},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(req.Validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if template.NotAfter.After(ca.cert.NotAfter) {
+		template.NotAfter = ca.cert.NotAfter
+	}
+	for _, host := range req.Hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	if len(req.Hosts) > 0 {
+		template.ExtKeyUsage = append(template.ExtKeyUsage, x509.ExtKeyUsageServerAuth)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err = encodeKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encodeCert(der), keyPEM, nil
+}
+
+// IssueFiles issues a certificate and writes it to dir as <name>.pem and
+// <name>-key.pem, replacing any earlier one, and returns the certificate
+// path
+func (ca *CA) IssueFiles(dir string, req Request) (string, error) {
+	certPEM, keyPEM, err := ca.Issue(req)
+	if err != nil {
+		return "", err
+	}
+	certPath, keyPath := CertPaths(dir, req.Name)
+	// The key goes first so a reader never pairs the new certificate with
+	// the old key
+	if err := writeFile(keyPath, keyPEM, 0600); err != nil {
+		return "", err
+	}
+	if err := writeFile(certPath, certPEM, 0644); err != nil {
+		return "", err
+	}
+	return certPath, nil
+}
+
+// CertPaths returns where IssueFiles writes the certificate and key named
+// name
+func CertPaths(dir, name string) (certPath, keyPath string) {
+	return filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem")
+}
+
+// LoadCertificate reads the first certificate in a PEM file
+func LoadCertificate(path string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%s: no PEM certificate", path)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cert, nil
+}
+
+// Fingerprint identifies a certificate's key as "sha256:" and the hex
+// SHA-256 of its public key info. It survives reissuing a certificate for
+// the same key, and is what pins match.
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// normalizePin accepts a fingerprint with or without its "sha256:" prefix
+// and with or without colons between bytes
+func normalizePin(pin string) (string, error) {
+	pin = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(pin), "sha256:"))
+	pin = strings.ReplaceAll(pin, ":", "")
+	if b, err := hex.DecodeString(pin); err != nil || len(b) != sha256.Size {
+		return "", fmt.Errorf("pin %q is not a SHA-256 fingerprint", pin)
+	}
+	return "sha256:" + pin, nil
+}
+
+func newSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+func encodeCert(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func encodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+// writeFile replaces path atomically, so a server reloading it never reads
+// half a file
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
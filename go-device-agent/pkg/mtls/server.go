@@ -0,0 +1,143 @@
+package mtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ServerConfig is a TLS server that requires client certificates
+type ServerConfig struct {
+	// CAFile verifies client certificates
+	CAFile string
+
+	// CertFile and KeyFile are the server's certificate, reread when they
+	// change so it can be rotated without a restart
+	CertFile string
+	KeyFile  string
+
+	// Pins, if set, are the fingerprints (see Fingerprint) of the only
+	// client keys accepted, even among certificates the CA issued
+	Pins []string
+}
+
+// TLSConfig builds the server's TLS configuration
+func (c ServerConfig) TLSConfig() (*tls.Config, error) {
+	caPEM, err := os.ReadFile(c.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("%s: no PEM certificates", c.CAFile)
+	}
+	pins := make([]string, 0, len(c.Pins))
+	for _, p := range c.Pins {
+		pin, err := normalizePin(p)
+		if err != nil {
+			return nil, err
+		}
+		pins = append(pins, pin)
+	}
+	keyPair := &keyPairReloader{certFile: c.CertFile, keyFile: c.KeyFile}
+	if _, err := keyPair.get(); err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		ClientAuth:     tls.RequireAndVerifyClientCert,
+		ClientCAs:      pool,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return keyPair.get() },
+	}
+	if len(pins) > 0 {
+		// Runs after the chain is verified against the CA
+		config.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("no client certificate")
+			}
+			if fp := Fingerprint(cs.PeerCertificates[0]); !slices.Contains(pins, fp) {
+				return fmt.Errorf("client certificate %s (%s) is not pinned", cs.PeerCertificates[0].Subject.CommonName, fp)
+			}
+			return nil
+		}
+	}
+	return config, nil
+}
+
+// keyPairReloader serves a certificate and key from disk, rereading them
+// when the certificate file's modification time changes
+type keyPairReloader struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+// reloadCheck is how often get looks at the certificate file
+const reloadCheck = 10 * time.Second
+
+func (r *keyPairReloader) get() (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cert != nil && time.Since(r.checked) < reloadCheck {
+		return r.cert, nil
+	}
+	r.checked = time.Now()
+	info, err := os.Stat(r.certFile)
+	if err != nil {
+		if r.cert != nil {
+			return r.cert, nil
+		}
+		return nil, err
+	}
+	if r.cert != nil && info.ModTime().Equal(r.modTime) {
+		return r.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		// Keep serving the previous pair until the new one is whole
+		if r.cert != nil {
+			return r.cert, nil
+		}
+		return nil, err
+	}
+	r.cert, r.modTime = &cert, info.ModTime()
+	return r.cert, nil
+}
+
+// renewBefore is how long before expiry EnsureCert reissues a certificate
+const renewBefore = 30 * 24 * time.Hour
+
+// EnsureCert issues the certificate req names into dir if it is missing,
+// expires within 30 days, or no longer covers req.Hosts, returning its
+// paths and whether it was (re)issued
+func (ca *CA) EnsureCert(dir string, req Request) (certPath, keyPath string, issued bool, err error) {
+	certPath, keyPath = CertPaths(dir, req.Name)
+	if cert, err := LoadCertificate(certPath); err == nil && time.Until(cert.NotAfter) > renewBefore && covers(cert, req.Hosts) {
+		if _, err := os.Stat(keyPath); err == nil {
+			return certPath, keyPath, false, nil
+		}
+	}
+	if _, err := ca.IssueFiles(dir, req); err != nil {
+		return "", "", false, err
+	}
+	return certPath, keyPath, true, nil
+}
+
+// covers reports whether cert is valid for every host
+func covers(cert *x509.Certificate, hosts []string) bool {
+	for _, h := range hosts {
+		if cert.VerifyHostname(h) != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,292 @@
+// Package quota bounds how many intents of each type run per hour and per
+// day, such as at most 20 notification.send an hour or 5 system.run a day,
+// so a runaway agent loop can only do so much damage. Counts are kept in a
+// state file, so restarting the agent doesn't reset them.
+package quota
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/access"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+)
+
+// Limit is one quota, shared by all the intent types it lists. Hours and
+// days are calendar hours and days in local time.
+type Limit struct {
+	// Name identifies the quota in errors and quota.status (default its
+	// intent types joined with commas)
+	Name string `json:"name,omitempty"`
+
+	// IntentTypes lists the intent types (or module.*) counted
+	IntentTypes []string `json:"intent_types"`
+
+	// PerHour and PerDay are the most intents allowed (0 for no limit)
+	PerHour int `json:"per_hour,omitempty"`
+	PerDay  int `json:"per_day,omitempty"`
+}
+
+// Config lists the quotas. An intent counts towards every quota matching
+// it, and runs only if all of them have room.
+//
+//	{
+//	  "quotas": [
+//	    {"intent_types": ["notification.send"], "per_hour": 20},
+//	    {"intent_types": ["system.run"], "per_day": 5},
+//	    {"name": "everything", "intent_types": ["*"], "per_hour": 500}
+//	  ]
+//	}
+type Config struct {
+	Quotas []Limit `json:"quotas"`
+
+	// StateFile keeps the counts across restarts
+	StateFile string `json:"-"`
+}
+
+// LoadConfig reads a JSON quota config
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid quota config %s: %w", path, err)
+	}
+	if err := c.check(); err != nil {
+		return nil, fmt.Errorf("invalid quota config %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+func (c *Config) check() error {
+	names := make(map[string]bool, len(c.Quotas))
+	for n := range c.Quotas {
+		q := &c.Quotas[n]
+		if len(q.IntentTypes) == 0 {
+			return fmt.Errorf("quota %d needs intent_types", n)
+		}
+		if q.PerHour < 0 || q.PerDay < 0 || q.PerHour+q.PerDay == 0 {
+			return fmt.Errorf("quota %d needs a positive per_hour or per_day", n)
+		}
+		if q.Name == "" {
+			q.Name = strings.Join(q.IntentTypes, ",")
+		}
+		if names[q.Name] {
+			return fmt.Errorf("quota %s is defined twice", q.Name)
+		}
+		names[q.Name] = true
+	}
+	return nil
+}
+
+// Status is how much of a quota is used
+type Status struct {
+	Limit
+	UsedHour   int       `json:"used_hour"`
+	UsedDay    int       `json:"used_day"`
+	HourResets time.Time `json:"hour_resets"`
+	DayResets  time.Time `json:"day_resets"`
+}
+
+// counter is a quota's counts in the current hour and day
+type counter struct {
+	Hour      time.Time `json:"hour"`
+	HourCount int       `json:"hour_count"`
+	Day       time.Time `json:"day"`
+	DayCount  int       `json:"day_count"`
+}
+
+// roll starts new counts once now is past the counted hour or day
+func (c *counter) roll(now time.Time) {
+	hour, day := startOfHour(now), startOfDay(now)
+	if !c.Hour.Equal(hour) {
+		c.Hour, c.HourCount = hour, 0
+	}
+	if !c.Day.Equal(day) {
+		c.Day, c.DayCount = day, 0
+	}
+}
+
+func startOfHour(t time.Time) time.Time {
+	t = t.Local()
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, time.Local)
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.Local()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// Tracker counts intents against the quotas
+type Tracker struct {
+	cfg Config
+
+	// OnExceeded, if set, is called the first time in an hour or day that
+	// a quota refuses an intent, e.g. to warn that an agent loop is
+	// running away
+	OnExceeded func(name, intentType string, resets time.Time)
+
+	mu       sync.Mutex
+	counters map[string]*counter
+	warned   map[string]time.Time // quota name to the reset last warned of
+}
+
+// New creates a tracker, resuming the counts in cfg.StateFile if it exists
+func New(cfg Config) (*Tracker, error) {
+	if err := cfg.check(); err != nil {
+		return nil, err
+	}
+	saved := make(map[string]*counter)
+	if cfg.StateFile != "" {
+		data, err := os.ReadFile(cfg.StateFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, &saved); err != nil {
+				return nil, fmt.Errorf("invalid quota state %s: %w", cfg.StateFile, err)
+			}
+		}
+	}
+	// Counts of quotas no longer configured are dropped
+	t := &Tracker{cfg: cfg, counters: make(map[string]*counter), warned: make(map[string]time.Time)}
+	for _, q := range cfg.Quotas {
+		t.counters[q.Name] = &counter{}
+		if c := saved[q.Name]; c != nil {
+			t.counters[q.Name] = c
+		}
+	}
+	return t, nil
+}
+
+// SetQuotas replaces the quotas, keeping the counts of those still
+// configured under the same name
+func (t *Tracker) SetQuotas(quotas []Limit) error {
+	cfg := Config{Quotas: quotas, StateFile: t.cfg.StateFile}
+	if err := cfg.check(); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	counters := make(map[string]*counter, len(cfg.Quotas))
+	for _, q := range cfg.Quotas {
+		counters[q.Name] = &counter{}
+		if c := t.counters[q.Name]; c != nil {
+			counters[q.Name] = c
+		}
+	}
+	t.cfg, t.counters = cfg, counters
+	return t.save()
+}
+
+// Take counts an intent of the given type at now against every quota
+// matching it. If any of them is used up nothing is counted, and it fails
+// with QUOTA_EXCEEDED saying when the quota resets.
+func (t *Tracker) Take(intentType string, now time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var matched []*counter
+	for _, q := range t.cfg.Quotas {
+		if !matchAny(q.IntentTypes, intentType) {
+			continue
+		}
+		c := t.counters[q.Name]
+		c.roll(now)
+		var resets time.Time
+		var period string
+		switch {
+		case q.PerHour > 0 && c.HourCount >= q.PerHour:
+			resets, period = c.Hour.Add(time.Hour), fmt.Sprintf("%d per hour", q.PerHour)
+		case q.PerDay > 0 && c.DayCount >= q.PerDay:
+			resets, period = c.Day.AddDate(0, 0, 1), fmt.Sprintf("%d per day", q.PerDay)
+		default:
+			matched = append(matched, c)
+			continue
+		}
+		if t.OnExceeded != nil && !t.warned[q.Name].Equal(resets) {
+			t.warned[q.Name] = resets
+			go t.OnExceeded(q.Name, intentType, resets)
+		}
+		return agenterrors.Newf(agenterrors.QuotaExceeded,
+			"quota %s (%s) is used up; it resets at %s", q.Name, period, resets.Format("Jan 2 15:04"))
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+	for _, c := range matched {
+		c.HourCount++
+		c.DayCount++
+	}
+	// If saving fails counting carries on in memory; the next intent
+	// tries again
+	_ = t.save()
+	return nil
+}
+
+// Status reports every quota's use at now, in config order, or only those
+// counting intentType if it is set
+func (t *Tracker) Status(intentType string, now time.Time) []Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	statuses := []Status{}
+	for _, q := range t.cfg.Quotas {
+		if intentType != "" && !matchAny(q.IntentTypes, intentType) {
+			continue
+		}
+		c := *t.counters[q.Name]
+		c.roll(now)
+		statuses = append(statuses, Status{
+			Limit:      q,
+			UsedHour:   c.HourCount,
+			UsedDay:    c.DayCount,
+			HourResets: c.Hour.Add(time.Hour),
+			DayResets:  c.Day.AddDate(0, 0, 1),
+		})
+	}
+	return statuses
+}
+
+// Save writes the counts to the state file. They are also saved as each
+// intent is counted.
+func (t *Tracker) Save() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.save()
+}
+
+func (t *Tracker) save() error {
+	if t.cfg.StateFile == "" {
+		return nil
+	}
+	data, err := json.Marshal(t.counters)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.cfg.StateFile), 0o700); err != nil {
+		return err
+	}
+	// Written atomically so a crash never leaves a torn file
+	tmp := t.cfg.StateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, t.cfg.StateFile)
+}
+
+func matchAny(patterns []string, intentType string) bool {
+	for _, p := range patterns {
+		if access.MatchIntentType(p, intentType) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+// Package registry tracks the devices known to the device agent
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Kind classifies a device
+type Kind string
+
+// Known device kinds
+const (
+	KindLight         Kind = "light"
+	KindSwitch        Kind = "switch"
+	KindContactSensor Kind = "contact_sensor"
+	KindLock          Kind = "lock"
+	KindCamera        Kind = "camera"
+	KindThermostat    Kind = "thermostat"
+	KindSensor        Kind = "sensor"
+)
+
+// Device is a single device and its last known state.
+// State keys depend on the kind, e.g. "open" for contact sensors,
+// "locked" for locks, and "online" for cameras.
+type Device struct {
+	ID        string                 `json:"id"`
+	Name      string                 `json:"name"`
+	Kind      Kind                   `json:"kind"`
+	Room      string                 `json:"room,omitempty"`
+	Module    string                 `json:"module,omitempty"`
+	State     map[string]interface{} `json:"state,omitempty"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+// Registry is a concurrency-safe device store
+type Registry struct {
+	devices  map[string]*Device
+	watchers []func()
+	mu       sync.RWMutex
+}
+
+// New creates an empty registry
+func New() *Registry {
+	return &Registry{
+		devices: make(map[string]*Device),
+	}
+}
+
+// Upsert adds a device or replaces an existing one with the same ID
+func (r *Registry) Upsert(d Device) {
+	r.mu.Lock()
+	if d.UpdatedAt.IsZero() {
+		d.UpdatedAt = time.Now()
+	}
+	d.State = copyState(d.State)
+	old, existed := r.devices[d.ID]
+	changed := !existed || old.Name != d.Name || old.Kind != d.Kind || old.Room != d.Room || old.Module != d.Module
+	r.devices[d.ID] = &d
+	watchers := r.watchers
+	r.mu.Unlock()
+
+	if changed {
+		notify(watchers)
+	}
+}
+
+// Remove deletes a device, reporting whether it existed
+func (r *Registry) Remove(id string) bool {
+	r.mu.Lock()
+	_, ok := r.devices[id]
+	delete(r.devices, id)
+	watchers := r.watchers
+	r.mu.Unlock()
+
+	if ok {
+		notify(watchers)
+	}
+	return ok
+}
+
+// Watch calls fn whenever a device is added or removed, or its name, kind,
+// room, or module changes; state updates don't count. fn runs on the
+// caller's goroutine after the change is made.
+func (r *Registry) Watch(fn func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.watchers = append(r.watchers, fn)
+}
+
+func notify(watchers []func()) {
+	for _, fn := range watchers {
+		fn()
+	}
+}
+
+// Get returns a copy of a device
+func (r *Registry) Get(id string) (Device, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	d, ok := r.devices[id]
+	if !ok {
+		return Device{}, false
+	}
+	return d.clone(), true
+}
+
+// UpdateState merges state into a device's current state
+func (r *Registry) UpdateState(id string, state map[string]interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, ok := r.devices[id]
+	if !ok {
+		return fmt.Errorf("unknown device: %s", id)
+	}
+	if d.State == nil {
+		d.State = make(map[string]interface{}, len(state))
+	}
+	for k, v := range state {
+		d.State[k] = v
+	}
+	d.UpdatedAt = time.Now()
+	return nil
+}
+
+// List returns copies of all devices sorted by ID
+func (r *Registry) List() []Device {
+	return r.ByKind()
+}
+
+// ByKind returns copies of the devices of the given kinds sorted by ID.
+// With no kinds every device is returned.
+func (r *Registry) ByKind(kinds ...Kind) []Device {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	devices := make([]Device, 0, len(r.devices))
+	for _, d := range r.devices {
+		if len(kinds) > 0 && !containsKind(kinds, d.Kind) {
+			continue
+		}
+		devices = append(devices, d.clone())
+	}
+	sort.Slice(devices, func(a, b int) bool {
+		return devices[a].ID < devices[b].ID
+	})
+	return devices
+}
+
+func (d *Device) clone() Device {
+	c := *d
+	c.State = copyState(d.State)
+	return c
+}
+
+func copyState(state map[string]interface{}) map[string]interface{} {
+	if state == nil {
+		return nil
+	}
+	c := make(map[string]interface{}, len(state))
+	for k, v := range state {
+		c[k] = v
+	}
+	return c
+}
+
+func containsKind(kinds []Kind, kind Kind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,203 @@
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// systemPaths are read by every process: libraries, configuration such as
+// resolv.conf and CA bundles, time zones, and the kernel's own views
+var systemPaths = []string{"/bin", "/sbin", "/usr", "/lib", "/lib32", "/lib64", "/opt", "/etc", "/proc", "/sys", "/run"}
+
+// Landlock access rights by the ABI version that introduced them
+const (
+	accessABI1 = unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_READ_DIR |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_DIR | unix.LANDLOCK_ACCESS_FS_REMOVE_FILE |
+		unix.LANDLOCK_ACCESS_FS_MAKE_CHAR | unix.LANDLOCK_ACCESS_FS_MAKE_DIR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_REG | unix.LANDLOCK_ACCESS_FS_MAKE_SOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_FIFO | unix.LANDLOCK_ACCESS_FS_MAKE_BLOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SYM
+
+	accessRead = unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_READ_DIR
+
+	// accessFile are the rights that apply to files rather than directories
+	accessFile = unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_TRUNCATE | unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+)
+
+// Enforce confines the agent process, and the programs it starts, to what
+// n declares. With Landlock (Linux 5.13 and later) files outside the
+// system directories, the declared paths, /dev, and the temporary
+// directory can't be opened; n's paths should include the agent's own
+// state and configuration. Unless n needs to run programs, seccomp blocks
+// execve. Files already open are unaffected. The network isn't confined
+// here: executors that may not use it are refused at registration.
+func Enforce(n Needs) (*Report, error) {
+	report := &Report{}
+
+	// Both need no_new_privs; it is set on every thread when the runtime
+	// allows, which it doesn't in cgo builds, and otherwise on this one
+	// for seccomp to spread from
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	allThreads := true
+	if _, _, e := syscall.AllThreadsSyscall(syscall.SYS_PRCTL, unix.PR_SET_NO_NEW_PRIVS, 1, 0); e != 0 {
+		if e != syscall.ENOTSUP {
+			return nil, fmt.Errorf("no_new_privs: %w", e)
+		}
+		allThreads = false
+		if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+			return nil, fmt.Errorf("no_new_privs: %w", err)
+		}
+	}
+
+	if !allThreads {
+		report.Skipped = append(report.Skipped, "files: Landlock needs a build with CGO_ENABLED=0")
+	} else if abi, err := landlock(n); err != nil {
+		return nil, err
+	} else if abi == 0 {
+		report.Skipped = append(report.Skipped, "files: the kernel doesn't support Landlock")
+	} else {
+		report.Landlock = abi
+	}
+
+	if n.Subprocess {
+		report.Skipped = append(report.Skipped, "programs: an executor needs to run them")
+	} else if skipped, err := blockExec(); err != nil {
+		return nil, err
+	} else if skipped != "" {
+		report.Skipped = append(report.Skipped, "programs: "+skipped)
+	} else {
+		report.NoExec = true
+	}
+	return report, nil
+}
+
+// landlock restricts every thread to reading the system paths and n.Read,
+// and writing n.Write, /dev, and the temporary directory. It returns the
+// ABI version used, or 0 if the kernel has no Landlock.
+func landlock(n Needs) (int, error) {
+	version, _, e := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if e != 0 {
+		if e == unix.ENOSYS || e == unix.EOPNOTSUPP {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("landlock: %w", e)
+	}
+	abi := int(version)
+	handled := uint64(accessABI1)
+	if abi >= 2 {
+		handled |= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+	if abi >= 3 {
+		handled |= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+	if abi >= 5 {
+		handled |= unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+	}
+
+	attr := unix.LandlockRulesetAttr{Access_fs: handled}
+	fd, _, e := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if e != 0 {
+		return 0, fmt.Errorf("landlock: %w", e)
+	}
+	ruleset := int(fd)
+	defer unix.Close(ruleset)
+
+	devices := uint64(accessRead | unix.LANDLOCK_ACCESS_FS_WRITE_FILE | unix.LANDLOCK_ACCESS_FS_IOCTL_DEV)
+	for _, path := range append(systemPaths, n.Read...) {
+		if err := allow(ruleset, path, accessRead&handled); err != nil {
+			return 0, err
+		}
+	}
+	if err := allow(ruleset, "/dev", devices&handled); err != nil {
+		return 0, err
+	}
+	for _, path := range append([]string{os.TempDir()}, n.Write...) {
+		if err := allow(ruleset, path, handled); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, _, e := syscall.AllThreadsSyscall(unix.SYS_LANDLOCK_RESTRICT_SELF, uintptr(ruleset), 0, 0); e != 0 {
+		return 0, fmt.Errorf("landlock: %w", e)
+	}
+	return abi, nil
+}
+
+// allow adds a rule giving access beneath path; paths that don't exist
+// are skipped, as there is nothing there to allow
+func allow(ruleset int, path string, access uint64) error {
+	fd, err := unix.Open(absolute(path), unix.O_PATH|unix.O_CLOEXEC, 0)
+	if errors.Is(err, unix.ENOENT) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("landlock: %s: %w", path, err)
+	}
+	defer unix.Close(fd)
+	var st unix.Stat_t
+	if err := unix.Fstat(fd, &st); err != nil {
+		return fmt.Errorf("landlock: %s: %w", path, err)
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFDIR {
+		access &= accessFile
+	}
+	rule := unix.LandlockPathBeneathAttr{Allowed_access: access, Parent_fd: int32(fd)}
+	if _, _, e := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(ruleset), unix.LANDLOCK_RULE_PATH_BENEATH,
+		uintptr(unsafe.Pointer(&rule)), 0, 0, 0); e != 0 {
+		return fmt.Errorf("landlock: %s: %w", path, e)
+	}
+	return nil
+}
+
+// auditArch identifies this architecture's system calls to seccomp
+var auditArch = map[string]uint32{
+	"386":     unix.AUDIT_ARCH_I386,
+	"amd64":   unix.AUDIT_ARCH_X86_64,
+	"arm":     unix.AUDIT_ARCH_ARM,
+	"arm64":   unix.AUDIT_ARCH_AARCH64,
+	"riscv64": unix.AUDIT_ARCH_RISCV64,
+}
+
+// blockExec installs a seccomp filter on every thread failing execve and
+// execveat with EPERM. System calls of other architectures (32-bit
+// compatibility calls, x32) fail too, as nothing in the agent makes them.
+// It returns why no filter was installed if the system can't have one.
+func blockExec() (string, error) {
+	arch, ok := auditArch[runtime.GOARCH]
+	if !ok {
+		return "seccomp filters aren't supported on " + runtime.GOARCH, nil
+	}
+	const x32 = 0x40000000
+	deny := uint32(unix.SECCOMP_RET_ERRNO | uint32(unix.EPERM))
+	filter := []unix.SockFilter{
+		{Code: unix.BPF_LD | unix.BPF_W | unix.BPF_ABS, K: 4}, // seccomp_data.arch
+		{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, K: arch, Jt: 0, Jf: 5},
+		{Code: unix.BPF_LD | unix.BPF_W | unix.BPF_ABS, K: 0}, // seccomp_data.nr
+		{Code: unix.BPF_JMP | unix.BPF_JGE | unix.BPF_K, K: x32, Jt: 3, Jf: 0},
+		{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, K: unix.SYS_EXECVE, Jt: 2, Jf: 0},
+		{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, K: unix.SYS_EXECVEAT, Jt: 1, Jf: 0},
+		{Code: unix.BPF_RET | unix.BPF_K, K: unix.SECCOMP_RET_ALLOW},
+		{Code: unix.BPF_RET | unix.BPF_K, K: deny},
+	}
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	r, _, e := unix.Syscall(unix.SYS_SECCOMP, unix.SECCOMP_SET_MODE_FILTER, unix.SECCOMP_FILTER_FLAG_TSYNC, uintptr(unsafe.Pointer(&prog)))
+	if e != 0 {
+		if e == unix.ENOSYS || e == unix.EINVAL {
+			return "the kernel doesn't support seccomp filters", nil
+		}
+		return "", fmt.Errorf("seccomp: %w", e)
+	}
+	if r != 0 {
+		return "", fmt.Errorf("seccomp: thread %d couldn't be synchronized", r)
+	}
+	return "", nil
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package sandbox
+
+import "runtime"
+
+// Enforce confines the agent process where the OS allows it; only Linux
+// (Landlock and seccomp) is supported, so elsewhere it reports what it
+// skipped
+func Enforce(n Needs) (*Report, error) {
+	return &Report{Skipped: []string{"files and programs: not supported on " + runtime.GOOS}}, nil
+}
@@ -0,0 +1,161 @@
+// Package sandbox describes what executors need from the system outside
+// the agent (the network, files and directories, other programs) so the
+// gateway can refuse executors a policy doesn't allow, and so the agent
+// process can be confined to what its registered executors declared.
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Needs is what an executor declares it needs. Paths are files or
+// directories; a directory covers everything beneath it. State files that
+// are replaced atomically need their directory written, not just the file.
+type Needs struct {
+	Network    bool     `json:"network,omitempty"`
+	Read       []string `json:"read,omitempty"`
+	Write      []string `json:"write,omitempty"`
+	Subprocess bool     `json:"subprocess,omitempty"`
+}
+
+// Merge returns the needs of all the given needs together
+func Merge(needs ...Needs) Needs {
+	var all Needs
+	for _, n := range needs {
+		all.Network = all.Network || n.Network
+		all.Subprocess = all.Subprocess || n.Subprocess
+		all.Read = appendNew(all.Read, n.Read...)
+		all.Write = appendNew(all.Write, n.Write...)
+	}
+	return all
+}
+
+func appendNew(list []string, paths ...string) []string {
+	for _, p := range paths {
+		if p != "" && !slices.Contains(list, p) {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+// Grant is what a policy allows an executor
+type Grant struct {
+	Network    bool `json:"network"`
+	Subprocess bool `json:"subprocess"`
+
+	// Read and Write are the paths declared paths must lie beneath;
+	// writable paths may be read too
+	Read  []string `json:"read,omitempty"`
+	Write []string `json:"write,omitempty"`
+}
+
+// Policy decides which executors may be registered, by what they declare.
+// An executor is checked against its own entry in Executors, or Default if
+// it has none.
+//
+//	{
+//	  "require_declaration": true,
+//	  "default": {"network": true, "write": ["/var/lib/device-agent"]},
+//	  "executors": {
+//	    "system": {"subprocess": true, "write": ["/var/lib/device-agent"]},
+//	    "file": {"read": ["/home/me/Documents"], "write": ["/home/me/notes"]}
+//	  },
+//	  "deny": ["input"]
+//	}
+type Policy struct {
+	// RequireDeclaration refuses executors that declare no needs at all
+	RequireDeclaration bool `json:"require_declaration"`
+
+	Default   Grant            `json:"default"`
+	Executors map[string]Grant `json:"executors,omitempty"`
+
+	// Deny lists executors refused whatever they declare
+	Deny []string `json:"deny,omitempty"`
+}
+
+// LoadPolicy reads a JSON sandbox policy
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid sandbox policy %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// Check returns why the named executor may not be registered with the
+// needs it declared, or nil if it may. declared is false for executors
+// that don't declare their needs.
+func (p *Policy) Check(name string, n Needs, declared bool) error {
+	if slices.Contains(p.Deny, name) {
+		return fmt.Errorf("%s is denied by the sandbox policy", name)
+	}
+	if !declared {
+		if p.RequireDeclaration {
+			return fmt.Errorf("%s doesn't declare what it needs", name)
+		}
+		return nil
+	}
+	grant, ok := p.Executors[name]
+	if !ok {
+		grant = p.Default
+	}
+	if n.Network && !grant.Network {
+		return fmt.Errorf("%s needs the network, which the sandbox policy doesn't allow it", name)
+	}
+	if n.Subprocess && !grant.Subprocess {
+		return fmt.Errorf("%s needs to run programs, which the sandbox policy doesn't allow it", name)
+	}
+	for _, path := range n.Write {
+		if !within(path, grant.Write) {
+			return fmt.Errorf("%s needs to write %s, outside what the sandbox policy allows it", name, path)
+		}
+	}
+	for _, path := range n.Read {
+		if !within(path, grant.Read) && !within(path, grant.Write) {
+			return fmt.Errorf("%s needs to read %s, outside what the sandbox policy allows it", name, path)
+		}
+	}
+	return nil
+}
+
+// within reports whether path is one of dirs or lies beneath one
+func within(path string, dirs []string) bool {
+	path = absolute(path)
+	for _, dir := range dirs {
+		rel, err := filepath.Rel(absolute(dir), path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func absolute(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// Report says how far Enforce could confine the process
+type Report struct {
+	// Landlock is the Landlock ABI version file access was limited with,
+	// or 0 if it wasn't
+	Landlock int
+
+	// NoExec is set when starting other programs was blocked with seccomp
+	NoExec bool
+
+	// Skipped says what couldn't be enforced, and why
+	Skipped []string
+}
@@ -0,0 +1,43 @@
+package secrets
+
+import (
+	"os"
+	"strings"
+)
+
+// Env reads secrets from environment variables: smtp/password is
+// SECRET_SMTP_PASSWORD, with letters upper-cased and other characters
+// replaced by underscores
+type Env struct {
+	// Prefix defaults to "SECRET_"
+	Prefix string
+}
+
+func (e Env) Name() string {
+	return "env"
+}
+
+func (e Env) Lookup(name string) (string, error) {
+	value, ok := os.LookupEnv(e.Variable(name))
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// Variable returns the environment variable holding the named secret
+func (e Env) Variable(name string) string {
+	prefix := e.Prefix
+	if prefix == "" {
+		prefix = "SECRET_"
+	}
+	return prefix + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,207 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// Key derivation for the file backend. The iteration count is stored in
+// each file, so raising it later doesn't break older files.
+const (
+	kdfName       = "pbkdf2-sha256"
+	kdfIterations = 600_000
+	fileVersion   = 1
+)
+
+// ErrWrongPassphrase is returned when a secrets file doesn't decrypt
+var ErrWrongPassphrase = errors.New("wrong passphrase, or the secrets file was altered")
+
+// fileFormat is a secrets file on disk. The secrets are a JSON object of
+// names to values sealed with AES-256-GCM under a key derived from the
+// passphrase; the header fields are authenticated along with them.
+type fileFormat struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// header is the additional data binding the header fields to the
+// ciphertext
+func (f *fileFormat) header() []byte {
+	return fmt.Appendf(nil, "%d|%s|%d|%x", f.Version, f.KDF, f.Iterations, f.Salt)
+}
+
+// File keeps secrets encrypted at rest in a file readable only by its
+// owner, decrypting them into memory when opened
+type File struct {
+	path string
+
+	mu         sync.RWMutex
+	aead       cipher.AEAD
+	salt       []byte
+	iterations int
+	values     map[string]string
+}
+
+// OpenFile decrypts the secrets file at path with passphrase. A missing
+// file is an empty store, created on the first Set.
+func OpenFile(path, passphrase string) (*File, error) {
+	if passphrase == "" {
+		return nil, errors.New("secrets file needs a passphrase")
+	}
+	f := &File{path: path, values: make(map[string]string)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		f.salt = make([]byte, 16)
+		if _, err := rand.Read(f.salt); err != nil {
+			return nil, err
+		}
+		f.iterations = kdfIterations
+		if f.aead, err = deriveAEAD(passphrase, f.salt, f.iterations); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var format fileFormat
+	if err := json.Unmarshal(data, &format); err != nil {
+		return nil, fmt.Errorf("invalid secrets file %s: %w", path, err)
+	}
+	if format.Version != fileVersion || format.KDF != kdfName || format.Iterations <= 0 {
+		return nil, fmt.Errorf("invalid secrets file %s: unsupported version %d with %s", path, format.Version, format.KDF)
+	}
+	f.salt, f.iterations = format.Salt, format.Iterations
+	if f.aead, err = deriveAEAD(passphrase, f.salt, f.iterations); err != nil {
+		return nil, err
+	}
+	plain, err := f.aead.Open(nil, format.Nonce, format.Ciphertext, format.header())
+	if err != nil {
+		return nil, fmt.Errorf("secrets file %s: %w", path, ErrWrongPassphrase)
+	}
+	if err := json.Unmarshal(plain, &f.values); err != nil {
+		return nil, fmt.Errorf("invalid secrets file %s: %w", path, err)
+	}
+	return f, nil
+}
+
+func deriveAEAD(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (f *File) Name() string {
+	return "file"
+}
+
+func (f *File) Lookup(name string) (string, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	value, ok := f.values[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// Names lists the secrets in the file, sorted
+func (f *File) Names() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	names := make([]string, 0, len(f.values))
+	for name := range f.values {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Set stores a secret and rewrites the file
+func (f *File) Set(name, value string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid secret name %q", name)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	previous, had := f.values[name]
+	f.values[name] = value
+	if err := f.save(); err != nil {
+		if had {
+			f.values[name] = previous
+		} else {
+			delete(f.values, name)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes a secret and rewrites the file
+func (f *File) Delete(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	previous, ok := f.values[name]
+	if !ok {
+		return fmt.Errorf("secret %s: %w", name, ErrNotFound)
+	}
+	delete(f.values, name)
+	if err := f.save(); err != nil {
+		f.values[name] = previous
+		return err
+	}
+	return nil
+}
+
+// save seals the secrets under a fresh nonce and replaces the file
+func (f *File) save() error {
+	plain, err := json.Marshal(f.values)
+	if err != nil {
+		return err
+	}
+	format := fileFormat{
+		Version:    fileVersion,
+		KDF:        kdfName,
+		Iterations: f.iterations,
+		Salt:       f.salt,
+		Nonce:      make([]byte, f.aead.NonceSize()),
+	}
+	if _, err := rand.Read(format.Nonce); err != nil {
+		return err
+	}
+	format.Ciphertext = f.aead.Seal(nil, format.Nonce, plain, format.header())
+	data, err := json.MarshalIndent(format, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := f.path + ".tmp" + strconv.Itoa(os.Getpid())
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
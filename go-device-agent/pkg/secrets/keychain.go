@@ -0,0 +1,25 @@
+package secrets
+
+// Keychain reads secrets from the OS credential store: the Secret Service
+// (GNOME Keyring, KWallet) through secret-tool on Linux, the login keychain
+// on macOS, and Credential Manager on Windows. Secrets are stored under
+// the service name with the secret's name as the account, e.g.
+//
+//	secret-tool store --label=smtp service device-agent account smtp/password
+//	security add-generic-password -s device-agent -a smtp/password -w
+//	cmdkey /generic:device-agent/smtp/password /user:device-agent /pass
+type Keychain struct {
+	// Service defaults to "device-agent"
+	Service string
+}
+
+func (k Keychain) Name() string {
+	return "keychain"
+}
+
+func (k Keychain) service() string {
+	if k.Service == "" {
+		return "device-agent"
+	}
+	return k.Service
+}
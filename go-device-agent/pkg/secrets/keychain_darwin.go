@@ -0,0 +1,27 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// itemNotFound is the exit status security gives when no item matches
+const itemNotFound = 44
+
+func (k Keychain) Lookup(name string) (string, error) {
+	cmd := exec.Command("security", "find-generic-password", "-s", k.service(), "-a", name, "-w")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) && exit.ExitCode() == itemNotFound {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("security: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	// security adds a newline after the password
+	return strings.TrimSuffix(stdout.String(), "\n"), nil
+}
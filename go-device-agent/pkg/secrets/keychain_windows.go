@@ -0,0 +1,66 @@
+package secrets
+
+import (
+	"errors"
+	"unicode/utf16"
+	"unicode/utf8"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const credTypeGeneric = 1
+
+var (
+	advapi32 = windows.NewLazySystemDLL("advapi32.dll")
+
+	credRead = advapi32.NewProc("CredReadW")
+	credFree = advapi32.NewProc("CredFree")
+)
+
+// credential is the CREDENTIALW structure
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        windows.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+func (k Keychain) Lookup(name string) (string, error) {
+	target, err := windows.UTF16PtrFromString(k.service() + "/" + name)
+	if err != nil {
+		return "", err
+	}
+	var cred *credential
+	r, _, err := credRead.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if r == 0 {
+		if errors.Is(err, windows.ERROR_NOT_FOUND) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	defer credFree.Call(uintptr(unsafe.Pointer(cred)))
+	blob := unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)
+	return decodeBlob(blob), nil
+}
+
+// decodeBlob reads a credential's secret, which cmdkey and Credential
+// Manager store as UTF-16 and other tools as UTF-8
+func decodeBlob(blob []byte) string {
+	if len(blob)%2 == 0 && len(blob) > 0 && (!utf8.Valid(blob) || blob[1] == 0) {
+		units := make([]uint16, len(blob)/2)
+		for n := range units {
+			units[n] = uint16(blob[2*n]) | uint16(blob[2*n+1])<<8
+		}
+		return string(utf16.Decode(units))
+	}
+	return string(blob)
+}
@@ -0,0 +1,149 @@
+// Package secrets resolves the credentials executors need, such as SMTP
+// passwords and API keys, so configs can name them instead of holding them.
+// A config value of secret://smtp/password is looked up in the store's
+// backends in order: an encrypted file, the OS keychain, and the
+// environment.
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Scheme prefixes a reference to a secret
+const Scheme = "secret://"
+
+// ErrNotFound is returned by backends that don't hold a secret
+var ErrNotFound = errors.New("secret not found")
+
+// namePattern is slash-separated segments, e.g. "smtp/password"
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*(/[A-Za-z0-9][A-Za-z0-9_.-]*)*$`)
+
+// Backend holds secrets by name
+type Backend interface {
+	Name() string
+
+	// Lookup returns the secret, or ErrNotFound
+	Lookup(name string) (string, error)
+}
+
+// Store looks secrets up in its backends, the first holding one winning
+type Store struct {
+	backends []Backend
+}
+
+// New creates a store searching backends in order
+func New(backends ...Backend) *Store {
+	return &Store{backends: backends}
+}
+
+// Default is the store Resolve uses, reading only the environment unless
+// the agent is configured with more backends
+var Default = New(Env{})
+
+// Backends names the store's backends in search order
+func (s *Store) Backends() []string {
+	names := make([]string, len(s.backends))
+	for n, b := range s.backends {
+		names[n] = b.Name()
+	}
+	return names
+}
+
+// Get returns the named secret
+func (s *Store) Get(name string) (string, error) {
+	if !namePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid secret name %q", name)
+	}
+	for _, b := range s.backends {
+		value, err := b.Lookup(name)
+		if err == nil {
+			return value, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return "", fmt.Errorf("secret %s: %s: %w", name, b.Name(), err)
+		}
+	}
+	return "", fmt.Errorf("secret %s: %w (looked in %s)", name, ErrNotFound, strings.Join(s.Backends(), ", "))
+}
+
+// IsReference reports whether value names a secret rather than holding
+// one
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, Scheme)
+}
+
+// Resolve returns the secret value references, or value itself if it
+// isn't a reference
+func (s *Store) Resolve(value string) (string, error) {
+	name, ok := strings.CutPrefix(value, Scheme)
+	if !ok {
+		return value, nil
+	}
+	return s.Get(name)
+}
+
+// Resolve resolves value with the default store
+func Resolve(value string) (string, error) {
+	return Default.Resolve(value)
+}
+
+// Inject replaces every secret reference among the exported string fields
+// of the struct v points to, following nested structs, pointers, slices,
+// and map values, so a config can be loaded as-is and then have its
+// secrets filled in
+func (s *Store) Inject(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("Inject needs a non-nil pointer")
+	}
+	return s.inject(rv.Elem())
+}
+
+func (s *Store) inject(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.String:
+		if !v.CanSet() || !IsReference(v.String()) {
+			return nil
+		}
+		value, err := s.Resolve(v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(value)
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			return s.inject(v.Elem())
+		}
+	case reflect.Struct:
+		for n := 0; n < v.NumField(); n++ {
+			if v.Type().Field(n).IsExported() {
+				if err := s.inject(v.Field(n)); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for n := 0; n < v.Len(); n++ {
+			if err := s.inject(v.Index(n)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// Map elements can't be set in place, so each is copied, resolved,
+		// and stored back
+		iter := v.MapRange()
+		for iter.Next() {
+			elem := reflect.New(iter.Value().Type()).Elem()
+			elem.Set(iter.Value())
+			if err := s.inject(elem); err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), elem)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config says where spans are exported
+type Config struct {
+	// Service names the agent in the collector (default "device-agent")
+	Service string
+
+	// Endpoint is the collector's OTLP/HTTP base URL, such as
+	// http://localhost:4318; spans are posted to its /v1/traces as JSON.
+	// Without one spans are still recorded, so results carry trace IDs,
+	// but nothing is exported.
+	Endpoint string
+
+	// Headers are sent with each export, e.g. an API key
+	Headers map[string]string
+
+	// Interval is how often ended spans are exported (default 5s)
+	Interval time.Duration
+
+	// MaxQueue bounds the spans waiting for export; more are dropped
+	// (default 2048)
+	MaxQueue int
+
+	// Client posts the exports (default http.DefaultClient)
+	Client *http.Client
+
+	// Logger reports failed exports (default slog.Default())
+	Logger *slog.Logger
+}
+
+func (c *Config) defaults() {
+	if c.Service == "" {
+		c.Service = "device-agent"
+	}
+	c.Endpoint = strings.TrimSuffix(c.Endpoint, "/")
+	if c.Interval <= 0 {
+		c.Interval = 5 * time.Second
+	}
+	if c.MaxQueue <= 0 {
+		c.MaxQueue = 2048
+	}
+	if c.Client == nil {
+		c.Client = http.DefaultClient
+	}
+	if c.Logger == nil {
+		c.Logger = slog.Default()
+	}
+}
+
+// StartExport exports ended spans every interval until ctx is done
+func (t *Tracer) StartExport(ctx context.Context) {
+	if t.cfg.Endpoint == "" {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(t.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := t.Flush(ctx); err != nil {
+				t.cfg.Logger.Error("Trace export failed", "error", err)
+			}
+		}
+	}()
+}
+
+// Flush exports the spans ended since the last export. Spans that fail
+// to export are dropped rather than retried, so a down collector can't
+// hold memory.
+func (t *Tracer) Flush(ctx context.Context) error {
+	if t == nil || t.cfg.Endpoint == "" {
+		return nil
+	}
+	t.mu.Lock()
+	spans, dropped := t.pending, t.dropped
+	t.pending, t.dropped = nil, 0
+	t.mu.Unlock()
+	if dropped > 0 {
+		t.cfg.Logger.Warn("Dropped spans while the trace export queue was full", "spans", dropped)
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(t.encode(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.cfg.Endpoint+"/v1/traces", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("collector answered %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	return nil
+}
+
+// The OTLP/JSON encoding of an export request. IDs are hex and times are
+// nanoseconds since the epoch as decimal strings.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              Kind            `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            *otlpStatus     `json:"status,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string                 `json:"key"`
+		Value map[string]interface{} `json:"value"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+// statusError is OTLP's STATUS_CODE_ERROR
+const statusError = 2
+
+func (t *Tracer) encode(spans []*Span) otlpRequest {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		s.mu.Lock()
+		o := otlpSpan{
+			TraceID:           s.sc.TraceID.String(),
+			SpanID:            s.sc.SpanID.String(),
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        attributes(s.attrs),
+		}
+		if s.failed {
+			o.Status = &otlpStatus{Code: statusError, Message: s.message}
+		}
+		s.mu.Unlock()
+		if s.parent.IsValid() {
+			o.ParentSpanID = s.parent.String()
+		}
+		encoded = append(encoded, o)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: attributes(map[string]interface{}{"service.name": t.cfg.Service})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "github.com/vinod901/local-agent-core/go-device-agent"}, Spans: encoded}},
+	}}}
+}
+
+// attributes encodes attribute values as OTLP AnyValues
+func attributes(attrs map[string]interface{}) []otlpAttribute {
+	encoded := make([]otlpAttribute, 0, len(attrs))
+	for _, k := range slices.Sorted(maps.Keys(attrs)) {
+		v := attrs[k]
+		var value map[string]interface{}
+		switch v := v.(type) {
+		case string:
+			value = map[string]interface{}{"stringValue": v}
+		case bool:
+			value = map[string]interface{}{"boolValue": v}
+		case int:
+			value = map[string]interface{}{"intValue": strconv.Itoa(v)}
+		case int64:
+			value = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			value = map[string]interface{}{"doubleValue": v}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+		}
+		encoded = append(encoded, otlpAttribute{Key: k, Value: value})
+	}
+	return encoded
+}
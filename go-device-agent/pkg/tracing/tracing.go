@@ -0,0 +1,296 @@
+// Package tracing records spans for what the gateway does with each
+// intent (parsing, validation, policy checks, execution) and exports them
+// to an OpenTelemetry collector over OTLP/HTTP, so a slow intent shows
+// where its time went. Spans travel in contexts: executors and the shared
+// HTTP pool add children with Start, which does nothing when the context
+// carries no span, so code can be instrumented whether tracing is on or
+// not. Trace context crosses processes as a W3C traceparent header.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceID identifies a trace, the spans of one request
+type TraceID [16]byte
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// IsValid reports whether the ID is set
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// IsValid reports whether the ID is set
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+// SpanContext is what identifies a span to other processes
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// ErrInvalidTraceparent is returned for a malformed traceparent header
+var ErrInvalidTraceparent = errors.New("invalid traceparent")
+
+// ParseTraceparent parses a W3C traceparent header, such as
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+func ParseTraceparent(s string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, ErrInvalidTraceparent
+	}
+	// Later versions may append fields, but version 00 has exactly four
+	if parts[0] == "00" && len(parts) != 4 {
+		return sc, ErrInvalidTraceparent
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, ErrInvalidTraceparent
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, ErrInvalidTraceparent
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, ErrInvalidTraceparent
+	}
+	if !sc.TraceID.IsValid() || !sc.SpanID.IsValid() {
+		return sc, ErrInvalidTraceparent
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, nil
+}
+
+// Traceparent formats the span context as a W3C traceparent header
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// WithRemoteParent returns a context whose next root span continues the
+// trace of a span in another process
+func WithRemoteParent(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// Extract is WithRemoteParent for a traceparent header, returning ctx
+// unchanged if the header is empty or malformed
+func Extract(ctx context.Context, traceparent string) context.Context {
+	if traceparent == "" {
+		return ctx
+	}
+	sc, err := ParseTraceparent(traceparent)
+	if err != nil {
+		return ctx
+	}
+	return WithRemoteParent(ctx, sc)
+}
+
+// SpanFromContext returns the span ctx carries, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// Start starts a child of the span ctx carries, returning a context
+// carrying the child. Without a span in ctx it returns ctx and a nil Span,
+// whose methods do nothing.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	s := parent.tracer.newSpan(name, parent.sc.TraceID, parent.sc.SpanID, parent.sc.Sampled)
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// Kind says what a span's operation is, as OpenTelemetry's SpanKind
+type Kind int
+
+// Span kinds
+const (
+	KindInternal Kind = 1
+	KindServer   Kind = 2
+	KindClient   Kind = 3
+)
+
+// Span is one timed operation. A nil Span does nothing.
+type Span struct {
+	tracer *Tracer
+	sc     SpanContext
+	parent SpanID
+	start  time.Time
+
+	mu      sync.Mutex
+	name    string
+	kind    Kind
+	end     time.Time
+	attrs   map[string]interface{}
+	failed  bool
+	message string
+}
+
+// Context returns what identifies the span to other processes
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// TraceID returns the span's trace ID in hex, or "" for a nil Span
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return s.sc.TraceID.String()
+}
+
+// SetName renames the span, as once what it is about is known
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.name = name
+	s.mu.Unlock()
+}
+
+// SetKind sets the span's kind (default KindInternal)
+func (s *Span) SetKind(kind Kind) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.kind = kind
+	s.mu.Unlock()
+}
+
+// SetAttribute sets an attribute: a string, bool, integer, or float, or
+// anything else as its fmt form
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attrs[key] = value
+	s.mu.Unlock()
+}
+
+// Fail marks the span's operation failed, with a message
+func (s *Span) Fail(message string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.failed, s.message = true, message
+	s.mu.Unlock()
+}
+
+// End ends the span and queues it for export. Ending a span again does
+// nothing, so a deferred End can back up an earlier one.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.end.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	s.end = time.Now()
+	s.mu.Unlock()
+	if s.sc.Sampled {
+		s.tracer.queue(s)
+	}
+}
+
+// Tracer starts root spans and exports ended spans in batches
+type Tracer struct {
+	cfg Config
+
+	mu      sync.Mutex
+	pending []*Span
+	dropped int
+}
+
+// New creates a tracer. Call StartExport to export spans as they end.
+func New(cfg Config) *Tracer {
+	cfg.defaults()
+	return &Tracer{cfg: cfg}
+}
+
+// Start starts a root span, continuing the trace of a remote parent set
+// with WithRemoteParent, or a child if ctx already carries a span. A nil
+// Tracer returns ctx and a nil Span.
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	if SpanFromContext(ctx) != nil {
+		return Start(ctx, name)
+	}
+	var s *Span
+	if remote, ok := ctx.Value(remoteKey{}).(SpanContext); ok {
+		s = t.newSpan(name, remote.TraceID, remote.SpanID, remote.Sampled)
+	} else {
+		var trace TraceID
+		rand.Read(trace[:])
+		s = t.newSpan(name, trace, SpanID{}, true)
+	}
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+func (t *Tracer) newSpan(name string, trace TraceID, parent SpanID, sampled bool) *Span {
+	s := &Span{
+		tracer: t,
+		sc:     SpanContext{TraceID: trace, Sampled: sampled},
+		parent: parent,
+		start:  time.Now(),
+		name:   name,
+		kind:   KindInternal,
+		attrs:  make(map[string]interface{}),
+	}
+	rand.Read(s.sc.SpanID[:])
+	return s
+}
+
+// queue holds an ended span for the next export, dropping it if too many
+// are waiting, as when the collector is down
+func (t *Tracer) queue(s *Span) {
+	if t.cfg.Endpoint == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.pending) >= t.cfg.MaxQueue {
+		t.dropped++
+		return
+	}
+	t.pending = append(t.pending, s)
+}
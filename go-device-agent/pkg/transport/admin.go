@@ -0,0 +1,188 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/audit"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/config"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/history"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// defaultAuditTail is how many audit entries /v1/admin/audit, and history
+// records /v1/admin/history, return without a limit
+const defaultAuditTail = 20
+
+// reloader is something POST /v1/admin/reload reloads
+type reloader struct {
+	name   string
+	reload func() error
+}
+
+// AddInspector adds what fn returns to GET /v1/admin/policies under name,
+// for settings outside the gateway such as an executor's thresholds
+func (s *HTTPServer) AddInspector(name string, fn func() interface{}) {
+	if s.inspectors == nil {
+		s.inspectors = make(map[string]func() interface{})
+	}
+	s.inspectors[name] = fn
+}
+
+// AddReloader makes POST /v1/admin/reload call reload, reporting its error
+// under name. Reloaders run in the order they were added.
+func (s *HTTPServer) AddReloader(name string, reload func() error) {
+	s.reloaders = append(s.reloaders, reloader{name: name, reload: reload})
+}
+
+// SetConfigReloader makes POST /v1/admin/reload reload the config file
+// as well, answering with its report, and GET /v1/admin/config show the
+// latest report
+func (s *HTTPServer) SetConfigReloader(r *config.Reloader) {
+	s.config = r
+}
+
+// ServeAdminSocket serves the /v1/admin routes on a Unix socket at path
+// until ctx is cancelled. Only the agent's user can connect, so requests
+// need no admin token; this works without SetAdminToken or the HTTP
+// transport. A socket left behind by an earlier run is replaced.
+func (s *HTTPServer) ServeAdminSocket(ctx context.Context, path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return err
+	}
+	server := &http.Server{
+		Handler:           s.adminMux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	s.logger.Info("Admin socket listening", "path", path)
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// handleInFlight lists the intents being handled, longest running first
+func (s *HTTPServer) handleInFlight(w http.ResponseWriter, r *http.Request) {
+	writeResult(w, http.StatusOK, intent.JSON, map[string]interface{}{"intents": s.gw.InFlight()})
+}
+
+// handlePolicies reports what the gateway enforces, and what each
+// inspector added with AddInspector returns
+func (s *HTTPServer) handlePolicies(w http.ResponseWriter, r *http.Request) {
+	report := map[string]interface{}{"gateway": s.gw.Policies()}
+	for name, fn := range s.inspectors {
+		report[name] = fn()
+	}
+	writeResult(w, http.StatusOK, intent.JSON, report)
+}
+
+// handleAuditTail returns the most recent audit entries of every client,
+// the limit query parameter's worth (default 20), oldest first
+func (s *HTTPServer) handleAuditTail(w http.ResponseWriter, r *http.Request) {
+	auditLog := s.gw.AuditLog()
+	if auditLog == nil {
+		http.Error(w, "audit log not enabled", http.StatusNotFound)
+		return
+	}
+	filter := audit.Filter{Limit: defaultAuditTail}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+	entries, err := auditLog.Query(filter)
+	if err != nil {
+		s.logger.Error("Audit query failed", "error", err)
+		http.Error(w, "audit query failed", http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+	writeResult(w, http.StatusOK, intent.JSON, map[string]interface{}{"entries": entries})
+}
+
+// handleHistory returns the most recent results kept in the history, the
+// limit query parameter's worth (default 20), oldest first
+func (s *HTTPServer) handleHistory(w http.ResponseWriter, r *http.Request) {
+	store := s.gw.History()
+	if store == nil {
+		http.Error(w, "history not enabled", http.StatusNotFound)
+		return
+	}
+	q := history.Query{Limit: defaultAuditTail}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		q.Limit = limit
+	}
+	records, err := store.Query(q)
+	if err != nil {
+		s.logger.Error("History query failed", "error", err)
+		http.Error(w, "history query failed", http.StatusInternalServerError)
+		return
+	}
+	writeResult(w, http.StatusOK, intent.JSON, map[string]interface{}{"records": records})
+}
+
+// handleReload runs every reloader, reporting "ok" or the error of each.
+// It answers 500 if any failed; those keep their previous configuration.
+func (s *HTTPServer) handleReload(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	report := make(map[string]string, len(s.reloaders))
+	for _, rl := range s.reloaders {
+		if err := rl.reload(); err != nil {
+			s.logger.Error("Reload failed", "config", rl.name, "remote", r.RemoteAddr, "error", err)
+			report[rl.name] = err.Error()
+			status = http.StatusInternalServerError
+			continue
+		}
+		s.logger.Info("Reloaded", "config", rl.name, "remote", r.RemoteAddr)
+		report[rl.name] = "ok"
+	}
+	resp := map[string]interface{}{"reloaded": report}
+	if s.config != nil {
+		configReport := s.config.Reload("admin API")
+		if configReport.Err() != nil {
+			status = http.StatusInternalServerError
+		}
+		resp["config"] = configReport
+	}
+	writeResult(w, status, intent.JSON, resp)
+}
+
+// handleConfig reports the latest config reload
+func (s *HTTPServer) handleConfig(w http.ResponseWriter, r *http.Request) {
+	if s.config == nil {
+		http.Error(w, "config reload not enabled", http.StatusNotFound)
+		return
+	}
+	writeResult(w, http.StatusOK, intent.JSON, map[string]interface{}{"last_reload": s.config.Last()})
+}
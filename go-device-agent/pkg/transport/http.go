@@ -0,0 +1,717 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/access"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/audit"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/config"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/sandbox"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/tracing"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/usage"
+)
+
+// HTTPServer exposes the gateway over HTTP.
+// The request Content-Type selects the intent codec and the Accept header
+// selects the result codec, defaulting to the request's codec.
+type HTTPServer struct {
+	gw         *gateway.Gateway
+	mux        *http.ServeMux
+	adminMux   *http.ServeMux
+	adminToken string
+	auth       *access.Authenticator
+	tlsConfig  *tls.Config
+	results    *Results
+	webhooks   *Webhooks
+	logger     *slog.Logger
+
+	inspectors map[string]func() interface{}
+	reloaders  []reloader
+	config     *config.Reloader
+}
+
+// NewHTTPServer creates an HTTP transport for the gateway
+func NewHTTPServer(gw *gateway.Gateway, logger *slog.Logger) *HTTPServer {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	s := &HTTPServer{
+		gw:       gw,
+		mux:      http.NewServeMux(),
+		adminMux: http.NewServeMux(),
+		results:  NewResults(defaultResultBuffer),
+		webhooks: NewWebhooks(logger),
+		logger:   logger,
+	}
+	s.mux.HandleFunc("POST /v1/intents", s.authenticated(s.handleIntent))
+	s.mux.HandleFunc("GET /v1/results", s.authenticated(s.handleResults))
+	s.mux.HandleFunc("GET /v1/audit", s.authenticated(s.handleAudit))
+	s.mux.HandleFunc("GET /v1/capabilities", s.authenticated(s.handleCapabilities))
+	s.mux.HandleFunc("POST /v1/approvals/{id}/approve", s.handleDecide(true))
+	s.mux.HandleFunc("POST /v1/approvals/{id}/deny", s.handleDecide(false))
+	s.adminMux.HandleFunc("GET /v1/admin/approvals", s.handleListApprovals)
+	s.adminMux.HandleFunc("POST /v1/admin/approvals/{id}/approve", s.handleAnswer(true))
+	s.adminMux.HandleFunc("POST /v1/admin/approvals/{id}/deny", s.handleAnswer(false))
+	s.adminMux.HandleFunc("GET /v1/admin/executors", s.handleListExecutors)
+	s.adminMux.HandleFunc("POST /v1/admin/executors/{name}/disable", s.handleSetExecutor(false))
+	s.adminMux.HandleFunc("POST /v1/admin/executors/{name}/enable", s.handleSetExecutor(true))
+	s.adminMux.HandleFunc("GET /v1/admin/usage", s.handleUsage)
+	s.adminMux.HandleFunc("GET /v1/admin/stats", s.handleStats)
+	s.adminMux.HandleFunc("GET /v1/admin/auth", s.handleAuthStats)
+	s.adminMux.HandleFunc("GET /v1/admin/safety", s.handleSafety)
+	s.adminMux.HandleFunc("POST /v1/admin/safety/enable", s.handleSetSafety(true))
+	s.adminMux.HandleFunc("POST /v1/admin/safety/disable", s.handleSetSafety(false))
+	s.adminMux.HandleFunc("GET /v1/admin/mode", s.handleMode)
+	s.adminMux.HandleFunc("POST /v1/admin/mode/{name}", s.handleSetMode)
+	s.adminMux.HandleFunc("GET /v1/admin/maintenance", s.handleListMaintenance)
+	s.adminMux.HandleFunc("POST /v1/admin/maintenance", s.handleStartMaintenance)
+	s.adminMux.HandleFunc("DELETE /v1/admin/maintenance", s.handleEndMaintenance)
+	s.adminMux.HandleFunc("GET /v1/admin/webhooks", s.handleListWebhooks)
+	s.adminMux.HandleFunc("POST /v1/admin/webhooks", s.handleAddWebhook)
+	s.adminMux.HandleFunc("DELETE /v1/admin/webhooks/{id}", s.handleRemoveWebhook)
+	s.adminMux.HandleFunc("GET /v1/admin/inflight", s.handleInFlight)
+	s.adminMux.HandleFunc("GET /v1/admin/policies", s.handlePolicies)
+	s.adminMux.HandleFunc("GET /v1/admin/audit", s.handleAuditTail)
+	s.adminMux.HandleFunc("GET /v1/admin/history", s.handleHistory)
+	s.adminMux.HandleFunc("POST /v1/admin/reload", s.handleReload)
+	s.adminMux.HandleFunc("GET /v1/admin/config", s.handleConfig)
+	s.mux.Handle("/v1/admin/", s.admin(s.adminMux.ServeHTTP))
+	s.mux.Handle("GET /ui/", s.handleUI())
+	return s
+}
+
+// Webhooks returns the callback URLs async results are delivered to
+func (s *HTTPServer) Webhooks() *Webhooks {
+	return s.webhooks
+}
+
+// SetAdminToken enables the /v1/admin routes for requests carrying
+// "Authorization: Bearer <token>". Without a token they answer 404.
+func (s *HTTPServer) SetAdminToken(token string) {
+	s.adminToken = token
+}
+
+// SetAuthenticator requires the non-admin routes' requests to carry an
+// identity's API key or a signed token naming it, as "Authorization:
+// Bearer <credential>". Intents are sent as that identity, and the gateway
+// limits them to its roles; results and audit entries are limited to the
+// identity's own unless one of its roles has view_audit.
+func (s *HTTPServer) SetAuthenticator(a *access.Authenticator) {
+	s.auth = a
+}
+
+// SetTLSConfig serves HTTPS with the given configuration, such as one from
+// mtls.ServerConfig requiring client certificates. A verified client
+// certificate then authenticates as the identity its common name names,
+// for requests without a bearer credential.
+func (s *HTTPServer) SetTLSConfig(config *tls.Config) {
+	s.tlsConfig = config
+}
+
+// Handler returns the HTTP handler serving the transport's routes
+func (s *HTTPServer) Handler() http.Handler {
+	return s.mux
+}
+
+// ListenAndServe serves on addr until ctx is cancelled
+func (s *HTTPServer) ListenAndServe(ctx context.Context, addr string) error {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig:         s.tlsConfig,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+		s.webhooks.Close()
+	}()
+
+	var err error
+	if s.tlsConfig != nil {
+		s.logger.Info("HTTPS transport listening", "addr", addr)
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		s.logger.Info("HTTP transport listening", "addr", addr)
+		err = server.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *HTTPServer) handleIntent(w http.ResponseWriter, r *http.Request) {
+	codec, ok := intent.CodecForContentType(r.Header.Get("Content-Type"))
+	if !ok {
+		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+	responseCodec := intent.NegotiateCodec(r.Header.Get("Accept"), codec)
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxLineSize+1))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxLineSize {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// A core that traces its turns passes a traceparent header, and the
+	// intent's spans join its trace
+	ctx := tracing.Extract(r.Context(), r.Header.Get("traceparent"))
+
+	// Stateless cores ask for 202 Accepted and collect the result from
+	// /v1/results or a webhook. Intents that don't decode are answered
+	// synchronously so the rejection is immediate.
+	if preferAsync(r) {
+		if i, err := intent.Decode(body, codec); err == nil {
+			go s.processAsync(context.WithoutCancel(ctx), body, codec, i)
+			w.Header().Set("Preference-Applied", "respond-async")
+			w.Header().Set("Location", "/v1/results?intent_id="+url.QueryEscape(i.ID))
+			writeResult(w, http.StatusAccepted, intent.JSON, map[string]interface{}{"intent_id": i.ID, "status": "accepted"})
+			return
+		}
+	}
+
+	status := http.StatusOK
+	result, err := s.gw.ProcessEncodedIntent(ctx, body, codec)
+	if err != nil {
+		s.logger.WarnContext(ctx, "Rejected intent", "remote", r.RemoteAddr, "error", err)
+		status = http.StatusBadRequest
+		result = &gateway.ExecutionResult{
+			Success:   false,
+			Error:     err.Error(),
+			ErrorCode: agenterrors.CodeOf(err),
+			Timestamp: time.Now().Format(time.RFC3339),
+		}
+	}
+
+	writeResult(w, status, responseCodec, result)
+}
+
+// preferAsync reports whether the request carries "Prefer: respond-async"
+func preferAsync(r *http.Request) bool {
+	for _, v := range r.Header.Values("Prefer") {
+		for _, pref := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(pref), "respond-async") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// processAsync runs an accepted intent and hands its result to pollers and
+// webhooks
+func (s *HTTPServer) processAsync(ctx context.Context, body []byte, codec intent.Codec, i *intent.Intent) {
+	result, err := s.gw.ProcessEncodedIntent(ctx, body, codec)
+	if err != nil {
+		s.logger.WarnContext(ctx, "Rejected async intent", "intent_id", i.ID, "error", err)
+		result = &gateway.ExecutionResult{
+			Success:       false,
+			IntentID:      i.ID,
+			Action:        i.IntentType,
+			Error:         err.Error(),
+			ErrorCode:     agenterrors.CodeOf(err),
+			Timestamp:     time.Now().Format(time.RFC3339),
+			CorrelationID: i.CorrelationID,
+			SessionID:     i.SessionID,
+		}
+	}
+	owner := ""
+	if id := access.IdentityFrom(ctx); id != nil {
+		owner = id.Name
+	}
+	s.results.Add(result, owner)
+	s.webhooks.Deliver(i.IntentType, result)
+}
+
+// maxResultWait caps how long a results poll is held open
+const maxResultWait = time.Minute
+
+// handleResults long-polls for async results after the cursor query
+// parameter, optionally filtered by intent_id, correlation_id, or
+// session_id. With wait (e.g. "30s") it holds the request open until a
+// result arrives.
+func (s *HTTPServer) handleResults(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var after uint64
+	if v := q.Get("after"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(w, "after must be a cursor from a previous response", http.StatusBadRequest)
+			return
+		}
+		after = n
+	}
+	limit := 100
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	var wait time.Duration
+	if v := q.Get("wait"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			http.Error(w, "wait must be a Go duration such as 30s", http.StatusBadRequest)
+			return
+		}
+		wait = min(d, maxResultWait)
+	}
+
+	intentID, correlationID, sessionID := q.Get("intent_id"), q.Get("correlation_id"), q.Get("session_id")
+	owner, all := s.visibleTo(r)
+	match := func(d Delivered) bool {
+		result := d.Result
+		return (all || d.owner == owner) &&
+			(intentID == "" || result.IntentID == intentID) &&
+			(correlationID == "" || result.CorrelationID == correlationID) &&
+			(sessionID == "" || result.SessionID == sessionID)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), wait)
+	defer cancel()
+	results, cursor, missed := s.results.Wait(ctx, after, limit, match)
+	if results == nil {
+		results = []Delivered{}
+	}
+	writeResult(w, http.StatusOK, intent.JSON, map[string]interface{}{
+		"results": results,
+		"cursor":  cursor,
+		"missed":  missed,
+	})
+}
+
+// handleListWebhooks lists the registered webhooks without their secrets
+func (s *HTTPServer) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	writeResult(w, http.StatusOK, intent.JSON, map[string]interface{}{"webhooks": s.webhooks.List()})
+}
+
+// handleAddWebhook registers a webhook from a JSON body with url and
+// optional secret and intent_types, answering with its ID and secret
+func (s *HTTPServer) handleAddWebhook(w http.ResponseWriter, r *http.Request) {
+	var req Webhook
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxLineSize)).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	hook, err := s.webhooks.Add(Webhook{URL: req.URL, Secret: req.Secret, IntentTypes: req.IntentTypes})
+	if err != nil {
+		http.Error(w, err.Error(), adminStatus(err))
+		return
+	}
+	s.logger.Info("Webhook added", "webhook", hook.ID, "url", hook.URL, "remote", r.RemoteAddr)
+	writeResult(w, http.StatusCreated, intent.JSON, hook)
+}
+
+// handleRemoveWebhook unregisters the webhook named in the path
+func (s *HTTPServer) handleRemoveWebhook(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := s.webhooks.Remove(id); err != nil {
+		http.Error(w, err.Error(), adminStatus(err))
+		return
+	}
+	s.logger.Info("Webhook removed", "webhook", id, "remote", r.RemoteAddr)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleCapabilities returns the gateway's capability manifest as JSON
+func (s *HTTPServer) handleCapabilities(w http.ResponseWriter, r *http.Request) {
+	writeResult(w, http.StatusOK, intent.JSON, s.gw.Capabilities())
+}
+
+// authenticated requires the credential of an identity in the role
+// policy, if one is set, and attaches the identity to the request's context
+func (s *HTTPServer) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.auth == nil {
+			next(w, r)
+			return
+		}
+		credential, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		var id *access.Identity
+		var err error
+		if credential == "" && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+			id, err = s.auth.AuthenticateCertificate(r.TLS.VerifiedChains[0][0])
+		} else {
+			id, err = s.auth.Authenticate(credential)
+		}
+		if err != nil {
+			// The reason is logged but not returned, so probing clients
+			// learn nothing about which keys exist
+			s.logger.Warn("Rejected request", "remote", r.RemoteAddr, "path", r.URL.Path, "error", err)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "valid API key or token required", http.StatusUnauthorized)
+			return
+		}
+		next(w, r.WithContext(access.WithIdentity(r.Context(), id)))
+	}
+}
+
+// visibleTo returns whose results and audit entries the request may read:
+// all of them, or only those of the identity named
+func (s *HTTPServer) visibleTo(r *http.Request) (string, bool) {
+	id := access.IdentityFrom(r.Context())
+	if id == nil || s.auth.Policy().ViewsAudit(id) {
+		return "", true
+	}
+	return id.Name, false
+}
+
+// admin guards a handler with the admin token
+func (s *HTTPServer) admin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.adminToken == "" {
+			http.NotFound(w, r)
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "admin token required", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleListExecutors reports each executor's availability, whether it is
+// disabled or degraded, what it declared it needs from the system, its
+// usage since startup, its latency and outcome stats, and how many of its
+// intents are in flight
+func (s *HTTPServer) handleListExecutors(w http.ResponseWriter, r *http.Request) {
+	type status struct {
+		Name      string                 `json:"name"`
+		Available bool                   `json:"available"`
+		Disabled  bool                   `json:"disabled"`
+		Degraded  bool                   `json:"degraded"`
+		Actions   []string               `json:"actions"`
+		Needs     sandbox.Needs          `json:"needs"`
+		Usage     usage.Totals           `json:"usage"`
+		Stats     *gateway.ExecutorStats `json:"stats,omitempty"`
+		InFlight  int                    `json:"in_flight"`
+	}
+	needs := s.gw.ExecutorNeeds()
+	stats := s.gw.UsageStats()
+	latency := s.gw.Stats()
+	inFlight := make(map[string]int)
+	for _, f := range s.gw.InFlight() {
+		inFlight[f.Module]++
+	}
+	list := []status{}
+	for _, e := range s.gw.Capabilities().Executors {
+		actions := make([]string, 0, len(e.Actions))
+		for _, a := range e.Actions {
+			actions = append(actions, a.IntentType)
+		}
+		st := status{
+			Name:      e.Name,
+			Available: e.Available,
+			Disabled:  e.Disabled,
+			Degraded:  e.Degraded,
+			Actions:   actions,
+			Needs:     needs[e.Name],
+			Usage:     stats.Modules[e.Name],
+			InFlight:  inFlight[e.Name],
+		}
+		if l, ok := latency[e.Name]; ok {
+			st.Stats = &l
+		}
+		list = append(list, st)
+	}
+	writeResult(w, http.StatusOK, intent.JSON, map[string]interface{}{"executors": list})
+}
+
+// handleSetExecutor enables or disables the executor named in the path
+func (s *HTTPServer) handleSetExecutor(enable bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		var err error
+		if enable {
+			err = s.gw.EnableExecutor(name)
+		} else {
+			err = s.gw.DisableExecutor(name)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		s.logger.Info("Executor set", "executor", name, "enabled", enable, "remote", r.RemoteAddr)
+		writeResult(w, http.StatusOK, intent.JSON, map[string]interface{}{"executor": name, "disabled": !enable})
+	}
+}
+
+// handleUsage reports resource usage aggregated per executor and intent type
+func (s *HTTPServer) handleUsage(w http.ResponseWriter, r *http.Request) {
+	writeResult(w, http.StatusOK, intent.JSON, s.gw.UsageStats())
+}
+
+// handleStats reports each executor's latency percentiles, success rate,
+// and last error
+func (s *HTTPServer) handleStats(w http.ResponseWriter, r *http.Request) {
+	writeResult(w, http.StatusOK, intent.JSON, map[string]interface{}{"executors": s.gw.Stats()})
+}
+
+// handleAuthStats reports accepted and rejected credentials and roles file
+// reloads
+func (s *HTTPServer) handleAuthStats(w http.ResponseWriter, r *http.Request) {
+	if s.auth == nil {
+		http.Error(w, "authentication is not configured", http.StatusNotFound)
+		return
+	}
+	writeResult(w, http.StatusOK, intent.JSON, s.auth.Stats())
+}
+
+// handleSafety reports the safety policy and whether it is on
+func (s *HTTPServer) handleSafety(w http.ResponseWriter, r *http.Request) {
+	writeResult(w, http.StatusOK, intent.JSON, map[string]interface{}{
+		"enabled": s.gw.SafetyMode(),
+		"policy":  s.gw.SafetyPolicy(),
+	})
+}
+
+// handleSetSafety turns safety mode on or off
+func (s *HTTPServer) handleSetSafety(on bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.gw.SetSafetyMode(on)
+		s.logger.Info("Safety mode set", "enabled", on, "remote", r.RemoteAddr)
+		s.handleSafety(w, r)
+	}
+}
+
+// handleMode reports the current mode and the modes it can switch to
+func (s *HTTPServer) handleMode(w http.ResponseWriter, r *http.Request) {
+	writeResult(w, http.StatusOK, intent.JSON, map[string]interface{}{
+		"mode":  s.gw.Mode(),
+		"modes": s.gw.Modes(),
+	})
+}
+
+// handleSetMode switches the gateway's mode
+func (s *HTTPServer) handleSetMode(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := s.gw.SetMode(name); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	s.logger.Info("Mode set", "mode", name, "remote", r.RemoteAddr)
+	s.handleMode(w, r)
+}
+
+// handleDecide answers a pending approval. The one-time token in the query
+// is the credential: it was only sent to the approval's second channel,
+// such as a push notification's buttons.
+func (s *HTTPServer) handleDecide(approve bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		approvals := s.gw.Approvals()
+		if approvals == nil {
+			http.NotFound(w, r)
+			return
+		}
+		id := r.PathValue("id")
+		if err := approvals.Decide(id, r.URL.Query().Get("token"), approve); err != nil {
+			status := adminStatus(err)
+			if agenterrors.CodeOf(err) == agenterrors.Unauthorized {
+				status = http.StatusForbidden
+			}
+			s.logger.Warn("Rejected approval answer", "approval", id, "remote", r.RemoteAddr, "error", err)
+			http.Error(w, err.Error(), status)
+			return
+		}
+		s.logger.Info("Approval answered", "approval", id, "approve", approve, "remote", r.RemoteAddr)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleAnswer answers a pending approval waiting on the dashboard
+// approver. The admin token is the credential.
+func (s *HTTPServer) handleAnswer(approve bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		approvals := s.gw.Approvals()
+		if approvals == nil {
+			http.Error(w, "approvals are not configured", http.StatusNotFound)
+			return
+		}
+		id := r.PathValue("id")
+		if err := approvals.Answer(id, "dashboard", approve); err != nil {
+			s.logger.Warn("Rejected approval answer", "approval", id, "remote", r.RemoteAddr, "error", err)
+			http.Error(w, err.Error(), adminStatus(err))
+			return
+		}
+		s.logger.Info("Approval answered", "approval", id, "approve", approve, "remote", r.RemoteAddr)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleListApprovals lists the intents waiting for approval
+func (s *HTTPServer) handleListApprovals(w http.ResponseWriter, r *http.Request) {
+	approvals := s.gw.Approvals()
+	if approvals == nil {
+		http.Error(w, "approvals are not configured", http.StatusNotFound)
+		return
+	}
+	writeResult(w, http.StatusOK, intent.JSON, map[string]interface{}{"pending": approvals.Pending()})
+}
+
+// handleListMaintenance lists the open maintenance windows
+func (s *HTTPServer) handleListMaintenance(w http.ResponseWriter, r *http.Request) {
+	writeResult(w, http.StatusOK, intent.JSON, map[string]interface{}{"windows": s.gw.MaintenanceWindows()})
+}
+
+// handleStartMaintenance opens a window from a JSON body naming an executor
+// or device, with an optional reason and duration (e.g. "2h")
+func (s *HTTPServer) handleStartMaintenance(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Executor string `json:"executor"`
+		Device   string `json:"device"`
+		Reason   string `json:"reason"`
+		Duration string `json:"duration"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxLineSize)).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	m := gateway.Maintenance{Executor: req.Executor, Device: req.Device, Reason: req.Reason}
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			http.Error(w, "duration must be a positive Go duration such as 2h", http.StatusBadRequest)
+			return
+		}
+		m.Until = time.Now().Add(d).UTC()
+	}
+	if err := s.gw.StartMaintenance(m); err != nil {
+		http.Error(w, err.Error(), adminStatus(err))
+		return
+	}
+	s.logger.Info("Maintenance started", "executor", m.Executor, "device", m.Device, "remote", r.RemoteAddr)
+	s.handleListMaintenance(w, r)
+}
+
+// handleEndMaintenance closes the window named by the executor or device
+// query parameter
+func (s *HTTPServer) handleEndMaintenance(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if err := s.gw.EndMaintenance(q.Get("executor"), q.Get("device")); err != nil {
+		http.Error(w, err.Error(), adminStatus(err))
+		return
+	}
+	s.logger.Info("Maintenance ended", "executor", q.Get("executor"), "device", q.Get("device"), "remote", r.RemoteAddr)
+	s.handleListMaintenance(w, r)
+}
+
+// adminStatus maps a gateway error to an HTTP status
+func adminStatus(err error) int {
+	if agenterrors.CodeOf(err) == agenterrors.NotFound {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+// handleAudit lists audit entries filtered by the correlation_id,
+// session_id, intent_type, guest, origin, identity, since (RFC 3339), and
+// limit query parameters. format=jsonl or format=csv exports them as a
+// file instead.
+func (s *HTTPServer) handleAudit(w http.ResponseWriter, r *http.Request) {
+	auditLog := s.gw.AuditLog()
+	if auditLog == nil {
+		http.Error(w, "audit log not enabled", http.StatusNotFound)
+		return
+	}
+
+	q := r.URL.Query()
+	filter := audit.Filter{
+		CorrelationID: q.Get("correlation_id"),
+		SessionID:     q.Get("session_id"),
+		IntentType:    q.Get("intent_type"),
+		Guest:         q.Get("guest"),
+		Origin:        q.Get("origin"),
+		Identity:      q.Get("identity"),
+		Limit:         100,
+	}
+	if owner, all := s.visibleTo(r); !all {
+		filter.Identity = owner
+	}
+	if v := q.Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "since must be an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+		filter.Since = since
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			http.Error(w, "limit must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	if format := q.Get("format"); format != "" {
+		contentType := map[string]string{audit.FormatJSONL: "application/x-ndjson", audit.FormatCSV: "text/csv"}[format]
+		if contentType == "" {
+			http.Error(w, "format must be jsonl or csv", http.StatusBadRequest)
+			return
+		}
+		var buf bytes.Buffer
+		if _, err := auditLog.Export(&buf, filter, format); err != nil {
+			s.logger.Error("Audit export failed", "error", err)
+			http.Error(w, "audit export failed", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="audit.`+format+`"`)
+		w.Write(buf.Bytes())
+		return
+	}
+
+	entries, err := auditLog.Query(filter)
+	if err != nil {
+		s.logger.Error("Audit query failed", "error", err)
+		http.Error(w, "audit query failed", http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+	writeResult(w, http.StatusOK, intent.JSON, map[string]interface{}{"entries": entries})
+}
+
+// writeResult encodes a value with codec and writes it with the given status
+func writeResult(w http.ResponseWriter, status int, codec intent.Codec, v interface{}) {
+	data, err := codec.Marshal(v)
+	if err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", codec.ContentType())
+	w.WriteHeader(status)
+	w.Write(data)
+}
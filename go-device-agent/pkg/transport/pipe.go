@@ -0,0 +1,233 @@
+// Package transport connects the agent core to the intent gateway
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/access"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+)
+
+// maxLineSize bounds a single intent line or frame read from the pipe
+const maxLineSize = 1 << 20
+
+// Pipe serves intents over a pair of byte streams, typically stdin/stdout.
+// With the JSON codec each input line is one intent document and each output
+// line is the matching ExecutionResult, so the agent core can spawn the device
+// agent as a subprocess and talk to it without a network stack. Binary codecs
+// use frames prefixed with a 4-byte big-endian length instead of newlines.
+type Pipe struct {
+	gw       *gateway.Gateway
+	in       io.Reader
+	out      io.Writer
+	codec    intent.Codec
+	announce bool
+	identity *access.Identity
+	logger   *slog.Logger
+
+	// Serializes output, which capability diffs write from other goroutines
+	mu sync.Mutex
+}
+
+// NewPipe creates a pipe transport reading intents from in and writing results to out
+func NewPipe(gw *gateway.Gateway, in io.Reader, out io.Writer, logger *slog.Logger) *Pipe {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Pipe{
+		gw:     gw,
+		in:     in,
+		out:    out,
+		codec:  intent.JSON,
+		logger: logger,
+	}
+}
+
+// SetCodec sets the codec used for both directions of the pipe
+func (p *Pipe) SetCodec(codec intent.Codec) {
+	p.codec = codec
+}
+
+// SetAnnounce pushes the capability manifest as the first output message,
+// a capabilities.list result with no intent ID, followed by a
+// capabilities.diff result, also without an intent ID, whenever the
+// capabilities change
+func (p *Pipe) SetAnnounce(announce bool) {
+	p.announce = announce
+}
+
+// SetIdentity attributes every intent read from the pipe to id, whose
+// roles then limit them. The pipe's peer is whoever started the agent, so
+// it is named in configuration rather than authenticated.
+func (p *Pipe) SetIdentity(id *access.Identity) {
+	p.identity = id
+}
+
+// Serve processes intents until the input is closed or ctx is cancelled.
+// Every input message produces exactly one output message, including
+// messages that fail to parse, so callers can match requests to responses.
+func (p *Pipe) Serve(ctx context.Context) error {
+	if p.identity != nil {
+		ctx = access.WithIdentity(ctx, p.identity)
+	}
+	if p.announce {
+		stop, err := p.announceCapabilities()
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+	if p.codec == intent.JSON {
+		return p.serveLines(ctx)
+	}
+	return p.serveFrames(ctx)
+}
+
+func (p *Pipe) serveLines(ctx context.Context) error {
+	scanner := bufio.NewScanner(p.in)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		if err := p.write(p.process(ctx, line)); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (p *Pipe) serveFrames(ctx context.Context) error {
+	reader := bufio.NewReader(p.in)
+	var header [4]byte
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if _, err := io.ReadFull(reader, header[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		size := binary.BigEndian.Uint32(header[:])
+		if size > maxLineSize {
+			return fmt.Errorf("frame of %d bytes exceeds limit of %d", size, maxLineSize)
+		}
+		frame := make([]byte, size)
+		if _, err := io.ReadFull(reader, frame); err != nil {
+			return err
+		}
+
+		if err := p.write(p.process(ctx, frame)); err != nil {
+			return err
+		}
+	}
+}
+
+// announceCapabilities writes the manifest and starts forwarding diffs
+// newer than it, returning a function that stops them. Diffs made while
+// the manifest is being built are held until it is written.
+func (p *Pipe) announceCapabilities() (func(), error) {
+	var (
+		announced uint64
+		ready     bool
+		pending   []*gateway.CapabilityDiff
+	)
+	send := func(diff *gateway.CapabilityDiff) {
+		if diff.Revision <= announced {
+			return
+		}
+		err := p.writeLocked(&gateway.ExecutionResult{
+			Success:   true,
+			Module:    "capabilities",
+			Action:    "capabilities.diff",
+			Result:    map[string]interface{}{"diff": diff},
+			Timestamp: time.Now().Format(time.RFC3339),
+		})
+		if err != nil {
+			p.logger.Error("Failed to send capability diff", "error", err)
+		}
+	}
+	stop := p.gw.WatchCapabilities(func(diff *gateway.CapabilityDiff) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if !ready {
+			pending = append(pending, diff)
+			return
+		}
+		send(diff)
+	})
+
+	manifest := p.gw.CapabilitiesResult()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.writeLocked(manifest); err != nil {
+		stop()
+		return nil, err
+	}
+	announced, _ = manifest.Result["revision"].(uint64)
+	ready = true
+	for _, diff := range pending {
+		send(diff)
+	}
+	pending = nil
+	return stop, nil
+}
+
+// write encodes one result as a line or a length-prefixed frame
+func (p *Pipe) write(result *gateway.ExecutionResult) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.writeLocked(result)
+}
+
+func (p *Pipe) writeLocked(result *gateway.ExecutionResult) error {
+	data, err := p.codec.Marshal(result)
+	if err != nil {
+		return err
+	}
+	if p.codec == intent.JSON {
+		_, err = p.out.Write(append(data, '\n'))
+		return err
+	}
+	header := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	_, err = p.out.Write(append(header, data...))
+	return err
+}
+
+// process runs one message through the gateway, turning rejections into results
+func (p *Pipe) process(ctx context.Context, data []byte) *gateway.ExecutionResult {
+	result, err := p.gw.ProcessEncodedIntent(ctx, data, p.codec)
+	if err != nil {
+		p.logger.WarnContext(ctx, "Rejected intent", "error", err)
+		return &gateway.ExecutionResult{
+			Success:   false,
+			Error:     err.Error(),
+			ErrorCode: agenterrors.CodeOf(err),
+			Timestamp: time.Now().Format(time.RFC3339),
+		}
+	}
+	return result
+}
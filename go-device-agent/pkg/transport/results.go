@@ -0,0 +1,92 @@
+package transport
+
+import (
+	"context"
+	"sync"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+)
+
+// defaultResultBuffer is how many async results are kept for polling
+const defaultResultBuffer = 1000
+
+// Delivered is an async result with its position in the results buffer
+type Delivered struct {
+	Cursor uint64                   `json:"cursor"`
+	Result *gateway.ExecutionResult `json:"result"`
+
+	// owner is the identity that sent the intent, if any
+	owner string
+}
+
+// Results buffers the results of async intents so stateless cores can
+// collect them with a long poll. Readers track their own cursor, so several
+// pollers see every result rather than competing for them.
+type Results struct {
+	mu      sync.Mutex
+	buf     []Delivered
+	size    int
+	next    uint64
+	arrived chan struct{} // closed and replaced on every Add
+}
+
+// NewResults creates a buffer keeping the last size results
+func NewResults(size int) *Results {
+	if size <= 0 {
+		size = defaultResultBuffer
+	}
+	return &Results{size: size, next: 1, arrived: make(chan struct{})}
+}
+
+// Add appends a result of an intent sent by owner, an identity name or
+// "", dropping the oldest when the buffer is full
+func (r *Results) Add(result *gateway.ExecutionResult, owner string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buf = append(r.buf, Delivered{Cursor: r.next, Result: result, owner: owner})
+	r.next++
+	if len(r.buf) > r.size {
+		r.buf = r.buf[len(r.buf)-r.size:]
+	}
+	close(r.arrived)
+	r.arrived = make(chan struct{})
+}
+
+// Wait returns up to limit results after the cursor that match, waiting
+// until one arrives or ctx is done. missed reports that results after the
+// cursor were dropped before they could be read.
+func (r *Results) Wait(ctx context.Context, after uint64, limit int, match func(Delivered) bool) (results []Delivered, cursor uint64, missed bool) {
+	for {
+		r.mu.Lock()
+		cursor = r.next - 1
+		if after > cursor {
+			after = cursor // a cursor from before a restart
+		}
+		if len(r.buf) > 0 && r.buf[0].Cursor > after+1 {
+			missed = true
+		}
+		for _, d := range r.buf {
+			if d.Cursor <= after || (match != nil && !match(d)) {
+				continue
+			}
+			results = append(results, d)
+			if limit > 0 && len(results) == limit {
+				cursor = d.Cursor
+				break
+			}
+		}
+		arrived := r.arrived
+		r.mu.Unlock()
+
+		if len(results) > 0 {
+			return results, cursor, missed
+		}
+		// Nothing matched; later polls can skip what was looked at
+		after = cursor
+		select {
+		case <-arrived:
+		case <-ctx.Done():
+			return nil, cursor, missed
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package transport
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// uiFiles is the web dashboard: a page that polls the admin API with the
+// admin token the user enters
+//
+//go:embed ui
+var uiFiles embed.FS
+
+// handleUI serves the web dashboard under /ui/. Like the admin routes it
+// answers 404 without an admin token; the page itself holds no data, so
+// it is served without one and asks for it.
+func (s *HTTPServer) handleUI() http.Handler {
+	files, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		panic(err)
+	}
+	fileServer := http.StripPrefix("/ui/", http.FileServerFS(files))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.adminToken == "" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Security-Policy", "default-src 'self'; frame-ancestors 'none'")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		fileServer.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,227 @@
+// Web dashboard of the device agent. It polls the admin API with the admin
+// token, kept in sessionStorage, and renders everything as text nodes so
+// intent parameters and results can't inject markup.
+"use strict";
+
+const REFRESH_MS = 2000;
+const TOKEN_KEY = "agent-admin-token";
+
+let timer = null;
+
+function token() {
+  return sessionStorage.getItem(TOKEN_KEY);
+}
+
+async function api(method, path) {
+  const resp = await fetch(path, {
+    method,
+    headers: { Authorization: "Bearer " + token(), Accept: "application/json" },
+  });
+  if (resp.status === 401) {
+    signOut("The admin token was refused.");
+    throw new Error("unauthorized");
+  }
+  if (resp.status === 404) {
+    return null;
+  }
+  if (!resp.ok) {
+    throw new Error(method + " " + path + ": " + (await resp.text()).trim());
+  }
+  return resp.status === 204 ? {} : resp.json();
+}
+
+function el(tag, text, className) {
+  const node = document.createElement(tag);
+  if (text !== undefined && text !== null) {
+    node.textContent = String(text);
+  }
+  if (className) {
+    node.className = className;
+  }
+  return node;
+}
+
+function row(cells) {
+  const tr = document.createElement("tr");
+  for (const cell of cells) {
+    tr.appendChild(cell instanceof Node ? cell : el("td", cell));
+  }
+  return tr;
+}
+
+function fill(id, rows) {
+  document.querySelector("#" + id + " tbody").replaceChildren(...rows);
+}
+
+function ms(value) {
+  return value === undefined ? "-" : value.toFixed(1) + " ms";
+}
+
+function time(value) {
+  return value ? new Date(value).toLocaleTimeString() : "";
+}
+
+function outcome(success, code, error) {
+  return success
+    ? el("td", "ok", "ok")
+    : el("td", [code, error].filter(Boolean).join(": ") || "failed", "bad");
+}
+
+function button(text, className, onClick) {
+  const b = el("button", text, className);
+  b.addEventListener("click", onClick);
+  return b;
+}
+
+async function act(method, path) {
+  try {
+    await api(method, path);
+  } catch (err) {
+    setStatus(err.message);
+  }
+  refresh();
+}
+
+function renderApprovals(data) {
+  const pending = data ? data.pending : [];
+  document.getElementById("approvals-count").textContent = data ? "(" + pending.length + ")" : "(not configured)";
+  document.getElementById("approvals-empty").hidden = pending.length > 0;
+  fill("approvals", pending.map((p) => {
+    const actions = el("td");
+    if (p.approver === "dashboard") {
+      const id = encodeURIComponent(p.id);
+      actions.append(
+        button("Approve", "approve", () => act("POST", "/v1/admin/approvals/" + id + "/approve")),
+        " ",
+        button("Deny", "deny", () => act("POST", "/v1/admin/approvals/" + id + "/deny")),
+      );
+    } else {
+      actions.append(el("span", "answered by " + p.approver, "muted"));
+    }
+    return row([p.intent_type, p.risk, p.approver, p.identity || "", time(p.expires), actions]);
+  }));
+}
+
+function renderInFlight(data) {
+  const intents = data ? data.intents : [];
+  document.getElementById("inflight-count").textContent = "(" + intents.length + ")";
+  fill("inflight", intents.map((i) =>
+    row([i.intent_type, i.module || "", i.identity || "", el("td", (i.elapsed_ms / 1000).toFixed(1) + " s", "num")])));
+}
+
+function renderExecutors(data) {
+  const executors = data ? data.executors : [];
+  fill("executors", executors.map((e) => {
+    let state = el("td", "ok", "ok");
+    if (e.disabled) {
+      state = el("td", "disabled", "muted");
+    } else if (!e.available) {
+      state = el("td", "unavailable", "bad");
+    } else if (e.degraded) {
+      state = el("td", "degraded", "warn");
+    }
+    const s = e.stats || {};
+    const name = encodeURIComponent(e.name);
+    const toggle = e.disabled
+      ? button("Enable", "", () => act("POST", "/v1/admin/executors/" + name + "/enable"))
+      : button("Disable", "", () => act("POST", "/v1/admin/executors/" + name + "/disable"));
+    const cell = el("td");
+    cell.append(toggle);
+    return row([
+      e.name,
+      state,
+      el("td", s.executions || 0, "num"),
+      el("td", s.executions ? Math.round(s.success_rate * 100) + "%" : "-", "num"),
+      el("td", s.executions ? ms(s.p50_ms) : "-", "num"),
+      el("td", s.executions ? ms(s.p95_ms) : "-", "num"),
+      el("td", e.in_flight, "num"),
+      el("td", s.last_error || "", "detail"),
+      cell,
+    ]);
+  }));
+}
+
+function renderHistory(data) {
+  document.getElementById("history-empty").hidden = data !== null;
+  const records = data ? data.records.slice().reverse() : [];
+  fill("history", records.map((r) => row([
+    time(r.time),
+    r.intent_type,
+    r.executor || "",
+    outcome(r.success, r.error_code, r.error),
+    el("td", r.result ? JSON.stringify(r.result) : "", "detail"),
+  ])));
+}
+
+function renderAudit(data) {
+  document.getElementById("audit-empty").hidden = data !== null;
+  const entries = data ? data.entries.slice().reverse() : [];
+  fill("audit", entries.map((e) => row([
+    el("td", e.seq, "num"),
+    time(e.time),
+    e.intent_type,
+    e.module || "",
+    e.identity || e.guest || "",
+    outcome(e.success, "", e.error),
+  ])));
+}
+
+function setStatus(text) {
+  document.getElementById("status").textContent = text;
+}
+
+async function refresh() {
+  clearTimeout(timer);
+  if (!token()) {
+    return;
+  }
+  try {
+    const [mode, approvals, inflight, executors, history, audit] = await Promise.all([
+      api("GET", "/v1/admin/mode"),
+      api("GET", "/v1/admin/approvals"),
+      api("GET", "/v1/admin/inflight"),
+      api("GET", "/v1/admin/executors"),
+      api("GET", "/v1/admin/history?limit=25"),
+      api("GET", "/v1/admin/audit?limit=25"),
+    ]);
+    document.getElementById("mode").textContent = mode ? "mode " + mode.mode : "";
+    renderApprovals(approvals);
+    renderInFlight(inflight);
+    renderExecutors(executors);
+    renderHistory(history);
+    renderAudit(audit);
+    setStatus("updated " + new Date().toLocaleTimeString());
+  } catch (err) {
+    if (!token()) {
+      return;
+    }
+    setStatus(err.message);
+  }
+  timer = setTimeout(refresh, REFRESH_MS);
+}
+
+function show(signedIn) {
+  document.getElementById("sign-in").hidden = signedIn;
+  document.getElementById("dashboard").hidden = !signedIn;
+  document.getElementById("sign-out").hidden = !signedIn;
+}
+
+function signOut(message) {
+  sessionStorage.removeItem(TOKEN_KEY);
+  clearTimeout(timer);
+  show(false);
+  setStatus(message || "");
+}
+
+document.getElementById("sign-in").addEventListener("submit", (event) => {
+  event.preventDefault();
+  sessionStorage.setItem(TOKEN_KEY, document.getElementById("token").value);
+  document.getElementById("token").value = "";
+  show(true);
+  refresh();
+});
+
+document.getElementById("sign-out").addEventListener("click", () => signOut());
+
+show(Boolean(token()));
+refresh();
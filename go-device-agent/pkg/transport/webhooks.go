@@ -0,0 +1,285 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/internal/uuid"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/access"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/agenterrors"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/connpool"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+)
+
+// Headers sent with every webhook delivery. The signature is the hex
+// HMAC-SHA256, keyed with the webhook's secret, of the timestamp, a ".",
+// and the body, so receivers can reject forged and replayed deliveries.
+const (
+	SignatureHeader = "X-Agent-Signature"
+	TimestampHeader = "X-Agent-Timestamp"
+	DeliveryHeader  = "X-Agent-Delivery"
+)
+
+// Retry schedule: attempts back off from 1s to at most a minute
+const (
+	webhookAttempts   = 6
+	webhookQueue      = 256
+	webhookMaxBackoff = time.Minute
+)
+
+// Webhook is a callback URL that receives async results
+type Webhook struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+
+	// Secret signs deliveries; it is generated when left empty and only
+	// shown when the webhook is added
+	Secret string `json:"secret,omitempty"`
+
+	// IntentTypes limits deliveries to matching intents ("x.*" for a
+	// module); empty for all
+	IntentTypes []string `json:"intent_types,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type webhookWorker struct {
+	Webhook
+	queue chan *gateway.ExecutionResult
+	stop  chan struct{}
+}
+
+// Webhooks delivers async results to registered callback URLs, retrying
+// failures in the background. Each webhook has its own queue, so a slow
+// receiver doesn't hold up the others.
+type Webhooks struct {
+	client *http.Client
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	workers map[string]*webhookWorker
+}
+
+// NewWebhooks creates an empty set of webhooks
+func NewWebhooks(logger *slog.Logger) *Webhooks {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Webhooks{
+		client:  connpool.Default.HTTP(10 * time.Second),
+		logger:  logger,
+		workers: make(map[string]*webhookWorker),
+	}
+}
+
+// Add registers a webhook, generating its ID and any missing secret, and
+// returns it with the secret
+func (h *Webhooks) Add(w Webhook) (Webhook, error) {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Webhook{}, agenterrors.New(agenterrors.InvalidParams, "webhook url must be an absolute http or https URL")
+	}
+	if w.Secret == "" {
+		secret := make([]byte, 32)
+		rand.Read(secret)
+		w.Secret = hex.EncodeToString(secret)
+	}
+	w.ID = uuid.NewID()
+	w.CreatedAt = time.Now().UTC()
+
+	worker := &webhookWorker{
+		Webhook: w,
+		queue:   make(chan *gateway.ExecutionResult, webhookQueue),
+		stop:    make(chan struct{}),
+	}
+	h.mu.Lock()
+	h.workers[w.ID] = worker
+	h.mu.Unlock()
+	go h.run(worker)
+	return w, nil
+}
+
+// Remove unregisters a webhook, dropping its undelivered results
+func (h *Webhooks) Remove(id string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	worker, ok := h.workers[id]
+	if !ok {
+		return agenterrors.Newf(agenterrors.NotFound, "no webhook with id %s", id)
+	}
+	delete(h.workers, id)
+	close(worker.stop)
+	return nil
+}
+
+// List returns the registered webhooks without their secrets, oldest first
+func (h *Webhooks) List() []Webhook {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	list := make([]Webhook, 0, len(h.workers))
+	for _, worker := range h.workers {
+		w := worker.Webhook
+		w.Secret = ""
+		list = append(list, w)
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].CreatedAt.Before(list[b].CreatedAt) })
+	return list
+}
+
+// Close stops every webhook
+func (h *Webhooks) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for id, worker := range h.workers {
+		close(worker.stop)
+		delete(h.workers, id)
+	}
+}
+
+// Deliver queues a result for every matching webhook; intentType is the
+// type of the intent that produced it. A webhook whose queue is full
+// drops the result, which pollers can still collect.
+func (h *Webhooks) Deliver(intentType string, result *gateway.ExecutionResult) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, worker := range h.workers {
+		if !worker.matches(intentType) {
+			continue
+		}
+		select {
+		case worker.queue <- result:
+		default:
+			h.logger.Warn("Webhook is backed up; dropped result", "url", worker.URL, "intent_id", result.IntentID)
+		}
+	}
+}
+
+func (w *webhookWorker) matches(intentType string) bool {
+	if len(w.IntentTypes) == 0 {
+		return true
+	}
+	for _, pattern := range w.IntentTypes {
+		if access.MatchIntentType(pattern, intentType) {
+			return true
+		}
+	}
+	return false
+}
+
+// run delivers a webhook's results one at a time, in order
+func (h *Webhooks) run(w *webhookWorker) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-w.stop
+		cancel()
+	}()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case result := <-w.queue:
+			if err := h.deliver(ctx, w, result); err != nil && ctx.Err() == nil {
+				h.logger.Error("Gave up delivering result", "url", w.URL, "intent_id", result.IntentID, "error", err)
+			}
+		}
+	}
+}
+
+// deliver posts one result, retrying network errors, 429s, and 5xx
+// responses with exponential backoff
+func (h *Webhooks) deliver(ctx context.Context, w *webhookWorker, result *gateway.ExecutionResult) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	delivery := uuid.NewID()
+	backoff := time.Second
+	var lastErr error
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		retry, err := h.post(ctx, w, delivery, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry || attempt == webhookAttempts {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff = min(backoff*2, webhookMaxBackoff)
+	}
+	return lastErr
+}
+
+// post makes one delivery attempt, reporting whether a failure is worth
+// retrying. Retries reuse the delivery ID so receivers can deduplicate.
+func (h *Webhooks) post(ctx context.Context, w *webhookWorker, delivery string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(DeliveryHeader, delivery)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(w.Secret, timestamp, body))
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("receiver answered %s", resp.Status)
+	default:
+		return false, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+}
+
+// Sign computes a delivery signature, for receivers verifying webhooks
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks a delivery's signature header value and rejects
+// timestamps further than maxAge from now
+func VerifySignature(secret, timestamp, signature string, body []byte, maxAge time.Duration) error {
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid webhook timestamp")
+	}
+	if age := time.Since(time.Unix(sent, 0)); age > maxAge || age < -maxAge {
+		return errors.New("webhook timestamp outside the allowed window")
+	}
+	want := "sha256=" + Sign(secret, timestamp, body)
+	if !hmac.Equal([]byte(signature), []byte(want)) {
+		return errors.New("webhook signature mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,399 @@
+// Package tui is the agent's terminal dashboard, for running it by hand
+// during development and demos: intents in flight, each executor's health,
+// the latest results, and approvals waiting on the keyboard, which are
+// answered with a key press. It draws with ANSI escape sequences on the
+// terminal's alternate screen. The package also has the REPL of agent repl,
+// for composing intents by hand.
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/approval"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/events"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/usage"
+)
+
+const (
+	// maxResults is how many results the dashboard remembers
+	maxResults = 100
+
+	// refreshEvery redraws the elapsed times and stats between events
+	refreshEvery = 500 * time.Millisecond
+)
+
+// ANSI sequences
+const (
+	altScreen  = "\x1b[?1049h\x1b[?25l"
+	mainScreen = "\x1b[?25h\x1b[?1049l"
+	home       = "\x1b[H"
+	clearLine  = "\x1b[K"
+	clearBelow = "\x1b[J"
+	bold       = "\x1b[1m"
+	dim        = "\x1b[2m"
+	inverse    = "\x1b[7m"
+	red        = "\x1b[31m"
+	green      = "\x1b[32m"
+	yellow     = "\x1b[33m"
+	reset      = "\x1b[0m"
+)
+
+// result is a finished intent, from an intent.completed event
+type result struct {
+	at         time.Time
+	intentType string
+	module     string
+	success    bool
+	errorCode  string
+	err        string
+	wallTimeMs int64
+}
+
+// Dashboard draws the gateway's state and answers keyboard approvals
+type Dashboard struct {
+	gw       *gateway.Gateway
+	keyboard *approval.Keyboard
+	note     string
+
+	mu       sync.Mutex
+	results  []result
+	selected int
+	status   string
+	changed  chan struct{}
+}
+
+// New creates a dashboard of gw, following results on bus. With a
+// keyboard approver, approvals waiting on it can be answered.
+func New(gw *gateway.Gateway, bus *events.Bus, keyboard *approval.Keyboard) *Dashboard {
+	d := &Dashboard{gw: gw, keyboard: keyboard, changed: make(chan struct{}, 1)}
+	if bus != nil {
+		bus.Subscribe("intent.completed", d.completed)
+		bus.Subscribe("approval.*", func(events.Event) { d.redraw() })
+	}
+	return d
+}
+
+// SetNote sets a line shown at the bottom, such as where the logs go
+func (d *Dashboard) SetNote(note string) {
+	d.note = note
+}
+
+func (d *Dashboard) completed(e events.Event) {
+	r := result{at: e.Timestamp}
+	r.intentType, _ = e.Data["intent_type"].(string)
+	r.module, _ = e.Data["module"].(string)
+	r.success, _ = e.Data["success"].(bool)
+	r.errorCode, _ = e.Data["error_code"].(string)
+	r.err, _ = e.Data["error"].(string)
+	if u, ok := e.Data["usage"].(*usage.Usage); ok && u != nil {
+		r.wallTimeMs = u.WallTimeMs
+	}
+	d.mu.Lock()
+	d.results = append(d.results, r)
+	if len(d.results) > maxResults {
+		d.results = d.results[len(d.results)-maxResults:]
+	}
+	d.mu.Unlock()
+	d.redraw()
+}
+
+// redraw asks Run to draw again without waiting for it
+func (d *Dashboard) redraw() {
+	select {
+	case d.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Run draws the dashboard on out and reads keys from in until q is pressed
+// or ctx ends, then restores the terminal
+func (d *Dashboard) Run(ctx context.Context, in, out *os.File) error {
+	restore, err := makeRaw(in, out)
+	if err != nil {
+		return fmt.Errorf("terminal: %w", err)
+	}
+	defer restore()
+	fmt.Fprint(out, altScreen)
+	defer fmt.Fprint(out, mainScreen)
+
+	keys := make(chan string)
+	go readKeys(in, keys)
+	ticker := time.NewTicker(refreshEvery)
+	defer ticker.Stop()
+	for {
+		d.draw(out)
+		select {
+		case <-ctx.Done():
+			return nil
+		case key, ok := <-keys:
+			if !ok || !d.handle(key) {
+				return nil
+			}
+		case <-d.changed:
+		case <-ticker.C:
+		}
+	}
+}
+
+// keySequences names the escape sequences of the keys readKeys recognizes
+var keySequences = []struct{ seq, key string }{
+	{"\x1b[A", "up"}, {"\x1bOA", "up"},
+	{"\x1b[B", "down"}, {"\x1bOB", "down"},
+	{"\x1b[C", "right"}, {"\x1bOC", "right"},
+	{"\x1b[D", "left"}, {"\x1bOD", "left"},
+	{"\x1b[H", "home"}, {"\x1bOH", "home"}, {"\x1b[1~", "home"},
+	{"\x1b[F", "end"}, {"\x1bOF", "end"}, {"\x1b[4~", "end"},
+	{"\x1b[3~", "delete"},
+}
+
+// readKeys sends each key read from in, with arrow and editing keys by name
+// ("up", "left", "home", "delete", and so on), and closes keys when in does
+func readKeys(in *os.File, keys chan<- string) {
+	defer close(keys)
+	buf := make([]byte, 64)
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			return
+		}
+	next:
+		for s := string(buf[:n]); s != ""; {
+			for _, k := range keySequences {
+				if strings.HasPrefix(s, k.seq) {
+					keys <- k.key
+					s = s[len(k.seq):]
+					continue next
+				}
+			}
+			_, size := utf8.DecodeRuneInString(s)
+			keys <- s[:size]
+			s = s[size:]
+		}
+	}
+}
+
+// handle acts on a key, returning false to quit
+func (d *Dashboard) handle(key string) bool {
+	pending := d.pending()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch key {
+	case "q", "Q", "\x03":
+		return false
+	case "up", "k":
+		d.selected = max(d.selected-1, 0)
+	case "down", "j":
+		d.selected = min(d.selected+1, max(len(pending)-1, 0))
+	case "a", "y", "d", "n":
+		if d.selected >= len(pending) {
+			d.status = "No approval selected"
+			return true
+		}
+		req := pending[d.selected]
+		approve := key == "a" || key == "y"
+		if req.Approver != "keyboard" || d.keyboard == nil {
+			d.status = fmt.Sprintf("%s is waiting on %s, not the keyboard", req.IntentType, req.Approver)
+			return true
+		}
+		if err := d.keyboard.Answer(req.ID, approve); err != nil {
+			d.status = err.Error()
+			return true
+		}
+		if approve {
+			d.status = "Approved " + req.IntentType
+		} else {
+			d.status = "Denied " + req.IntentType
+		}
+	}
+	return true
+}
+
+func (d *Dashboard) pending() []approval.Request {
+	if a := d.gw.Approvals(); a != nil {
+		return a.Pending()
+	}
+	return nil
+}
+
+// draw writes one frame, fitting the sections to the terminal
+func (d *Dashboard) draw(out *os.File) {
+	width, height, err := size(out)
+	if err != nil || width <= 0 || height <= 0 {
+		width, height = 100, 40
+	}
+	var f frame
+	f.width = width
+
+	now := time.Now()
+	f.line(bold+fmt.Sprintf("Device agent · mode %s", d.gw.Mode())+reset,
+		dim+"↑/↓ select  a approve  d deny  q quit"+reset)
+
+	inFlight := d.gw.InFlight()
+	f.section(fmt.Sprintf("In flight (%d)", len(inFlight)))
+	for n, i := range inFlight {
+		if n == 5 {
+			f.line(dim+fmt.Sprintf("  … %d more", len(inFlight)-n)+reset, "")
+			break
+		}
+		f.line(fmt.Sprintf("  %-28s %-12s %-12s", i.IntentType, i.Module, i.Identity),
+			(time.Duration(i.ElapsedMs) * time.Millisecond).Round(100*time.Millisecond).String())
+	}
+
+	pending := d.pending()
+	d.mu.Lock()
+	d.selected = min(d.selected, max(len(pending)-1, 0))
+	selected, status := d.selected, d.status
+	results := append([]result(nil), d.results...)
+	d.mu.Unlock()
+	if d.gw.Approvals() != nil {
+		f.section(fmt.Sprintf("Approvals (%d)", len(pending)))
+		for n, r := range pending {
+			text := fmt.Sprintf("  %s [%s via %s] %s", r.ID, r.Risk, r.Approver, r.Summary())
+			if n == selected {
+				text = inverse + text + reset
+			}
+			f.line(text, fmt.Sprintf("expires in %s", r.Expires.Sub(now).Round(time.Second)))
+		}
+	}
+
+	caps := d.gw.Capabilities().Executors
+	stats := d.gw.Stats()
+	f.section(fmt.Sprintf("Executors (%d)", len(caps)))
+	f.line(dim+fmt.Sprintf("  %-14s %-10s %6s %6s %9s %9s  %s", "name", "status", "runs", "ok", "p50", "p95", "last error")+reset, "")
+	room := max((height-f.rows()-4)/2, 3)
+	for n, e := range caps {
+		if n == room && len(caps) > room+1 {
+			f.line(dim+fmt.Sprintf("  … %d more", len(caps)-n)+reset, "")
+			break
+		}
+		state := green + "ok" + reset
+		switch {
+		case e.Disabled:
+			state = dim + "disabled" + reset
+		case !e.Available:
+			state = red + "down" + reset
+		case e.Degraded:
+			state = yellow + "degraded" + reset
+		}
+		s := stats[e.Name]
+		ok, p50, p95 := "-", "-", "-"
+		if s.Executions > 0 {
+			ok = fmt.Sprintf("%.0f%%", s.SuccessRate*100)
+			p50, p95 = fmt.Sprintf("%.1fms", s.P50Ms), fmt.Sprintf("%.1fms", s.P95Ms)
+		}
+		f.line(fmt.Sprintf("  %-14s %s %6d %6s %9s %9s  %s",
+			e.Name, pad(state, 10), s.Executions, ok, p50, p95, s.LastError), "")
+	}
+
+	f.section("Recent results")
+	room = max(height-f.rows()-2, 1)
+	if len(results) > room {
+		results = results[len(results)-room:]
+	}
+	for n := len(results) - 1; n >= 0; n-- {
+		r := results[n]
+		mark, detail := green+"✓"+reset, ""
+		if !r.success {
+			mark, detail = red+"✗"+reset, r.errorCode+" "+r.err
+		}
+		f.line(fmt.Sprintf("  %s %s %-28s %-12s %s", r.at.Format("15:04:05"), mark, r.intentType, r.module, detail),
+			fmt.Sprintf("%dms", r.wallTimeMs))
+	}
+
+	for f.rows() < height-1 {
+		f.line("", "")
+	}
+	footer := status
+	if footer == "" {
+		footer = d.note
+	}
+	f.line(dim+footer+reset, "")
+	fmt.Fprint(out, home+strings.Join(f.lines[:min(len(f.lines), height)], clearLine+"\r\n")+clearLine+clearBelow)
+}
+
+// frame collects the lines of one drawing, cut to the terminal's width
+type frame struct {
+	width int
+	lines []string
+}
+
+func (f *frame) rows() int {
+	return len(f.lines)
+}
+
+func (f *frame) section(title string) {
+	f.line("", "")
+	f.line(bold+title+reset, "")
+}
+
+// line adds a line with left aligned text and, space permitting, right
+// aligned text after it
+func (f *frame) line(left, right string) {
+	l, r := visible(left), visible(right)
+	if right != "" && l+r+1 <= f.width {
+		f.lines = append(f.lines, left+strings.Repeat(" ", f.width-l-r)+right)
+		return
+	}
+	f.lines = append(f.lines, truncate(left, f.width))
+}
+
+// visible counts the runes of s that are printed, skipping escape
+// sequences
+func visible(s string) int {
+	n := 0
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			j := strings.IndexByte(s[i:], 'm')
+			if j < 0 {
+				break
+			}
+			i += j + 1
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		n++
+	}
+	return n
+}
+
+// truncate cuts s to width printed runes, keeping its escape sequences
+func truncate(s string, width int) string {
+	if visible(s) <= width {
+		return s
+	}
+	var b strings.Builder
+	n := 0
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			j := strings.IndexByte(s[i:], 'm')
+			if j < 0 {
+				break
+			}
+			b.WriteString(s[i : i+j+1])
+			i += j + 1
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		if n < width-1 {
+			b.WriteString(s[i : i+size])
+		} else if n == width-1 {
+			b.WriteString("…")
+		}
+		i += size
+		n++
+	}
+	return b.String() + reset
+}
+
+// pad pads s with spaces to width printed runes
+func pad(s string, width int) string {
+	return s + strings.Repeat(" ", max(width-visible(s), 0))
+}
@@ -0,0 +1,127 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// lineEditor reads lines from keys sent by readKeys, with editing, history
+// on ↑/↓, and completion on Tab
+type lineEditor struct {
+	out     io.Writer
+	keys    <-chan string
+	history []string
+
+	// complete returns the text to put in place of the word before the
+	// cursor, and the candidates to list when there is more than one
+	complete func(before string) (string, []string)
+}
+
+// readLine reads one line, returning io.EOF when Ctrl+D is pressed on an
+// empty line or keys closes
+func (e *lineEditor) readLine(ctx context.Context, prompt string) (string, error) {
+	var line []rune
+	cursor := 0
+	recall := len(e.history) // the history entry shown; len means the new line
+	pending := ""            // the new line, kept while browsing history
+	draw := func() {
+		fmt.Fprint(e.out, "\r"+prompt+string(line)+clearLine)
+		if back := len(line) - cursor; back > 0 {
+			fmt.Fprintf(e.out, "\x1b[%dD", back)
+		}
+	}
+	draw()
+	for {
+		var key string
+		var ok bool
+		select {
+		case <-ctx.Done():
+			fmt.Fprint(e.out, "\r\n")
+			return "", ctx.Err()
+		case key, ok = <-e.keys:
+		}
+		if !ok {
+			fmt.Fprint(e.out, "\r\n")
+			return "", io.EOF
+		}
+		switch key {
+		case "\r", "\n":
+			fmt.Fprint(e.out, "\r\n")
+			text := string(line)
+			if strings.TrimSpace(text) != "" && (len(e.history) == 0 || e.history[len(e.history)-1] != text) {
+				e.history = append(e.history, text)
+			}
+			return text, nil
+		case "\x04": // Ctrl+D
+			if len(line) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			if cursor < len(line) {
+				line = append(line[:cursor], line[cursor+1:]...)
+			}
+		case "\x03": // Ctrl+C, where it isn't a signal
+			fmt.Fprint(e.out, "^C\r\n")
+			line, cursor = nil, 0
+		case "\x7f", "\b":
+			if cursor > 0 {
+				line = append(line[:cursor-1], line[cursor:]...)
+				cursor--
+			}
+		case "delete":
+			if cursor < len(line) {
+				line = append(line[:cursor], line[cursor+1:]...)
+			}
+		case "left", "\x02":
+			cursor = max(cursor-1, 0)
+		case "right", "\x06":
+			cursor = min(cursor+1, len(line))
+		case "home", "\x01":
+			cursor = 0
+		case "end", "\x05":
+			cursor = len(line)
+		case "\x15": // Ctrl+U
+			line, cursor = line[cursor:], 0
+		case "\x0b": // Ctrl+K
+			line = line[:cursor]
+		case "up", "down":
+			if recall == len(e.history) {
+				pending = string(line)
+			}
+			if key == "up" {
+				recall = max(recall-1, 0)
+			} else {
+				recall = min(recall+1, len(e.history))
+			}
+			text := pending
+			if recall < len(e.history) {
+				text = e.history[recall]
+			}
+			line = []rune(text)
+			cursor = len(line)
+		case "\t":
+			if e.complete == nil {
+				break
+			}
+			before := string(line[:cursor])
+			replacement, candidates := e.complete(before)
+			if len(candidates) > 1 {
+				fmt.Fprint(e.out, "\r\n"+strings.Join(candidates, "  ")+clearLine+"\r\n")
+			}
+			head := []rune(replacement)
+			line = append(head, line[cursor:]...)
+			cursor = len(head)
+		default:
+			r := []rune(key)
+			if len(r) != 1 || !unicode.IsPrint(r[0]) {
+				break
+			}
+			line = append(line[:cursor], append(r, line[cursor:]...)...)
+			cursor++
+		}
+		draw()
+	}
+}
@@ -0,0 +1,518 @@
+package tui
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/gateway"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/intent"
+	"github.com/vinod901/local-agent-core/go-device-agent/pkg/schema"
+)
+
+// replCommand is one of the REPL's commands, with its arguments and what it
+// does
+type replCommand struct{ name, args, help string }
+
+// replCommands are in the order help lists them
+var replCommands = []replCommand{
+	{"types", "[prefix]", "list the intent types the executors handle"},
+	{"new", "<intent_type>", "start an intent and show its parameters"},
+	{"set", "<param> <value>", "set a parameter; values are JSON, or else text"},
+	{"unset", "<param>", "remove a parameter"},
+	{"module", "<name>", "target another module than the executor found for the type"},
+	{"confidence", "<0-1>", "set the intent's confidence"},
+	{"reasoning", "<text>", "set the intent's reasoning"},
+	{"permission", "on|off", "mark the intent as needing permission"},
+	{"show", "", "print the intent as it would be sent"},
+	{"schema", "[intent_type]", "show an intent type's parameters"},
+	{"validate", "", "run the gateway's checks without executing"},
+	{"send", "", "submit the intent to the gateway and print the result"},
+	{"help", "", "list the commands"},
+	{"quit", "", "leave the REPL (or Ctrl+D)"},
+}
+
+// draft is the intent being composed
+type draft struct {
+	intentType string
+	module     string
+	params     map[string]interface{}
+	confidence float32
+	reasoning  string
+	permission bool
+}
+
+func (d *draft) builder() *intent.Builder {
+	b := intent.New(d.intentType).
+		Params(d.params).
+		Confidence(d.confidence).
+		Reasoning(d.reasoning)
+	if d.module != "" {
+		b.Target(d.module)
+	}
+	if d.permission {
+		b.RequirePermission()
+	}
+	return b
+}
+
+// REPL composes intents field by field at a prompt, with completion of
+// intent types and parameters from the capability manifest, and submits
+// them to the gateway, for trying out executors during development
+type REPL struct {
+	gw    *gateway.Gateway
+	out   io.Writer
+	draft *draft
+}
+
+// NewREPL creates a REPL submitting intents to gw
+func NewREPL(gw *gateway.Gateway) *REPL {
+	return &REPL{gw: gw}
+}
+
+// Run reads commands from in and writes to out until quit, Ctrl+D, or ctx
+// ends. On a terminal lines can be edited, recalled with ↑/↓, and completed
+// with Tab; otherwise, as when commands are piped in, they are read plainly.
+func (r *REPL) Run(ctx context.Context, in, out *os.File) error {
+	r.out = out
+	fmt.Fprintln(out, bold+"Device agent REPL"+reset+dim+" · new <intent_type> to start, Tab completes, help lists commands"+reset)
+
+	var readLine func(ctx context.Context, prompt string) (string, error)
+	if restore, err := makeRaw(in, out); err == nil {
+		defer restore()
+		keys := make(chan string)
+		go readKeys(in, keys)
+		editor := &lineEditor{out: out, keys: keys, complete: r.complete}
+		readLine = editor.readLine
+	} else {
+		lines := make(chan string)
+		go func() {
+			defer close(lines)
+			scanner := bufio.NewScanner(in)
+			for scanner.Scan() {
+				lines <- scanner.Text()
+			}
+		}()
+		readLine = func(ctx context.Context, prompt string) (string, error) {
+			fmt.Fprint(out, prompt)
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case line, ok := <-lines:
+				if !ok {
+					fmt.Fprintln(out)
+					return "", io.EOF
+				}
+				fmt.Fprintln(out, line)
+				return line, nil
+			}
+		}
+	}
+
+	for {
+		line, err := readLine(ctx, r.prompt())
+		if err != nil {
+			return nil
+		}
+		if !r.run(ctx, line) {
+			return nil
+		}
+	}
+}
+
+func (r *REPL) prompt() string {
+	if r.draft == nil {
+		return bold + "agent> " + reset
+	}
+	return bold + r.draft.intentType + "> " + reset
+}
+
+// run carries out one command line, returning false to quit
+func (r *REPL) run(ctx context.Context, line string) bool {
+	command, rest, _ := strings.Cut(strings.TrimSpace(line), " ")
+	rest = strings.TrimSpace(rest)
+	switch command {
+	case "":
+	case "quit", "exit":
+		return false
+	case "help", "?":
+		for _, c := range replCommands {
+			fmt.Fprintf(r.out, "  %-28s %s\n", c.name+" "+c.args, dim+c.help+reset)
+		}
+	case "types":
+		r.types(rest)
+	case "new":
+		r.newDraft(rest)
+	case "schema":
+		intentType := rest
+		if intentType == "" && r.draft != nil {
+			intentType = r.draft.intentType
+		}
+		if intentType == "" {
+			r.errorf("schema needs an intent type")
+			break
+		}
+		action, module := r.action(intentType)
+		if action == nil {
+			r.errorf("no executor handles %s", intentType)
+			break
+		}
+		r.describe(action, module)
+	default:
+		if r.draft == nil {
+			if slices.ContainsFunc(replCommands, func(c replCommand) bool { return c.name == command }) {
+				r.errorf("start an intent with new <intent_type> first")
+			} else {
+				r.errorf("unknown command %q; help lists the commands", command)
+			}
+			break
+		}
+		r.edit(ctx, command, rest)
+	}
+	return true
+}
+
+// edit carries out a command acting on the draft
+func (r *REPL) edit(ctx context.Context, command, rest string) {
+	d := r.draft
+	switch command {
+	case "set":
+		name, value, _ := strings.Cut(rest, " ")
+		value = strings.TrimSpace(value)
+		if name == "" || value == "" {
+			r.errorf("set needs a parameter and a value")
+			return
+		}
+		var v interface{}
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			v = value
+		}
+		d.params[name] = v
+		if action, _ := r.action(d.intentType); action != nil && action.Parameters != nil {
+			if _, known := action.Parameters.Properties[name]; !known && len(action.Parameters.Properties) > 0 {
+				fmt.Fprintln(r.out, yellow+"note: "+d.intentType+" has no parameter "+name+reset)
+			}
+		}
+	case "unset":
+		if _, ok := d.params[rest]; !ok {
+			r.errorf("%s is not set", rest)
+			return
+		}
+		delete(d.params, rest)
+	case "module":
+		if rest == "" {
+			r.errorf("module needs a name")
+			return
+		}
+		d.module = rest
+	case "confidence":
+		c, err := strconv.ParseFloat(rest, 32)
+		if err != nil || c < 0 || c > 1 {
+			r.errorf("confidence must be a number from 0 to 1")
+			return
+		}
+		d.confidence = float32(c)
+	case "reasoning":
+		if rest == "" {
+			r.errorf("reasoning needs text")
+			return
+		}
+		d.reasoning = rest
+	case "permission":
+		switch rest {
+		case "on":
+			d.permission = true
+		case "off":
+			d.permission = false
+		default:
+			r.errorf("permission is on or off")
+		}
+	case "show":
+		i, err := d.builder().Build()
+		if err != nil {
+			r.errorf("%v", err)
+			return
+		}
+		r.printJSON(i)
+	case "validate":
+		r.validate(ctx)
+	case "send":
+		r.send(ctx)
+	default:
+		r.errorf("unknown command %q; help lists the commands", command)
+	}
+}
+
+// types lists the intent types starting with prefix and their modules
+func (r *REPL) types(prefix string) {
+	for _, e := range r.gw.Capabilities().Executors {
+		for _, a := range e.Actions {
+			if !strings.HasPrefix(a.IntentType, prefix) {
+				continue
+			}
+			var notes []string
+			if !e.Available || e.Disabled {
+				notes = append(notes, "unavailable")
+			}
+			if a.RequiresPermission {
+				notes = append(notes, "needs permission")
+			}
+			if a.Denied {
+				notes = append(notes, "denied by mode")
+			}
+			note := ""
+			if len(notes) > 0 {
+				note = dim + " (" + strings.Join(notes, ", ") + ")" + reset
+			}
+			fmt.Fprintf(r.out, "  %-32s %s%s\n", a.IntentType, e.Name, note)
+		}
+	}
+}
+
+// newDraft starts an intent of the given type, targeting the executor
+// that handles it
+func (r *REPL) newDraft(intentType string) {
+	if intentType == "" {
+		r.errorf("new needs an intent type; types lists them")
+		return
+	}
+	d := &draft{
+		intentType: intentType,
+		params:     make(map[string]interface{}),
+		confidence: 1.0,
+		reasoning:  "composed in the agent REPL",
+	}
+	action, module := r.action(intentType)
+	if action == nil {
+		fmt.Fprintln(r.out, yellow+"note: no executor handles "+intentType+"; set its module with module <name>"+reset)
+	} else {
+		d.module = module
+		d.permission = action.RequiresPermission
+		r.describe(action, module)
+	}
+	r.draft = d
+}
+
+// action finds the executor action handling intentType
+func (r *REPL) action(intentType string) (*gateway.ActionCapability, string) {
+	for _, e := range r.gw.Capabilities().Executors {
+		for n := range e.Actions {
+			if e.Actions[n].IntentType == intentType {
+				return &e.Actions[n], e.Name
+			}
+		}
+	}
+	return nil, ""
+}
+
+// describe prints an action's parameters from its schema
+func (r *REPL) describe(action *gateway.ActionCapability, module string) {
+	fmt.Fprintf(r.out, "%s → %s\n", bold+action.IntentType+reset, module)
+	s := action.Parameters
+	if s == nil || len(s.Properties) == 0 {
+		fmt.Fprintln(r.out, dim+"  no parameter schema"+reset)
+		return
+	}
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(a, b int) bool {
+		ra, rb := slices.Contains(s.Required, names[a]), slices.Contains(s.Required, names[b])
+		if ra != rb {
+			return ra
+		}
+		return names[a] < names[b]
+	})
+	for _, name := range names {
+		fmt.Fprintf(r.out, "  %-20s %s\n", name, hint(s.Properties[name], slices.Contains(s.Required, name)))
+	}
+}
+
+// hint summarizes a parameter's schema in one line
+func hint(p *schema.Schema, required bool) string {
+	var parts []string
+	if p.Type != "" {
+		parts = append(parts, p.Type)
+	}
+	if required {
+		parts = append(parts, yellow+"required"+reset)
+	}
+	if len(p.Enum) > 0 {
+		values := make([]string, len(p.Enum))
+		for n, v := range p.Enum {
+			values[n] = fmt.Sprint(v)
+		}
+		parts = append(parts, "one of "+strings.Join(values, ", "))
+	}
+	switch {
+	case p.Minimum != nil && p.Maximum != nil:
+		parts = append(parts, fmt.Sprintf("%g to %g", *p.Minimum, *p.Maximum))
+	case p.Minimum != nil:
+		parts = append(parts, fmt.Sprintf("at least %g", *p.Minimum))
+	case p.Maximum != nil:
+		parts = append(parts, fmt.Sprintf("at most %g", *p.Maximum))
+	}
+	if p.Description != "" {
+		parts = append(parts, dim+p.Description+reset)
+	}
+	return strings.Join(parts, " · ")
+}
+
+func (r *REPL) validate(ctx context.Context) {
+	data, err := r.draft.builder().JSON()
+	if err != nil {
+		r.errorf("%v", err)
+		return
+	}
+	v := r.gw.ValidateIntent(ctx, data, intent.JSON)
+	for _, c := range v.Checks {
+		if c.OK {
+			fmt.Fprintf(r.out, "  %s %s\n", green+"✓"+reset, c.Name)
+			continue
+		}
+		fmt.Fprintf(r.out, "  %s %s: %s %s\n", red+"✗"+reset, c.Name, c.ErrorCode, c.Error)
+		if detail, ok := c.Detail.(map[string]interface{}); ok {
+			if fields, ok := detail["field_errors"].([]schema.FieldError); ok {
+				for _, f := range fields {
+					fmt.Fprintf(r.out, "      %s: %s\n", f.Field, f.Message)
+				}
+			}
+		}
+	}
+	switch {
+	case !v.Valid:
+		fmt.Fprintln(r.out, red+"invalid"+reset)
+	case v.NeedsChallenge:
+		fmt.Fprintln(r.out, green+"valid"+reset+", needs a PIN")
+	case v.NeedsApproval != "":
+		fmt.Fprintln(r.out, green+"valid"+reset+", needs "+v.NeedsApproval+" risk approval")
+	default:
+		fmt.Fprintln(r.out, green+"valid"+reset)
+	}
+}
+
+func (r *REPL) send(ctx context.Context) {
+	data, err := r.draft.builder().JSON()
+	if err != nil {
+		r.errorf("%v", err)
+		return
+	}
+	result, err := r.gw.ProcessIntent(ctx, data)
+	if err != nil {
+		r.errorf("rejected: %v", err)
+		return
+	}
+	status := green + "✓ " + result.Module + " " + result.Action + reset
+	if !result.Success {
+		status = red + "✗ " + string(result.ErrorCode) + " " + result.Error + reset
+	}
+	if result.Usage != nil {
+		status += dim + fmt.Sprintf(" (%dms)", result.Usage.WallTimeMs) + reset
+	}
+	fmt.Fprintln(r.out, status)
+	if len(result.Result) > 0 {
+		r.printJSON(result.Result)
+	}
+	if len(result.Adjusted) > 0 {
+		fmt.Fprintln(r.out, yellow+"adjusted by the safety policy:"+reset)
+		r.printJSON(result.Adjusted)
+	}
+}
+
+func (r *REPL) printJSON(v interface{}) {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		r.errorf("%v", err)
+		return
+	}
+	fmt.Fprintln(r.out, string(out))
+}
+
+func (r *REPL) errorf(format string, args ...interface{}) {
+	fmt.Fprintln(r.out, red+fmt.Sprintf(format, args...)+reset)
+}
+
+// complete completes the word before the cursor: a command, an intent
+// type, a parameter name, or one of a parameter's enum values
+func (r *REPL) complete(before string) (string, []string) {
+	words := strings.Fields(before)
+	if before == "" || strings.HasSuffix(before, " ") {
+		words = append(words, "")
+	}
+	word := words[len(words)-1]
+	var candidates []string
+	switch {
+	case len(words) == 1:
+		for _, c := range replCommands {
+			candidates = append(candidates, c.name)
+		}
+	case len(words) == 2 && (words[0] == "new" || words[0] == "schema" || words[0] == "types"):
+		for _, e := range r.gw.Capabilities().Executors {
+			for _, a := range e.Actions {
+				candidates = append(candidates, a.IntentType)
+			}
+		}
+	case len(words) == 2 && words[0] == "module":
+		for _, e := range r.gw.Capabilities().Executors {
+			candidates = append(candidates, e.Name)
+		}
+	case len(words) == 2 && words[0] == "permission":
+		candidates = []string{"on", "off"}
+	case len(words) == 2 && words[0] == "unset" && r.draft != nil:
+		for name := range r.draft.params {
+			candidates = append(candidates, name)
+		}
+	case len(words) == 2 && words[0] == "set" && r.draft != nil:
+		if p := r.params(); p != nil {
+			for name := range p.Properties {
+				candidates = append(candidates, name)
+			}
+		}
+	case len(words) == 3 && words[0] == "set" && r.draft != nil:
+		if p := r.params(); p != nil && p.Properties[words[1]] != nil {
+			for _, v := range p.Properties[words[1]].Enum {
+				candidates = append(candidates, fmt.Sprint(v))
+			}
+		}
+	}
+
+	var matches []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, word) && !slices.Contains(matches, c) {
+			matches = append(matches, c)
+		}
+	}
+	sort.Strings(matches)
+	head := before[:len(before)-len(word)]
+	switch len(matches) {
+	case 0:
+		return before, nil
+	case 1:
+		return head + matches[0] + " ", nil
+	}
+	// Extend the word to what every match starts with
+	common := matches[0]
+	for _, m := range matches[1:] {
+		for !strings.HasPrefix(m, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	return head + common, matches
+}
+
+// params is the parameter schema of the draft's intent type, if any
+func (r *REPL) params() *schema.Schema {
+	action, _ := r.action(r.draft.intentType)
+	if action == nil {
+		return nil
+	}
+	return action.Parameters
+}
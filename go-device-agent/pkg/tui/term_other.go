@@ -0,0 +1,18 @@
+//go:build !linux && !darwin && !windows
+
+package tui
+
+import (
+	"errors"
+	"os"
+)
+
+var errUnsupported = errors.New("the dashboard needs a Linux, macOS, or Windows terminal")
+
+func makeRaw(in, out *os.File) (func(), error) {
+	return nil, errUnsupported
+}
+
+func size(out *os.File) (int, int, error) {
+	return 0, 0, errUnsupported
+}
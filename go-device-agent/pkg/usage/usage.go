@@ -0,0 +1,193 @@
+// Package usage measures what handling an intent costs: wall time, calls
+// to external services, bytes transferred, and commands sent to devices.
+// The gateway puts a Meter in each intent's context; the shared HTTP pool
+// and executors that drive devices count against it.
+package usage
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Usage is what one intent consumed
+type Usage struct {
+	WallTimeMs     int64 `json:"wall_time_ms"`
+	ExternalCalls  int64 `json:"external_calls"`
+	BytesSent      int64 `json:"bytes_sent"`
+	BytesReceived  int64 `json:"bytes_received"`
+	DeviceCommands int64 `json:"device_commands"`
+}
+
+// Meter counts usage for one intent. Counts also go to the meter of the
+// intent that spawned it, so a plan or fan-out reports its steps' usage.
+// A nil Meter counts nothing.
+type Meter struct {
+	parent   *Meter
+	start    time.Time
+	calls    atomic.Int64
+	sent     atomic.Int64
+	received atomic.Int64
+	commands atomic.Int64
+}
+
+type meterKey struct{}
+
+// Start returns a context carrying a new meter, nested under any meter
+// ctx already carries
+func Start(ctx context.Context) (context.Context, *Meter) {
+	m := &Meter{parent: FromContext(ctx), start: time.Now()}
+	return context.WithValue(ctx, meterKey{}, m), m
+}
+
+// FromContext returns the meter ctx carries, or nil
+func FromContext(ctx context.Context) *Meter {
+	m, _ := ctx.Value(meterKey{}).(*Meter)
+	return m
+}
+
+// ExternalCall counts a request to a service outside the agent
+func (m *Meter) ExternalCall() {
+	for ; m != nil; m = m.parent {
+		m.calls.Add(1)
+	}
+}
+
+// Transferred counts bytes sent and received
+func (m *Meter) Transferred(sent, received int64) {
+	for ; m != nil; m = m.parent {
+		m.sent.Add(sent)
+		m.received.Add(received)
+	}
+}
+
+// DeviceCommand counts a command sent to a device
+func (m *Meter) DeviceCommand() {
+	for ; m != nil; m = m.parent {
+		m.commands.Add(1)
+	}
+}
+
+// Usage returns the counts so far, with the time since the meter started
+func (m *Meter) Usage() Usage {
+	if m == nil {
+		return Usage{}
+	}
+	return Usage{
+		WallTimeMs:     time.Since(m.start).Milliseconds(),
+		ExternalCalls:  m.calls.Load(),
+		BytesSent:      m.sent.Load(),
+		BytesReceived:  m.received.Load(),
+		DeviceCommands: m.commands.Load(),
+	}
+}
+
+// DeviceCommand counts a device command against ctx's meter, for executors
+// that don't otherwise need it
+func DeviceCommand(ctx context.Context) {
+	FromContext(ctx).DeviceCommand()
+}
+
+// Totals aggregates the usage of many intents of one kind
+type Totals struct {
+	Count          int64   `json:"count"`
+	Failures       int64   `json:"failures"`
+	WallTimeMs     int64   `json:"wall_time_ms"`
+	MaxWallTimeMs  int64   `json:"max_wall_time_ms"`
+	AvgWallTimeMs  float64 `json:"avg_wall_time_ms"`
+	ExternalCalls  int64   `json:"external_calls"`
+	BytesSent      int64   `json:"bytes_sent"`
+	BytesReceived  int64   `json:"bytes_received"`
+	DeviceCommands int64   `json:"device_commands"`
+}
+
+func (t *Totals) add(u Usage, failed bool) {
+	t.Count++
+	if failed {
+		t.Failures++
+	}
+	t.WallTimeMs += u.WallTimeMs
+	t.MaxWallTimeMs = max(t.MaxWallTimeMs, u.WallTimeMs)
+	t.AvgWallTimeMs = float64(t.WallTimeMs) / float64(t.Count)
+	t.ExternalCalls += u.ExternalCalls
+	t.BytesSent += u.BytesSent
+	t.BytesReceived += u.BytesReceived
+	t.DeviceCommands += u.DeviceCommands
+}
+
+// Stats aggregates usage per executor and per intent type since the agent
+// started. It is safe for concurrent use.
+type Stats struct {
+	mu      sync.Mutex
+	since   time.Time
+	modules map[string]*Totals
+	intents map[string]*Totals
+}
+
+// NewStats creates empty stats
+func NewStats() *Stats {
+	return &Stats{
+		since:   time.Now(),
+		modules: make(map[string]*Totals),
+		intents: make(map[string]*Totals),
+	}
+}
+
+// Record adds one intent's usage
+func (s *Stats) Record(module, intentType string, u Usage, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, entry := range []struct {
+		totals map[string]*Totals
+		key    string
+	}{{s.modules, module}, {s.intents, intentType}} {
+		if entry.key == "" {
+			continue
+		}
+		t, ok := entry.totals[entry.key]
+		if !ok {
+			t = &Totals{}
+			entry.totals[entry.key] = t
+		}
+		t.add(u, failed)
+	}
+}
+
+// Snapshot is a copy of the stats
+type Snapshot struct {
+	Since   time.Time         `json:"since"`
+	Modules map[string]Totals `json:"modules"`
+	Intents map[string]Totals `json:"intents"`
+
+	// Costliest lists intent types by total wall time, highest first
+	Costliest []string `json:"costliest"`
+}
+
+// Snapshot copies the stats
+func (s *Stats) Snapshot() Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap := Snapshot{
+		Since:     s.since,
+		Modules:   make(map[string]Totals, len(s.modules)),
+		Intents:   make(map[string]Totals, len(s.intents)),
+		Costliest: make([]string, 0, len(s.intents)),
+	}
+	for k, t := range s.modules {
+		snap.Modules[k] = *t
+	}
+	for k, t := range s.intents {
+		snap.Intents[k] = *t
+		snap.Costliest = append(snap.Costliest, k)
+	}
+	sort.Slice(snap.Costliest, func(a, b int) bool {
+		ta, tb := snap.Intents[snap.Costliest[a]], snap.Intents[snap.Costliest[b]]
+		if ta.WallTimeMs != tb.WallTimeMs {
+			return ta.WallTimeMs > tb.WallTimeMs
+		}
+		return snap.Costliest[a] < snap.Costliest[b]
+	})
+	return snap
+}